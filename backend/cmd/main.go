@@ -13,6 +13,7 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
 	"fmt"
 	"log"
 	"net/http"
@@ -38,42 +39,72 @@ import (
 	userhandler "gamelink/internal/handler/user"
 	"gamelink/internal/logging"
 	"gamelink/internal/model"
+	"gamelink/internal/pkg/fieldcrypt"
+	availabilityrepo "gamelink/internal/repository/availability"
 	chatrepo "gamelink/internal/repository/chat"
 	commissionrepo "gamelink/internal/repository/commission"
 	"gamelink/internal/repository/common"
+	couponrepo "gamelink/internal/repository/coupon"
+	creditrepo "gamelink/internal/repository/credit"
+	dispatchrepo "gamelink/internal/repository/dispatch"
+	disputerepo "gamelink/internal/repository/dispute"
+	extensionrepo "gamelink/internal/repository/extension"
 	feedrepo "gamelink/internal/repository/feed"
+	fxrepo "gamelink/internal/repository/fx"
 	gamerepo "gamelink/internal/repository/game"
+	ledgerrepo "gamelink/internal/repository/ledger"
 	notificationrepo "gamelink/internal/repository/notification"
+	operationlogrepo "gamelink/internal/repository/operation_log"
 	orderrepo "gamelink/internal/repository/order"
+	orderhistoryrepo "gamelink/internal/repository/order_history"
 	paymentrepo "gamelink/internal/repository/payment"
 	permissionrepo "gamelink/internal/repository/permission"
 	playerrepo "gamelink/internal/repository/player"
 	playertagrepo "gamelink/internal/repository/player_tag"
 	rankingrepo "gamelink/internal/repository/ranking"
+	reconciliationrepo "gamelink/internal/repository/reconciliation"
 	reviewrepo "gamelink/internal/repository/review"
 	reviewreplyrepo "gamelink/internal/repository/reviewreply"
 	rolerepo "gamelink/internal/repository/role"
 	serviceitemrepo "gamelink/internal/repository/serviceitem"
 	statsrepo "gamelink/internal/repository/stats"
+	teamrepo "gamelink/internal/repository/team"
+	uploadrepo "gamelink/internal/repository/upload"
 	userrepo "gamelink/internal/repository/user"
+	walletrepo "gamelink/internal/repository/wallet"
 	withdrawrepo "gamelink/internal/repository/withdraw"
 	"gamelink/internal/scheduler"
 	adminservice "gamelink/internal/service/admin"
+	assignmentservice "gamelink/internal/service/assignment"
 	authservice "gamelink/internal/service/auth"
+	availabilityservice "gamelink/internal/service/availability"
 	chatservice "gamelink/internal/service/chat"
 	commissionservice "gamelink/internal/service/commission"
+	couponservice "gamelink/internal/service/coupon"
+	creditservice "gamelink/internal/service/credit"
+	dispatchservice "gamelink/internal/service/dispatch"
 	earningsservice "gamelink/internal/service/earnings"
+	extensionservice "gamelink/internal/service/extension"
 	feedservice "gamelink/internal/service/feed"
+	fxservice "gamelink/internal/service/fx"
 	giftservice "gamelink/internal/service/gift"
 	itemservice "gamelink/internal/service/item"
+	ledgerservice "gamelink/internal/service/ledger"
 	notificationservice "gamelink/internal/service/notification"
 	orderservice "gamelink/internal/service/order"
 	paymentservice "gamelink/internal/service/payment"
 	permissionservice "gamelink/internal/service/permission"
 	playerservice "gamelink/internal/service/player"
+	pricingservice "gamelink/internal/service/pricing"
+	reconciliationservice "gamelink/internal/service/reconciliation"
 	reviewservice "gamelink/internal/service/review"
 	roleservice "gamelink/internal/service/role"
 	statsservice "gamelink/internal/service/stats"
+	teamservice "gamelink/internal/service/team"
+	uploadservice "gamelink/internal/service/upload"
+	walletservice "gamelink/internal/service/wallet"
+	withdrawservice "gamelink/internal/service/withdraw"
+	"gamelink/internal/storage"
 )
 
 func main() {
@@ -87,6 +118,13 @@ func main() {
 		log.Println("crypto middleware disabled")
 	}
 
+	keyring, err := fieldcrypt.ParseKeyring(cfg.FieldEncryption.ActiveKeyID, cfg.FieldEncryption.Keys, cfg.FieldEncryption.BlindIndexKey)
+	if err != nil {
+		log.Fatalf("初始化字段加密密钥失败: %v", err)
+	}
+	fieldcrypt.SetDefault(keyring)
+	log.Printf("field encryption enabled, active key=%s", keyring.ActiveKeyID())
+
 	orm, err := db.Open(cfg)
 	if err != nil {
 		log.Fatalf("打开数据库失败: %v", err)
@@ -101,6 +139,11 @@ func main() {
 		}
 	}()
 
+	fileStore, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("初始化文件存储失败: %v", err)
+	}
+
 	cacheClient, err := cache.New(cfg.Cache)
 	if err != nil {
 		log.Fatalf("初始化缓存失败: %v", err)
@@ -189,22 +232,182 @@ func main() {
 	commissionRepo := commissionrepo.NewCommissionRepository(orm)
 	serviceItemRepo := serviceitemrepo.NewServiceItemRepository(orm)
 	rankingCommissionRepo := rankingrepo.NewRankingCommissionRepository(orm)
+	ledgerRepo := ledgerrepo.NewLedgerRepository(orm)
 	feedRepo := feedrepo.NewFeedRepository(orm)
 	notificationRepo := notificationrepo.NewNotificationRepository(orm)
 
+	// FX service: exchange rates for multi-currency pricing and reporting
+	fxSvc := fxservice.NewExchangeRateService(fxrepo.NewRateRepository(orm))
+	if cfg.FX.RatesFile != "" {
+		fxSvc.SetProvider(fxservice.NewLocalFileRateProvider(cfg.FX.RatesFile))
+	}
+
+	// Ledger service: automatic vouchers for payments, commissions, withdrawals and refunds
+	ledgerSvc := ledgerservice.NewLedgerService(ledgerRepo)
+	ledgerSvc.SetTxManager(uow)
+	ledgerSvc.SetFX(fxSvc)
+	// Reconciliation service: match provider bills against payments and refunds
+	reconciliationSvc := reconciliationservice.NewReconciliationService(reconciliationrepo.NewReconciliationRepository(orm))
+	reconciliationSvc.SetTxManager(uow)
+
+	// Wallet service: player balances (pending / available / frozen) with immutable transactions
+	walletSvc := walletservice.NewWalletService(walletrepo.NewWalletRepository(orm))
+	walletSvc.SetTxManager(uow)
+
+	// Player credit: rule-based score whose tier gates order grabbing, commission and listing
+	creditSvc := creditservice.NewService(creditrepo.NewCreditRepository(orm), playerRepo)
+	if err := creditSvc.EnsureDefaultRules(context.Background()); err != nil {
+		log.Printf("初始化信用规则失败: %v", err)
+	}
+
 	// Initialize user-side services
 	commissionSvc := commissionservice.NewCommissionService(commissionRepo, orderRepo, playerRepo)
+	commissionSvc.SetCredit(creditSvc)
+	commissionSvc.SetLedger(ledgerSvc)
+	commissionSvc.SetWallet(walletSvc)
+	commissionSvc.SetFX(fxSvc)
+	commissionSvc.SetAdjustments(commissionrepo.NewAdjustmentRepository(orm))
+	commissionSvc.SetTxManager(uow)
 	serviceItemSvc := itemservice.NewServiceItemService(serviceItemRepo, gameRepo, playerRepo)
+	serviceItemSvc.SetPrices(serviceitemrepo.NewPriceRepository(orm))
+	serviceItemSvc.SetFX(fxSvc)
+	commissionSvc.SetServiceItems(serviceItemRepo)
+	// Pricing engine: the single source of order prices and commission splits
+	pricingSvc := pricingservice.NewService(serviceItemRepo, playerRepo)
+	pricingSvc.SetCommissionCalculator(commissionSvc)
+	pricingSvc.SetItemPricer(serviceItemSvc)
+	pricingSvc.SetFX(fxSvc)
+	adminSvc.SetPricer(pricingSvc)
+	// Order status history: every state-machine transition is recorded here
+	orderHistoryRepo := orderhistoryrepo.NewHistoryRepository(orm)
+	adminSvc.SetStatusHistory(orderHistoryRepo)
 	giftSvc := giftservice.NewGiftService(serviceItemRepo, orderRepo, playerRepo, commissionRepo)
+	giftSvc.SetStatusHistory(orderHistoryRepo)
+	giftSvc.SetLedger(ledgerSvc)
+	giftSvc.SetWallet(walletSvc)
+	giftSvc.SetPricer(serviceItemSvc)
+	giftSvc.SetQuoter(pricingSvc)
+	// Player calendar: weekly availability, blackouts and booking conflict detection
+	availabilitySvc := availabilityservice.NewService(availabilityrepo.NewCalendarRepository(orm), playerRepo)
+	adminSvc.SetBookingChecker(availabilitySvc)
 	orderSvc := orderservice.NewOrderService(orderRepo, playerRepo, userRepo, gameRepo, paymentRepo, reviewRepo, commissionRepo)
+	orderSvc.SetCommissionRecorder(commissionSvc)
+	orderSvc.SetFX(fxSvc)
+	orderSvc.SetPricer(pricingSvc)
+	orderSvc.SetCredit(creditSvc)
+	// Coupons: templates, issuance, checkout locking and release on cancel/refund
+	couponSvc := couponservice.NewService(couponrepo.NewCouponRepository(orm), orderRepo)
+	couponSvc.SetTxManager(uow)
+	orderSvc.SetCoupons(couponSvc)
+	authSvc.SetCouponIssuer(couponSvc)
+	orderSvc.SetStatusHistory(orderHistoryRepo)
+	orderSvc.SetBookingChecker(availabilitySvc)
+	// Redis cache fronts order claims with a distributed lock; the DB conditional update stays authoritative
+	if locker, ok := cacheClient.(cache.Locker); ok {
+		orderSvc.SetClaimLocker(locker)
+	}
 	// Inject chat group repo for order chat auto-destroy
 	orderSvc.SetChatGroupRepository(chatGroupRepo)
 	paymentSvc := paymentservice.NewPaymentService(paymentRepo, orderRepo)
+	paymentSvc.SetTxManager(uow)
+	paymentSvc.SetRefundRepository(paymentrepo.NewRefundRepository(orm))
+	paymentSvc.SetLedger(ledgerSvc)
+	adminSvc.SetRefunder(paymentSvc)
+	adminSvc.SetCommissionRecorder(commissionSvc)
+	// Order lifecycle timeouts: close unpaid payments, refund unaccepted orders and notify both parties
+	orderSvc.SetRefunder(paymentSvc)
+	orderSvc.SetPaymentCanceler(paymentSvc)
+	orderSvc.SetNotifications(notificationRepo)
+	orderSvc.SetTimeoutPolicy(orderTimeoutPolicy(cfg.OrderTimeout))
+	if err := configurePaymentGateways(paymentSvc, cfg, api); err != nil {
+		log.Fatalf("初始化支付渠道失败: %v", err)
+	}
 	playerSvc := playerservice.NewPlayerService(playerRepo, userRepo, gameRepo, orderRepo, reviewRepo, playerTagRepo, cacheClient)
+	playerSvc.SetCredit(creditSvc)
+	// Dispatch engine: scores candidate players, pushes offers or auto-assigns confirmed orders
+	dispatchSvc := dispatchservice.NewService(dispatchrepo.NewDispatchRepository(orm), orderRepo, playerRepo)
+	dispatchSvc.SetPolicy(dispatchPolicy(cfg.Dispatch))
+	dispatchSvc.SetStatusHistory(orderHistoryRepo)
+	dispatchSvc.SetTags(playerTagRepo)
+	dispatchSvc.SetPresence(playerSvc)
+	dispatchSvc.SetBookingChecker(availabilitySvc)
+	dispatchSvc.SetNotifications(notificationRepo)
+	orderSvc.SetDispatchHold(dispatchSvc)
+	// Team orders: leaders snatch team orders, assemble a lineup and split income per member
+	teamSvc := teamservice.NewService(teamrepo.NewTeamRepository(orm), orderRepo, playerRepo, serviceItemRepo)
+	dispatchWindow, err := time.ParseDuration(cfg.Team.DispatchWindow)
+	if err != nil {
+		log.Fatalf("解析 TEAM_DISPATCH_WINDOW 失败: %v", err)
+	}
+	teamSvc.SetDispatchWindow(dispatchWindow)
+	teamSvc.SetStatusHistory(orderHistoryRepo)
+	teamSvc.SetOperationLogs(operationlogrepo.NewOperationLogRepository(orm))
+	teamSvc.SetNotifications(notificationRepo)
+	commissionSvc.SetIncomeSplitter(teamSvc)
+	// Order extensions: users extend in-progress orders and pay the difference as a supplementary payment
+	extensionRepo := extensionrepo.NewExtensionRepository(orm)
+	extensionSvc := extensionservice.NewService(extensionRepo, orderRepo, playerRepo)
+	extensionSvc.SetPricer(pricingSvc)
+	extensionSvc.SetBookingChecker(availabilitySvc)
+	extensionSvc.SetOperationLogs(operationlogrepo.NewOperationLogRepository(orm))
+	extensionSvc.SetNotifications(notificationRepo)
+	paymentSvc.SetExtensions(extensionSvc)
+	orderSvc.SetExtensions(extensionRepo)
+	// Dispute desk: new disputes go to on-duty CS agents and escalate to supervisors when the SLA is breached
+	disputeSvc := assignmentservice.NewAssignmentService(disputerepo.NewDisputeRepository(orm), orderRepo, userRepo,
+		operationlogrepo.NewOperationLogRepository(orm), notificationRepo, paymentRepo)
+	disputeSvc.SetRefunder(paymentSvc)
+	disputeSvc.SetStatusHistory(orderHistoryRepo)
+	disputeSvc.SetCommissionAdjuster(commissionSvc)
+	disputeSvc.SetTxManager(uow)
+	disputeSvc.SetCredit(creditSvc)
+	disputeSvc.SetAgents(disputerepo.NewAgentRepository(orm))
+	disputeSLA, err := time.ParseDuration(cfg.Dispute.SLA)
+	if err != nil {
+		log.Fatalf("解析 DISPUTE_SLA 失败: %v", err)
+	}
+	escalationSLA, err := time.ParseDuration(cfg.Dispute.EscalationSLA)
+	if err != nil {
+		log.Fatalf("解析 DISPUTE_ESCALATION_SLA 失败: %v", err)
+	}
+	disputeSvc.SetSLA(disputeSLA, escalationSLA)
+	// Uploads: files go to the configured storage backend and are served via signed, expiring links
+	urlTTL, err := time.ParseDuration(cfg.Storage.URLTTL)
+	if err != nil {
+		log.Fatalf("解析 STORAGE_URL_TTL 失败: %v", err)
+	}
+	uploadSvc := uploadservice.NewService(uploadrepo.NewUploadRepository(orm), fileStore, cfg.Storage.SigningKey, urlTTL)
+	disputeSvc.SetEvidence(uploadSvc)
 	reviewSvc := reviewservice.NewReviewService(reviewRepo, orderRepo, playerRepo, userRepo, reviewReplyRepo)
+	reviewSvc.SetCredit(creditSvc)
 	earningsSvc := earningsservice.NewEarningsService(playerRepo, orderRepo, withdrawRepo)
+	earningsSvc.SetWallet(walletSvc)
+	earningsSvc.SetTxManager(uow)
+	earningsSvc.SetFX(fxSvc)
+	// Withdraw service: review state machine, payout batches and provider payouts
+	withdrawSvc := withdrawservice.NewWithdrawService(withdrawRepo, withdrawrepo.NewPayoutRepository(orm))
+	withdrawSvc.SetTxManager(uow)
+	withdrawSvc.SetWallet(walletSvc)
+	withdrawSvc.SetLedger(ledgerSvc)
+	withdrawSvc.SetNotifications(notificationRepo)
+	withdrawSvc.SetPayoutProvider(withdrawservice.NewLocalPayoutProvider())
 	chatSvc := chatservice.NewChatService(chatGroupRepo, chatMemberRepo, chatMessageRepo, chatReportRepo, cacheClient)
+	chatSvc.SetAttachments(uploadSvc)
+	// Chat gateway: WebSocket push fanned out over Redis pub/sub (in-process for the memory cache)
+	var chatHub *chatservice.Hub
+	if bus, ok := cacheClient.(cache.PubSub); ok {
+		chatHub = chatservice.NewHub(chatSvc, bus, cacheClient)
+		chatSvc.SetEvents(chatHub)
+		hubCtx, stopHub := context.WithCancel(context.Background())
+		defer stopHub()
+		go func() {
+			if err := chatHub.Run(hubCtx); err != nil {
+				log.Printf("chat gateway stopped: %v", err)
+			}
+		}()
+	}
 	feedSvc := feedservice.NewService(feedRepo, nil)
+	feedSvc.SetAttachments(uploadSvc)
 	notificationSvc := notificationservice.NewService(notificationRepo)
 
 	// Initialize settlement scheduler
@@ -212,23 +415,85 @@ func main() {
 	settlementScheduler.Start()
 	defer settlementScheduler.Stop()
 
+	// Initialize commission scheduler (record commissions missed when orders completed)
+	commissionScheduler := scheduler.NewCommissionScheduler(commissionSvc)
+	commissionScheduler.Start()
+	defer commissionScheduler.Stop()
+
 	// Initialize chat retention scheduler (30 days retention)
 	chatRetention := scheduler.NewChatRetentionScheduler(chatGroupRepo, chatMessageRepo, 30)
 	chatRetention.Start()
 	defer chatRetention.Stop()
 
+	// Initialize refund scheduler (retry submissions and confirm async refunds)
+	refundScheduler := scheduler.NewRefundScheduler(paymentSvc)
+	refundScheduler.Start()
+	defer refundScheduler.Stop()
+
+	// Initialize payout scheduler (submit provider payouts, retry and query results)
+	payoutScheduler := scheduler.NewPayoutScheduler(withdrawSvc)
+	payoutScheduler.Start()
+	defer payoutScheduler.Stop()
+
+	// Initialize coupon scheduler (release coupons of canceled/refunded orders, redeem completed ones, expire stale ones)
+	couponScheduler := scheduler.NewCouponScheduler(couponSvc)
+	couponScheduler.Start()
+	defer couponScheduler.Stop()
+
+	// Initialize order timeout scheduler (auto-cancel, auto-refund and auto-complete)
+	orderTimeoutScheduler := scheduler.NewOrderTimeoutScheduler(orderSvc, cfg.OrderTimeout.Interval)
+	orderTimeoutScheduler.Start()
+	defer orderTimeoutScheduler.Stop()
+
+	// Initialize dispatch scheduler (expire unanswered offers and dispatch new orders)
+	dispatchScheduler := scheduler.NewDispatchScheduler(dispatchSvc, cfg.Dispatch.Interval)
+	dispatchScheduler.Start()
+	defer dispatchScheduler.Stop()
+
+	// Initialize team assignment scheduler (release team orders whose lineup was not confirmed in time)
+	teamScheduler := scheduler.NewTeamAssignmentScheduler(teamSvc, cfg.Team.Interval)
+	teamScheduler.Start()
+	defer teamScheduler.Stop()
+
+	// Initialize dispute SLA scheduler (assign waiting disputes to on-duty agents and escalate SLA breaches)
+	disputeScheduler := scheduler.NewDisputeSLAScheduler(disputeSvc, cfg.Dispute.Interval)
+	disputeScheduler.Start()
+	defer disputeScheduler.Stop()
+
+	// Initialize FX rate scheduler (only when a rate provider is configured)
+	if cfg.FX.RatesFile != "" {
+		fxScheduler := scheduler.NewFXRateScheduler(fxSvc, cfg.FX.RefreshInterval)
+		fxScheduler.Start()
+		defer fxScheduler.Stop()
+	}
+
+	// 支付渠道异步回调（公开路由，依赖渠道签名校验）
+	userhandler.RegisterPaymentNotifyRoutes(api, paymentSvc)
+	// 上传文件下载（公开路由，依赖链接签名与有效期）
+	userhandler.RegisterUploadContentRoutes(api, uploadSvc)
+
 	// Register user-side routes (require authentication)
 	authMiddleware := middleware.JWTAuth()
+	if chatHub != nil {
+		userhandler.RegisterChatGatewayRoutes(api, chatHub, authMiddleware)
+	}
 	userGroup := api.Group("/user")
 	userGroup.Use(authMiddleware)
 	{
 		userhandler.RegisterOrderRoutes(userGroup, orderSvc, authMiddleware)
+		userhandler.RegisterTeamOrderRoutes(userGroup, teamSvc, authMiddleware)
+		userhandler.RegisterExtensionRoutes(userGroup, extensionSvc, authMiddleware)
+		userhandler.RegisterDisputeRoutes(userGroup, disputeSvc, authMiddleware)
+		userhandler.RegisterPricingRoutes(userGroup, pricingSvc, authMiddleware)
+		userhandler.RegisterCouponRoutes(userGroup, couponSvc, authMiddleware)
 		userhandler.RegisterPaymentRoutes(userGroup, paymentSvc, authMiddleware)
 		userhandler.RegisterPlayerRoutes(userGroup, playerSvc, authMiddleware)
+		userhandler.RegisterAvailabilityRoutes(userGroup, availabilitySvc)
 		userhandler.RegisterReviewRoutes(userGroup, reviewSvc, authMiddleware)
 		userhandler.RegisterGiftRoutes(userGroup, giftSvc, serviceItemSvc, authMiddleware)
 		userhandler.RegisterChatRoutes(userGroup, chatSvc, authMiddleware)
 		userhandler.RegisterFeedRoutes(userGroup, feedSvc, authMiddleware)
+		userhandler.RegisterUploadRoutes(userGroup, uploadSvc, authMiddleware)
 	}
 
 	// Register player-side routes (require authentication)
@@ -236,11 +501,16 @@ func main() {
 	playerGroup.Use(authMiddleware)
 	{
 		playerhandler.RegisterProfileRoutes(playerGroup, playerSvc, authMiddleware)
+		playerhandler.RegisterAvailabilityRoutes(playerGroup, availabilitySvc, authMiddleware)
 		playerhandler.RegisterOrderRoutes(playerGroup, orderSvc, authMiddleware)
+		playerhandler.RegisterDispatchRoutes(playerGroup, dispatchSvc, authMiddleware)
+		playerhandler.RegisterTeamRoutes(playerGroup, teamSvc, authMiddleware)
+		playerhandler.RegisterExtensionRoutes(playerGroup, extensionSvc, authMiddleware)
 		playerhandler.RegisterEarningsRoutes(playerGroup, earningsSvc, authMiddleware)
 		playerhandler.RegisterCommissionRoutes(playerGroup, commissionSvc, authMiddleware)
 		playerhandler.RegisterGiftRoutes(playerGroup, giftSvc, authMiddleware)
 		playerhandler.RegisterReviewRoutes(playerGroup, reviewSvc, authMiddleware)
+		playerhandler.RegisterCreditRoutes(playerGroup, creditSvc, authMiddleware)
 	}
 
 	if cfg.EnableSwagger {
@@ -309,8 +579,29 @@ func main() {
 	// Service Item management routes (admin) - 统一管理护航服务和礼物
 	adminhandler.RegisterServiceItemRoutes(rbacGroup, serviceItemSvc)
 
+	// Coupon routes (admin) - 优惠券模板与发放
+	adminhandler.RegisterCouponRoutes(rbacGroup, couponSvc)
+
+	// Credit routes (admin) - 陪玩师信用规则、违约上报与人工调整
+	adminhandler.RegisterCreditRoutes(rbacGroup, creditSvc)
+
 	// Withdraw management routes (admin) - 提现审核管理
-	adminhandler.RegisterWithdrawRoutes(rbacGroup, withdrawRepo)
+	adminhandler.RegisterWithdrawRoutes(rbacGroup, withdrawSvc)
+
+	// Ledger routes (admin) - 总账凭证与科目余额
+	adminhandler.RegisterLedgerRoutes(rbacGroup, ledgerSvc)
+
+	// Reconciliation routes (admin) - 渠道对账单导入与异常处理
+	adminhandler.RegisterReconciliationRoutes(rbacGroup, reconciliationSvc)
+
+	// FX routes (admin) - 汇率查询、手工录入与刷新
+	adminhandler.RegisterFXRoutes(rbacGroup, fxSvc)
+
+	// Dispatch routes (admin) - 候选陪玩师推荐与手动派单
+	adminhandler.RegisterDispatchRoutes(rbacGroup, dispatchSvc)
+
+	// Dispute routes (admin) - 客服争议工作台与排班
+	adminhandler.RegisterDisputeRoutes(rbacGroup, disputeSvc)
 
 	// Dashboard routes (admin) - 数据统计和Dashboard
 	adminhandler.RegisterDashboardRoutes(rbacGroup, userRepo, playerRepo, orderRepo, withdrawRepo, serviceItemRepo, commissionRepo)
@@ -416,6 +707,112 @@ func assignDefaultRolePermissions(ctx context.Context, roleSvc *roleservice.Role
 	return nil
 }
 
+// configurePaymentGateways 按支付模式注册渠道网关：sandbox 模式挂载本地沙箱，live 模式接入微信支付/支付宝。
+func configurePaymentGateways(svc *paymentservice.PaymentService, cfg config.AppConfig, api gin.IRouter) error {
+	pc := cfg.Payment
+	notifyBase := pc.NotifyBaseURL
+	if notifyBase == "" {
+		notifyBase = fmt.Sprintf("http://127.0.0.1:%s/api/v1/payments/notify", cfg.Port)
+	}
+	svc.SetNotifyBaseURL(notifyBase)
+
+	if pc.Mode != config.PaymentModeLive {
+		const sandboxPrefix = "/api/v1/sandbox/pay"
+		sandbox := paymentservice.NewSandboxServer(pc.SandboxSecret)
+		api.Any("/sandbox/pay/*path", gin.WrapH(http.StripPrefix(sandboxPrefix, sandbox)))
+		base := strings.TrimSuffix(notifyBase, "/api/v1/payments/notify") + sandboxPrefix
+		svc.SetGateway(paymentservice.NewMountedSandboxGateway(model.PaymentMethodWeChat, sandbox, base))
+		svc.SetGateway(paymentservice.NewMountedSandboxGateway(model.PaymentMethodAlipay, sandbox, base))
+		log.Printf("payment sandbox enabled at %s", base)
+		return nil
+	}
+
+	var gateways []paymentservice.Gateway
+	if wx := pc.WeChat; wx.MchID != "" {
+		privateKey, err := readRSAPrivateKey(wx.PrivateKeyPath)
+		if err != nil {
+			return fmt.Errorf("wechat pay private key: %w", err)
+		}
+		platformKey, err := readRSAPublicKey(wx.PlatformCertPath)
+		if err != nil {
+			return fmt.Errorf("wechat pay platform cert: %w", err)
+		}
+		gateways = append(gateways, paymentservice.NewWeChatGateway(paymentservice.WeChatConfig{
+			AppID:             wx.AppID,
+			MchID:             wx.MchID,
+			MerchantSerialNo:  wx.SerialNo,
+			PrivateKey:        privateKey,
+			APIv3Key:          wx.APIv3Key,
+			PlatformPublicKey: platformKey,
+			PlatformSerialNo:  wx.PlatformSerialNo,
+			BaseURL:           wx.BaseURL,
+		}))
+	}
+	if ali := pc.Alipay; ali.AppID != "" {
+		privateKey, err := readRSAPrivateKey(ali.PrivateKeyPath)
+		if err != nil {
+			return fmt.Errorf("alipay private key: %w", err)
+		}
+		publicKey, err := readRSAPublicKey(ali.PublicKeyPath)
+		if err != nil {
+			return fmt.Errorf("alipay public key: %w", err)
+		}
+		gateways = append(gateways, paymentservice.NewAlipayGateway(paymentservice.AlipayConfig{
+			AppID:           ali.AppID,
+			PrivateKey:      privateKey,
+			AlipayPublicKey: publicKey,
+			GatewayURL:      ali.GatewayURL,
+		}))
+	}
+	svc.SetGateways(gateways...)
+	return nil
+}
+
+// orderTimeoutPolicy 解析订单超时窗口；配置已在启动时校验，空值沿用默认窗口。
+func orderTimeoutPolicy(c config.OrderTimeoutConfig) orderservice.TimeoutPolicy {
+	p := orderservice.DefaultTimeoutPolicy
+	parse := func(v string, dst *time.Duration) {
+		if d, err := time.ParseDuration(v); err == nil {
+			*dst = d
+		}
+	}
+	parse(c.PaymentTimeout, &p.PaymentTimeout)
+	parse(c.AcceptTimeout, &p.AcceptTimeout)
+	parse(c.CompleteGrace, &p.CompleteGrace)
+	parse(c.ConfirmWindow, &p.ConfirmWindow)
+	return p
+}
+
+func dispatchPolicy(c config.DispatchConfig) dispatchservice.Policy {
+	p := dispatchservice.DefaultPolicy
+	if c.Mode != "" {
+		p.Mode = model.DispatchMode(c.Mode)
+	}
+	if c.TopN > 0 {
+		p.TopN = c.TopN
+	}
+	if d, err := time.ParseDuration(c.OfferTimeout); err == nil && d > 0 {
+		p.OfferTimeout = d
+	}
+	return p
+}
+
+func readRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return paymentservice.ParseRSAPrivateKeyPEM(data)
+}
+
+func readRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return paymentservice.ParseRSAPublicKeyPEM(data)
+}
+
 func resolveGinMode() string {
 	if mode := os.Getenv("GIN_MODE"); mode != "" {
 		return mode
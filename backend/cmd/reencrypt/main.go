@@ -0,0 +1,47 @@
+// Command reencrypt 以当前主密钥重新加密数据库中的敏感字段。
+//
+// 主密钥轮换步骤：
+//  1. 在 FIELD_ENCRYPTION_KEYS 中新增密钥，并将 FIELD_ENCRYPTION_ACTIVE_KEY 切换为新密钥 ID；
+//  2. 滚动重启服务，新写入的数据使用新密钥，旧数据仍可用旧密钥解密；
+//  3. 运行 go run ./cmd/reencrypt，把存量数据迁移到新密钥（可重复执行）；
+//  4. 确认完成后从 FIELD_ENCRYPTION_KEYS 中移除旧密钥。
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"gamelink/internal/config"
+	"gamelink/internal/db"
+	"gamelink/internal/pkg/fieldcrypt"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 200, "每批处理的行数")
+	flag.Parse()
+
+	cfg := config.Load()
+	if err := config.Validate(os.Getenv("APP_ENV"), cfg); err != nil {
+		log.Fatalf("配置校验失败: %v", err)
+	}
+	keyring, err := fieldcrypt.ParseKeyring(cfg.FieldEncryption.ActiveKeyID, cfg.FieldEncryption.Keys, cfg.FieldEncryption.BlindIndexKey)
+	if err != nil {
+		log.Fatalf("初始化字段加密密钥失败: %v", err)
+	}
+	fieldcrypt.SetDefault(keyring)
+
+	orm, err := db.Open(cfg)
+	if err != nil {
+		log.Fatalf("打开数据库失败: %v", err)
+	}
+	if sqlDB, err := orm.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	stats, err := db.ReencryptFields(orm, *batchSize)
+	if err != nil {
+		log.Fatalf("重加密失败（已处理 users=%d withdraws=%d，可重新执行）: %v", stats.Users, stats.Withdraws, err)
+	}
+	log.Printf("重加密完成：active key=%s users=%d withdraws=%d", keyring.ActiveKeyID(), stats.Users, stats.Withdraws)
+}
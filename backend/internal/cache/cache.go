@@ -15,6 +15,12 @@ type Cache interface {
 	Close(ctx context.Context) error
 }
 
+// Locker 分布式锁（Redis 缓存实现），用于在多实例部署下串行化同一资源上的操作。
+type Locker interface {
+	// TryLock 尝试获取锁，不等待；获取成功时返回的 unlock 仅释放自己持有的锁。
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
 // New 根据配置创建缓存实例。
 func New(cfg config.CacheConfig) (Cache, error) {
 	switch cfg.Type {
@@ -16,6 +16,7 @@ type memoryCache struct {
 	mu     sync.RWMutex
 	values map[string]memoryEntry
 	stopCh chan struct{}
+	bus    memoryBus
 }
 
 // NewMemory 创建内存缓存。
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// PubSub 跨实例广播：Redis 缓存基于 Redis pub/sub，内存缓存只在本进程内投递。
+type PubSub interface {
+	// Publish 向频道广播一条消息。
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe 订阅频道；调用返回的 cancel 取消订阅并关闭消息通道。
+	// 订阅方消费过慢时新消息会被丢弃。
+	Subscribe(ctx context.Context, channel string) (messages <-chan []byte, cancel func(), err error)
+}
+
+// subscriberBuffer 每个订阅者的消息缓冲。
+const subscriberBuffer = 256
+
+// memoryBus 是内存缓存的进程内广播。
+type memoryBus struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan []byte]struct{}
+}
+
+func (c *memoryCache) Publish(_ context.Context, channel string, payload []byte) error {
+	c.bus.mu.RLock()
+	defer c.bus.mu.RUnlock()
+	for ch := range c.bus.subs[channel] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+	return nil
+}
+
+func (c *memoryCache) Subscribe(_ context.Context, channel string) (<-chan []byte, func(), error) {
+	ch := make(chan []byte, subscriberBuffer)
+	c.bus.mu.Lock()
+	if c.bus.subs == nil {
+		c.bus.subs = make(map[string]map[chan []byte]struct{})
+	}
+	if c.bus.subs[channel] == nil {
+		c.bus.subs[channel] = make(map[chan []byte]struct{})
+	}
+	c.bus.subs[channel][ch] = struct{}{}
+	c.bus.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			c.bus.mu.Lock()
+			delete(c.bus.subs[channel], ch)
+			c.bus.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel, nil
+}
+
+func (c *redisCache) Publish(ctx context.Context, channel string, payload []byte) error {
+	return c.client.Publish(ctx, channel, payload).Err()
+}
+
+func (c *redisCache) Subscribe(ctx context.Context, channel string) (<-chan []byte, func(), error) {
+	sub := c.client.Subscribe(ctx, channel)
+	// 等待订阅确认，保证返回后发布的消息都能收到
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, nil, err
+	}
+	out := make(chan []byte, subscriberBuffer)
+	done := make(chan struct{})
+	msgs := sub.Channel()
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			close(done)
+			_ = sub.Close()
+		})
+	}
+	return out, cancel, nil
+}
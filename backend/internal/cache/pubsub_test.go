@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"gamelink/internal/config"
+)
+
+func TestPubSubImplementations(t *testing.T) {
+	mr := miniredis.RunT(t)
+	defer mr.Close()
+
+	redisCache, err := NewRedis(config.RedisConfig{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatalf("NewRedis failed: %v", err)
+	}
+	defer redisCache.Close(context.Background())
+
+	for name, c := range map[string]Cache{"memory": NewMemory(), "redis": redisCache} {
+		t.Run(name, func(t *testing.T) {
+			bus, ok := c.(PubSub)
+			if !ok {
+				t.Fatalf("%s cache does not implement PubSub", name)
+			}
+			ctx := context.Background()
+
+			msgs, cancel, err := bus.Subscribe(ctx, "events")
+			if err != nil {
+				t.Fatalf("Subscribe failed: %v", err)
+			}
+			if err := bus.Publish(ctx, "other", []byte("ignored")); err != nil {
+				t.Fatalf("Publish failed: %v", err)
+			}
+			if err := bus.Publish(ctx, "events", []byte("hello")); err != nil {
+				t.Fatalf("Publish failed: %v", err)
+			}
+			select {
+			case got := <-msgs:
+				if string(got) != "hello" {
+					t.Fatalf("expected hello, got %q", got)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("message not delivered")
+			}
+
+			cancel()
+			deadline := time.After(time.Second)
+			for {
+				select {
+				case _, open := <-msgs:
+					if !open {
+						return
+					}
+				case <-deadline:
+					t.Fatal("channel not closed after cancel")
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package cache
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -58,3 +60,24 @@ func (c *redisCache) Delete(ctx context.Context, key string) error {
 func (c *redisCache) Close(context.Context) error {
 	return c.client.Close()
 }
+
+// unlockScript 仅当锁仍由自己持有时删除，避免误删超时后被他人获取的锁。
+var unlockScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+
+// TryLock 基于 SET NX PX 的分布式锁。
+func (c *redisCache) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, false, err
+	}
+	token := hex.EncodeToString(buf)
+	ok, err := c.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	unlock := func() {
+		// 使用独立上下文，请求已取消时也能释放锁
+		_ = unlockScript.Run(context.Background(), c.client, []string{key}, token).Err()
+	}
+	return unlock, true, nil
+}
@@ -51,6 +51,43 @@ func TestNewRedisOperations(t *testing.T) {
 	}
 }
 
+func TestRedisTryLock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, err := NewRedis(config.RedisConfig{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatalf("NewRedis failed: %v", err)
+	}
+	defer c.Close(context.Background())
+	locker, ok := c.(Locker)
+	if !ok {
+		t.Fatalf("redis cache should implement Locker")
+	}
+	ctx := context.Background()
+
+	unlock, ok, err := locker.TryLock(ctx, "lock:order:1", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("expected first lock to succeed, ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := locker.TryLock(ctx, "lock:order:1", time.Minute); err != nil || ok {
+		t.Fatalf("expected second lock to fail, ok=%v err=%v", ok, err)
+	}
+
+	// 锁过期后被他人获取，原持有者释放时不能删除他人的锁
+	mr.FastForward(2 * time.Minute)
+	unlock2, ok, err := locker.TryLock(ctx, "lock:order:1", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("expected lock after expiry, ok=%v err=%v", ok, err)
+	}
+	unlock()
+	if !mr.Exists("lock:order:1") {
+		t.Fatalf("stale unlock must not release another holder's lock")
+	}
+	unlock2()
+	if mr.Exists("lock:order:1") {
+		t.Fatalf("expected lock to be released")
+	}
+}
+
 func TestNewRedisPingFailure(t *testing.T) {
 	_, err := NewRedis(config.RedisConfig{
 		Addr: "127.0.0.1:0",
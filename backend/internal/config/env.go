@@ -15,19 +15,35 @@ const (
 	// DefaultDevJWTSecret 为开发环境提供兜底的 JWT 密钥（仅限本地调试）。
 	DefaultDevJWTSecret = "gamelink-default-secret-key-change-in-development"
 	defaultTokenTTL     = 24
+
+	// 开发环境兜底的字段加密主密钥与盲索引密钥（base64，仅限本地调试）。
+	defaultDevFieldKeyID         = "dev"
+	defaultDevFieldKey           = "Z2FtZWxpbmstZGV2LWZpZWxkLWVuY3J5cHRpb24tazE="
+	defaultDevFieldBlindIndexKey = "Z2FtZWxpbmstZGV2LWJsaW5kLWluZGV4LWtleS0wMDE="
+
+	// 开发环境兜底的上传下载链接签名密钥（仅限本地调试）。
+	defaultDevStorageSigningKey = "gamelink-dev-upload-signing-key"
 )
 
 // AppConfig 汇总服务运行所需的核心配置。
 type AppConfig struct {
-	Port          string
-	EnableSwagger bool
-	Database      DatabaseConfig
-	Cache         CacheConfig
-	Crypto        CryptoConfig
-	Auth          AuthConfig
-	Seed          SeedConfig
-	SuperAdmin    SuperAdminConfig
-	AdminAuth     AdminAuthConfig
+	Port            string
+	EnableSwagger   bool
+	Database        DatabaseConfig
+	Cache           CacheConfig
+	Crypto          CryptoConfig
+	FieldEncryption FieldEncryptionConfig
+	Auth            AuthConfig
+	Seed            SeedConfig
+	SuperAdmin      SuperAdminConfig
+	AdminAuth       AdminAuthConfig
+	Payment         PaymentConfig
+	FX              FXConfig
+	OrderTimeout    OrderTimeoutConfig
+	Dispatch        DispatchConfig
+	Team            TeamConfig
+	Dispute         DisputeConfig
+	Storage         StorageConfig
 }
 
 // DatabaseConfig 描述数据库驱动与连接信息。
@@ -59,6 +75,15 @@ type CryptoConfig struct {
 	UseSignature bool     `yaml:"use_signature"`
 }
 
+// FieldEncryptionConfig 描述数据库敏感字段加密密钥。
+// Keys 为主密钥 ID 到 base64 编码 32 字节密钥的映射，轮换时新增密钥并切换 ActiveKeyID，
+// 旧密钥保留到重加密命令执行完毕；BlindIndexKey 用于手机号、邮箱的盲索引，不随主密钥轮换。
+type FieldEncryptionConfig struct {
+	ActiveKeyID   string            `yaml:"active_key_id"`
+	Keys          map[string]string `yaml:"keys"`
+	BlindIndexKey string            `yaml:"blind_index_key"`
+}
+
 // AuthConfig 描述鉴权配置。
 type AuthConfig struct {
 	JWTSecret     string `yaml:"jwt_secret"`
@@ -83,6 +108,96 @@ type AdminAuthConfig struct {
 	Mode string `yaml:"mode"`
 }
 
+// FXConfig 描述汇率数据源。RatesFile 为本地汇率文件，为空时只使用手工录入的汇率。
+type FXConfig struct {
+	RatesFile       string `yaml:"rates_file"`
+	RefreshInterval string `yaml:"refresh_interval"` // cron @every 间隔，如 1h
+}
+
+// OrderTimeoutConfig 描述订单生命周期超时窗口，均为 Go duration 字符串（如 30m、2h），0 表示不自动处理。
+type OrderTimeoutConfig struct {
+	PaymentTimeout string `yaml:"payment_timeout"` // 下单后未支付自动取消
+	AcceptTimeout  string `yaml:"accept_timeout"`  // 支付后无人接单自动退款
+	CompleteGrace  string `yaml:"complete_grace"`  // 预约结束后用户未确认自动完成
+	ConfirmWindow  string `yaml:"confirm_window"`  // 陪玩师提交完成后用户未确认自动完成，默认与争议窗口一致
+	Interval       string `yaml:"interval"`        // 扫描间隔（cron @every）
+}
+
+// DispatchConfig 描述智能派单策略。Mode 为 pool（抢单大厅）、push（推送给评分最高的 TopN 位陪玩师）或 auto（自动指派）。
+type DispatchConfig struct {
+	Mode         string `yaml:"mode"`
+	TopN         int    `yaml:"top_n"`
+	OfferTimeout string `yaml:"offer_timeout"` // 推送邀请的响应时限
+	Interval     string `yaml:"interval"`      // 扫描间隔（cron @every）
+}
+
+// TeamConfig 描述车队抢单。队长抢单后须在 DispatchWindow 内完成组队，否则订单释放回车队大厅。
+type TeamConfig struct {
+	DispatchWindow string `yaml:"dispatch_window"`
+	Interval       string `yaml:"interval"` // 超时释放扫描间隔（cron @every）
+}
+
+// DisputeConfig 描述客服争议工作台。SLA 为新争议的处理时限，超时后升级给上一级客服并重新计时 EscalationSLA。
+type DisputeConfig struct {
+	SLA           string `yaml:"sla"`
+	EscalationSLA string `yaml:"escalation_sla"`
+	Interval      string `yaml:"interval"` // 自动指派与 SLA 扫描间隔（cron @every）
+}
+
+// StorageConfig 描述上传文件的存储后端。Driver 为 local（本地目录）或 s3（S3 兼容对象存储）；
+// 文件一律经服务端签发的限时链接下载，SigningKey 为链接签名密钥，URLTTL 为链接有效期。
+type StorageConfig struct {
+	Driver     string   `yaml:"driver"`
+	LocalDir   string   `yaml:"local_dir"`
+	SigningKey string   `yaml:"signing_key"`
+	URLTTL     string   `yaml:"url_ttl"`
+	S3         S3Config `yaml:"s3"`
+}
+
+// S3Config 描述 S3 兼容对象存储（AWS S3、MinIO 等），使用 path-style 访问。
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+}
+
+// PaymentConfig 描述支付渠道配置。Mode 为 live 时接入微信/支付宝，为空或 sandbox 时使用本地沙箱（生产环境不允许）。
+type PaymentConfig struct {
+	Mode          string          `yaml:"mode"`
+	NotifyBaseURL string          `yaml:"notify_base_url"`
+	SandboxSecret string          `yaml:"sandbox_secret"`
+	WeChat        WeChatPayConfig `yaml:"wechat"`
+	Alipay        AlipayPayConfig `yaml:"alipay"`
+}
+
+// WeChatPayConfig 描述微信支付 APIv3 商户配置，密钥均以 PEM 文件路径提供。
+type WeChatPayConfig struct {
+	AppID            string `yaml:"app_id"`
+	MchID            string `yaml:"mch_id"`
+	SerialNo         string `yaml:"serial_no"`
+	PrivateKeyPath   string `yaml:"private_key_path"`
+	APIv3Key         string `yaml:"api_v3_key"`
+	PlatformCertPath string `yaml:"platform_cert_path"`
+	PlatformSerialNo string `yaml:"platform_serial_no"`
+	BaseURL          string `yaml:"base_url"`
+}
+
+// AlipayPayConfig 描述支付宝开放平台配置。
+type AlipayPayConfig struct {
+	AppID          string `yaml:"app_id"`
+	PrivateKeyPath string `yaml:"private_key_path"`
+	PublicKeyPath  string `yaml:"public_key_path"`
+	GatewayURL     string `yaml:"gateway_url"`
+}
+
+// 支付模式
+const (
+	PaymentModeSandbox = "sandbox"
+	PaymentModeLive    = "live"
+)
+
 type cryptoFileConfig struct {
 	Enabled      *bool    `yaml:"enabled"`
 	SecretKey    string   `yaml:"secret_key"`
@@ -113,13 +228,21 @@ type fileConfig struct {
 		Port          string `yaml:"port"`
 		EnableSwagger *bool  `yaml:"enable_swagger"`
 	} `yaml:"server"`
-	Database  DatabaseConfig   `yaml:"database"`
-	Cache     CacheConfig      `yaml:"cache"`
-	Crypto    cryptoFileConfig `yaml:"crypto"`
-	Auth      authFileConfig   `yaml:"auth"`
-	Seed      SeedConfig       `yaml:"seed"`
-	SuperAdmin superAdminFileConfig  `yaml:"super_admin"`
-	AdminAuth  adminAuthFileConfig  `yaml:"admin_auth"`
+	Database        DatabaseConfig        `yaml:"database"`
+	Cache           CacheConfig           `yaml:"cache"`
+	Crypto          cryptoFileConfig      `yaml:"crypto"`
+	FieldEncryption FieldEncryptionConfig `yaml:"field_encryption"`
+	Auth            authFileConfig        `yaml:"auth"`
+	Seed            SeedConfig            `yaml:"seed"`
+	SuperAdmin      superAdminFileConfig  `yaml:"super_admin"`
+	AdminAuth       adminAuthFileConfig   `yaml:"admin_auth"`
+	Payment         PaymentConfig         `yaml:"payment"`
+	FX              FXConfig              `yaml:"fx"`
+	OrderTimeout    OrderTimeoutConfig    `yaml:"order_timeout"`
+	Dispatch        DispatchConfig        `yaml:"dispatch"`
+	Team            TeamConfig            `yaml:"team"`
+	Dispute         DisputeConfig         `yaml:"dispute"`
+	Storage         StorageConfig         `yaml:"storage"`
 }
 
 // Load 读取配置文件及环境变量，生成最终配置。
@@ -149,7 +272,7 @@ func Load() AppConfig {
 			SecretKey:    "GameLink2025SecretKey!@#",
 			IV:           "GameLink2025IV!!!",
 			Methods:      []string{"POST", "PUT", "PATCH"},
-			ExcludePaths: []string{"/api/v1/health", "/api/v1/ping", "/api/v1/auth/refresh"},
+			ExcludePaths: []string{"/api/v1/health", "/api/v1/ping", "/api/v1/auth/refresh", "/api/v1/payments/notify", "/api/v1/sandbox/pay"},
 			UseSignature: true,
 		},
 		Auth: AuthConfig{
@@ -166,6 +289,37 @@ func Load() AppConfig {
 		AdminAuth: AdminAuthConfig{
 			Mode: "admin", // 默认使用 AdminAuth，生产环境建议使用 jwt
 		},
+		FX: FXConfig{
+			RefreshInterval: "1h",
+		},
+		OrderTimeout: OrderTimeoutConfig{
+			PaymentTimeout: "30m",
+			AcceptTimeout:  "2h",
+			CompleteGrace:  "24h",
+			ConfirmWindow:  "24h",
+			Interval:       "1m",
+		},
+		Dispatch: DispatchConfig{
+			Mode:         "pool",
+			TopN:         3,
+			OfferTimeout: "2m",
+			Interval:     "30s",
+		},
+		Team: TeamConfig{
+			DispatchWindow: "10m",
+			Interval:       "1m",
+		},
+		Dispute: DisputeConfig{
+			SLA:           "30m",
+			EscalationSLA: "30m",
+			Interval:      "1m",
+		},
+		Storage: StorageConfig{
+			Driver:   "local",
+			LocalDir: "uploads",
+			URLTTL:   "15m",
+			S3:       S3Config{Region: "us-east-1"},
+		},
 	}
 
 	loadFromFile(env, &cfg)
@@ -182,6 +336,18 @@ func Load() AppConfig {
 		}
 	}
 
+	if len(cfg.FieldEncryption.Keys) == 0 && env != "production" {
+		cfg.FieldEncryption.ActiveKeyID = defaultDevFieldKeyID
+		cfg.FieldEncryption.Keys = map[string]string{defaultDevFieldKeyID: defaultDevFieldKey}
+	}
+	if cfg.FieldEncryption.BlindIndexKey == "" && env != "production" {
+		cfg.FieldEncryption.BlindIndexKey = defaultDevFieldBlindIndexKey
+	}
+
+	if cfg.Storage.SigningKey == "" && env != "production" {
+		cfg.Storage.SigningKey = defaultDevStorageSigningKey
+	}
+
 	if cfg.Auth.TokenTTLHours <= 0 {
 		cfg.Auth.TokenTTLHours = defaultTokenTTL
 	}
@@ -276,6 +442,160 @@ func loadFromFile(env string, cfg *AppConfig) {
 	if fc.AdminAuth.Mode != "" {
 		cfg.AdminAuth.Mode = fc.AdminAuth.Mode
 	}
+	mergeFieldEncryptionConfig(&cfg.FieldEncryption, fc.FieldEncryption)
+	mergePaymentConfig(&cfg.Payment, fc.Payment)
+	mergeFXConfig(&cfg.FX, fc.FX)
+	mergeOrderTimeoutConfig(&cfg.OrderTimeout, fc.OrderTimeout)
+	mergeDispatchConfig(&cfg.Dispatch, fc.Dispatch)
+	mergeTeamConfig(&cfg.Team, fc.Team)
+	mergeDisputeConfig(&cfg.Dispute, fc.Dispute)
+	mergeStorageConfig(&cfg.Storage, fc.Storage)
+}
+
+// mergeStorageConfig 以非空字段覆盖存储配置。
+func mergeStorageConfig(dst *StorageConfig, src StorageConfig) {
+	setIfNotEmpty := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	if src.Driver != "" {
+		dst.Driver = strings.ToLower(src.Driver)
+	}
+	setIfNotEmpty(&dst.LocalDir, src.LocalDir)
+	setIfNotEmpty(&dst.SigningKey, src.SigningKey)
+	setIfNotEmpty(&dst.URLTTL, src.URLTTL)
+	setIfNotEmpty(&dst.S3.Endpoint, src.S3.Endpoint)
+	setIfNotEmpty(&dst.S3.Region, src.S3.Region)
+	setIfNotEmpty(&dst.S3.Bucket, src.S3.Bucket)
+	setIfNotEmpty(&dst.S3.AccessKey, src.S3.AccessKey)
+	setIfNotEmpty(&dst.S3.SecretKey, src.S3.SecretKey)
+}
+
+// mergeDisputeConfig 以非空字段覆盖争议工作台配置。
+func mergeDisputeConfig(dst *DisputeConfig, src DisputeConfig) {
+	if src.SLA != "" {
+		dst.SLA = src.SLA
+	}
+	if src.EscalationSLA != "" {
+		dst.EscalationSLA = src.EscalationSLA
+	}
+	if src.Interval != "" {
+		dst.Interval = src.Interval
+	}
+}
+
+// mergeTeamConfig 以非空字段覆盖车队配置。
+func mergeTeamConfig(dst *TeamConfig, src TeamConfig) {
+	if src.DispatchWindow != "" {
+		dst.DispatchWindow = src.DispatchWindow
+	}
+	if src.Interval != "" {
+		dst.Interval = src.Interval
+	}
+}
+
+// mergeDispatchConfig 以非空字段覆盖派单配置。
+func mergeDispatchConfig(dst *DispatchConfig, src DispatchConfig) {
+	if src.Mode != "" {
+		dst.Mode = src.Mode
+	}
+	if src.TopN > 0 {
+		dst.TopN = src.TopN
+	}
+	if src.OfferTimeout != "" {
+		dst.OfferTimeout = src.OfferTimeout
+	}
+	if src.Interval != "" {
+		dst.Interval = src.Interval
+	}
+}
+
+// mergeOrderTimeoutConfig 以非空字段覆盖订单超时配置。
+func mergeOrderTimeoutConfig(dst *OrderTimeoutConfig, src OrderTimeoutConfig) {
+	if src.PaymentTimeout != "" {
+		dst.PaymentTimeout = src.PaymentTimeout
+	}
+	if src.AcceptTimeout != "" {
+		dst.AcceptTimeout = src.AcceptTimeout
+	}
+	if src.CompleteGrace != "" {
+		dst.CompleteGrace = src.CompleteGrace
+	}
+	if src.ConfirmWindow != "" {
+		dst.ConfirmWindow = src.ConfirmWindow
+	}
+	if src.Interval != "" {
+		dst.Interval = src.Interval
+	}
+}
+
+// mergeFXConfig 以非空字段覆盖汇率配置。
+func mergeFXConfig(dst *FXConfig, src FXConfig) {
+	if src.RatesFile != "" {
+		dst.RatesFile = src.RatesFile
+	}
+	if src.RefreshInterval != "" {
+		dst.RefreshInterval = src.RefreshInterval
+	}
+}
+
+// mergeFieldEncryptionConfig 以非空字段覆盖字段加密配置，密钥按 ID 合并。
+func mergeFieldEncryptionConfig(dst *FieldEncryptionConfig, src FieldEncryptionConfig) {
+	if src.ActiveKeyID != "" {
+		dst.ActiveKeyID = src.ActiveKeyID
+	}
+	for id, key := range src.Keys {
+		if key == "" {
+			continue
+		}
+		if dst.Keys == nil {
+			dst.Keys = make(map[string]string)
+		}
+		dst.Keys[id] = key
+	}
+	if src.BlindIndexKey != "" {
+		dst.BlindIndexKey = src.BlindIndexKey
+	}
+}
+
+// parseKeyList 解析 "id1:key1,id2:key2" 形式的密钥列表。
+func parseKeyList(v string) map[string]string {
+	keys := make(map[string]string)
+	for _, item := range strings.Split(v, ",") {
+		id, key, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || strings.TrimSpace(id) == "" {
+			continue
+		}
+		keys[strings.TrimSpace(id)] = strings.TrimSpace(key)
+	}
+	return keys
+}
+
+// mergePaymentConfig 以非空字段覆盖支付配置。
+func mergePaymentConfig(dst *PaymentConfig, src PaymentConfig) {
+	setIfNotEmpty := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	if src.Mode != "" {
+		dst.Mode = strings.ToLower(src.Mode)
+	}
+	setIfNotEmpty(&dst.NotifyBaseURL, src.NotifyBaseURL)
+	setIfNotEmpty(&dst.SandboxSecret, src.SandboxSecret)
+	setIfNotEmpty(&dst.WeChat.AppID, src.WeChat.AppID)
+	setIfNotEmpty(&dst.WeChat.MchID, src.WeChat.MchID)
+	setIfNotEmpty(&dst.WeChat.SerialNo, src.WeChat.SerialNo)
+	setIfNotEmpty(&dst.WeChat.PrivateKeyPath, src.WeChat.PrivateKeyPath)
+	setIfNotEmpty(&dst.WeChat.APIv3Key, src.WeChat.APIv3Key)
+	setIfNotEmpty(&dst.WeChat.PlatformCertPath, src.WeChat.PlatformCertPath)
+	setIfNotEmpty(&dst.WeChat.PlatformSerialNo, src.WeChat.PlatformSerialNo)
+	setIfNotEmpty(&dst.WeChat.BaseURL, src.WeChat.BaseURL)
+	setIfNotEmpty(&dst.Alipay.AppID, src.Alipay.AppID)
+	setIfNotEmpty(&dst.Alipay.PrivateKeyPath, src.Alipay.PrivateKeyPath)
+	setIfNotEmpty(&dst.Alipay.PublicKeyPath, src.Alipay.PublicKeyPath)
+	setIfNotEmpty(&dst.Alipay.GatewayURL, src.Alipay.GatewayURL)
 }
 
 func overrideFromEnv(cfg *AppConfig) {
@@ -343,6 +663,16 @@ func overrideFromEnv(cfg *AppConfig) {
 		}
 	}
 
+	// 字段加密密钥：FIELD_ENCRYPTION_KEYS="k2:base64,k1:base64"
+	fieldEnc := FieldEncryptionConfig{
+		ActiveKeyID:   os.Getenv("FIELD_ENCRYPTION_ACTIVE_KEY"),
+		BlindIndexKey: os.Getenv("FIELD_ENCRYPTION_BLIND_INDEX_KEY"),
+	}
+	if keys := os.Getenv("FIELD_ENCRYPTION_KEYS"); keys != "" {
+		fieldEnc.Keys = parseKeyList(keys)
+	}
+	mergeFieldEncryptionConfig(&cfg.FieldEncryption, fieldEnc)
+
 	if jwtSecret := os.Getenv("JWT_SECRET_KEY"); jwtSecret != "" {
 		cfg.Auth.JWTSecret = jwtSecret
 	}
@@ -380,6 +710,86 @@ func overrideFromEnv(cfg *AppConfig) {
 	if mode := os.Getenv("ADMIN_AUTH_MODE"); mode != "" {
 		cfg.AdminAuth.Mode = strings.ToLower(mode)
 	}
+
+	// 支付渠道
+	mergePaymentConfig(&cfg.Payment, PaymentConfig{
+		Mode:          os.Getenv("PAYMENT_MODE"),
+		NotifyBaseURL: os.Getenv("PAYMENT_NOTIFY_BASE_URL"),
+		SandboxSecret: os.Getenv("PAYMENT_SANDBOX_SECRET"),
+		WeChat: WeChatPayConfig{
+			AppID:            os.Getenv("WECHATPAY_APP_ID"),
+			MchID:            os.Getenv("WECHATPAY_MCH_ID"),
+			SerialNo:         os.Getenv("WECHATPAY_SERIAL_NO"),
+			PrivateKeyPath:   os.Getenv("WECHATPAY_PRIVATE_KEY_PATH"),
+			APIv3Key:         os.Getenv("WECHATPAY_API_V3_KEY"),
+			PlatformCertPath: os.Getenv("WECHATPAY_PLATFORM_CERT_PATH"),
+			PlatformSerialNo: os.Getenv("WECHATPAY_PLATFORM_SERIAL_NO"),
+			BaseURL:          os.Getenv("WECHATPAY_BASE_URL"),
+		},
+		Alipay: AlipayPayConfig{
+			AppID:          os.Getenv("ALIPAY_APP_ID"),
+			PrivateKeyPath: os.Getenv("ALIPAY_PRIVATE_KEY_PATH"),
+			PublicKeyPath:  os.Getenv("ALIPAY_PUBLIC_KEY_PATH"),
+			GatewayURL:     os.Getenv("ALIPAY_GATEWAY_URL"),
+		},
+	})
+
+	// 汇率数据源
+	mergeFXConfig(&cfg.FX, FXConfig{
+		RatesFile:       os.Getenv("FX_RATES_FILE"),
+		RefreshInterval: os.Getenv("FX_REFRESH_INTERVAL"),
+	})
+
+	// 订单超时
+	mergeOrderTimeoutConfig(&cfg.OrderTimeout, OrderTimeoutConfig{
+		PaymentTimeout: os.Getenv("ORDER_PAYMENT_TIMEOUT"),
+		AcceptTimeout:  os.Getenv("ORDER_ACCEPT_TIMEOUT"),
+		CompleteGrace:  os.Getenv("ORDER_COMPLETE_GRACE"),
+		ConfirmWindow:  os.Getenv("ORDER_CONFIRM_WINDOW"),
+		Interval:       os.Getenv("ORDER_TIMEOUT_INTERVAL"),
+	})
+
+	// 智能派单
+	mergeDispatchConfig(&cfg.Dispatch, DispatchConfig{
+		Mode:         os.Getenv("DISPATCH_MODE"),
+		OfferTimeout: os.Getenv("DISPATCH_OFFER_TIMEOUT"),
+		Interval:     os.Getenv("DISPATCH_INTERVAL"),
+	})
+	if topN := os.Getenv("DISPATCH_TOP_N"); topN != "" {
+		if n, err := strconv.Atoi(topN); err != nil {
+			log.Printf("DISPATCH_TOP_N=%q 无法解析，保持原值 %d", topN, cfg.Dispatch.TopN)
+		} else {
+			cfg.Dispatch.TopN = n
+		}
+	}
+
+	// 车队抢单
+	mergeTeamConfig(&cfg.Team, TeamConfig{
+		DispatchWindow: os.Getenv("TEAM_DISPATCH_WINDOW"),
+		Interval:       os.Getenv("TEAM_RELEASE_INTERVAL"),
+	})
+
+	// 客服争议工作台
+	mergeDisputeConfig(&cfg.Dispute, DisputeConfig{
+		SLA:           os.Getenv("DISPUTE_SLA"),
+		EscalationSLA: os.Getenv("DISPUTE_ESCALATION_SLA"),
+		Interval:      os.Getenv("DISPUTE_SLA_INTERVAL"),
+	})
+
+	// 上传文件存储
+	mergeStorageConfig(&cfg.Storage, StorageConfig{
+		Driver:     os.Getenv("STORAGE_DRIVER"),
+		LocalDir:   os.Getenv("STORAGE_LOCAL_DIR"),
+		SigningKey: os.Getenv("STORAGE_SIGNING_KEY"),
+		URLTTL:     os.Getenv("STORAGE_URL_TTL"),
+		S3: S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		},
+	})
 }
 
 func normalizeHTTPMethods(methods []string) []string {
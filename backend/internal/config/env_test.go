@@ -22,9 +22,54 @@ func TestValidateProductionRequiresDSN(t *testing.T) {
 		t.Fatal("expected validation error when DSN missing")
 	}
 	cfg.Database.DSN = "postgres://example"
+	if err := Validate("production", cfg); err == nil {
+		t.Fatal("expected validation error when field encryption keys missing")
+	}
+	cfg.FieldEncryption = FieldEncryptionConfig{ActiveKeyID: "k1", Keys: map[string]string{"k1": "key"}, BlindIndexKey: "index"}
+	if err := Validate("production", cfg); err == nil {
+		t.Fatal("expected validation error when storage signing key missing")
+	}
+	cfg.Storage.SigningKey = "sign"
+	if err := Validate("production", cfg); err == nil {
+		t.Fatal("expected validation error when payment mode is not live")
+	}
+	cfg.Payment = PaymentConfig{
+		Mode:          PaymentModeLive,
+		NotifyBaseURL: "https://api.example.com/api/v1/payments/notify",
+		Alipay:        AlipayPayConfig{AppID: "2021000000000001", PrivateKeyPath: "/etc/alipay/key.pem", PublicKeyPath: "/etc/alipay/public.pem"},
+	}
 	if err := Validate("production", cfg); err != nil {
 		t.Fatalf("unexpected validation error: %v", err)
 	}
+	cfg.FieldEncryption.ActiveKeyID = "k2"
+	if err := Validate("production", cfg); err == nil {
+		t.Fatal("expected validation error when active key is not configured")
+	}
+}
+
+func TestFieldEncryptionFromEnv(t *testing.T) {
+	t.Setenv("FIELD_ENCRYPTION_KEYS", "k2:bmV3, k1:b2xk")
+	t.Setenv("FIELD_ENCRYPTION_ACTIVE_KEY", "k2")
+	t.Setenv("FIELD_ENCRYPTION_BLIND_INDEX_KEY", "aW5kZXg=")
+
+	cfg := AppConfig{FieldEncryption: FieldEncryptionConfig{ActiveKeyID: "k0", Keys: map[string]string{"k0": "b3JpZ2lu"}}}
+	overrideFromEnv(&cfg)
+	fe := cfg.FieldEncryption
+	if fe.ActiveKeyID != "k2" || fe.BlindIndexKey != "aW5kZXg=" {
+		t.Fatalf("unexpected field encryption config: %+v", fe)
+	}
+	if len(fe.Keys) != 3 || fe.Keys["k1"] != "b2xk" || fe.Keys["k2"] != "bmV3" || fe.Keys["k0"] != "b3JpZ2lu" {
+		t.Fatalf("keys not merged: %+v", fe.Keys)
+	}
+
+	t.Setenv("APP_ENV", "unit-test")
+	t.Setenv("FIELD_ENCRYPTION_KEYS", "")
+	t.Setenv("FIELD_ENCRYPTION_ACTIVE_KEY", "")
+	t.Setenv("FIELD_ENCRYPTION_BLIND_INDEX_KEY", "")
+	dev := Load().FieldEncryption
+	if dev.Keys[dev.ActiveKeyID] == "" || dev.BlindIndexKey == "" {
+		t.Fatalf("expected development fallback keys, got %+v", dev)
+	}
 }
 
 func TestValidateCrypto(t *testing.T) {
@@ -422,3 +467,155 @@ func TestOverrideFromEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePayment(t *testing.T) {
+	live := func(mutate func(*PaymentConfig)) AppConfig {
+		cfg := AppConfig{Payment: PaymentConfig{
+			Mode:          PaymentModeLive,
+			NotifyBaseURL: "https://api.example.com/api/v1/payments/notify",
+			WeChat: WeChatPayConfig{
+				MchID:            "1900000001",
+				SerialNo:         "SERIAL",
+				PrivateKeyPath:   "/etc/wechatpay/key.pem",
+				APIv3Key:         "0123456789abcdef0123456789abcdef",
+				PlatformCertPath: "/etc/wechatpay/platform.pem",
+			},
+		}}
+		if mutate != nil {
+			mutate(&cfg.Payment)
+		}
+		return cfg
+	}
+
+	if err := Validate("development", AppConfig{Payment: PaymentConfig{Mode: PaymentModeSandbox}}); err != nil {
+		t.Fatalf("sandbox mode should be valid: %v", err)
+	}
+	if err := Validate("development", live(nil)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Validate("development", live(func(p *PaymentConfig) { p.NotifyBaseURL = "" })); err == nil {
+		t.Fatal("expected error when notify base url missing")
+	}
+	if err := Validate("development", live(func(p *PaymentConfig) { p.WeChat.APIv3Key = "short" })); err == nil {
+		t.Fatal("expected error for invalid api v3 key")
+	}
+	if err := Validate("development", live(func(p *PaymentConfig) { p.Alipay.AppID = "2021000000000001" })); err == nil {
+		t.Fatal("expected error when alipay keys missing")
+	}
+	if err := Validate("development", live(func(p *PaymentConfig) { p.WeChat = WeChatPayConfig{} })); err == nil {
+		t.Fatal("expected error when no gateway configured in live mode")
+	}
+	if err := Validate("development", AppConfig{Payment: PaymentConfig{Mode: "mock"}}); err == nil {
+		t.Fatal("expected error for unknown payment mode")
+	}
+}
+
+func TestOverrideFromEnvPayment(t *testing.T) {
+	t.Setenv("PAYMENT_MODE", "LIVE")
+	t.Setenv("PAYMENT_NOTIFY_BASE_URL", "https://api.example.com/api/v1/payments/notify")
+	t.Setenv("WECHATPAY_MCH_ID", "1900000001")
+	t.Setenv("ALIPAY_APP_ID", "2021000000000001")
+
+	cfg := AppConfig{Payment: PaymentConfig{Mode: PaymentModeSandbox, SandboxSecret: "keep"}}
+	overrideFromEnv(&cfg)
+	if cfg.Payment.Mode != PaymentModeLive {
+		t.Fatalf("expected live mode, got %q", cfg.Payment.Mode)
+	}
+	if cfg.Payment.WeChat.MchID != "1900000001" || cfg.Payment.Alipay.AppID != "2021000000000001" {
+		t.Fatalf("channel credentials not loaded: %+v", cfg.Payment)
+	}
+	if cfg.Payment.SandboxSecret != "keep" {
+		t.Fatalf("unset env must not clear existing values")
+	}
+}
+
+func TestOrderTimeoutConfig(t *testing.T) {
+	t.Setenv("ORDER_ACCEPT_TIMEOUT", "45m")
+
+	cfg := AppConfig{OrderTimeout: OrderTimeoutConfig{PaymentTimeout: "30m", AcceptTimeout: "2h"}}
+	overrideFromEnv(&cfg)
+	if cfg.OrderTimeout.AcceptTimeout != "45m" || cfg.OrderTimeout.PaymentTimeout != "30m" {
+		t.Fatalf("unexpected order timeout config: %+v", cfg.OrderTimeout)
+	}
+	if err := Validate("development", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.OrderTimeout.CompleteGrace = "one day"
+	if err := Validate("development", cfg); err == nil {
+		t.Fatal("expected error for invalid duration")
+	}
+}
+
+func TestDispatchConfig(t *testing.T) {
+	t.Setenv("DISPATCH_MODE", "push")
+	t.Setenv("DISPATCH_TOP_N", "5")
+
+	cfg := AppConfig{Dispatch: DispatchConfig{Mode: "pool", TopN: 3, OfferTimeout: "2m"}}
+	overrideFromEnv(&cfg)
+	if cfg.Dispatch.Mode != "push" || cfg.Dispatch.TopN != 5 || cfg.Dispatch.OfferTimeout != "2m" {
+		t.Fatalf("unexpected dispatch config: %+v", cfg.Dispatch)
+	}
+	if err := Validate("development", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Dispatch.Mode = "random"
+	if err := Validate("development", cfg); err == nil {
+		t.Fatal("expected error for unknown dispatch mode")
+	}
+}
+
+func TestTeamConfig(t *testing.T) {
+	t.Setenv("TEAM_DISPATCH_WINDOW", "15m")
+
+	cfg := AppConfig{Team: TeamConfig{DispatchWindow: "10m", Interval: "1m"}}
+	overrideFromEnv(&cfg)
+	if cfg.Team.DispatchWindow != "15m" || cfg.Team.Interval != "1m" {
+		t.Fatalf("unexpected team config: %+v", cfg.Team)
+	}
+	if err := Validate("development", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Team.DispatchWindow = "soon"
+	if err := Validate("development", cfg); err == nil {
+		t.Fatal("expected error for invalid team dispatch window")
+	}
+}
+
+func TestDisputeConfig(t *testing.T) {
+	t.Setenv("DISPUTE_ESCALATION_SLA", "1h")
+
+	cfg := AppConfig{Dispute: DisputeConfig{SLA: "30m", EscalationSLA: "30m", Interval: "1m"}}
+	overrideFromEnv(&cfg)
+	if cfg.Dispute.SLA != "30m" || cfg.Dispute.EscalationSLA != "1h" || cfg.Dispute.Interval != "1m" {
+		t.Fatalf("unexpected dispute config: %+v", cfg.Dispute)
+	}
+	if err := Validate("development", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Dispute.SLA = "-5m"
+	if err := Validate("development", cfg); err == nil {
+		t.Fatal("expected error for non-positive dispute sla")
+	}
+}
+
+func TestStorageConfig(t *testing.T) {
+	t.Setenv("STORAGE_DRIVER", "S3")
+	t.Setenv("S3_BUCKET", "evidence")
+
+	cfg := AppConfig{Storage: StorageConfig{Driver: "local", LocalDir: "uploads", URLTTL: "15m"}}
+	overrideFromEnv(&cfg)
+	if cfg.Storage.Driver != "s3" || cfg.Storage.S3.Bucket != "evidence" || cfg.Storage.LocalDir != "uploads" {
+		t.Fatalf("unexpected storage config: %+v", cfg.Storage)
+	}
+	if err := Validate("development", cfg); err == nil {
+		t.Fatal("expected error for incomplete s3 config")
+	}
+	cfg.Storage.S3 = S3Config{Endpoint: "http://127.0.0.1:9000", Bucket: "evidence", AccessKey: "ak", SecretKey: "sk"}
+	if err := Validate("development", cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Storage.URLTTL = "0s"
+	if err := Validate("development", cfg); err == nil {
+		t.Fatal("expected error for non-positive url ttl")
+	}
+}
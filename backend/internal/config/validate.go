@@ -1,6 +1,10 @@
 package config
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Validate checks configuration for required values in production.
 func Validate(env string, cfg AppConfig) error {
@@ -8,6 +12,21 @@ func Validate(env string, cfg AppConfig) error {
 		if cfg.Database.DSN == "" {
 			return errors.New("DB_DSN is required in production")
 		}
+		fe := cfg.FieldEncryption
+		if len(fe.Keys) == 0 || fe.BlindIndexKey == "" {
+			return errors.New("FIELD_ENCRYPTION_KEYS and FIELD_ENCRYPTION_BLIND_INDEX_KEY are required in production")
+		}
+		if cfg.Storage.SigningKey == "" {
+			return errors.New("STORAGE_SIGNING_KEY is required in production")
+		}
+		if cfg.Payment.Mode != PaymentModeLive {
+			return errors.New("PAYMENT_MODE must be live in production")
+		}
+	}
+	if fe := cfg.FieldEncryption; len(fe.Keys) > 0 {
+		if _, ok := fe.Keys[fe.ActiveKeyID]; !ok {
+			return errors.New("FIELD_ENCRYPTION_ACTIVE_KEY must reference one of FIELD_ENCRYPTION_KEYS")
+		}
 	}
 	if cfg.Crypto.Enabled {
 		keyLen := len(cfg.Crypto.SecretKey)
@@ -21,5 +40,75 @@ func Validate(env string, cfg AppConfig) error {
 			return errors.New("crypto methods must not be empty when encryption is enabled")
 		}
 	}
+	ot := cfg.OrderTimeout
+	for name, v := range map[string]string{
+		"ORDER_PAYMENT_TIMEOUT":  ot.PaymentTimeout,
+		"ORDER_ACCEPT_TIMEOUT":   ot.AcceptTimeout,
+		"ORDER_COMPLETE_GRACE":   ot.CompleteGrace,
+		"ORDER_CONFIRM_WINDOW":   ot.ConfirmWindow,
+		"ORDER_TIMEOUT_INTERVAL": ot.Interval,
+	} {
+		if d, err := time.ParseDuration(v); v != "" && (err != nil || d < 0) {
+			return fmt.Errorf("%s must be a non-negative duration such as 30m", name)
+		}
+	}
+	dp := cfg.Dispatch
+	switch dp.Mode {
+	case "", "pool", "push", "auto":
+	default:
+		return errors.New("DISPATCH_MODE must be pool, push or auto")
+	}
+	if dp.TopN < 0 {
+		return errors.New("DISPATCH_TOP_N must not be negative")
+	}
+	for name, v := range map[string]string{
+		"DISPATCH_OFFER_TIMEOUT": dp.OfferTimeout,
+		"DISPATCH_INTERVAL":      dp.Interval,
+	} {
+		if d, err := time.ParseDuration(v); v != "" && (err != nil || d <= 0) {
+			return fmt.Errorf("%s must be a positive duration such as 2m", name)
+		}
+	}
+	for name, v := range map[string]string{
+		"TEAM_DISPATCH_WINDOW":   cfg.Team.DispatchWindow,
+		"TEAM_RELEASE_INTERVAL":  cfg.Team.Interval,
+		"DISPUTE_SLA":            cfg.Dispute.SLA,
+		"DISPUTE_ESCALATION_SLA": cfg.Dispute.EscalationSLA,
+		"DISPUTE_SLA_INTERVAL":   cfg.Dispute.Interval,
+		"STORAGE_URL_TTL":        cfg.Storage.URLTTL,
+	} {
+		if d, err := time.ParseDuration(v); v != "" && (err != nil || d <= 0) {
+			return fmt.Errorf("%s must be a positive duration such as 10m", name)
+		}
+	}
+	switch st := cfg.Storage; st.Driver {
+	case "", "local":
+	case "s3":
+		if st.S3.Endpoint == "" || st.S3.Bucket == "" || st.S3.AccessKey == "" || st.S3.SecretKey == "" {
+			return errors.New("s3 storage requires S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY")
+		}
+	default:
+		return errors.New("STORAGE_DRIVER must be local or s3")
+	}
+	switch cfg.Payment.Mode {
+	case "", PaymentModeSandbox:
+	case PaymentModeLive:
+		if cfg.Payment.NotifyBaseURL == "" {
+			return errors.New("PAYMENT_NOTIFY_BASE_URL is required in live payment mode")
+		}
+		wx := cfg.Payment.WeChat
+		if wx.MchID != "" && (wx.SerialNo == "" || wx.PrivateKeyPath == "" || len(wx.APIv3Key) != 32 || wx.PlatformCertPath == "") {
+			return errors.New("wechat pay requires serial_no, private_key_path, 32-byte api_v3_key and platform_cert_path")
+		}
+		ali := cfg.Payment.Alipay
+		if ali.AppID != "" && (ali.PrivateKeyPath == "" || ali.PublicKeyPath == "") {
+			return errors.New("alipay requires private_key_path and public_key_path")
+		}
+		if wx.MchID == "" && ali.AppID == "" {
+			return errors.New("live payment mode requires wechat pay or alipay to be configured")
+		}
+	default:
+		return errors.New("PAYMENT_MODE must be sandbox or live")
+	}
 	return nil
 }
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/pkg/fieldcrypt"
+)
+
+const defaultReencryptBatch = 200
+
+// ReencryptStats 重加密结果
+type ReencryptStats struct {
+	Users     int
+	Withdraws int
+}
+
+// ReencryptFields 以当前主密钥重新加密用户手机号、邮箱与提现收款账户，并补齐盲索引。
+// 已使用当前主密钥的行会被跳过，可重复执行；主密钥轮换后运行，完成后即可下线旧密钥。
+func ReencryptFields(db *gorm.DB, batchSize int) (ReencryptStats, error) {
+	k := fieldcrypt.Default()
+	if k == nil {
+		return ReencryptStats{}, fieldcrypt.ErrNoKeyring
+	}
+	return encryptFields(db, fieldcrypt.Prefix+k.ActiveKeyID()+":", batchSize)
+}
+
+// encryptFields 重写值不以 keep 开头的加密字段，并补齐缺失的盲索引。
+// keep 为 fieldcrypt.Prefix 时只加密历史明文；未配置密钥环时只补盲索引。
+func encryptFields(db *gorm.DB, keep string, batchSize int) (ReencryptStats, error) {
+	if fieldcrypt.Default() == nil {
+		keep = ""
+	}
+	if batchSize <= 0 {
+		batchSize = defaultReencryptBatch
+	}
+	var stats ReencryptStats
+	var err error
+	if stats.Users, err = reencryptUsers(db, keep, batchSize); err != nil {
+		return stats, err
+	}
+	if keep == "" {
+		return stats, nil
+	}
+	stats.Withdraws, err = reencryptWithdraws(db, keep, batchSize)
+	return stats, err
+}
+
+func reencryptUsers(db *gorm.DB, keep string, batchSize int) (int, error) {
+	cond := "(phone <> '' AND phone_bidx IS NULL) OR (email <> '' AND email_bidx IS NULL)"
+	var args []any
+	if keep != "" {
+		cond += " OR (phone <> '' AND phone NOT LIKE ?) OR (email <> '' AND email NOT LIKE ?)"
+		args = append(args, keep+"%", keep+"%")
+	}
+	cond = "(" + cond + ")"
+
+	updated := 0
+	var lastID uint64
+	for {
+		var users []model.User
+		err := db.Unscoped().Where("id > ?", lastID).Where(cond, args...).
+			Order("id ASC").Limit(batchSize).Find(&users).Error
+		if err != nil {
+			return updated, err
+		}
+		if len(users) == 0 {
+			return updated, nil
+		}
+		for i := range users {
+			u := &users[i]
+			lastID = u.ID
+			u.PhoneIndex = model.UserPhoneIndex(u.Phone)
+			u.EmailIndex = model.UserEmailIndex(u.Email)
+			err := db.Unscoped().Model(u).
+				Select("phone", "email", "phone_bidx", "email_bidx").
+				UpdateColumns(u).Error
+			if err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
+
+func reencryptWithdraws(db *gorm.DB, keep string, batchSize int) (int, error) {
+	updated := 0
+	var lastID uint64
+	for {
+		var withdraws []model.Withdraw
+		err := db.Where("id > ? AND account_info <> '' AND account_info NOT LIKE ?", lastID, keep+"%").
+			Order("id ASC").Limit(batchSize).Find(&withdraws).Error
+		if err != nil {
+			return updated, err
+		}
+		if len(withdraws) == 0 {
+			return updated, nil
+		}
+		for i := range withdraws {
+			w := &withdraws[i]
+			lastID = w.ID
+			if err := db.Model(w).Select("account_info").UpdateColumns(w).Error; err != nil {
+				return updated, err
+			}
+			updated++
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+	"gamelink/internal/pkg/fieldcrypt"
+	userrepo "gamelink/internal/repository/user"
+)
+
+func keyring(t *testing.T, active string, ids ...string) *fieldcrypt.Keyring {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte{id[len(id)-1]}, 32)
+	}
+	k, err := fieldcrypt.NewKeyring(active, keys, []byte("blind-index-key-0123"))
+	require.NoError(t, err)
+	return k
+}
+
+func TestEncryptFields_LegacyPlaintextAndRotation(t *testing.T) {
+	db := newMemDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Withdraw{}))
+	t.Cleanup(func() { fieldcrypt.SetDefault(nil) })
+	fieldcrypt.SetDefault(keyring(t, "k1", "k1"))
+
+	// 加密上线前的明文数据：没有盲索引
+	require.NoError(t, db.Exec("INSERT INTO users (id, phone, email, name, created_at, updated_at) VALUES (1, '13800000001', 'Alice@Example.com', 'alice', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)").Error)
+	require.NoError(t, db.Exec("INSERT INTO users (id, phone, email, name, created_at, updated_at) VALUES (2, '', 'bob@example.com', 'bob', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)").Error)
+	require.NoError(t, db.Exec("INSERT INTO withdraws (id, player_id, user_id, amount_cents, method, account_info, status) VALUES (1, 1, 1, 100, 'alipay', 'alice@alipay', 'pending')").Error)
+
+	stats, err := encryptFields(db, fieldcrypt.Prefix, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ReencryptStats{Users: 2, Withdraws: 1}, stats)
+
+	rawColumn := func(query string) string {
+		var v string
+		require.NoError(t, db.Raw(query).Scan(&v).Error)
+		return v
+	}
+	assert.True(t, strings.HasPrefix(rawColumn("SELECT phone FROM users WHERE id = 1"), fieldcrypt.Prefix+"k1:"))
+	assert.Equal(t, "", rawColumn("SELECT phone FROM users WHERE id = 2"))
+	assert.True(t, strings.HasPrefix(rawColumn("SELECT account_info FROM withdraws WHERE id = 1"), fieldcrypt.Prefix+"k1:"))
+
+	// 再次执行不会重写已加密的行
+	stats, err = encryptFields(db, fieldcrypt.Prefix, 10)
+	require.NoError(t, err)
+	assert.Equal(t, ReencryptStats{}, stats)
+
+	repo := userrepo.NewUserRepository(db)
+	u, err := repo.FindByEmail(context.Background(), "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), u.ID)
+	assert.Equal(t, "Alice@Example.com", u.Email)
+	u, err = repo.FindByPhone(context.Background(), "13800000001")
+	require.NoError(t, err)
+	assert.Equal(t, "13800000001", u.Phone)
+
+	// 轮换主密钥：旧密文仍可读，重加密后全部使用新密钥
+	fieldcrypt.SetDefault(keyring(t, "k2", "k1", "k2"))
+	stats, err = ReencryptFields(db, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ReencryptStats{Users: 2, Withdraws: 1}, stats)
+	assert.True(t, strings.HasPrefix(rawColumn("SELECT email FROM users WHERE id = 2"), fieldcrypt.Prefix+"k2:"))
+	assert.True(t, strings.HasPrefix(rawColumn("SELECT account_info FROM withdraws WHERE id = 1"), fieldcrypt.Prefix+"k2:"))
+
+	// 下线旧密钥后数据依旧可读、可查
+	fieldcrypt.SetDefault(keyring(t, "k2", "k2"))
+	var w model.Withdraw
+	require.NoError(t, db.First(&w, 1).Error)
+	assert.Equal(t, "alice@alipay", w.AccountInfo)
+	u, err = repo.FindByEmail(context.Background(), "bob@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", u.Name)
+}
+
+func TestUserBlindIndexUniqueness(t *testing.T) {
+	db := newMemDB(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}))
+	t.Cleanup(func() { fieldcrypt.SetDefault(nil) })
+	fieldcrypt.SetDefault(keyring(t, "k1", "k1"))
+
+	// 未填写手机号的用户盲索引为 NULL，不触发唯一约束
+	require.NoError(t, db.Create(&model.User{Email: "a@example.com", Name: "a"}).Error)
+	require.NoError(t, db.Create(&model.User{Email: "b@example.com", Name: "b"}).Error)
+	// 密文每次不同，重复邮箱由盲索引拦截
+	assert.Error(t, db.Create(&model.User{Email: "A@example.com", Name: "dup"}).Error)
+
+	repo := userrepo.NewUserRepository(db)
+	u, err := repo.FindByEmail(context.Background(), "a@example.com")
+	require.NoError(t, err)
+	u.Phone = "13900000000"
+	require.NoError(t, repo.Update(context.Background(), u))
+	got, err := repo.FindByPhone(context.Background(), "13900000000")
+	require.NoError(t, err)
+	assert.Equal(t, u.ID, got.ID)
+}
@@ -13,6 +13,7 @@ import (
 
 	"gamelink/internal/config"
 	"gamelink/internal/model"
+	"gamelink/internal/pkg/fieldcrypt"
 )
 
 // prepareOrdersMigration 在 autoMigrate 之前处理 orders 表的字段迁移
@@ -110,23 +111,61 @@ func autoMigrate(db *gorm.DB) error {
 		return err
 	}
 
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&model.Game{},
 		&model.Player{},
 		&model.PlayerGame{},
 		&model.PlayerSkillTag{},
+		&model.PlayerAvailabilitySlot{},
+		&model.PlayerBlackout{},
 		&model.User{},
 		&model.Order{},
+		&model.OrderStatusHistory{},
+		&model.DispatchOffer{},
+		&model.OrderAssignment{},
+		&model.Team{},
+		&model.TeamMember{},
+		&model.TeamOrderAssignment{},
+		&model.TeamAssignmentMember{},
+		&model.TeamPayoutPlan{},
+		&model.CouponTemplate{},
+		&model.UserCoupon{},
+		&model.OrderExtension{},
+		&model.OrderDispute{},
+		&model.DisputeAgent{},
 		&model.Payment{},
+		&model.PaymentCallback{},
+		&model.Refund{},
 		&model.Review{},
 		&model.Withdraw{},
+		&model.WithdrawBatch{},
 		&model.OperationLog{},
 		// Service Item (统一管理护航服务和礼物)
 		&model.ServiceItem{},
+		&model.ServiceItemPrice{},
+		&model.ExchangeRate{},
 		// Commission models
 		&model.CommissionRule{},
 		&model.CommissionRecord{},
 		&model.MonthlySettlement{},
+		&model.CommissionAdjustment{},
+		// Credit models
+		&model.PlayerCredit{},
+		&model.CreditEvent{},
+		&model.CreditRule{},
+		// Wallet models
+		&model.PlayerWallet{},
+		&model.WalletTransaction{},
+		// Ledger models
+		&model.FinancialAccount{},
+		&model.FinancialVoucher{},
+		&model.FinancialVoucherEntry{},
+		&model.FinancialTransaction{},
+		&model.FinancialReport{},
+		&model.FinancialPeriod{},
+		// Reconciliation models
+		&model.Reconciliation{},
+		&model.ReconciliationDetail{},
 		// Ranking models
 		&model.PlayerRanking{},
 		&model.RankingCommissionConfig{},
@@ -147,7 +186,23 @@ func autoMigrate(db *gorm.DB) error {
 		&model.FeedReport{},
 		&model.NotificationEvent{},
 		&model.ReviewReply{},
-	)
+	); err != nil {
+		return err
+	}
+	return dropLegacyUserIndexes(db)
+}
+
+// dropLegacyUserIndexes 手机号、邮箱改为加密存储后，唯一约束改由盲索引列承担
+func dropLegacyUserIndexes(db *gorm.DB) error {
+	m := db.Migrator()
+	for _, name := range []string{"idx_users_phone", "idx_users_email"} {
+		if m.HasIndex(&model.User{}, name) {
+			if err := m.DropIndex(&model.User{}, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // runDataFixups contains data migrations that adjust existing values.
@@ -168,6 +223,14 @@ func runDataFixups(db *gorm.DB) error {
 	if err := db.Exec("UPDATE players SET rating_count = 0 WHERE rating_count < 0").Error; err != nil {
 		return err
 	}
+	// Backfill refund records for orders refunded before the refunds table existed
+	if err := backfillLegacyRefunds(db); err != nil {
+		return err
+	}
+	// Open wallets for players with settled income from before wallets existed
+	if err := backfillPlayerWallets(db); err != nil {
+		return err
+	}
 	// Ensure RBAC default roles exist
 	if err := ensureDefaultRoles(db); err != nil {
 		return err
@@ -176,6 +239,14 @@ func runDataFixups(db *gorm.DB) error {
 	if err := ensureDefaultCommissionRule(db); err != nil {
 		return err
 	}
+	// Ensure system ledger accounts exist
+	if err := ensureSystemFinancialAccounts(db); err != nil {
+		return err
+	}
+	// Encrypt plaintext PII left from before field encryption and backfill blind indexes
+	if _, err := encryptFields(db, fieldcrypt.Prefix, defaultReencryptBatch); err != nil {
+		return err
+	}
 	return ensureSuperAdmin(db)
 }
 
@@ -208,6 +279,13 @@ func ensureIndexes(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_oplogs_entity ON operation_logs (entity_type, entity_id, created_at DESC)",
 		"CREATE INDEX IF NOT EXISTS idx_oplogs_actor ON operation_logs (actor_user_id, created_at DESC)",
 		"CREATE INDEX IF NOT EXISTS idx_oplogs_action ON operation_logs (action, created_at DESC)",
+		// Team order indexes
+		"CREATE INDEX IF NOT EXISTS idx_orders_queue_team ON orders (queue_type, assigned_team_id, status)",
+		"CREATE INDEX IF NOT EXISTS idx_team_members_team_status ON team_members (team_id, status)",
+		"CREATE INDEX IF NOT EXISTS idx_team_assignments_status_deadline ON team_order_assignments (status, dispatch_deadline)",
+		"CREATE INDEX IF NOT EXISTS idx_team_assignment_members_state ON team_assignment_members (assignment_id, state)",
+		"CREATE INDEX IF NOT EXISTS idx_user_coupons_user_status ON user_coupons (user_id, status)",
+		"CREATE INDEX IF NOT EXISTS idx_user_coupons_template_user ON user_coupons (template_id, user_id)",
 	}
 	for _, s := range stmts {
 		if err := db.Exec(s).Error; err != nil {
@@ -307,9 +385,9 @@ func ensureSuperAdmin(db *gorm.DB) error {
 
 	lookup := db.Model(&model.User{})
 	if email != "" {
-		lookup = lookup.Where("email = ?", email)
+		lookup = lookup.Where("email_bidx = ?", model.UserEmailIndex(email))
 	} else {
-		lookup = lookup.Where("phone = ?", phone)
+		lookup = lookup.Where("phone_bidx = ?", model.UserPhoneIndex(phone))
 	}
 
 	var existing model.User
@@ -396,7 +474,138 @@ func generateOrderNumbers(db *gorm.DB) error {
 	return nil
 }
 
-// ensureDefaultCommissionRule 确保默认抽成规则存在
+// backfillLegacyRefunds 为仅在订单上记录了退款金额的历史订单补建退款单，
+// 使退款查询与可退余额统一基于 refunds 表计算。
+func backfillLegacyRefunds(db *gorm.DB) error {
+	var orders []model.Order
+	err := db.Where("refund_amount_cents > 0 AND id NOT IN (?)", db.Model(&model.Refund{}).Select("order_id")).
+		Find(&orders).Error
+	if err != nil {
+		return err
+	}
+	for i := range orders {
+		o := &orders[i]
+		refundedAt := o.RefundedAt
+		if refundedAt == nil {
+			refundedAt = &o.UpdatedAt
+		}
+		refund := &model.Refund{
+			OrderID:     o.ID,
+			AmountCents: o.RefundAmountCents,
+			Currency:    o.Currency,
+			Reason:      o.RefundReason,
+			Source:      model.RefundSourceSystem,
+			Status:      model.RefundStatusSucceeded,
+			OutRefundNo: fmt.Sprintf("RFLEGACY%08d", o.ID),
+			RefundedAt:  refundedAt,
+		}
+		var payment model.Payment
+		if err := db.Where("order_id = ? AND status = ?", o.ID, model.PaymentStatusRefunded).Order("id DESC").First(&payment).Error; err == nil {
+			refund.PaymentID = &payment.ID
+			refund.Method = payment.Method
+		}
+		if err := db.Create(refund).Error; err != nil {
+			log.Printf("warning: failed to backfill refund for order %d: %v", o.ID, err)
+		}
+	}
+	return nil
+}
+
+// backfillPlayerWallets 为钱包上线前已有月结收入的陪玩师开户：
+// 可提现 = 已月结收入 − 已完成及处理中的提现，处理中的提现补记冻结流水，之后照常解冻或扣减。
+// 未月结的抽成记录在月结时补记入账；已有钱包的陪玩师不再处理。
+func backfillPlayerWallets(db *gorm.DB) error {
+	var incomes []struct {
+		PlayerID uint64
+		Currency model.Currency
+		Cents    int64
+	}
+	err := db.Model(&model.CommissionRecord{}).
+		Select("player_id, currency, SUM(player_income_cents) AS cents").
+		Where("settlement_status = ?", "settled").
+		Where("NOT EXISTS (SELECT 1 FROM player_wallets WHERE player_wallets.player_id = commission_records.player_id AND player_wallets.currency = commission_records.currency)").
+		Group("player_id, currency").
+		Having("SUM(player_income_cents) > 0").
+		Scan(&incomes).Error
+	if err != nil {
+		return err
+	}
+	for _, in := range incomes {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return openLegacyWallet(tx, in.PlayerID, in.Currency, in.Cents)
+		})
+		if err != nil {
+			log.Printf("warning: failed to backfill wallet for player %d (%s): %v", in.PlayerID, in.Currency, err)
+		}
+	}
+	return nil
+}
+
+func openLegacyWallet(tx *gorm.DB, playerID uint64, currency model.Currency, settledCents int64) error {
+	var withdraws []model.Withdraw
+	err := tx.Select("id, amount_cents, status").
+		Where("player_id = ? AND currency = ? AND status IN ?", playerID, currency, []model.WithdrawStatus{
+			model.WithdrawStatusPending, model.WithdrawStatusApproved, model.WithdrawStatusProcessing, model.WithdrawStatusCompleted,
+		}).
+		Order("id").Find(&withdraws).Error
+	if err != nil {
+		return err
+	}
+	opening := settledCents
+	wallet := &model.PlayerWallet{PlayerID: playerID, Currency: currency, TotalIncomeCents: settledCents}
+	for _, w := range withdraws {
+		if w.Status == model.WithdrawStatusCompleted {
+			opening -= w.AmountCents
+			wallet.TotalWithdrawnCents += w.AmountCents
+		} else {
+			wallet.FrozenCents += w.AmountCents
+		}
+	}
+	wallet.AvailableCents = opening - wallet.FrozenCents
+	if wallet.AvailableCents < 0 {
+		return fmt.Errorf("withdrawals exceed settled income %d", settledCents)
+	}
+	if err := tx.Create(wallet).Error; err != nil {
+		return err
+	}
+
+	txs := []model.WalletTransaction{{
+		Type: model.WalletTxOpening, BusinessType: model.WalletBusinessOpening, BusinessID: wallet.ID,
+		AmountCents: opening, AvailableDelta: opening, AvailableAfter: opening, Remark: "钱包开户：历史已结算收入扣除已提现",
+	}}
+	available, frozen := opening, int64(0)
+	for _, w := range withdraws {
+		if w.Status == model.WithdrawStatusCompleted {
+			continue
+		}
+		available -= w.AmountCents
+		frozen += w.AmountCents
+		txs = append(txs, model.WalletTransaction{
+			Type: model.WalletTxWithdrawFreeze, BusinessType: model.WalletBusinessWithdraw, BusinessID: w.ID,
+			AmountCents: w.AmountCents, AvailableDelta: -w.AmountCents, FrozenDelta: w.AmountCents,
+			AvailableAfter: available, FrozenAfter: frozen, Remark: "申请提现",
+		})
+	}
+	for i := range txs {
+		txs[i].WalletID = wallet.ID
+		txs[i].PlayerID = playerID
+		txs[i].Currency = currency
+	}
+	return tx.Create(&txs).Error
+}
+
+// ensureSystemFinancialAccounts 补齐自动凭证使用的系统科目。
+func ensureSystemFinancialAccounts(db *gorm.DB) error {
+	for _, account := range model.SystemFinancialAccounts() {
+		account := account
+		if err := db.Where("code = ?", account.Code).FirstOrCreate(&account).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureDefaultCommissionRule 确保存在启用的全局默认抽成规则（20%），已存在时不做修改。
 func ensureDefaultCommissionRule(db *gorm.DB) error {
 	var existing model.CommissionRule
 	err := db.Where("type = ? AND is_active = ?", "default", true).
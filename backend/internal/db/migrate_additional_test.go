@@ -163,6 +163,7 @@ func TestEnsureIndexesCreatesExpectedIndexes(t *testing.T) {
 		"CREATE TABLE team_members (id integer primary key, team_id integer, status text)",
 		"CREATE TABLE team_order_assignments (id integer primary key, status text, dispatch_deadline datetime)",
 		"CREATE TABLE team_assignment_members (id integer primary key, assignment_id integer, state text)",
+		"CREATE TABLE user_coupons (id integer primary key, user_id integer, template_id integer, status text)",
 	}
 	for _, s := range stmts {
 		require.NoError(t, db.Exec(s).Error)
@@ -179,3 +180,43 @@ func TestEnsureIndexesCreatesExpectedIndexes(t *testing.T) {
 	require.NoError(t, db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type='index' AND name='idx_oplogs_entity'").Scan(&count).Error)
 	require.Equal(t, int64(1), count)
 }
+
+func TestBackfillPlayerWallets_SeedsAvailableFromSettledIncome(t *testing.T) {
+	db := newMemDB(t)
+	require.NoError(t, db.AutoMigrate(&model.CommissionRecord{}, &model.Withdraw{}, &model.PlayerWallet{}, &model.WalletTransaction{}))
+	require.NoError(t, db.Create([]*model.CommissionRecord{
+		{OrderID: 1, PlayerID: 7, PlayerIncomeCents: 8000, Currency: model.CurrencyCNY, SettlementStatus: "settled"},
+		{OrderID: 2, PlayerID: 7, PlayerIncomeCents: 4000, Currency: model.CurrencyCNY, SettlementStatus: "settled"},
+		{OrderID: 3, PlayerID: 7, PlayerIncomeCents: 3000, Currency: model.CurrencyCNY, SettlementStatus: "pending"},
+	}).Error)
+	for _, w := range []struct {
+		amount int64
+		status model.WithdrawStatus
+	}{
+		{5000, model.WithdrawStatusCompleted},
+		{2000, model.WithdrawStatusPending},
+		{1000, model.WithdrawStatusRejected},
+	} {
+		require.NoError(t, db.Exec("INSERT INTO withdraws (player_id, user_id, amount_cents, currency, method, account_info, status) VALUES (7, 70, ?, 'CNY', 'alipay', '', ?)",
+			w.amount, w.status).Error)
+	}
+
+	require.NoError(t, backfillPlayerWallets(db))
+	require.NoError(t, backfillPlayerWallets(db))
+
+	var wallets []model.PlayerWallet
+	require.NoError(t, db.Find(&wallets).Error)
+	require.Len(t, wallets, 1)
+	w := wallets[0]
+	// 已月结 12000 − 已提现 5000 − 处理中 2000；未月结收入留待月结补记
+	require.Equal(t, int64(5000), w.AvailableCents)
+	require.Equal(t, int64(2000), w.FrozenCents)
+	require.Zero(t, w.PendingCents)
+	require.Equal(t, int64(12000), w.TotalIncomeCents)
+	require.Equal(t, int64(5000), w.TotalWithdrawnCents)
+
+	// 处理中的提现补记冻结流水，拒绝或打款时可照常解冻或扣减
+	var freezes int64
+	require.NoError(t, db.Model(&model.WalletTransaction{}).Where("type = ?", model.WalletTxWithdrawFreeze).Count(&freezes).Error)
+	require.EqualValues(t, 1, freezes)
+}
@@ -723,9 +723,9 @@ func seedUser(tx *gorm.DB, input seedUserInput) (*model.User, error) {
 	}
 	lookup := tx.Model(&model.User{})
 	if input.Email != "" {
-		lookup = lookup.Where("email = ?", input.Email)
+		lookup = lookup.Where("email_bidx = ?", model.UserEmailIndex(input.Email))
 	} else {
-		lookup = lookup.Where("phone = ?", input.Phone)
+		lookup = lookup.Where("phone_bidx = ?", model.UserPhoneIndex(input.Phone))
 	}
 	var existing model.User
 	if err := lookup.First(&existing).Error; err == nil {
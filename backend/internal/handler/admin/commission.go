@@ -1,8 +1,10 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -139,6 +141,7 @@ func triggerSettlementHandler(c *gin.Context, scheduler interface{ TriggerSettle
 // @Produce      json
 // @Param        Authorization  header    string  true  "Bearer {token}"
 // @Param        month          query     string  true  "月份 (YYYY-MM)"
+// @Param        currency       query     string  false "报表币种 (CNY/USD/EUR)，指定后按汇率折算合计"
 // @Success      200            {object}  model.APIResponse[commission.PlatformStatsResponse]
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
@@ -146,7 +149,13 @@ func triggerSettlementHandler(c *gin.Context, scheduler interface{ TriggerSettle
 func getPlatformStatsHandler(c *gin.Context, svc *commission.CommissionService) {
 	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
 
-	stats, err := svc.GetPlatformStats(c.Request.Context(), month)
+	currency := model.Currency(strings.ToUpper(c.Query("currency")))
+
+	stats, err := svc.GetPlatformStats(c.Request.Context(), month, currency)
+	if errors.Is(err, commission.ErrValidation) {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		writeJSONError(c, http.StatusInternalServerError, err.Error())
 		return
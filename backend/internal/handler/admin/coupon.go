@@ -0,0 +1,149 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/coupon"
+)
+
+// RegisterCouponRoutes 注册优惠券模板与发放路由
+func RegisterCouponRoutes(router gin.IRouter, svc *coupon.Service) {
+	group := router.Group("/admin/coupons/templates")
+	{
+		group.GET("", func(c *gin.Context) { listCouponTemplatesHandler(c, svc) })
+		group.POST("", func(c *gin.Context) { createCouponTemplateHandler(c, svc) })
+		group.PUT("/:id", func(c *gin.Context) { updateCouponTemplateHandler(c, svc) })
+		group.POST("/:id/issue", func(c *gin.Context) { issueCouponsHandler(c, svc) })
+	}
+}
+
+// listCouponTemplatesHandler 优惠券模板列表
+// @Summary      优惠券模板列表
+// @Tags         Admin - Coupon
+// @Produce      json
+// @Param        page       query  int  false  "页码"
+// @Param        page_size  query  int  false  "每页数量"
+// @Success      200  {object}  model.APIResponse[[]model.CouponTemplate]
+// @Router       /admin/coupons/templates [get]
+func listCouponTemplatesHandler(c *gin.Context, svc *coupon.Service) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	templates, total, err := svc.ListTemplates(c.Request.Context(), page, pageSize)
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.CouponTemplate]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       templates,
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// createCouponTemplateHandler 创建优惠券模板
+// @Summary      创建优惠券模板
+// @Description  fixed 立减、percent 折扣（可封顶）、threshold 满减；可限定游戏、服务项目、币种与首单用户，bearer 指定优惠由平台或陪玩师承担
+// @Tags         Admin - Coupon
+// @Accept       json
+// @Produce      json
+// @Param        request  body  coupon.TemplateRequest  true  "模板"
+// @Success      201  {object}  model.APIResponse[model.CouponTemplate]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Router       /admin/coupons/templates [post]
+func createCouponTemplateHandler(c *gin.Context, svc *coupon.Service) {
+	var req coupon.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	tmpl, err := svc.CreateTemplate(c.Request.Context(), req)
+	if err != nil {
+		writeCouponError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[*model.CouponTemplate]{Success: true, Code: http.StatusCreated, Message: "created", Data: tmpl})
+}
+
+// updateCouponTemplateHandler 更新优惠券模板
+// @Summary      更新优惠券模板
+// @Tags         Admin - Coupon
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                     true  "模板ID"
+// @Param        request  body  coupon.TemplateRequest  true  "模板"
+// @Success      200  {object}  model.APIResponse[model.CouponTemplate]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/coupons/templates/{id} [put]
+func updateCouponTemplateHandler(c *gin.Context, svc *coupon.Service) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid template id")
+		return
+	}
+	var req coupon.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	tmpl, err := svc.UpdateTemplate(c.Request.Context(), id, req)
+	if err != nil {
+		writeCouponError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[*model.CouponTemplate]{Success: true, Code: http.StatusOK, Message: "OK", Data: tmpl})
+}
+
+// issueCouponsHandler 发放优惠券
+// @Summary      发放优惠券
+// @Description  向一个或多个用户发放优惠券，受模板发放总量限制
+// @Tags         Admin - Coupon
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                  true  "模板ID"
+// @Param        request  body  coupon.IssueRequest  true  "用户列表"
+// @Success      200  {object}  model.APIResponse[[]model.UserCoupon]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Failure      409  {object}  model.APIResponse[any]
+// @Router       /admin/coupons/templates/{id}/issue [post]
+func issueCouponsHandler(c *gin.Context, svc *coupon.Service) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid template id")
+		return
+	}
+	var req coupon.IssueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	coupons, err := svc.Issue(c.Request.Context(), id, req.UserIDs)
+	if err != nil {
+		writeCouponError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.UserCoupon]{Success: true, Code: http.StatusOK, Message: "OK", Data: coupons})
+}
+
+func writeCouponError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, coupon.ErrNotFound):
+		writeJSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, coupon.ErrValidation):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, coupon.ErrSoldOut), errors.Is(err, coupon.ErrUnavailable):
+		writeJSONError(c, http.StatusConflict, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,194 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/credit"
+)
+
+// RegisterCreditRoutes 注册陪玩师信用路由：计分规则、信用概览、违约上报与人工调整
+func RegisterCreditRoutes(router gin.IRouter, svc *credit.Service) {
+	rules := router.Group("/admin/credit/rules")
+	{
+		rules.GET("", func(c *gin.Context) { listCreditRulesHandler(c, svc) })
+		rules.PUT("/:type", func(c *gin.Context) { updateCreditRuleHandler(c, svc) })
+	}
+	players := router.Group("/admin/players/:id/credit")
+	{
+		players.GET("", func(c *gin.Context) { getPlayerCreditHandler(c, svc) })
+		players.POST("/incidents", func(c *gin.Context) { reportCreditIncidentHandler(c, svc) })
+		players.POST("/override", func(c *gin.Context) { overrideCreditHandler(c, svc) })
+	}
+}
+
+// listCreditRulesHandler 信用计分规则列表
+// @Summary      信用计分规则列表
+// @Tags         Admin - Credit
+// @Produce      json
+// @Success      200  {object}  model.APIResponse[[]model.CreditRule]
+// @Router       /admin/credit/rules [get]
+func listCreditRulesHandler(c *gin.Context, svc *credit.Service) {
+	rules, err := svc.ListRules(c.Request.Context())
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.CreditRule]{Success: true, Code: http.StatusOK, Message: "OK", Data: rules})
+}
+
+// updateCreditRuleHandler 调整信用计分规则
+// @Summary      调整信用计分规则
+// @Description  调整事件的加减分、阈值（好评最低评分、连续完成单数）与启用状态；调整只影响之后的事件
+// @Tags         Admin - Credit
+// @Accept       json
+// @Produce      json
+// @Param        type     path  string                    true  "事件类型"
+// @Param        request  body  credit.UpdateRuleRequest  true  "规则"
+// @Success      200  {object}  model.APIResponse[model.CreditRule]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/credit/rules/{type} [put]
+func updateCreditRuleHandler(c *gin.Context, svc *credit.Service) {
+	var req credit.UpdateRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	rule, err := svc.UpdateRule(c.Request.Context(), model.CreditEventType(c.Param("type")), req)
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[*model.CreditRule]{Success: true, Code: http.StatusOK, Message: "OK", Data: rule})
+}
+
+// getPlayerCreditHandler 陪玩师信用概览
+// @Summary      陪玩师信用概览
+// @Description  信用分、生效等级及其策略，以及分页的信用变动记录（最新在前）
+// @Tags         Admin - Credit
+// @Produce      json
+// @Param        id         path   int  true   "陪玩师ID"
+// @Param        page       query  int  false  "页码"
+// @Param        page_size  query  int  false  "每页数量"
+// @Success      200  {object}  model.APIResponse[credit.CreditOverview]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/players/{id}/credit [get]
+func getPlayerCreditHandler(c *gin.Context, svc *credit.Service) {
+	playerID, ok := parseCreditPlayerID(c)
+	if !ok {
+		return
+	}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	overview, total, err := svc.Overview(c.Request.Context(), playerID, page, pageSize)
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[*credit.CreditOverview]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       overview,
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// reportCreditIncidentHandler 上报陪玩师违约
+// @Summary      上报陪玩师违约
+// @Description  接单后取消、爽约或时效投诉，按规则扣分并清零连续完成数；同一订单的同类违约只记一次，重复上报或规则停用时不返回记录
+// @Tags         Admin - Credit
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                     true  "陪玩师ID"
+// @Param        request  body  credit.IncidentRequest  true  "违约"
+// @Success      201  {object}  model.APIResponse[model.CreditEvent]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/players/{id}/credit/incidents [post]
+func reportCreditIncidentHandler(c *gin.Context, svc *credit.Service) {
+	playerID, ok := parseCreditPlayerID(c)
+	if !ok {
+		return
+	}
+	var req credit.IncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.PlayerID = playerID
+	req.ActorUserID = creditActor(c)
+	event, err := svc.ReportIncident(c.Request.Context(), req)
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[*model.CreditEvent]{Success: true, Code: http.StatusCreated, Message: "created", Data: event})
+}
+
+// overrideCreditHandler 人工调整陪玩师信用
+// @Summary      人工调整陪玩师信用
+// @Description  直接设置信用分，或锁定生效等级（tier 传空字符串解除锁定）；调整写入信用记录
+// @Tags         Admin - Credit
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                     true  "陪玩师ID"
+// @Param        request  body  credit.OverrideRequest  true  "调整"
+// @Success      200  {object}  model.APIResponse[model.PlayerCredit]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/players/{id}/credit/override [post]
+func overrideCreditHandler(c *gin.Context, svc *credit.Service) {
+	playerID, ok := parseCreditPlayerID(c)
+	if !ok {
+		return
+	}
+	var req credit.OverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.PlayerID = playerID
+	req.ActorUserID = creditActor(c)
+	result, err := svc.Override(c.Request.Context(), req)
+	if err != nil {
+		writeCreditError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[*model.PlayerCredit]{Success: true, Code: http.StatusOK, Message: "OK", Data: result})
+}
+
+func parseCreditPlayerID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		writeJSONError(c, http.StatusBadRequest, "Invalid player id")
+		return 0, false
+	}
+	return id, true
+}
+
+// creditActor 操作者用户ID（由认证中间件设置）
+func creditActor(c *gin.Context) *uint64 {
+	if id := c.GetUint64("user_id"); id > 0 {
+		return &id
+	}
+	return nil
+}
+
+func writeCreditError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, credit.ErrNotFound):
+		writeJSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, credit.ErrValidation):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
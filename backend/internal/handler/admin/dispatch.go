@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/dispatch"
+)
+
+// RegisterDispatchRoutes 注册智能派单路由
+func RegisterDispatchRoutes(router gin.IRouter, svc *dispatch.Service) {
+	group := router.Group("/admin/orders/:id/dispatch")
+	{
+		group.GET("/candidates", func(c *gin.Context) { listDispatchCandidatesHandler(c, svc) })
+		group.POST("", func(c *gin.Context) { dispatchOrderHandler(c, svc) })
+	}
+}
+
+// listDispatchCandidatesHandler 推荐陪玩师
+// @Summary      推荐陪玩师
+// @Description  按游戏匹配、技能标签、评分、在线状态、当前负载、接单率与价格匹配度为订单的候选陪玩师打分
+// @Tags         Admin - Dispatch
+// @Produce      json
+// @Param        id     path   int  true   "订单ID"
+// @Param        limit  query  int  false  "条数，默认 10"
+// @Success      200  {object}  model.APIResponse[[]dispatch.Candidate]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/orders/{id}/dispatch/candidates [get]
+func listDispatchCandidatesHandler(c *gin.Context, svc *dispatch.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid order id")
+		return
+	}
+	limit := 10
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeJSONError(c, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+	candidates, err := svc.Recommend(c.Request.Context(), orderID, limit)
+	if err != nil {
+		writeDispatchError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]dispatch.Candidate]{Success: true, Code: http.StatusOK, Message: "OK", Data: candidates})
+}
+
+// dispatchOrderHandler 派单
+// @Summary      派单
+// @Description  push 推送给评分最高的 N 位陪玩师；auto 直接指派给评分最高的陪玩师；pool 取消未响应的邀请，订单回到抢单大厅
+// @Tags         Admin - Dispatch
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                       true  "订单ID"
+// @Param        request  body  dispatch.DispatchRequest  true  "派单参数，未填写时取默认策略"
+// @Success      200  {object}  model.APIResponse[dispatch.DispatchResult]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Failure      409  {object}  model.APIResponse[any]
+// @Router       /admin/orders/{id}/dispatch [post]
+func dispatchOrderHandler(c *gin.Context, svc *dispatch.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid order id")
+		return
+	}
+	var req dispatch.DispatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := svc.Dispatch(c.Request.Context(), orderID, req)
+	if err != nil {
+		writeDispatchError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[dispatch.DispatchResult]{Success: true, Code: http.StatusOK, Message: "Order dispatched", Data: *result})
+}
+
+func writeDispatchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, dispatch.ErrValidation):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, dispatch.ErrNotFound):
+		writeJSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, dispatch.ErrNotDispatchable), errors.Is(err, dispatch.ErrNoCandidate):
+		writeJSONError(c, http.StatusConflict, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
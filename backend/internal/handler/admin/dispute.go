@@ -10,6 +10,7 @@ import (
 
 	apierr "gamelink/internal/handler"
 	"gamelink/internal/model"
+	"gamelink/internal/repository"
 	"gamelink/internal/service/assignment"
 )
 
@@ -23,6 +24,24 @@ func NewDisputeHandler(svc *assignment.AssignmentService) *DisputeHandler {
 	return &DisputeHandler{svc: svc}
 }
 
+// RegisterDisputeRoutes 注册客服争议工作台路由（争议处理与客服排班）
+func RegisterDisputeRoutes(router gin.IRouter, svc *assignment.AssignmentService) {
+	h := NewDisputeHandler(svc)
+	group := router.Group("/admin/disputes")
+	{
+		group.GET("", h.ListDisputes)
+		group.GET("/pending", h.ListPendingDisputes)
+		group.GET("/agents", h.ListAgents)
+		group.PUT("/agents/:userId", h.UpsertAgent)
+		group.PUT("/agents/:userId/duty", h.SetAgentDuty)
+		group.DELETE("/agents/:userId", h.RemoveAgent)
+		group.GET("/:id", h.GetDisputeDetail)
+		group.POST("/:id/assign", h.AssignDispute)
+		group.POST("/:id/assign/cancel", h.RollbackAssignment)
+		group.POST("/:id/resolve", h.ResolveDispute)
+	}
+}
+
 // GetDisputeDetail retrieves dispute details
 // @Summary      Get Dispute Detail
 // @Tags         Admin/Disputes
@@ -32,7 +51,7 @@ func NewDisputeHandler(svc *assignment.AssignmentService) *DisputeHandler {
 // @Param        id  path  uint64  true  "Dispute ID"
 // @Success      200  {object}  map[string]any
 // @Failure      404  {object}  map[string]any
-// @Router       /admin/orders/{id}/disputes [get]
+// @Router       /admin/disputes/{id} [get]
 func (h *DisputeHandler) GetDisputeDetail(c *gin.Context) {
 	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -67,7 +86,7 @@ func (h *DisputeHandler) GetDisputeDetail(c *gin.Context) {
 // @Param        pageSize  query  int  false  "Page size"    default(20)
 // @Success      200  {object}  map[string]any
 // @Failure      400  {object}  map[string]any
-// @Router       /admin/orders/pending-assign [get]
+// @Router       /admin/disputes/pending [get]
 func (h *DisputeHandler) ListPendingDisputes(c *gin.Context) {
 	page := 1
 	pageSize := 20
@@ -125,7 +144,7 @@ type AssignDisputePayload struct {
 // @Success      200  {object}  map[string]any
 // @Failure      400  {object}  map[string]any
 // @Failure      404  {object}  map[string]any
-// @Router       /admin/orders/{id}/assign [post]
+// @Router       /admin/disputes/{id}/assign [post]
 func (h *DisputeHandler) AssignDispute(c *gin.Context) {
 	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -140,7 +159,7 @@ func (h *DisputeHandler) AssignDispute(c *gin.Context) {
 	}
 
 	// Get actor user ID from context (set by auth middleware)
-	actorUserID, exists := c.Get("userID")
+	actorUserID, exists := c.Get("user_id")
 	if !exists {
 		writeJSONError(c, http.StatusUnauthorized, "User ID not found in context")
 		return
@@ -160,6 +179,10 @@ func (h *DisputeHandler) AssignDispute(c *gin.Context) {
 	})
 
 	if err != nil {
+		if errors.Is(err, assignment.ErrNotFound) {
+			writeJSONError(c, http.StatusNotFound, "Dispute or assignee not found")
+			return
+		}
 		if errors.Is(err, assignment.ErrValidation) {
 			writeJSONError(c, http.StatusBadRequest, err.Error())
 			return
@@ -188,6 +211,7 @@ type RollbackAssignmentPayload struct {
 
 // RollbackAssignment rolls back a dispute assignment
 // @Summary      Rollback Assignment
+// @Description  已结案的争议会一并撤销退款（渠道受理前）并回滚陪玩师扣款与平台赔付
 // @Tags         Admin/Disputes
 // @Security     BearerAuth
 // @Accept       json
@@ -197,7 +221,7 @@ type RollbackAssignmentPayload struct {
 // @Success      200  {object}  map[string]any
 // @Failure      400  {object}  map[string]any
 // @Failure      404  {object}  map[string]any
-// @Router       /admin/orders/{id}/assign/cancel [post]
+// @Router       /admin/disputes/{id}/assign/cancel [post]
 func (h *DisputeHandler) RollbackAssignment(c *gin.Context) {
 	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -212,7 +236,7 @@ func (h *DisputeHandler) RollbackAssignment(c *gin.Context) {
 	}
 
 	// Get actor user ID from context
-	actorUserID, exists := c.Get("userID")
+	actorUserID, exists := c.Get("user_id")
 	if !exists {
 		writeJSONError(c, http.StatusUnauthorized, "User ID not found in context")
 		return
@@ -225,6 +249,10 @@ func (h *DisputeHandler) RollbackAssignment(c *gin.Context) {
 	})
 
 	if err != nil {
+		if errors.Is(err, assignment.ErrNotFound) {
+			writeJSONError(c, http.StatusNotFound, "Dispute not found")
+			return
+		}
 		if errors.Is(err, assignment.ErrValidation) {
 			writeJSONError(c, http.StatusBadRequest, err.Error())
 			return
@@ -251,6 +279,9 @@ type ResolveDisputePayload struct {
 	Resolution       string `json:"resolution" binding:"required,oneof=refund partial reassign reject"`
 	ResolutionAmount int64  `json:"resolutionAmount"`
 	ResolutionNotes  string `json:"resolutionNotes" binding:"required"`
+	// 退款中由陪玩师承担（从其收入扣回）与平台承担的部分（分）；平台部分为 0 时承担其余金额
+	PlayerDeductionCents      int64 `json:"playerDeductionCents"`
+	PlatformCompensationCents int64 `json:"platformCompensationCents"`
 }
 
 // ResolveDispute resolves a dispute with a decision
@@ -264,7 +295,7 @@ type ResolveDisputePayload struct {
 // @Success      200  {object}  map[string]any
 // @Failure      400  {object}  map[string]any
 // @Failure      404  {object}  map[string]any
-// @Router       /admin/orders/{id}/mediate [post]
+// @Router       /admin/disputes/{id}/resolve [post]
 func (h *DisputeHandler) ResolveDispute(c *gin.Context) {
 	disputeID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -279,7 +310,7 @@ func (h *DisputeHandler) ResolveDispute(c *gin.Context) {
 	}
 
 	// Get actor user ID from context
-	actorUserID, exists := c.Get("userID")
+	actorUserID, exists := c.Get("user_id")
 	if !exists {
 		writeJSONError(c, http.StatusUnauthorized, "User ID not found in context")
 		return
@@ -293,9 +324,16 @@ func (h *DisputeHandler) ResolveDispute(c *gin.Context) {
 		ResolutionAmount: payload.ResolutionAmount,
 		ResolutionNotes:  payload.ResolutionNotes,
 		ActorUserID:      actorUserID.(uint64),
+
+		PlayerDeductionCents:      payload.PlayerDeductionCents,
+		PlatformCompensationCents: payload.PlatformCompensationCents,
 	})
 
 	if err != nil {
+		if errors.Is(err, assignment.ErrNotFound) {
+			writeJSONError(c, http.StatusNotFound, "Dispute not found")
+			return
+		}
 		if errors.Is(err, assignment.ErrValidation) {
 			writeJSONError(c, http.StatusBadRequest, err.Error())
 			return
@@ -316,3 +354,185 @@ func (h *DisputeHandler) ResolveDispute(c *gin.Context) {
 		},
 	})
 }
+
+// ListDisputes lists disputes on the CS workbench
+// @Summary      List Disputes
+// @Description  按状态（逗号分隔）、处理客服、是否超过 SLA 与关键字筛选争议
+// @Tags         Admin/Disputes
+// @Security     BearerAuth
+// @Produce      json
+// @Param        status       query  string  false  "pending,assigned,mediating,resolved,rejected,canceled"
+// @Param        assignee     query  int     false  "处理客服用户ID"
+// @Param        sla_breached query  bool    false  "是否超过 SLA"
+// @Param        keyword      query  string  false  "原因或描述关键字"
+// @Param        page         query  int     false  "页码"
+// @Param        page_size    query  int     false  "每页数量"
+// @Success      200  {object}  model.APIResponse[[]model.OrderDispute]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Router       /admin/disputes [get]
+func (h *DisputeHandler) ListDisputes(c *gin.Context) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	opts := repository.DisputeListOptions{Page: page, PageSize: pageSize, Keyword: c.Query("keyword")}
+	for _, token := range parseCSVParams(c.QueryArray("status")) {
+		opts.Statuses = append(opts.Statuses, model.DisputeStatus(token))
+	}
+	assignee, err := queryUint64Ptr(c, "assignee")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid assignee")
+		return
+	}
+	opts.AssignedToUserID = assignee
+	if value := c.Query("sla_breached"); value != "" {
+		breached, err := strconv.ParseBool(value)
+		if err != nil {
+			writeJSONError(c, http.StatusBadRequest, "Invalid sla_breached")
+			return
+		}
+		opts.SLABreached = &breached
+	}
+
+	disputes, total, err := h.svc.ListDisputes(c.Request.Context(), opts)
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.OrderDispute]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       ensureSlice(disputes),
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// ListAgents lists the CS agent roster
+// @Summary      List Dispute Agents
+// @Description  客服排班：层级（1 一线客服、2 主管、3 经理）、是否值班、擅长游戏、负载上限与当前未结争议数
+// @Tags         Admin/Disputes
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  model.APIResponse[[]assignment.AgentView]
+// @Router       /admin/disputes/agents [get]
+func (h *DisputeHandler) ListAgents(c *gin.Context) {
+	agents, err := h.svc.ListAgents(c.Request.Context())
+	if err != nil {
+		writeDisputeAgentError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]assignment.AgentView]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    agents,
+	})
+}
+
+// UpsertAgent adds a user to the roster or updates their settings
+// @Summary      Upsert Dispute Agent
+// @Description  新争议自动指派给值班一线客服中负载最低者（优先擅长该游戏的客服，负载相同按最久未指派轮转）；超过 SLA 后升级给上一级值班客服
+// @Tags         Admin/Disputes
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        userId   path  uint64                         true  "客服用户ID"
+// @Param        request  body  assignment.UpsertAgentRequest  true  "排班设置"
+// @Success      200  {object}  model.APIResponse[model.DisputeAgent]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/disputes/agents/{userId} [put]
+func (h *DisputeHandler) UpsertAgent(c *gin.Context) {
+	userID, err := parseUintParam(c, "userId")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	var req assignment.UpsertAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, apierr.ErrInvalidJSONPayload)
+		return
+	}
+	agent, err := h.svc.UpsertAgent(c.Request.Context(), userID, req)
+	if err != nil {
+		writeDisputeAgentError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[*model.DisputeAgent]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    agent,
+	})
+}
+
+// SetAgentDutyPayload starts or ends an agent's shift
+type SetAgentDutyPayload struct {
+	OnDuty bool `json:"onDuty"`
+}
+
+// SetAgentDuty starts or ends an agent's shift
+// @Summary      Set Dispute Agent Duty
+// @Description  下班不会收回已指派的争议
+// @Tags         Admin/Disputes
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        userId   path  uint64               true  "客服用户ID"
+// @Param        request  body  SetAgentDutyPayload  true  "是否值班"
+// @Success      200  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/disputes/agents/{userId}/duty [put]
+func (h *DisputeHandler) SetAgentDuty(c *gin.Context) {
+	userID, err := parseUintParam(c, "userId")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	var payload SetAgentDutyPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		writeJSONError(c, http.StatusBadRequest, apierr.ErrInvalidJSONPayload)
+		return
+	}
+	if err := h.svc.SetAgentOnDuty(c.Request.Context(), userID, payload.OnDuty); err != nil {
+		writeDisputeAgentError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[any]{Success: true, Code: http.StatusOK, Message: "OK"})
+}
+
+// RemoveAgent removes a user from the roster
+// @Summary      Remove Dispute Agent
+// @Tags         Admin/Disputes
+// @Security     BearerAuth
+// @Produce      json
+// @Param        userId  path  uint64  true  "客服用户ID"
+// @Success      200  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/disputes/agents/{userId} [delete]
+func (h *DisputeHandler) RemoveAgent(c *gin.Context) {
+	userID, err := parseUintParam(c, "userId")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if err := h.svc.RemoveAgent(c.Request.Context(), userID); err != nil {
+		writeDisputeAgentError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[any]{Success: true, Code: http.StatusOK, Message: "OK"})
+}
+
+func writeDisputeAgentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, assignment.ErrValidation):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, assignment.ErrNotFound):
+		writeJSONError(c, http.StatusNotFound, "User not found")
+	case errors.Is(err, assignment.ErrNoRoster):
+		writeJSONError(c, http.StatusServiceUnavailable, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -175,6 +175,10 @@ func (m *mockPaymentRepoForAdminDispute) List(ctx context.Context, opts reposito
 func (m *mockPaymentRepoForAdminDispute) Get(ctx context.Context, id uint64) (*model.Payment, error) {
 	return nil, repository.ErrNotFound
 }
+
+func (m *mockPaymentRepoForAdminDispute) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (m *mockPaymentRepoForAdminDispute) Update(ctx context.Context, p *model.Payment) error { return nil }
 func (m *mockPaymentRepoForAdminDispute) Delete(ctx context.Context, id uint64) error { return nil }
 
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/fx"
+)
+
+// RegisterFXRoutes 注册汇率管理路由
+func RegisterFXRoutes(router gin.IRouter, svc *fx.ExchangeRateService) {
+	group := router.Group("/admin/fx-rates")
+	{
+		group.GET("", func(c *gin.Context) { listFXRatesHandler(c, svc) })
+		group.POST("", func(c *gin.Context) { setFXRateHandler(c, svc) })
+		group.POST("/refresh", func(c *gin.Context) { refreshFXRatesHandler(c, svc) })
+		group.GET("/history", func(c *gin.Context) { listFXRateHistoryHandler(c, svc) })
+	}
+}
+
+// SetFXRatePayload 手工录入汇率请求体
+type SetFXRatePayload struct {
+	Base        string  `json:"base" binding:"required"`
+	Quote       string  `json:"quote" binding:"required"`
+	Rate        float64 `json:"rate" binding:"required,gt=0"`
+	EffectiveAt string  `json:"effective_at"` // RFC3339，为空表示立即生效
+}
+
+// listFXRatesHandler 当前汇率
+// @Summary      当前汇率
+// @Description  各币种对当前生效的汇率
+// @Tags         Admin - FX
+// @Produce      json
+// @Success      200  {object}  model.APIResponse[[]model.ExchangeRate]
+// @Router       /admin/fx-rates [get]
+func listFXRatesHandler(c *gin.Context, svc *fx.ExchangeRateService) {
+	rates, err := svc.ListRates(c.Request.Context())
+	if err != nil {
+		writeFXError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.ExchangeRate]{Success: true, Code: http.StatusOK, Message: "OK", Data: rates})
+}
+
+// setFXRateHandler 手工录入汇率
+// @Summary      手工录入汇率
+// @Description  录入 1 单位 base 可兑换的 quote 数量，追加为新的生效汇率
+// @Tags         Admin - FX
+// @Accept       json
+// @Produce      json
+// @Param        request  body  SetFXRatePayload  true  "汇率"
+// @Success      201  {object}  model.APIResponse[model.ExchangeRate]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Router       /admin/fx-rates [post]
+func setFXRateHandler(c *gin.Context, svc *fx.ExchangeRateService) {
+	var payload SetFXRatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req := fx.SetRateRequest{
+		Base:  model.Currency(strings.ToUpper(strings.TrimSpace(payload.Base))),
+		Quote: model.Currency(strings.ToUpper(strings.TrimSpace(payload.Quote))),
+		Rate:  payload.Rate,
+	}
+	if payload.EffectiveAt != "" {
+		at, err := time.Parse(time.RFC3339, payload.EffectiveAt)
+		if err != nil {
+			writeJSONError(c, http.StatusBadRequest, "Invalid effective_at")
+			return
+		}
+		req.EffectiveAt = &at
+	}
+	rate, err := svc.SetRate(c.Request.Context(), req)
+	if err != nil {
+		writeFXError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[model.ExchangeRate]{Success: true, Code: http.StatusCreated, Message: "Rate saved", Data: *rate})
+}
+
+// refreshFXRatesHandler 立即刷新汇率
+// @Summary      立即刷新汇率
+// @Description  从配置的汇率数据源拉取最新汇率
+// @Tags         Admin - FX
+// @Produce      json
+// @Success      200  {object}  model.APIResponse[[]model.ExchangeRate]
+// @Failure      409  {object}  model.APIResponse[any]
+// @Router       /admin/fx-rates/refresh [post]
+func refreshFXRatesHandler(c *gin.Context, svc *fx.ExchangeRateService) {
+	rates, err := svc.RefreshRates(c.Request.Context())
+	if err != nil {
+		writeFXError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.ExchangeRate]{Success: true, Code: http.StatusOK, Message: "Rates refreshed", Data: rates})
+}
+
+// listFXRateHistoryHandler 历史汇率
+// @Summary      历史汇率
+// @Tags         Admin - FX
+// @Produce      json
+// @Param        base   query  string  true   "基准币种"
+// @Param        quote  query  string  true   "报价币种"
+// @Param        limit  query  int     false  "条数，默认 100"
+// @Success      200  {object}  model.APIResponse[[]model.ExchangeRate]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Router       /admin/fx-rates/history [get]
+func listFXRateHistoryHandler(c *gin.Context, svc *fx.ExchangeRateService) {
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeJSONError(c, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+	rates, err := svc.ListHistory(c.Request.Context(),
+		model.Currency(strings.ToUpper(c.Query("base"))),
+		model.Currency(strings.ToUpper(c.Query("quote"))),
+		limit)
+	if err != nil {
+		writeFXError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.ExchangeRate]{Success: true, Code: http.StatusOK, Message: "OK", Data: rates})
+}
+
+func writeFXError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, fx.ErrValidation):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, fx.ErrRateUnavailable):
+		writeJSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, fx.ErrProviderNotConfigured):
+		writeJSONError(c, http.StatusConflict, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
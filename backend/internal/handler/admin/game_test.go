@@ -169,6 +169,10 @@ func (f *fakePaymentRepo) Get(ctx context.Context, id uint64) (*model.Payment, e
 	}
 	return f.obj, nil
 }
+
+func (f *fakePaymentRepo) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (f *fakePaymentRepo) Update(ctx context.Context, p *model.Payment) error { f.obj = p; return nil }
 func (f *fakePaymentRepo) Delete(ctx context.Context, id uint64) error        { return nil }
 
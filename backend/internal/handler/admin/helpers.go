@@ -95,6 +95,21 @@ func ensureSlice[T any](items []T) []T {
 	return items
 }
 
+// newPagination builds pagination metadata using the repository's page normalization.
+func newPagination(page, pageSize int, total int64) *model.Pagination {
+	page = repository.NormalizePage(page)
+	pageSize = repository.NormalizePageSize(pageSize)
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return &model.Pagination{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      int(total),
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
+
 // parsePagination parses page and page_size with defaults and writes error response when invalid.
 func parsePagination(c *gin.Context) (int, int, bool) {
 	page, err := queryIntDefault(c, "page", 1)
@@ -1,8 +1,10 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
@@ -21,6 +23,9 @@ func RegisterServiceItemRoutes(router gin.IRouter, svc *item.ServiceItemService)
 		group.DELETE("/:id", func(c *gin.Context) { deleteServiceItemHandler(c, svc) })
 		group.POST("/batch-update-status", func(c *gin.Context) { batchUpdateStatusHandler(c, svc) })
 		group.POST("/batch-update-price", func(c *gin.Context) { batchUpdatePriceHandler(c, svc) })
+		group.GET("/:id/prices", func(c *gin.Context) { listServiceItemPricesHandler(c, svc) })
+		group.PUT("/:id/prices", func(c *gin.Context) { setServiceItemPriceHandler(c, svc) })
+		group.DELETE("/:id/prices/:currency", func(c *gin.Context) { deleteServiceItemPriceHandler(c, svc) })
 	}
 }
 
@@ -269,3 +274,114 @@ func batchUpdatePriceHandler(c *gin.Context, svc *item.ServiceItemService) {
 		Message: "Price updated successfully",
 	})
 }
+
+// listServiceItemPricesHandler 查询服务项目币种定价
+// @Summary      查询服务项目币种定价
+// @Description  列出服务项目在基础币种以外单独设置的价格
+// @Tags         Admin - ServiceItem
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "服务项目ID"
+// @Success      200            {object}  model.APIResponse[[]model.ServiceItemPrice]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /admin/service-items/{id}/prices [get]
+func listServiceItemPricesHandler(c *gin.Context, svc *item.ServiceItemService) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid service item ID")
+		return
+	}
+
+	prices, err := svc.ListPrices(c.Request.Context(), id)
+	if err != nil {
+		writeServiceItemPriceError(c, err)
+		return
+	}
+
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.ServiceItemPrice]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    prices,
+	})
+}
+
+// setServiceItemPriceHandler 设置服务项目币种定价
+// @Summary      设置服务项目币种定价
+// @Description  为服务项目设置某一币种的固定价格，未设置的币种按汇率换算
+// @Tags         Admin - ServiceItem
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                true  "Bearer {token}"
+// @Param        id             path      int                   true  "服务项目ID"
+// @Param        request        body      item.SetPriceRequest  true  "币种定价"
+// @Success      200            {object}  model.APIResponse[model.ServiceItemPrice]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Router       /admin/service-items/{id}/prices [put]
+func setServiceItemPriceHandler(c *gin.Context, svc *item.ServiceItemService) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid service item ID")
+		return
+	}
+
+	var req item.SetPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	price, err := svc.SetPrice(c.Request.Context(), id, req)
+	if err != nil {
+		writeServiceItemPriceError(c, err)
+		return
+	}
+
+	writeJSON(c, http.StatusOK, model.APIResponse[model.ServiceItemPrice]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "Price updated successfully",
+		Data:    *price,
+	})
+}
+
+// deleteServiceItemPriceHandler 删除服务项目币种定价
+// @Summary      删除服务项目币种定价
+// @Tags         Admin - ServiceItem
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "服务项目ID"
+// @Param        currency       path      string  true  "币种"
+// @Success      200            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /admin/service-items/{id}/prices/{currency} [delete]
+func deleteServiceItemPriceHandler(c *gin.Context, svc *item.ServiceItemService) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid service item ID")
+		return
+	}
+
+	currency := model.Currency(strings.ToUpper(c.Param("currency")))
+	if err := svc.DeletePrice(c.Request.Context(), id, currency); err != nil {
+		writeServiceItemPriceError(c, err)
+		return
+	}
+
+	writeJSON(c, http.StatusOK, model.APIResponse[any]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "Price deleted successfully",
+	})
+}
+
+func writeServiceItemPriceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, item.ErrNotFound):
+		writeJSONError(c, http.StatusNotFound, "Service item price not found")
+	case errors.Is(err, item.ErrValidation):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,494 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	apierr "gamelink/internal/handler"
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	ledgerrepo "gamelink/internal/repository/ledger"
+	"gamelink/internal/service/ledger"
+)
+
+// RegisterLedgerRoutes 注册总账（复式记账）管理路由
+func RegisterLedgerRoutes(router gin.IRouter, svc *ledger.LedgerService) {
+	group := router.Group("/admin/ledger")
+	{
+		group.GET("/accounts", func(c *gin.Context) { listLedgerAccountsHandler(c, svc) })
+		group.GET("/transactions", func(c *gin.Context) { listLedgerTransactionsHandler(c, svc) })
+		group.GET("/vouchers", func(c *gin.Context) { listVouchersHandler(c, svc) })
+		group.POST("/vouchers", func(c *gin.Context) { createVoucherHandler(c, svc) })
+		group.GET("/vouchers/:id", func(c *gin.Context) { getVoucherHandler(c, svc) })
+		group.POST("/vouchers/:id/approve", func(c *gin.Context) { voucherActionHandler(c, svc.ApproveVoucher) })
+		group.POST("/vouchers/:id/reject", func(c *gin.Context) { voucherActionHandler(c, svc.RejectVoucher) })
+		group.POST("/vouchers/:id/post", func(c *gin.Context) { voucherActionHandler(c, svc.PostVoucher) })
+		group.POST("/vouchers/:id/reverse", func(c *gin.Context) { reverseVoucherHandler(c, svc) })
+		group.GET("/reports", func(c *gin.Context) { listReportsHandler(c, svc) })
+		group.POST("/reports", func(c *gin.Context) { generateReportHandler(c, svc) })
+		group.GET("/reports/:id", func(c *gin.Context) { getReportHandler(c, svc) })
+		group.GET("/reports/:id/export", func(c *gin.Context) { exportReportHandler(c, svc) })
+		group.GET("/periods", func(c *gin.Context) { listPeriodsHandler(c, svc) })
+		group.POST("/periods/close", func(c *gin.Context) { closePeriodHandler(c, svc) })
+	}
+}
+
+// VoucherLinePayload 凭证分录请求体
+type VoucherLinePayload struct {
+	AccountCode string `json:"account_code" binding:"required"`
+	Abstract    string `json:"abstract"`
+	DebitCents  int64  `json:"debit_cents"`
+	CreditCents int64  `json:"credit_cents"`
+}
+
+// CreateVoucherPayload 手工凭证请求体
+type CreateVoucherPayload struct {
+	Date            string               `json:"date"` // YYYY-MM-DD，默认当天
+	Type            string               `json:"type"` // manual | adjust
+	Abstract        string               `json:"abstract" binding:"required"`
+	BusinessType    string               `json:"business_type"`
+	BusinessNo      string               `json:"business_no"`
+	AttachmentCount int                  `json:"attachment_count"`
+	Lines           []VoucherLinePayload `json:"lines" binding:"required"`
+}
+
+// ReverseVoucherPayload 红字冲销请求体
+type ReverseVoucherPayload struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// listLedgerAccountsHandler 科目余额表
+// @Summary      科目余额表
+// @Tags         Admin - Ledger
+// @Produce      json
+// @Success      200  {object}  model.APIResponse[[]model.FinancialAccount]
+// @Router       /admin/ledger/accounts [get]
+func listLedgerAccountsHandler(c *gin.Context, svc *ledger.LedgerService) {
+	accounts, err := svc.ListAccounts(c.Request.Context())
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.FinancialAccount]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    ensureSlice(accounts),
+	})
+}
+
+// listLedgerTransactionsHandler 科目流水
+// @Summary      科目流水
+// @Tags         Admin - Ledger
+// @Produce      json
+// @Param        account_code  query  string  false  "科目编码"
+// @Param        date_from     query  string  false  "开始日期"
+// @Param        date_to       query  string  false  "结束日期"
+// @Param        page          query  int     false  "页码"
+// @Param        page_size     query  int     false  "每页数量"
+// @Success      200  {object}  model.APIResponse[[]model.FinancialTransaction]
+// @Router       /admin/ledger/transactions [get]
+func listLedgerTransactionsHandler(c *gin.Context, svc *ledger.LedgerService) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	dateFrom, dateTo, ok := parseLedgerDateRange(c)
+	if !ok {
+		return
+	}
+	txs, total, err := svc.ListTransactions(c.Request.Context(), ledgerrepo.TransactionListOptions{
+		AccountCode: strings.TrimSpace(c.Query("account_code")),
+		DateFrom:    dateFrom,
+		DateTo:      dateTo,
+		Page:        page,
+		PageSize:    pageSize,
+	})
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.FinancialTransaction]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       ensureSlice(txs),
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// listVouchersHandler 凭证列表
+// @Summary      凭证列表
+// @Tags         Admin - Ledger
+// @Produce      json
+// @Param        status         query  string  false  "凭证状态"
+// @Param        business_type  query  string  false  "业务类型"
+// @Param        date_from      query  string  false  "开始日期"
+// @Param        date_to        query  string  false  "结束日期"
+// @Param        page           query  int     false  "页码"
+// @Param        page_size      query  int     false  "每页数量"
+// @Success      200  {object}  model.APIResponse[[]model.FinancialVoucher]
+// @Router       /admin/ledger/vouchers [get]
+func listVouchersHandler(c *gin.Context, svc *ledger.LedgerService) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	dateFrom, dateTo, ok := parseLedgerDateRange(c)
+	if !ok {
+		return
+	}
+	opts := ledgerrepo.VoucherListOptions{
+		BusinessType: strings.TrimSpace(c.Query("business_type")),
+		DateFrom:     dateFrom,
+		DateTo:       dateTo,
+		Page:         page,
+		PageSize:     pageSize,
+	}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		st := model.FinancialVoucherStatus(strings.ToLower(status))
+		opts.Status = &st
+	}
+	vouchers, total, err := svc.ListVouchers(c.Request.Context(), opts)
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.FinancialVoucher]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       ensureSlice(vouchers),
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// getVoucherHandler 凭证详情
+// @Summary      凭证详情
+// @Tags         Admin - Ledger
+// @Produce      json
+// @Param        id  path  int  true  "凭证ID"
+// @Success      200  {object}  model.APIResponse[model.FinancialVoucher]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/ledger/vouchers/{id} [get]
+func getVoucherHandler(c *gin.Context, svc *ledger.LedgerService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid voucher ID")
+		return
+	}
+	voucher, err := svc.GetVoucher(c.Request.Context(), id)
+	if err != nil {
+		writeLedgerError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[model.FinancialVoucher]{Success: true, Code: http.StatusOK, Message: "OK", Data: *voucher})
+}
+
+// createVoucherHandler 创建手工凭证（草稿）
+// @Summary      创建手工凭证
+// @Description  借贷必须平衡，创建后为草稿，需他人审核后过账
+// @Tags         Admin - Ledger
+// @Accept       json
+// @Produce      json
+// @Param        request  body  CreateVoucherPayload  true  "凭证"
+// @Success      201  {object}  model.APIResponse[model.FinancialVoucher]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Router       /admin/ledger/vouchers [post]
+func createVoucherHandler(c *gin.Context, svc *ledger.LedgerService) {
+	var payload CreateVoucherPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req := ledger.CreateVoucherRequest{
+		Type:            model.FinancialVoucherType(strings.ToLower(strings.TrimSpace(payload.Type))),
+		Abstract:        payload.Abstract,
+		BusinessType:    payload.BusinessType,
+		BusinessNo:      payload.BusinessNo,
+		AttachmentCount: payload.AttachmentCount,
+		CreatedBy:       c.GetUint64("user_id"),
+	}
+	if payload.Date != "" {
+		date, err := time.ParseInLocation("2006-01-02", payload.Date, time.Local)
+		if err != nil {
+			writeJSONError(c, http.StatusBadRequest, "Invalid voucher date")
+			return
+		}
+		req.Date = date
+	}
+	for _, line := range payload.Lines {
+		req.Lines = append(req.Lines, ledger.VoucherLine{
+			AccountCode: line.AccountCode,
+			Abstract:    line.Abstract,
+			DebitCents:  line.DebitCents,
+			CreditCents: line.CreditCents,
+		})
+	}
+	voucher, err := svc.CreateVoucher(c.Request.Context(), req)
+	if err != nil {
+		writeLedgerError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[model.FinancialVoucher]{Success: true, Code: http.StatusCreated, Message: "Voucher created", Data: *voucher})
+}
+
+// voucherActionHandler 审核 / 驳回 / 过账
+// @Summary      凭证审核、驳回与过账
+// @Tags         Admin - Ledger
+// @Produce      json
+// @Param        id  path  int  true  "凭证ID"
+// @Success      200  {object}  model.APIResponse[model.FinancialVoucher]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Failure      409  {object}  model.APIResponse[any]
+// @Router       /admin/ledger/vouchers/{id}/approve [post]
+// @Router       /admin/ledger/vouchers/{id}/reject [post]
+// @Router       /admin/ledger/vouchers/{id}/post [post]
+func voucherActionHandler(c *gin.Context, action func(ctx context.Context, id, actorID uint64) (*model.FinancialVoucher, error)) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid voucher ID")
+		return
+	}
+	voucher, err := action(c.Request.Context(), id, c.GetUint64("user_id"))
+	if err != nil {
+		writeLedgerError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[model.FinancialVoucher]{Success: true, Code: http.StatusOK, Message: "OK", Data: *voucher})
+}
+
+// reverseVoucherHandler 红字冲销
+// @Summary      红字冲销
+// @Description  为已过账凭证生成金额取负的冲销凭证（草稿），需审核后过账
+// @Tags         Admin - Ledger
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                    true  "凭证ID"
+// @Param        request  body  ReverseVoucherPayload  true  "冲销原因"
+// @Success      201  {object}  model.APIResponse[model.FinancialVoucher]
+// @Failure      409  {object}  model.APIResponse[any]
+// @Router       /admin/ledger/vouchers/{id}/reverse [post]
+func reverseVoucherHandler(c *gin.Context, svc *ledger.LedgerService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid voucher ID")
+		return
+	}
+	var payload ReverseVoucherPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	reversal, err := svc.ReverseVoucher(c.Request.Context(), id, c.GetUint64("user_id"), payload.Reason)
+	if err != nil {
+		writeLedgerError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[model.FinancialVoucher]{Success: true, Code: http.StatusCreated, Message: "Reversal voucher created", Data: *reversal})
+}
+
+// GenerateReportPayload 生成报表请求体
+type GenerateReportPayload struct {
+	Type        string `json:"type" binding:"required"`         // trial_balance | income_statement | balance_sheet | cash_flow
+	PeriodStart string `json:"period_start" binding:"required"` // YYYY-MM-DD
+	PeriodEnd   string `json:"period_end" binding:"required"`   // YYYY-MM-DD（含）
+	Currency    string `json:"currency"`                        // 报表币种，默认 CNY；外币分录按当前汇率折算
+}
+
+// ClosePeriodPayload 期末结账请求体
+type ClosePeriodPayload struct {
+	Period string `json:"period" binding:"required"` // YYYY-MM
+}
+
+// generateReportHandler 生成财务报表
+// @Summary      生成财务报表
+// @Description  根据期间内已过账的凭证分录生成报表并保存
+// @Tags         Admin - Ledger
+// @Accept       json
+// @Produce      json
+// @Param        request  body  GenerateReportPayload  true  "报表类型与期间"
+// @Success      201  {object}  model.APIResponse[model.FinancialReport]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Router       /admin/ledger/reports [post]
+func generateReportHandler(c *gin.Context, svc *ledger.LedgerService) {
+	var payload GenerateReportPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	start, err := time.ParseInLocation("2006-01-02", payload.PeriodStart, time.Local)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid period_start")
+		return
+	}
+	end, err := time.ParseInLocation("2006-01-02", payload.PeriodEnd, time.Local)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid period_end")
+		return
+	}
+	report, err := svc.GenerateReport(c.Request.Context(), ledger.GenerateReportRequest{
+		Type:        model.FinancialReportType(strings.ToLower(strings.TrimSpace(payload.Type))),
+		PeriodStart: start,
+		PeriodEnd:   end.AddDate(0, 0, 1),
+		Currency:    model.Currency(strings.ToUpper(strings.TrimSpace(payload.Currency))),
+		GeneratedBy: c.GetUint64("user_id"),
+	})
+	if err != nil {
+		writeLedgerError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[model.FinancialReport]{Success: true, Code: http.StatusCreated, Message: "Report generated", Data: *report})
+}
+
+// listReportsHandler 报表列表
+// @Summary      报表列表
+// @Tags         Admin - Ledger
+// @Produce      json
+// @Param        type       query  string  false  "报表类型"
+// @Param        page       query  int     false  "页码"
+// @Param        page_size  query  int     false  "每页数量"
+// @Success      200  {object}  model.APIResponse[[]model.FinancialReport]
+// @Router       /admin/ledger/reports [get]
+func listReportsHandler(c *gin.Context, svc *ledger.LedgerService) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	opts := ledgerrepo.ReportListOptions{Page: page, PageSize: pageSize}
+	if typ := strings.TrimSpace(c.Query("type")); typ != "" {
+		t := model.FinancialReportType(strings.ToLower(typ))
+		opts.Type = &t
+	}
+	reports, total, err := svc.ListReports(c.Request.Context(), opts)
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.FinancialReport]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       ensureSlice(reports),
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// getReportHandler 报表详情
+// @Summary      报表详情
+// @Tags         Admin - Ledger
+// @Produce      json
+// @Param        id  path  int  true  "报表ID"
+// @Success      200  {object}  model.APIResponse[model.FinancialReport]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/ledger/reports/{id} [get]
+func getReportHandler(c *gin.Context, svc *ledger.LedgerService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid report ID")
+		return
+	}
+	report, err := svc.GetReport(c.Request.Context(), id)
+	if err != nil {
+		writeLedgerError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[model.FinancialReport]{Success: true, Code: http.StatusOK, Message: "OK", Data: *report})
+}
+
+// exportReportHandler 导出报表
+// @Summary      导出报表
+// @Tags         Admin - Ledger
+// @Produce      octet-stream
+// @Param        id      path   int     true   "报表ID"
+// @Param        format  query  string  false  "csv（默认）| xlsx"
+// @Success      200  {file}  file
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/ledger/reports/{id}/export [get]
+func exportReportHandler(c *gin.Context, svc *ledger.LedgerService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid report ID")
+		return
+	}
+	file, err := svc.ExportReport(c.Request.Context(), id, c.Query("format"))
+	if err != nil {
+		writeLedgerError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=\""+file.Name+"\"")
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+// listPeriodsHandler 已结账期间
+// @Summary      已结账期间
+// @Tags         Admin - Ledger
+// @Produce      json
+// @Success      200  {object}  model.APIResponse[[]model.FinancialPeriod]
+// @Router       /admin/ledger/periods [get]
+func listPeriodsHandler(c *gin.Context, svc *ledger.LedgerService) {
+	periods, err := svc.ListPeriods(c.Request.Context())
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.FinancialPeriod]{Success: true, Code: http.StatusOK, Message: "OK", Data: ensureSlice(periods)})
+}
+
+// closePeriodHandler 期末结账
+// @Summary      期末结账
+// @Description  将收入、费用科目余额结转到本年利润并锁定该月，期间内不能有未过账凭证
+// @Tags         Admin - Ledger
+// @Accept       json
+// @Produce      json
+// @Param        request  body  ClosePeriodPayload  true  "会计期间"
+// @Success      201  {object}  model.APIResponse[model.FinancialPeriod]
+// @Failure      409  {object}  model.APIResponse[any]
+// @Router       /admin/ledger/periods/close [post]
+func closePeriodHandler(c *gin.Context, svc *ledger.LedgerService) {
+	var payload ClosePeriodPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	period, err := svc.ClosePeriod(c.Request.Context(), strings.TrimSpace(payload.Period), c.GetUint64("user_id"))
+	if err != nil {
+		writeLedgerError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[model.FinancialPeriod]{Success: true, Code: http.StatusCreated, Message: "Period closed", Data: *period})
+}
+
+func parseLedgerDateRange(c *gin.Context) (*time.Time, *time.Time, bool) {
+	dateFrom, err := queryTimePtr(c, "date_from")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, apierr.ErrInvalidDateFrom)
+		return nil, nil, false
+	}
+	dateTo, err := queryTimePtr(c, "date_to")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, apierr.ErrInvalidDateTo)
+		return nil, nil, false
+	}
+	return dateFrom, dateTo, true
+}
+
+func writeLedgerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		writeJSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, ledger.ErrValidation), errors.Is(err, ledger.ErrUnbalanced):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ledger.ErrSelfReview):
+		writeJSONError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, ledger.ErrInvalidStatus), errors.Is(err, ledger.ErrAlreadyReversed), errors.Is(err, ledger.ErrPeriodClosed):
+		writeJSONError(c, http.StatusConflict, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -69,6 +69,8 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		Currency:        model.Currency(strings.ToUpper(strings.TrimSpace(p.Currency))),
 		ScheduledStart:  start,
 		ScheduledEnd:    end,
+		ItemID:          p.ItemID,
+		Quantity:        p.Quantity,
 	})
 	if errors.Is(err, adminservice.ErrValidation) {
 		_ = c.Error(adminservice.ErrValidation)
@@ -91,6 +93,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 // @Param        request  body  AssignOrderPayload  true  "指派信息"
 // @Success      200  {object}  map[string]any
 // @Failure      404  {object}  map[string]any
+// @Failure      409  {object}  map[string]any
 // @Router       /admin/orders/{id}/assign [post]
 func (h *OrderHandler) AssignOrder(c *gin.Context) {
 	id, err := parseUintParam(c, "id")
@@ -108,6 +111,10 @@ func (h *OrderHandler) AssignOrder(c *gin.Context) {
 		_ = c.Error(adminservice.ErrValidation)
 		return
 	}
+	if errors.Is(err, adminservice.ErrBookingConflict) {
+		writeJSONError(c, http.StatusConflict, err.Error())
+		return
+	}
 	if errors.Is(err, adminservice.ErrNotFound) {
 		_ = c.Error(adminservice.ErrNotFound)
 		return
@@ -642,6 +649,8 @@ type CreateOrderPayload struct {
 	Currency        string  `json:"currency" binding:"required"`
 	ScheduledStart  *string `json:"scheduled_start"`
 	ScheduledEnd    *string `json:"scheduled_end"`
+	ItemID          uint64  `json:"item_id"`
+	Quantity        int     `json:"quantity" binding:"omitempty,min=1,max=99"`
 }
 
 // AssignOrderPayload defines player assignment.
@@ -29,6 +29,10 @@ func newFakePaymentRepo2() *fakePaymentRepo2 { return &fakePaymentRepo2{m: map[u
 func (r *fakePaymentRepo2) Create(ctx context.Context, p *model.Payment) error { if p.ID==0 { p.ID = uint64(len(r.m)+1) } ; r.m[p.ID]=p; return nil }
 func (r *fakePaymentRepo2) List(ctx context.Context, opts repository.PaymentListOptions) ([]model.Payment, int64, error) { out:=[]model.Payment{}; for _, v:= range r.m { if opts.OrderID!=nil && v.OrderID==*opts.OrderID { out = append(out, *v) } } ; return out, int64(len(out)), nil }
 func (r *fakePaymentRepo2) Get(ctx context.Context, id uint64) (*model.Payment, error) { v:=r.m[id]; if v==nil { return nil, repository.ErrNotFound } ; return v, nil }
+
+func (r *fakePaymentRepo2) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (r *fakePaymentRepo2) Update(ctx context.Context, p *model.Payment) error { r.m[p.ID]=p; return nil }
 func (r *fakePaymentRepo2) Delete(ctx context.Context, id uint64) error { delete(r.m, id); return nil }
 
@@ -27,6 +27,8 @@ type failPayRepo struct{}
 func (failPayRepo) Create(context.Context, *model.Payment) error { return nil }
 func (failPayRepo) List(context.Context, repository.PaymentListOptions) ([]model.Payment, int64, error) { return nil, 0, nil }
 func (failPayRepo) Get(context.Context, uint64) (*model.Payment, error) { return &model.Payment{Base: model.Base{ID:1}, Status:model.PaymentStatusPaid}, nil }
+
+func (failPayRepo) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (failPayRepo) Update(context.Context, *model.Payment) error { return nil }
 func (failPayRepo) Delete(context.Context, uint64) error { return nil }
 
@@ -78,6 +78,10 @@ func (f *fakePaymentRepoForHandler) Get(ctx context.Context, id uint64) (*model.
 	return nil, repository.ErrNotFound
 }
 
+func (f *fakePaymentRepoForHandler) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
+
 func (f *fakePaymentRepoForHandler) Update(ctx context.Context, p *model.Payment) error {
 	for i := range f.items {
 		if f.items[i].ID == p.ID {
@@ -0,0 +1,270 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	reconrepo "gamelink/internal/repository/reconciliation"
+	"gamelink/internal/service/reconciliation"
+)
+
+// maxBillFileSize 对账单文件大小上限
+const maxBillFileSize = 20 << 20
+
+// RegisterReconciliationRoutes 注册渠道对账管理路由
+func RegisterReconciliationRoutes(router gin.IRouter, svc *reconciliation.ReconciliationService) {
+	group := router.Group("/admin/reconciliations")
+	{
+		group.GET("", func(c *gin.Context) { listReconciliationsHandler(c, svc) })
+		group.POST("", func(c *gin.Context) { importReconciliationHandler(c, svc) })
+		group.GET("/:id", func(c *gin.Context) { getReconciliationHandler(c, svc) })
+		group.GET("/:id/details", func(c *gin.Context) { listReconciliationDetailsHandler(c, svc) })
+		group.POST("/:id/details/:detailId/resolve", func(c *gin.Context) { resolveReconciliationDetailHandler(c, svc) })
+	}
+}
+
+// ResolveReconciliationDetailPayload 异常处理请求体
+type ResolveReconciliationDetailPayload struct {
+	Resolution string `json:"resolution" binding:"required"`
+}
+
+// importReconciliationHandler 导入渠道对账单
+// @Summary      导入渠道对账单
+// @Description  上传微信/支付宝对账单 CSV（UTF-8），与平台支付单、退款单逐笔核对
+// @Tags         Admin - Reconciliation
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        provider      formData  string  true   "渠道 wechat | alipay"
+// @Param        period_start  formData  string  true   "账单开始日期 YYYY-MM-DD"
+// @Param        period_end    formData  string  false  "账单结束日期 YYYY-MM-DD（含），默认同开始日期"
+// @Param        file          formData  file    true   "对账单文件"
+// @Success      201  {object}  model.APIResponse[model.Reconciliation]
+// @Failure      400  {object}  model.APIResponse[any]
+// @Router       /admin/reconciliations [post]
+func importReconciliationHandler(c *gin.Context, svc *reconciliation.ReconciliationService) {
+	provider := model.PaymentMethod(strings.ToLower(strings.TrimSpace(c.PostForm("provider"))))
+	start, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(c.PostForm("period_start")), time.Local)
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid period_start")
+		return
+	}
+	end := start
+	if value := strings.TrimSpace(c.PostForm("period_end")); value != "" {
+		if end, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+			writeJSONError(c, http.StatusBadRequest, "Invalid period_end")
+			return
+		}
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Bill file required")
+		return
+	}
+	if file.Size > maxBillFileSize {
+		writeJSONError(c, http.StatusBadRequest, "Bill file too large")
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer f.Close()
+
+	rec, err := svc.Import(c.Request.Context(), reconciliation.ImportRequest{
+		Provider:    provider,
+		PeriodStart: start,
+		PeriodEnd:   end.AddDate(0, 0, 1),
+		FileName:    file.Filename,
+		Bill:        f,
+		OperatorID:  c.GetUint64("user_id"),
+	})
+	if err != nil {
+		writeReconciliationError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[model.Reconciliation]{Success: true, Code: http.StatusCreated, Message: "Reconciliation completed", Data: *rec})
+}
+
+// listReconciliationsHandler 对账单列表
+// @Summary      对账单列表
+// @Tags         Admin - Reconciliation
+// @Produce      json
+// @Param        provider   query  string  false  "渠道"
+// @Param        status     query  string  false  "状态 success | exception"
+// @Param        date_from  query  string  false  "账单开始日期下限"
+// @Param        date_to    query  string  false  "账单开始日期上限"
+// @Param        page       query  int     false  "页码"
+// @Param        page_size  query  int     false  "每页数量"
+// @Success      200  {object}  model.APIResponse[[]model.Reconciliation]
+// @Router       /admin/reconciliations [get]
+func listReconciliationsHandler(c *gin.Context, svc *reconciliation.ReconciliationService) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	dateFrom, dateTo, ok := parseLedgerDateRange(c)
+	if !ok {
+		return
+	}
+	opts := reconrepo.ReconciliationListOptions{
+		DateFrom: dateFrom,
+		DateTo:   dateTo,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if provider := strings.TrimSpace(c.Query("provider")); provider != "" {
+		p := model.PaymentMethod(strings.ToLower(provider))
+		opts.Provider = &p
+	}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		st := model.ReconciliationStatus(strings.ToLower(status))
+		opts.Status = &st
+	}
+	recs, total, err := svc.List(c.Request.Context(), opts)
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.Reconciliation]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       ensureSlice(recs),
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// getReconciliationHandler 对账单详情
+// @Summary      对账单详情
+// @Tags         Admin - Reconciliation
+// @Produce      json
+// @Param        id  path  int  true  "对账单ID"
+// @Success      200  {object}  model.APIResponse[model.Reconciliation]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/reconciliations/{id} [get]
+func getReconciliationHandler(c *gin.Context, svc *reconciliation.ReconciliationService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid reconciliation ID")
+		return
+	}
+	rec, err := svc.Get(c.Request.Context(), id)
+	if err != nil {
+		writeReconciliationError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[model.Reconciliation]{Success: true, Code: http.StatusOK, Message: "OK", Data: *rec})
+}
+
+// listReconciliationDetailsHandler 对账明细
+// @Summary      对账明细
+// @Tags         Admin - Reconciliation
+// @Produce      json
+// @Param        id               path   int     true   "对账单ID"
+// @Param        status           query  string  false  "matched | missing_internal | missing_external | amount_mismatch"
+// @Param        exceptions_only  query  bool    false  "仅异常明细"
+// @Param        resolved         query  bool    false  "是否已处理"
+// @Param        page             query  int     false  "页码"
+// @Param        page_size        query  int     false  "每页数量"
+// @Success      200  {object}  model.APIResponse[[]model.ReconciliationDetail]
+// @Router       /admin/reconciliations/{id}/details [get]
+func listReconciliationDetailsHandler(c *gin.Context, svc *reconciliation.ReconciliationService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid reconciliation ID")
+		return
+	}
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	opts := reconrepo.DetailListOptions{
+		ReconciliationID: id,
+		Status:           strings.ToLower(strings.TrimSpace(c.Query("status"))),
+		Page:             page,
+		PageSize:         pageSize,
+	}
+	if value := strings.TrimSpace(c.Query("exceptions_only")); value != "" {
+		if opts.ExceptionsOnly, err = strconv.ParseBool(value); err != nil {
+			writeJSONError(c, http.StatusBadRequest, "Invalid exceptions_only")
+			return
+		}
+	}
+	if value := strings.TrimSpace(c.Query("resolved")); value != "" {
+		resolved, err := strconv.ParseBool(value)
+		if err != nil {
+			writeJSONError(c, http.StatusBadRequest, "Invalid resolved")
+			return
+		}
+		opts.Resolved = &resolved
+	}
+	details, total, err := svc.ListDetails(c.Request.Context(), opts)
+	if err != nil {
+		writeReconciliationError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.ReconciliationDetail]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       ensureSlice(details),
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// resolveReconciliationDetailHandler 处理异常明细
+// @Summary      处理对账异常
+// @Description  记录处理说明；对账单的全部异常处理完后状态变为 success
+// @Tags         Admin - Reconciliation
+// @Accept       json
+// @Produce      json
+// @Param        id        path  int                                 true  "对账单ID"
+// @Param        detailId  path  int                                 true  "明细ID"
+// @Param        request   body  ResolveReconciliationDetailPayload  true  "处理说明"
+// @Success      200  {object}  model.APIResponse[model.ReconciliationDetail]
+// @Failure      409  {object}  model.APIResponse[any]
+// @Router       /admin/reconciliations/{id}/details/{detailId}/resolve [post]
+func resolveReconciliationDetailHandler(c *gin.Context, svc *reconciliation.ReconciliationService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid reconciliation ID")
+		return
+	}
+	detailID, err := parseUintParam(c, "detailId")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid detail ID")
+		return
+	}
+	var payload ResolveReconciliationDetailPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	detail, err := svc.ResolveDetail(c.Request.Context(), id, detailID, c.GetUint64("user_id"), payload.Resolution)
+	if err != nil {
+		writeReconciliationError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[model.ReconciliationDetail]{Success: true, Code: http.StatusOK, Message: "OK", Data: *detail})
+}
+
+func writeReconciliationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		writeJSONError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, reconciliation.ErrValidation):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, reconciliation.ErrInvalidStatus):
+		writeJSONError(c, http.StatusConflict, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
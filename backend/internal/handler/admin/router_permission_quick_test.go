@@ -96,6 +96,8 @@ type routerPayments struct{}
 func (routerPayments) Create(_ context.Context, _ *model.Payment) error { return nil }
 func (routerPayments) List(_ context.Context, _ repository.PaymentListOptions) ([]model.Payment, int64, error) { return nil, 0, nil }
 func (routerPayments) Get(_ context.Context, _ uint64) (*model.Payment, error) { return nil, repository.ErrNotFound }
+
+func (routerPayments) GetByOutTradeNo(_ context.Context, _ string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (routerPayments) Update(_ context.Context, _ *model.Payment) error { return nil }
 func (routerPayments) Delete(_ context.Context, _ uint64) error { return nil }
 
@@ -76,6 +76,10 @@ type dummyPaymentRepo2 struct{}
 func (dummyPaymentRepo2) Create(ctx context.Context, payment *model.Payment) error { return nil }
 func (dummyPaymentRepo2) List(ctx context.Context, opts repository.PaymentListOptions) ([]model.Payment, int64, error) { return nil, 0, nil }
 func (dummyPaymentRepo2) Get(ctx context.Context, id uint64) (*model.Payment, error) { return nil, repository.ErrNotFound }
+
+func (dummyPaymentRepo2) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (dummyPaymentRepo2) Update(ctx context.Context, payment *model.Payment) error { return nil }
 func (dummyPaymentRepo2) Delete(ctx context.Context, id uint64) error { return nil }
 
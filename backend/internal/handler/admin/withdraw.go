@@ -1,35 +1,35 @@
 package admin
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"gamelink/internal/model"
 	withdrawrepo "gamelink/internal/repository/withdraw"
+	withdrawservice "gamelink/internal/service/withdraw"
 )
 
-// WithdrawAdminService 提现管理服务接口
-type WithdrawAdminService interface {
-	GetWithdraws(ctx context.Context, opts withdrawrepo.WithdrawListOptions) ([]model.Withdraw, int64, error)
-	GetWithdraw(ctx context.Context, id uint64) (*model.Withdraw, error)
-	ApproveWithdraw(ctx context.Context, id uint64, adminID uint64, remark string) error
-	RejectWithdraw(ctx context.Context, id uint64, adminID uint64, reason string) error
-	CompleteWithdraw(ctx context.Context, id uint64, adminID uint64) error
-}
-
-// RegisterWithdrawRoutes 注册管理端提现管理路由
-func RegisterWithdrawRoutes(router gin.IRouter, withdrawRepo withdrawrepo.WithdrawRepository) {
+// RegisterWithdrawRoutes 注册管理端提现管理与打款批次路由
+func RegisterWithdrawRoutes(router gin.IRouter, svc *withdrawservice.WithdrawService) {
 	group := router.Group("/admin/withdraws")
 	{
-		group.GET("", func(c *gin.Context) { listWithdrawsHandler(c, withdrawRepo) })
-		group.GET("/:id", func(c *gin.Context) { getWithdrawHandler(c, withdrawRepo) })
-		group.POST("/:id/approve", func(c *gin.Context) { approveWithdrawHandler(c, withdrawRepo) })
-		group.POST("/:id/reject", func(c *gin.Context) { rejectWithdrawHandler(c, withdrawRepo) })
-		group.POST("/:id/complete", func(c *gin.Context) { completeWithdrawHandler(c, withdrawRepo) })
+		group.GET("", func(c *gin.Context) { listWithdrawsHandler(c, svc) })
+		group.GET("/:id", func(c *gin.Context) { getWithdrawHandler(c, svc) })
+		group.POST("/:id/approve", func(c *gin.Context) { approveWithdrawHandler(c, svc) })
+		group.POST("/:id/reject", func(c *gin.Context) { rejectWithdrawHandler(c, svc) })
+		group.POST("/:id/complete", func(c *gin.Context) { completeWithdrawHandler(c, svc) })
+		group.POST("/:id/fail", func(c *gin.Context) { failWithdrawHandler(c, svc) })
+	}
+	batches := router.Group("/admin/withdraw-batches")
+	{
+		batches.POST("", func(c *gin.Context) { createWithdrawBatchHandler(c, svc) })
+		batches.GET("", func(c *gin.Context) { listWithdrawBatchesHandler(c, svc) })
+		batches.GET("/:id", func(c *gin.Context) { getWithdrawBatchHandler(c, svc) })
+		batches.GET("/:id/export", func(c *gin.Context) { exportWithdrawBatchHandler(c, svc) })
 	}
 }
 
@@ -48,7 +48,7 @@ func RegisterWithdrawRoutes(router gin.IRouter, withdrawRepo withdrawrepo.Withdr
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
 // @Router       /admin/withdraws [get]
-func listWithdrawsHandler(c *gin.Context, repo withdrawrepo.WithdrawRepository) {
+func listWithdrawsHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
 
@@ -70,7 +70,7 @@ func listWithdrawsHandler(c *gin.Context, repo withdrawrepo.WithdrawRepository)
 		}
 	}
 
-	withdraws, total, err := repo.List(c.Request.Context(), opts)
+	withdraws, total, err := svc.List(c.Request.Context(), opts)
 	if err != nil {
 		writeJSONError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -98,17 +98,16 @@ func listWithdrawsHandler(c *gin.Context, repo withdrawrepo.WithdrawRepository)
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
 // @Router       /admin/withdraws/{id} [get]
-func getWithdrawHandler(c *gin.Context, repo withdrawrepo.WithdrawRepository) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+func getWithdrawHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	id, err := parseUintParam(c, "id")
 	if err != nil {
 		writeJSONError(c, http.StatusBadRequest, "Invalid withdraw ID")
 		return
 	}
 
-	withdraw, err := repo.Get(c.Request.Context(), id)
+	withdraw, err := svc.Get(c.Request.Context(), id)
 	if err != nil {
-		writeJSONError(c, http.StatusNotFound, "Withdraw not found")
+		writeWithdrawError(c, err)
 		return
 	}
 
@@ -137,48 +136,27 @@ type ApproveWithdrawRequest struct {
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
 // @Router       /admin/withdraws/{id}/approve [post]
-func approveWithdrawHandler(c *gin.Context, repo withdrawrepo.WithdrawRepository) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+func approveWithdrawHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	id, err := parseUintParam(c, "id")
 	if err != nil {
 		writeJSONError(c, http.StatusBadRequest, "Invalid withdraw ID")
 		return
 	}
 
-	adminID, _ := c.Get("user_id")
-	adminUserID := adminID.(uint64)
-
 	var req ApproveWithdrawRequest
-	c.ShouldBindJSON(&req)
+	_ = c.ShouldBindJSON(&req)
 
-	withdraw, err := repo.Get(c.Request.Context(), id)
+	withdraw, err := svc.Approve(c.Request.Context(), id, c.GetUint64("user_id"), req.Remark)
 	if err != nil {
-		writeJSONError(c, http.StatusNotFound, "Withdraw not found")
-		return
-	}
-
-	// 只能审批待处理的提现
-	if withdraw.Status != model.WithdrawStatusPending {
-		writeJSONError(c, http.StatusBadRequest, "Can only approve pending withdraws")
-		return
-	}
-
-	// 更新状态
-	now := time.Now()
-	withdraw.Status = model.WithdrawStatusApproved
-	withdraw.ProcessedBy = &adminUserID
-	withdraw.ProcessedAt = &now
-	withdraw.AdminRemark = req.Remark
-
-	if err := repo.Update(c.Request.Context(), withdraw); err != nil {
-		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		writeWithdrawError(c, err)
 		return
 	}
 
-	writeJSON(c, http.StatusOK, model.APIResponse[any]{
+	writeJSON(c, http.StatusOK, model.APIResponse[model.Withdraw]{
 		Success: true,
 		Code:    http.StatusOK,
 		Message: "Withdraw approved successfully",
+		Data:    *withdraw,
 	})
 }
 
@@ -189,114 +167,252 @@ type RejectWithdrawRequest struct {
 
 // rejectWithdrawHandler 拒绝提现
 // @Summary      拒绝提现
-// @Description  管理员拒绝提现申�?// @Tags         Admin - Withdraw
+// @Description  管理员拒绝提现申请，冻结金额退回陪玩师可提现余额
+// @Tags         Admin - Withdraw
 // @Accept       json
 // @Produce      json
 // @Param        Authorization  header    string                  true  "Bearer {token}"
 // @Param        id             path      int                     true  "提现ID"
 // @Param        request        body      RejectWithdrawRequest  true  "拒绝原因"
-// @Success      200            {object}  model.APIResponse[any]
+// @Success      200            {object}  model.APIResponse[model.Withdraw]
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
 // @Router       /admin/withdraws/{id}/reject [post]
-func rejectWithdrawHandler(c *gin.Context, repo withdrawrepo.WithdrawRepository) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+func rejectWithdrawHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	id, err := parseUintParam(c, "id")
 	if err != nil {
 		writeJSONError(c, http.StatusBadRequest, "Invalid withdraw ID")
 		return
 	}
 
-	adminID, _ := c.Get("user_id")
-	adminUserID := adminID.(uint64)
-
 	var req RejectWithdrawRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		writeJSONError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	withdraw, err := repo.Get(c.Request.Context(), id)
+	withdraw, err := svc.Reject(c.Request.Context(), id, c.GetUint64("user_id"), req.Reason)
 	if err != nil {
-		writeJSONError(c, http.StatusNotFound, "Withdraw not found")
+		writeWithdrawError(c, err)
 		return
 	}
 
-	// 只能审批待处理的提现
-	if withdraw.Status != model.WithdrawStatusPending {
-		writeJSONError(c, http.StatusBadRequest, "Can only reject pending withdraws")
-		return
-	}
-
-	// 更新状态
-	now := time.Now()
-	withdraw.Status = model.WithdrawStatusRejected
-	withdraw.ProcessedBy = &adminUserID
-	withdraw.ProcessedAt = &now
-	withdraw.RejectReason = req.Reason
-
-	if err := repo.Update(c.Request.Context(), withdraw); err != nil {
-		writeJSONError(c, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	writeJSON(c, http.StatusOK, model.APIResponse[any]{
+	writeJSON(c, http.StatusOK, model.APIResponse[model.Withdraw]{
 		Success: true,
 		Code:    http.StatusOK,
 		Message: "Withdraw rejected",
+		Data:    *withdraw,
 	})
 }
 
-// completeWithdrawHandler 完成提现（已打款�?// @Summary      完成提现
-// @Description  管理员标记提现已完成（已打款�?// @Tags         Admin - Withdraw
+// completeWithdrawHandler 完成提现（已打款）
+// @Summary      完成提现
+// @Description  管理员确认提现已打款成功（线下打款或银行文件批次）
+// @Tags         Admin - Withdraw
 // @Accept       json
 // @Produce      json
 // @Param        Authorization  header    string  true  "Bearer {token}"
 // @Param        id             path      int     true  "提现ID"
-// @Success      200            {object}  model.APIResponse[any]
+// @Success      200            {object}  model.APIResponse[model.Withdraw]
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
 // @Router       /admin/withdraws/{id}/complete [post]
-func completeWithdrawHandler(c *gin.Context, repo withdrawrepo.WithdrawRepository) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 64)
+func completeWithdrawHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	id, err := parseUintParam(c, "id")
 	if err != nil {
 		writeJSONError(c, http.StatusBadRequest, "Invalid withdraw ID")
 		return
 	}
 
-	adminID, _ := c.Get("user_id")
-	adminUserID := adminID.(uint64)
-
-	withdraw, err := repo.Get(c.Request.Context(), id)
+	withdraw, err := svc.Complete(c.Request.Context(), id, c.GetUint64("user_id"))
 	if err != nil {
-		writeJSONError(c, http.StatusNotFound, "Withdraw not found")
+		writeWithdrawError(c, err)
 		return
 	}
 
-	// 只能完成已批准的提现
-	if withdraw.Status != model.WithdrawStatusApproved {
-		writeJSONError(c, http.StatusBadRequest, "Can only complete approved withdraws")
+	writeJSON(c, http.StatusOK, model.APIResponse[model.Withdraw]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "Withdraw completed successfully",
+		Data:    *withdraw,
+	})
+}
+
+// FailWithdrawRequest 打款失败请求
+type FailWithdrawRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// failWithdrawHandler 标记打款失败
+// @Summary      标记打款失败
+// @Description  银行退票等打款失败时使用，冻结金额退回陪玩师可提现余额
+// @Tags         Admin - Withdraw
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string               true  "Bearer {token}"
+// @Param        id             path      int                  true  "提现ID"
+// @Param        request        body      FailWithdrawRequest  true  "失败原因"
+// @Success      200            {object}  model.APIResponse[model.Withdraw]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Router       /admin/withdraws/{id}/fail [post]
+func failWithdrawHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid withdraw ID")
 		return
 	}
 
-	// 更新状态
-	now := time.Now()
-	withdraw.Status = model.WithdrawStatusCompleted
-	withdraw.CompletedAt = &now
-	if withdraw.ProcessedBy == nil {
-		withdraw.ProcessedBy = &adminUserID
+	var req FailWithdrawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if err := repo.Update(c.Request.Context(), withdraw); err != nil {
-		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	withdraw, err := svc.Fail(c.Request.Context(), id, c.GetUint64("user_id"), req.Reason)
+	if err != nil {
+		writeWithdrawError(c, err)
 		return
 	}
 
-	writeJSON(c, http.StatusOK, model.APIResponse[any]{
+	writeJSON(c, http.StatusOK, model.APIResponse[model.Withdraw]{
 		Success: true,
 		Code:    http.StatusOK,
-		Message: "Withdraw completed successfully",
+		Message: "Withdraw marked as failed",
+		Data:    *withdraw,
 	})
 }
 
+// CreateWithdrawBatchPayload 生成打款批次请求体
+type CreateWithdrawBatchPayload struct {
+	Channel  string `json:"channel" binding:"required"`
+	Currency string `json:"currency"` // 批次币种，默认 CNY
+	Method   string `json:"method"`
+	Limit    int    `json:"limit"`
+}
+
+// createWithdrawBatchHandler 生成打款批次
+// @Summary      生成打款批次
+// @Description  将已审核的提现纳入批次：bank_file 导出银行代付文件线下打款，provider 由调度器自动提交代付渠道
+// @Tags         Admin - Withdraw
+// @Accept       json
+// @Produce      json
+// @Param        request  body      CreateWithdrawBatchPayload  true  "批次参数"
+// @Success      201      {object}  model.APIResponse[withdrawservice.BatchDetail]
+// @Failure      400      {object}  model.APIResponse[any]
+// @Router       /admin/withdraw-batches [post]
+func createWithdrawBatchHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	var payload CreateWithdrawBatchPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	req := withdrawservice.CreateBatchRequest{
+		Channel:   model.PayoutChannel(strings.ToLower(strings.TrimSpace(payload.Channel))),
+		Currency:  model.Currency(strings.ToUpper(strings.TrimSpace(payload.Currency))),
+		Limit:     payload.Limit,
+		CreatedBy: c.GetUint64("user_id"),
+	}
+	if method := strings.TrimSpace(payload.Method); method != "" {
+		m := model.WithdrawMethod(strings.ToLower(method))
+		req.Method = &m
+	}
+	detail, err := svc.CreateBatch(c.Request.Context(), req)
+	if err != nil {
+		writeWithdrawError(c, err)
+		return
+	}
+	writeJSON(c, http.StatusCreated, model.APIResponse[withdrawservice.BatchDetail]{Success: true, Code: http.StatusCreated, Message: "created", Data: *detail})
+}
+
+// listWithdrawBatchesHandler 打款批次列表
+// @Summary      打款批次列表
+// @Tags         Admin - Withdraw
+// @Produce      json
+// @Param        status     query  string  false  "状态 processing | completed"
+// @Param        page       query  int     false  "页码"
+// @Param        page_size  query  int     false  "每页数量"
+// @Success      200  {object}  model.APIResponse[[]model.WithdrawBatch]
+// @Router       /admin/withdraw-batches [get]
+func listWithdrawBatchesHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	page, pageSize, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	opts := withdrawrepo.BatchListOptions{Page: page, PageSize: pageSize}
+	if status := strings.TrimSpace(c.Query("status")); status != "" {
+		st := model.WithdrawBatchStatus(strings.ToLower(status))
+		opts.Status = &st
+	}
+	batches, total, err := svc.ListBatches(c.Request.Context(), opts)
+	if err != nil {
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, model.APIResponse[[]model.WithdrawBatch]{
+		Success:    true,
+		Code:       http.StatusOK,
+		Message:    "OK",
+		Data:       ensureSlice(batches),
+		Pagination: newPagination(page, pageSize, total),
+	})
+}
+
+// getWithdrawBatchHandler 打款批次详情
+// @Summary      打款批次详情
+// @Tags         Admin - Withdraw
+// @Produce      json
+// @Param        id  path  int  true  "批次ID"
+// @Success      200  {object}  model.APIResponse[withdrawservice.BatchDetail]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/withdraw-batches/{id} [get]
+func getWithdrawBatchHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid batch ID")
+		return
+	}
+	detail, err := svc.GetBatch(c.Request.Context(), id)
+	if err != nil {
+		writeWithdrawError(c, err)
+		return
+	}
+	detail.Withdraws = ensureSlice(detail.Withdraws)
+	writeJSON(c, http.StatusOK, model.APIResponse[withdrawservice.BatchDetail]{Success: true, Code: http.StatusOK, Message: "OK", Data: *detail})
+}
+
+// exportWithdrawBatchHandler 导出银行代付文件
+// @Summary      导出银行代付文件
+// @Tags         Admin - Withdraw
+// @Produce      text/csv
+// @Param        id  path  int  true  "批次ID"
+// @Success      200  {file}  file
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /admin/withdraw-batches/{id}/export [get]
+func exportWithdrawBatchHandler(c *gin.Context, svc *withdrawservice.WithdrawService) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		writeJSONError(c, http.StatusBadRequest, "Invalid batch ID")
+		return
+	}
+	file, err := svc.ExportBatchFile(c.Request.Context(), id)
+	if err != nil {
+		writeWithdrawError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=\""+file.Name+"\"")
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
+
+func writeWithdrawError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, withdrawservice.ErrNotFound):
+		writeJSONError(c, http.StatusNotFound, "Withdraw not found")
+	case errors.Is(err, withdrawservice.ErrValidation), errors.Is(err, withdrawservice.ErrInvalidTransition):
+		writeJSONError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, withdrawservice.ErrPayoutNotConfigured):
+		writeJSONError(c, http.StatusConflict, err.Error())
+	default:
+		writeJSONError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -6,6 +6,7 @@ import (
     "net/http/httptest"
     "testing"
     "bytes"
+    "time"
 
     "github.com/gin-gonic/gin"
     "github.com/stretchr/testify/assert"
@@ -13,6 +14,7 @@ import (
     "gamelink/internal/model"
     "gamelink/internal/repository"
     withdrawrepo "gamelink/internal/repository/withdraw"
+    withdrawservice "gamelink/internal/service/withdraw"
 )
 
 type fakeWithdrawRepo struct{ items map[uint64]model.Withdraw }
@@ -22,11 +24,25 @@ func (f *fakeWithdrawRepo) Get(ctx context.Context, id uint64) (*model.Withdraw,
 func (f *fakeWithdrawRepo) Update(ctx context.Context, w *model.Withdraw) error { f.items[w.ID] = *w; return nil }
 func (f *fakeWithdrawRepo) List(ctx context.Context, _ withdrawrepo.WithdrawListOptions) ([]model.Withdraw, int64, error) { out := make([]model.Withdraw,0,len(f.items)); for _, v:= range f.items { out = append(out, v) } ; return out, int64(len(out)), nil }
 func (f *fakeWithdrawRepo) GetPlayerBalance(ctx context.Context, _ uint64) (*withdrawrepo.PlayerBalance, error) { return &withdrawrepo.PlayerBalance{}, nil }
+func (f *fakeWithdrawRepo) Transition(ctx context.Context, w *model.Withdraw, from model.WithdrawStatus) error { if f.items[w.ID].Status != from { return withdrawrepo.ErrStatusConflict }; f.items[w.ID] = *w; return nil }
+func (f *fakeWithdrawRepo) ListApproved(ctx context.Context, _ model.Currency, _ *model.WithdrawMethod, _ int) ([]model.Withdraw, error) { return nil, nil }
+func (f *fakeWithdrawRepo) ListDuePayouts(ctx context.Context, _ time.Time, _ int) ([]model.Withdraw, error) { return nil, nil }
+func (f *fakeWithdrawRepo) ListByBatch(ctx context.Context, _ uint64) ([]model.Withdraw, error) { return nil, nil }
+func (f *fakeWithdrawRepo) CreateBatch(ctx context.Context, _ *model.WithdrawBatch) error { return nil }
+func (f *fakeWithdrawRepo) GetBatch(ctx context.Context, _ uint64) (*model.WithdrawBatch, error) { return nil, repository.ErrNotFound }
+func (f *fakeWithdrawRepo) UpdateBatch(ctx context.Context, _ *model.WithdrawBatch) error { return nil }
+func (f *fakeWithdrawRepo) ListBatches(ctx context.Context, _ withdrawrepo.BatchListOptions) ([]model.WithdrawBatch, int64, error) { return nil, 0, nil }
+func (f *fakeWithdrawRepo) CountBatchStatuses(ctx context.Context, _ uint64) (map[model.WithdrawStatus]int64, error) { return nil, nil }
+
+type fakeWithdrawStore interface {
+    withdrawrepo.WithdrawRepository
+    withdrawrepo.PayoutRepository
+}
 
-func setupWithdrawRouter(repo withdrawrepo.WithdrawRepository) *gin.Engine {
+func setupWithdrawRouter(repo fakeWithdrawStore) *gin.Engine {
     r := newTestEngine()
     r.Use(func(c *gin.Context){ c.Set("user_id", uint64(1)); c.Next() })
-    RegisterWithdrawRoutes(r, repo)
+    RegisterWithdrawRoutes(r, withdrawservice.NewWithdrawService(repo, repo))
     return r
 }
 
@@ -70,7 +86,7 @@ func TestWithdraw_List_Get_Approve_Reject_Complete(t *testing.T) {
 }
 
 type errWithdrawRepo struct{ fakeWithdrawRepo }
-func (e *errWithdrawRepo) Update(ctx context.Context, w *model.Withdraw) error { return assert.AnError }
+func (e *errWithdrawRepo) Transition(ctx context.Context, w *model.Withdraw, from model.WithdrawStatus) error { return assert.AnError }
 
 func TestWithdraw_InvalidID_And_StatusErrors(t *testing.T) {
     repo := newFakeWithdrawRepo()
@@ -0,0 +1,146 @@
+package player
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/apierr"
+	"gamelink/internal/model"
+	"gamelink/internal/service/availability"
+)
+
+// RegisterAvailabilityRoutes 注册陪玩师端日程管理路由
+func RegisterAvailabilityRoutes(router gin.IRouter, svc *availability.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/player/availability")
+	group.Use(authMiddleware) // 需要认证
+	group.GET("", func(c *gin.Context) { getScheduleHandler(c, svc) })
+	group.PUT("/weekly", func(c *gin.Context) { setWeeklyAvailabilityHandler(c, svc) })
+	group.POST("/blackouts", func(c *gin.Context) { addBlackoutHandler(c, svc) })
+	group.DELETE("/blackouts/:id", func(c *gin.Context) { removeBlackoutHandler(c, svc) })
+}
+
+// getScheduleHandler 获取我的日程
+// @Summary      获取我的日程
+// @Description  获取每周可接单时段与未结束的不可接单时段
+// @Tags         Player - Availability
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Success      200            {object}  model.APIResponse[availability.ScheduleResponse]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /player/availability [get]
+func getScheduleHandler(c *gin.Context, svc *availability.Service) {
+	resp, err := svc.GetSchedule(c.Request.Context(), getUserIDFromContext(c))
+	if err != nil {
+		respondAvailabilityError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[availability.ScheduleResponse]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    *resp,
+	})
+}
+
+// setWeeklyAvailabilityHandler 设置每周可接单时段
+// @Summary      设置每周可接单时段
+// @Description  整体替换每周模板；传空列表表示不限制接单时间
+// @Tags         Player - Availability
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                            true  "Bearer {token}"
+// @Param        request        body      availability.SetWeeklyRequest     true  "每周模板"
+// @Success      200            {object}  model.APIResponse[availability.ScheduleResponse]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Router       /player/availability/weekly [put]
+func setWeeklyAvailabilityHandler(c *gin.Context, svc *availability.Service) {
+	var req availability.SetWeeklyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp, err := svc.SetWeekly(c.Request.Context(), getUserIDFromContext(c), req)
+	if err != nil {
+		respondAvailabilityError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[availability.ScheduleResponse]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "日程已更新",
+		Data:    *resp,
+	})
+}
+
+// addBlackoutHandler 新增不可接单时段
+// @Summary      新增不可接单时段
+// @Tags         Player - Availability
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                          true  "Bearer {token}"
+// @Param        request        body      availability.BlackoutRequest    true  "不可接单时段"
+// @Success      200            {object}  model.APIResponse[model.PlayerBlackout]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Router       /player/availability/blackouts [post]
+func addBlackoutHandler(c *gin.Context, svc *availability.Service) {
+	var req availability.BlackoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	blackout, err := svc.AddBlackout(c.Request.Context(), getUserIDFromContext(c), req)
+	if err != nil {
+		respondAvailabilityError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[model.PlayerBlackout]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    *blackout,
+	})
+}
+
+// removeBlackoutHandler 删除不可接单时段
+// @Summary      删除不可接单时段
+// @Tags         Player - Availability
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "不可接单时段ID"
+// @Success      200            {object}  model.APIResponse[any]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /player/availability/blackouts/{id} [delete]
+func removeBlackoutHandler(c *gin.Context, svc *availability.Service) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	if err := svc.RemoveBlackout(c.Request.Context(), getUserIDFromContext(c), id); err != nil {
+		respondAvailabilityError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[any]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+	})
+}
+
+func respondAvailabilityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, availability.ErrValidation):
+		respondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, availability.ErrNotFound):
+		respondError(c, http.StatusNotFound, err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,58 @@
+package player
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/credit"
+)
+
+// RegisterCreditRoutes 注册陪玩师信用路由
+func RegisterCreditRoutes(router gin.IRouter, svc *credit.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/player/credit")
+	group.Use(authMiddleware) // 需要认证
+	group.GET("", func(c *gin.Context) { getMyCreditHandler(c, svc) })
+}
+
+// getMyCreditHandler 获取我的信用
+// @Summary      获取我的信用
+// @Description  信用分、生效等级及其接单与抽成策略，以及分页的信用变动记录（最新在前）
+// @Tags         Player - Credit
+// @Produce      json
+// @Param        Authorization  header    string  true   "Bearer {token}"
+// @Param        page           query     int     false  "页码"
+// @Param        pageSize       query     int     false  "每页数量"
+// @Success      200            {object}  model.APIResponse[map[string]any]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /player/credit [get]
+func getMyCreditHandler(c *gin.Context, svc *credit.Service) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	overview, total, err := svc.MyOverview(c.Request.Context(), getUserIDFromContext(c), page, pageSize)
+	if err != nil {
+		if errors.Is(err, credit.ErrNotFound) {
+			respondError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(c, http.StatusOK, model.APIResponse[map[string]any]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data: map[string]any{
+			"credit": overview.Credit,
+			"policy": overview.Policy,
+			"events": overview.Events,
+			"total":  total,
+		},
+	})
+}
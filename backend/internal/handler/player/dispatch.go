@@ -0,0 +1,117 @@
+package player
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/apierr"
+	"gamelink/internal/model"
+	"gamelink/internal/service/availability"
+	"gamelink/internal/service/dispatch"
+)
+
+// RegisterDispatchRoutes 注册陪玩师端派单邀请路由
+func RegisterDispatchRoutes(router gin.IRouter, svc *dispatch.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/player/dispatch/offers")
+	group.Use(authMiddleware) // 需要认证
+	group.GET("", func(c *gin.Context) { listDispatchOffersHandler(c, svc) })
+	group.POST("/:id/accept", func(c *gin.Context) { acceptDispatchOfferHandler(c, svc) })
+	group.POST("/:id/decline", func(c *gin.Context) { declineDispatchOfferHandler(c, svc) })
+}
+
+// listDispatchOffersHandler 我的派单邀请
+// @Summary      我的派单邀请
+// @Description  列出推送给我的派单邀请（最新在前）
+// @Tags         Player - Dispatch
+// @Produce      json
+// @Param        Authorization  header    string  true   "Bearer {token}"
+// @Param        status         query     string  false  "pending/accepted/declined/expired/canceled"
+// @Success      200            {object}  model.APIResponse[[]model.DispatchOffer]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /player/dispatch/offers [get]
+func listDispatchOffersHandler(c *gin.Context, svc *dispatch.Service) {
+	status := model.DispatchOfferStatus(c.Query("status"))
+	offers, err := svc.ListMyOffers(c.Request.Context(), getUserIDFromContext(c), status)
+	if err != nil {
+		respondDispatchError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[[]model.DispatchOffer]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    offers,
+	})
+}
+
+// acceptDispatchOfferHandler 接受派单邀请
+// @Summary      接受派单邀请
+// @Description  接受后订单指派给我并开始服务，其余陪玩师的邀请随之取消
+// @Tags         Player - Dispatch
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "邀请ID"
+// @Success      200            {object}  model.APIResponse[model.OrderAssignment]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]  "邀请已失效或时间冲突"
+// @Router       /player/dispatch/offers/{id}/accept [post]
+func acceptDispatchOfferHandler(c *gin.Context, svc *dispatch.Service) {
+	offerID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	assignment, err := svc.AcceptOffer(c.Request.Context(), getUserIDFromContext(c), offerID)
+	if err != nil {
+		respondDispatchError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[model.OrderAssignment]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "接单成功",
+		Data:    *assignment,
+	})
+}
+
+// declineDispatchOfferHandler 拒绝派单邀请
+// @Summary      拒绝派单邀请
+// @Tags         Player - Dispatch
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "邀请ID"
+// @Success      200            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]
+// @Router       /player/dispatch/offers/{id}/decline [post]
+func declineDispatchOfferHandler(c *gin.Context, svc *dispatch.Service) {
+	offerID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	if err := svc.DeclineOffer(c.Request.Context(), getUserIDFromContext(c), offerID); err != nil {
+		respondDispatchError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[any]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "已拒绝",
+	})
+}
+
+func respondDispatchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, dispatch.ErrNotFound):
+		respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, dispatch.ErrOfferClosed), errors.Is(err, availability.ErrBookingConflict):
+		respondError(c, http.StatusConflict, err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -1,6 +1,7 @@
 package player
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -19,6 +20,7 @@ func RegisterEarningsRoutes(router gin.IRouter, svc *earnings.EarningsService, a
     group.GET("/trend", func(c *gin.Context) { getEarningsTrendHandler(c, svc) })
     group.POST("/withdraw", func(c *gin.Context) { requestWithdrawHandler(c, svc) })
     group.GET("/withdraw-history", func(c *gin.Context) { getWithdrawHistoryHandler(c, svc) })
+    group.GET("/wallet-transactions", func(c *gin.Context) { getWalletTransactionsHandler(c, svc) })
 }
 
 // getEarningsSummaryHandler 获取收益概览
@@ -110,6 +112,10 @@ func requestWithdrawHandler(c *gin.Context, svc *earnings.EarningsService) {
 			respondError(c, http.StatusBadRequest, err.Error())
 			return
 		}
+		if errors.Is(err, earnings.ErrWalletBusy) {
+			respondError(c, http.StatusConflict, err.Error())
+			return
+		}
 		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -154,3 +160,35 @@ func getWithdrawHistoryHandler(c *gin.Context, svc *earnings.EarningsService) {
 		Data:    *resp,
 	})
 }
+
+// getWalletTransactionsHandler 获取钱包流水
+// @Summary      获取钱包流水
+// @Description  收入入账、月结、提现冻结/退回/打款等余额变动明细
+// @Tags         Player - Earnings
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string  true   "Bearer {token}"
+// @Param        page           query     int     false  "页码"
+// @Param        pageSize       query     int     false  "每页数量"
+// @Success      200            {object}  model.APIResponse[earnings.WalletTransactionsResponse]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Router       /player/earnings/wallet-transactions [get]
+func getWalletTransactionsHandler(c *gin.Context, svc *earnings.EarningsService) {
+	userID := getUserIDFromContext(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+
+	resp, err := svc.GetWalletTransactions(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(c, http.StatusOK, model.APIResponse[earnings.WalletTransactionsResponse]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    *resp,
+	})
+}
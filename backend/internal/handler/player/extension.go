@@ -0,0 +1,114 @@
+package player
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/apierr"
+	"gamelink/internal/model"
+	"gamelink/internal/service/extension"
+)
+
+// RegisterExtensionRoutes 注册陪玩师端订单加时路由
+func RegisterExtensionRoutes(router gin.IRouter, svc *extension.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/player/orders")
+	group.Use(authMiddleware) // 需要认证
+	group.GET("/:id/extensions", func(c *gin.Context) { listOrderExtensionsHandler(c, svc) })
+	group.PUT("/:id/extensions/:extensionId/respond", func(c *gin.Context) { respondOrderExtensionHandler(c, svc) })
+}
+
+// listOrderExtensionsHandler 订单加时记录
+// @Summary      订单加时记录
+// @Tags         Player - Orders
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "订单ID"
+// @Success      200            {object}  model.APIResponse[[]model.OrderExtension]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /player/orders/{id}/extensions [get]
+func listOrderExtensionsHandler(c *gin.Context, svc *extension.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	exts, err := svc.ListExtensions(c.Request.Context(), getUserIDFromContext(c), orderID)
+	if err != nil {
+		respondExtensionError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[[]model.OrderExtension]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    exts,
+	})
+}
+
+// respondOrderExtensionHandler 处理加时申请
+// @Summary      处理加时申请
+// @Description  服务陪玩师同意或拒绝用户的加时申请；同意时加时后的时段不能与其他订单重叠
+// @Tags         Player - Orders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                             true  "Bearer {token}"
+// @Param        id             path      int                                true  "订单ID"
+// @Param        extensionId    path      int                                true  "加时申请ID"
+// @Param        request        body      extension.RespondExtensionRequest  true  "处理结果"
+// @Success      200            {object}  model.APIResponse[model.OrderExtension]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]
+// @Router       /player/orders/{id}/extensions/{extensionId}/respond [put]
+func respondOrderExtensionHandler(c *gin.Context, svc *extension.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	extensionID, err := strconv.ParseUint(c.Param("extensionId"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	var req extension.RespondExtensionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	ext, err := svc.RespondExtension(c.Request.Context(), getUserIDFromContext(c), orderID, extensionID, req)
+	if err != nil {
+		respondExtensionError(c, err)
+		return
+	}
+	message := "已拒绝加时"
+	if req.Accept {
+		message = "已同意加时，等待用户补款"
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[model.OrderExtension]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    *ext,
+	})
+}
+
+func respondExtensionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, extension.ErrNotFound):
+		respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, extension.ErrValidation):
+		respondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, extension.ErrForbidden), errors.Is(err, extension.ErrNotPlayer):
+		respondError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, extension.ErrOrderNotInProgress), errors.Is(err, extension.ErrInvalidStatus),
+		errors.Is(err, extension.ErrBookingConflict):
+		respondError(c, http.StatusConflict, err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -34,6 +34,7 @@ func RegisterOrderRoutes(router gin.IRouter, svc *order.OrderService, authMiddle
 // @Success      200            {object}  model.APIResponse[map[string]any]
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]  "信用等级不允许抢单"
 // @Router       /player/orders/available [get]
 func getAvailableOrdersHandler(c *gin.Context, svc *order.OrderService) {
 	var req order.AvailableOrdersRequest
@@ -41,8 +42,13 @@ func getAvailableOrdersHandler(c *gin.Context, svc *order.OrderService) {
 		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	req.PlayerUserID = getUserIDFromContext(c)
 
 	orders, total, err := svc.GetAvailableOrders(c.Request.Context(), req)
+	if err == order.ErrCreditRestricted {
+		respondError(c, http.StatusForbidden, err.Error())
+		return
+	}
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, err.Error())
 		return
@@ -69,6 +75,9 @@ func getAvailableOrdersHandler(c *gin.Context, svc *order.OrderService) {
 // @Success      200            {object}  model.APIResponse[any]
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]  "信用等级不允许接该订单"
+// @Failure      409            {object}  model.APIResponse[any]  "订单已被其他陪玩师接走"
+// @Failure      503            {object}  model.APIResponse[any]  "订单正在被其他陪玩师接单，稍后重试"
 // @Router       /player/orders/{id}/accept [post]
 func acceptOrderHandler(c *gin.Context, svc *order.OrderService) {
 	userID := getUserIDFromContext(c)
@@ -85,6 +94,19 @@ func acceptOrderHandler(c *gin.Context, svc *order.OrderService) {
 			respondError(c, http.StatusBadRequest, err.Error())
 			return
 		}
+		if err == order.ErrOrderTaken || err == order.ErrOrderReserved {
+			respondError(c, http.StatusConflict, err.Error())
+			return
+		}
+		if err == order.ErrCreditRestricted {
+			respondError(c, http.StatusForbidden, err.Error())
+			return
+		}
+		if err == order.ErrOrderBusy {
+			c.Header("Retry-After", "1")
+			respondError(c, http.StatusServiceUnavailable, err.Error())
+			return
+		}
 		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -130,13 +152,15 @@ func getMyAcceptedOrdersHandler(c *gin.Context, svc *order.OrderService) {
 	})
 }
 
-// completeOrderByPlayerHandler 完成订单（陪玩师端）
-// @Summary      完成订单
-// @Description  陪玩师完成订�?// @Tags         Player - Orders
+// completeOrderByPlayerHandler 提交完成（陪玩师端）
+// @Summary      提交完成
+// @Description  陪玩师提交订单完成，可附完成凭证截图；订单进入待确认，用户确认或确认窗口到期后完成
+// @Tags         Player - Orders
 // @Accept       json
 // @Produce      json
-// @Param        Authorization  header    string  true  "Bearer {token}"
-// @Param        id             path      int     true  "订单ID"
+// @Param        Authorization  header    string                         true   "Bearer {token}"
+// @Param        id             path      int                            true   "订单ID"
+// @Param        request        body      order.SubmitCompletionRequest  false  "完成凭证"
 // @Success      200            {object}  model.APIResponse[any]
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
@@ -151,7 +175,15 @@ func completeOrderByPlayerHandler(c *gin.Context, svc *order.OrderService) {
 		return
 	}
 
-	if err := svc.CompleteOrderByPlayer(c.Request.Context(), userID, orderID); err != nil {
+	var req order.SubmitCompletionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := svc.CompleteOrderByPlayer(c.Request.Context(), userID, orderID, req); err != nil {
 		if err == order.ErrUnauthorized {
 			respondError(c, http.StatusForbidden, err.Error())
 			return
@@ -167,6 +199,6 @@ func completeOrderByPlayerHandler(c *gin.Context, svc *order.OrderService) {
 	respondJSON(c, http.StatusOK, model.APIResponse[any]{
 		Success: true,
 		Code:    http.StatusOK,
-		Message: "订单已完成",
+		Message: "已提交完成，等待用户确认",
 	})
 }
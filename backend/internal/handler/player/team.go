@@ -0,0 +1,398 @@
+package player
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/apierr"
+	"gamelink/internal/model"
+	"gamelink/internal/service/orderstate"
+	"gamelink/internal/service/team"
+)
+
+// RegisterTeamRoutes 注册陪玩师端车队与车队订单路由
+func RegisterTeamRoutes(router gin.IRouter, svc *team.Service, authMiddleware gin.HandlerFunc) {
+	teams := router.Group("/player/teams")
+	teams.Use(authMiddleware) // 需要认证
+	teams.POST("", func(c *gin.Context) { createTeamHandler(c, svc) })
+	teams.GET("", func(c *gin.Context) { listMyTeamsHandler(c, svc) })
+	teams.GET("/:id", func(c *gin.Context) { getTeamHandler(c, svc) })
+	teams.POST("/:id/members", func(c *gin.Context) { addTeamMemberHandler(c, svc) })
+	teams.DELETE("/:id/members/:memberId", func(c *gin.Context) { removeTeamMemberHandler(c, svc) })
+
+	orders := router.Group("/player/team-orders")
+	orders.Use(authMiddleware)
+	orders.GET("/pool", func(c *gin.Context) { listTeamPoolHandler(c, svc) })
+	orders.GET("/:id/assignment", func(c *gin.Context) { getTeamAssignmentHandler(c, svc) })
+	orders.POST("/:id/snatch", func(c *gin.Context) { snatchTeamOrderHandler(c, svc) })
+	orders.PUT("/:id/lineup", func(c *gin.Context) { setTeamLineupHandler(c, svc) })
+	orders.POST("/:id/confirm", func(c *gin.Context) { confirmTeamAssignmentHandler(c, svc) })
+	orders.PUT("/:id/payout-plan", func(c *gin.Context) { upsertTeamPayoutPlanHandler(c, svc) })
+}
+
+// createTeamHandler 创建车队
+// @Summary      创建车队
+// @Description  已认证陪玩师创建车队并成为队长
+// @Tags         Player - Teams
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer {token}"
+// @Param        request        body      team.CreateTeamRequest  true  "车队信息"
+// @Success      200            {object}  model.APIResponse[team.TeamDetail]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Router       /player/teams [post]
+func createTeamHandler(c *gin.Context, svc *team.Service) {
+	var req team.CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	detail, err := svc.CreateTeam(c.Request.Context(), getUserIDFromContext(c), req)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[team.TeamDetail]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "车队创建成功",
+		Data:    *detail,
+	})
+}
+
+// listMyTeamsHandler 我的车队
+// @Summary      我的车队
+// @Tags         Player - Teams
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Success      200            {object}  model.APIResponse[[]model.Team]
+// @Router       /player/teams [get]
+func listMyTeamsHandler(c *gin.Context, svc *team.Service) {
+	teams, err := svc.ListMyTeams(c.Request.Context(), getUserIDFromContext(c))
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[[]model.Team]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    teams,
+	})
+}
+
+// getTeamHandler 车队详情
+// @Summary      车队详情
+// @Description  车队信息与在队成员，仅车队成员可查看
+// @Tags         Player - Teams
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "车队ID"
+// @Success      200            {object}  model.APIResponse[team.TeamDetail]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /player/teams/{id} [get]
+func getTeamHandler(c *gin.Context, svc *team.Service) {
+	teamID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	detail, err := svc.GetTeam(c.Request.Context(), getUserIDFromContext(c), teamID)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[team.TeamDetail]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    *detail,
+	})
+}
+
+// addTeamMemberHandler 添加车队成员
+// @Summary      添加车队成员
+// @Description  队长添加已认证陪玩师为车队成员
+// @Tags         Player - Teams
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                 true  "Bearer {token}"
+// @Param        id             path      int                    true  "车队ID"
+// @Param        request        body      team.AddMemberRequest  true  "成员信息"
+// @Success      200            {object}  model.APIResponse[model.TeamMember]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Router       /player/teams/{id}/members [post]
+func addTeamMemberHandler(c *gin.Context, svc *team.Service) {
+	teamID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	var req team.AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	member, err := svc.AddMember(c.Request.Context(), getUserIDFromContext(c), teamID, req)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[model.TeamMember]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "已添加成员",
+		Data:    *member,
+	})
+}
+
+// removeTeamMemberHandler 移出或退出车队
+// @Summary      移出车队成员
+// @Description  队长移出成员，或成员本人退出车队
+// @Tags         Player - Teams
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "车队ID"
+// @Param        memberId       path      int     true  "成员ID"
+// @Success      200            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /player/teams/{id}/members/{memberId} [delete]
+func removeTeamMemberHandler(c *gin.Context, svc *team.Service) {
+	teamID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	memberID, err := strconv.ParseUint(c.Param("memberId"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	if err := svc.RemoveMember(c.Request.Context(), getUserIDFromContext(c), teamID, memberID); err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[any]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "已移出车队",
+	})
+}
+
+// listTeamPoolHandler 车队大厅
+// @Summary      车队大厅
+// @Description  已支付、尚未被车队抢走的车队订单（最早在前）
+// @Tags         Player - Team Orders
+// @Produce      json
+// @Param        Authorization  header    string  true   "Bearer {token}"
+// @Param        gameId         query     int     false  "游戏ID"
+// @Param        page           query     int     false  "页码"
+// @Param        pageSize       query     int     false  "每页数量"
+// @Success      200            {object}  model.APIResponse[map[string]any]
+// @Router       /player/team-orders/pool [get]
+func listTeamPoolHandler(c *gin.Context, svc *team.Service) {
+	var req team.PoolRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	orders, total, err := svc.ListPool(c.Request.Context(), req)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[map[string]any]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data: map[string]any{
+			"orders": orders,
+			"total":  total,
+		},
+	})
+}
+
+// getTeamAssignmentHandler 车队接单详情
+// @Summary      车队接单详情
+// @Description  出车成员、确认状态与分配方式，车队成员可查看
+// @Tags         Player - Team Orders
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "订单ID"
+// @Success      200            {object}  model.APIResponse[team.AssignmentDetail]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /player/team-orders/{id}/assignment [get]
+func getTeamAssignmentHandler(c *gin.Context, svc *team.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	detail, err := svc.GetAssignment(c.Request.Context(), getUserIDFromContext(c), orderID)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondAssignment(c, "OK", detail)
+}
+
+// snatchTeamOrderHandler 车队抢单
+// @Summary      车队抢单
+// @Description  队长为车队抢单，须在组队时限内设置出车成员并由成员确认，超时订单释放回车队大厅
+// @Tags         Player - Team Orders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string              true  "Bearer {token}"
+// @Param        id             path      int                 true  "订单ID"
+// @Param        request        body      team.SnatchRequest  true  "抢单车队"
+// @Success      200            {object}  model.APIResponse[model.TeamOrderAssignment]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]  "订单已被其他车队抢走"
+// @Router       /player/team-orders/{id}/snatch [post]
+func snatchTeamOrderHandler(c *gin.Context, svc *team.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	var req team.SnatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	assignment, err := svc.SnatchOrder(c.Request.Context(), getUserIDFromContext(c), orderID, req)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[model.TeamOrderAssignment]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "抢单成功",
+		Data:    *assignment,
+	})
+}
+
+// setTeamLineupHandler 设置出车成员
+// @Summary      设置出车成员
+// @Description  队长设置出车成员，人数须与订单所需人数一致且包含队长
+// @Tags         Player - Team Orders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string              true  "Bearer {token}"
+// @Param        id             path      int                 true  "订单ID"
+// @Param        request        body      team.LineupRequest  true  "出车成员"
+// @Success      200            {object}  model.APIResponse[team.AssignmentDetail]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]
+// @Router       /player/team-orders/{id}/lineup [put]
+func setTeamLineupHandler(c *gin.Context, svc *team.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	var req team.LineupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	detail, err := svc.SetLineup(c.Request.Context(), getUserIDFromContext(c), orderID, req)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondAssignment(c, "出车成员已设置", detail)
+}
+
+// confirmTeamAssignmentHandler 出车成员确认
+// @Summary      确认出车
+// @Description  出车成员确认参与；全部确认后订单开始服务
+// @Tags         Player - Team Orders
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "订单ID"
+// @Success      200            {object}  model.APIResponse[team.AssignmentDetail]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]
+// @Router       /player/team-orders/{id}/confirm [post]
+func confirmTeamAssignmentHandler(c *gin.Context, svc *team.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	detail, err := svc.ConfirmAssignment(c.Request.Context(), getUserIDFromContext(c), orderID)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondAssignment(c, "已确认", detail)
+}
+
+// upsertTeamPayoutPlanHandler 设置收入分配方案
+// @Summary      设置收入分配方案
+// @Description  队长设置本单收入分配：equal 均分，custom 按比例（须覆盖全部出车成员且合计 100）
+// @Tags         Player - Team Orders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer {token}"
+// @Param        id             path      int                     true  "订单ID"
+// @Param        request        body      team.PayoutPlanRequest  true  "分配方案"
+// @Success      200            {object}  model.APIResponse[team.AssignmentDetail]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]
+// @Router       /player/team-orders/{id}/payout-plan [put]
+func upsertTeamPayoutPlanHandler(c *gin.Context, svc *team.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	var req team.PayoutPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	detail, err := svc.UpsertPayoutPlan(c.Request.Context(), getUserIDFromContext(c), orderID, req)
+	if err != nil {
+		respondTeamError(c, err)
+		return
+	}
+	respondAssignment(c, "分配方案已保存", detail)
+}
+
+func respondAssignment(c *gin.Context, msg string, detail *team.AssignmentDetail) {
+	respondJSON(c, http.StatusOK, model.APIResponse[team.AssignmentDetail]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: msg,
+		Data:    *detail,
+	})
+}
+
+func respondTeamError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, team.ErrNotFound):
+		respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, team.ErrValidation):
+		respondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, team.ErrForbidden), errors.Is(err, team.ErrNotPlayer):
+		respondError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, team.ErrOrderUnavailable), errors.Is(err, team.ErrAssignmentClosed),
+		errors.Is(err, orderstate.ErrStatusConflict):
+		respondError(c, http.StatusConflict, err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, err.Error())
+	}
+}
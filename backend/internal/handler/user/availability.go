@@ -0,0 +1,83 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/apierr"
+	"gamelink/internal/model"
+	"gamelink/internal/service/availability"
+)
+
+// RegisterAvailabilityRoutes 注册用户端陪玩师可预约时段路由
+func RegisterAvailabilityRoutes(router gin.IRouter, svc *availability.Service) {
+	group := router.Group("/user/players")
+	{
+		// 公开接口（不需要认证）
+		group.GET("/:id/slots", func(c *gin.Context) { listBookableSlotsHandler(c, svc) })
+	}
+}
+
+// bookableSlotsQuery 可预约时段查询参数，日期格式 YYYY-MM-DD（按服务端时区，包含 to 当天）
+type bookableSlotsQuery struct {
+	From string `form:"from" binding:"required"`
+	To   string `form:"to"`
+}
+
+// listBookableSlotsHandler 获取陪玩师可预约时段
+// @Summary      获取陪玩师可预约时段
+// @Description  返回指定日期范围内（最多 31 天）陪玩师可预约的时间段，已扣除不可接单时段与已确认/服务中的订单
+// @Tags         User - Players
+// @Produce      json
+// @Param        id    path      int     true   "陪玩师ID"
+// @Param        from  query     string  true   "开始日期 YYYY-MM-DD"
+// @Param        to    query     string  false  "结束日期 YYYY-MM-DD（含），默认与开始日期相同"
+// @Success      200   {object}  model.APIResponse[availability.BookableSlotsResponse]
+// @Failure      400   {object}  model.APIResponse[any]
+// @Failure      404   {object}  model.APIResponse[any]
+// @Router       /user/players/{id}/slots [get]
+func listBookableSlotsHandler(c *gin.Context, svc *availability.Service) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	var q bookableSlotsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if q.To == "" {
+		q.To = q.From
+	}
+	from, errFrom := time.ParseInLocation("2006-01-02", q.From, time.Local)
+	to, errTo := time.ParseInLocation("2006-01-02", q.To, time.Local)
+	if errFrom != nil || errTo != nil {
+		respondError(c, http.StatusBadRequest, "invalid date, expected YYYY-MM-DD")
+		return
+	}
+
+	resp, err := svc.ListBookableSlots(c.Request.Context(), id, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		switch {
+		case errors.Is(err, availability.ErrValidation):
+			respondError(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, availability.ErrNotFound):
+			respondError(c, http.StatusNotFound, err.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(c, http.StatusOK, model.APIResponse[availability.BookableSlotsResponse]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    *resp,
+	})
+}
@@ -129,7 +129,7 @@ func listChatMessagesHandler(c *gin.Context, svc *chatservice.ChatService) {
 type sendMessageRequest struct {
 	Content     string `json:"content"`
 	MessageType string `json:"messageType"`
-	ImageURL    string `json:"imageUrl"`
+	ImageUploadID *uint64 `json:"imageUploadId"`
 	ReplyToID   *uint64 `json:"replyToId"`
 }
 
@@ -170,7 +170,7 @@ func sendChatMessageHandler(c *gin.Context, svc *chatservice.ChatService) {
 		Content:     req.Content,
 		MessageType: messageType,
 		ReplyToID:   req.ReplyToID,
-		ImageURL:    req.ImageURL,
+		ImageUploadID: req.ImageUploadID,
 	})
 	if err != nil {
 		switch err {
@@ -178,7 +178,7 @@ func sendChatMessageHandler(c *gin.Context, svc *chatservice.ChatService) {
 			respondError(c, http.StatusForbidden, err.Error())
 		case chatservice.ErrInactiveGroup:
 			respondError(c, http.StatusGone, err.Error())
-		case chatservice.ErrMessageTooLarge:
+		case chatservice.ErrMessageTooLarge, chatservice.ErrInvalidImage:
 			respondError(c, http.StatusBadRequest, err.Error())
 		case chatservice.ErrThrottled:
 			respondError(c, http.StatusTooManyRequests, err.Error())
@@ -0,0 +1,116 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	chatservice "gamelink/internal/service/chat"
+)
+
+const (
+	// wsWriteWait 单帧写超时
+	wsWriteWait = 10 * time.Second
+	// wsReadWait 两个心跳周期内收不到任何帧（含 pong）即断开
+	wsReadWait = 2*chatservice.PresenceHeartbeat + 10*time.Second
+	// wsMaxFrameSize 客户端帧只有回执、输入中等小消息
+	wsMaxFrameSize = 4 << 10
+)
+
+// 鉴权依赖 JWT 而非 Cookie，跨域页面拿不到令牌，因此不限制 Origin
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(*http.Request) bool { return true },
+}
+
+// RegisterChatGatewayRoutes 注册聊天 WebSocket 网关。
+// 浏览器建立 WebSocket 时无法设置请求头，令牌也可以通过 ?token= 传入。
+func RegisterChatGatewayRoutes(router gin.IRouter, hub *chatservice.Hub, authMiddleware gin.HandlerFunc) {
+	router.GET("/user/chat/ws", tokenFromQuery, authMiddleware, func(c *gin.Context) { chatGatewayHandler(c, hub) })
+}
+
+// tokenFromQuery 请求头未携带令牌时，使用查询参数中的令牌
+func tokenFromQuery(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" {
+		if token := strings.TrimSpace(c.Query("token")); token != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	c.Next()
+}
+
+// chatGatewayHandler 聊天实时网关
+// @Summary      聊天实时网关
+// @Description  升级为 WebSocket 后推送新消息、审核状态、成员变动、输入中、送达/已读回执与在线状态；
+// @Description  客户端可发送 {"type":"typing|delivered|read|presence|ping","groupId":1,"messageId":2,"userIds":[3]}
+// @Tags         User - Chat
+// @Param        token  query  string  false  "访问令牌（无法设置 Authorization 头时使用）"
+// @Success      101
+// @Failure      401  {object}  model.APIResponse[any]
+// @Router       /user/chat/ws [get]
+func chatGatewayHandler(c *gin.Context, hub *chatservice.Hub) {
+	userID := getUserIDFromContext(c)
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade 已写入错误响应
+		return
+	}
+	ctx := context.WithoutCancel(c.Request.Context())
+	client := hub.Connect(ctx, userID)
+	defer hub.Disconnect(ctx, client)
+
+	go writeChatFrames(ctx, conn, client, hub)
+	readChatFrames(ctx, conn, client, hub)
+}
+
+// readChatFrames 读取客户端帧直到连接断开
+func readChatFrames(ctx context.Context, conn *websocket.Conn, client *chatservice.Client, hub *chatservice.Hub) {
+	defer conn.Close()
+	conn.SetReadLimit(wsMaxFrameSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsReadWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsReadWait))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(wsReadWait))
+		hub.Handle(ctx, client, data)
+	}
+}
+
+// writeChatFrames 推送事件，并按心跳周期发送 ping、刷新在线状态
+func writeChatFrames(ctx context.Context, conn *websocket.Conn, client *chatservice.Client, hub *chatservice.Hub) {
+	ticker := time.NewTicker(chatservice.PresenceHeartbeat)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+	for {
+		select {
+		case frame, ok := <-client.Send():
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				// 连接已从网关注销：读端断开，或推送积压被网关丢弃
+				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			hub.Heartbeat(ctx, client.UserID)
+		}
+	}
+}
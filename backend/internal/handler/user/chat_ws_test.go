@@ -0,0 +1,67 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/cache"
+	chatservice "gamelink/internal/service/chat"
+)
+
+func TestChatGateway_AuthenticatesWithQueryToken(t *testing.T) {
+	svc, _, _, _, _ := setupChatTest()
+	mem := cache.NewMemory()
+	hub := chatservice.NewHub(svc, mem.(cache.PubSub), mem)
+
+	// 测试用鉴权：令牌即用户ID
+	auth := func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer 7" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set("user_id", uint64(7))
+		c.Next()
+	}
+	router := gin.New()
+	RegisterChatGatewayRoutes(router, hub, auth)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/user/chat/ws"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token=7", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.True(t, hub.Online(context.Background(), []uint64{7})[7])
+
+	require.NoError(t, conn.WriteJSON(chatservice.Frame{Type: "presence", UserIDs: []uint64{7}}))
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var event chatservice.Event
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, chatservice.EventPresence, event.Type)
+	assert.Equal(t, uint64(7), event.UserID)
+	require.NotNil(t, event.Online)
+	assert.True(t, *event.Online)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"bogus"}`)))
+	var failed map[string]string
+	require.NoError(t, conn.ReadJSON(&failed))
+	assert.Equal(t, chatservice.ErrUnknownFrame.Error(), failed["error"])
+
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		return !hub.Online(context.Background(), []uint64{7})[7]
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/coupon"
+)
+
+// RegisterCouponRoutes 注册用户端优惠券路由
+func RegisterCouponRoutes(router gin.IRouter, svc *coupon.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/user/coupons")
+	group.Use(authMiddleware) // 需要认证
+	group.GET("", func(c *gin.Context) { listMyCouponsHandler(c, svc) })
+}
+
+// listMyCouponsHandler 我的优惠券
+// @Summary      我的优惠券
+// @Description  下单时在请求中传入 couponId 使用；取消或退款后优惠券自动退回
+// @Tags         User - Coupon
+// @Produce      json
+// @Param        Authorization  header    string  true   "Bearer {token}"
+// @Param        status         query     string  false  "available/locked/used/expired"
+// @Param        page           query     int     false  "页码"
+// @Param        pageSize       query     int     false  "每页数量"
+// @Success      200            {object}  model.APIResponse[[]model.UserCoupon]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Router       /user/coupons [get]
+func listMyCouponsHandler(c *gin.Context, svc *coupon.Service) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	status := model.CouponStatus(c.Query("status"))
+
+	coupons, total, err := svc.ListMyCoupons(c.Request.Context(), getUserIDFromContext(c), status, page, pageSize)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[[]model.UserCoupon]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    coupons,
+		Meta:    map[string]any{"total": total},
+	})
+}
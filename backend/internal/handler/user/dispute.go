@@ -22,12 +22,22 @@ func NewDisputeHandler(svc *assignment.AssignmentService) *DisputeHandler {
 	return &DisputeHandler{svc: svc}
 }
 
+// RegisterDisputeRoutes 注册用户端订单争议路由
+func RegisterDisputeRoutes(router gin.IRouter, svc *assignment.AssignmentService, authMiddleware gin.HandlerFunc) {
+	h := NewDisputeHandler(svc)
+	group := router.Group("/user/disputes")
+	group.Use(authMiddleware) // 需要认证
+	group.POST("", h.InitiateDispute)
+	group.GET("/:id", h.GetDisputeDetail)
+}
+
 // InitiateDisputePayload represents the request to initiate a dispute
 type InitiateDisputePayload struct {
-	OrderID      uint64   `json:"orderId" binding:"required"`
-	Reason       string   `json:"reason" binding:"required,max=255"`
-	Description  string   `json:"description" binding:"max=2000"`
-	EvidenceURLs []string `json:"evidenceUrls" binding:"max=9"`
+	OrderID     uint64 `json:"orderId" binding:"required"`
+	Reason      string `json:"reason" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=2000"`
+	// EvidenceUploadIDs 证据文件的上传 ID，先通过 POST /user/uploads 上传
+	EvidenceUploadIDs []uint64 `json:"evidenceUploadIds" binding:"max=9"`
 }
 
 // InitiateDispute creates a new dispute for an order
@@ -40,10 +50,10 @@ type InitiateDisputePayload struct {
 // @Success      201  {object}  map[string]any
 // @Failure      400  {object}  map[string]any
 // @Failure      404  {object}  map[string]any
-// @Router       /user/orders/{id}/dispute [post]
+// @Router       /user/disputes [post]
 func (h *DisputeHandler) InitiateDispute(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		respondError(c, http.StatusUnauthorized, "User ID not found in context")
 		return
@@ -55,26 +65,18 @@ func (h *DisputeHandler) InitiateDispute(c *gin.Context) {
 		return
 	}
 
-	// Validate evidence URLs count
-	if len(payload.EvidenceURLs) > 9 {
-		respondError(c, http.StatusBadRequest, "Maximum 9 evidence URLs allowed")
+	// Validate evidence count
+	if len(payload.EvidenceUploadIDs) > 9 {
+		respondError(c, http.StatusBadRequest, "Maximum 9 evidence files allowed")
 		return
 	}
 
-	// Validate evidence URLs are not empty
-	for _, url := range payload.EvidenceURLs {
-		if url == "" {
-			respondError(c, http.StatusBadRequest, "Evidence URLs cannot be empty")
-			return
-		}
-	}
-
 	resp, err := h.svc.InitiateDispute(c.Request.Context(), assignment.InitiateDisputeRequest{
-		OrderID:      payload.OrderID,
-		UserID:       userID.(uint64),
-		Reason:       payload.Reason,
-		Description:  payload.Description,
-		EvidenceURLs: payload.EvidenceURLs,
+		OrderID:           payload.OrderID,
+		UserID:            userID.(uint64),
+		Reason:            payload.Reason,
+		Description:       payload.Description,
+		EvidenceUploadIDs: payload.EvidenceUploadIDs,
 	})
 
 	if err != nil {
@@ -103,18 +105,20 @@ func (h *DisputeHandler) InitiateDispute(c *gin.Context) {
 	}
 
 	type InitiateDisputeResponse struct {
-		DisputeID   uint64 `json:"disputeId"`
-		TraceID     string `json:"traceId"`
-		SLADeadline string `json:"slaDeadline"`
+		DisputeID        uint64  `json:"disputeId"`
+		TraceID          string  `json:"traceId"`
+		SLADeadline      string  `json:"slaDeadline"`
+		AssignedToUserID *uint64 `json:"assignedToUserId,omitempty"`
 	}
 
 	respondJSON(c, http.StatusCreated, model.APIResponse[InitiateDisputeResponse]{
 		Success: true,
 		Code:    http.StatusCreated,
 		Data: InitiateDisputeResponse{
-			DisputeID:   resp.DisputeID,
-			TraceID:     resp.TraceID,
-			SLADeadline: resp.SLADeadline.Format("2006-01-02T15:04:05Z07:00"),
+			DisputeID:        resp.DisputeID,
+			TraceID:          resp.TraceID,
+			SLADeadline:      resp.SLADeadline.Format("2006-01-02T15:04:05Z07:00"),
+			AssignedToUserID: resp.AssignedToUserID,
 		},
 	})
 }
@@ -128,10 +132,10 @@ func (h *DisputeHandler) InitiateDispute(c *gin.Context) {
 // @Param        id  path  uint64  true  "Dispute ID"
 // @Success      200  {object}  map[string]any
 // @Failure      404  {object}  map[string]any
-// @Router       /user/orders/{id}/disputes [get]
+// @Router       /user/disputes/{id} [get]
 func (h *DisputeHandler) GetDisputeDetail(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		respondError(c, http.StatusUnauthorized, "User ID not found in context")
 		return
@@ -161,6 +161,10 @@ func (m *mockPaymentRepoForDispute) List(ctx context.Context, opts repository.Pa
 func (m *mockPaymentRepoForDispute) Get(ctx context.Context, id uint64) (*model.Payment, error) {
 	return nil, repository.ErrNotFound
 }
+
+func (m *mockPaymentRepoForDispute) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (m *mockPaymentRepoForDispute) Update(ctx context.Context, p *model.Payment) error { return nil }
 func (m *mockPaymentRepoForDispute) Delete(ctx context.Context, id uint64) error { return nil }
 
@@ -181,10 +185,9 @@ func TestDisputeHandler_InitiateDispute_Success(t *testing.T) {
 	handler := setupDisputeHandler(t)
 
 	payload := InitiateDisputePayload{
-		OrderID:      1,
-		Reason:       "Product defective",
-		Description:  "Item arrived broken",
-		EvidenceURLs: []string{"https://example.com/image1.jpg"},
+		OrderID:     1,
+		Reason:      "Product defective",
+		Description: "Item arrived broken",
 	}
 
 	body, _ := json.Marshal(payload)
@@ -194,7 +197,7 @@ func TestDisputeHandler_InitiateDispute_Success(t *testing.T) {
 
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
-	c.Set("userID", uint64(1))
+	c.Set("user_id", uint64(1))
 	c.Set("request_id", "trace-123")
 	c.Params = gin.Params{{Key: "id", Value: "1"}}
 
@@ -214,7 +217,7 @@ func TestDisputeHandler_InitiateDispute_InvalidJSON(t *testing.T) {
 
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
-	c.Set("userID", uint64(1))
+	c.Set("user_id", uint64(1))
 	c.Params = gin.Params{{Key: "id", Value: "1"}}
 
 	handler.InitiateDispute(c)
@@ -238,7 +241,7 @@ func TestDisputeHandler_InitiateDispute_MissingUserID(t *testing.T) {
 
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
-	// Don't set userID
+	// Don't set user_id
 	c.Params = gin.Params{{Key: "id", Value: "1"}}
 
 	handler.InitiateDispute(c)
@@ -256,7 +259,7 @@ func TestDisputeHandler_InitiateDispute_InvalidOrderID(t *testing.T) {
 
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
-	c.Set("userID", uint64(1))
+	c.Set("user_id", uint64(1))
 	c.Params = gin.Params{{Key: "id", Value: "abc"}}
 
 	handler.InitiateDispute(c)
@@ -280,7 +283,7 @@ func TestDisputeHandler_InitiateDispute_MissingReason(t *testing.T) {
 
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
-	c.Set("userID", uint64(1))
+	c.Set("user_id", uint64(1))
 	c.Params = gin.Params{{Key: "id", Value: "1"}}
 
 	handler.InitiateDispute(c)
@@ -297,7 +300,7 @@ func TestDisputeHandler_GetDisputeDetail_Success(t *testing.T) {
 
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
-	c.Set("userID", uint64(1))
+	c.Set("user_id", uint64(1))
 	c.Params = gin.Params{{Key: "id", Value: "1"}}
 
 	handler.GetDisputeDetail(c)
@@ -315,7 +318,7 @@ func TestDisputeHandler_GetDisputeDetail_InvalidDisputeID(t *testing.T) {
 
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
-	c.Set("userID", uint64(1))
+	c.Set("user_id", uint64(1))
 	c.Params = gin.Params{{Key: "id", Value: "abc"}}
 
 	handler.GetDisputeDetail(c)
@@ -332,7 +335,7 @@ func TestDisputeHandler_GetDisputeDetail_MissingUserID(t *testing.T) {
 
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
-	// Don't set userID
+	// Don't set user_id
 	c.Params = gin.Params{{Key: "id", Value: "1"}}
 
 	handler.GetDisputeDetail(c)
@@ -0,0 +1,141 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/apierr"
+	"gamelink/internal/model"
+	"gamelink/internal/service/extension"
+)
+
+// RegisterExtensionRoutes 注册用户端订单加时路由
+func RegisterExtensionRoutes(router gin.IRouter, svc *extension.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/user/orders")
+	group.Use(authMiddleware) // 需要认证
+	group.GET("/:id/extensions", func(c *gin.Context) { listOrderExtensionsHandler(c, svc) })
+	group.POST("/:id/extensions", func(c *gin.Context) { requestOrderExtensionHandler(c, svc) })
+	group.PUT("/:id/extensions/:extensionId/cancel", func(c *gin.Context) { cancelOrderExtensionHandler(c, svc) })
+}
+
+// listOrderExtensionsHandler 订单加时记录
+// @Summary      订单加时记录
+// @Tags         User - Orders
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "订单ID"
+// @Success      200            {object}  model.APIResponse[[]model.OrderExtension]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /user/orders/{id}/extensions [get]
+func listOrderExtensionsHandler(c *gin.Context, svc *extension.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	exts, err := svc.ListExtensions(c.Request.Context(), getUserIDFromContext(c), orderID)
+	if err != nil {
+		respondExtensionError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[[]model.OrderExtension]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    exts,
+	})
+}
+
+// requestOrderExtensionHandler 申请加时
+// @Summary      申请加时
+// @Description  服务中的订单申请加时，按订单服务项目与陪玩师报价；陪玩师同意后以 extensionId 创建补款支付，支付成功后延长预约结束时间
+// @Tags         User - Orders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                             true  "Bearer {token}"
+// @Param        id             path      int                                true  "订单ID"
+// @Param        request        body      extension.RequestExtensionRequest  true  "加时时长"
+// @Success      200            {object}  model.APIResponse[model.OrderExtension]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]
+// @Router       /user/orders/{id}/extensions [post]
+func requestOrderExtensionHandler(c *gin.Context, svc *extension.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	var req extension.RequestExtensionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	ext, err := svc.RequestExtension(c.Request.Context(), getUserIDFromContext(c), orderID, req)
+	if err != nil {
+		respondExtensionError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[model.OrderExtension]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "加时申请已提交",
+		Data:    *ext,
+	})
+}
+
+// cancelOrderExtensionHandler 撤回加时申请
+// @Summary      撤回加时申请
+// @Description  撤回尚未补款的加时申请
+// @Tags         User - Orders
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "订单ID"
+// @Param        extensionId    path      int     true  "加时申请ID"
+// @Success      200            {object}  model.APIResponse[model.OrderExtension]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]
+// @Router       /user/orders/{id}/extensions/{extensionId}/cancel [put]
+func cancelOrderExtensionHandler(c *gin.Context, svc *extension.Service) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	extensionID, err := strconv.ParseUint(c.Param("extensionId"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	ext, err := svc.CancelExtension(c.Request.Context(), getUserIDFromContext(c), orderID, extensionID)
+	if err != nil {
+		respondExtensionError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[model.OrderExtension]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "加时申请已撤回",
+		Data:    *ext,
+	})
+}
+
+func respondExtensionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, extension.ErrNotFound):
+		respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, extension.ErrValidation):
+		respondError(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, extension.ErrForbidden):
+		respondError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, extension.ErrOrderNotInProgress), errors.Is(err, extension.ErrExtensionPending),
+		errors.Is(err, extension.ErrInvalidStatus):
+		respondError(c, http.StatusConflict, err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, err.Error())
+	}
+}
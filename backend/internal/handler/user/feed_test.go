@@ -61,12 +61,32 @@ func (m *mockFeedRepository) CreateReport(ctx context.Context, report *model.Fee
 	return nil
 }
 
+// mockFeedAttachments 将任意上传视为调用者本人的图片
+type mockFeedAttachments struct{}
+
+func (mockFeedAttachments) Attach(ctx context.Context, ownerID uint64, ids []uint64) ([]model.Upload, error) {
+	uploads := make([]model.Upload, 0, len(ids))
+	for _, id := range ids {
+		uploads = append(uploads, model.Upload{ID: id, UserID: ownerID, MimeType: "image/png", FileSize: 1024})
+	}
+	return uploads, nil
+}
+
+func (mockFeedAttachments) URLs(ctx context.Context, ids []uint64) (map[uint64]string, error) {
+	urls := make(map[uint64]string, len(ids))
+	for _, id := range ids {
+		urls[id] = fmt.Sprintf("/api/v1/uploads/%d/content", id)
+	}
+	return urls, nil
+}
+
 func setupFeedTest(t *testing.T) *gin.Engine {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 	
 	repo := newMockFeedRepository()
 	svc := feedservice.NewService(repo, feedservice.NewDefaultModerationEngine())
+	svc.SetAttachments(mockFeedAttachments{})
 
 	engine := gin.New()
 	engine.Use(func(c *gin.Context) {
@@ -81,7 +101,7 @@ func setupFeedTest(t *testing.T) *gin.Engine {
 
 func TestCreateFeed_Success(t *testing.T) {
 	router := setupFeedTest(t)
-	body := `{"content":"今天天气真好","visibility":"public","images":[{"url":"https://img/1.png","sizeBytes":1024}]}`
+	body := `{"content":"今天天气真好","visibility":"public","images":[{"uploadId":1}]}`
 	req := httptest.NewRequest(http.MethodPost, "/user/feeds", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
@@ -113,7 +133,7 @@ func TestCreateFeed_TooManyImages(t *testing.T) {
 	router := setupFeedTest(t)
 	images := make([]string, 0, 10)
 	for i := 0; i < 10; i++ {
-		images = append(images, fmt.Sprintf(`{"url":"https://img/%d.png","sizeBytes":1024}`, i))
+		images = append(images, fmt.Sprintf(`{"uploadId":%d}`, i+1))
 	}
 	body := `{"content":"hello","images":[` + strings.Join(images, ",") + `]}`
 	req := httptest.NewRequest(http.MethodPost, "/user/feeds", strings.NewReader(body))
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -9,6 +10,7 @@ import (
 	"gamelink/internal/apierr"
 	"gamelink/internal/model"
 	"gamelink/internal/service/order"
+	"gamelink/internal/service/orderstate"
 )
 
 // RegisterOrderRoutes 注册用户端订单路由
@@ -20,6 +22,7 @@ func RegisterOrderRoutes(router gin.IRouter, svc *order.OrderService, authMiddle
 	group.GET("/:id", func(c *gin.Context) { getOrderDetailHandler(c, svc) })
 	group.PUT("/:id/cancel", func(c *gin.Context) { cancelOrderHandler(c, svc) })
 	group.PUT("/:id/complete", func(c *gin.Context) { completeOrderHandler(c, svc) })
+	group.PUT("/:id/object", func(c *gin.Context) { objectCompletionHandler(c, svc) })
 }
 
 // createOrderHandler 创建订单
@@ -33,6 +36,7 @@ func RegisterOrderRoutes(router gin.IRouter, svc *order.OrderService, authMiddle
 // @Success      200            {object}  model.APIResponse[order.CreateOrderResponse]
 // @Failure      400            {object}  model.APIResponse[any]
 // @Failure      401            {object}  model.APIResponse[any]
+// @Failure      409            {object}  model.APIResponse[any]
 // @Router       /user/orders [post]
 func createOrderHandler(c *gin.Context, svc *order.OrderService) {
 	userID := getUserIDFromContext(c)
@@ -45,6 +49,15 @@ func createOrderHandler(c *gin.Context, svc *order.OrderService) {
 
 	resp, err := svc.CreateOrder(c.Request.Context(), userID, req)
 	if err != nil {
+		if errors.Is(err, order.ErrBookingConflict) || errors.Is(err, order.ErrPlayerUnavailable) ||
+			errors.Is(err, order.ErrCouponUnavailable) || errors.Is(err, order.ErrCouponUsageLimit) {
+			respondError(c, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, order.ErrValidation) || errors.Is(err, order.ErrCouponNotApplicable) {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -197,7 +210,7 @@ func cancelOrderHandler(c *gin.Context, svc *order.OrderService) {
 
 // completeOrderHandler 完成订单
 // @Summary      完成订单
-// @Description  用户确认订单完成
+// @Description  用户确认订单完成；陪玩师提交完成后待确认的订单也通过此接口确认
 // @Tags         User - Orders
 // @Accept       json
 // @Produce      json
@@ -237,6 +250,53 @@ func completeOrderHandler(c *gin.Context, svc *order.OrderService) {
 	})
 }
 
+// objectCompletionHandler 对完成提出异议
+// @Summary      对完成提出异议
+// @Description  用户对陪玩师提交的完成提出异议，订单退回服务中；需要平台介入时请发起争议
+// @Tags         User - Orders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                         true  "Bearer {token}"
+// @Param        id             path      int                            true  "订单ID"
+// @Param        request        body      order.ObjectCompletionRequest  true  "异议原因"
+// @Success      200            {object}  model.APIResponse[any]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      403            {object}  model.APIResponse[any]
+// @Router       /user/orders/{id}/object [put]
+func objectCompletionHandler(c *gin.Context, svc *order.OrderService) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+
+	var req order.ObjectCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := svc.ObjectCompletion(c.Request.Context(), getUserIDFromContext(c), orderID, req); err != nil {
+		switch {
+		case errors.Is(err, order.ErrUnauthorized):
+			respondError(c, http.StatusForbidden, err.Error())
+		case errors.Is(err, order.ErrInvalidTransition), errors.Is(err, orderstate.ErrStatusConflict):
+			respondError(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, order.ErrNotFound):
+			respondError(c, http.StatusNotFound, err.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	respondJSON(c, http.StatusOK, model.APIResponse[any]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "已提出异议，订单退回服务中",
+	})
+}
+
 // getUserIDFromContext 从上下文获取用户ID
 func getUserIDFromContext(c *gin.Context) uint64 {
     // 从 JWT 中间件设置的上下文中获取用户ID
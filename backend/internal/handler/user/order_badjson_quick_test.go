@@ -56,6 +56,8 @@ type obPayments struct{}
 func (obPayments) Create(context.Context, *model.Payment) error { return nil }
 func (obPayments) List(context.Context, repository.PaymentListOptions) ([]model.Payment, int64, error) { return nil, 0, nil }
 func (obPayments) Get(context.Context, uint64) (*model.Payment, error) { return nil, repository.ErrNotFound }
+
+func (obPayments) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (obPayments) Update(context.Context, *model.Payment) error { return nil }
 func (obPayments) Delete(context.Context, uint64) error { return nil }
 
@@ -66,6 +66,8 @@ type dummyPayments struct{}
 func (dummyPayments) Create(context.Context, *model.Payment) error { return nil }
 func (dummyPayments) List(context.Context, repository.PaymentListOptions) ([]model.Payment, int64, error) { return nil, 0, nil }
 func (dummyPayments) Get(context.Context, uint64) (*model.Payment, error) { return nil, repository.ErrNotFound }
+
+func (dummyPayments) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (dummyPayments) Update(context.Context, *model.Payment) error { return nil }
 func (dummyPayments) Delete(context.Context, uint64) error { return nil }
 
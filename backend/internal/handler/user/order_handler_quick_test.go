@@ -58,6 +58,8 @@ type fakePaymentRepoOrd struct{ items map[uint64]model.Payment }
 func (f *fakePaymentRepoOrd) Create(context.Context, *model.Payment) error { return nil }
 func (f *fakePaymentRepoOrd) List(context.Context, repository.PaymentListOptions) ([]model.Payment, int64, error) { out:=make([]model.Payment,0,len(f.items)); for _, v:= range f.items { out = append(out, v) } ; return out, int64(len(out)), nil }
 func (f *fakePaymentRepoOrd) Get(context.Context, uint64) (*model.Payment, error) { return nil, repository.ErrNotFound }
+
+func (f *fakePaymentRepoOrd) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (f *fakePaymentRepoOrd) Update(context.Context, *model.Payment) error { return nil }
 func (f *fakePaymentRepoOrd) Delete(context.Context, uint64) error { return nil }
 
@@ -55,6 +55,8 @@ type invalidPayments struct{}
 func (invalidPayments) Create(context.Context, *model.Payment) error { return nil }
 func (invalidPayments) List(context.Context, repository.PaymentListOptions) ([]model.Payment, int64, error) { return nil, 0, nil }
 func (invalidPayments) Get(context.Context, uint64) (*model.Payment, error) { return nil, repository.ErrNotFound }
+
+func (invalidPayments) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (invalidPayments) Update(context.Context, *model.Payment) error { return nil }
 func (invalidPayments) Delete(context.Context, uint64) error { return nil }
 
@@ -133,6 +133,10 @@ func (m *fakePaymentRepository) List(ctx context.Context, opts repository.Paymen
 func (m *fakePaymentRepository) Get(ctx context.Context, id uint64) (*model.Payment, error) {
 	return &model.Payment{}, nil
 }
+
+func (m *fakePaymentRepository) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (m *fakePaymentRepository) Update(ctx context.Context, payment *model.Payment) error { return nil }
 func (m *fakePaymentRepository) Delete(ctx context.Context, id uint64) error              { return nil }
 
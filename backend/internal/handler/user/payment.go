@@ -1,6 +1,8 @@
 package user
 
 import (
+	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 
@@ -20,6 +22,44 @@ func RegisterPaymentRoutes(router gin.IRouter, svc *payment.PaymentService, auth
     group.POST("/:id/cancel", func(c *gin.Context) { cancelPaymentHandler(c, svc) })
 }
 
+// RegisterPaymentNotifyRoutes 注册支付渠道异步回调路由（无需登录，依赖渠道签名校验）
+func RegisterPaymentNotifyRoutes(router gin.IRouter, svc *payment.PaymentService) {
+	router.POST("/payments/notify/:provider", func(c *gin.Context) { paymentNotifyHandler(c, svc) })
+}
+
+// maxNotifyBodyBytes 回调报文大小上限
+const maxNotifyBodyBytes = 64 << 10
+
+// paymentNotifyHandler 处理渠道支付结果通知
+// @Summary      支付结果回调
+// @Description  微信支付/支付宝/沙箱异步通知，按渠道要求的格式应答
+// @Tags         Payments - Notify
+// @Accept       json
+// @Produce      plain
+// @Param        provider  path  string  true  "支付方式 wechat|alipay"
+// @Success      200
+// @Router       /payments/notify/{provider} [post]
+func paymentNotifyHandler(c *gin.Context, svc *payment.PaymentService) {
+	method := model.PaymentMethod(c.Param("provider"))
+	if _, ok := svc.Gateway(method); !ok {
+		respondError(c, http.StatusNotFound, "unknown payment provider")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxNotifyBodyBytes))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "无法读取请求体")
+		return
+	}
+
+	err = svc.HandleNotification(c.Request.Context(), method, c.Request.Header, body)
+	if err != nil {
+		slog.Warn("payment notification rejected", "provider", method, "error", err)
+	}
+	status, contentType, ack := svc.NotificationAck(method, err == nil)
+	c.Data(status, contentType, ack)
+}
+
 // createPaymentHandler 创建支付
 // @Summary      创建支付
 // @Description  为订单创建支付
@@ -20,6 +20,10 @@ func newFakePaymentRepo() *fakePaymentRepo { return &fakePaymentRepo{items: map[
 func (f *fakePaymentRepo) Create(ctx context.Context, p *model.Payment) error { p.ID=f.next; f.next++; f.items[p.ID]=p; return nil }
 func (f *fakePaymentRepo) List(ctx context.Context, opts repository.PaymentListOptions) ([]model.Payment, int64, error) { out:=make([]model.Payment,0,len(f.items)); for _, v:= range f.items { out = append(out, *v) } ; return out, int64(len(out)), nil }
 func (f *fakePaymentRepo) Get(ctx context.Context, id uint64) (*model.Payment, error) { v := f.items[id]; if v==nil { return nil, repository.ErrNotFound } ; return v, nil }
+
+func (f *fakePaymentRepo) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (f *fakePaymentRepo) Update(ctx context.Context, p *model.Payment) error { f.items[p.ID]=p; return nil }
 func (f *fakePaymentRepo) Delete(ctx context.Context, id uint64) error { delete(f.items, id); return nil }
 
@@ -34,6 +38,7 @@ func setupUserPaymentRouter(payRepo *fakePaymentRepo, ordRepo *fakeOrderRepoPay)
     r := gin.New()
     r.Use(func(c *gin.Context){ c.Set("user_id", uint64(1)); c.Next() })
     svc := paysvc.NewPaymentService(payRepo, ordRepo)
+    useSandboxGateways(svc)
     RegisterPaymentRoutes(r, svc, func(c *gin.Context){ c.Next() })
     return r
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -122,6 +123,15 @@ func (m *mockPaymentRepoForUserPayment) Get(ctx context.Context, id uint64) (*mo
 	return nil, repository.ErrNotFound
 }
 
+func (m *mockPaymentRepoForUserPayment) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	for _, p := range m.payments {
+		if p.OutTradeNo != "" && p.OutTradeNo == outTradeNo {
+			return p, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
 func (m *mockPaymentRepoForUserPayment) Update(ctx context.Context, payment *model.Payment) error {
 	m.payments[payment.ID] = payment
 	return nil
@@ -134,11 +144,24 @@ func (m *mockPaymentRepoForUserPayment) Delete(ctx context.Context, id uint64) e
 
 // ---- Tests for user_payment.go ----
 
+// sandboxTestSecret 测试沙箱的回调签名密钥。
+const sandboxTestSecret = "test-secret"
+
+// useSandboxGateways 为支付服务接入进程内沙箱网关。
+func useSandboxGateways(svc *payment.PaymentService) {
+	sandbox := payment.NewSandboxServer(sandboxTestSecret)
+	svc.SetGateways(
+		payment.NewInProcessSandboxGateway(model.PaymentMethodWeChat, sandbox),
+		payment.NewInProcessSandboxGateway(model.PaymentMethodAlipay, sandbox),
+	)
+}
+
 func TestCreatePaymentHandler_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	paymentRepo := newMockPaymentRepoForUserPayment()
 	paymentSvc := payment.NewPaymentService(paymentRepo, newFakeOrderRepositoryForPayment())
+	useSandboxGateways(paymentSvc)
 
 	router := gin.New()
 	router.POST("/user/payments", func(c *gin.Context) {
@@ -454,3 +477,61 @@ func TestGetPaymentStatusHandler_MissingUserID(t *testing.T) {
 		t.Fatalf("Expected status 200, got %d", w.Code)
 	}
 }
+
+func TestPaymentNotifyHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	paymentRepo := newMockPaymentRepoForUserPayment()
+	orderRepo := newFakeOrderRepositoryForPayment()
+	paymentSvc := payment.NewPaymentService(paymentRepo, orderRepo)
+	useSandboxGateways(paymentSvc)
+	created, err := paymentSvc.CreatePayment(context.Background(), 100, payment.CreatePaymentRequest{OrderID: 10, Method: model.PaymentMethodWeChat})
+	if err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+
+	router := gin.New()
+	RegisterPaymentNotifyRoutes(router, paymentSvc)
+
+	notify := func(provider string, header http.Header, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/payments/notify/"+provider, bytes.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"method":       "wechat",
+		"out_trade_no": paymentRepo.payments[created.PaymentID].OutTradeNo,
+		"trade_no":     "4200000000001",
+		"status":       "SUCCESS",
+		"amount_cents": 5000,
+		"currency":     "CNY",
+	})
+
+	if w := notify("unionpay", nil, body); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown provider, got %d", w.Code)
+	}
+
+	forged := payment.SignSandboxPayload([]byte("wrong-secret"), body, time.Now())
+	if w := notify("wechat", forged, body); w.Code == http.StatusOK {
+		t.Fatalf("expected forged notification to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if paymentRepo.payments[created.PaymentID].Status != model.PaymentStatusPending {
+		t.Fatalf("forged notification must not change payment status")
+	}
+
+	signed := payment.SignSandboxPayload([]byte(sandboxTestSecret), body, time.Now())
+	if w := notify("wechat", signed, body); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if paymentRepo.payments[created.PaymentID].Status != model.PaymentStatusPaid {
+		t.Fatalf("expected payment to be paid after notification")
+	}
+	if orderRepo.orders[10].Status != model.OrderStatusConfirmed {
+		t.Fatalf("expected order to be confirmed, got %s", orderRepo.orders[10].Status)
+	}
+}
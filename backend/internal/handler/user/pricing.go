@@ -0,0 +1,56 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/pricing"
+)
+
+// RegisterPricingRoutes 注册用户端报价路由
+func RegisterPricingRoutes(router gin.IRouter, svc *pricing.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/user/pricing")
+	group.Use(authMiddleware) // 需要认证
+	group.POST("/quote", func(c *gin.Context) { quoteHandler(c, svc) })
+}
+
+// quoteHandler 订单报价
+// @Summary      订单报价
+// @Description  按服务项目、段位、数量/时长与陪玩师时薪计算价格明细及抽成，下单时使用同一报价
+// @Tags         User - Orders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                true  "Bearer {token}"
+// @Param        request        body      pricing.QuoteRequest  true  "报价请求"
+// @Success      200            {object}  model.APIResponse[pricing.Quote]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /user/pricing/quote [post]
+func quoteHandler(c *gin.Context, svc *pricing.Service) {
+	var req pricing.QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	quote, err := svc.Quote(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, pricing.ErrNotFound):
+			respondError(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, pricing.ErrValidation), errors.Is(err, pricing.ErrNoServiceItem):
+			respondError(c, http.StatusBadRequest, err.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[pricing.Quote]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    *quote,
+	})
+}
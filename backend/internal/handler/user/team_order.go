@@ -0,0 +1,56 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/team"
+)
+
+// RegisterTeamOrderRoutes 注册用户端车队订单路由
+func RegisterTeamOrderRoutes(router gin.IRouter, svc *team.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/user/team-orders")
+	group.Use(authMiddleware) // 需要认证
+	group.POST("", func(c *gin.Context) { createTeamOrderHandler(c, svc) })
+}
+
+// createTeamOrderHandler 下车队订单
+// @Summary      下车队订单
+// @Description  按团队护航服务项目下单，价格、所需人数与时长取自服务项目；支付后进入车队大厅由车队抢单
+// @Tags         User - Orders
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                       true  "Bearer {token}"
+// @Param        request        body      team.CreateTeamOrderRequest  true  "车队订单请求"
+// @Success      200            {object}  model.APIResponse[team.TeamOrderResponse]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /user/team-orders [post]
+func createTeamOrderHandler(c *gin.Context, svc *team.Service) {
+	var req team.CreateTeamOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp, err := svc.CreateTeamOrder(c.Request.Context(), getUserIDFromContext(c), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, team.ErrNotFound):
+			respondError(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, team.ErrValidation):
+			respondError(c, http.StatusBadRequest, err.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[team.TeamOrderResponse]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "订单创建成功",
+		Data:    *resp,
+	})
+}
@@ -0,0 +1,192 @@
+package user
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	apierr "gamelink/internal/handler"
+	"gamelink/internal/handler/middleware"
+	"gamelink/internal/model"
+	uploadservice "gamelink/internal/service/upload"
+)
+
+// maxUploadBodyBytes 上传请求体上限：视频上限加上表单开销
+const maxUploadBodyBytes = 101 << 20
+
+// userUploadTypes 用户可指定的上传用途
+var userUploadTypes = map[model.UploadType]bool{
+	model.UploadTypeAvatar:          true,
+	model.UploadTypeGameScreenshot:  true,
+	model.UploadTypeReviewImage:     true,
+	model.UploadTypeChatImage:       true,
+	model.UploadTypeDisputeEvidence: true,
+	model.UploadTypeFeedImage:       true,
+	model.UploadTypeOther:           true,
+}
+
+// RegisterUploadRoutes 注册用户端文件上传路由
+func RegisterUploadRoutes(router gin.IRouter, svc *uploadservice.Service, authMiddleware gin.HandlerFunc) {
+	group := router.Group("/user/uploads")
+	group.Use(authMiddleware) // 需要认证
+	group.POST("", func(c *gin.Context) { uploadFileHandler(c, svc) })
+	group.GET("/:id", func(c *gin.Context) { getUploadHandler(c, svc) })
+}
+
+// RegisterUploadContentRoutes 注册签名下载路由；凭链接中的签名访问，无需登录
+func RegisterUploadContentRoutes(router gin.IRouter, svc *uploadservice.Service) {
+	router.GET("/uploads/:id/content", func(c *gin.Context) { uploadContentHandler(c, svc) })
+}
+
+// uploadFileHandler 上传文件
+// @Summary      上传文件
+// @Description  图片（jpg/png/gif/webp，≤5MB）或视频（mp4/mpeg/mov/webm，≤100MB）；返回上传 ID 与限时下载链接，争议证据、聊天图片、动态配图按上传 ID 引用
+// @Tags         User - Upload
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        Authorization  header    string  true   "Bearer {token}"
+// @Param        file           formData  file    true   "文件"
+// @Param        type           formData  string  false  "dispute_evidence/chat_image/feed_image/review_image/game_screenshot/avatar/other"
+// @Success      201            {object}  model.APIResponse[uploadservice.UploadView]
+// @Failure      400            {object}  model.APIResponse[any]
+// @Failure      401            {object}  model.APIResponse[any]
+// @Router       /user/uploads [post]
+func uploadFileHandler(c *gin.Context, svc *uploadservice.Service) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBodyBytes)
+	file, err := c.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	uploadType := model.UploadType(c.DefaultPostForm("type", string(model.UploadTypeOther)))
+	if !userUploadTypes[uploadType] {
+		respondError(c, http.StatusBadRequest, "unsupported upload type")
+		return
+	}
+
+	cfg := middleware.GetImageConfig()
+	if isVideoExt(filepath.Ext(file.Filename)) {
+		cfg = middleware.GetVideoConfig()
+	}
+	if err := middleware.ValidateFile(file, cfg); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer src.Close()
+
+	view, err := svc.Upload(c.Request.Context(), getUserIDFromContext(c), uploadservice.UploadRequest{
+		Type:     uploadType,
+		FileName: file.Filename,
+		Body:     src,
+	})
+	if err != nil {
+		if errors.Is(err, uploadservice.ErrValidation) {
+			respondError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(c, http.StatusCreated, model.APIResponse[*uploadservice.UploadView]{
+		Success: true,
+		Code:    http.StatusCreated,
+		Message: "created",
+		Data:    view,
+	})
+}
+
+// getUploadHandler 查看自己的上传并刷新下载链接
+// @Summary      上传详情
+// @Tags         User - Upload
+// @Produce      json
+// @Param        Authorization  header    string  true  "Bearer {token}"
+// @Param        id             path      int     true  "上传ID"
+// @Success      200            {object}  model.APIResponse[uploadservice.UploadView]
+// @Failure      404            {object}  model.APIResponse[any]
+// @Router       /user/uploads/{id} [get]
+func getUploadHandler(c *gin.Context, svc *uploadservice.Service) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	view, err := svc.GetUpload(c.Request.Context(), getUserIDFromContext(c), id)
+	if err != nil {
+		if errors.Is(err, uploadservice.ErrNotFound) {
+			respondError(c, http.StatusNotFound, "upload not found")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(c, http.StatusOK, model.APIResponse[*uploadservice.UploadView]{
+		Success: true,
+		Code:    http.StatusOK,
+		Message: "OK",
+		Data:    view,
+	})
+}
+
+// uploadContentHandler 凭签名链接下载文件
+// @Summary      下载上传文件
+// @Tags         Upload
+// @Produce      octet-stream
+// @Param        id         path   int     true  "上传ID"
+// @Param        expires    query  int     true  "过期时间（Unix 秒）"
+// @Param        signature  query  string  true  "签名"
+// @Success      200
+// @Failure      403  {object}  model.APIResponse[any]
+// @Failure      404  {object}  model.APIResponse[any]
+// @Router       /uploads/{id}/content [get]
+func uploadContentHandler(c *gin.Context, svc *uploadservice.Service) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, apierr.ErrInvalidID)
+		return
+	}
+	upload, body, err := svc.Open(c.Request.Context(), id, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, uploadservice.ErrInvalidSignature):
+			respondError(c, http.StatusForbidden, err.Error())
+		case errors.Is(err, uploadservice.ErrNotFound):
+			respondError(c, http.StatusNotFound, "upload not found")
+		default:
+			respondError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	defer body.Close()
+
+	// 链接过期前允许客户端缓存
+	maxAge := int64(0)
+	if exp, err := strconv.ParseInt(c.Query("expires"), 10, 64); err == nil {
+		maxAge = max(exp-time.Now().Unix(), 0)
+	}
+	c.DataFromReader(http.StatusOK, upload.FileSize, upload.MimeType, body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("inline", map[string]string{"filename": upload.FileName}),
+		"Cache-Control":          "private, max-age=" + strconv.FormatInt(maxAge, 10),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func isVideoExt(ext string) bool {
+	for _, v := range middleware.GetVideoConfig().AllowedExtensions {
+		if strings.EqualFold(ext, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	uploadrepo "gamelink/internal/repository/upload"
+	uploadservice "gamelink/internal/service/upload"
+	"gamelink/internal/storage"
+)
+
+func setupUploadTest(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Upload{}))
+	store, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	svc := uploadservice.NewService(uploadrepo.NewUploadRepository(db), store, "test-key", 0)
+
+	engine := gin.New()
+	api := engine.Group("/api/v1")
+	RegisterUploadContentRoutes(api, svc)
+	auth := func(c *gin.Context) { c.Set("user_id", uint64(1)); c.Next() }
+	RegisterUploadRoutes(api, svc, auth)
+	return engine
+}
+
+func multipartUpload(t *testing.T, fileName string, data []byte, uploadType string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", fileName)
+	require.NoError(t, err)
+	_, _ = part.Write(data)
+	if uploadType != "" {
+		require.NoError(t, w.WriteField("type", uploadType))
+	}
+	require.NoError(t, w.Close())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user/uploads", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	router := setupUploadTest(t)
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 3, 2))))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, multipartUpload(t, "proof.png", img.Bytes(), "dispute_evidence"))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp model.APIResponse[uploadservice.UploadView]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, model.UploadTypeDisputeEvidence, resp.Data.UploadType)
+	assert.Equal(t, 3, resp.Data.Width)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, resp.Data.URL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, img.Bytes(), w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, strings.Replace(resp.Data.URL, "signature=", "signature=x", 1), nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestUpload_Rejected(t *testing.T) {
+	router := setupUploadTest(t)
+	cases := []struct {
+		name, file, uploadType string
+		data                   []byte
+	}{
+		{"extension not allowed", "script.sh", "", []byte("#!/bin/sh")},
+		{"content does not match", "fake.png", "", []byte("plain text pretending to be png")},
+		{"image too large", "big.png", "", append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 5<<20)...)},
+		{"unknown type", "a.png", "bogus", []byte("\x89PNG\r\n\x1a\n")},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, multipartUpload(t, tc.file, tc.data, tc.uploadType))
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.name)
+	}
+}
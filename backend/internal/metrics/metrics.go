@@ -17,6 +17,9 @@ var (
 
 	// DBQueryDuration measures gorm operation duration seconds by op (query/create/update/delete) and table.
 	DBQueryDuration *prometheus.HistogramVec
+
+	// OrderClaimsTotal counts player order claims (抢单) by result (claimed/taken/reserved/lock_busy/error).
+	OrderClaimsTotal *prometheus.CounterVec
 )
 
 // Init registers metrics. Safe to call multiple times.
@@ -45,6 +48,21 @@ func Init(reg prometheus.Registerer) {
 			},
 			[]string{"op", "table"},
 		)
-		reg.MustRegister(HTTPRequestsTotal, HTTPRequestDuration, DBQueryDuration)
+		OrderClaimsTotal = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "order_claims_total",
+				Help: "Total number of order claim attempts by result",
+			},
+			[]string{"result"},
+		)
+		reg.MustRegister(HTTPRequestsTotal, HTTPRequestDuration, DBQueryDuration, OrderClaimsTotal)
 	})
 }
+
+// ObserveOrderClaim records an order claim attempt. No-op until Init is called.
+func ObserveOrderClaim(result string) {
+	if OrderClaimsTotal == nil {
+		return
+	}
+	OrderClaimsTotal.WithLabelValues(result).Inc()
+}
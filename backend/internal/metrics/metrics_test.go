@@ -19,9 +19,13 @@ func resetMetricsForTest() {
 	if DBQueryDuration != nil {
 		prometheus.Unregister(DBQueryDuration)
 	}
+	if OrderClaimsTotal != nil {
+		prometheus.Unregister(OrderClaimsTotal)
+	}
 	HTTPRequestsTotal = nil
 	HTTPRequestDuration = nil
 	DBQueryDuration = nil
+	OrderClaimsTotal = nil
 	once = sync.Once{}
 }
 
@@ -29,7 +33,7 @@ func TestInitRegistersMetrics(t *testing.T) {
 	resetMetricsForTest()
 	reg := prometheus.NewRegistry()
 	Init(reg)
-	if HTTPRequestsTotal == nil || HTTPRequestDuration == nil || DBQueryDuration == nil {
+	if HTTPRequestsTotal == nil || HTTPRequestDuration == nil || DBQueryDuration == nil || OrderClaimsTotal == nil {
 		t.Fatal("expected metrics to be initialised")
 	}
 	// second init should be a no-op
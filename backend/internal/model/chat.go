@@ -74,7 +74,8 @@ type ChatMessage struct {
 	Content     string          `json:"content" gorm:"type:text;not null"`
 	MessageType ChatMessageType `json:"messageType" gorm:"column:message_type;type:varchar(16);default:'text'"`
 	ReplyToID   *uint64         `json:"replyToId" gorm:"column:reply_to_id"`
-	ImageURL    string          `json:"imageUrl" gorm:"column:image_url;size:255"`
+	ImageURL    string          `json:"imageUrl" gorm:"column:image_url;size:255"` // 图片消息的限时下载链接，由 ImageUploadID 签发
+	ImageUploadID *uint64       `json:"imageUploadId,omitempty" gorm:"column:image_upload_id"`
 	Metadata    string          `json:"metadata" gorm:"type:json"`
 	IsDeleted   bool            `json:"isDeleted" gorm:"column:is_deleted;default:false"`
 	AuditStatus ChatMessageAuditStatus `json:"auditStatus" gorm:"column:audit_status;type:varchar(16);default:'pending';index"`
@@ -31,8 +31,11 @@ type CommissionRecord struct {
 	CommissionRate     int       `gorm:"not null" json:"commissionRate"`         // 抽成比例
 	CommissionCents    int64     `gorm:"not null" json:"commissionCents"`        // 平台抽成金额
 	PlayerIncomeCents  int64     `gorm:"not null" json:"playerIncomeCents"`      // 陪玩师收入
+	SubsidyCents       int64     `gorm:"not null;default:0" json:"subsidyCents"` // 平台优惠补贴，计入陪玩师收入
+	Currency           Currency  `gorm:"type:char(3);not null;default:'CNY'" json:"currency"` // 与订单币种一致
 	SettlementStatus   string    `gorm:"type:varchar(32);not null;default:'pending'" json:"settlementStatus"` // pending/settled
 	SettlementMonth    string    `gorm:"type:varchar(7);index" json:"settlementMonth"` // YYYY-MM
+	TeamID             *uint64   `gorm:"index" json:"teamId,omitempty"` // 车队订单：按出车成员拆分的分成记录
 	SettledAt          *time.Time `json:"settledAt"`
 	CreatedAt          time.Time `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt          time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
@@ -48,12 +51,14 @@ type MonthlySettlement struct {
 	ID                    uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
 	PlayerID              uint64    `gorm:"not null;index" json:"playerId"`
 	SettlementMonth       string    `gorm:"type:varchar(7);not null;index" json:"settlementMonth"` // YYYY-MM
+	Currency              Currency  `gorm:"type:char(3);not null;default:'CNY'" json:"currency"`   // 每个币种单独结算
 	TotalOrderCount       int64     `gorm:"not null" json:"totalOrderCount"`
 	TotalAmountCents      int64     `gorm:"not null" json:"totalAmountCents"`
 	TotalCommissionCents  int64     `gorm:"not null" json:"totalCommissionCents"`
 	TotalIncomeCents      int64     `gorm:"not null" json:"totalIncomeCents"`
 	BonusCents            int64     `gorm:"default:0" json:"bonusCents"`        // 奖金
-	FinalIncomeCents      int64     `gorm:"not null" json:"finalIncomeCents"`   // 最终收入
+	DeductionCents        int64     `gorm:"default:0" json:"deductionCents"`    // 结转到本期扣除的争议扣款
+	FinalIncomeCents      int64     `gorm:"not null" json:"finalIncomeCents"`   // 最终收入 = 收入 + 奖金 - 扣款
 	Status                string    `gorm:"type:varchar(32);not null;default:'pending'" json:"status"` // pending/confirmed/paid
 	IncomeRank            *int      `json:"incomeRank"`                         // 收入排名
 	OrderRank             *int      `json:"orderRank"`                          // 订单数排名
@@ -68,3 +73,54 @@ func (MonthlySettlement) TableName() string {
 	return "monthly_settlements"
 }
 
+
+// CommissionAdjustmentKind 抽成调整类型
+type CommissionAdjustmentKind string
+
+const (
+	// CommissionAdjustmentPlayerDeduction 争议裁决中由陪玩师承担的扣款
+	CommissionAdjustmentPlayerDeduction CommissionAdjustmentKind = "player_deduction"
+	// CommissionAdjustmentPlatformCompensation 争议裁决中由平台承担的赔付
+	CommissionAdjustmentPlatformCompensation CommissionAdjustmentKind = "platform_compensation"
+)
+
+// CommissionAdjustmentStatus 抽成调整状态
+type CommissionAdjustmentStatus string
+
+const (
+	// CommissionAdjustmentPending 抽成记录所在月份已结算，扣款结转到下一次月结
+	CommissionAdjustmentPending CommissionAdjustmentStatus = "pending"
+	// CommissionAdjustmentApplied 已生效
+	CommissionAdjustmentApplied CommissionAdjustmentStatus = "applied"
+	// CommissionAdjustmentReverted 争议回退后已撤销
+	CommissionAdjustmentReverted CommissionAdjustmentStatus = "reverted"
+)
+
+// CommissionAdjustment 抽成调整
+//
+// 争议退款按责任拆分：陪玩师承担的部分冲减其抽成记录的收入（已月结时结转到下一次月结），
+// 平台承担的部分只记账。每条调整可整体撤销。
+type CommissionAdjustment struct {
+	ID              uint64                     `gorm:"primaryKey;autoIncrement" json:"id"`
+	DisputeID       uint64                     `gorm:"not null;index" json:"disputeId"`
+	OrderID         uint64                     `gorm:"not null;index" json:"orderId"`
+	RecordID        *uint64                    `gorm:"index" json:"recordId,omitempty"` // 被冲减的抽成记录，平台赔付为空
+	PlayerID        uint64                     `gorm:"index" json:"playerId,omitempty"`  // 平台赔付为 0
+	Kind            CommissionAdjustmentKind   `gorm:"type:varchar(32);not null" json:"kind"`
+	AmountCents     int64                      `gorm:"not null" json:"amountCents"`
+	Currency        Currency                   `gorm:"type:char(3);not null;default:'CNY'" json:"currency"`
+	Status          CommissionAdjustmentStatus `gorm:"type:varchar(16);not null;index" json:"status"`
+	CarryOver       bool                       `gorm:"not null;default:false" json:"carryOver"`       // 结转到下一次月结扣除
+	SettlementMonth string                     `gorm:"type:varchar(7);index" json:"settlementMonth"`  // 生效（或待结转）的结算月份
+	SettlementID    *uint64                    `json:"settlementId,omitempty"`                        // 结转扣款所在的月度结算
+	Reason          string                     `gorm:"type:text" json:"reason"`
+	AppliedAt       *time.Time                 `json:"appliedAt,omitempty"`
+	RevertedAt      *time.Time                 `json:"revertedAt,omitempty"`
+	CreatedAt       time.Time                  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt       time.Time                  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (CommissionAdjustment) TableName() string {
+	return "commission_adjustments"
+}
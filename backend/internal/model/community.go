@@ -58,7 +58,8 @@ func (Feed) TableName() string { return "feeds" }
 type FeedImage struct {
 	Base
 	FeedID    uint64 `json:"feedId" gorm:"column:feed_id;index"`
-	URL       string `json:"url" gorm:"column:url;type:text"`
+	UploadID  uint64 `json:"uploadId" gorm:"column:upload_id;index"`
+	URL       string `json:"url" gorm:"column:url;type:text"` // 旧数据的图片地址；按上传引用的图片在读取时签发链接
 	Order     int    `json:"order" gorm:"column:display_order;default:0"`
 	Width     int    `json:"width" gorm:"column:width;default:0"`
 	Height    int    `json:"height" gorm:"column:height;default:0"`
@@ -0,0 +1,140 @@
+package model
+
+import "time"
+
+// CouponType 优惠券类型
+type CouponType string
+
+const (
+	// CouponTypeFixed 立减固定金额
+	CouponTypeFixed CouponType = "fixed"
+	// CouponTypePercent 按比例折扣，可设置封顶金额
+	CouponTypePercent CouponType = "percent"
+	// CouponTypeThreshold 满减：订单满门槛金额立减
+	CouponTypeThreshold CouponType = "threshold"
+)
+
+// CouponBearer 优惠承担方
+type CouponBearer string
+
+const (
+	// CouponBearerPlatform 平台承担：陪玩师按原价分成，优惠从平台抽成中扣除
+	CouponBearerPlatform CouponBearer = "platform"
+	// CouponBearerPlayer 陪玩师承担：平台按原价抽成，优惠从陪玩师收入中扣除
+	CouponBearerPlayer CouponBearer = "player"
+)
+
+// CouponStatus 用户优惠券状态
+type CouponStatus string
+
+const (
+	CouponStatusAvailable CouponStatus = "available" // 可用
+	CouponStatusLocked    CouponStatus = "locked"    // 下单锁定
+	CouponStatusUsed      CouponStatus = "used"      // 订单完成后核销
+	CouponStatusExpired   CouponStatus = "expired"   // 已过期
+)
+
+// CouponSource 优惠券发放方式
+type CouponSource string
+
+const (
+	CouponSourceManual CouponSource = "manual" // 后台手动发放
+	CouponSourceBatch  CouponSource = "batch"  // 后台批量发放
+	CouponSourceEvent  CouponSource = "event"  // 事件触发发放
+)
+
+// CouponEvent 触发发券的业务事件
+type CouponEvent string
+
+const (
+	// CouponEventUserRegistered 新用户注册
+	CouponEventUserRegistered CouponEvent = "user_registered"
+)
+
+// CouponTemplate 优惠券模板
+type CouponTemplate struct {
+	Base
+	Name             string       `json:"name" gorm:"size:128;not null"`
+	Description      string       `json:"description,omitempty" gorm:"type:text"`
+	Type             CouponType   `json:"type" gorm:"size:16;not null"`
+	AmountCents      int64        `json:"amountCents" gorm:"column:amount_cents;default:0"`                 // 立减/满减金额
+	Percent          int          `json:"percent" gorm:"default:0"`                                         // 折扣比例，例如 15 表示减免 15%
+	MaxDiscountCents int64        `json:"maxDiscountCents" gorm:"column:max_discount_cents;default:0"`      // 折扣封顶，0 为不封顶
+	ThresholdCents   int64        `json:"thresholdCents" gorm:"column:threshold_cents;default:0"`           // 使用门槛
+	Currency         Currency     `json:"currency" gorm:"type:char(3);not null;default:'CNY'"`              // 仅限该币种订单使用
+	GameID           *uint64      `json:"gameId,omitempty" gorm:"column:game_id;index"`                     // 限定游戏
+	ItemID           *uint64      `json:"itemId,omitempty" gorm:"column:item_id;index"`                     // 限定服务项目
+	NewUserOnly      bool         `json:"newUserOnly" gorm:"column:new_user_only;default:false"`            // 仅限首单用户
+	PerUserLimit     int          `json:"perUserLimit" gorm:"column:per_user_limit"`                        // 每人可使用次数，0 为不限
+	TotalQuantity    int          `json:"totalQuantity" gorm:"column:total_quantity;default:0"`             // 发放总量，0 为不限
+	IssuedCount      int          `json:"issuedCount" gorm:"column:issued_count;default:0"`                 // 已发放数量
+	ValidDays        int          `json:"validDays" gorm:"column:valid_days;default:0"`                     // 领取后有效天数，0 时以 ValidUntil 为准
+	ValidUntil       *time.Time   `json:"validUntil,omitempty" gorm:"column:valid_until"`                   // 最晚有效期
+	Bearer           CouponBearer `json:"bearer" gorm:"size:16;not null;default:'platform'"`                // 优惠承担方
+	TriggerEvent     CouponEvent  `json:"triggerEvent,omitempty" gorm:"column:trigger_event;size:32;index"` // 自动发放的事件
+	IsActive         bool         `json:"isActive" gorm:"column:is_active"`
+}
+
+// TableName 指定表名
+func (CouponTemplate) TableName() string { return "coupon_templates" }
+
+// Discount 计算订单金额可减免的金额，不满足门槛时为 0，且不超过订单金额
+func (t *CouponTemplate) Discount(amountCents int64) int64 {
+	if amountCents <= 0 || amountCents < t.ThresholdCents {
+		return 0
+	}
+	var discount int64
+	switch t.Type {
+	case CouponTypeFixed, CouponTypeThreshold:
+		discount = t.AmountCents
+	case CouponTypePercent:
+		discount = amountCents * int64(t.Percent) / 100
+		if t.MaxDiscountCents > 0 && discount > t.MaxDiscountCents {
+			discount = t.MaxDiscountCents
+		}
+	}
+	if discount > amountCents {
+		discount = amountCents
+	}
+	return discount
+}
+
+// UserCoupon 发放到用户的优惠券
+type UserCoupon struct {
+	Base
+	TemplateID uint64       `json:"templateId" gorm:"column:template_id;not null;index"`
+	UserID     uint64       `json:"userId" gorm:"column:user_id;not null;index"`
+	Status     CouponStatus `json:"status" gorm:"size:16;not null;default:'available'"`
+	Source     CouponSource `json:"source" gorm:"size:16;not null"`
+	ExpiresAt  *time.Time   `json:"expiresAt,omitempty" gorm:"column:expires_at"`
+	OrderID    *uint64      `json:"orderId,omitempty" gorm:"column:order_id;index"`
+	LockedAt   *time.Time   `json:"lockedAt,omitempty" gorm:"column:locked_at"`
+	UsedAt     *time.Time   `json:"usedAt,omitempty" gorm:"column:used_at"`
+
+	Template *CouponTemplate `json:"template,omitempty" gorm:"foreignKey:TemplateID"`
+}
+
+// TableName 指定表名
+func (UserCoupon) TableName() string { return "user_coupons" }
+
+// SplitDiscounted 按抽成比例拆分实付金额。
+//
+// 抽成基数为优惠前原价；平台承担时陪玩师按原价分成，优惠先冲减平台抽成，
+// 冲减后不足的部分由平台补贴（subsidyCents），满足 抽成 + 陪玩师收入 = 实付 + 补贴；
+// 陪玩师承担时平台按原价抽成，优惠冲减陪玩师收入，抽成以实付金额为限。
+func SplitDiscounted(paidCents, discountCents int64, rate int, bearer CouponBearer) (commissionCents, playerIncomeCents, subsidyCents int64) {
+	gross := paidCents + discountCents
+	commissionCents = gross * int64(rate) / 100
+	if discountCents <= 0 {
+		return commissionCents, paidCents - commissionCents, 0
+	}
+	if bearer == CouponBearerPlayer {
+		commissionCents = min(commissionCents, paidCents)
+		return commissionCents, paidCents - commissionCents, 0
+	}
+	playerIncomeCents = gross - commissionCents
+	if playerIncomeCents > paidCents {
+		return 0, playerIncomeCents, playerIncomeCents - paidCents
+	}
+	return paidCents - playerIncomeCents, playerIncomeCents, 0
+}
@@ -0,0 +1,48 @@
+package model
+
+import "testing"
+
+func TestCouponTemplate_Discount(t *testing.T) {
+	cases := []struct {
+		name   string
+		tmpl   CouponTemplate
+		amount int64
+		want   int64
+	}{
+		{"立减", CouponTemplate{Type: CouponTypeFixed, AmountCents: 500}, 3000, 500},
+		{"立减不超过订单金额", CouponTemplate{Type: CouponTypeFixed, AmountCents: 5000}, 3000, 3000},
+		{"满减未达门槛", CouponTemplate{Type: CouponTypeThreshold, AmountCents: 2000, ThresholdCents: 10000}, 9999, 0},
+		{"满减达到门槛", CouponTemplate{Type: CouponTypeThreshold, AmountCents: 2000, ThresholdCents: 10000}, 10000, 2000},
+		{"折扣", CouponTemplate{Type: CouponTypePercent, Percent: 15}, 10000, 1500},
+		{"折扣封顶", CouponTemplate{Type: CouponTypePercent, Percent: 50, MaxDiscountCents: 1000}, 10000, 1000},
+	}
+	for _, tc := range cases {
+		if got := tc.tmpl.Discount(tc.amount); got != tc.want {
+			t.Fatalf("%s: 期望减免 %d，实际 %d", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSplitDiscounted(t *testing.T) {
+	cases := []struct {
+		name                              string
+		paid, discount                    int64
+		bearer                            CouponBearer
+		wantCommission, want, wantSubsidy int64
+	}{
+		{"无优惠", 10000, 0, CouponBearerPlatform, 2000, 8000, 0},
+		{"平台承担", 9000, 1000, CouponBearerPlatform, 1000, 8000, 0},
+		{"平台承担且优惠超过抽成", 5000, 5000, CouponBearerPlatform, 0, 8000, 3000},
+		{"陪玩师承担", 9000, 1000, CouponBearerPlayer, 2000, 7000, 0},
+		{"陪玩师承担且实付低于抽成", 1000, 9000, CouponBearerPlayer, 1000, 0, 0},
+	}
+	for _, tc := range cases {
+		commission, income, subsidy := SplitDiscounted(tc.paid, tc.discount, 20, tc.bearer)
+		if commission != tc.wantCommission || income != tc.want || subsidy != tc.wantSubsidy {
+			t.Fatalf("%s: 期望抽成 %d/收入 %d/补贴 %d，实际 %d/%d/%d", tc.name, tc.wantCommission, tc.want, tc.wantSubsidy, commission, income, subsidy)
+		}
+		if commission+income != tc.paid+subsidy {
+			t.Fatalf("%s: 抽成与收入之和应等于实付金额加平台补贴", tc.name)
+		}
+	}
+}
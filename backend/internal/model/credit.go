@@ -0,0 +1,198 @@
+package model
+
+import "time"
+
+// 信用分取值范围与新陪玩师的初始分
+const (
+	CreditMinScore     = 0
+	CreditMaxScore     = 100
+	CreditInitialScore = 80
+)
+
+// CreditEventType 信用事件类型
+type CreditEventType string
+
+const (
+	// CreditEventCancelAfterAccept 接单后取消
+	CreditEventCancelAfterAccept CreditEventType = "cancel_after_accept"
+	// CreditEventNoShow 爽约：接单后未按约定时间提供服务
+	CreditEventNoShow CreditEventType = "no_show"
+	// CreditEventDisputeLost 争议裁决为退款，陪玩师败诉
+	CreditEventDisputeLost CreditEventType = "dispute_lost"
+	// CreditEventSLAComplaint 响应或服务时效被投诉
+	CreditEventSLAComplaint CreditEventType = "sla_complaint"
+	// CreditEventGoodReview 收到好评（评分不低于规则阈值）
+	CreditEventGoodReview CreditEventType = "good_review"
+	// CreditEventCompletionStreak 连续完成订单达到规则阈值
+	CreditEventCompletionStreak CreditEventType = "completion_streak"
+	// CreditEventOverride 管理员调整信用分或锁定等级
+	CreditEventOverride CreditEventType = "admin_override"
+	// CreditEventRevoke 撤销此前的事件（如争议裁决被回退）
+	CreditEventRevoke CreditEventType = "revoke"
+)
+
+// IsIncident 是否为需要人工上报的违约事件
+func (t CreditEventType) IsIncident() bool {
+	switch t {
+	case CreditEventCancelAfterAccept, CreditEventNoShow, CreditEventSLAComplaint:
+		return true
+	}
+	return false
+}
+
+// CreditTier 信用等级
+type CreditTier string
+
+const (
+	CreditTierExcellent  CreditTier = "excellent"  // 优秀
+	CreditTierGood       CreditTier = "good"       // 良好
+	CreditTierFair       CreditTier = "fair"       // 一般
+	CreditTierPoor       CreditTier = "poor"       // 较差
+	CreditTierRestricted CreditTier = "restricted" // 受限
+)
+
+// CreditTierPolicy 信用等级对应的接单权限、抽成与曝光
+type CreditTierPolicy struct {
+	Tier                CreditTier `json:"tier"`
+	MinScore            int        `json:"minScore"`            // 达到该分数进入本等级
+	CanGrab             bool       `json:"canGrab"`             // 能否在抢单大厅接单
+	MaxGrabPriceCents   int64      `json:"maxGrabPriceCents"`   // 可接订单的最高金额，0 为不限
+	CommissionRateDelta int        `json:"commissionRateDelta"` // 在选定抽成比例上加减的百分点
+	Listed              bool       `json:"listed"`              // 是否在用户端陪玩师列表中展示
+}
+
+// CreditTierPolicies 信用等级表，按 MinScore 从高到低排列
+var CreditTierPolicies = []CreditTierPolicy{
+	{Tier: CreditTierExcellent, MinScore: 95, CanGrab: true, CommissionRateDelta: -2, Listed: true},
+	{Tier: CreditTierGood, MinScore: 80, CanGrab: true, Listed: true},
+	{Tier: CreditTierFair, MinScore: 60, CanGrab: true, MaxGrabPriceCents: 50000, CommissionRateDelta: 2, Listed: true},
+	{Tier: CreditTierPoor, MinScore: 40, CanGrab: true, MaxGrabPriceCents: 20000, CommissionRateDelta: 5},
+	{Tier: CreditTierRestricted, MinScore: CreditMinScore, CommissionRateDelta: 10},
+}
+
+// AdjustCommissionRate 在抽成比例上加减本等级的百分点，结果限制在 0-100
+func (p CreditTierPolicy) AdjustCommissionRate(rate int) int {
+	rate += p.CommissionRateDelta
+	if rate < 0 {
+		return 0
+	}
+	if rate > 100 {
+		return 100
+	}
+	return rate
+}
+
+// AllowsOrder 本等级能否接该订单
+func (p CreditTierPolicy) AllowsOrder(order *Order) bool {
+	if !p.CanGrab {
+		return false
+	}
+	return p.MaxGrabPriceCents <= 0 || order.TotalPriceCents <= p.MaxGrabPriceCents
+}
+
+// CreditTierForScore 返回分数所在的信用等级
+func CreditTierForScore(score int) CreditTierPolicy {
+	for _, p := range CreditTierPolicies {
+		if score >= p.MinScore {
+			return p
+		}
+	}
+	return CreditTierPolicies[len(CreditTierPolicies)-1]
+}
+
+// CreditTierPolicyOf 返回信用等级的策略，未知等级返回 false
+func CreditTierPolicyOf(tier CreditTier) (CreditTierPolicy, bool) {
+	for _, p := range CreditTierPolicies {
+		if p.Tier == tier {
+			return p, true
+		}
+	}
+	return CreditTierPolicy{}, false
+}
+
+// DefaultCreditTierPolicy 尚无信用记录的陪玩师按初始分所在等级处理
+func DefaultCreditTierPolicy() CreditTierPolicy {
+	return CreditTierForScore(CreditInitialScore)
+}
+
+// PlayerCredit 陪玩师信用分
+type PlayerCredit struct {
+	Base
+	PlayerID         uint64      `json:"playerId" gorm:"column:player_id;uniqueIndex;not null"`
+	Score            int         `json:"score" gorm:"not null"`
+	Tier             CreditTier  `json:"tier" gorm:"size:16;not null;index"`                         // 生效等级：锁定等级优先，否则按分数
+	PinnedTier       *CreditTier `json:"pinnedTier,omitempty" gorm:"column:pinned_tier;size:16"`     // 管理员锁定的等级
+	CompletionStreak int         `json:"completionStreak" gorm:"column:completion_streak;default:0"` // 自上次违约以来连续完成的订单数
+}
+
+// TableName 指定表名
+func (PlayerCredit) TableName() string { return "player_credits" }
+
+// Refresh 按分数与锁定等级重新计算生效等级
+func (c *PlayerCredit) Refresh() {
+	if c.Score < CreditMinScore {
+		c.Score = CreditMinScore
+	}
+	if c.Score > CreditMaxScore {
+		c.Score = CreditMaxScore
+	}
+	if c.PinnedTier != nil {
+		c.Tier = *c.PinnedTier
+		return
+	}
+	c.Tier = CreditTierForScore(c.Score).Tier
+}
+
+// Policy 返回生效等级的策略
+func (c *PlayerCredit) Policy() CreditTierPolicy {
+	if p, ok := CreditTierPolicyOf(c.Tier); ok {
+		return p
+	}
+	return CreditTierForScore(c.Score)
+}
+
+// CreditEvent 信用分变动记录，写入后不再修改（撤销时只标记 RevokedAt 并写一条反向事件）
+type CreditEvent struct {
+	Base
+	PlayerID    uint64          `json:"playerId" gorm:"column:player_id;not null;index"`
+	Type        CreditEventType `json:"type" gorm:"size:32;not null;index"`
+	Delta       int             `json:"delta" gorm:"not null"` // 实际变动分数（已按上下限截断）
+	ScoreAfter  int             `json:"scoreAfter" gorm:"column:score_after;not null"`
+	TierAfter   CreditTier      `json:"tierAfter" gorm:"column:tier_after;size:16;not null"`
+	OrderID     *uint64         `json:"orderId,omitempty" gorm:"column:order_id;index"`
+	DisputeID   *uint64         `json:"disputeId,omitempty" gorm:"column:dispute_id"`
+	ReviewID    *uint64         `json:"reviewId,omitempty" gorm:"column:review_id"`
+	BusinessKey *string         `json:"-" gorm:"column:business_key;size:64;uniqueIndex"` // 同一业务只记一次
+	RevokesID   *uint64         `json:"revokesId,omitempty" gorm:"column:revokes_id"`     // 撤销事件指向被撤销的事件
+	RevokedAt   *time.Time      `json:"revokedAt,omitempty" gorm:"column:revoked_at"`
+	Reason      string          `json:"reason,omitempty" gorm:"type:text"`
+	ActorUserID *uint64         `json:"actorUserId,omitempty" gorm:"column:actor_user_id"`
+}
+
+// TableName 指定表名
+func (CreditEvent) TableName() string { return "credit_events" }
+
+// CreditRule 信用事件的计分规则，可由管理员调整
+type CreditRule struct {
+	Base
+	EventType   CreditEventType `json:"eventType" gorm:"column:event_type;size:32;uniqueIndex;not null"`
+	Delta       int             `json:"delta" gorm:"not null"`      // 每次事件的加减分
+	Threshold   int             `json:"threshold" gorm:"default:0"` // 好评的最低评分、连续完成的单数；其他事件不使用
+	IsActive    bool            `json:"isActive" gorm:"column:is_active"`
+	Description string          `json:"description,omitempty" gorm:"size:255"`
+}
+
+// TableName 指定表名
+func (CreditRule) TableName() string { return "credit_rules" }
+
+// DefaultCreditRules 默认计分规则，启动时补齐缺失的规则
+func DefaultCreditRules() []CreditRule {
+	return []CreditRule{
+		{EventType: CreditEventCancelAfterAccept, Delta: -10, IsActive: true, Description: "接单后取消"},
+		{EventType: CreditEventNoShow, Delta: -15, IsActive: true, Description: "爽约"},
+		{EventType: CreditEventDisputeLost, Delta: -10, IsActive: true, Description: "争议败诉"},
+		{EventType: CreditEventSLAComplaint, Delta: -5, IsActive: true, Description: "时效投诉"},
+		{EventType: CreditEventGoodReview, Delta: 1, Threshold: 5, IsActive: true, Description: "收到五星好评"},
+		{EventType: CreditEventCompletionStreak, Delta: 3, Threshold: 10, IsActive: true, Description: "连续完成 10 单"},
+	}
+}
@@ -10,6 +10,9 @@ const (
 	CurrencyEUR Currency = "EUR"
 )
 
+// DefaultCurrency 平台本位币：历史数据未记录币种时按此处理，陪玩师时薪与科目期初余额也以此计价。
+const DefaultCurrency = CurrencyCNY
+
 // SupportedCurrencies 返回受支持的货币列表。
 func SupportedCurrencies() []Currency {
 	return []Currency{
@@ -33,3 +36,11 @@ func IsValidCurrency(value Currency) bool {
 func (Currency) GormDataType() string {
 	return "char(3)"
 }
+
+// OrDefault 未指定币种时返回本位币。
+func (c Currency) OrDefault() Currency {
+	if c == "" {
+		return DefaultCurrency
+	}
+	return c
+}
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// DispatchMode 派单模式
+type DispatchMode string
+
+// DispatchMode values.
+const (
+	DispatchModePool DispatchMode = "pool" // 进入抢单大厅，由陪玩师自行接单
+	DispatchModePush DispatchMode = "push" // 推送给评分最高的 N 位陪玩师，超时无人接受回落抢单大厅
+	DispatchModeAuto DispatchMode = "auto" // 直接指派给评分最高的陪玩师
+)
+
+// DispatchOfferStatus 派单邀请状态
+type DispatchOfferStatus string
+
+// DispatchOfferStatus values.
+const (
+	DispatchOfferPending  DispatchOfferStatus = "pending"
+	DispatchOfferAccepted DispatchOfferStatus = "accepted"
+	DispatchOfferDeclined DispatchOfferStatus = "declined"
+	DispatchOfferExpired  DispatchOfferStatus = "expired"
+	DispatchOfferCanceled DispatchOfferStatus = "canceled" // 订单已被他人接受或不再可派
+)
+
+// DispatchOffer 推送给陪玩师的派单邀请
+type DispatchOffer struct {
+	ID          uint64              `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID     uint64              `gorm:"not null;index" json:"orderId"`
+	PlayerID    uint64              `gorm:"not null;index:idx_dispatch_offer_player_status,priority:1" json:"playerId"`
+	Rank        int                 `gorm:"not null" json:"rank"` // 推送时的评分排名，从 1 开始
+	Score       float64             `gorm:"not null" json:"score"`
+	Status      DispatchOfferStatus `gorm:"type:varchar(16);not null;index:idx_dispatch_offer_player_status,priority:2;index:idx_dispatch_offer_status_expire,priority:1" json:"status"`
+	ExpiresAt   time.Time           `gorm:"not null;index:idx_dispatch_offer_status_expire,priority:2" json:"expiresAt"`
+	RespondedAt *time.Time          `json:"respondedAt,omitempty"`
+	CreatedAt   time.Time           `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time           `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (DispatchOffer) TableName() string {
+	return "dispatch_offers"
+}
+
+// OrderAssignment 订单指派记录
+type OrderAssignment struct {
+	ID          uint64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID     uint64           `gorm:"not null;index" json:"orderId"`
+	PlayerID    uint64           `gorm:"not null;index" json:"playerId"`
+	Source      AssignmentSource `gorm:"type:varchar(16);not null" json:"source"`
+	Mode        DispatchMode     `gorm:"type:varchar(16)" json:"mode,omitempty"`
+	Score       float64          `json:"score"`
+	OfferID     *uint64          `json:"offerId,omitempty"` // 通过派单邀请接受时关联的邀请
+	ActorUserID *uint64          `json:"actorUserId,omitempty"`
+	CreatedAt   time.Time        `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (OrderAssignment) TableName() string {
+	return "order_assignments"
+}
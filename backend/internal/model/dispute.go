@@ -51,6 +51,8 @@ type OrderDispute struct {
 	Reason               string            `json:"reason" gorm:"column:reason;type:text;not null"`                   // 争议原因
 	Description          string            `json:"description" gorm:"column:description;type:text"`                  // 详细描述
 	EvidenceURLs         EvidenceURLArray  `json:"evidenceUrls" gorm:"column:evidence_urls;type:json"`               // 证据截图URL列表
+	EvidenceUploadIDs    IDList            `json:"evidenceUploadIds" gorm:"column:evidence_upload_ids;type:json"`   // 证据文件的上传ID
+	EvidenceFileURLs     []string          `json:"evidenceFileUrls,omitempty" gorm:"-"`                             // 证据文件的限时下载链接，读取时签发
 	
 	// 指派信息
 	AssignedToUserID     *uint64           `json:"assignedToUserId" gorm:"column:assigned_to_user_id;index"`         // 指派给的客服ID
@@ -61,10 +63,15 @@ type OrderDispute struct {
 	SLADeadline          *time.Time        `json:"slaDeadline" gorm:"column:sla_deadline;index"`                     // SLA 截止时间（默认30分钟）
 	SLABreached          bool              `json:"slaBreached" gorm:"column:sla_breached;default:false"`             // 是否超过SLA
 	SLABreachedAt        *time.Time        `json:"slaBreachedAt" gorm:"column:sla_breached_at"`                      // 超过SLA的时间
+	EscalationLevel      int               `json:"escalationLevel" gorm:"column:escalation_level;default:0"`         // 升级次数，处理层级为 EscalationLevel+1
+	EscalatedAt          *time.Time        `json:"escalatedAt" gorm:"column:escalated_at"`                           // 最近一次升级时间
 	
 	// 处理信息
 	Resolution           DisputeResolution `json:"resolution" gorm:"column:resolution;size:32;default:'pending'"`   // 处理决定
 	ResolutionAmount     int64             `json:"resolutionAmount" gorm:"column:resolution_amount;default:0"`       // 退款金额（分）
+	PlatformCompensationCents int64        `json:"platformCompensationCents" gorm:"column:platform_compensation_cents;default:0"` // 退款中由平台承担的部分（分）
+	PlayerDeductionCents int64             `json:"playerDeductionCents" gorm:"column:player_deduction_cents;default:0"` // 退款中由陪玩师承担、从其收入扣除的部分（分）
+	RefundID             *uint64           `json:"refundId,omitempty" gorm:"column:refund_id"`                       // 裁决发起的退款单
 	ResolutionNotes      string            `json:"resolutionNotes" gorm:"column:resolution_notes;type:text"`         // 处理备注
 	ResolvedAt           *time.Time        `json:"resolvedAt" gorm:"column:resolved_at"`                             // 解决时间
 	ResolvedByUserID     *uint64           `json:"resolvedByUserId" gorm:"column:resolved_by_user_id"`               // 处理人ID
@@ -89,9 +96,18 @@ type OrderDispute struct {
 type EvidenceURLArray []string
 
 // Scan implements the sql.Scanner interface.
+// NULL 列（如未提交完成凭证的订单）扫描为空列表。
 func (e *EvidenceURLArray) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
 		return errors.New("type assertion failed")
 	}
 	return json.Unmarshal(bytes, &e)
@@ -127,13 +143,16 @@ func (d *OrderDispute) GetSLARemaining() int64 {
 	return remaining
 }
 
+// DisputeWindow 订单完成后可发起争议的时长；陪玩师提交完成后的自动确认窗口默认与之一致
+const DisputeWindow = 24 * time.Hour
+
 // CanInitiateDispute checks if a dispute can be initiated for the given order.
 // Disputes can only be initiated within 24 hours of order completion.
 func CanInitiateDispute(order *Order) bool {
 	if order.CompletedAt == nil {
-		// Can also initiate during service (in_progress status)
-		return order.Status == OrderStatusInProgress
+		// Can also initiate during service or while the completion awaits confirmation
+		return order.Status == OrderStatusInProgress || order.Status == OrderStatusPendingConfirmation
 	}
 	// 24 hours after completion
-	return time.Since(*order.CompletedAt) <= 24*time.Hour
+	return time.Since(*order.CompletedAt) <= DisputeWindow
 }
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DisputeAgentTier 客服层级，SLA 超时后争议逐级升级
+type DisputeAgentTier int
+
+// DisputeAgentTier values.
+const (
+	DisputeTierAgent      DisputeAgentTier = 1 // 一线客服
+	DisputeTierSupervisor DisputeAgentTier = 2 // 客服主管
+	DisputeTierManager    DisputeAgentTier = 3 // 客服经理
+)
+
+// DisputeAgent 争议工作台的客服排班，自动指派只在值班客服中挑选
+type DisputeAgent struct {
+	Base
+	UserID         uint64           `json:"userId" gorm:"column:user_id;not null;uniqueIndex"`
+	Tier           DisputeAgentTier `json:"tier" gorm:"column:tier;not null;default:1;index"`
+	OnDuty         bool             `json:"onDuty" gorm:"column:on_duty;default:false;index"`
+	GameIDs        IDList           `json:"gameIds" gorm:"column:game_ids;type:json"`      // 擅长的游戏，为空表示不限
+	MaxOpen        int              `json:"maxOpen" gorm:"column:max_open;default:0"`      // 同时处理的争议上限，0 表示不限
+	LastAssignedAt *time.Time       `json:"lastAssignedAt" gorm:"column:last_assigned_at"` // 负载相同时按最久未指派轮转
+}
+
+// TableName 指定表名
+func (DisputeAgent) TableName() string { return "dispute_agents" }
+
+// Handles 客服是否擅长该游戏
+func (a *DisputeAgent) Handles(gameID uint64) bool {
+	if len(a.GameIDs) == 0 {
+		return true
+	}
+	for _, id := range a.GameIDs {
+		if id == gameID {
+			return true
+		}
+	}
+	return false
+}
+
+// IDList 以 JSON 数组存储的 ID 列表（擅长游戏、证据上传等）
+type IDList []uint64
+
+// Scan implements the sql.Scanner interface.
+func (g *IDList) Scan(value interface{}) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		*g = nil
+		return nil
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("type assertion failed")
+	}
+	return json.Unmarshal(bytes, g)
+}
+
+// Value implements the driver.Valuer interface.
+func (g IDList) Value() (driver.Value, error) {
+	return json.Marshal(g)
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// ExchangeRate 汇率：1 单位 BaseCurrency 可兑换 Rate 单位 QuoteCurrency。
+//
+// 每次刷新追加新行而不覆盖旧值，换算取生效时间最新的一条，历史汇率可追溯。
+type ExchangeRate struct {
+	ID            uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	BaseCurrency  Currency  `gorm:"type:char(3);not null;index:idx_exchange_rate_pair,priority:1" json:"baseCurrency"`
+	QuoteCurrency Currency  `gorm:"type:char(3);not null;index:idx_exchange_rate_pair,priority:2" json:"quoteCurrency"`
+	Rate          float64   `gorm:"type:decimal(20,10);not null" json:"rate"`
+	Source        string    `gorm:"type:varchar(32);not null" json:"source"` // 数据源：local_file / manual 等
+	EffectiveAt   time.Time `gorm:"not null;index:idx_exchange_rate_pair,priority:3" json:"effectiveAt"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
@@ -63,10 +63,47 @@ type FinancialAccount struct {
 	// 关联关系
 	Parent           *FinancialAccount       `json:"parent,omitempty" gorm:"foreignKey:ParentCode;references:Code"`
 	Children         []FinancialAccount      `json:"children,omitempty" gorm:"foreignKey:ParentCode;references:Code"`
-	Vouchers         []FinancialVoucher      `json:"-" gorm:"foreignKey:AccountCode;references:Code"`
+	Entries          []FinancialVoucherEntry `json:"-" gorm:"foreignKey:AccountCode;references:Code"`
 	Transactions     []FinancialTransaction  `json:"-" gorm:"foreignKey:AccountCode;references:Code"`
 }
 
+// 系统科目编码，自动凭证按以下科目记账。
+const (
+	FinancialAccountChannelFunds   = "1012" // 其他货币资金-支付渠道
+	FinancialAccountPlayerPayable  = "2202" // 应付账款-陪玩师
+	FinancialAccountAdvanceReceipt = "2203" // 预收账款-用户订单
+	FinancialAccountCurrentProfit  = "4103" // 本年利润（期末结账转入）
+	FinancialAccountCommission     = "6001" // 主营业务收入-平台抽成
+	FinancialAccountCouponSubsidy  = "6601" // 销售费用-优惠补贴
+	FinancialAccountCompensation   = "6711" // 营业外支出-争议赔付
+)
+
+// SystemFinancialAccounts 返回系统科目表（启动时补齐）。
+func SystemFinancialAccounts() []FinancialAccount {
+	return []FinancialAccount{
+		{Code: FinancialAccountChannelFunds, Name: "其他货币资金-支付渠道", Type: FinancialAccountTypeAsset, Level: FinancialAccountLevel1, Direction: FinancialAccountDirectionDebit, Status: FinancialAccountStatusActive, IsSystem: true, Description: "微信、支付宝等渠道账户资金"},
+		{Code: FinancialAccountPlayerPayable, Name: "应付账款-陪玩师", Type: FinancialAccountTypeLiability, Level: FinancialAccountLevel1, Direction: FinancialAccountDirectionCredit, Status: FinancialAccountStatusActive, IsSystem: true, Description: "已完成订单应付陪玩师的收益"},
+		{Code: FinancialAccountAdvanceReceipt, Name: "预收账款-用户订单", Type: FinancialAccountTypeLiability, Level: FinancialAccountLevel1, Direction: FinancialAccountDirectionCredit, Status: FinancialAccountStatusActive, IsSystem: true, Description: "用户已支付、尚未完成的订单款"},
+		{Code: FinancialAccountCurrentProfit, Name: "本年利润", Type: FinancialAccountTypeEquity, Level: FinancialAccountLevel1, Direction: FinancialAccountDirectionCredit, Status: FinancialAccountStatusActive, IsSystem: true, Description: "期末结账时由收入、费用科目结转"},
+		{Code: FinancialAccountCommission, Name: "主营业务收入-平台抽成", Type: FinancialAccountTypeRevenue, Level: FinancialAccountLevel1, Direction: FinancialAccountDirectionCredit, Status: FinancialAccountStatusActive, IsSystem: true, Description: "订单完成后确认的平台抽成收入"},
+		{Code: FinancialAccountCouponSubsidy, Name: "销售费用-优惠补贴", Type: FinancialAccountTypeExpense, Level: FinancialAccountLevel1, Direction: FinancialAccountDirectionDebit, Status: FinancialAccountStatusActive, IsSystem: true, Description: "平台承担的优惠超过抽成时补贴给陪玩师的部分"},
+		{Code: FinancialAccountCompensation, Name: "营业外支出-争议赔付", Type: FinancialAccountTypeExpense, Level: FinancialAccountLevel1, Direction: FinancialAccountDirectionDebit, Status: FinancialAccountStatusActive, IsSystem: true, Description: "争议裁决中由平台承担的退款"},
+	}
+}
+
+// 自动凭证的业务类型，与业务单号共同保证同一业务事件只记账一次。
+const (
+	LedgerBusinessPaymentReceived = "payment_received" // 收款
+	LedgerBusinessCommission      = "commission_earned" // 确认抽成收入
+	LedgerBusinessPlayerPayable   = "player_payable"   // 确认应付陪玩师
+	LedgerBusinessCouponSubsidy   = "coupon_subsidy"   // 平台优惠补贴
+	LedgerBusinessWithdrawalPaid  = "withdrawal_paid"  // 提现打款
+	LedgerBusinessRefundIssued    = "refund_issued"    // 退款
+	LedgerBusinessDisputeAdjust   = "dispute_adjustment" // 争议退款责任拆分
+	LedgerBusinessReversal        = "reversal"         // 红字冲销
+	LedgerBusinessPeriodClosing   = "period_closing"   // 期末结账
+)
+
 // FinancialVoucherType 凭证类型
 type FinancialVoucherType string
 
@@ -102,10 +139,11 @@ type FinancialVoucher struct {
 	TotalAmount      int64                   `json:"totalAmount" gorm:"column:total_amount;default:0"`                     // 总金额（分）
 
 	// 业务关联
-	BusinessType     string                  `json:"businessType,omitempty" gorm:"column:business_type;size:50"`           // 业务类型
-	BusinessNo       string                  `json:"businessNo,omitempty" gorm:"column:business_no;size:64"`               // 业务单号
+	BusinessType     string                  `json:"businessType,omitempty" gorm:"column:business_type;size:50;index:idx_voucher_business"` // 业务类型
+	BusinessNo       string                  `json:"businessNo,omitempty" gorm:"column:business_no;size:64;index:idx_voucher_business"`     // 业务单号
+	ReversalOfID     *uint64                 `json:"reversalOfId,omitempty" gorm:"column:reversal_of_id;index"`            // 红字冲销的原凭证ID
 
-	// 审计字段
+	// 审计字段（系统自动凭证的制单人为 0）
 	CreatedBy        uint64                  `json:"createdBy" gorm:"column:created_by;not null;index"`                    // 制单人
 	ReviewedBy       *uint64                 `json:"reviewedBy,omitempty" gorm:"column:reviewed_by;index"`                 // 审核人
 	PostedBy         *uint64                 `json:"postedBy,omitempty" gorm:"column:posted_by;index"`                    // 过账人
@@ -114,9 +152,6 @@ type FinancialVoucher struct {
 
 	// 关联关系
 	Entries          []FinancialVoucherEntry `json:"entries,omitempty" gorm:"foreignKey:VoucherID;references:ID"`
-	Creator          User                    `json:"-" gorm:"foreignKey:CreatedBy;references:ID"`
-	Reviewer         *User                   `json:"-" gorm:"foreignKey:ReviewedBy;references:ID"`
-	Poster           *User                   `json:"-" gorm:"foreignKey:PostedBy;references:ID"`
 }
 
 // FinancialVoucherEntry 凭证分录
@@ -213,6 +248,10 @@ type Reconciliation struct {
 	MatchedRecords   int                  `json:"matchedRecords" gorm:"column:matched_records;default:0"`                       // 匹配记录数
 	DifferenceAmount int64                `json:"differenceAmount" gorm:"column:difference_amount;default:0"`                   // 差异金额（分）
 	Abstract         string               `json:"abstract" gorm:"column:abstract;size:500"`                                   // 摘要
+	Provider         PaymentMethod        `json:"provider,omitempty" gorm:"column:provider;size:32;index"`                    // 支付渠道（渠道对账）
+	SourceFile       string               `json:"sourceFile,omitempty" gorm:"column:source_file;size:255"`                    // 导入的账单文件名
+	ExceptionRecords int                  `json:"exceptionRecords" gorm:"column:exception_records;default:0"`                 // 异常记录数
+	PendingRecords   int                  `json:"pendingRecords" gorm:"column:pending_records;default:0"`                     // 待处理异常数
 
 	// 处理信息
 	ProcessedAt      *time.Time           `json:"processedAt,omitempty" gorm:"column:processed_at"`                           // 处理时间
@@ -223,6 +262,14 @@ type Reconciliation struct {
 	Processor        *User                  `json:"-" gorm:"foreignKey:ProcessedBy;references:ID"`
 }
 
+// ReconciliationDetail.Status 取值（对账结果）
+const (
+	ReconciliationDetailMatched         = "matched"          // 两边一致
+	ReconciliationDetailMissingInternal = "missing_internal" // 渠道有、平台无（或平台未入账）
+	ReconciliationDetailMissingExternal = "missing_external" // 平台有、渠道无
+	ReconciliationDetailAmountMismatch  = "amount_mismatch"  // 金额不一致
+)
+
 // ReconciliationDetail 对账明细
 type ReconciliationDetail struct {
 	Base
@@ -233,19 +280,26 @@ type ReconciliationDetail struct {
 	ExternalType     string `json:"externalType" gorm:"column:external_type;size:50"`                 // 外部类型
 	ExternalNo       string `json:"externalNo" gorm:"column:external_no;size:64"`                     // 外部单号
 	ExternalAmount   int64  `json:"externalAmount" gorm:"column:external_amount;not null"`             // 外部金额（分）
-	ExternalDate     time.Time `json:"externalDate" gorm:"column:external_date;not null"`             // 外部日期
+	ExternalDate     *time.Time `json:"externalDate,omitempty" gorm:"column:external_date"`           // 外部日期
 
 	// 内部数据
 	InternalType     string `json:"internalType" gorm:"column:internal_type;size:50"`                 // 内部类型
 	InternalNo       string `json:"internalNo" gorm:"column:internal_no;size:64"`                     // 内部单号
 	InternalAmount   int64  `json:"internalAmount" gorm:"column:internal_amount;not null"`             // 内部金额（分）
-	InternalDate     time.Time `json:"internalDate" gorm:"column:internal_date;not null"`             // 内部日期
+	InternalID       *uint64 `json:"internalId,omitempty" gorm:"column:internal_id"`                 // 内部记录ID（支付或退款）
+	InternalDate     *time.Time `json:"internalDate,omitempty" gorm:"column:internal_date"`           // 内部日期
 
 	// 对账结果
 	Status           string `json:"status" gorm:"column:status;size:20;default:'pending'"`           // 对账状态
 	DifferenceAmount int64  `json:"differenceAmount" gorm:"column:difference_amount;default:0"`       // 差异金额（分）
 	Remark           string `json:"remark,omitempty" gorm:"column:remark;size:500"`                   // 备注
 
+	// 异常处理
+	Resolved         bool       `json:"resolved" gorm:"column:resolved;default:false;index"`          // 是否已处理
+	Resolution       string     `json:"resolution,omitempty" gorm:"column:resolution;size:500"`       // 处理说明
+	ResolvedBy       *uint64    `json:"resolvedBy,omitempty" gorm:"column:resolved_by"`               // 处理人
+	ResolvedAt       *time.Time `json:"resolvedAt,omitempty" gorm:"column:resolved_at"`               // 处理时间
+
 	// 关联关系
 	Reconciliation   Reconciliation `json:"-" gorm:"foreignKey:ReconciliationID;references:ID"`
 }
@@ -281,6 +335,27 @@ type FinancialReport struct {
 	Generator        *User               `json:"-" gorm:"foreignKey:GeneratedBy;references:ID"`
 }
 
+// FinancialReportStatusGenerated 报表已生成
+const FinancialReportStatusGenerated = "generated"
+
+// FinancialPeriodStatusClosed 会计期间已结账
+const FinancialPeriodStatusClosed = "closed"
+
+// FinancialPeriod 会计期间（按自然月结账）
+//
+// 期间结账后不能再录入该期间日期的手工凭证；迟到的自动凭证记入当前日期。
+type FinancialPeriod struct {
+	Base
+	Period           string     `json:"period" gorm:"column:period;size:7;uniqueIndex;not null"`          // 期间 YYYY-MM
+	PeriodStart      time.Time  `json:"periodStart" gorm:"column:period_start;not null"`                  // 期间开始
+	PeriodEnd        time.Time  `json:"periodEnd" gorm:"column:period_end;not null"`                      // 期间结束（不含）
+	Status           string     `json:"status" gorm:"column:status;size:20;default:'closed'"`             // 状态
+	ClosingVoucherID *uint64    `json:"closingVoucherId,omitempty" gorm:"column:closing_voucher_id"`      // 结账凭证ID（损益为零时为空）
+	NetProfit        int64      `json:"netProfit" gorm:"column:net_profit;default:0"`                     // 结转净利润（分）
+	ClosedBy         uint64     `json:"closedBy" gorm:"column:closed_by;not null"`                        // 结账人
+	ClosedAt         time.Time  `json:"closedAt" gorm:"column:closed_at;not null"`                        // 结账时间
+}
+
 // FinancialAccountSetting 科目设置
 type FinancialAccountSetting struct {
 	Base
@@ -324,6 +399,10 @@ func (FinancialReport) TableName() string {
 	return "financial_reports"
 }
 
+func (FinancialPeriod) TableName() string {
+	return "financial_periods"
+}
+
 func (FinancialAccountSetting) TableName() string {
 	return "financial_account_settings"
 }
\ No newline at end of file
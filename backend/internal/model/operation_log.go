@@ -30,11 +30,20 @@ const (
 	OpActionStart        OperationAction = "start"
 	OpActionComplete     OperationAction = "complete"
 
+	// 订单加时
+	OpActionRequestExtension OperationAction = "request_extension"
+	OpActionAcceptExtension  OperationAction = "accept_extension"
+	OpActionDeclineExtension OperationAction = "decline_extension"
+	OpActionCancelExtension  OperationAction = "cancel_extension"
+	OpActionExtendOrder      OperationAction = "extend_order"
+
 	// 支付
 	OpActionCapture    OperationAction = "capture"
 	OpActionRefund     OperationAction = "refund"
 	OpActionUpdate     OperationAction = "update"
 	OpActionUpdateRole OperationAction = "update_role"
+	// OpActionRejectCallback 渠道回调校验不通过（金额/币种/渠道不一致）
+	OpActionRejectCallback OperationAction = "reject_callback"
 
 	// 争议处理
 	OpActionInitiateDispute OperationAction = "initiate_dispute"
@@ -43,17 +52,20 @@ const (
 	OpActionResolveDispute  OperationAction = "resolve_dispute"
 	OpActionRollbackDispute OperationAction = "rollback_dispute"
 	OpActionRejectDispute   OperationAction = "reject_dispute"
+	OpActionEscalateDispute OperationAction = "escalate_dispute"
 )
 
 // OperationEntityType 枚举被审计的实体类型。
 type OperationEntityType string
 
 const (
-	OpEntityOrder   OperationEntityType = "order"
-	OpEntityPayment OperationEntityType = "payment"
-	OpEntityPlayer  OperationEntityType = "player"
-	OpEntityGame    OperationEntityType = "game"
-	OpEntityReview  OperationEntityType = "review"
-	OpEntityUser    OperationEntityType = "user"
-	OpEntityDispute OperationEntityType = "dispute"
+	OpEntityOrder    OperationEntityType = "order"
+	OpEntityPayment  OperationEntityType = "payment"
+	OpEntityPlayer   OperationEntityType = "player"
+	OpEntityGame     OperationEntityType = "game"
+	OpEntityReview   OperationEntityType = "review"
+	OpEntityUser     OperationEntityType = "user"
+	OpEntityDispute  OperationEntityType = "dispute"
+	OpEntityRefund   OperationEntityType = "refund"
+	OpEntityWithdraw OperationEntityType = "withdraw"
 )
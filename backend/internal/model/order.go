@@ -13,6 +13,9 @@ const (
 	OrderStatusCompleted  OrderStatus = "completed"
 	OrderStatusCanceled   OrderStatus = "canceled"
 	OrderStatusRefunded   OrderStatus = "refunded"
+
+	// OrderStatusPendingConfirmation 陪玩师已提交完成，等待用户确认
+	OrderStatusPendingConfirmation OrderStatus = "pending_confirmation"
 )
 
 // Order represents a unified order (护航服务 or 礼物)
@@ -30,8 +33,15 @@ type Order struct {
 	TotalPriceCents   int64    `json:"totalPriceCents" gorm:"column:total_price_cents;not null"`      // 总价（分）
 	CommissionCents   int64    `json:"commissionCents" gorm:"column:commission_cents;default:0"`      // 平台抽成（分）
 	PlayerIncomeCents int64    `json:"playerIncomeCents" gorm:"column:player_income_cents;default:0"` // 陪玩师收入（分）
+	CommissionRate    int      `json:"commissionRate" gorm:"column:commission_rate;default:0"`        // 报价时确定的抽成比例（%）
+	SubsidyCents      int64    `json:"subsidyCents,omitempty" gorm:"column:subsidy_cents;default:0"`  // 平台承担优惠超过抽成时的补贴（分）
 	Currency          Currency `json:"currency,omitempty" gorm:"type:char(3);default:'CNY'"`          // 货币
 
+	// 优惠券：总价为优惠后的实付金额
+	DiscountCents  int64        `json:"discountCents,omitempty" gorm:"column:discount_cents;default:0"`    // 优惠金额（分）
+	CouponID       *uint64      `json:"couponId,omitempty" gorm:"column:coupon_id;index"`                  // 使用的用户优惠券
+	DiscountBearer CouponBearer `json:"discountBearer,omitempty" gorm:"column:discount_bearer;size:16"`    // 优惠承担方
+
 	// 订单信息
 	Status      OrderStatus `json:"status" gorm:"size:32;index;default:'pending'"` // 订单状态
 	Title       string      `json:"title,omitempty" gorm:"size:128"`               // 订单标题
@@ -44,6 +54,11 @@ type Order struct {
 	StartedAt      *time.Time `json:"startedAt,omitempty" gorm:"column:started_at"`           // 实际开始时间
 	CompletedAt    *time.Time `json:"completedAt,omitempty" gorm:"column:completed_at"`       // 完成时间
 
+	// 完成确认：陪玩师提交完成后等待用户确认或提出异议
+	CompletionSubmittedAt *time.Time       `json:"completionSubmittedAt,omitempty" gorm:"column:completion_submitted_at"`       // 陪玩师提交完成时间
+	CompletionProofURLs   EvidenceURLArray `json:"completionProofUrls,omitempty" gorm:"column:completion_proof_urls;type:json"` // 完成凭证截图
+	CompletionNote        string           `json:"completionNote,omitempty" gorm:"column:completion_note;size:500"`             // 陪玩师完成说明
+
 	// 礼物订单字段
 	GiftMessage string     `json:"giftMessage,omitempty" gorm:"column:gift_message;type:text"` // 礼物留言
 	IsAnonymous bool       `json:"isAnonymous" gorm:"column:is_anonymous;default:false"`       // 是否匿名
@@ -59,6 +74,12 @@ type Order struct {
 	OrderConfig string `json:"orderConfig,omitempty" gorm:"column:order_config;type:json"` // 订单配置（JSON）
 	UserNotes   string `json:"userNotes,omitempty" gorm:"column:user_notes;type:text"`     // 用户备注
 
+	// 车队订单字段
+	QueueType        OrderQueueType   `json:"queueType,omitempty" gorm:"column:queue_type;size:16;default:'solo'"` // 接单队列
+	RequiredMembers  int              `json:"requiredMembers,omitempty" gorm:"column:required_members;default:0"`  // 车队订单所需出车人数
+	AssignedTeamID   *uint64          `json:"assignedTeamId,omitempty" gorm:"column:assigned_team_id"`             // 抢到订单的车队
+	AssignmentSource AssignmentSource `json:"assignmentSource,omitempty" gorm:"column:assignment_source;size:16"`  // 指派来源
+
 	// 争议相关字段
 	HasDispute  bool   `json:"hasDispute" gorm:"column:has_dispute;default:false;index"`   // 是否有争议
 	DisputeID   *uint64 `json:"disputeId,omitempty" gorm:"column:dispute_id;index"`        // 关联的争议ID
@@ -72,6 +93,11 @@ type Order struct {
 	Dispute         *OrderDispute `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:DisputeID;references:ID"`
 }
 
+// IsTeamOrder 判断是否为车队订单
+func (o *Order) IsTeamOrder() bool {
+	return o.QueueType == OrderQueueTypeTeam
+}
+
 // IsGiftOrder 判断是否为礼物订单
 func (o *Order) IsGiftOrder() bool {
 	return o.RecipientPlayerID != nil && *o.RecipientPlayerID > 0
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// OrderExtensionStatus 加时申请状态
+type OrderExtensionStatus string
+
+// OrderExtensionStatus values.
+const (
+	OrderExtensionRequested OrderExtensionStatus = "requested" // 用户已申请，等待陪玩师确认
+	OrderExtensionAccepted  OrderExtensionStatus = "accepted"  // 陪玩师已同意，等待用户补款
+	OrderExtensionDeclined  OrderExtensionStatus = "declined"  // 陪玩师拒绝
+	OrderExtensionCanceled  OrderExtensionStatus = "canceled"  // 用户撤回或订单已结束
+	OrderExtensionPaid      OrderExtensionStatus = "paid"      // 补款成功，已计入订单
+)
+
+// OrderExtension 服务中订单的加时申请，补款成功后延长预约结束时间并计入订单金额与抽成
+type OrderExtension struct {
+	Base
+	OrderID           uint64               `json:"orderId" gorm:"column:order_id;not null;index"`
+	UserID            uint64               `json:"userId" gorm:"column:user_id;not null;index"`
+	PlayerID          uint64               `json:"playerId" gorm:"column:player_id;not null;index"`
+	DurationMinutes   int                  `json:"durationMinutes" gorm:"column:duration_minutes;not null"`
+	AmountCents       int64                `json:"amountCents" gorm:"column:amount_cents;not null"`          // 补款金额
+	CommissionRate    int                  `json:"commissionRate" gorm:"column:commission_rate;default:0"`   // 抽成比例（%）
+	CommissionCents   int64                `json:"commissionCents" gorm:"column:commission_cents;default:0"` // 平台抽成
+	PlayerIncomeCents int64                `json:"playerIncomeCents" gorm:"column:player_income_cents;default:0"`
+	Currency          Currency             `json:"currency" gorm:"type:char(3);default:'CNY'"`
+	Status            OrderExtensionStatus `json:"status" gorm:"size:16;not null;index"`
+	Note              string               `json:"note,omitempty" gorm:"type:text"` // 用户留言
+	DeclineReason     string               `json:"declineReason,omitempty" gorm:"column:decline_reason;type:text"`
+	PaymentID         *uint64              `json:"paymentId,omitempty" gorm:"column:payment_id"`     // 补款支付记录
+	RespondedAt       *time.Time           `json:"respondedAt,omitempty" gorm:"column:responded_at"` // 陪玩师处理时间
+	PaidAt            *time.Time           `json:"paidAt,omitempty" gorm:"column:paid_at"`
+}
+
+// TableName 指定表名
+func (OrderExtension) TableName() string { return "order_extensions" }
+
+// IsOpen 申请是否仍待处理或待补款
+func (e *OrderExtension) IsOpen() bool {
+	return e.Status == OrderExtensionRequested || e.Status == OrderExtensionAccepted
+}
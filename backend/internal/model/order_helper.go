@@ -25,3 +25,12 @@ func GenerateGiftOrderNo() string {
 	return GenerateOrderNo("GIFT")
 }
 
+// GeneratePaymentOutTradeNo 生成支付商户单号（提交给支付渠道的 out_trade_no）
+func GeneratePaymentOutTradeNo() string {
+	return GenerateOrderNo("PAY")
+}
+
+// GenerateOutRefundNo 生成退款商户单号（提交给支付渠道的 out_refund_no）
+func GenerateOutRefundNo() string {
+	return GenerateOrderNo("RF")
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// OrderActorRole 订单状态变更的操作方
+type OrderActorRole string
+
+const (
+	OrderActorUser   OrderActorRole = "user"   // 下单用户
+	OrderActorPlayer OrderActorRole = "player" // 陪玩师
+	OrderActorAdmin  OrderActorRole = "admin"  // 后台管理员
+	OrderActorSystem OrderActorRole = "system" // 支付回调、退款结算、定时任务等
+)
+
+// OrderStatusHistory 订单状态流转记录，每次状态变更追加一行，只增不改。
+type OrderStatusHistory struct {
+	ID          uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID     uint64         `gorm:"not null;index" json:"orderId"`
+	FromStatus  OrderStatus    `gorm:"type:varchar(32);not null" json:"fromStatus"`
+	ToStatus    OrderStatus    `gorm:"type:varchar(32);not null" json:"toStatus"`
+	ActorUserID *uint64        `gorm:"index" json:"actorUserId,omitempty"`
+	ActorRole   OrderActorRole `gorm:"type:varchar(16);not null" json:"actorRole"`
+	Reason      string         `gorm:"type:text" json:"reason,omitempty"`
+	TraceID     string         `gorm:"type:varchar(64);index" json:"traceId,omitempty"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
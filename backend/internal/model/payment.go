@@ -29,11 +29,13 @@ const (
 type Payment struct {
 	Base
 	OrderID         uint64          `json:"orderId" gorm:"column:order_id;not null;index"`
+	ExtensionID     *uint64         `json:"extensionId,omitempty" gorm:"column:extension_id;index"` // 加时补款对应的加时申请
 	UserID          uint64          `json:"userId" gorm:"column:user_id;not null;index"`
 	Method          PaymentMethod   `json:"method" gorm:"size:32"`
 	AmountCents     int64           `json:"amountCents" gorm:"column:amount_cents"`
 	Currency        Currency        `json:"currency,omitempty" gorm:"type:char(3)"` // default CNY
 	Status          PaymentStatus   `json:"status" gorm:"size:32;index"`
+	OutTradeNo      string          `json:"outTradeNo,omitempty" gorm:"column:out_trade_no;size:64;uniqueIndex:idx_payment_out_trade_no,where:out_trade_no != ''"` // merchant trade number sent to provider
 	ProviderTradeNo string          `json:"providerTradeNo,omitempty" gorm:"column:provider_trade_no;size:128"`
 	ProviderRaw     json.RawMessage `json:"providerRaw,omitempty" gorm:"column:provider_raw;type:json"` // provider response payload
 	PaidAt          *time.Time      `json:"paidAt,omitempty" gorm:"column:paid_at"`
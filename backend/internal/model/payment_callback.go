@@ -0,0 +1,37 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PaymentCallbackStatus enumerates callback inbox states.
+type PaymentCallbackStatus string
+
+// PaymentCallbackStatus values.
+const (
+	PaymentCallbackStatusReceived  PaymentCallbackStatus = "received"
+	PaymentCallbackStatusProcessed PaymentCallbackStatus = "processed"
+	PaymentCallbackStatusIgnored   PaymentCallbackStatus = "ignored"  // payment no longer pending
+	PaymentCallbackStatusRejected  PaymentCallbackStatus = "rejected" // amount/currency/provider mismatch
+)
+
+// PaymentCallback is the inbox record of a verified provider notification.
+//
+// Each provider trade number is stored once, so retried or concurrent
+// deliveries of the same notification are applied at most once.
+type PaymentCallback struct {
+	Base
+	Provider        PaymentMethod         `json:"provider" gorm:"size:32;not null;uniqueIndex:idx_payment_callback_trade,priority:1"`
+	ProviderTradeNo string                `json:"providerTradeNo" gorm:"column:provider_trade_no;size:128;not null;uniqueIndex:idx_payment_callback_trade,priority:2"`
+	OutTradeNo      string                `json:"outTradeNo" gorm:"column:out_trade_no;size:64;index"`
+	PaymentID       *uint64               `json:"paymentId,omitempty" gorm:"column:payment_id;index"`
+	TradeState      string                `json:"tradeState" gorm:"column:trade_state;size:32"`
+	AmountCents     int64                 `json:"amountCents" gorm:"column:amount_cents"`
+	Currency        Currency              `json:"currency,omitempty" gorm:"type:char(3)"`
+	Source          string                `json:"source" gorm:"size:16"` // notify | query
+	Status          PaymentCallbackStatus `json:"status" gorm:"size:16;index"`
+	Error           string                `json:"error,omitempty" gorm:"type:text"`
+	Payload         json.RawMessage       `json:"payload,omitempty" gorm:"type:json"`
+	ProcessedAt     *time.Time            `json:"processedAt,omitempty" gorm:"column:processed_at"`
+}
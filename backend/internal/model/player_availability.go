@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// PlayerAvailabilitySlot 陪玩师每周可接单时段模板（按服务端时区）
+type PlayerAvailabilitySlot struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	PlayerID    uint64    `gorm:"not null;index:idx_availability_player_day,priority:1" json:"playerId"`
+	Weekday     int       `gorm:"not null;index:idx_availability_player_day,priority:2" json:"weekday"` // 0=周日 ... 6=周六
+	StartMinute int       `gorm:"not null" json:"startMinute"`                                          // 当天零点起的分钟数
+	EndMinute   int       `gorm:"not null" json:"endMinute"`                                            // 不含，最大 1440
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (PlayerAvailabilitySlot) TableName() string {
+	return "player_availability_slots"
+}
+
+// PlayerBlackout 陪玩师临时不可接单时段
+type PlayerBlackout struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	PlayerID  uint64    `gorm:"not null;index:idx_blackout_player_time,priority:1" json:"playerId"`
+	StartAt   time.Time `gorm:"not null;index:idx_blackout_player_time,priority:2" json:"startAt"`
+	EndAt     time.Time `gorm:"not null" json:"endAt"`
+	Reason    string    `gorm:"type:varchar(255)" json:"reason,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (PlayerBlackout) TableName() string {
+	return "player_blackouts"
+}
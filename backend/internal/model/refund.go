@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RefundStatus enumerates refund states.
+type RefundStatus string
+
+// RefundStatus values.
+const (
+	RefundStatusPending    RefundStatus = "pending"    // created, not yet accepted by the provider (retried)
+	RefundStatusProcessing RefundStatus = "processing" // accepted by the provider, awaiting confirmation
+	RefundStatusSucceeded  RefundStatus = "succeeded"
+	RefundStatusFailed     RefundStatus = "failed"
+)
+
+// Holds reports whether the refund still reserves part of the paid amount.
+func (s RefundStatus) Holds() bool {
+	return s != RefundStatusFailed
+}
+
+// RefundSource records which flow requested the refund.
+type RefundSource string
+
+// RefundSource values.
+const (
+	RefundSourceAdmin   RefundSource = "admin"
+	RefundSourceDispute RefundSource = "dispute"
+	RefundSourceSystem  RefundSource = "system"
+	// RefundSourceLatePayment returns a payment captured after its order was closed or
+	// already paid by another payment; it does not change the order.
+	RefundSourceLatePayment RefundSource = "late_payment"
+)
+
+// Refund is a single (possibly partial) refund against an order payment.
+//
+// An order may carry several refunds; the sum of non-failed refunds never
+// exceeds the paid amount. Orders without an online payment (e.g. offline
+// transfers) are refunded with PaymentID nil.
+type Refund struct {
+	Base
+	OrderID          uint64          `json:"orderId" gorm:"column:order_id;not null;index"`
+	PaymentID        *uint64         `json:"paymentId,omitempty" gorm:"column:payment_id;index"`
+	Method           PaymentMethod   `json:"method,omitempty" gorm:"size:32"`
+	AmountCents      int64           `json:"amountCents" gorm:"column:amount_cents;not null"`
+	Currency         Currency        `json:"currency,omitempty" gorm:"type:char(3)"`
+	Reason           string          `json:"reason" gorm:"type:text"`
+	Source           RefundSource    `json:"source" gorm:"size:16"`
+	Status           RefundStatus    `json:"status" gorm:"size:16;index"`
+	OutRefundNo      string          `json:"outRefundNo" gorm:"column:out_refund_no;size:64;uniqueIndex"` // merchant refund number sent to provider
+	ProviderRefundNo string          `json:"providerRefundNo,omitempty" gorm:"column:provider_refund_no;size:128"`
+	ProviderRaw      json.RawMessage `json:"providerRaw,omitempty" gorm:"column:provider_raw;type:json"`
+	RetryCount       int             `json:"retryCount" gorm:"column:retry_count;default:0"`
+	LastError        string          `json:"lastError,omitempty" gorm:"column:last_error;type:text"`
+	RequestedBy      *uint64         `json:"requestedBy,omitempty" gorm:"column:requested_by"`
+	RefundedAt       *time.Time      `json:"refundedAt,omitempty" gorm:"column:refunded_at"`
+}
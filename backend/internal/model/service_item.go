@@ -26,6 +26,7 @@ type ServiceItem struct {
 	PlayerID       *uint64                `gorm:"index" json:"playerId"`
 	RankLevel      string                 `gorm:"type:varchar(32)" json:"rankLevel"`
 	BasePriceCents int64                  `gorm:"not null;default:0" json:"basePriceCents"`
+	Currency       Currency               `gorm:"type:char(3);not null;default:'CNY'" json:"currency"`  // 基础价格币种
 	ServiceHours   int                    `gorm:"not null;default:0" json:"serviceHours"`               // 服务时长（小时），礼物为0
 	CommissionRate float64                `gorm:"type:decimal(5,2);default:0.20" json:"commissionRate"` // 抽成比例
 	MinUsers       int                    `gorm:"default:1" json:"minUsers"`
@@ -50,8 +51,27 @@ func (s *ServiceItem) IsGift() bool {
 
 // CalculateCommission 计算抽成
 func (s *ServiceItem) CalculateCommission(quantity int) (platformCommission, playerIncome int64) {
-	totalAmount := s.BasePriceCents * int64(quantity)
+	return s.SplitAmount(s.BasePriceCents * int64(quantity))
+}
+
+// SplitAmount 按抽成比例拆分任意币种的订单金额
+func (s *ServiceItem) SplitAmount(totalAmount int64) (platformCommission, playerIncome int64) {
 	platformCommission = int64(float64(totalAmount) * s.CommissionRate)
 	playerIncome = totalAmount - platformCommission
 	return platformCommission, playerIncome
 }
+
+// ServiceItemPrice 服务项目在指定币种下的定价；未单独定价的币种按汇率由基础价格换算。
+type ServiceItemPrice struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	ItemID     uint64    `gorm:"not null;uniqueIndex:idx_item_price_currency" json:"itemId"`
+	Currency   Currency  `gorm:"type:char(3);not null;uniqueIndex:idx_item_price_currency" json:"currency"`
+	PriceCents int64     `gorm:"not null" json:"priceCents"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (ServiceItemPrice) TableName() string {
+	return "service_item_prices"
+}
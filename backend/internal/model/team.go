@@ -0,0 +1,148 @@
+package model
+
+import "time"
+
+// OrderQueueType 订单接单队列
+type OrderQueueType string
+
+// OrderQueueType values.
+const (
+	OrderQueueTypeSolo OrderQueueType = "solo" // 单人接单（抢单大厅 / 派单）
+	OrderQueueTypeTeam OrderQueueType = "team" // 车队抢单
+)
+
+// TeamStatus 车队状态
+type TeamStatus string
+
+// TeamStatus values.
+const (
+	TeamStatusActive    TeamStatus = "active"
+	TeamStatusDisbanded TeamStatus = "disbanded"
+)
+
+// TeamRole 车队成员角色
+type TeamRole string
+
+// TeamRole values.
+const (
+	TeamRoleLeader TeamRole = "leader"
+	TeamRoleMember TeamRole = "member"
+)
+
+// TeamMemberStatus 车队成员状态
+type TeamMemberStatus string
+
+// TeamMemberStatus values.
+const (
+	TeamMemberStatusActive  TeamMemberStatus = "active"
+	TeamMemberStatusRemoved TeamMemberStatus = "removed" // 被队长移出或主动退出
+)
+
+// TeamAssignmentStatus 车队接单状态
+type TeamAssignmentStatus string
+
+// TeamAssignmentStatus values.
+const (
+	TeamAssignmentStatusDispatching TeamAssignmentStatus = "dispatching" // 已抢单，队长组队中
+	TeamAssignmentStatusConfirmed   TeamAssignmentStatus = "confirmed"   // 成员均已确认，订单服务中
+	TeamAssignmentStatusReleased    TeamAssignmentStatus = "released"    // 组队超时释放，订单回到车队大厅
+)
+
+// TeamAssignmentMemberState 出车成员确认状态
+type TeamAssignmentMemberState string
+
+// TeamAssignmentMemberState values.
+const (
+	TeamAssignmentMemberPending   TeamAssignmentMemberState = "pending"
+	TeamAssignmentMemberConfirmed TeamAssignmentMemberState = "confirmed"
+)
+
+// TeamProfitMode 车队收入分配方式
+type TeamProfitMode string
+
+// TeamProfitMode values.
+const (
+	TeamProfitModeEqual  TeamProfitMode = "equal"  // 出车成员均分
+	TeamProfitModeCustom TeamProfitMode = "custom" // 队长自定义比例
+)
+
+// Team 车队
+type Team struct {
+	Base
+	Name         string     `json:"name" gorm:"size:64;not null"`
+	LeaderUserID uint64     `json:"leaderUserId" gorm:"column:leader_user_id;not null;index"`
+	GameID       *uint64    `json:"gameId,omitempty" gorm:"column:game_id;index"`
+	Description  string     `json:"description,omitempty" gorm:"type:text"`
+	Status       TeamStatus `json:"status" gorm:"size:16;not null;default:'active';index"`
+}
+
+// TableName 指定表名
+func (Team) TableName() string {
+	return "teams"
+}
+
+// TeamMember 车队成员（须为陪玩师）
+type TeamMember struct {
+	Base
+	TeamID             uint64           `json:"teamId" gorm:"column:team_id;not null;uniqueIndex:idx_team_member_user,priority:1"`
+	UserID             uint64           `json:"userId" gorm:"column:user_id;not null;uniqueIndex:idx_team_member_user,priority:2;index"`
+	PlayerID           uint64           `json:"playerId" gorm:"column:player_id;not null;index"`
+	Role               TeamRole         `json:"role" gorm:"size:16;not null"`
+	Status             TeamMemberStatus `json:"status" gorm:"size:16;not null;default:'active'"`
+	ProfitShareDefault int              `json:"profitShareDefault" gorm:"column:profit_share_default;default:0"` // 默认分成比例（%），仅作队长分配参考
+}
+
+// TableName 指定表名
+func (TeamMember) TableName() string {
+	return "team_members"
+}
+
+// TeamOrderAssignment 车队接单记录；同一订单同时只有一条未释放的记录
+type TeamOrderAssignment struct {
+	Base
+	OrderID          uint64               `json:"orderId" gorm:"column:order_id;not null;index"`
+	TeamID           uint64               `json:"teamId" gorm:"column:team_id;not null;index"`
+	LeaderUserID     uint64               `json:"leaderUserId" gorm:"column:leader_user_id;not null"`
+	Status           TeamAssignmentStatus `json:"status" gorm:"size:16;not null"`
+	DispatchDeadline time.Time            `json:"dispatchDeadline" gorm:"column:dispatch_deadline"` // 组队截止时间，超时未确认自动释放
+	LockedAt         time.Time            `json:"lockedAt" gorm:"column:locked_at"`
+	ConfirmedAt      *time.Time           `json:"confirmedAt,omitempty" gorm:"column:confirmed_at"`
+	ReleasedAt       *time.Time           `json:"releasedAt,omitempty" gorm:"column:released_at"`
+}
+
+// TableName 指定表名
+func (TeamOrderAssignment) TableName() string {
+	return "team_order_assignments"
+}
+
+// TeamAssignmentMember 出车成员及其分成比例
+type TeamAssignmentMember struct {
+	ID           uint64                    `json:"id" gorm:"primaryKey;autoIncrement"`
+	AssignmentID uint64                    `json:"assignmentId" gorm:"column:assignment_id;not null"`
+	MemberID     uint64                    `json:"memberId" gorm:"column:member_id;not null"` // TeamMember.ID
+	UserID       uint64                    `json:"userId" gorm:"column:user_id;not null;index"`
+	PlayerID     uint64                    `json:"playerId" gorm:"column:player_id;not null"`
+	State        TeamAssignmentMemberState `json:"state" gorm:"size:16;not null"`
+	SharePercent int                       `json:"sharePercent" gorm:"column:share_percent;default:0"` // 自定义分成比例（%）
+	ConfirmedAt  *time.Time                `json:"confirmedAt,omitempty" gorm:"column:confirmed_at"`
+	CreatedAt    time.Time                 `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (TeamAssignmentMember) TableName() string {
+	return "team_assignment_members"
+}
+
+// TeamPayoutPlan 队长为一次接单设置的收入分配方案；自定义比例保存在出车成员上
+type TeamPayoutPlan struct {
+	ID           uint64         `json:"id" gorm:"primaryKey;autoIncrement"`
+	AssignmentID uint64         `json:"assignmentId" gorm:"column:assignment_id;not null;uniqueIndex"`
+	ProfitMode   TeamProfitMode `json:"profitMode" gorm:"column:profit_mode;size:16;not null"`
+	CreatedAt    time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (TeamPayoutPlan) TableName() string {
+	return "team_payout_plans"
+}
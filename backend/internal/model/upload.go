@@ -20,6 +20,10 @@ const (
 	UploadTypeReviewImage UploadType = "review_image"
 	// UploadTypeChatImage 聊天图片
 	UploadTypeChatImage UploadType = "chat_image"
+	// UploadTypeDisputeEvidence 争议证据
+	UploadTypeDisputeEvidence UploadType = "dispute_evidence"
+	// UploadTypeFeedImage 动态配图
+	UploadTypeFeedImage UploadType = "feed_image"
 	// UploadTypeOther 其他
 	UploadTypeOther UploadType = "other"
 )
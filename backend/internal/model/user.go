@@ -1,6 +1,13 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/pkg/fieldcrypt"
+)
 
 // Role defines platform roles for access control.
 type Role string
@@ -25,8 +32,10 @@ const (
 // User represents a platform account.
 type User struct {
 	Base
-	Phone        string     `json:"phone,omitempty" gorm:"size:32;uniqueIndex"`
-	Email        string     `json:"email,omitempty" gorm:"size:128;uniqueIndex"`
+	Phone        string     `json:"phone,omitempty" gorm:"size:512;serializer:encrypted"` // 加密存储
+	Email        string     `json:"email,omitempty" gorm:"size:512;serializer:encrypted"` // 加密存储
+	PhoneIndex   *string    `json:"-" gorm:"column:phone_bidx;size:64;uniqueIndex"`        // 手机号盲索引，用于查找与唯一约束
+	EmailIndex   *string    `json:"-" gorm:"column:email_bidx;size:64;uniqueIndex"`        // 邮箱盲索引
 	PasswordHash string     `json:"-" gorm:"column:password_hash;size:255"`
 	Name         string     `json:"name" gorm:"size:64"`
 	AvatarURL    string     `json:"avatarUrl,omitempty" gorm:"column:avatar_url;size:255"`
@@ -37,3 +46,33 @@ type User struct {
 	// 多角色支持（新增）
 	Roles []RoleModel `json:"roles,omitempty" gorm:"many2many:user_roles;"`
 }
+
+const (
+	userPhoneIndexScope = "user.phone"
+	userEmailIndexScope = "user.email"
+)
+
+// UserPhoneIndex 计算手机号盲索引，空手机号返回 nil
+func UserPhoneIndex(phone string) *string {
+	return blindIndexPtr(userPhoneIndexScope, strings.TrimSpace(phone))
+}
+
+// UserEmailIndex 计算邮箱盲索引（忽略大小写），空邮箱返回 nil
+func UserEmailIndex(email string) *string {
+	return blindIndexPtr(userEmailIndexScope, strings.ToLower(strings.TrimSpace(email)))
+}
+
+func blindIndexPtr(scope, value string) *string {
+	if value == "" {
+		return nil
+	}
+	idx := fieldcrypt.BlindIndex(scope, value)
+	return &idx
+}
+
+// BeforeSave 维护手机号、邮箱的盲索引
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.PhoneIndex = UserPhoneIndex(u.Phone)
+	u.EmailIndex = UserEmailIndex(u.Email)
+	return nil
+}
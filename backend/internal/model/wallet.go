@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+// PlayerWallet 陪玩师钱包
+//
+// 余额分三个桶：待结算（订单完成、尚未月结）、可提现、冻结（提现处理中）。
+// 每次变动都写一条 WalletTransaction，钱包行加行锁后更新，Version 兜底校验。
+// 陪玩师每个币种一个钱包，不同币种的余额互不换算。
+type PlayerWallet struct {
+	ID                  uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	PlayerID            uint64    `gorm:"not null;uniqueIndex:idx_wallet_player_currency" json:"playerId"`
+	Currency            Currency  `gorm:"type:char(3);not null;default:'CNY';uniqueIndex:idx_wallet_player_currency" json:"currency"`
+	AvailableCents      int64     `gorm:"not null;default:0" json:"availableCents"`      // 可提现
+	FrozenCents         int64     `gorm:"not null;default:0" json:"frozenCents"`         // 提现冻结
+	PendingCents        int64     `gorm:"not null;default:0" json:"pendingCents"`        // 待结算
+	TotalIncomeCents    int64     `gorm:"not null;default:0" json:"totalIncomeCents"`    // 累计收入
+	TotalWithdrawnCents int64     `gorm:"not null;default:0" json:"totalWithdrawnCents"` // 累计已提现
+	Version             int64     `gorm:"not null;default:0" json:"version"`
+	CreatedAt           time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt           time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (PlayerWallet) TableName() string {
+	return "player_wallets"
+}
+
+// WalletTransactionType 钱包流水类型
+type WalletTransactionType string
+
+const (
+	// WalletTxOpening 钱包上线前的陪玩师开户：历史已月结收入扣除已提现后的可提现余额
+	WalletTxOpening WalletTransactionType = "opening"
+	// WalletTxIncome 订单收入入账（待结算）
+	WalletTxIncome WalletTransactionType = "income"
+	// WalletTxSettle 月结：待结算转可提现
+	WalletTxSettle WalletTransactionType = "settle"
+	// WalletTxWithdrawFreeze 申请提现：可提现转冻结
+	WalletTxWithdrawFreeze WalletTransactionType = "withdraw_freeze"
+	// WalletTxWithdrawRelease 提现被拒绝或失败：冻结退回可提现
+	WalletTxWithdrawRelease WalletTransactionType = "withdraw_release"
+	// WalletTxWithdrawPaid 提现打款完成：扣减冻结
+	WalletTxWithdrawPaid WalletTransactionType = "withdraw_paid"
+	// WalletTxDeduction 争议扣款：冲减待结算（已月结时冲减可提现）
+	WalletTxDeduction WalletTransactionType = "deduction"
+	// WalletTxDeductionRevert 争议回退：退回扣款
+	WalletTxDeductionRevert WalletTransactionType = "deduction_revert"
+)
+
+// 钱包流水关联的业务类型
+const (
+	WalletBusinessCommission = "commission"
+	WalletBusinessWithdraw   = "withdraw"
+	WalletBusinessAdjustment = "commission_adjustment"
+	WalletBusinessOpening    = "wallet_opening"
+)
+
+// WalletTransaction 钱包流水（只增不改）
+//
+// 同一业务单据的同类流水只能有一条，重复调用按幂等处理。
+type WalletTransaction struct {
+	ID             uint64                `gorm:"primaryKey;autoIncrement" json:"id"`
+	WalletID       uint64                `gorm:"not null;index" json:"walletId"`
+	PlayerID       uint64                `gorm:"not null;index" json:"playerId"`
+	Type           WalletTransactionType `gorm:"type:varchar(32);not null;uniqueIndex:idx_wallet_tx_business" json:"type"`
+	BusinessType   string                `gorm:"type:varchar(32);not null;uniqueIndex:idx_wallet_tx_business" json:"businessType"`
+	BusinessID     uint64                `gorm:"not null;uniqueIndex:idx_wallet_tx_business" json:"businessId"`
+	AmountCents    int64                 `gorm:"not null" json:"amountCents"`
+	Currency       Currency              `gorm:"type:char(3);not null;default:'CNY'" json:"currency"`
+	AvailableDelta int64                 `gorm:"not null;default:0" json:"availableDelta"`
+	FrozenDelta    int64                 `gorm:"not null;default:0" json:"frozenDelta"`
+	PendingDelta   int64                 `gorm:"not null;default:0" json:"pendingDelta"`
+	AvailableAfter int64                 `gorm:"not null" json:"availableAfter"`
+	FrozenAfter    int64                 `gorm:"not null" json:"frozenAfter"`
+	PendingAfter   int64                 `gorm:"not null" json:"pendingAfter"`
+	Remark         string                `gorm:"type:varchar(255)" json:"remark"`
+	CreatedAt      time.Time             `gorm:"autoCreateTime;index" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (WalletTransaction) TableName() string {
+	return "wallet_transactions"
+}
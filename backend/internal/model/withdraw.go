@@ -12,6 +12,8 @@ const (
 	WithdrawStatusPending WithdrawStatus = "pending"
 	// WithdrawStatusApproved 已批准
 	WithdrawStatusApproved WithdrawStatus = "approved"
+	// WithdrawStatusProcessing 打款中（已纳入打款批次）
+	WithdrawStatusProcessing WithdrawStatus = "processing"
 	// WithdrawStatusRejected 已拒绝
 	WithdrawStatusRejected WithdrawStatus = "rejected"
 	// WithdrawStatusCompleted 已完成
@@ -20,6 +22,28 @@ const (
 	WithdrawStatusFailed WithdrawStatus = "failed"
 )
 
+// withdrawTransitions 提现状态机：rejected、completed、failed 为终态
+var withdrawTransitions = map[WithdrawStatus][]WithdrawStatus{
+	WithdrawStatusPending:    {WithdrawStatusApproved, WithdrawStatusRejected},
+	WithdrawStatusApproved:   {WithdrawStatusProcessing, WithdrawStatusCompleted, WithdrawStatusFailed},
+	WithdrawStatusProcessing: {WithdrawStatusCompleted, WithdrawStatusFailed},
+}
+
+// CanTransitionTo 判断是否允许从当前状态流转到 next
+func (s WithdrawStatus) CanTransitionTo(next WithdrawStatus) bool {
+	for _, allowed := range withdrawTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal 是否为终态
+func (s WithdrawStatus) IsFinal() bool {
+	return len(withdrawTransitions[s]) == 0
+}
+
 // WithdrawMethod 提现方式
 type WithdrawMethod string
 
@@ -38,14 +62,23 @@ type Withdraw struct {
 	PlayerID    uint64         `gorm:"not null;index" json:"playerId"`
 	UserID      uint64         `gorm:"not null;index" json:"userId"` // 冗余字段，方便查询
 	AmountCents int64          `gorm:"not null" json:"amountCents"`  // 提现金额（分）
+	Currency    Currency       `gorm:"type:char(3);not null;default:'CNY'" json:"currency"` // 从同币种钱包扣减
 	Method      WithdrawMethod `gorm:"type:varchar(32);not null" json:"method"`
-	AccountInfo string         `gorm:"type:varchar(255);not null" json:"accountInfo"` // 账号信息（加密存储）
+	AccountInfo string         `gorm:"type:varchar(1024);not null;serializer:encrypted" json:"accountInfo"` // 账号信息（加密存储）
 	Status      WithdrawStatus `gorm:"type:varchar(32);not null;default:'pending'" json:"status"`
 	RejectReason string        `gorm:"type:text" json:"rejectReason"`    // 拒绝原因
 	AdminRemark  string        `gorm:"type:text" json:"adminRemark"`     // 管理员备注
 	ProcessedBy  *uint64       `gorm:"index" json:"processedBy"`         // 处理人ID
 	ProcessedAt  *time.Time    `json:"processedAt"`                      // 处理时间
 	CompletedAt  *time.Time    `json:"completedAt"`                      // 完成时间
+	// 打款信息：审核通过后纳入批次
+	BatchID          *uint64       `gorm:"index" json:"batchId"`
+	PayoutChannel    PayoutChannel `gorm:"type:varchar(16)" json:"payoutChannel"`
+	OutPayoutNo      string        `gorm:"type:varchar(64);index" json:"outPayoutNo"`      // 商户付款单号，重试沿用
+	ProviderPayoutNo string        `gorm:"type:varchar(128)" json:"providerPayoutNo"`      // 渠道付款单号
+	RetryCount       int           `gorm:"default:0" json:"retryCount"`                    // 渠道提交失败次数
+	NextRetryAt      *time.Time    `gorm:"index" json:"nextRetryAt"`                       // 下次提交或查询时间
+	LastError        string        `gorm:"type:text" json:"lastError"`                     // 最近一次打款错误或失败原因
 	CreatedAt    time.Time     `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt    time.Time     `gorm:"autoUpdateTime" json:"updatedAt"`
 }
@@ -55,3 +88,45 @@ func (Withdraw) TableName() string {
 	return "withdraws"
 }
 
+// PayoutChannel 打款方式
+type PayoutChannel string
+
+const (
+	// PayoutChannelBankFile 生成银行批量代付文件，线下提交后逐笔确认
+	PayoutChannelBankFile PayoutChannel = "bank_file"
+	// PayoutChannelProvider 通过代付渠道接口自动打款
+	PayoutChannelProvider PayoutChannel = "provider"
+)
+
+// WithdrawBatchStatus 打款批次状态
+type WithdrawBatchStatus string
+
+const (
+	// WithdrawBatchStatusProcessing 批次内仍有未终结的提现
+	WithdrawBatchStatusProcessing WithdrawBatchStatus = "processing"
+	// WithdrawBatchStatusCompleted 批次内提现均已完成或失败
+	WithdrawBatchStatusCompleted WithdrawBatchStatus = "completed"
+)
+
+// WithdrawBatch 提现打款批次
+type WithdrawBatch struct {
+	ID               uint64              `gorm:"primaryKey;autoIncrement" json:"id"`
+	BatchNo          string              `gorm:"type:varchar(32);uniqueIndex;not null" json:"batchNo"`
+	Channel          PayoutChannel       `gorm:"type:varchar(16);not null" json:"channel"`
+	Method           *WithdrawMethod     `gorm:"type:varchar(32)" json:"method"` // 为空表示不限提现方式
+	Currency         Currency            `gorm:"type:char(3);not null;default:'CNY'" json:"currency"` // 同一批次只含一种币种
+	Status           WithdrawBatchStatus `gorm:"type:varchar(16);not null;index" json:"status"`
+	TotalCount       int64               `gorm:"not null" json:"totalCount"`
+	TotalAmountCents int64               `gorm:"not null" json:"totalAmountCents"`
+	SuccessCount     int64               `gorm:"default:0" json:"successCount"`
+	FailedCount      int64               `gorm:"default:0" json:"failedCount"`
+	CreatedBy        uint64              `gorm:"not null" json:"createdBy"`
+	CompletedAt      *time.Time          `json:"completedAt"`
+	CreatedAt        time.Time           `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt        time.Time           `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (WithdrawBatch) TableName() string {
+	return "withdraw_batches"
+}
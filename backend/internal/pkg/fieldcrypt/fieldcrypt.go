@@ -0,0 +1,251 @@
+// Package fieldcrypt 提供数据库敏感字段的 AES-GCM 信封加密与盲索引。
+//
+// 每个值使用随机数据密钥加密，数据密钥再由带版本号的主密钥加密后一并保存：
+//
+//	enc:1:<keyID>:<base64(加密的数据密钥)>:<base64(nonce|密文)>
+//
+// 主密钥轮换后旧密文仍可用旧 keyID 解密，由重加密命令逐步迁移到当前主密钥。
+// 不带 enc: 前缀的值视为历史明文，读取时原样返回。
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Prefix 密文前缀（含格式版本）
+const Prefix = "enc:1:"
+
+const keySize = 32
+
+var (
+	// ErrNoKeyring 未配置密钥环却需要解密
+	ErrNoKeyring = errors.New("fieldcrypt: keyring not configured")
+	// ErrUnknownKey 密文使用的主密钥不在密钥环中
+	ErrUnknownKey = errors.New("fieldcrypt: unknown key id")
+	// ErrMalformed 密文格式错误或校验失败
+	ErrMalformed = errors.New("fieldcrypt: malformed ciphertext")
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9.-]{1,32}$`)
+
+var b64 = base64.RawURLEncoding
+
+// Keyring 主密钥环：多个版本的主密钥用于解密，当前主密钥用于加密
+type Keyring struct {
+	activeID string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring 创建密钥环；主密钥必须为 32 字节，盲索引密钥至少 16 字节
+func NewKeyring(activeID string, keys map[string][]byte, blindIndexKey []byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("fieldcrypt: at least one key is required")
+	}
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("fieldcrypt: active key %q not found", activeID)
+	}
+	if len(blindIndexKey) < 16 {
+		return nil, errors.New("fieldcrypt: blind index key must be at least 16 bytes")
+	}
+	k := &Keyring{activeID: activeID, keys: make(map[string]cipher.AEAD, len(keys)), indexKey: append([]byte(nil), blindIndexKey...)}
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("fieldcrypt: invalid key id %q", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("fieldcrypt: key %q must be %d bytes", id, keySize)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// ParseKeyring 从配置创建密钥环，密钥均为 base64 编码
+func ParseKeyring(activeID string, keys map[string]string, blindIndexKey string) (*Keyring, error) {
+	raw := make(map[string][]byte, len(keys))
+	for id, encoded := range keys {
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: decode key %q: %w", id, err)
+		}
+		raw[id] = key
+	}
+	indexKey, err := decodeKey(blindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: decode blind index key: %w", err)
+	}
+	return NewKeyring(activeID, raw, indexKey)
+}
+
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return key, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// ActiveKeyID 当前用于加密的主密钥 ID
+func (k *Keyring) ActiveKeyID() string { return k.activeID }
+
+// Encrypt 加密明文；空字符串保持为空
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	payload, err := seal(data, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return "", err
+	}
+	return Prefix + k.activeID + ":" + b64.EncodeToString(wrapped) + ":" + b64.EncodeToString(payload), nil
+}
+
+// Decrypt 解密密文；历史明文原样返回
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	master, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, parts[0])
+	}
+	wrapped, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	payload, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	dataKey, err := open(master, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	plaintext, err := open(data, payload, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 值为历史明文或未使用当前主密钥时返回 true
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	id, ok := KeyID(value)
+	return !ok || id != k.activeID
+}
+
+// BlindIndex 计算盲索引（HMAC-SHA256），scope 区分不同字段，避免跨字段比对
+func (k *Keyring) BlindIndex(scope, value string) string {
+	return blindIndex(k.indexKey, scope, value)
+}
+
+// IsEncrypted 是否为本包生成的密文
+func IsEncrypted(value string) bool { return strings.HasPrefix(value, Prefix) }
+
+// KeyID 返回密文使用的主密钥 ID
+func KeyID(value string) (string, bool) {
+	if !IsEncrypted(value) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(value, Prefix)
+	i := strings.IndexByte(rest, ':')
+	if i <= 0 {
+		return "", false
+	}
+	return rest[:i], true
+}
+
+var defaultKeyring atomic.Pointer[Keyring]
+
+// SetDefault 设置全局密钥环，GORM 序列化器与盲索引均使用它
+func SetDefault(k *Keyring) { defaultKeyring.Store(k) }
+
+// Default 返回全局密钥环，未配置时为 nil
+func Default() *Keyring { return defaultKeyring.Load() }
+
+// BlindIndex 使用全局密钥环计算盲索引；空值返回空字符串。
+// 未配置密钥环时（仅单元测试）退化为无密钥的 HMAC，结果稳定但不具备保密性。
+func BlindIndex(scope, value string) string {
+	if value == "" {
+		return ""
+	}
+	if k := Default(); k != nil {
+		return k.BlindIndex(scope, value)
+	}
+	return blindIndex(nil, scope, value)
+}
+
+func blindIndex(key []byte, scope, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,153 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func testKeyring(t *testing.T, active string, ids ...string) *Keyring {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte('a' + i)}, keySize)
+	}
+	k, err := NewKeyring(active, keys, []byte("blind-index-key-0123"))
+	require.NoError(t, err)
+	return k
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+
+	c1, err := k.Encrypt("6222 0000 1111 2222")
+	require.NoError(t, err)
+	c2, err := k.Encrypt("6222 0000 1111 2222")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(c1, Prefix+"k1:"))
+	assert.NotEqual(t, c1, c2, "each value uses a fresh data key and nonce")
+
+	plain, err := k.Decrypt(c1)
+	require.NoError(t, err)
+	assert.Equal(t, "6222 0000 1111 2222", plain)
+
+	empty, err := k.Encrypt("")
+	require.NoError(t, err)
+	assert.Equal(t, "", empty)
+
+	// 历史明文原样返回
+	plain, err = k.Decrypt("13800000000")
+	require.NoError(t, err)
+	assert.Equal(t, "13800000000", plain)
+
+	// 篡改密文无法通过校验
+	pos := len(c1) - 10
+	flip := "A"
+	if c1[pos] == 'A' {
+		flip = "B"
+	}
+	tampered := c1[:pos] + flip + c1[pos+1:]
+	_, err = k.Decrypt(tampered)
+	assert.ErrorIs(t, err, ErrMalformed)
+	_, err = k.Decrypt(Prefix + "k1:broken")
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := testKeyring(t, "k1", "k1")
+	c, err := old.Encrypt("alice@example.com")
+	require.NoError(t, err)
+
+	rotated := testKeyring(t, "k2", "k1", "k2")
+	plain, err := rotated.Decrypt(c)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", plain)
+	assert.True(t, rotated.NeedsRotation(c))
+	assert.True(t, rotated.NeedsRotation("plaintext"))
+	assert.False(t, rotated.NeedsRotation(""))
+
+	c2, err := rotated.Encrypt(plain)
+	require.NoError(t, err)
+	id, ok := KeyID(c2)
+	assert.True(t, ok)
+	assert.Equal(t, "k2", id)
+	assert.False(t, rotated.NeedsRotation(c2))
+
+	// 旧密钥下线后，未迁移的密文无法解密
+	retired := testKeyring(t, "k2", "k2")
+	_, err = retired.Decrypt(c)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestBlindIndex(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+	a := k.BlindIndex("user.email", "alice@example.com")
+	assert.Len(t, a, 64)
+	assert.Equal(t, a, k.BlindIndex("user.email", "alice@example.com"))
+	assert.NotEqual(t, a, k.BlindIndex("user.phone", "alice@example.com"), "scopes must not collide")
+
+	// 盲索引不随主密钥轮换变化
+	rotated := testKeyring(t, "k2", "k1", "k2")
+	assert.Equal(t, a, rotated.BlindIndex("user.email", "alice@example.com"))
+}
+
+func TestNewKeyringValidation(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, keySize)
+	index := []byte("blind-index-key-0123")
+	_, err := NewKeyring("k1", nil, index)
+	assert.Error(t, err)
+	_, err = NewKeyring("k2", map[string][]byte{"k1": key}, index)
+	assert.Error(t, err)
+	_, err = NewKeyring("k1", map[string][]byte{"k1": key[:16]}, index)
+	assert.Error(t, err)
+	_, err = NewKeyring("k:1", map[string][]byte{"k:1": key}, index)
+	assert.Error(t, err)
+	_, err = NewKeyring("k1", map[string][]byte{"k1": key}, []byte("short"))
+	assert.Error(t, err)
+
+	k, err := ParseKeyring("k1", map[string]string{"k1": "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="}, "YmxpbmQtaW5kZXgta2V5LTAxMjM=")
+	require.NoError(t, err)
+	assert.Equal(t, "k1", k.ActiveKeyID())
+	_, err = ParseKeyring("k1", map[string]string{"k1": "not base64!"}, "YmxpbmQtaW5kZXgta2V5LTAxMjM=")
+	assert.Error(t, err)
+}
+
+type secret struct {
+	ID      uint64
+	Account string `gorm:"size:512;serializer:encrypted"`
+}
+
+func TestSerializer(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&secret{}))
+	t.Cleanup(func() { SetDefault(nil) })
+
+	// 未配置密钥环时按明文写入
+	SetDefault(nil)
+	require.NoError(t, db.Create(&secret{ID: 1, Account: "legacy"}).Error)
+
+	SetDefault(testKeyring(t, "k1", "k1"))
+	require.NoError(t, db.Create(&secret{ID: 2, Account: "alipay:alice"}).Error)
+
+	var raw string
+	require.NoError(t, db.Raw("SELECT account FROM secrets WHERE id = 2").Scan(&raw).Error)
+	assert.True(t, IsEncrypted(raw))
+	assert.NotContains(t, raw, "alice")
+
+	var got []secret
+	require.NoError(t, db.Order("id").Find(&got).Error)
+	require.Len(t, got, 2)
+	assert.Equal(t, "legacy", got[0].Account)
+	assert.Equal(t, "alipay:alice", got[1].Account)
+
+	SetDefault(nil)
+	err = db.First(&secret{}, 2).Error
+	assert.True(t, errors.Is(err, ErrNoKeyring), "got %v", err)
+}
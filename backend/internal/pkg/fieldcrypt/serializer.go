@@ -0,0 +1,62 @@
+package fieldcrypt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName GORM 序列化器名称，字段标签写作 `gorm:"serializer:encrypted"`
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Serializer 透明加解密 string 字段的 GORM 序列化器
+//
+// 写入时使用全局密钥环的当前主密钥加密；未配置密钥环时（仅单元测试）按明文写入。
+// 注意：map 形式的 Updates 不经过序列化器，加密字段必须以结构体更新。
+type Serializer struct{}
+
+// Scan 解密数据库值
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var raw string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("fieldcrypt: unsupported value %T for field %s", dbValue, field.Name)
+	}
+	plaintext := raw
+	if IsEncrypted(raw) {
+		k := Default()
+		if k == nil {
+			return ErrNoKeyring
+		}
+		var err error
+		if plaintext, err = k.Decrypt(raw); err != nil {
+			return fmt.Errorf("decrypt field %s: %w", field.Name, err)
+		}
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+// Value 加密字段值
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("fieldcrypt: field %s must be a string", field.Name)
+	}
+	k := Default()
+	if k == nil {
+		return plaintext, nil
+	}
+	return k.Encrypt(plaintext)
+}
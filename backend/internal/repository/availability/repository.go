@@ -0,0 +1,100 @@
+package availability
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// bookedStatuses 占用陪玩师时间的订单状态
+var bookedStatuses = []model.OrderStatus{model.OrderStatusConfirmed, model.OrderStatusInProgress}
+
+// CalendarRepository 陪玩师日程仓储：每周可接单模板、临时不可接单时段与已占用的订单
+type CalendarRepository interface {
+	// ListWeekly 列出陪玩师每周模板（按星期、开始时间排序）
+	ListWeekly(ctx context.Context, playerID uint64) ([]model.PlayerAvailabilitySlot, error)
+	// ReplaceWeekly 以 slots 整体替换陪玩师每周模板
+	ReplaceWeekly(ctx context.Context, playerID uint64, slots []model.PlayerAvailabilitySlot) error
+	// ListBlackouts 列出与 [from, to) 有交集的不可接单时段
+	ListBlackouts(ctx context.Context, playerID uint64, from, to time.Time) ([]model.PlayerBlackout, error)
+	CreateBlackout(ctx context.Context, blackout *model.PlayerBlackout) error
+	// DeleteBlackout 删除陪玩师自己的不可接单时段
+	DeleteBlackout(ctx context.Context, playerID, id uint64) error
+	// ListBookings 列出与 [from, to) 有交集的已确认/服务中订单，exceptOrderID 非 0 时排除该订单
+	ListBookings(ctx context.Context, playerID uint64, from, to time.Time, exceptOrderID uint64) ([]model.Order, error)
+}
+
+type calendarRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarRepository 创建陪玩师日程仓储
+func NewCalendarRepository(db *gorm.DB) CalendarRepository {
+	return &calendarRepository{db: db}
+}
+
+func (r *calendarRepository) ListWeekly(ctx context.Context, playerID uint64) ([]model.PlayerAvailabilitySlot, error) {
+	var slots []model.PlayerAvailabilitySlot
+	err := r.db.WithContext(ctx).
+		Where("player_id = ?", playerID).
+		Order("weekday ASC, start_minute ASC").
+		Find(&slots).Error
+	return slots, err
+}
+
+func (r *calendarRepository) ReplaceWeekly(ctx context.Context, playerID uint64, slots []model.PlayerAvailabilitySlot) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("player_id = ?", playerID).Delete(&model.PlayerAvailabilitySlot{}).Error; err != nil {
+			return err
+		}
+		if len(slots) == 0 {
+			return nil
+		}
+		for i := range slots {
+			slots[i].ID = 0
+			slots[i].PlayerID = playerID
+		}
+		return tx.Create(&slots).Error
+	})
+}
+
+func (r *calendarRepository) ListBlackouts(ctx context.Context, playerID uint64, from, to time.Time) ([]model.PlayerBlackout, error) {
+	var blackouts []model.PlayerBlackout
+	err := r.db.WithContext(ctx).
+		Where("player_id = ? AND start_at < ? AND end_at > ?", playerID, to, from).
+		Order("start_at ASC").
+		Find(&blackouts).Error
+	return blackouts, err
+}
+
+func (r *calendarRepository) CreateBlackout(ctx context.Context, blackout *model.PlayerBlackout) error {
+	return r.db.WithContext(ctx).Create(blackout).Error
+}
+
+func (r *calendarRepository) DeleteBlackout(ctx context.Context, playerID, id uint64) error {
+	tx := r.db.WithContext(ctx).Where("id = ? AND player_id = ?", id, playerID).Delete(&model.PlayerBlackout{})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *calendarRepository) ListBookings(ctx context.Context, playerID uint64, from, to time.Time, exceptOrderID uint64) ([]model.Order, error) {
+	query := r.db.WithContext(ctx).
+		Where("player_id = ? AND status IN ?", playerID, bookedStatuses).
+		Where("scheduled_start IS NOT NULL AND scheduled_end IS NOT NULL").
+		Where("scheduled_start < ? AND scheduled_end > ?", to, from)
+	if exceptOrderID != 0 {
+		query = query.Where("id <> ?", exceptOrderID)
+	}
+	var orders []model.Order
+	err := query.Order("scheduled_start ASC").Find(&orders).Error
+	return orders, err
+}
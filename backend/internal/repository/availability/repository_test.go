@@ -0,0 +1,86 @@
+package availability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+func newTestRepo(t *testing.T) (CalendarRepository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.PlayerAvailabilitySlot{}, &model.PlayerBlackout{}, &model.Order{}))
+	return NewCalendarRepository(db), db
+}
+
+func TestCalendarRepository_Weekly(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.ReplaceWeekly(ctx, 1, []model.PlayerAvailabilitySlot{
+		{Weekday: 2, StartMinute: 600, EndMinute: 720},
+		{Weekday: 1, StartMinute: 1200, EndMinute: 1380},
+	}))
+	require.NoError(t, repo.ReplaceWeekly(ctx, 2, []model.PlayerAvailabilitySlot{{Weekday: 1, StartMinute: 0, EndMinute: 60}}))
+
+	slots, err := repo.ListWeekly(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, slots, 2)
+	assert.Equal(t, 1, slots[0].Weekday)
+
+	// 整体替换只影响本人模板
+	require.NoError(t, repo.ReplaceWeekly(ctx, 1, nil))
+	slots, err = repo.ListWeekly(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, slots)
+	slots, err = repo.ListWeekly(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, slots, 1)
+}
+
+func TestCalendarRepository_BlackoutsAndBookings(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+	day := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+
+	b := &model.PlayerBlackout{PlayerID: 1, StartAt: at(10), EndAt: at(12)}
+	require.NoError(t, repo.CreateBlackout(ctx, b))
+	list, err := repo.ListBlackouts(ctx, 1, at(11), at(13))
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+	list, err = repo.ListBlackouts(ctx, 1, at(12), at(13))
+	require.NoError(t, err)
+	assert.Empty(t, list, "adjacent slots do not overlap")
+
+	assert.ErrorIs(t, repo.DeleteBlackout(ctx, 2, b.ID), repository.ErrNotFound)
+	require.NoError(t, repo.DeleteBlackout(ctx, 1, b.ID))
+
+	playerID := uint64(1)
+	mk := func(status model.OrderStatus, start, end int) *model.Order {
+		s, e := at(start), at(end)
+		o := &model.Order{UserID: 9, PlayerID: &playerID, Status: status, ScheduledStart: &s, ScheduledEnd: &e}
+		require.NoError(t, db.Create(o).Error)
+		return o
+	}
+	confirmed := mk(model.OrderStatusConfirmed, 14, 16)
+	mk(model.OrderStatusPending, 14, 16)
+	mk(model.OrderStatusCanceled, 14, 16)
+	mk(model.OrderStatusInProgress, 18, 19)
+
+	orders, err := repo.ListBookings(ctx, 1, at(15), at(20), 0)
+	require.NoError(t, err)
+	assert.Len(t, orders, 2)
+	orders, err = repo.ListBookings(ctx, 1, at(15), at(17), confirmed.ID)
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+}
@@ -0,0 +1,51 @@
+package commission
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+)
+
+// AdjustmentRepository 抽成调整仓储
+type AdjustmentRepository interface {
+	Create(ctx context.Context, adj *model.CommissionAdjustment) error
+	Update(ctx context.Context, adj *model.CommissionAdjustment) error
+	// ListByDispute 返回争议产生的全部调整，按创建顺序
+	ListByDispute(ctx context.Context, disputeID uint64) ([]model.CommissionAdjustment, error)
+	// ListCarryOver 返回结算月份不晚于 month、仍待结转的扣款，按创建顺序
+	ListCarryOver(ctx context.Context, month string) ([]model.CommissionAdjustment, error)
+}
+
+type adjustmentRepository struct {
+	db *gorm.DB
+}
+
+// NewAdjustmentRepository 创建抽成调整仓储
+func NewAdjustmentRepository(db *gorm.DB) AdjustmentRepository {
+	return &adjustmentRepository{db: db}
+}
+
+func (r *adjustmentRepository) Create(ctx context.Context, adj *model.CommissionAdjustment) error {
+	return r.db.WithContext(ctx).Create(adj).Error
+}
+
+func (r *adjustmentRepository) Update(ctx context.Context, adj *model.CommissionAdjustment) error {
+	return r.db.WithContext(ctx).Save(adj).Error
+}
+
+func (r *adjustmentRepository) ListByDispute(ctx context.Context, disputeID uint64) ([]model.CommissionAdjustment, error) {
+	var adjs []model.CommissionAdjustment
+	err := r.db.WithContext(ctx).Where("dispute_id = ?", disputeID).Order("id ASC").Find(&adjs).Error
+	return adjs, err
+}
+
+func (r *adjustmentRepository) ListCarryOver(ctx context.Context, month string) ([]model.CommissionAdjustment, error) {
+	var adjs []model.CommissionAdjustment
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND carry_over = ? AND settlement_month <= ?", model.CommissionAdjustmentPending, true, month).
+		Order("id ASC").
+		Find(&adjs).Error
+	return adjs, err
+}
@@ -0,0 +1,45 @@
+package commission
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+)
+
+func TestAdjustmentRepository_ListCarryOver(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.CommissionAdjustment{}))
+	repo := NewAdjustmentRepository(db)
+	ctx := context.Background()
+
+	adjs := []*model.CommissionAdjustment{
+		{DisputeID: 1, OrderID: 1, PlayerID: 7, Kind: model.CommissionAdjustmentPlayerDeduction, AmountCents: 100, Status: model.CommissionAdjustmentPending, CarryOver: true, SettlementMonth: "2026-03"},
+		{DisputeID: 2, OrderID: 2, PlayerID: 7, Kind: model.CommissionAdjustmentPlayerDeduction, AmountCents: 200, Status: model.CommissionAdjustmentPending, CarryOver: true, SettlementMonth: "2026-05"},
+		{DisputeID: 1, OrderID: 1, PlayerID: 8, Kind: model.CommissionAdjustmentPlayerDeduction, AmountCents: 300, Status: model.CommissionAdjustmentApplied, SettlementMonth: "2026-03"},
+		{DisputeID: 1, OrderID: 1, Kind: model.CommissionAdjustmentPlatformCompensation, AmountCents: 400, Status: model.CommissionAdjustmentApplied},
+	}
+	for _, adj := range adjs {
+		require.NoError(t, repo.Create(ctx, adj))
+	}
+
+	carried, err := repo.ListCarryOver(ctx, "2026-04")
+	require.NoError(t, err)
+	require.Len(t, carried, 1)
+	assert.Equal(t, adjs[0].ID, carried[0].ID)
+
+	adjs[0].Status = model.CommissionAdjustmentApplied
+	require.NoError(t, repo.Update(ctx, adjs[0]))
+	carried, err = repo.ListCarryOver(ctx, "2026-05")
+	require.NoError(t, err)
+	require.Len(t, carried, 1)
+	assert.Equal(t, adjs[1].ID, carried[0].ID)
+
+	byDispute, err := repo.ListByDispute(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, byDispute, 3)
+	assert.Equal(t, []uint64{adjs[0].ID, adjs[2].ID, adjs[3].ID}, []uint64{byDispute[0].ID, byDispute[1].ID, byDispute[2].ID})
+}
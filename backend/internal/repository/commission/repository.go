@@ -8,6 +8,7 @@ import (
 	"gamelink/internal/repository"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CommissionRepository 抽成记录仓储接口
@@ -40,6 +41,17 @@ type CommissionRepository interface {
 	GetPlayerMonthlyIncome(ctx context.Context, playerID uint64, month string) (int64, error)
 }
 
+// MissingRecordFinder 查找已完成但尚未记录抽成的订单，供抽成补记任务使用（可选实现）。
+type MissingRecordFinder interface {
+	// ListCompletedWithoutRecord 返回 since 之后完成、有陪玩师且无抽成记录的非礼物订单ID，按ID升序
+	ListCompletedWithoutRecord(ctx context.Context, since time.Time, limit int) ([]uint64, error)
+}
+
+// RecordLocker 在事务内加行锁读取抽成记录，月结与争议扣款在此排队（可选实现）。
+type RecordLocker interface {
+	GetRecordForUpdate(ctx context.Context, id uint64) (*model.CommissionRecord, error)
+}
+
 // CommissionRuleListOptions 抽成规则查询选项
 type CommissionRuleListOptions struct {
 	Type     *string
@@ -233,6 +245,19 @@ func (r *commissionRepository) GetRecord(ctx context.Context, id uint64) (*model
 	return &record, nil
 }
 
+// GetRecordForUpdate 加行锁读取抽成记录
+func (r *commissionRepository) GetRecordForUpdate(ctx context.Context, id uint64) (*model.CommissionRecord, error) {
+	var record model.CommissionRecord
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
 // GetRecordByOrderID 根据订单ID获取抽成记录
 func (r *commissionRepository) GetRecordByOrderID(ctx context.Context, orderID uint64) (*model.CommissionRecord, error) {
 	var record model.CommissionRecord
@@ -300,6 +325,20 @@ func (r *commissionRepository) UpdateRecord(ctx context.Context, record *model.C
 	return r.db.WithContext(ctx).Save(record).Error
 }
 
+// ListCompletedWithoutRecord 查找已完成但尚未记录抽成的订单
+func (r *commissionRepository) ListCompletedWithoutRecord(ctx context.Context, since time.Time, limit int) ([]uint64, error) {
+	var ids []uint64
+	err := r.db.WithContext(ctx).
+		Model(&model.Order{}).
+		Where("status = ? AND completed_at >= ?", model.OrderStatusCompleted, since).
+		Where("player_id IS NOT NULL AND recipient_player_id IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM commission_records WHERE commission_records.order_id = orders.id)").
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
 // CreateSettlement 创建月度结算
 func (r *commissionRepository) CreateSettlement(ctx context.Context, settlement *model.MonthlySettlement) error {
 	return r.db.WithContext(ctx).Create(settlement).Error
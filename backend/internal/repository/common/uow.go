@@ -6,26 +6,48 @@ import (
 	"gorm.io/gorm"
 
 	"gamelink/internal/repository"
+	"gamelink/internal/repository/commission"
+	"gamelink/internal/repository/coupon"
+	"gamelink/internal/repository/dispute"
+	"gamelink/internal/repository/extension"
 	"gamelink/internal/repository/game"
+	"gamelink/internal/repository/ledger"
 	operationlog "gamelink/internal/repository/operation_log"
 	"gamelink/internal/repository/order"
+	orderhistory "gamelink/internal/repository/order_history"
 	"gamelink/internal/repository/payment"
 	"gamelink/internal/repository/player"
 	playertag "gamelink/internal/repository/player_tag"
+	"gamelink/internal/repository/reconciliation"
 	"gamelink/internal/repository/review"
 	"gamelink/internal/repository/user"
+	"gamelink/internal/repository/wallet"
+	"gamelink/internal/repository/withdraw"
 )
 
 // Repos bundles repository interfaces bound to a specific DB (tx) handle.
 type Repos struct {
-	Games    repository.GameRepository
-	Users    repository.UserRepository
-	Players  repository.PlayerRepository
-	Orders   repository.OrderRepository
-	Payments repository.PaymentRepository
-	Tags     repository.PlayerTagRepository
-	OpLogs   repository.OperationLogRepository
-	Reviews  repository.ReviewRepository
+	Games           repository.GameRepository
+	Users           repository.UserRepository
+	Players         repository.PlayerRepository
+	Orders          repository.OrderRepository
+	OrderHistory    orderhistory.HistoryRepository
+	Payments        repository.PaymentRepository
+	Callbacks       repository.PaymentCallbackRepository
+	Refunds         repository.RefundRepository
+	Ledger          ledger.LedgerRepository
+	Reconciliations reconciliation.ReconciliationRepository
+	Withdraws       withdraw.WithdrawRepository
+	Payouts         withdraw.PayoutRepository
+	Wallets         wallet.WalletRepository
+	Tags            repository.PlayerTagRepository
+	OpLogs          repository.OperationLogRepository
+	Reviews         repository.ReviewRepository
+	Extensions      extension.ExtensionRepository
+	Commissions     commission.CommissionRepository
+	Adjustments     commission.AdjustmentRepository
+	Disputes        repository.DisputeRepository
+	Coupons         coupon.CouponRepository
 }
 
 // UnitOfWork provides a simple transaction wrapper for GORM repositories.
@@ -41,14 +63,27 @@ func NewUnitOfWork(db *gorm.DB) *UnitOfWork { return &UnitOfWork{db: db} }
 func (u *UnitOfWork) WithTx(ctx context.Context, fn func(r *Repos) error) error {
 	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		r := &Repos{
-			Games:    game.NewGameRepository(tx),
-			Users:    user.NewUserRepository(tx),
-			Players:  player.NewPlayerRepository(tx),
-			Orders:   order.NewOrderRepository(tx),
-			Payments: payment.NewPaymentRepository(tx),
-			Tags:     playertag.NewPlayerTagRepository(tx),
-			OpLogs:   operationlog.NewOperationLogRepository(tx),
-			Reviews:  review.NewReviewRepository(tx),
+			Games:           game.NewGameRepository(tx),
+			Users:           user.NewUserRepository(tx),
+			Players:         player.NewPlayerRepository(tx),
+			Orders:          order.NewOrderRepository(tx),
+			OrderHistory:    orderhistory.NewHistoryRepository(tx),
+			Payments:        payment.NewPaymentRepository(tx),
+			Callbacks:       payment.NewPaymentCallbackRepository(tx),
+			Refunds:         payment.NewRefundRepository(tx),
+			Ledger:          ledger.NewLedgerRepository(tx),
+			Reconciliations: reconciliation.NewReconciliationRepository(tx),
+			Withdraws:       withdraw.NewWithdrawRepository(tx),
+			Payouts:         withdraw.NewPayoutRepository(tx),
+			Wallets:         wallet.NewWalletRepository(tx),
+			Tags:            playertag.NewPlayerTagRepository(tx),
+			OpLogs:          operationlog.NewOperationLogRepository(tx),
+			Reviews:         review.NewReviewRepository(tx),
+			Extensions:      extension.NewExtensionRepository(tx),
+			Commissions:     commission.NewCommissionRepository(tx),
+			Adjustments:     commission.NewAdjustmentRepository(tx),
+			Disputes:        dispute.NewDisputeRepository(tx),
+			Coupons:         coupon.NewCouponRepository(tx),
 		}
 		return fn(r)
 	})
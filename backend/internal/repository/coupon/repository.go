@@ -0,0 +1,244 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+var (
+	// ErrSoldOut 模板发放总量已用完
+	ErrSoldOut = errors.New("coupon template sold out")
+	// ErrUsageLimit 用户已锁定或核销的同模板优惠券达到每人使用次数
+	ErrUsageLimit = errors.New("coupon usage limit reached")
+)
+
+// CouponRepository 优惠券仓储：模板、发放与锁定核销
+type CouponRepository interface {
+	CreateTemplate(ctx context.Context, tmpl *model.CouponTemplate) error
+	UpdateTemplate(ctx context.Context, tmpl *model.CouponTemplate) error
+	GetTemplate(ctx context.Context, id uint64) (*model.CouponTemplate, error)
+	ListTemplates(ctx context.Context, page, pageSize int) ([]model.CouponTemplate, int64, error)
+	// ListEventTemplates 列出由事件自动发放的启用模板
+	ListEventTemplates(ctx context.Context, event model.CouponEvent) ([]model.CouponTemplate, error)
+
+	// IssueCoupons 在同一事务中占用模板发放额度并写入用户优惠券，额度不足返回 ErrSoldOut
+	IssueCoupons(ctx context.Context, templateID uint64, coupons []model.UserCoupon) error
+	// CountIssued 统计用户从模板以指定方式领取的张数
+	CountIssued(ctx context.Context, templateID, userID uint64, source model.CouponSource) (int64, error)
+	GetUserCoupon(ctx context.Context, id uint64) (*model.UserCoupon, error)
+	// ListUserCoupons 列出用户的优惠券（最新在前），status 为空时不过滤
+	ListUserCoupons(ctx context.Context, userID uint64, status model.CouponStatus, page, pageSize int) ([]model.UserCoupon, int64, error)
+	// CountRedemptions 统计用户已锁定或核销的同模板优惠券
+	CountRedemptions(ctx context.Context, templateID, userID uint64) (int64, error)
+
+	// LockCoupon 仅当优惠券属于该用户、可用且未过期时锁定，返回是否锁定成功。
+	// 锁定时持有模板行锁校验每人使用次数，超出返回 ErrUsageLimit
+	LockCoupon(ctx context.Context, id, userID uint64, now time.Time) (bool, error)
+	// BindOrder 将锁定的优惠券关联到订单
+	BindOrder(ctx context.Context, id, orderID uint64) error
+	// TransitCoupon 仅当优惠券处于 from 之一时更新为 to；回到可用时解除订单关联
+	TransitCoupon(ctx context.Context, id uint64, from []model.CouponStatus, to model.CouponStatus, at time.Time) (bool, error)
+	// ListSettleable 列出需要结转的优惠券：订单已取消/退款的锁定或已核销券、订单已完成的锁定券，
+	// 以及锁定早于 staleBefore 却未关联订单的券
+	ListSettleable(ctx context.Context, staleBefore time.Time, limit int) ([]model.UserCoupon, error)
+	// ExpireCoupons 将已过期的可用优惠券标记为过期
+	ExpireCoupons(ctx context.Context, now time.Time) (int64, error)
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+// NewCouponRepository 创建优惠券仓储
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{db: db}
+}
+
+func (r *couponRepository) CreateTemplate(ctx context.Context, tmpl *model.CouponTemplate) error {
+	return r.db.WithContext(ctx).Create(tmpl).Error
+}
+
+func (r *couponRepository) UpdateTemplate(ctx context.Context, tmpl *model.CouponTemplate) error {
+	return r.db.WithContext(ctx).Save(tmpl).Error
+}
+
+func (r *couponRepository) GetTemplate(ctx context.Context, id uint64) (*model.CouponTemplate, error) {
+	var tmpl model.CouponTemplate
+	if err := r.db.WithContext(ctx).First(&tmpl, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &tmpl, nil
+}
+
+func (r *couponRepository) ListTemplates(ctx context.Context, page, pageSize int) ([]model.CouponTemplate, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.CouponTemplate{})
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var templates []model.CouponTemplate
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&templates).Error
+	return templates, total, err
+}
+
+func (r *couponRepository) ListEventTemplates(ctx context.Context, event model.CouponEvent) ([]model.CouponTemplate, error) {
+	var templates []model.CouponTemplate
+	err := r.db.WithContext(ctx).Where("trigger_event = ? AND is_active = ?", event, true).Order("id").Find(&templates).Error
+	return templates, err
+}
+
+func (r *couponRepository) IssueCoupons(ctx context.Context, templateID uint64, coupons []model.UserCoupon) error {
+	if len(coupons) == 0 {
+		return nil
+	}
+	n := len(coupons)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.CouponTemplate{}).
+			Where("id = ? AND (total_quantity = 0 OR issued_count + ? <= total_quantity)", templateID, n).
+			Update("issued_count", gorm.Expr("issued_count + ?", n))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSoldOut
+		}
+		return tx.Create(&coupons).Error
+	})
+}
+
+func (r *couponRepository) CountIssued(ctx context.Context, templateID, userID uint64, source model.CouponSource) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.UserCoupon{}).
+		Where("template_id = ? AND user_id = ? AND source = ?", templateID, userID, source).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *couponRepository) GetUserCoupon(ctx context.Context, id uint64) (*model.UserCoupon, error) {
+	var coupon model.UserCoupon
+	if err := r.db.WithContext(ctx).Preload("Template").First(&coupon, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) ListUserCoupons(ctx context.Context, userID uint64, status model.CouponStatus, page, pageSize int) ([]model.UserCoupon, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.UserCoupon{}).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var coupons []model.UserCoupon
+	err := query.Preload("Template").Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&coupons).Error
+	return coupons, total, err
+}
+
+func (r *couponRepository) CountRedemptions(ctx context.Context, templateID, userID uint64) (int64, error) {
+	return countRedemptions(r.db.WithContext(ctx), templateID, userID)
+}
+
+func countRedemptions(db *gorm.DB, templateID, userID uint64) (int64, error) {
+	var count int64
+	err := db.Model(&model.UserCoupon{}).
+		Where("template_id = ? AND user_id = ? AND status IN ?", templateID, userID,
+			[]model.CouponStatus{model.CouponStatusLocked, model.CouponStatusUsed}).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *couponRepository) LockCoupon(ctx context.Context, id, userID uint64, now time.Time) (bool, error) {
+	locked := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var coupon model.UserCoupon
+		if err := tx.Select("id", "template_id").First(&coupon, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		// 模板行锁串行化同模板的锁券，同一用户并发下单不会突破每人使用次数
+		var tmpl model.CouponTemplate
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tmpl, coupon.TemplateID).Error; err != nil {
+			return notFound(err)
+		}
+		if tmpl.PerUserLimit > 0 {
+			used, err := countRedemptions(tx, tmpl.ID, userID)
+			if err != nil {
+				return err
+			}
+			if used >= int64(tmpl.PerUserLimit) {
+				return ErrUsageLimit
+			}
+		}
+		res := tx.Model(&model.UserCoupon{}).
+			Where("id = ? AND user_id = ? AND status = ?", id, userID, model.CouponStatusAvailable).
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Updates(map[string]any{"status": model.CouponStatusLocked, "locked_at": now, "order_id": nil})
+		locked = res.RowsAffected == 1
+		return res.Error
+	})
+	return locked, err
+}
+
+func (r *couponRepository) BindOrder(ctx context.Context, id, orderID uint64) error {
+	return r.db.WithContext(ctx).Model(&model.UserCoupon{}).
+		Where("id = ? AND status = ?", id, model.CouponStatusLocked).
+		Update("order_id", orderID).Error
+}
+
+func (r *couponRepository) TransitCoupon(ctx context.Context, id uint64, from []model.CouponStatus, to model.CouponStatus, at time.Time) (bool, error) {
+	updates := map[string]any{"status": to}
+	switch to {
+	case model.CouponStatusAvailable, model.CouponStatusExpired:
+		updates["order_id"] = nil
+		updates["locked_at"] = nil
+		updates["used_at"] = nil
+	case model.CouponStatusUsed:
+		updates["used_at"] = at
+	}
+	res := r.db.WithContext(ctx).Model(&model.UserCoupon{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *couponRepository) ListSettleable(ctx context.Context, staleBefore time.Time, limit int) ([]model.UserCoupon, error) {
+	closed := []model.OrderStatus{model.OrderStatusCanceled, model.OrderStatusRefunded}
+	var coupons []model.UserCoupon
+	err := r.db.WithContext(ctx).
+		Joins("LEFT JOIN orders ON orders.id = user_coupons.order_id").
+		Where("(user_coupons.status IN ? AND orders.status IN ?) OR (user_coupons.status = ? AND orders.status = ?)"+
+			" OR (user_coupons.status = ? AND user_coupons.order_id IS NULL AND user_coupons.locked_at < ?)",
+			[]model.CouponStatus{model.CouponStatusLocked, model.CouponStatusUsed}, closed,
+			model.CouponStatusLocked, model.OrderStatusCompleted,
+			model.CouponStatusLocked, staleBefore).
+		Preload("Template").
+		Order("user_coupons.id").
+		Limit(limit).
+		Find(&coupons).Error
+	return coupons, err
+}
+
+func (r *couponRepository) ExpireCoupons(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&model.UserCoupon{}).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", model.CouponStatusAvailable, now).
+		Update("status", model.CouponStatusExpired)
+	return res.RowsAffected, res.Error
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package coupon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+func newTestRepo(t *testing.T) (CouponRepository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Order{}, &model.CouponTemplate{}, &model.UserCoupon{}))
+	return NewCouponRepository(db), db
+}
+
+func TestCouponRepository_IssueRespectsTotalQuantity(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	tmpl := &model.CouponTemplate{Name: "立减", Type: model.CouponTypeFixed, AmountCents: 500, TotalQuantity: 2, IsActive: true}
+	require.NoError(t, repo.CreateTemplate(ctx, tmpl))
+
+	batch := []model.UserCoupon{
+		{TemplateID: tmpl.ID, UserID: 1, Status: model.CouponStatusAvailable, Source: model.CouponSourceBatch},
+		{TemplateID: tmpl.ID, UserID: 2, Status: model.CouponStatusAvailable, Source: model.CouponSourceBatch},
+	}
+	require.NoError(t, repo.IssueCoupons(ctx, tmpl.ID, batch))
+	assert.NotZero(t, batch[0].ID)
+
+	err := repo.IssueCoupons(ctx, tmpl.ID, []model.UserCoupon{{TemplateID: tmpl.ID, UserID: 3, Status: model.CouponStatusAvailable, Source: model.CouponSourceManual}})
+	assert.ErrorIs(t, err, ErrSoldOut)
+
+	got, err := repo.GetTemplate(ctx, tmpl.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.IssuedCount)
+
+	n, err := repo.CountIssued(ctx, tmpl.ID, 1, model.CouponSourceBatch)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	_, err = repo.GetUserCoupon(ctx, 999)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestCouponRepository_LockTransitAndSettleable(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	tmpl := &model.CouponTemplate{Name: "立减", Type: model.CouponTypeFixed, AmountCents: 500, IsActive: true}
+	require.NoError(t, repo.CreateTemplate(ctx, tmpl))
+	past := now.Add(-time.Hour)
+	coupons := []model.UserCoupon{
+		{TemplateID: tmpl.ID, UserID: 1, Status: model.CouponStatusAvailable, Source: model.CouponSourceManual},
+		{TemplateID: tmpl.ID, UserID: 1, Status: model.CouponStatusAvailable, Source: model.CouponSourceManual, ExpiresAt: &past},
+	}
+	require.NoError(t, repo.IssueCoupons(ctx, tmpl.ID, coupons))
+
+	// 他人的券、过期券不能锁定；同一张券只能锁定一次
+	ok, err := repo.LockCoupon(ctx, coupons[0].ID, 2, now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = repo.LockCoupon(ctx, coupons[1].ID, 1, now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = repo.LockCoupon(ctx, coupons[0].ID, 1, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.LockCoupon(ctx, coupons[0].ID, 1, now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	n, err := repo.CountRedemptions(ctx, tmpl.ID, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	order := &model.Order{UserID: 1, Status: model.OrderStatusCanceled, TotalPriceCents: 1000}
+	require.NoError(t, db.Create(order).Error)
+	require.NoError(t, repo.BindOrder(ctx, coupons[0].ID, order.ID))
+
+	settleable, err := repo.ListSettleable(ctx, now.Add(-30*time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, settleable, 1)
+	assert.Equal(t, coupons[0].ID, settleable[0].ID)
+	require.NotNil(t, settleable[0].Template)
+
+	ok, err = repo.TransitCoupon(ctx, coupons[0].ID, []model.CouponStatus{model.CouponStatusLocked}, model.CouponStatusAvailable, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	got, err := repo.GetUserCoupon(ctx, coupons[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.CouponStatusAvailable, got.Status)
+	assert.Nil(t, got.OrderID)
+	assert.Nil(t, got.LockedAt)
+
+	expired, err := repo.ExpireCoupons(ctx, now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, expired)
+	list, total, err := repo.ListUserCoupons(ctx, 1, model.CouponStatusAvailable, 1, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Len(t, list, 1)
+}
+
+func TestCouponRepository_LockEnforcesPerUserLimit(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	tmpl := &model.CouponTemplate{Name: "立减", Type: model.CouponTypeFixed, AmountCents: 500, PerUserLimit: 1, IsActive: true}
+	require.NoError(t, repo.CreateTemplate(ctx, tmpl))
+	coupons := []model.UserCoupon{
+		{TemplateID: tmpl.ID, UserID: 1, Status: model.CouponStatusAvailable, Source: model.CouponSourceManual},
+		{TemplateID: tmpl.ID, UserID: 1, Status: model.CouponStatusAvailable, Source: model.CouponSourceManual},
+	}
+	require.NoError(t, repo.IssueCoupons(ctx, tmpl.ID, coupons))
+
+	ok, err := repo.LockCoupon(ctx, coupons[0].ID, 1, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	// 限额在锁券时校验，不依赖调用方事先统计
+	ok, err = repo.LockCoupon(ctx, coupons[1].ID, 1, now)
+	assert.ErrorIs(t, err, ErrUsageLimit)
+	assert.False(t, ok)
+
+	ok, err = repo.TransitCoupon(ctx, coupons[0].ID, []model.CouponStatus{model.CouponStatusLocked}, model.CouponStatusAvailable, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = repo.LockCoupon(ctx, coupons[1].ID, 1, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,173 @@
+package credit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// ErrDuplicateEvent 同一业务键的信用事件已记录，或被撤销的事件已撤销过
+var ErrDuplicateEvent = errors.New("credit event already recorded")
+
+// CreditRepository 陪玩师信用仓储：计分规则、信用分与变动记录
+type CreditRepository interface {
+	ListRules(ctx context.Context) ([]model.CreditRule, error)
+	GetRule(ctx context.Context, eventType model.CreditEventType) (*model.CreditRule, error)
+	UpdateRule(ctx context.Context, rule *model.CreditRule) error
+	// EnsureRules 补齐缺失的规则，已有规则保持不变
+	EnsureRules(ctx context.Context, rules []model.CreditRule) error
+
+	GetCredit(ctx context.Context, playerID uint64) (*model.PlayerCredit, error)
+	// ListCredits 批量查询信用分，没有记录的陪玩师不在结果中
+	ListCredits(ctx context.Context, playerIDs []uint64) ([]model.PlayerCredit, error)
+	GetEventByKey(ctx context.Context, key string) (*model.CreditEvent, error)
+	// ListEvents 分页列出陪玩师的信用变动（最新在前）
+	ListEvents(ctx context.Context, playerID uint64, page, pageSize int) ([]model.CreditEvent, int64, error)
+
+	// ApplyEvent 在同一事务中锁定（不存在时按初始分创建）陪玩师信用，由 fn 修改信用并返回要写入的事件，
+	// 然后保存信用与事件；fn 返回 nil 时只保存信用。
+	// 撤销事件会释放被撤销事件的业务键，同一业务之后可以重新记录。
+	// key 对应的事件已存在，或事件撤销的目标已被撤销时返回 ErrDuplicateEvent。
+	ApplyEvent(ctx context.Context, playerID uint64, key *string, fn func(credit *model.PlayerCredit) *model.CreditEvent) (*model.CreditEvent, error)
+}
+
+type creditRepository struct {
+	db *gorm.DB
+}
+
+// NewCreditRepository 创建信用仓储
+func NewCreditRepository(db *gorm.DB) CreditRepository {
+	return &creditRepository{db: db}
+}
+
+func (r *creditRepository) ListRules(ctx context.Context) ([]model.CreditRule, error) {
+	var rules []model.CreditRule
+	err := r.db.WithContext(ctx).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *creditRepository) GetRule(ctx context.Context, eventType model.CreditEventType) (*model.CreditRule, error) {
+	var rule model.CreditRule
+	if err := r.db.WithContext(ctx).Where("event_type = ?", eventType).First(&rule).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &rule, nil
+}
+
+func (r *creditRepository) UpdateRule(ctx context.Context, rule *model.CreditRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+func (r *creditRepository) EnsureRules(ctx context.Context, rules []model.CreditRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_type"}}, DoNothing: true}).Create(&rules).Error
+}
+
+func (r *creditRepository) GetCredit(ctx context.Context, playerID uint64) (*model.PlayerCredit, error) {
+	var credit model.PlayerCredit
+	if err := r.db.WithContext(ctx).Where("player_id = ?", playerID).First(&credit).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &credit, nil
+}
+
+func (r *creditRepository) ListCredits(ctx context.Context, playerIDs []uint64) ([]model.PlayerCredit, error) {
+	if len(playerIDs) == 0 {
+		return nil, nil
+	}
+	var credits []model.PlayerCredit
+	err := r.db.WithContext(ctx).Where("player_id IN ?", playerIDs).Find(&credits).Error
+	return credits, err
+}
+
+func (r *creditRepository) GetEventByKey(ctx context.Context, key string) (*model.CreditEvent, error) {
+	var event model.CreditEvent
+	if err := r.db.WithContext(ctx).Where("business_key = ?", key).First(&event).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &event, nil
+}
+
+func (r *creditRepository) ListEvents(ctx context.Context, playerID uint64, page, pageSize int) ([]model.CreditEvent, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.CreditEvent{}).Where("player_id = ?", playerID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var events []model.CreditEvent
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error
+	return events, total, err
+}
+
+func (r *creditRepository) ApplyEvent(ctx context.Context, playerID uint64, key *string, fn func(credit *model.PlayerCredit) *model.CreditEvent) (*model.CreditEvent, error) {
+	var event *model.CreditEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if key != nil {
+			var count int64
+			if err := tx.Model(&model.CreditEvent{}).Where("business_key = ?", *key).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrDuplicateEvent
+			}
+		}
+		credit, err := lockCredit(tx, playerID)
+		if err != nil {
+			return err
+		}
+		event = fn(credit)
+		if err := tx.Save(credit).Error; err != nil {
+			return err
+		}
+		if event == nil {
+			return nil
+		}
+		if event.RevokesID != nil {
+			res := tx.Model(&model.CreditEvent{}).
+				Where("id = ? AND player_id = ? AND revoked_at IS NULL", *event.RevokesID, playerID).
+				Updates(map[string]any{"revoked_at": time.Now(), "business_key": nil})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrDuplicateEvent
+			}
+		}
+		event.PlayerID = playerID
+		event.BusinessKey = key
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// lockCredit 锁定陪玩师信用行，不存在时按初始分创建
+func lockCredit(tx *gorm.DB, playerID uint64) (*model.PlayerCredit, error) {
+	initial := model.PlayerCredit{PlayerID: playerID, Score: model.CreditInitialScore}
+	initial.Refresh()
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "player_id"}}, DoNothing: true}).Create(&initial).Error; err != nil {
+		return nil, err
+	}
+	var credit model.PlayerCredit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("player_id = ?", playerID).First(&credit).Error; err != nil {
+		return nil, err
+	}
+	return &credit, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
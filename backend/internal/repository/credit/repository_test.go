@@ -0,0 +1,105 @@
+package credit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+func newTestRepo(t *testing.T) CreditRepository {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.PlayerCredit{}, &model.CreditEvent{}, &model.CreditRule{}))
+	return NewCreditRepository(db)
+}
+
+func deduct(delta int) func(*model.PlayerCredit) *model.CreditEvent {
+	return func(credit *model.PlayerCredit) *model.CreditEvent {
+		credit.Score += delta
+		credit.Refresh()
+		return &model.CreditEvent{Type: model.CreditEventNoShow, Delta: delta, ScoreAfter: credit.Score, TierAfter: credit.Tier}
+	}
+}
+
+func TestCreditRepository_ApplyEventCreatesInitialCreditAndIsIdempotent(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	_, err := repo.GetCredit(ctx, 7)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	key := "no_show:order:1"
+	event, err := repo.ApplyEvent(ctx, 7, &key, deduct(-15))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), event.PlayerID)
+	assert.Equal(t, model.CreditInitialScore-15, event.ScoreAfter)
+
+	_, err = repo.ApplyEvent(ctx, 7, &key, deduct(-15))
+	assert.ErrorIs(t, err, ErrDuplicateEvent)
+
+	credit, err := repo.GetCredit(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditInitialScore-15, credit.Score)
+	assert.Equal(t, model.CreditTierFair, credit.Tier)
+
+	events, total, err := repo.ListEvents(ctx, 7, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, events, 1)
+}
+
+func TestCreditRepository_RevokeFreesBusinessKey(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	key := "dispute_lost:dispute:3"
+	original, err := repo.ApplyEvent(ctx, 7, &key, deduct(-10))
+	require.NoError(t, err)
+
+	revoke := func(credit *model.PlayerCredit) *model.CreditEvent {
+		event := deduct(10)(credit)
+		event.Type = model.CreditEventRevoke
+		event.RevokesID = &original.ID
+		return event
+	}
+	_, err = repo.ApplyEvent(ctx, 7, nil, revoke)
+	require.NoError(t, err)
+	_, err = repo.ApplyEvent(ctx, 7, nil, revoke)
+	assert.ErrorIs(t, err, ErrDuplicateEvent, "an event is revoked only once")
+
+	_, err = repo.GetEventByKey(ctx, key)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.ApplyEvent(ctx, 7, &key, deduct(-10))
+	require.NoError(t, err, "the same dispute can be booked again after its revocation")
+
+	credit, err := repo.GetCredit(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditInitialScore-10, credit.Score)
+}
+
+func TestCreditRepository_EnsureRulesKeepsAdjustedRules(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.EnsureRules(ctx, model.DefaultCreditRules()))
+	rule, err := repo.GetRule(ctx, model.CreditEventNoShow)
+	require.NoError(t, err)
+	rule.Delta = -30
+	require.NoError(t, repo.UpdateRule(ctx, rule))
+
+	require.NoError(t, repo.EnsureRules(ctx, model.DefaultCreditRules()))
+	rule, err = repo.GetRule(ctx, model.CreditEventNoShow)
+	require.NoError(t, err)
+	assert.Equal(t, -30, rule.Delta)
+	rules, err := repo.ListRules(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rules, len(model.DefaultCreditRules()))
+}
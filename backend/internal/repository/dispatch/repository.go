@@ -0,0 +1,185 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// AcceptanceStats 陪玩师对派单邀请的历史响应
+type AcceptanceStats struct {
+	Accepted int64
+	Total    int64 // 已接受、已拒绝与已过期的邀请总数
+}
+
+// DispatchRepository 派单仓储：派单邀请、指派记录与候选陪玩师查询
+type DispatchRepository interface {
+	// ListCandidates 列出已认证的陪玩师；gameID 非 0 时只返回主玩或擅长该游戏的陪玩师
+	ListCandidates(ctx context.Context, gameID uint64) ([]model.Player, error)
+	// AcceptanceStats 统计陪玩师对派单邀请的历史响应
+	AcceptanceStats(ctx context.Context, playerIDs []uint64) (map[uint64]AcceptanceStats, error)
+
+	CreateOffers(ctx context.Context, offers []model.DispatchOffer) error
+	GetOffer(ctx context.Context, id uint64) (*model.DispatchOffer, error)
+	// TransitOffer 仅当邀请处于 from 状态时更新为 to，返回是否更新成功
+	TransitOffer(ctx context.Context, id uint64, from, to model.DispatchOfferStatus, at time.Time) (bool, error)
+	// CancelPendingOffers 取消订单其余待响应的邀请
+	CancelPendingOffers(ctx context.Context, orderID uint64, at time.Time) error
+	// ListOffersByPlayer 列出陪玩师的邀请（最新在前），status 为空时不过滤
+	ListOffersByPlayer(ctx context.Context, playerID uint64, status model.DispatchOfferStatus) ([]model.DispatchOffer, error)
+	ListOffersByOrder(ctx context.Context, orderID uint64) ([]model.DispatchOffer, error)
+	// ListExpiredOffers 列出已过期但仍待响应的邀请
+	ListExpiredOffers(ctx context.Context, now time.Time, limit int) ([]model.DispatchOffer, error)
+	// CountPendingOffers 统计订单待响应的邀请数
+	CountPendingOffers(ctx context.Context, orderID uint64) (int64, error)
+
+	CreateAssignment(ctx context.Context, a *model.OrderAssignment) error
+	ListAssignmentsByOrder(ctx context.Context, orderID uint64) ([]model.OrderAssignment, error)
+	// ListUndispatchedOrders 列出尚未指定陪玩师、也未派过单的已支付单人订单（最早在前）
+	ListUndispatchedOrders(ctx context.Context, limit int) ([]model.Order, error)
+}
+
+type dispatchRepository struct {
+	db *gorm.DB
+}
+
+// NewDispatchRepository 创建派单仓储
+func NewDispatchRepository(db *gorm.DB) DispatchRepository {
+	return &dispatchRepository{db: db}
+}
+
+func (r *dispatchRepository) ListCandidates(ctx context.Context, gameID uint64) ([]model.Player, error) {
+	query := r.db.WithContext(ctx).Where("verification_status = ?", model.VerificationVerified)
+	if gameID != 0 {
+		sub := r.db.Model(&model.PlayerGame{}).Select("player_id").Where("game_id = ?", gameID)
+		query = query.Where("main_game_id = ? OR id IN (?)", gameID, sub)
+	}
+	var players []model.Player
+	err := query.Order("id ASC").Find(&players).Error
+	return players, err
+}
+
+func (r *dispatchRepository) AcceptanceStats(ctx context.Context, playerIDs []uint64) (map[uint64]AcceptanceStats, error) {
+	out := make(map[uint64]AcceptanceStats, len(playerIDs))
+	if len(playerIDs) == 0 {
+		return out, nil
+	}
+	var rows []struct {
+		PlayerID uint64
+		Status   model.DispatchOfferStatus
+		Count    int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.DispatchOffer{}).
+		Select("player_id, status, COUNT(*) AS count").
+		Where("player_id IN ? AND status IN ?", playerIDs, []model.DispatchOfferStatus{
+			model.DispatchOfferAccepted, model.DispatchOfferDeclined, model.DispatchOfferExpired,
+		}).
+		Group("player_id, status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		st := out[row.PlayerID]
+		st.Total += row.Count
+		if row.Status == model.DispatchOfferAccepted {
+			st.Accepted += row.Count
+		}
+		out[row.PlayerID] = st
+	}
+	return out, nil
+}
+
+func (r *dispatchRepository) CreateOffers(ctx context.Context, offers []model.DispatchOffer) error {
+	if len(offers) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&offers).Error
+}
+
+func (r *dispatchRepository) GetOffer(ctx context.Context, id uint64) (*model.DispatchOffer, error) {
+	var offer model.DispatchOffer
+	if err := r.db.WithContext(ctx).First(&offer, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func (r *dispatchRepository) TransitOffer(ctx context.Context, id uint64, from, to model.DispatchOfferStatus, at time.Time) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&model.DispatchOffer{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]any{"status": to, "responded_at": at})
+	return tx.RowsAffected > 0, tx.Error
+}
+
+func (r *dispatchRepository) CancelPendingOffers(ctx context.Context, orderID uint64, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.DispatchOffer{}).
+		Where("order_id = ? AND status = ?", orderID, model.DispatchOfferPending).
+		Updates(map[string]any{"status": model.DispatchOfferCanceled, "responded_at": at}).Error
+}
+
+func (r *dispatchRepository) ListOffersByPlayer(ctx context.Context, playerID uint64, status model.DispatchOfferStatus) ([]model.DispatchOffer, error) {
+	query := r.db.WithContext(ctx).Where("player_id = ?", playerID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var offers []model.DispatchOffer
+	err := query.Order("created_at DESC, id DESC").Limit(100).Find(&offers).Error
+	return offers, err
+}
+
+func (r *dispatchRepository) ListOffersByOrder(ctx context.Context, orderID uint64) ([]model.DispatchOffer, error) {
+	var offers []model.DispatchOffer
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("`rank` ASC, id ASC").Find(&offers).Error
+	return offers, err
+}
+
+func (r *dispatchRepository) ListExpiredOffers(ctx context.Context, now time.Time, limit int) ([]model.DispatchOffer, error) {
+	var offers []model.DispatchOffer
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", model.DispatchOfferPending, now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&offers).Error
+	return offers, err
+}
+
+func (r *dispatchRepository) CountPendingOffers(ctx context.Context, orderID uint64) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&model.DispatchOffer{}).
+		Where("order_id = ? AND status = ?", orderID, model.DispatchOfferPending).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *dispatchRepository) CreateAssignment(ctx context.Context, a *model.OrderAssignment) error {
+	return r.db.WithContext(ctx).Create(a).Error
+}
+
+func (r *dispatchRepository) ListAssignmentsByOrder(ctx context.Context, orderID uint64) ([]model.OrderAssignment, error) {
+	var rows []model.OrderAssignment
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("id ASC").Find(&rows).Error
+	return rows, err
+}
+
+func (r *dispatchRepository) ListUndispatchedOrders(ctx context.Context, limit int) ([]model.Order, error) {
+	offered := r.db.Model(&model.DispatchOffer{}).Select("order_id")
+	assigned := r.db.Model(&model.OrderAssignment{}).Select("order_id")
+	var orders []model.Order
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND player_id IS NULL", model.OrderStatusConfirmed).
+		Where("id NOT IN (?) AND id NOT IN (?)", offered, assigned).
+		Where("queue_type IS NULL OR queue_type <> ?", model.OrderQueueTypeTeam).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
@@ -0,0 +1,127 @@
+package dispatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+func newTestRepo(t *testing.T) (DispatchRepository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.PlayerGame{}, &model.Order{},
+		&model.DispatchOffer{}, &model.OrderAssignment{}))
+	return NewDispatchRepository(db), db
+}
+
+func TestDispatchRepository_ListCandidates(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	main := &model.Player{UserID: 1, MainGameID: 7, VerificationStatus: model.VerificationVerified}
+	secondary := &model.Player{UserID: 2, MainGameID: 8, VerificationStatus: model.VerificationVerified}
+	unverified := &model.Player{UserID: 3, MainGameID: 7, VerificationStatus: model.VerificationPending}
+	other := &model.Player{UserID: 4, MainGameID: 9, VerificationStatus: model.VerificationVerified}
+	for _, p := range []*model.Player{main, secondary, unverified, other} {
+		require.NoError(t, db.Create(p).Error)
+	}
+	require.NoError(t, db.Create(&model.PlayerGame{PlayerID: secondary.ID, GameID: 7}).Error)
+
+	players, err := repo.ListCandidates(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, players, 2)
+	assert.Equal(t, main.ID, players[0].ID)
+	assert.Equal(t, secondary.ID, players[1].ID)
+
+	all, err := repo.ListCandidates(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestDispatchRepository_Offers(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, repo.CreateOffers(ctx, []model.DispatchOffer{
+		{OrderID: 1, PlayerID: 10, Rank: 1, Status: model.DispatchOfferPending, ExpiresAt: now.Add(-time.Second)},
+		{OrderID: 1, PlayerID: 11, Rank: 2, Status: model.DispatchOfferPending, ExpiresAt: now.Add(time.Minute)},
+		{OrderID: 2, PlayerID: 10, Rank: 1, Status: model.DispatchOfferAccepted, ExpiresAt: now},
+	}))
+
+	expired, err := repo.ListExpiredOffers(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, uint64(10), expired[0].PlayerID)
+
+	// 比较并交换：已响应的邀请不会被再次更新
+	ok, err := repo.TransitOffer(ctx, expired[0].ID, model.DispatchOfferPending, model.DispatchOfferExpired, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.TransitOffer(ctx, expired[0].ID, model.DispatchOfferPending, model.DispatchOfferAccepted, now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	n, err := repo.CountPendingOffers(ctx, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+	require.NoError(t, repo.CancelPendingOffers(ctx, 1, now))
+	n, err = repo.CountPendingOffers(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	stats, err := repo.AcceptanceStats(ctx, []uint64{10, 11})
+	require.NoError(t, err)
+	assert.Equal(t, AcceptanceStats{Accepted: 1, Total: 2}, stats[10])
+	assert.Equal(t, AcceptanceStats{}, stats[11]) // 被取消的邀请不计入
+
+	mine, err := repo.ListOffersByPlayer(ctx, 10, model.DispatchOfferAccepted)
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	assert.Equal(t, uint64(2), mine[0].OrderID)
+
+	_, err = repo.GetOffer(ctx, 999)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestDispatchRepository_ListUndispatchedOrders(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	newOrder := func(status model.OrderStatus, playerID uint64) *model.Order {
+		o := &model.Order{UserID: 1, Status: status, TotalPriceCents: 100}
+		if playerID != 0 {
+			o.SetPlayerID(playerID)
+		}
+		require.NoError(t, db.Create(o).Error)
+		return o
+	}
+	open := newOrder(model.OrderStatusConfirmed, 0)
+	offered := newOrder(model.OrderStatusConfirmed, 0)
+	assigned := newOrder(model.OrderStatusConfirmed, 0)
+	newOrder(model.OrderStatusConfirmed, 5)
+	newOrder(model.OrderStatusPending, 0)
+
+	require.NoError(t, repo.CreateOffers(ctx, []model.DispatchOffer{
+		{OrderID: offered.ID, PlayerID: 1, Status: model.DispatchOfferExpired, ExpiresAt: time.Now()},
+	}))
+	require.NoError(t, repo.CreateAssignment(ctx, &model.OrderAssignment{OrderID: assigned.ID, PlayerID: 1, Source: model.AssignmentSourceManual}))
+
+	orders, err := repo.ListUndispatchedOrders(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, open.ID, orders[0].ID)
+
+	rows, err := repo.ListAssignmentsByOrder(ctx, assigned.ID)
+	require.NoError(t, err)
+	assert.Len(t, rows, 1)
+}
@@ -0,0 +1,128 @@
+package dispute
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// AgentRepository 争议工作台客服排班仓储
+type AgentRepository interface {
+	// Upsert 按用户新增或更新客服的层级、擅长游戏与负载上限
+	Upsert(ctx context.Context, agent *model.DisputeAgent) error
+	Get(ctx context.Context, userID uint64) (*model.DisputeAgent, error)
+	List(ctx context.Context) ([]model.DisputeAgent, error)
+	ListOnDuty(ctx context.Context) ([]model.DisputeAgent, error)
+	SetOnDuty(ctx context.Context, userID uint64, onDuty bool) error
+	// TouchAssigned 记录最近一次被指派的时间，用于负载相同时轮转
+	TouchAssigned(ctx context.Context, userID uint64, at time.Time) error
+	// CountOpen 统计各客服手上未结的争议数（已指派或调解中）
+	CountOpen(ctx context.Context, userIDs []uint64) (map[uint64]int64, error)
+	Delete(ctx context.Context, userID uint64) error
+}
+
+type agentRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentRepository 创建客服排班仓储
+func NewAgentRepository(db *gorm.DB) AgentRepository {
+	return &agentRepository{db: db}
+}
+
+func (r *agentRepository) Upsert(ctx context.Context, agent *model.DisputeAgent) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tier", "on_duty", "game_ids", "max_open", "updated_at"}),
+	}).Create(agent).Error
+	if err != nil {
+		return err
+	}
+	// 冲突更新时 Create 不会回填主键
+	stored, err := r.Get(ctx, agent.UserID)
+	if err != nil {
+		return err
+	}
+	*agent = *stored
+	return nil
+}
+
+func (r *agentRepository) Get(ctx context.Context, userID uint64) (*model.DisputeAgent, error) {
+	var agent model.DisputeAgent
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&agent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &agent, nil
+}
+
+func (r *agentRepository) List(ctx context.Context) ([]model.DisputeAgent, error) {
+	var agents []model.DisputeAgent
+	err := r.db.WithContext(ctx).Order("tier, user_id").Find(&agents).Error
+	return agents, err
+}
+
+func (r *agentRepository) ListOnDuty(ctx context.Context) ([]model.DisputeAgent, error) {
+	var agents []model.DisputeAgent
+	err := r.db.WithContext(ctx).Where("on_duty = ?", true).Order("tier, user_id").Find(&agents).Error
+	return agents, err
+}
+
+func (r *agentRepository) SetOnDuty(ctx context.Context, userID uint64, onDuty bool) error {
+	res := r.db.WithContext(ctx).Model(&model.DisputeAgent{}).Where("user_id = ?", userID).Update("on_duty", onDuty)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *agentRepository) TouchAssigned(ctx context.Context, userID uint64, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.DisputeAgent{}).Where("user_id = ?", userID).Update("last_assigned_at", at).Error
+}
+
+func (r *agentRepository) CountOpen(ctx context.Context, userIDs []uint64) (map[uint64]int64, error) {
+	counts := make(map[uint64]int64, len(userIDs))
+	if len(userIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		AssignedToUserID uint64
+		Total            int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.OrderDispute{}).
+		Select("assigned_to_user_id, COUNT(*) AS total").
+		Where("assigned_to_user_id IN ?", userIDs).
+		Where("status IN ?", []model.DisputeStatus{model.DisputeStatusAssigned, model.DisputeStatusMediating}).
+		Group("assigned_to_user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.AssignedToUserID] = row.Total
+	}
+	return counts, nil
+}
+
+// Delete 移出排班；按用户唯一，直接物理删除以便重新加入
+func (r *agentRepository) Delete(ctx context.Context, userID uint64) error {
+	res := r.db.WithContext(ctx).Unscoped().Where("user_id = ?", userID).Delete(&model.DisputeAgent{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package dispute
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+func TestAgentRepository(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.OrderDispute{}, &model.DisputeAgent{}))
+	agents := NewAgentRepository(db)
+	disputes := NewDisputeRepository(db)
+	ctx := context.Background()
+
+	agent := &model.DisputeAgent{UserID: 10, Tier: model.DisputeTierAgent, OnDuty: true, GameIDs: model.IDList{1, 2}}
+	require.NoError(t, agents.Upsert(ctx, agent))
+	require.NotZero(t, agent.ID)
+	require.NoError(t, agents.Upsert(ctx, &model.DisputeAgent{UserID: 11, Tier: model.DisputeTierSupervisor}))
+
+	// 同一用户再次保存时更新设置
+	updated := &model.DisputeAgent{UserID: 10, Tier: model.DisputeTierAgent, OnDuty: true, GameIDs: model.IDList{3}, MaxOpen: 2}
+	require.NoError(t, agents.Upsert(ctx, updated))
+	assert.Equal(t, agent.ID, updated.ID)
+	got, err := agents.Get(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, model.IDList{3}, got.GameIDs)
+	assert.Equal(t, 2, got.MaxOpen)
+
+	onDuty, err := agents.ListOnDuty(ctx)
+	require.NoError(t, err)
+	require.Len(t, onDuty, 1)
+	assert.Equal(t, uint64(10), onDuty[0].UserID)
+	require.NoError(t, agents.SetOnDuty(ctx, 11, true))
+	assert.ErrorIs(t, agents.SetOnDuty(ctx, 99, true), repository.ErrNotFound)
+
+	at := time.Now()
+	require.NoError(t, agents.TouchAssigned(ctx, 10, at))
+	got, err = agents.Get(ctx, 10)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastAssignedAt)
+	assert.WithinDuration(t, at, *got.LastAssignedAt, time.Second)
+
+	// 只统计已指派和调解中的争议
+	for i, status := range []model.DisputeStatus{model.DisputeStatusAssigned, model.DisputeStatusMediating, model.DisputeStatusResolved} {
+		assignee := uint64(10)
+		require.NoError(t, disputes.Create(ctx, &model.OrderDispute{OrderID: uint64(i + 1), UserID: 1, Reason: "r", Status: status, AssignedToUserID: &assignee}))
+	}
+	counts, err := agents.CountOpen(ctx, []uint64{10, 11})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]int64{10: 2}, counts)
+
+	require.NoError(t, agents.Delete(ctx, 10))
+	_, err = agents.Get(ctx, 10)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	require.NoError(t, agents.Upsert(ctx, &model.DisputeAgent{UserID: 10, Tier: model.DisputeTierAgent}))
+	all, err := agents.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
@@ -188,6 +188,28 @@ func TestDisputeRepository_ListSLABreached(t *testing.T) {
 	assert.Len(t, disputes, 2)
 }
 
+func TestDisputeRepository_ListPendingAssignment(t *testing.T) {
+	repo := setupDisputeTest(t)
+	ctx := context.Background()
+
+	agent := uint64(7)
+	for i, d := range []*model.OrderDispute{
+		{OrderID: 1, UserID: 100, Reason: "Reason", Status: model.DisputeStatusPending},
+		{OrderID: 2, UserID: 100, Reason: "Reason", Status: model.DisputeStatusPending},
+		{OrderID: 3, UserID: 100, Reason: "Reason", Status: model.DisputeStatusAssigned, AssignedToUserID: &agent},
+	} {
+		d.CreatedAt = time.Now().Add(time.Duration(i) * time.Minute)
+		assert.NoError(t, repo.Create(ctx, d))
+	}
+
+	disputes, total, err := repo.ListPendingAssignment(ctx, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	if assert.Len(t, disputes, 2) {
+		assert.Equal(t, uint64(1), disputes[0].OrderID)
+	}
+}
+
 func TestDisputeRepository_MarkSLABreached(t *testing.T) {
 	repo := setupDisputeTest(t)
 	ctx := context.Background()
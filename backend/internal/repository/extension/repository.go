@@ -0,0 +1,83 @@
+package extension
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// ExtensionRepository 订单加时申请仓储
+type ExtensionRepository interface {
+	Create(ctx context.Context, ext *model.OrderExtension) error
+	Get(ctx context.Context, id uint64) (*model.OrderExtension, error)
+	// ListByOrder 列出订单的全部加时申请（最早在前）
+	ListByOrder(ctx context.Context, orderID uint64) ([]model.OrderExtension, error)
+	// GetOpenByOrder 订单待处理或待补款的加时申请，没有时返回 ErrNotFound
+	GetOpenByOrder(ctx context.Context, orderID uint64) (*model.OrderExtension, error)
+	// Transit 仅当申请处于 from 之一时写入 ext 的状态与处理字段，返回是否更新成功
+	Transit(ctx context.Context, ext *model.OrderExtension, from ...model.OrderExtensionStatus) (bool, error)
+}
+
+type extensionRepository struct {
+	db *gorm.DB
+}
+
+// NewExtensionRepository 创建加时申请仓储
+func NewExtensionRepository(db *gorm.DB) ExtensionRepository {
+	return &extensionRepository{db: db}
+}
+
+func (r *extensionRepository) Create(ctx context.Context, ext *model.OrderExtension) error {
+	return r.db.WithContext(ctx).Create(ext).Error
+}
+
+func (r *extensionRepository) Get(ctx context.Context, id uint64) (*model.OrderExtension, error) {
+	var ext model.OrderExtension
+	if err := r.db.WithContext(ctx).First(&ext, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &ext, nil
+}
+
+func (r *extensionRepository) ListByOrder(ctx context.Context, orderID uint64) ([]model.OrderExtension, error) {
+	var exts []model.OrderExtension
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("id").Find(&exts).Error
+	return exts, err
+}
+
+func (r *extensionRepository) GetOpenByOrder(ctx context.Context, orderID uint64) (*model.OrderExtension, error) {
+	var ext model.OrderExtension
+	err := r.db.WithContext(ctx).
+		Where("order_id = ? AND status IN ?", orderID,
+			[]model.OrderExtensionStatus{model.OrderExtensionRequested, model.OrderExtensionAccepted}).
+		Order("id DESC").
+		First(&ext).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &ext, nil
+}
+
+func (r *extensionRepository) Transit(ctx context.Context, ext *model.OrderExtension, from ...model.OrderExtensionStatus) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&model.OrderExtension{}).
+		Where("id = ? AND status IN ?", ext.ID, from).
+		Updates(map[string]any{
+			"status":         ext.Status,
+			"decline_reason": ext.DeclineReason,
+			"payment_id":     ext.PaymentID,
+			"responded_at":   ext.RespondedAt,
+			"paid_at":        ext.PaidAt,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// RateRepository 汇率仓储接口
+type RateRepository interface {
+	// CreateRates 追加一批汇率
+	CreateRates(ctx context.Context, rates []model.ExchangeRate) error
+	// Latest 获取币种对在 at 时刻（含）之前最新生效的汇率
+	Latest(ctx context.Context, base, quote model.Currency, at time.Time) (*model.ExchangeRate, error)
+	// ListLatest 列出每个币种对当前生效的汇率
+	ListLatest(ctx context.Context) ([]model.ExchangeRate, error)
+	// ListHistory 查询币种对的历史汇率（最新在前）
+	ListHistory(ctx context.Context, base, quote model.Currency, limit int) ([]model.ExchangeRate, error)
+}
+
+type rateRepository struct {
+	db *gorm.DB
+}
+
+// NewRateRepository 创建汇率仓储
+func NewRateRepository(db *gorm.DB) RateRepository {
+	return &rateRepository{db: db}
+}
+
+func (r *rateRepository) CreateRates(ctx context.Context, rates []model.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&rates).Error
+}
+
+func (r *rateRepository) Latest(ctx context.Context, base, quote model.Currency, at time.Time) (*model.ExchangeRate, error) {
+	var rate model.ExchangeRate
+	err := r.db.WithContext(ctx).
+		Where("base_currency = ? AND quote_currency = ? AND effective_at <= ?", base, quote, at).
+		Order("effective_at DESC, id DESC").
+		First(&rate).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *rateRepository) ListLatest(ctx context.Context) ([]model.ExchangeRate, error) {
+	// 币种有限，逐对取最新值即可
+	var pairs []struct {
+		BaseCurrency  model.Currency
+		QuoteCurrency model.Currency
+	}
+	err := r.db.WithContext(ctx).Model(&model.ExchangeRate{}).
+		Distinct("base_currency", "quote_currency").
+		Order("base_currency ASC, quote_currency ASC").
+		Scan(&pairs).Error
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	rates := make([]model.ExchangeRate, 0, len(pairs))
+	for _, p := range pairs {
+		rate, err := r.Latest(ctx, p.BaseCurrency, p.QuoteCurrency, now)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue // 只有未来生效的汇率
+		}
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, *rate)
+	}
+	return rates, nil
+}
+
+func (r *rateRepository) ListHistory(ctx context.Context, base, quote model.Currency, limit int) ([]model.ExchangeRate, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var rates []model.ExchangeRate
+	err := r.db.WithContext(ctx).
+		Where("base_currency = ? AND quote_currency = ?", base, quote).
+		Order("effective_at DESC, id DESC").
+		Limit(limit).
+		Find(&rates).Error
+	return rates, err
+}
@@ -56,6 +56,7 @@ type PaymentRepository interface {
 	Create(ctx context.Context, payment *model.Payment) error
 	List(ctx context.Context, opts PaymentListOptions) ([]model.Payment, int64, error)
 	Get(ctx context.Context, id uint64) (*model.Payment, error)
+	GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error)
 	Update(ctx context.Context, payment *model.Payment) error
 	Delete(ctx context.Context, id uint64) error
 }
@@ -115,6 +116,26 @@ type ReviewRepository interface {
 	Delete(ctx context.Context, id uint64) error
 }
 
+// PaymentCallbackRepository defines payment callback inbox operations.
+type PaymentCallbackRepository interface {
+	// CreateIfAbsent inserts the callback unless one with the same provider trade number exists.
+	CreateIfAbsent(ctx context.Context, cb *model.PaymentCallback) (bool, error)
+	GetByProviderTradeNo(ctx context.Context, provider model.PaymentMethod, providerTradeNo string) (*model.PaymentCallback, error)
+	Update(ctx context.Context, cb *model.PaymentCallback) error
+}
+
+// RefundRepository defines refund data access operations.
+type RefundRepository interface {
+	Create(ctx context.Context, refund *model.Refund) error
+	Get(ctx context.Context, id uint64) (*model.Refund, error)
+	GetByOutRefundNo(ctx context.Context, outRefundNo string) (*model.Refund, error)
+	Update(ctx context.Context, refund *model.Refund) error
+	// ListByOrder returns all refunds of an order, oldest first.
+	ListByOrder(ctx context.Context, orderID uint64) ([]model.Refund, error)
+	// ListByStatus returns up to limit refunds in the given states, oldest first.
+	ListByStatus(ctx context.Context, statuses []model.RefundStatus, limit int) ([]model.Refund, error)
+}
+
 // OperationLogRepository defines operation log data access operations.
 type OperationLogRepository interface {
 	Append(ctx context.Context, log *model.OperationLog) error
@@ -237,6 +258,8 @@ type OrderListOptions struct {
 	Keyword  string
 	DateFrom *time.Time
 	DateTo   *time.Time
+	// QueueType 按接单队列过滤；solo 同时匹配未设置队列的历史订单
+	QueueType model.OrderQueueType
 }
 
 // FeedListOptions describes feed query filters.
@@ -0,0 +1,390 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// LedgerRepository 总账仓储接口（科目、凭证、流水）
+type LedgerRepository interface {
+	// EnsureAccount 科目编码不存在时创建
+	EnsureAccount(ctx context.Context, account *model.FinancialAccount) error
+	// GetAccount 按编码获取科目
+	GetAccount(ctx context.Context, code string) (*model.FinancialAccount, error)
+	// ListAccounts 列出全部科目
+	ListAccounts(ctx context.Context) ([]model.FinancialAccount, error)
+	// ApplyBalance 原子地调整科目余额，返回调整后的余额
+	ApplyBalance(ctx context.Context, code string, delta int64) (int64, error)
+
+	// CreateVoucher 创建凭证及其分录
+	CreateVoucher(ctx context.Context, voucher *model.FinancialVoucher) error
+	// GetVoucher 获取凭证（含分录，按行号排序）
+	GetVoucher(ctx context.Context, id uint64) (*model.FinancialVoucher, error)
+	// FindVoucherByBusiness 按业务类型与业务单号查找凭证
+	FindVoucherByBusiness(ctx context.Context, businessType, businessNo string) (*model.FinancialVoucher, error)
+	// FindReversal 查找冲销指定凭证的红字凭证
+	FindReversal(ctx context.Context, voucherID uint64) (*model.FinancialVoucher, error)
+	// UpdateVoucherStatus 更新凭证状态及审核、过账信息
+	UpdateVoucherStatus(ctx context.Context, voucher *model.FinancialVoucher) error
+	// ListVouchers 查询凭证列表
+	ListVouchers(ctx context.Context, opts VoucherListOptions) ([]model.FinancialVoucher, int64, error)
+
+	// CreateTransactions 写入科目流水
+	CreateTransactions(ctx context.Context, txs []model.FinancialTransaction) error
+	// ListTransactions 查询科目流水
+	ListTransactions(ctx context.Context, opts TransactionListOptions) ([]model.FinancialTransaction, int64, error)
+
+	// SumEntries 按科目汇总已过账分录的借贷发生额
+	SumEntries(ctx context.Context, opts EntrySumOptions) ([]AccountSum, error)
+	// SumEntriesByBusiness 按凭证业务类型汇总指定科目的已过账发生额
+	SumEntriesByBusiness(ctx context.Context, accountCodes []string, from, to time.Time) ([]BusinessSum, error)
+	// CountUnposted 统计期间内尚未过账（草稿、待审、已审）的凭证
+	CountUnposted(ctx context.Context, from, to time.Time) (int64, error)
+
+	// GetPeriod 获取已结账期间（YYYY-MM）
+	GetPeriod(ctx context.Context, period string) (*model.FinancialPeriod, error)
+	// CreatePeriod 记录期间结账
+	CreatePeriod(ctx context.Context, period *model.FinancialPeriod) error
+	// ListPeriods 列出已结账期间（最新在前）
+	ListPeriods(ctx context.Context) ([]model.FinancialPeriod, error)
+
+	// CreateReport 保存财务报表
+	CreateReport(ctx context.Context, report *model.FinancialReport) error
+	// GetReport 获取财务报表
+	GetReport(ctx context.Context, id uint64) (*model.FinancialReport, error)
+	// ListReports 查询财务报表（不含报表数据）
+	ListReports(ctx context.Context, opts ReportListOptions) ([]model.FinancialReport, int64, error)
+}
+
+// EntrySumOptions 分录汇总选项；From 为空表示从最早开始累计。
+type EntrySumOptions struct {
+	From           *time.Time
+	To             time.Time
+	ExcludeClosing bool // 排除结账凭证（利润表使用）
+}
+
+// AccountSum 科目借贷发生额（按币种分别汇总）
+type AccountSum struct {
+	AccountCode string
+	Currency    model.Currency
+	Debit       int64
+	Credit      int64
+}
+
+// BusinessSum 业务类型借贷发生额（按币种分别汇总）
+type BusinessSum struct {
+	BusinessType string
+	Currency     model.Currency
+	Debit        int64
+	Credit       int64
+}
+
+// ReportListOptions 报表查询选项
+type ReportListOptions struct {
+	Type     *model.FinancialReportType
+	Page     int
+	PageSize int
+}
+
+// VoucherListOptions 凭证查询选项
+type VoucherListOptions struct {
+	Status       *model.FinancialVoucherStatus
+	BusinessType string
+	DateFrom     *time.Time
+	DateTo       *time.Time
+	Page         int
+	PageSize     int
+}
+
+// TransactionListOptions 流水查询选项
+type TransactionListOptions struct {
+	AccountCode string
+	DateFrom    *time.Time
+	DateTo      *time.Time
+	Page        int
+	PageSize    int
+}
+
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+// NewLedgerRepository 创建总账仓储
+func NewLedgerRepository(db *gorm.DB) LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+func (r *ledgerRepository) EnsureAccount(ctx context.Context, account *model.FinancialAccount) error {
+	return r.db.WithContext(ctx).Where("code = ?", account.Code).FirstOrCreate(account).Error
+}
+
+func (r *ledgerRepository) GetAccount(ctx context.Context, code string) (*model.FinancialAccount, error) {
+	var account model.FinancialAccount
+	if err := r.db.WithContext(ctx).Where("code = ?", code).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *ledgerRepository) ListAccounts(ctx context.Context) ([]model.FinancialAccount, error) {
+	var accounts []model.FinancialAccount
+	err := r.db.WithContext(ctx).Order("code ASC").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *ledgerRepository) ApplyBalance(ctx context.Context, code string, delta int64) (int64, error) {
+	db := r.db.WithContext(ctx)
+	res := db.Model(&model.FinancialAccount{}).Where("code = ?", code).
+		UpdateColumn("current_balance", gorm.Expr("current_balance + ?", delta))
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, repository.ErrNotFound
+	}
+	var balance int64
+	if err := db.Model(&model.FinancialAccount{}).Where("code = ?", code).Select("current_balance").Scan(&balance).Error; err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+func (r *ledgerRepository) CreateVoucher(ctx context.Context, voucher *model.FinancialVoucher) error {
+	return r.db.WithContext(ctx).Create(voucher).Error
+}
+
+func (r *ledgerRepository) GetVoucher(ctx context.Context, id uint64) (*model.FinancialVoucher, error) {
+	var voucher model.FinancialVoucher
+	err := r.db.WithContext(ctx).
+		Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("line_no ASC") }).
+		First(&voucher, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &voucher, nil
+}
+
+func (r *ledgerRepository) FindVoucherByBusiness(ctx context.Context, businessType, businessNo string) (*model.FinancialVoucher, error) {
+	var voucher model.FinancialVoucher
+	err := r.db.WithContext(ctx).
+		Where("business_type = ? AND business_no = ?", businessType, businessNo).
+		Where("status <> ?", model.FinancialVoucherStatusCancelled).
+		First(&voucher).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &voucher, nil
+}
+
+func (r *ledgerRepository) FindReversal(ctx context.Context, voucherID uint64) (*model.FinancialVoucher, error) {
+	var voucher model.FinancialVoucher
+	err := r.db.WithContext(ctx).
+		Where("reversal_of_id = ?", voucherID).
+		Where("status NOT IN ?", []model.FinancialVoucherStatus{model.FinancialVoucherStatusCancelled, model.FinancialVoucherStatusRejected}).
+		First(&voucher).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &voucher, nil
+}
+
+func (r *ledgerRepository) UpdateVoucherStatus(ctx context.Context, voucher *model.FinancialVoucher) error {
+	res := r.db.WithContext(ctx).Model(&model.FinancialVoucher{}).Where("id = ?", voucher.ID).Updates(map[string]any{
+		"status":      voucher.Status,
+		"reviewed_by": voucher.ReviewedBy,
+		"reviewed_at": voucher.ReviewedAt,
+		"posted_by":   voucher.PostedBy,
+		"posted_at":   voucher.PostedAt,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *ledgerRepository) ListVouchers(ctx context.Context, opts VoucherListOptions) ([]model.FinancialVoucher, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.FinancialVoucher{})
+	if opts.Status != nil {
+		query = query.Where("status = ?", *opts.Status)
+	}
+	if opts.BusinessType != "" {
+		query = query.Where("business_type = ?", opts.BusinessType)
+	}
+	if opts.DateFrom != nil {
+		query = query.Where("voucher_date >= ?", *opts.DateFrom)
+	}
+	if opts.DateTo != nil {
+		query = query.Where("voucher_date < ?", *opts.DateTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := repository.NormalizePage(opts.Page)
+	size := repository.NormalizePageSize(opts.PageSize)
+	var vouchers []model.FinancialVoucher
+	err := query.Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("line_no ASC") }).
+		Order("voucher_date DESC, id DESC").Offset((page - 1) * size).Limit(size).Find(&vouchers).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return vouchers, total, nil
+}
+
+func (r *ledgerRepository) CreateTransactions(ctx context.Context, txs []model.FinancialTransaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&txs).Error
+}
+
+func (r *ledgerRepository) ListTransactions(ctx context.Context, opts TransactionListOptions) ([]model.FinancialTransaction, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.FinancialTransaction{})
+	if opts.AccountCode != "" {
+		query = query.Where("account_code = ?", opts.AccountCode)
+	}
+	if opts.DateFrom != nil {
+		query = query.Where("transaction_date >= ?", *opts.DateFrom)
+	}
+	if opts.DateTo != nil {
+		query = query.Where("transaction_date < ?", *opts.DateTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := repository.NormalizePage(opts.Page)
+	size := repository.NormalizePageSize(opts.PageSize)
+	var txs []model.FinancialTransaction
+	err := query.Order("transaction_date ASC, id ASC").Offset((page - 1) * size).Limit(size).Find(&txs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return txs, total, nil
+}
+
+func (r *ledgerRepository) postedEntries(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table("financial_voucher_entries AS e").
+		Joins("JOIN financial_vouchers AS v ON v.id = e.voucher_id").
+		Where("v.status = ? AND e.deleted_at IS NULL AND v.deleted_at IS NULL", model.FinancialVoucherStatusPosted)
+}
+
+func (r *ledgerRepository) SumEntries(ctx context.Context, opts EntrySumOptions) ([]AccountSum, error) {
+	query := r.postedEntries(ctx).Where("v.voucher_date < ?", opts.To)
+	if opts.From != nil {
+		query = query.Where("v.voucher_date >= ?", *opts.From)
+	}
+	if opts.ExcludeClosing {
+		query = query.Where("v.type <> ?", model.FinancialVoucherTypeClosing)
+	}
+	var sums []AccountSum
+	err := query.Select("e.account_code AS account_code, e.currency AS currency, COALESCE(SUM(e.debit_amount), 0) AS debit, COALESCE(SUM(e.credit_amount), 0) AS credit").
+		Group("e.account_code, e.currency").Order("e.account_code ASC, e.currency ASC").Scan(&sums).Error
+	return sums, err
+}
+
+func (r *ledgerRepository) SumEntriesByBusiness(ctx context.Context, accountCodes []string, from, to time.Time) ([]BusinessSum, error) {
+	if len(accountCodes) == 0 {
+		return nil, nil
+	}
+	var sums []BusinessSum
+	err := r.postedEntries(ctx).
+		Where("e.account_code IN ?", accountCodes).
+		Where("v.voucher_date >= ? AND v.voucher_date < ?", from, to).
+		Select("v.business_type AS business_type, e.currency AS currency, COALESCE(SUM(e.debit_amount), 0) AS debit, COALESCE(SUM(e.credit_amount), 0) AS credit").
+		Group("v.business_type, e.currency").Order("v.business_type ASC, e.currency ASC").Scan(&sums).Error
+	return sums, err
+}
+
+func (r *ledgerRepository) CountUnposted(ctx context.Context, from, to time.Time) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&model.FinancialVoucher{}).
+		Where("status IN ?", []model.FinancialVoucherStatus{
+			model.FinancialVoucherStatusDraft, model.FinancialVoucherStatusPending, model.FinancialVoucherStatusApproved,
+		}).
+		Where("voucher_date >= ? AND voucher_date < ?", from, to).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *ledgerRepository) GetPeriod(ctx context.Context, period string) (*model.FinancialPeriod, error) {
+	var p model.FinancialPeriod
+	if err := r.db.WithContext(ctx).Where("period = ?", period).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ledgerRepository) CreatePeriod(ctx context.Context, period *model.FinancialPeriod) error {
+	return r.db.WithContext(ctx).Create(period).Error
+}
+
+func (r *ledgerRepository) ListPeriods(ctx context.Context) ([]model.FinancialPeriod, error) {
+	var periods []model.FinancialPeriod
+	err := r.db.WithContext(ctx).Order("period DESC").Find(&periods).Error
+	return periods, err
+}
+
+func (r *ledgerRepository) CreateReport(ctx context.Context, report *model.FinancialReport) error {
+	return r.db.WithContext(ctx).Omit("Generator").Create(report).Error
+}
+
+func (r *ledgerRepository) GetReport(ctx context.Context, id uint64) (*model.FinancialReport, error) {
+	var report model.FinancialReport
+	if err := r.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *ledgerRepository) ListReports(ctx context.Context, opts ReportListOptions) ([]model.FinancialReport, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.FinancialReport{})
+	if opts.Type != nil {
+		query = query.Where("type = ?", *opts.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := repository.NormalizePage(opts.Page)
+	size := repository.NormalizePageSize(opts.PageSize)
+	var reports []model.FinancialReport
+	err := query.Omit("report_data").Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&reports).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return reports, total, nil
+}
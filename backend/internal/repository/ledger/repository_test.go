@@ -0,0 +1,117 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&model.FinancialAccount{},
+		&model.FinancialVoucher{},
+		&model.FinancialVoucherEntry{},
+		&model.FinancialTransaction{},
+		&model.FinancialReport{},
+		&model.FinancialPeriod{},
+	)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestLedgerRepository_EnsureAccountAndApplyBalance(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLedgerRepository(db)
+	ctx := context.Background()
+
+	for _, account := range model.SystemFinancialAccounts() {
+		account := account
+		require.NoError(t, repo.EnsureAccount(ctx, &account))
+	}
+	// 重复初始化不会产生重复科目
+	for _, account := range model.SystemFinancialAccounts() {
+		account := account
+		require.NoError(t, repo.EnsureAccount(ctx, &account))
+	}
+	accounts, err := repo.ListAccounts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, accounts, len(model.SystemFinancialAccounts()))
+
+	after, err := repo.ApplyBalance(ctx, model.FinancialAccountChannelFunds, 1500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), after)
+	after, err = repo.ApplyBalance(ctx, model.FinancialAccountChannelFunds, -500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), after)
+
+	_, err = repo.ApplyBalance(ctx, "9999", 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetAccount(ctx, "9999")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestLedgerRepository_VoucherLookups(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLedgerRepository(db)
+	ctx := context.Background()
+
+	voucher := &model.FinancialVoucher{
+		VoucherNo:    "V001",
+		VoucherDate:  time.Now(),
+		Type:         model.FinancialVoucherTypeAuto,
+		Abstract:     "收款",
+		Status:       model.FinancialVoucherStatusPosted,
+		TotalAmount:  100,
+		BusinessType: model.LedgerBusinessPaymentReceived,
+		BusinessNo:   "PAY-1",
+		Entries: []model.FinancialVoucherEntry{
+			{LineNo: 2, AccountCode: model.FinancialAccountAdvanceReceipt, CreditAmount: 100},
+			{LineNo: 1, AccountCode: model.FinancialAccountChannelFunds, DebitAmount: 100},
+		},
+	}
+	require.NoError(t, repo.CreateVoucher(ctx, voucher))
+
+	got, err := repo.GetVoucher(ctx, voucher.ID)
+	require.NoError(t, err)
+	require.Len(t, got.Entries, 2)
+	assert.Equal(t, 1, got.Entries[0].LineNo)
+
+	found, err := repo.FindVoucherByBusiness(ctx, model.LedgerBusinessPaymentReceived, "PAY-1")
+	require.NoError(t, err)
+	assert.Equal(t, voucher.ID, found.ID)
+	_, err = repo.FindVoucherByBusiness(ctx, model.LedgerBusinessPaymentReceived, "PAY-2")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	// 被驳回的冲销凭证不占用冲销名额
+	rejected := &model.FinancialVoucher{
+		VoucherNo: "V002", VoucherDate: time.Now(), Type: model.FinancialVoucherTypeReversal,
+		Abstract: "冲销", Status: model.FinancialVoucherStatusRejected, ReversalOfID: &voucher.ID,
+	}
+	require.NoError(t, repo.CreateVoucher(ctx, rejected))
+	_, err = repo.FindReversal(ctx, voucher.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	draft := model.FinancialVoucherStatusDraft
+	rejected.Status = draft
+	require.NoError(t, repo.UpdateVoucherStatus(ctx, rejected))
+	reversal, err := repo.FindReversal(ctx, voucher.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rejected.ID, reversal.ID)
+
+	list, total, err := repo.ListVouchers(ctx, VoucherListOptions{Status: &draft})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "V002", list[0].VoucherNo)
+}
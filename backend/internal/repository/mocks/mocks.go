@@ -570,6 +570,21 @@ func (mr *MockPaymentRepositoryMockRecorder) Get(ctx, id interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockPaymentRepository)(nil).Get), ctx, id)
 }
 
+// GetByOutTradeNo mocks base method.
+func (m *MockPaymentRepository) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByOutTradeNo", ctx, outTradeNo)
+	ret0, _ := ret[0].(*model.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByOutTradeNo indicates an expected call of GetByOutTradeNo.
+func (mr *MockPaymentRepositoryMockRecorder) GetByOutTradeNo(ctx, outTradeNo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByOutTradeNo", reflect.TypeOf((*MockPaymentRepository)(nil).GetByOutTradeNo), ctx, outTradeNo)
+}
+
 // List mocks base method.
 func (m *MockPaymentRepository) List(ctx context.Context, opts repository.PaymentListOptions) ([]model.Payment, int64, error) {
 	m.ctrl.T.Helper()
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
@@ -41,6 +42,13 @@ func (r *gormOrderRepository) List(ctx context.Context, opts repository.OrderLis
 	if opts.GameID != nil {
 		query = query.Where("game_id = ?", *opts.GameID)
 	}
+	switch opts.QueueType {
+	case "":
+	case model.OrderQueueTypeSolo:
+		query = query.Where("queue_type = ? OR queue_type = '' OR queue_type IS NULL", opts.QueueType)
+	default:
+		query = query.Where("queue_type = ?", opts.QueueType)
+	}
 	if opts.DateFrom != nil {
 		query = query.Where("created_at >= ?", *opts.DateFrom)
 	}
@@ -81,33 +89,21 @@ func (r *gormOrderRepository) Get(ctx context.Context, id uint64) (*model.Order,
 	return &order, nil
 }
 
+// GetForUpdate 加行锁读取订单（SELECT ... FOR UPDATE），需在事务内调用。
+func (r *gormOrderRepository) GetForUpdate(ctx context.Context, id uint64) (*model.Order, error) {
+	var order model.Order
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
 // Update updates editable fields of an order.
 func (r *gormOrderRepository) Update(ctx context.Context, order *model.Order) error {
-	tx := r.db.WithContext(ctx).Model(order).Where("id = ?", order.ID).Updates(map[string]any{
-		"player_id":            order.PlayerID,
-		"recipient_player_id":  order.RecipientPlayerID,
-		"game_id":              order.GameID,
-		"status":               order.Status,
-		"quantity":             order.Quantity,
-		"unit_price_cents":     order.UnitPriceCents,
-		"total_price_cents":    order.TotalPriceCents,
-		"commission_cents":     order.CommissionCents,
-		"player_income_cents":  order.PlayerIncomeCents,
-		"currency":             order.Currency,
-		"title":                order.Title,
-		"description":          order.Description,
-		"scheduled_start":      order.ScheduledStart,
-		"scheduled_end":        order.ScheduledEnd,
-		"started_at":           order.StartedAt,
-		"completed_at":         order.CompletedAt,
-		"cancel_reason":        order.CancelReason,
-		"refund_amount_cents":  order.RefundAmountCents,
-		"refund_reason":        order.RefundReason,
-		"refunded_at":          order.RefundedAt,
-		"gift_message":         order.GiftMessage,
-		"is_anonymous":         order.IsAnonymous,
-		"delivered_at":         order.DeliveredAt,
-	})
+	tx := r.db.WithContext(ctx).Model(order).Where("id = ?", order.ID).Updates(updateColumns(order))
 	if tx.Error != nil {
 		return tx.Error
 	}
@@ -117,6 +113,59 @@ func (r *gormOrderRepository) Update(ctx context.Context, order *model.Order) er
 	return nil
 }
 
+// UpdateIfStatus 仅当订单当前状态仍为 from 时更新（比较并交换），返回是否更新成功。
+// 并发修改同一订单时只有一方成功，其余返回 false。
+func (r *gormOrderRepository) UpdateIfStatus(ctx context.Context, order *model.Order, from model.OrderStatus) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&model.Order{}).
+		Where("id = ? AND status = ?", order.ID, from).
+		Updates(updateColumns(order))
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected > 0, nil
+}
+
+// updateColumns 可编辑字段
+func updateColumns(order *model.Order) map[string]any {
+	return map[string]any{
+		"player_id":               order.PlayerID,
+		"recipient_player_id":     order.RecipientPlayerID,
+		"game_id":                 order.GameID,
+		"status":                  order.Status,
+		"quantity":                order.Quantity,
+		"unit_price_cents":        order.UnitPriceCents,
+		"total_price_cents":       order.TotalPriceCents,
+		"commission_cents":        order.CommissionCents,
+		"player_income_cents":     order.PlayerIncomeCents,
+		"commission_rate":         order.CommissionRate,
+		"subsidy_cents":           order.SubsidyCents,
+		"currency":                order.Currency,
+		"discount_cents":          order.DiscountCents,
+		"coupon_id":               order.CouponID,
+		"discount_bearer":         order.DiscountBearer,
+		"title":                   order.Title,
+		"description":             order.Description,
+		"scheduled_start":         order.ScheduledStart,
+		"scheduled_end":           order.ScheduledEnd,
+		"started_at":              order.StartedAt,
+		"completed_at":            order.CompletedAt,
+		"completion_submitted_at": order.CompletionSubmittedAt,
+		"completion_proof_urls":   order.CompletionProofURLs,
+		"completion_note":         order.CompletionNote,
+		"cancel_reason":           order.CancelReason,
+		"refund_amount_cents":     order.RefundAmountCents,
+		"refund_reason":           order.RefundReason,
+		"refunded_at":             order.RefundedAt,
+		"gift_message":            order.GiftMessage,
+		"is_anonymous":            order.IsAnonymous,
+		"delivered_at":            order.DeliveredAt,
+		"queue_type":              order.QueueType,
+		"required_members":        order.RequiredMembers,
+		"assigned_team_id":        order.AssignedTeamID,
+		"assignment_source":       order.AssignmentSource,
+	}
+}
+
 // Delete soft-deletes an order by id.
 func (r *gormOrderRepository) Delete(ctx context.Context, id uint64) error {
 	tx := r.db.WithContext(ctx).Delete(&model.Order{}, id)
@@ -370,4 +370,26 @@ func TestOrderRepository_List(t *testing.T) {
 		}
 		t.Logf("found %d orders with combined filters", total)
 	})
+
+	t.Run("List with queue type filter", func(t *testing.T) {
+		team := &model.Order{UserID: 10, ItemID: 1, OrderNo: "TEST-LIST-TEAM", Status: model.OrderStatusConfirmed,
+			QueueType: model.OrderQueueTypeTeam, RequiredMembers: 3}
+		if err := repo.Create(testContext(), team); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		_, solo, err := repo.List(testContext(), repository.OrderListOptions{QueueType: model.OrderQueueTypeSolo})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if solo != 15 {
+			t.Errorf("expected 15 solo orders, got %d", solo)
+		}
+		teams, total, err := repo.List(testContext(), repository.OrderListOptions{QueueType: model.OrderQueueTypeTeam})
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if total != 1 || teams[0].ID != team.ID {
+			t.Errorf("expected only the team order, got %d", total)
+		}
+	})
 }
@@ -0,0 +1,39 @@
+package orderhistory
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+)
+
+// HistoryRepository 订单状态流转记录仓储接口
+type HistoryRepository interface {
+	// Append 追加一条状态流转记录
+	Append(ctx context.Context, entry *model.OrderStatusHistory) error
+	// ListByOrder 按发生顺序列出订单的状态流转记录
+	ListByOrder(ctx context.Context, orderID uint64) ([]model.OrderStatusHistory, error)
+}
+
+type historyRepository struct {
+	db *gorm.DB
+}
+
+// NewHistoryRepository 创建订单状态流转记录仓储
+func NewHistoryRepository(db *gorm.DB) HistoryRepository {
+	return &historyRepository{db: db}
+}
+
+func (r *historyRepository) Append(ctx context.Context, entry *model.OrderStatusHistory) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *historyRepository) ListByOrder(ctx context.Context, orderID uint64) ([]model.OrderStatusHistory, error) {
+	var rows []model.OrderStatusHistory
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("created_at ASC, id ASC").
+		Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,39 @@
+package orderhistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+)
+
+func TestHistoryRepository_AppendAndList(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.OrderStatusHistory{}))
+	repo := NewHistoryRepository(db)
+	ctx := context.Background()
+
+	actor := uint64(7)
+	require.NoError(t, repo.Append(ctx, &model.OrderStatusHistory{OrderID: 1, FromStatus: model.OrderStatusPending, ToStatus: model.OrderStatusConfirmed, ActorRole: model.OrderActorSystem, TraceID: "trace-1"}))
+	require.NoError(t, repo.Append(ctx, &model.OrderStatusHistory{OrderID: 2, FromStatus: model.OrderStatusPending, ToStatus: model.OrderStatusCanceled, ActorRole: model.OrderActorUser}))
+	require.NoError(t, repo.Append(ctx, &model.OrderStatusHistory{OrderID: 1, FromStatus: model.OrderStatusConfirmed, ToStatus: model.OrderStatusInProgress, ActorRole: model.OrderActorPlayer, ActorUserID: &actor}))
+
+	rows, err := repo.ListByOrder(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, model.OrderStatusConfirmed, rows[0].ToStatus)
+	assert.Equal(t, "trace-1", rows[0].TraceID)
+	assert.Equal(t, model.OrderStatusInProgress, rows[1].ToStatus)
+	require.NotNil(t, rows[1].ActorUserID)
+	assert.Equal(t, actor, *rows[1].ActorUserID)
+
+	rows, err = repo.ListByOrder(ctx, 3)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
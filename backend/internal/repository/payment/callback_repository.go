@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+type gormPaymentCallbackRepository struct {
+	db *gorm.DB
+}
+
+// NewPaymentCallbackRepository 创建支付回调收件箱仓储。
+func NewPaymentCallbackRepository(db *gorm.DB) repository.PaymentCallbackRepository {
+	return &gormPaymentCallbackRepository{db: db}
+}
+
+// CreateIfAbsent 依赖 (provider, provider_trade_no) 唯一索引去重，重复时不报错而是返回 false。
+func (r *gormPaymentCallbackRepository) CreateIfAbsent(ctx context.Context, cb *model.PaymentCallback) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "provider"}, {Name: "provider_trade_no"}}, DoNothing: true}).
+		Create(cb)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// GetByProviderTradeNo 按渠道交易号查询回调记录。
+func (r *gormPaymentCallbackRepository) GetByProviderTradeNo(ctx context.Context, provider model.PaymentMethod, providerTradeNo string) (*model.PaymentCallback, error) {
+	var cb model.PaymentCallback
+	err := r.db.WithContext(ctx).
+		Where("provider = ? AND provider_trade_no = ?", provider, providerTradeNo).
+		First(&cb).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &cb, nil
+}
+
+// Update 更新回调处理结果。
+func (r *gormPaymentCallbackRepository) Update(ctx context.Context, cb *model.PaymentCallback) error {
+	res := r.db.WithContext(ctx).Model(&model.PaymentCallback{}).Where("id = ?", cb.ID).Updates(map[string]any{
+		"payment_id":   cb.PaymentID,
+		"status":       cb.Status,
+		"error":        cb.Error,
+		"processed_at": cb.ProcessedAt,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+func TestPaymentCallbackRepository_CreateIfAbsent(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(&model.PaymentCallback{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := NewPaymentCallbackRepository(db)
+	ctx := testContext()
+
+	first := &model.PaymentCallback{Provider: model.PaymentMethodWeChat, ProviderTradeNo: "4200001", OutTradeNo: "PAY1", Status: model.PaymentCallbackStatusReceived}
+	created, err := repo.CreateIfAbsent(ctx, first)
+	if err != nil || !created {
+		t.Fatalf("expected first insert to succeed, created=%v err=%v", created, err)
+	}
+
+	dup := &model.PaymentCallback{Provider: model.PaymentMethodWeChat, ProviderTradeNo: "4200001", OutTradeNo: "PAY1", Status: model.PaymentCallbackStatusReceived}
+	created, err = repo.CreateIfAbsent(ctx, dup)
+	if err != nil || created {
+		t.Fatalf("expected duplicate to be skipped, created=%v err=%v", created, err)
+	}
+
+	// 不同渠道的相同交易号互不影响
+	other := &model.PaymentCallback{Provider: model.PaymentMethodAlipay, ProviderTradeNo: "4200001", Status: model.PaymentCallbackStatusReceived}
+	if created, err = repo.CreateIfAbsent(ctx, other); err != nil || !created {
+		t.Fatalf("expected other provider insert to succeed, created=%v err=%v", created, err)
+	}
+
+	now := time.Now()
+	paymentID := uint64(9)
+	first.Status = model.PaymentCallbackStatusProcessed
+	first.PaymentID = &paymentID
+	first.ProcessedAt = &now
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	got, err := repo.GetByProviderTradeNo(ctx, model.PaymentMethodWeChat, "4200001")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got.Status != model.PaymentCallbackStatusProcessed || got.PaymentID == nil || *got.PaymentID != 9 {
+		t.Fatalf("unexpected callback: %+v", got)
+	}
+
+	if _, err := repo.GetByProviderTradeNo(ctx, model.PaymentMethodWeChat, "missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := repo.Update(ctx, &model.PaymentCallback{Base: model.Base{ID: 999}}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
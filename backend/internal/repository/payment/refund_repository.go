@@ -0,0 +1,83 @@
+package payment
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+type gormRefundRepository struct {
+	db *gorm.DB
+}
+
+// NewRefundRepository 创建退款单仓储。
+func NewRefundRepository(db *gorm.DB) repository.RefundRepository {
+	return &gormRefundRepository{db: db}
+}
+
+// Create 创建退款单。
+func (r *gormRefundRepository) Create(ctx context.Context, refund *model.Refund) error {
+	return r.db.WithContext(ctx).Create(refund).Error
+}
+
+// Get 按 ID 查询退款单。
+func (r *gormRefundRepository) Get(ctx context.Context, id uint64) (*model.Refund, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+// GetByOutRefundNo 按商户退款单号查询。
+func (r *gormRefundRepository) GetByOutRefundNo(ctx context.Context, outRefundNo string) (*model.Refund, error) {
+	return r.first(ctx, "out_refund_no = ?", outRefundNo)
+}
+
+func (r *gormRefundRepository) first(ctx context.Context, query string, arg any) (*model.Refund, error) {
+	var refund model.Refund
+	if err := r.db.WithContext(ctx).Where(query, arg).First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// Update 保存退款单（状态、渠道单号、重试信息等）。
+func (r *gormRefundRepository) Update(ctx context.Context, refund *model.Refund) error {
+	res := r.db.WithContext(ctx).Model(&model.Refund{}).Where("id = ?", refund.ID).Updates(map[string]any{
+		"status":             refund.Status,
+		"provider_refund_no": refund.ProviderRefundNo,
+		"provider_raw":       refund.ProviderRaw,
+		"retry_count":        refund.RetryCount,
+		"last_error":         refund.LastError,
+		"refunded_at":        refund.RefundedAt,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// ListByOrder 返回订单的全部退款单（按创建顺序）。
+func (r *gormRefundRepository) ListByOrder(ctx context.Context, orderID uint64) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at ASC, id ASC").Find(&refunds).Error
+	return refunds, err
+}
+
+// ListByStatus 返回指定状态的退款单，供重试与异步确认任务使用。
+func (r *gormRefundRepository) ListByStatus(ctx context.Context, statuses []model.RefundStatus, limit int) ([]model.Refund, error) {
+	var refunds []model.Refund
+	q := r.db.WithContext(ctx).Where("status IN ?", statuses).Order("updated_at ASC, id ASC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&refunds).Error
+	return refunds, err
+}
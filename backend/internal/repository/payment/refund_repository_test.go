@@ -0,0 +1,68 @@
+package payment
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+func TestRefundRepository_CRUD(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Refund{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := NewRefundRepository(db)
+	ctx := testContext()
+
+	first := &model.Refund{OrderID: 1, AmountCents: 300, Status: model.RefundStatusSucceeded, OutRefundNo: "RF1"}
+	second := &model.Refund{OrderID: 1, AmountCents: 200, Status: model.RefundStatusPending, OutRefundNo: "RF2"}
+	other := &model.Refund{OrderID: 2, AmountCents: 100, Status: model.RefundStatusProcessing, OutRefundNo: "RF3"}
+	for _, rf := range []*model.Refund{first, second, other} {
+		if err := repo.Create(ctx, rf); err != nil {
+			t.Fatalf("create refund: %v", err)
+		}
+	}
+	if err := repo.Create(ctx, &model.Refund{OrderID: 3, OutRefundNo: "RF1"}); err == nil {
+		t.Fatal("expected duplicate out_refund_no to be rejected")
+	}
+
+	list, err := repo.ListByOrder(ctx, 1)
+	if err != nil || len(list) != 2 || list[0].OutRefundNo != "RF1" {
+		t.Fatalf("unexpected order refunds: %+v err=%v", list, err)
+	}
+
+	got, err := repo.GetByOutRefundNo(ctx, "RF2")
+	if err != nil || got.ID != second.ID {
+		t.Fatalf("lookup by out_refund_no failed: %+v err=%v", got, err)
+	}
+	got.Status = model.RefundStatusFailed
+	got.RetryCount = 3
+	got.LastError = "timeout"
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatalf("update refund: %v", err)
+	}
+	reloaded, err := repo.Get(ctx, second.ID)
+	if err != nil || reloaded.Status != model.RefundStatusFailed || reloaded.RetryCount != 3 || reloaded.LastError != "timeout" {
+		t.Fatalf("update not persisted: %+v err=%v", reloaded, err)
+	}
+
+	open, err := repo.ListByStatus(ctx, []model.RefundStatus{model.RefundStatusPending, model.RefundStatusProcessing}, 10)
+	if err != nil || len(open) != 1 || open[0].OutRefundNo != "RF3" {
+		t.Fatalf("unexpected open refunds: %+v err=%v", open, err)
+	}
+
+	if _, err := repo.Get(ctx, 999); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := repo.Update(ctx, &model.Refund{Base: model.Base{ID: 999}}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound on update, got %v", err)
+	}
+}
@@ -76,10 +76,23 @@ func (r *gormPaymentRepository) Get(ctx context.Context, id uint64) (*model.Paym
 	return &payment, nil
 }
 
+// GetByOutTradeNo returns a payment by the merchant trade number sent to the provider.
+func (r *gormPaymentRepository) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	var payment model.Payment
+	if err := r.db.WithContext(ctx).Where("out_trade_no = ?", outTradeNo).First(&payment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
 // Update updates editable fields of a payment.
 func (r *gormPaymentRepository) Update(ctx context.Context, payment *model.Payment) error {
 	tx := r.db.WithContext(ctx).Model(payment).Where("id = ?", payment.ID).Updates(map[string]any{
 		"status":            payment.Status,
+		"out_trade_no":      payment.OutTradeNo,
 		"provider_trade_no": payment.ProviderTradeNo,
 		"provider_raw":      payment.ProviderRaw,
 		"paid_at":           payment.PaidAt,
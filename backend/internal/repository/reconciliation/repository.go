@@ -0,0 +1,244 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// ReconciliationRepository 对账仓储接口
+type ReconciliationRepository interface {
+	// Create 创建对账单
+	Create(ctx context.Context, rec *model.Reconciliation) error
+	// Get 获取对账单（不含明细）
+	Get(ctx context.Context, id uint64) (*model.Reconciliation, error)
+	// Update 更新对账单
+	Update(ctx context.Context, rec *model.Reconciliation) error
+	// List 查询对账单列表
+	List(ctx context.Context, opts ReconciliationListOptions) ([]model.Reconciliation, int64, error)
+
+	// CreateDetails 批量写入对账明细
+	CreateDetails(ctx context.Context, details []model.ReconciliationDetail) error
+	// GetDetail 获取对账明细
+	GetDetail(ctx context.Context, id uint64) (*model.ReconciliationDetail, error)
+	// UpdateDetail 更新对账明细
+	UpdateDetail(ctx context.Context, detail *model.ReconciliationDetail) error
+	// ListDetails 查询对账明细
+	ListDetails(ctx context.Context, opts DetailListOptions) ([]model.ReconciliationDetail, int64, error)
+	// CountPendingExceptions 统计未处理的异常明细
+	CountPendingExceptions(ctx context.Context, reconciliationID uint64) (int64, error)
+
+	// ListPaymentsPaidBetween 查询期间内已支付（含后续退款）的渠道支付单
+	ListPaymentsPaidBetween(ctx context.Context, method model.PaymentMethod, from, to time.Time) ([]model.Payment, error)
+	// FindPayment 按渠道交易号或商户单号查找支付单（用于跨期匹配）
+	FindPayment(ctx context.Context, method model.PaymentMethod, providerTradeNo, outTradeNo string) (*model.Payment, error)
+	// ListRefundsSucceededBetween 查询期间内退款成功的渠道退款单
+	ListRefundsSucceededBetween(ctx context.Context, method model.PaymentMethod, from, to time.Time) ([]model.Refund, error)
+	// FindRefund 按渠道退款单号或商户退款单号查找退款单
+	FindRefund(ctx context.Context, method model.PaymentMethod, providerRefundNo, outRefundNo string) (*model.Refund, error)
+}
+
+// ReconciliationListOptions 对账单查询选项
+type ReconciliationListOptions struct {
+	Provider *model.PaymentMethod
+	Status   *model.ReconciliationStatus
+	DateFrom *time.Time
+	DateTo   *time.Time
+	Page     int
+	PageSize int
+}
+
+// DetailListOptions 对账明细查询选项
+type DetailListOptions struct {
+	ReconciliationID uint64
+	Status           string
+	ExceptionsOnly   bool
+	Resolved         *bool
+	Page             int
+	PageSize         int
+}
+
+type reconciliationRepository struct {
+	db *gorm.DB
+}
+
+// NewReconciliationRepository 创建对账仓储
+func NewReconciliationRepository(db *gorm.DB) ReconciliationRepository {
+	return &reconciliationRepository{db: db}
+}
+
+func (r *reconciliationRepository) Create(ctx context.Context, rec *model.Reconciliation) error {
+	return r.db.WithContext(ctx).Omit("Details").Create(rec).Error
+}
+
+func (r *reconciliationRepository) Get(ctx context.Context, id uint64) (*model.Reconciliation, error) {
+	var rec model.Reconciliation
+	if err := r.db.WithContext(ctx).First(&rec, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (r *reconciliationRepository) Update(ctx context.Context, rec *model.Reconciliation) error {
+	return r.db.WithContext(ctx).Omit("Details", "Processor").Save(rec).Error
+}
+
+func (r *reconciliationRepository) List(ctx context.Context, opts ReconciliationListOptions) ([]model.Reconciliation, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Reconciliation{})
+	if opts.Provider != nil {
+		query = query.Where("provider = ?", *opts.Provider)
+	}
+	if opts.Status != nil {
+		query = query.Where("status = ?", *opts.Status)
+	}
+	if opts.DateFrom != nil {
+		query = query.Where("period_start >= ?", *opts.DateFrom)
+	}
+	if opts.DateTo != nil {
+		query = query.Where("period_start < ?", *opts.DateTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := repository.NormalizePage(opts.Page)
+	size := repository.NormalizePageSize(opts.PageSize)
+	var recs []model.Reconciliation
+	err := query.Order("period_start DESC, id DESC").Offset((page - 1) * size).Limit(size).Find(&recs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return recs, total, nil
+}
+
+func (r *reconciliationRepository) CreateDetails(ctx context.Context, details []model.ReconciliationDetail) error {
+	if len(details) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit("Reconciliation").CreateInBatches(&details, 200).Error
+}
+
+func (r *reconciliationRepository) GetDetail(ctx context.Context, id uint64) (*model.ReconciliationDetail, error) {
+	var detail model.ReconciliationDetail
+	if err := r.db.WithContext(ctx).First(&detail, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &detail, nil
+}
+
+func (r *reconciliationRepository) UpdateDetail(ctx context.Context, detail *model.ReconciliationDetail) error {
+	return r.db.WithContext(ctx).Omit("Reconciliation").Save(detail).Error
+}
+
+func (r *reconciliationRepository) ListDetails(ctx context.Context, opts DetailListOptions) ([]model.ReconciliationDetail, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.ReconciliationDetail{}).
+		Where("reconciliation_id = ?", opts.ReconciliationID)
+	if opts.Status != "" {
+		query = query.Where("status = ?", opts.Status)
+	}
+	if opts.ExceptionsOnly {
+		query = query.Where("status <> ?", model.ReconciliationDetailMatched)
+	}
+	if opts.Resolved != nil {
+		query = query.Where("resolved = ?", *opts.Resolved)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := repository.NormalizePage(opts.Page)
+	size := repository.NormalizePageSize(opts.PageSize)
+	var details []model.ReconciliationDetail
+	err := query.Order("line_no ASC, id ASC").Offset((page - 1) * size).Limit(size).Find(&details).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return details, total, nil
+}
+
+func (r *reconciliationRepository) CountPendingExceptions(ctx context.Context, reconciliationID uint64) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&model.ReconciliationDetail{}).
+		Where("reconciliation_id = ? AND status <> ? AND resolved = ?", reconciliationID, model.ReconciliationDetailMatched, false).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *reconciliationRepository) ListPaymentsPaidBetween(ctx context.Context, method model.PaymentMethod, from, to time.Time) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := r.db.WithContext(ctx).
+		Where("method = ? AND status IN ?", method, []model.PaymentStatus{model.PaymentStatusPaid, model.PaymentStatusRefunded}).
+		Where("paid_at >= ? AND paid_at < ?", from, to).
+		Order("paid_at ASC, id ASC").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *reconciliationRepository) FindPayment(ctx context.Context, method model.PaymentMethod, providerTradeNo, outTradeNo string) (*model.Payment, error) {
+	var payment model.Payment
+	query := r.db.WithContext(ctx).Where("method = ?", method)
+	switch {
+	case providerTradeNo != "" && outTradeNo != "":
+		query = query.Where("provider_trade_no = ? OR out_trade_no = ?", providerTradeNo, outTradeNo)
+	case providerTradeNo != "":
+		query = query.Where("provider_trade_no = ?", providerTradeNo)
+	case outTradeNo != "":
+		query = query.Where("out_trade_no = ?", outTradeNo)
+	default:
+		return nil, repository.ErrNotFound
+	}
+	if err := query.First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *reconciliationRepository) ListRefundsSucceededBetween(ctx context.Context, method model.PaymentMethod, from, to time.Time) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := r.db.WithContext(ctx).
+		Where("method = ? AND status = ? AND payment_id IS NOT NULL", method, model.RefundStatusSucceeded).
+		Where("refunded_at >= ? AND refunded_at < ?", from, to).
+		Order("refunded_at ASC, id ASC").
+		Find(&refunds).Error
+	return refunds, err
+}
+
+func (r *reconciliationRepository) FindRefund(ctx context.Context, method model.PaymentMethod, providerRefundNo, outRefundNo string) (*model.Refund, error) {
+	var refund model.Refund
+	query := r.db.WithContext(ctx).Where("method = ?", method)
+	switch {
+	case providerRefundNo != "" && outRefundNo != "":
+		query = query.Where("provider_refund_no = ? OR out_refund_no = ?", providerRefundNo, outRefundNo)
+	case providerRefundNo != "":
+		query = query.Where("provider_refund_no = ?", providerRefundNo)
+	case outRefundNo != "":
+		query = query.Where("out_refund_no = ?", outRefundNo)
+	default:
+		return nil, repository.ErrNotFound
+	}
+	if err := query.First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &refund, nil
+}
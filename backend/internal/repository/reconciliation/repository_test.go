@@ -0,0 +1,124 @@
+package reconciliation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&model.Payment{},
+		&model.Refund{},
+		&model.Reconciliation{},
+		&model.ReconciliationDetail{},
+	)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestReconciliationRepository_InternalLookups(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewReconciliationRepository(db)
+	ctx := context.Background()
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	inside := day.Add(10 * time.Hour)
+	outside := day.Add(-time.Hour)
+	payments := []model.Payment{
+		{OrderID: 1, Method: model.PaymentMethodWeChat, Status: model.PaymentStatusPaid, OutTradeNo: "P1", ProviderTradeNo: "W1", PaidAt: &inside},
+		{OrderID: 2, Method: model.PaymentMethodWeChat, Status: model.PaymentStatusRefunded, OutTradeNo: "P2", PaidAt: &inside},
+		{OrderID: 3, Method: model.PaymentMethodWeChat, Status: model.PaymentStatusPaid, OutTradeNo: "P3", PaidAt: &outside},
+		{OrderID: 4, Method: model.PaymentMethodAlipay, Status: model.PaymentStatusPaid, OutTradeNo: "P4", PaidAt: &inside},
+	}
+	require.NoError(t, db.Create(&payments).Error)
+
+	list, err := repo.ListPaymentsPaidBetween(ctx, model.PaymentMethodWeChat, day, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	found, err := repo.FindPayment(ctx, model.PaymentMethodWeChat, "W1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "P1", found.OutTradeNo)
+	found, err = repo.FindPayment(ctx, model.PaymentMethodWeChat, "unknown", "P3")
+	require.NoError(t, err)
+	assert.Equal(t, "P3", found.OutTradeNo)
+	_, err = repo.FindPayment(ctx, model.PaymentMethodWeChat, "", "P4")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.FindPayment(ctx, model.PaymentMethodWeChat, "", "")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	paymentID := payments[0].ID
+	refunds := []model.Refund{
+		{OrderID: 1, PaymentID: &paymentID, Method: model.PaymentMethodWeChat, Status: model.RefundStatusSucceeded, OutRefundNo: "R1", ProviderRefundNo: "WR1", RefundedAt: &inside},
+		{OrderID: 1, PaymentID: &paymentID, Method: model.PaymentMethodWeChat, Status: model.RefundStatusProcessing, OutRefundNo: "R2"},
+		{OrderID: 1, Method: model.PaymentMethodWeChat, Status: model.RefundStatusSucceeded, OutRefundNo: "R3", RefundedAt: &inside}, // 线下退款
+	}
+	require.NoError(t, db.Create(&refunds).Error)
+
+	rlist, err := repo.ListRefundsSucceededBetween(ctx, model.PaymentMethodWeChat, day, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, rlist, 1)
+	assert.Equal(t, "R1", rlist[0].OutRefundNo)
+	refund, err := repo.FindRefund(ctx, model.PaymentMethodWeChat, "WR1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "R1", refund.OutRefundNo)
+}
+
+func TestReconciliationRepository_Details(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewReconciliationRepository(db)
+	ctx := context.Background()
+
+	rec := &model.Reconciliation{
+		ReconciliationNo:   "RC1",
+		ReconciliationDate: time.Now(),
+		Type:               model.ReconciliationTypePayment,
+		Status:             model.ReconciliationStatusException,
+		PeriodStart:        time.Now().Add(-24 * time.Hour),
+		PeriodEnd:          time.Now(),
+		Provider:           model.PaymentMethodWeChat,
+	}
+	require.NoError(t, repo.Create(ctx, rec))
+	details := []model.ReconciliationDetail{
+		{ReconciliationID: rec.ID, LineNo: 2, Status: model.ReconciliationDetailMatched},
+		{ReconciliationID: rec.ID, LineNo: 3, Status: model.ReconciliationDetailAmountMismatch},
+		{ReconciliationID: rec.ID, LineNo: 4, Status: model.ReconciliationDetailMissingInternal},
+	}
+	require.NoError(t, repo.CreateDetails(ctx, details))
+
+	pending, err := repo.CountPendingExceptions(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pending)
+
+	list, total, err := repo.ListDetails(ctx, DetailListOptions{ReconciliationID: rec.ID, ExceptionsOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, 3, list[0].LineNo)
+
+	detail, err := repo.GetDetail(ctx, list[0].ID)
+	require.NoError(t, err)
+	detail.Resolved = true
+	require.NoError(t, repo.UpdateDetail(ctx, detail))
+	pending, err = repo.CountPendingExceptions(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+
+	status := model.ReconciliationStatusException
+	recs, total, err := repo.List(ctx, ReconciliationListOptions{Status: &status})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "RC1", recs[0].ReconciliationNo)
+}
@@ -0,0 +1,79 @@
+package serviceitem
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// PriceRepository 服务项目多币种定价仓储
+type PriceRepository interface {
+	// ListPrices 列出服务项目的全部币种定价
+	ListPrices(ctx context.Context, itemID uint64) ([]model.ServiceItemPrice, error)
+	// GetPrice 获取服务项目在指定币种下的定价
+	GetPrice(ctx context.Context, itemID uint64, currency model.Currency) (*model.ServiceItemPrice, error)
+	// UpsertPrice 设置服务项目在指定币种下的定价，已存在时覆盖
+	UpsertPrice(ctx context.Context, price *model.ServiceItemPrice) error
+	// DeletePrice 删除指定币种定价
+	DeletePrice(ctx context.Context, itemID uint64, currency model.Currency) error
+}
+
+type priceRepository struct {
+	db *gorm.DB
+}
+
+// NewPriceRepository 创建服务项目定价仓储
+func NewPriceRepository(db *gorm.DB) PriceRepository {
+	return &priceRepository{db: db}
+}
+
+func (r *priceRepository) ListPrices(ctx context.Context, itemID uint64) ([]model.ServiceItemPrice, error) {
+	var prices []model.ServiceItemPrice
+	err := r.db.WithContext(ctx).Where("item_id = ?", itemID).Order("currency ASC").Find(&prices).Error
+	return prices, err
+}
+
+func (r *priceRepository) GetPrice(ctx context.Context, itemID uint64, currency model.Currency) (*model.ServiceItemPrice, error) {
+	var price model.ServiceItemPrice
+	err := r.db.WithContext(ctx).Where("item_id = ? AND currency = ?", itemID, currency).First(&price).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &price, nil
+}
+
+func (r *priceRepository) UpsertPrice(ctx context.Context, price *model.ServiceItemPrice) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "item_id"}, {Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price_cents", "updated_at"}),
+	}).Create(price).Error
+	if err != nil {
+		return err
+	}
+	// 冲突更新时 Create 不会回填主键
+	stored, err := r.GetPrice(ctx, price.ItemID, price.Currency)
+	if err != nil {
+		return err
+	}
+	*price = *stored
+	return nil
+}
+
+func (r *priceRepository) DeletePrice(ctx context.Context, itemID uint64, currency model.Currency) error {
+	res := r.db.WithContext(ctx).Where("item_id = ? AND currency = ?", itemID, currency).Delete(&model.ServiceItemPrice{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
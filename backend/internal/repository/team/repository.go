@@ -0,0 +1,282 @@
+package team
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// TeamRepository 车队仓储：车队与成员、车队抢单、出车成员与收入分配方案
+type TeamRepository interface {
+	// CreateTeam 创建车队并将队长写入成员表
+	CreateTeam(ctx context.Context, team *model.Team, leader *model.TeamMember) error
+	GetTeam(ctx context.Context, id uint64) (*model.Team, error)
+	// ListTeamsByUser 列出用户作为在队成员的车队
+	ListTeamsByUser(ctx context.Context, userID uint64) ([]model.Team, error)
+
+	// SaveMember 新增成员，或更新已存在的成员（如重新入队）
+	SaveMember(ctx context.Context, member *model.TeamMember) error
+	GetMember(ctx context.Context, id uint64) (*model.TeamMember, error)
+	GetMemberByUser(ctx context.Context, teamID, userID uint64) (*model.TeamMember, error)
+	// ListMembers 列出车队在队成员
+	ListMembers(ctx context.Context, teamID uint64) ([]model.TeamMember, error)
+
+	// ListPoolOrders 车队大厅：已支付、尚无车队抢到的车队订单（最早在前）
+	ListPoolOrders(ctx context.Context, gameID *uint64, page, pageSize int) ([]model.Order, int64, error)
+	// ClaimOrder 车队抢单：仅当订单仍在车队大厅时写入车队并创建接单记录，返回是否抢到
+	ClaimOrder(ctx context.Context, assignment *model.TeamOrderAssignment) (bool, error)
+	// GetActiveAssignment 订单当前未释放的车队接单记录
+	GetActiveAssignment(ctx context.Context, orderID uint64) (*model.TeamOrderAssignment, error)
+	// ConfirmAssignment 仅当接单记录处于组队中时标记为已确认，返回是否更新成功
+	ConfirmAssignment(ctx context.Context, id uint64, at time.Time) (bool, error)
+	// ReleaseAssignment 仅当接单记录处于组队中时释放，并将订单放回车队大厅
+	ReleaseAssignment(ctx context.Context, assignment *model.TeamOrderAssignment, at time.Time) (bool, error)
+	// ListExpiredAssignments 列出组队已超时的接单记录
+	ListExpiredAssignments(ctx context.Context, now time.Time, limit int) ([]model.TeamOrderAssignment, error)
+
+	// ReplaceAssignmentMembers 重新设置出车成员
+	ReplaceAssignmentMembers(ctx context.Context, assignmentID uint64, members []model.TeamAssignmentMember) error
+	ListAssignmentMembers(ctx context.Context, assignmentID uint64) ([]model.TeamAssignmentMember, error)
+	// ConfirmAssignmentMember 出车成员确认，仅待确认时更新，返回是否更新成功
+	ConfirmAssignmentMember(ctx context.Context, assignmentID, userID uint64, at time.Time) (bool, error)
+
+	// SavePayoutPlan 保存分配方案，shares 为出车成员 ID 到分成比例的映射（均分时为空）
+	SavePayoutPlan(ctx context.Context, plan *model.TeamPayoutPlan, shares map[uint64]int) error
+	GetPayoutPlan(ctx context.Context, assignmentID uint64) (*model.TeamPayoutPlan, error)
+}
+
+type teamRepository struct {
+	db *gorm.DB
+}
+
+// NewTeamRepository 创建车队仓储
+func NewTeamRepository(db *gorm.DB) TeamRepository {
+	return &teamRepository{db: db}
+}
+
+func (r *teamRepository) CreateTeam(ctx context.Context, team *model.Team, leader *model.TeamMember) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(team).Error; err != nil {
+			return err
+		}
+		leader.TeamID = team.ID
+		return tx.Create(leader).Error
+	})
+}
+
+func (r *teamRepository) GetTeam(ctx context.Context, id uint64) (*model.Team, error) {
+	var team model.Team
+	if err := r.db.WithContext(ctx).First(&team, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &team, nil
+}
+
+func (r *teamRepository) ListTeamsByUser(ctx context.Context, userID uint64) ([]model.Team, error) {
+	joined := r.db.Model(&model.TeamMember{}).Select("team_id").
+		Where("user_id = ? AND status = ?", userID, model.TeamMemberStatusActive)
+	var teams []model.Team
+	err := r.db.WithContext(ctx).
+		Where("id IN (?) AND status = ?", joined, model.TeamStatusActive).
+		Order("id ASC").
+		Find(&teams).Error
+	return teams, err
+}
+
+func (r *teamRepository) SaveMember(ctx context.Context, member *model.TeamMember) error {
+	return r.db.WithContext(ctx).Save(member).Error
+}
+
+func (r *teamRepository) GetMember(ctx context.Context, id uint64) (*model.TeamMember, error) {
+	var member model.TeamMember
+	if err := r.db.WithContext(ctx).First(&member, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &member, nil
+}
+
+func (r *teamRepository) GetMemberByUser(ctx context.Context, teamID, userID uint64) (*model.TeamMember, error) {
+	var member model.TeamMember
+	err := r.db.WithContext(ctx).Where("team_id = ? AND user_id = ?", teamID, userID).First(&member).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &member, nil
+}
+
+func (r *teamRepository) ListMembers(ctx context.Context, teamID uint64) ([]model.TeamMember, error) {
+	var members []model.TeamMember
+	err := r.db.WithContext(ctx).
+		Where("team_id = ? AND status = ?", teamID, model.TeamMemberStatusActive).
+		Order("id ASC").
+		Find(&members).Error
+	return members, err
+}
+
+func (r *teamRepository) ListPoolOrders(ctx context.Context, gameID *uint64, page, pageSize int) ([]model.Order, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Order{}).
+		Where("status = ? AND queue_type = ? AND assigned_team_id IS NULL", model.OrderStatusConfirmed, model.OrderQueueTypeTeam)
+	if gameID != nil {
+		query = query.Where("game_id = ?", *gameID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	page = repository.NormalizePage(page)
+	pageSize = repository.NormalizePageSize(pageSize)
+	var orders []model.Order
+	err := query.Order("created_at ASC, id ASC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&orders).Error
+	return orders, total, err
+}
+
+func (r *teamRepository) ClaimOrder(ctx context.Context, assignment *model.TeamOrderAssignment) (bool, error) {
+	claimed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Order{}).
+			Where("id = ? AND status = ? AND queue_type = ? AND assigned_team_id IS NULL",
+				assignment.OrderID, model.OrderStatusConfirmed, model.OrderQueueTypeTeam).
+			Updates(map[string]any{
+				"assigned_team_id":  assignment.TeamID,
+				"assignment_source": model.AssignmentSourceTeam,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		if err := tx.Create(assignment).Error; err != nil {
+			return err
+		}
+		claimed = true
+		return nil
+	})
+	return claimed, err
+}
+
+func (r *teamRepository) GetActiveAssignment(ctx context.Context, orderID uint64) (*model.TeamOrderAssignment, error) {
+	var a model.TeamOrderAssignment
+	err := r.db.WithContext(ctx).
+		Where("order_id = ? AND status <> ?", orderID, model.TeamAssignmentStatusReleased).
+		Order("id DESC").
+		First(&a).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &a, nil
+}
+
+func (r *teamRepository) ConfirmAssignment(ctx context.Context, id uint64, at time.Time) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&model.TeamOrderAssignment{}).
+		Where("id = ? AND status = ?", id, model.TeamAssignmentStatusDispatching).
+		Updates(map[string]any{"status": model.TeamAssignmentStatusConfirmed, "confirmed_at": at})
+	return tx.RowsAffected > 0, tx.Error
+}
+
+func (r *teamRepository) ReleaseAssignment(ctx context.Context, assignment *model.TeamOrderAssignment, at time.Time) (bool, error) {
+	released := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.TeamOrderAssignment{}).
+			Where("id = ? AND status = ?", assignment.ID, model.TeamAssignmentStatusDispatching).
+			Updates(map[string]any{"status": model.TeamAssignmentStatusReleased, "released_at": at})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		err := tx.Model(&model.Order{}).
+			Where("id = ? AND assigned_team_id = ? AND status = ?", assignment.OrderID, assignment.TeamID, model.OrderStatusConfirmed).
+			Updates(map[string]any{"assigned_team_id": nil, "assignment_source": ""}).Error
+		if err != nil {
+			return err
+		}
+		released = true
+		return nil
+	})
+	return released, err
+}
+
+func (r *teamRepository) ListExpiredAssignments(ctx context.Context, now time.Time, limit int) ([]model.TeamOrderAssignment, error) {
+	var rows []model.TeamOrderAssignment
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND dispatch_deadline <= ?", model.TeamAssignmentStatusDispatching, now).
+		Order("dispatch_deadline ASC").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+func (r *teamRepository) ReplaceAssignmentMembers(ctx context.Context, assignmentID uint64, members []model.TeamAssignmentMember) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("assignment_id = ?", assignmentID).Delete(&model.TeamAssignmentMember{}).Error; err != nil {
+			return err
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		return tx.Create(&members).Error
+	})
+}
+
+func (r *teamRepository) ListAssignmentMembers(ctx context.Context, assignmentID uint64) ([]model.TeamAssignmentMember, error) {
+	var rows []model.TeamAssignmentMember
+	err := r.db.WithContext(ctx).Where("assignment_id = ?", assignmentID).Order("id ASC").Find(&rows).Error
+	return rows, err
+}
+
+func (r *teamRepository) ConfirmAssignmentMember(ctx context.Context, assignmentID, userID uint64, at time.Time) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&model.TeamAssignmentMember{}).
+		Where("assignment_id = ? AND user_id = ? AND state = ?", assignmentID, userID, model.TeamAssignmentMemberPending).
+		Updates(map[string]any{"state": model.TeamAssignmentMemberConfirmed, "confirmed_at": at})
+	return tx.RowsAffected > 0, tx.Error
+}
+
+func (r *teamRepository) SavePayoutPlan(ctx context.Context, plan *model.TeamPayoutPlan, shares map[uint64]int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.TeamPayoutPlan
+		err := tx.Where("assignment_id = ?", plan.AssignmentID).First(&existing).Error
+		switch {
+		case err == nil:
+			plan.ID = existing.ID
+			plan.CreatedAt = existing.CreatedAt
+			if err := tx.Save(plan).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(plan).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		if err := tx.Model(&model.TeamAssignmentMember{}).
+			Where("assignment_id = ?", plan.AssignmentID).
+			Update("share_percent", 0).Error; err != nil {
+			return err
+		}
+		for memberID, percent := range shares {
+			if err := tx.Model(&model.TeamAssignmentMember{}).
+				Where("assignment_id = ? AND member_id = ?", plan.AssignmentID, memberID).
+				Update("share_percent", percent).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *teamRepository) GetPayoutPlan(ctx context.Context, assignmentID uint64) (*model.TeamPayoutPlan, error) {
+	var plan model.TeamPayoutPlan
+	if err := r.db.WithContext(ctx).Where("assignment_id = ?", assignmentID).First(&plan).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &plan, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package team
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+func newTestRepo(t *testing.T) (TeamRepository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Order{}, &model.Team{}, &model.TeamMember{},
+		&model.TeamOrderAssignment{}, &model.TeamAssignmentMember{}, &model.TeamPayoutPlan{}))
+	return NewTeamRepository(db), db
+}
+
+func TestTeamRepository_TeamsAndMembers(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	team := &model.Team{Name: "车队", LeaderUserID: 1, Status: model.TeamStatusActive}
+	leader := &model.TeamMember{UserID: 1, PlayerID: 11, Role: model.TeamRoleLeader, Status: model.TeamMemberStatusActive}
+	require.NoError(t, repo.CreateTeam(ctx, team, leader))
+	assert.Equal(t, team.ID, leader.TeamID)
+
+	member := &model.TeamMember{TeamID: team.ID, UserID: 2, PlayerID: 12, Role: model.TeamRoleMember, Status: model.TeamMemberStatusActive}
+	require.NoError(t, repo.SaveMember(ctx, member))
+	members, err := repo.ListMembers(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	member.Status = model.TeamMemberStatusRemoved
+	require.NoError(t, repo.SaveMember(ctx, member))
+	members, err = repo.ListMembers(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+	teams, err := repo.ListTeamsByUser(ctx, 2)
+	require.NoError(t, err)
+	assert.Empty(t, teams)
+
+	_, err = repo.GetMemberByUser(ctx, team.ID, 3)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestTeamRepository_ClaimAndRelease(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	order := &model.Order{UserID: 1, Status: model.OrderStatusConfirmed, QueueType: model.OrderQueueTypeTeam, RequiredMembers: 2}
+	solo := &model.Order{UserID: 1, Status: model.OrderStatusConfirmed}
+	require.NoError(t, db.Create(order).Error)
+	require.NoError(t, db.Create(solo).Error)
+
+	pool, total, err := repo.ListPoolOrders(ctx, nil, 1, 20)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Equal(t, order.ID, pool[0].ID)
+
+	first := &model.TeamOrderAssignment{OrderID: order.ID, TeamID: 1, LeaderUserID: 1,
+		Status: model.TeamAssignmentStatusDispatching, DispatchDeadline: now.Add(-time.Second), LockedAt: now}
+	ok, err := repo.ClaimOrder(ctx, first)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	second := &model.TeamOrderAssignment{OrderID: order.ID, TeamID: 2, LeaderUserID: 2,
+		Status: model.TeamAssignmentStatusDispatching, DispatchDeadline: now, LockedAt: now}
+	ok, err = repo.ClaimOrder(ctx, second)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Zero(t, second.ID)
+
+	// 单人订单不能被车队抢
+	ok, err = repo.ClaimOrder(ctx, &model.TeamOrderAssignment{OrderID: solo.ID, TeamID: 1, Status: model.TeamAssignmentStatusDispatching})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	expired, err := repo.ListExpiredAssignments(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+
+	ok, err = repo.ReleaseAssignment(ctx, &expired[0], now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.ReleaseAssignment(ctx, &expired[0], now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	var saved model.Order
+	require.NoError(t, db.First(&saved, order.ID).Error)
+	assert.Nil(t, saved.AssignedTeamID)
+	_, err = repo.GetActiveAssignment(ctx, order.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestTeamRepository_PayoutPlan(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.ReplaceAssignmentMembers(ctx, 1, []model.TeamAssignmentMember{
+		{AssignmentID: 1, MemberID: 10, UserID: 1, PlayerID: 11, State: model.TeamAssignmentMemberConfirmed},
+		{AssignmentID: 1, MemberID: 20, UserID: 2, PlayerID: 12, State: model.TeamAssignmentMemberPending},
+	}))
+	ok, err := repo.ConfirmAssignmentMember(ctx, 1, 2, time.Now())
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.ConfirmAssignmentMember(ctx, 1, 2, time.Now())
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, repo.SavePayoutPlan(ctx, &model.TeamPayoutPlan{AssignmentID: 1, ProfitMode: model.TeamProfitModeCustom},
+		map[uint64]int{10: 70, 20: 30}))
+	require.NoError(t, repo.SavePayoutPlan(ctx, &model.TeamPayoutPlan{AssignmentID: 1, ProfitMode: model.TeamProfitModeCustom},
+		map[uint64]int{10: 60, 20: 40}))
+	plan, err := repo.GetPayoutPlan(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.TeamProfitModeCustom, plan.ProfitMode)
+
+	members, err := repo.ListAssignmentMembers(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, 60, members[0].SharePercent)
+	assert.Equal(t, 40, members[1].SharePercent)
+	assert.Equal(t, model.TeamAssignmentMemberConfirmed, members[1].State)
+}
@@ -0,0 +1,70 @@
+package upload
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// UploadRepository 上传文件记录仓储
+type UploadRepository interface {
+	Create(ctx context.Context, upload *model.Upload) error
+	Get(ctx context.Context, id uint64) (*model.Upload, error)
+	// GetByHash 同一用户已完成的同内容上传，用于去重，没有时返回 ErrNotFound
+	GetByHash(ctx context.Context, userID uint64, hash string) (*model.Upload, error)
+	// ListByIDs 按 ID 批量读取，缺失的 ID 不报错
+	ListByIDs(ctx context.Context, ids []uint64) ([]model.Upload, error)
+}
+
+type uploadRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadRepository 创建上传记录仓储
+func NewUploadRepository(db *gorm.DB) UploadRepository {
+	return &uploadRepository{db: db}
+}
+
+func (r *uploadRepository) Create(ctx context.Context, upload *model.Upload) error {
+	return r.db.WithContext(ctx).Create(upload).Error
+}
+
+func (r *uploadRepository) Get(ctx context.Context, id uint64) (*model.Upload, error) {
+	var upload model.Upload
+	if err := r.db.WithContext(ctx).First(&upload, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) GetByHash(ctx context.Context, userID uint64, hash string) (*model.Upload, error) {
+	var upload model.Upload
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND hash = ? AND status = ?", userID, hash, model.UploadStatusCompleted).
+		Order("id").
+		First(&upload).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) ListByIDs(ctx context.Context, ids []uint64) ([]model.Upload, error) {
+	var uploads []model.Upload
+	if len(ids) == 0 {
+		return uploads, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&uploads).Error
+	return uploads, err
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
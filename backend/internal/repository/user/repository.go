@@ -70,8 +70,9 @@ func (r *gormUserRepository) ListWithFilters(ctx context.Context, opts repositor
 		q = q.Where("created_at <= ?", *opts.DateTo)
 	}
 	if kw := strings.TrimSpace(opts.Keyword); kw != "" {
+		// 手机号、邮箱加密存储，只能按盲索引精确匹配
 		like := "%" + kw + "%"
-		q = q.Where("name LIKE ? OR email LIKE ? OR phone LIKE ?", like, like, like)
+		q = q.Where("name LIKE ? OR email_bidx = ? OR phone_bidx = ?", like, model.UserEmailIndex(kw), model.UserPhoneIndex(kw))
 	}
 
 	var total int64
@@ -100,7 +101,7 @@ func (r *gormUserRepository) Get(ctx context.Context, id uint64) (*model.User, e
 // FindByEmail returns a user by unique email.
 func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email_bidx = ?", model.UserEmailIndex(email)).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, repository.ErrNotFound
 		}
@@ -112,7 +113,7 @@ func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*mo
 // GetByPhone returns a user by unique phone.
 func (r *gormUserRepository) GetByPhone(ctx context.Context, phone string) (*model.User, error) {
 	var user model.User
-	if err := r.db.WithContext(ctx).Where("phone = ?", phone).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("phone_bidx = ?", model.UserPhoneIndex(phone)).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, repository.ErrNotFound
 		}
@@ -133,16 +134,10 @@ func (r *gormUserRepository) Create(ctx context.Context, user *model.User) error
 
 // Update updates editable fields of a user.
 func (r *gormUserRepository) Update(ctx context.Context, user *model.User) error {
-	tx := r.db.WithContext(ctx).Model(user).Updates(map[string]any{
-		"phone":         user.Phone,
-		"email":         user.Email,
-		"name":          user.Name,
-		"avatar_url":    user.AvatarURL,
-		"role":          user.Role,
-		"status":        user.Status,
-		"password_hash": user.PasswordHash,
-		"last_login_at": user.LastLoginAt,
-	})
+	// 以结构体更新：加密字段经序列化器加密，盲索引由 BeforeSave 维护
+	tx := r.db.WithContext(ctx).Model(user).
+		Select("phone", "email", "phone_bidx", "email_bidx", "name", "avatar_url", "role", "status", "password_hash", "last_login_at").
+		Updates(user)
 	if tx.Error != nil {
 		return tx.Error
 	}
@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// ErrVersionConflict 钱包已被并发修改（乐观锁版本不匹配）
+var ErrVersionConflict = errors.New("wallet version conflict")
+
+// WalletRepository 陪玩师钱包仓储接口
+type WalletRepository interface {
+	// GetOrCreate 获取陪玩师指定币种的钱包，不存在时创建空钱包
+	GetOrCreate(ctx context.Context, playerID uint64, currency model.Currency) (*model.PlayerWallet, error)
+	// LockForUpdate 获取并锁定陪玩师指定币种的钱包（SELECT ... FOR UPDATE），不存在时先创建；
+	// 需在事务内调用，锁持有到事务结束
+	LockForUpdate(ctx context.Context, playerID uint64, currency model.Currency) (*model.PlayerWallet, error)
+	// Get 获取陪玩师指定币种的钱包
+	Get(ctx context.Context, playerID uint64, currency model.Currency) (*model.PlayerWallet, error)
+	// ListByPlayer 列出陪玩师的全部币种钱包
+	ListByPlayer(ctx context.Context, playerID uint64) ([]model.PlayerWallet, error)
+	// UpdateBalances 按版本号更新余额，版本不匹配时返回 ErrVersionConflict；成功后 wallet.Version 加一
+	UpdateBalances(ctx context.Context, wallet *model.PlayerWallet) error
+
+	// CreateTransaction 写入钱包流水
+	CreateTransaction(ctx context.Context, tx *model.WalletTransaction) error
+	// FindTransaction 按业务单据与流水类型查找流水
+	FindTransaction(ctx context.Context, txType model.WalletTransactionType, businessType string, businessID uint64) (*model.WalletTransaction, error)
+	// ListTransactions 查询钱包流水
+	ListTransactions(ctx context.Context, opts TransactionListOptions) ([]model.WalletTransaction, int64, error)
+}
+
+// TransactionListOptions 钱包流水查询选项
+type TransactionListOptions struct {
+	PlayerID uint64
+	Currency *model.Currency
+	Type     *model.WalletTransactionType
+	DateFrom *time.Time
+	DateTo   *time.Time
+	Page     int
+	PageSize int
+}
+
+type walletRepository struct {
+	db *gorm.DB
+}
+
+// NewWalletRepository 创建钱包仓储
+func NewWalletRepository(db *gorm.DB) WalletRepository {
+	return &walletRepository{db: db}
+}
+
+func (r *walletRepository) GetOrCreate(ctx context.Context, playerID uint64, currency model.Currency) (*model.PlayerWallet, error) {
+	currency = currency.OrDefault()
+	wallet, err := r.Get(ctx, playerID, currency)
+	if err == nil || !errors.Is(err, repository.ErrNotFound) {
+		return wallet, err
+	}
+	// 并发创建时以先写入者为准
+	err = r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "player_id"}, {Name: "currency"}}, DoNothing: true}).
+		Create(&model.PlayerWallet{PlayerID: playerID, Currency: currency}).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, playerID, currency)
+}
+
+func (r *walletRepository) LockForUpdate(ctx context.Context, playerID uint64, currency model.Currency) (*model.PlayerWallet, error) {
+	currency = currency.OrDefault()
+	db := r.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "player_id"}, {Name: "currency"}}, DoNothing: true}).
+		Create(&model.PlayerWallet{PlayerID: playerID, Currency: currency}).Error
+	if err != nil {
+		return nil, err
+	}
+	var wallet model.PlayerWallet
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("player_id = ? AND currency = ?", playerID, currency).First(&wallet).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *walletRepository) Get(ctx context.Context, playerID uint64, currency model.Currency) (*model.PlayerWallet, error) {
+	var wallet model.PlayerWallet
+	err := r.db.WithContext(ctx).Where("player_id = ? AND currency = ?", playerID, currency.OrDefault()).First(&wallet).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *walletRepository) ListByPlayer(ctx context.Context, playerID uint64) ([]model.PlayerWallet, error) {
+	var wallets []model.PlayerWallet
+	err := r.db.WithContext(ctx).Where("player_id = ?", playerID).Order("currency ASC").Find(&wallets).Error
+	return wallets, err
+}
+
+func (r *walletRepository) UpdateBalances(ctx context.Context, wallet *model.PlayerWallet) error {
+	now := time.Now()
+	res := r.db.WithContext(ctx).Model(&model.PlayerWallet{}).
+		Where("id = ? AND version = ?", wallet.ID, wallet.Version).
+		Updates(map[string]any{
+			"available_cents":       wallet.AvailableCents,
+			"frozen_cents":          wallet.FrozenCents,
+			"pending_cents":         wallet.PendingCents,
+			"total_income_cents":    wallet.TotalIncomeCents,
+			"total_withdrawn_cents": wallet.TotalWithdrawnCents,
+			"version":               wallet.Version + 1,
+			"updated_at":            now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	wallet.Version++
+	wallet.UpdatedAt = now
+	return nil
+}
+
+func (r *walletRepository) CreateTransaction(ctx context.Context, tx *model.WalletTransaction) error {
+	return r.db.WithContext(ctx).Create(tx).Error
+}
+
+func (r *walletRepository) FindTransaction(ctx context.Context, txType model.WalletTransactionType, businessType string, businessID uint64) (*model.WalletTransaction, error) {
+	var tx model.WalletTransaction
+	err := r.db.WithContext(ctx).
+		Where("type = ? AND business_type = ? AND business_id = ?", txType, businessType, businessID).
+		First(&tx).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (r *walletRepository) ListTransactions(ctx context.Context, opts TransactionListOptions) ([]model.WalletTransaction, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.WalletTransaction{}).Where("player_id = ?", opts.PlayerID)
+	if opts.Currency != nil {
+		query = query.Where("currency = ?", *opts.Currency)
+	}
+	if opts.Type != nil {
+		query = query.Where("type = ?", *opts.Type)
+	}
+	if opts.DateFrom != nil {
+		query = query.Where("created_at >= ?", *opts.DateFrom)
+	}
+	if opts.DateTo != nil {
+		query = query.Where("created_at < ?", *opts.DateTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize < 1 {
+		opts.PageSize = 20
+	}
+
+	var txs []model.WalletTransaction
+	err := query.Order("id DESC").Offset((opts.Page - 1) * opts.PageSize).Limit(opts.PageSize).Find(&txs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return txs, total, nil
+}
@@ -0,0 +1,132 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&model.PlayerWallet{}, &model.WalletTransaction{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestWalletRepository_GetOrCreate(t *testing.T) {
+	repo := NewWalletRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	_, err := repo.Get(ctx, 7, model.CurrencyCNY)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	first, err := repo.GetOrCreate(ctx, 7, "")
+	require.NoError(t, err)
+	assert.NotZero(t, first.ID)
+	assert.Equal(t, uint64(7), first.PlayerID)
+	assert.Equal(t, model.CurrencyCNY, first.Currency)
+
+	again, err := repo.GetOrCreate(ctx, 7, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+
+	// 每个币种一个钱包
+	usd, err := repo.GetOrCreate(ctx, 7, model.CurrencyUSD)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, usd.ID)
+	wallets, err := repo.ListByPlayer(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, wallets, 2)
+	assert.Equal(t, model.CurrencyCNY, wallets[0].Currency)
+	assert.Equal(t, model.CurrencyUSD, wallets[1].Currency)
+}
+
+func TestWalletRepository_LockForUpdate(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		repo := NewWalletRepository(tx)
+		locked, err := repo.LockForUpdate(ctx, 3, "")
+		require.NoError(t, err)
+		assert.NotZero(t, locked.ID)
+		assert.Equal(t, model.CurrencyCNY, locked.Currency)
+
+		again, err := repo.LockForUpdate(ctx, 3, model.CurrencyCNY)
+		require.NoError(t, err)
+		assert.Equal(t, locked.ID, again.ID)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestWalletRepository_UpdateBalancesOptimisticLock(t *testing.T) {
+	repo := NewWalletRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	a, err := repo.GetOrCreate(ctx, 1, model.CurrencyCNY)
+	require.NoError(t, err)
+	b, err := repo.Get(ctx, 1, model.CurrencyCNY)
+	require.NoError(t, err)
+
+	a.AvailableCents = 500
+	require.NoError(t, repo.UpdateBalances(ctx, a))
+	assert.Equal(t, int64(1), a.Version)
+
+	// b 持有旧版本，更新失败且不覆盖 a 的结果
+	b.AvailableCents = 900
+	assert.ErrorIs(t, repo.UpdateBalances(ctx, b), ErrVersionConflict)
+
+	got, err := repo.Get(ctx, 1, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), got.AvailableCents)
+	assert.Equal(t, int64(1), got.Version)
+}
+
+func TestWalletRepository_Transactions(t *testing.T) {
+	repo := NewWalletRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	w, err := repo.GetOrCreate(ctx, 1, model.CurrencyCNY)
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateTransaction(ctx, &model.WalletTransaction{
+		WalletID: w.ID, PlayerID: 1, Type: model.WalletTxIncome,
+		BusinessType: model.WalletBusinessCommission, BusinessID: 10, AmountCents: 800, PendingDelta: 800, PendingAfter: 800,
+	}))
+	require.NoError(t, repo.CreateTransaction(ctx, &model.WalletTransaction{
+		WalletID: w.ID, PlayerID: 1, Type: model.WalletTxSettle,
+		BusinessType: model.WalletBusinessCommission, BusinessID: 10, AmountCents: 800, PendingDelta: -800, AvailableDelta: 800, AvailableAfter: 800,
+	}))
+	// 同一业务单据的同类流水只能写一次
+	err = repo.CreateTransaction(ctx, &model.WalletTransaction{
+		WalletID: w.ID, PlayerID: 1, Type: model.WalletTxIncome,
+		BusinessType: model.WalletBusinessCommission, BusinessID: 10, AmountCents: 800,
+	})
+	assert.Error(t, err)
+
+	found, err := repo.FindTransaction(ctx, model.WalletTxSettle, model.WalletBusinessCommission, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), found.AvailableAfter)
+	_, err = repo.FindTransaction(ctx, model.WalletTxWithdrawFreeze, model.WalletBusinessWithdraw, 10)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	txs, total, err := repo.ListTransactions(ctx, TransactionListOptions{PlayerID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, model.WalletTxSettle, txs[0].Type)
+
+	typ := model.WalletTxIncome
+	_, total, err = repo.ListTransactions(ctx, TransactionListOptions{PlayerID: 1, Type: &typ})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
@@ -0,0 +1,156 @@
+package withdraw
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// ErrStatusConflict 提现状态已被其他操作修改
+var ErrStatusConflict = errors.New("withdraw status changed concurrently")
+
+// PayoutRepository 提现打款仓储接口（状态流转、打款批次）
+type PayoutRepository interface {
+	// Transition 仅当提现仍处于 from 状态时保存全部字段，否则返回 ErrStatusConflict
+	Transition(ctx context.Context, withdraw *model.Withdraw, from model.WithdrawStatus) error
+	// ListApproved 按审核时间顺序列出指定币种的待打款提现；method 为空表示不限提现方式
+	ListApproved(ctx context.Context, currency model.Currency, method *model.WithdrawMethod, limit int) ([]model.Withdraw, error)
+	// ListDuePayouts 列出需要向代付渠道提交或查询的提现
+	ListDuePayouts(ctx context.Context, now time.Time, limit int) ([]model.Withdraw, error)
+	// ListByBatch 列出批次内的提现
+	ListByBatch(ctx context.Context, batchID uint64) ([]model.Withdraw, error)
+
+	// CreateBatch 创建打款批次
+	CreateBatch(ctx context.Context, batch *model.WithdrawBatch) error
+	// GetBatch 获取打款批次
+	GetBatch(ctx context.Context, id uint64) (*model.WithdrawBatch, error)
+	// UpdateBatch 更新打款批次
+	UpdateBatch(ctx context.Context, batch *model.WithdrawBatch) error
+	// ListBatches 查询打款批次
+	ListBatches(ctx context.Context, opts BatchListOptions) ([]model.WithdrawBatch, int64, error)
+	// CountBatchStatuses 按状态统计批次内提现数量
+	CountBatchStatuses(ctx context.Context, batchID uint64) (map[model.WithdrawStatus]int64, error)
+}
+
+// BatchListOptions 打款批次查询选项
+type BatchListOptions struct {
+	Status   *model.WithdrawBatchStatus
+	Page     int
+	PageSize int
+}
+
+// NewPayoutRepository 创建提现打款仓储
+func NewPayoutRepository(db *gorm.DB) PayoutRepository {
+	return &withdrawRepository{db: db}
+}
+
+// Transition 条件更新：并发审核或重复回调时只有一方能推进状态
+func (r *withdrawRepository) Transition(ctx context.Context, withdraw *model.Withdraw, from model.WithdrawStatus) error {
+	res := r.db.WithContext(ctx).Model(&model.Withdraw{}).
+		Where("id = ? AND status = ?", withdraw.ID, from).
+		Select("*").Omit("id", "created_at").
+		Updates(withdraw)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (r *withdrawRepository) ListApproved(ctx context.Context, currency model.Currency, method *model.WithdrawMethod, limit int) ([]model.Withdraw, error) {
+	query := r.db.WithContext(ctx).Where("status = ? AND batch_id IS NULL AND currency = ?", model.WithdrawStatusApproved, currency.OrDefault())
+	if method != nil {
+		query = query.Where("method = ?", *method)
+	}
+	var withdraws []model.Withdraw
+	err := query.Order("processed_at ASC, id ASC").Limit(limit).Find(&withdraws).Error
+	return withdraws, err
+}
+
+func (r *withdrawRepository) ListDuePayouts(ctx context.Context, now time.Time, limit int) ([]model.Withdraw, error) {
+	var withdraws []model.Withdraw
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND payout_channel = ?", model.WithdrawStatusProcessing, model.PayoutChannelProvider).
+		Where("next_retry_at IS NULL OR next_retry_at <= ?", now).
+		Order("next_retry_at ASC, id ASC").
+		Limit(limit).
+		Find(&withdraws).Error
+	return withdraws, err
+}
+
+func (r *withdrawRepository) ListByBatch(ctx context.Context, batchID uint64) ([]model.Withdraw, error) {
+	var withdraws []model.Withdraw
+	err := r.db.WithContext(ctx).Where("batch_id = ?", batchID).Order("id ASC").Find(&withdraws).Error
+	return withdraws, err
+}
+
+func (r *withdrawRepository) CreateBatch(ctx context.Context, batch *model.WithdrawBatch) error {
+	return r.db.WithContext(ctx).Create(batch).Error
+}
+
+func (r *withdrawRepository) GetBatch(ctx context.Context, id uint64) (*model.WithdrawBatch, error) {
+	var batch model.WithdrawBatch
+	if err := r.db.WithContext(ctx).First(&batch, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *withdrawRepository) UpdateBatch(ctx context.Context, batch *model.WithdrawBatch) error {
+	return r.db.WithContext(ctx).Save(batch).Error
+}
+
+func (r *withdrawRepository) ListBatches(ctx context.Context, opts BatchListOptions) ([]model.WithdrawBatch, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.WithdrawBatch{})
+	if opts.Status != nil {
+		query = query.Where("status = ?", *opts.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize < 1 {
+		opts.PageSize = 20
+	}
+
+	var batches []model.WithdrawBatch
+	err := query.Order("id DESC").Offset((opts.Page - 1) * opts.PageSize).Limit(opts.PageSize).Find(&batches).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return batches, total, nil
+}
+
+func (r *withdrawRepository) CountBatchStatuses(ctx context.Context, batchID uint64) (map[model.WithdrawStatus]int64, error) {
+	var rows []struct {
+		Status model.WithdrawStatus
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.Withdraw{}).
+		Select("status, COUNT(*) AS count").
+		Where("batch_id = ?", batchID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[model.WithdrawStatus]int64, len(rows))
+	for _, row := range rows {
+		out[row.Status] = row.Count
+	}
+	return out, nil
+}
@@ -169,7 +169,10 @@ func (r *withdrawRepository) GetPlayerBalance(ctx context.Context, playerID uint
 	var pendingBalance int64
 	err = r.db.WithContext(ctx).
 		Model(&model.Order{}).
-		Where("player_id = ? AND status = ?", playerID, model.OrderStatusInProgress).
+		Where("player_id = ? AND status IN ?", playerID, []model.OrderStatus{
+			model.OrderStatusInProgress,
+			model.OrderStatusPendingConfirmation,
+		}).
 		Select("COALESCE(SUM(total_price_cents), 0)").
 		Scan(&pendingBalance).Error
 	if err != nil {
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+)
+
+// CommissionRecorder 为已完成但尚未记录抽成的订单补记抽成（由抽成服务实现）。
+type CommissionRecorder interface {
+	RecordMissingCommissions(ctx context.Context, limit int) (int, error)
+}
+
+// commissionBatchSize 每轮最多补记的订单数量。
+const commissionBatchSize = 100
+
+// CommissionScheduler 抽成补记调度器：订单完成后记录抽成失败时，定期补记。
+type CommissionScheduler struct {
+	*job
+	commissions CommissionRecorder
+}
+
+// NewCommissionScheduler 创建抽成补记调度器，每 5 分钟补记一次。
+func NewCommissionScheduler(commissions CommissionRecorder) *CommissionScheduler {
+	s := &CommissionScheduler{commissions: commissions}
+	s.job = newJob("Commission", "", "5m", s.process)
+	return s
+}
+
+func (s *CommissionScheduler) process(ctx context.Context) {
+	n, err := s.commissions.RecordMissingCommissions(ctx, commissionBatchSize)
+	if err != nil {
+		log.Printf("[Commission] record missing commissions error: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[Commission] recorded %d missing commissions", n)
+	}
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+)
+
+// CouponSettler 结转优惠券：过期作废、取消/退款退回、完成核销（由优惠券服务实现）。
+type CouponSettler interface {
+	Settle(ctx context.Context, limit int) (int, error)
+}
+
+// couponBatchSize 每轮最多结转的优惠券数量。
+const couponBatchSize = 200
+
+// CouponScheduler 优惠券调度器：按订单状态退回或核销锁定的优惠券，并作废过期优惠券。
+type CouponScheduler struct {
+	*job
+	coupons CouponSettler
+}
+
+// NewCouponScheduler 创建优惠券调度器，每分钟结转一次优惠券。
+func NewCouponScheduler(coupons CouponSettler) *CouponScheduler {
+	s := &CouponScheduler{coupons: coupons}
+	s.job = newJob("Coupon", "", "1m", s.process)
+	return s
+}
+
+func (s *CouponScheduler) process(ctx context.Context) {
+	n, err := s.coupons.Settle(ctx, couponBatchSize)
+	if err != nil {
+		log.Printf("[Coupon] settle coupons error: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[Coupon] settled %d coupons", n)
+	}
+}
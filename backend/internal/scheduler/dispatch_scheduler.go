@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	dispatchservice "gamelink/internal/service/dispatch"
+)
+
+// DispatchProcessor 过期派单邀请并派出新订单（由派单服务实现）。
+type DispatchProcessor interface {
+	ProcessDispatch(ctx context.Context, limit int) (dispatchservice.ProcessResult, error)
+}
+
+// dispatchBatchSize 每轮最多处理的邀请与订单数量。
+const dispatchBatchSize = 100
+
+// DispatchScheduler 派单调度器：超时未响应的邀请过期后订单回落抢单大厅，并按默认模式派出新订单。
+type DispatchScheduler struct {
+	*job
+	dispatcher DispatchProcessor
+}
+
+// NewDispatchScheduler 创建派单调度器；interval 为 cron @every 间隔（如 30s），为空时每 30 秒执行。
+func NewDispatchScheduler(dispatcher DispatchProcessor, interval string) *DispatchScheduler {
+	s := &DispatchScheduler{dispatcher: dispatcher}
+	s.job = newJob("Dispatch", interval, "30s", s.process)
+	return s
+}
+
+func (s *DispatchScheduler) process(ctx context.Context) {
+	res, err := s.dispatcher.ProcessDispatch(ctx, dispatchBatchSize)
+	if err != nil {
+		log.Printf("[Dispatch] process error: %v", err)
+	}
+	if res.Expired > 0 || res.Dispatched > 0 {
+		log.Printf("[Dispatch] expired %d offers, dispatched %d orders", res.Expired, res.Dispatched)
+	}
+}
@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	"gamelink/internal/service/assignment"
+)
+
+// DisputeSLAProcessor 指派待处理争议并执行 SLA 超时升级（由争议指派服务实现）。
+type DisputeSLAProcessor interface {
+	ProcessSLA(ctx context.Context, limit int) (assignment.SLAResult, error)
+}
+
+// disputeSLABatchSize 每轮最多自动指派的争议数量。
+const disputeSLABatchSize = 100
+
+// DisputeSLAScheduler 争议工作台调度器：把待处理争议指派给值班客服，超过 SLA 的争议升级给上一级客服。
+type DisputeSLAScheduler struct {
+	*job
+	processor DisputeSLAProcessor
+}
+
+// NewDisputeSLAScheduler 创建争议 SLA 调度器；interval 为 cron @every 间隔，为空时每分钟执行。
+func NewDisputeSLAScheduler(processor DisputeSLAProcessor, interval string) *DisputeSLAScheduler {
+	s := &DisputeSLAScheduler{processor: processor}
+	s.job = newJob("DisputeSLA", interval, "1m", s.process)
+	return s
+}
+
+func (s *DisputeSLAScheduler) process(ctx context.Context) {
+	res, err := s.processor.ProcessSLA(ctx, disputeSLABatchSize)
+	if err != nil {
+		log.Printf("[DisputeSLA] process error: %v", err)
+	}
+	if res.Assigned > 0 || res.Breached > 0 {
+		log.Printf("[DisputeSLA] assigned %d disputes, %d breached SLA, %d escalated", res.Assigned, res.Breached, res.Escalated)
+	}
+}
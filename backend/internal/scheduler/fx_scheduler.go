@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	"gamelink/internal/model"
+)
+
+// RateRefresher 从数据源刷新汇率（由汇率服务实现）。
+type RateRefresher interface {
+	RefreshRates(ctx context.Context) ([]model.ExchangeRate, error)
+}
+
+// FXRateScheduler 汇率刷新调度器
+type FXRateScheduler struct {
+	*job
+	rates RateRefresher
+}
+
+// NewFXRateScheduler 创建汇率刷新调度器；interval 为 cron @every 间隔（如 1h），为空时每小时刷新。
+// 启动时立即刷新一次，之后按间隔刷新。
+func NewFXRateScheduler(rates RateRefresher, interval string) *FXRateScheduler {
+	s := &FXRateScheduler{rates: rates}
+	s.job = newJob("FX", interval, "1h", s.process)
+	s.runNow = true
+	return s
+}
+
+func (s *FXRateScheduler) process(ctx context.Context) {
+	rates, err := s.rates.RefreshRates(ctx)
+	if err != nil {
+		log.Printf("[FX] refresh rates error: %v", err)
+		return
+	}
+	log.Printf("[FX] refreshed %d rates", len(rates))
+}
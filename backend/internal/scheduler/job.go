@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	"github.com/robfig/cron/v3"
+)
+
+// job 按 @every 间隔执行的批处理任务，各调度器嵌入后复用 Start/Stop/ProcessOnce。
+type job struct {
+	name     string
+	interval string
+	run      func(ctx context.Context)
+	runNow   bool // Start 时先立即执行一轮
+	cron     *cron.Cron
+}
+
+// newJob 创建批处理任务；name 为日志前缀，interval 为空时使用 fallback。
+func newJob(name, interval, fallback string, run func(ctx context.Context)) *job {
+	if interval == "" {
+		interval = fallback
+	}
+	return &job{name: name, interval: interval, run: run, cron: cron.New()}
+}
+
+// Start 按间隔执行任务
+func (j *job) Start() {
+	if _, err := j.cron.AddFunc("@every "+j.interval, j.ProcessOnce); err != nil {
+		log.Printf("[%s] add job error: %v", j.name, err)
+		return
+	}
+	if j.runNow {
+		j.ProcessOnce()
+	}
+	j.cron.Start()
+	log.Printf("[%s] scheduler started - every %s", j.name, j.interval)
+}
+
+// Stop 停止调度器
+func (j *job) Stop() { j.cron.Stop() }
+
+// ProcessOnce 手动执行一轮（用于测试和补偿）
+func (j *job) ProcessOnce() { j.run(context.Background()) }
@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/assignment"
+	dispatchservice "gamelink/internal/service/dispatch"
+	orderservice "gamelink/internal/service/order"
+)
+
+// fakeProcessor 同时实现各调度器依赖的处理接口，记录调用次数与批量大小。
+type fakeProcessor struct {
+	calls     int
+	lastLimit int
+	err       error
+}
+
+func (f *fakeProcessor) record(limit int) error {
+	f.calls++
+	f.lastLimit = limit
+	return f.err
+}
+
+func (f *fakeProcessor) ProcessPayouts(_ context.Context, limit int) (int, error) {
+	return 1, f.record(limit)
+}
+
+func (f *fakeProcessor) ProcessPendingRefunds(_ context.Context, limit int) (int, error) {
+	return 1, f.record(limit)
+}
+
+func (f *fakeProcessor) ProcessTimeouts(_ context.Context, limit int) (orderservice.TimeoutResult, error) {
+	return orderservice.TimeoutResult{Canceled: 1}, f.record(limit)
+}
+
+func (f *fakeProcessor) ProcessDispatch(_ context.Context, limit int) (dispatchservice.ProcessResult, error) {
+	return dispatchservice.ProcessResult{Expired: 1}, f.record(limit)
+}
+
+func (f *fakeProcessor) ReleaseExpiredAssignments(_ context.Context, limit int) (int, error) {
+	return 1, f.record(limit)
+}
+
+func (f *fakeProcessor) Settle(_ context.Context, limit int) (int, error) {
+	return 1, f.record(limit)
+}
+
+func (f *fakeProcessor) ProcessSLA(_ context.Context, limit int) (assignment.SLAResult, error) {
+	return assignment.SLAResult{Assigned: 1, Breached: 1, Escalated: 1}, f.record(limit)
+}
+
+func (f *fakeProcessor) RecordMissingCommissions(_ context.Context, limit int) (int, error) {
+	return 1, f.record(limit)
+}
+
+func (f *fakeProcessor) RefreshRates(context.Context) ([]model.ExchangeRate, error) {
+	return nil, f.record(0)
+}
+
+func TestBatchSchedulers(t *testing.T) {
+	cases := []struct {
+		name     string
+		build    func(f *fakeProcessor) *job
+		batch    int
+		fallback string
+	}{
+		{"payout", func(f *fakeProcessor) *job { return NewPayoutScheduler(f).job }, payoutBatchSize, "1m"},
+		{"refund", func(f *fakeProcessor) *job { return NewRefundScheduler(f).job }, refundBatchSize, "1m"},
+		{"order timeout", func(f *fakeProcessor) *job { return NewOrderTimeoutScheduler(f, "").job }, orderTimeoutBatchSize, "1m"},
+		{"dispatch", func(f *fakeProcessor) *job { return NewDispatchScheduler(f, "").job }, dispatchBatchSize, "30s"},
+		{"team assignment", func(f *fakeProcessor) *job { return NewTeamAssignmentScheduler(f, "").job }, teamReleaseBatchSize, "1m"},
+		{"coupon", func(f *fakeProcessor) *job { return NewCouponScheduler(f).job }, couponBatchSize, "1m"},
+		{"dispute sla", func(f *fakeProcessor) *job { return NewDisputeSLAScheduler(f, "").job }, disputeSLABatchSize, "1m"},
+		{"commission", func(f *fakeProcessor) *job { return NewCommissionScheduler(f).job }, commissionBatchSize, "5m"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeProcessor{}
+			j := tc.build(fake)
+			assert.Equal(t, tc.fallback, j.interval)
+
+			j.ProcessOnce()
+			assert.Equal(t, 1, fake.calls)
+			assert.Equal(t, tc.batch, fake.lastLimit)
+
+			// 出错时仅记录日志，不影响下一轮
+			fake.err = errors.New("boom")
+			j.ProcessOnce()
+			assert.Equal(t, 2, fake.calls)
+
+			j.Start()
+			assert.Len(t, j.cron.Entries(), 1)
+			j.Stop()
+		})
+	}
+}
+
+func TestFXRateScheduler_RefreshesOnStart(t *testing.T) {
+	fake := &fakeProcessor{}
+	s := NewFXRateScheduler(fake, "")
+	assert.Equal(t, "1h", s.interval)
+
+	s.Start()
+	assert.Equal(t, 1, fake.calls, "rates are refreshed once at startup")
+	assert.Len(t, s.cron.Entries(), 1)
+	s.Stop()
+
+	fake.err = errors.New("boom")
+	s.ProcessOnce()
+	assert.Equal(t, 2, fake.calls)
+}
+
+func TestJob_CustomInterval(t *testing.T) {
+	custom := newJob("Custom", "10s", "1m", func(context.Context) {})
+	assert.Equal(t, "10s", custom.interval)
+
+	// 非法间隔不注册任务
+	bad := newJob("Bad", "soon", "1m", func(context.Context) {})
+	bad.Start()
+	assert.Empty(t, bad.cron.Entries())
+	bad.Stop()
+}
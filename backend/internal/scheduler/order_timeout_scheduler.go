@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	orderservice "gamelink/internal/service/order"
+)
+
+// OrderTimeoutProcessor 处理超时订单（由订单服务实现）。
+type OrderTimeoutProcessor interface {
+	ProcessTimeouts(ctx context.Context, limit int) (orderservice.TimeoutResult, error)
+}
+
+// orderTimeoutBatchSize 每轮每类最多处理的订单数量。
+const orderTimeoutBatchSize = 100
+
+// OrderTimeoutScheduler 订单超时调度器：未支付自动取消、未接单自动退款、超时未确认自动完成。
+type OrderTimeoutScheduler struct {
+	*job
+	orders OrderTimeoutProcessor
+}
+
+// NewOrderTimeoutScheduler 创建订单超时调度器；interval 为 cron @every 间隔（如 1m），为空时每分钟执行。
+func NewOrderTimeoutScheduler(orders OrderTimeoutProcessor, interval string) *OrderTimeoutScheduler {
+	s := &OrderTimeoutScheduler{orders: orders}
+	s.job = newJob("OrderTimeout", interval, "1m", s.process)
+	return s
+}
+
+func (s *OrderTimeoutScheduler) process(ctx context.Context) {
+	res, err := s.orders.ProcessTimeouts(ctx, orderTimeoutBatchSize)
+	if err != nil {
+		log.Printf("[OrderTimeout] process error: %v", err)
+	}
+	if res.Total() > 0 {
+		log.Printf("[OrderTimeout] canceled %d, refunded %d, completed %d, confirmed %d orders", res.Canceled, res.Refunded, res.Completed, res.Confirmed)
+	}
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+)
+
+// PayoutProcessor 推进代付批次中的提现（由提现服务实现）。
+type PayoutProcessor interface {
+	ProcessPayouts(ctx context.Context, limit int) (int, error)
+}
+
+// payoutBatchSize 每轮最多推进的提现数量。
+const payoutBatchSize = 100
+
+// PayoutScheduler 代付调度器：提交未受理的付款、重试失败的提交，并查询处理中的付款结果。
+type PayoutScheduler struct {
+	*job
+	payouts PayoutProcessor
+}
+
+// NewPayoutScheduler 创建代付调度器，每分钟推进一次代付。
+func NewPayoutScheduler(payouts PayoutProcessor) *PayoutScheduler {
+	s := &PayoutScheduler{payouts: payouts}
+	s.job = newJob("Payout", "", "1m", s.process)
+	return s
+}
+
+func (s *PayoutScheduler) process(ctx context.Context) {
+	n, err := s.payouts.ProcessPayouts(ctx, payoutBatchSize)
+	if err != nil {
+		log.Printf("[Payout] process payouts error: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[Payout] finished %d payouts", n)
+	}
+}
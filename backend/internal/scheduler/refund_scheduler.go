@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+)
+
+// RefundProcessor 推进未终结的退款单（由支付服务实现）。
+type RefundProcessor interface {
+	ProcessPendingRefunds(ctx context.Context, limit int) (int, error)
+}
+
+// refundBatchSize 每轮最多推进的退款单数量。
+const refundBatchSize = 100
+
+// RefundScheduler 退款调度器：重试提交失败的退款，并向渠道查询处理中的退款结果。
+type RefundScheduler struct {
+	*job
+	refunds RefundProcessor
+}
+
+// NewRefundScheduler 创建退款调度器，每分钟推进一次待处理退款。
+func NewRefundScheduler(refunds RefundProcessor) *RefundScheduler {
+	s := &RefundScheduler{refunds: refunds}
+	s.job = newJob("Refund", "", "1m", s.process)
+	return s
+}
+
+func (s *RefundScheduler) process(ctx context.Context) {
+	n, err := s.refunds.ProcessPendingRefunds(ctx, refundBatchSize)
+	if err != nil {
+		log.Printf("[Refund] process pending refunds error: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[Refund] advanced %d pending refunds", n)
+	}
+}
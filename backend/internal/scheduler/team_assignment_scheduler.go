@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+)
+
+// TeamAssignmentReleaser 释放组队超时的车队接单（由车队服务实现）。
+type TeamAssignmentReleaser interface {
+	ReleaseExpiredAssignments(ctx context.Context, limit int) (int, error)
+}
+
+// teamReleaseBatchSize 每轮最多释放的车队接单数量。
+const teamReleaseBatchSize = 100
+
+// TeamAssignmentScheduler 车队接单调度器：组队超时的订单释放回车队大厅。
+type TeamAssignmentScheduler struct {
+	*job
+	releaser TeamAssignmentReleaser
+}
+
+// NewTeamAssignmentScheduler 创建车队接单调度器；interval 为 cron @every 间隔，为空时每分钟执行。
+func NewTeamAssignmentScheduler(releaser TeamAssignmentReleaser, interval string) *TeamAssignmentScheduler {
+	s := &TeamAssignmentScheduler{releaser: releaser}
+	s.job = newJob("TeamAssignment", interval, "1m", s.process)
+	return s
+}
+
+func (s *TeamAssignmentScheduler) process(ctx context.Context) {
+	n, err := s.releaser.ReleaseExpiredAssignments(ctx, teamReleaseBatchSize)
+	if err != nil {
+		log.Printf("[TeamAssignment] process error: %v", err)
+	}
+	if n > 0 {
+		log.Printf("[TeamAssignment] released %d expired assignments", n)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"sort"
 	"strings"
@@ -18,6 +19,12 @@ import (
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
 	"gamelink/internal/repository/common"
+	orderhistory "gamelink/internal/repository/order_history"
+	"gamelink/internal/service/availability"
+	commissionservice "gamelink/internal/service/commission"
+	"gamelink/internal/service/orderstate"
+	paymentservice "gamelink/internal/service/payment"
+	"gamelink/internal/service/pricing"
 )
 
 var (
@@ -26,7 +33,11 @@ var (
 	// ErrUserNotFound 用于统一标识用户不存在的场景。
 	ErrUserNotFound = errors.New("user not found")
 	// ErrOrderInvalidTransition 代表订单状态流转不合法。
-	ErrOrderInvalidTransition = errors.New("invalid order status transition")
+	ErrOrderInvalidTransition = orderstate.ErrInvalidTransition
+	// ErrRefundViaRefundOrder 订单不能直接改为已退款，需通过 RefundOrder 创建退款单。
+	ErrRefundViaRefundOrder = fmt.Errorf("%w: refund orders through RefundOrder", ErrValidation)
+	// ErrBookingConflict 指派的陪玩师在订单时段已有其他订单。
+	ErrBookingConflict = availability.ErrBookingConflict
 
 	// ErrNotFound 暴露仓储的未找到错误，便于 handler 判定。
 	ErrNotFound = repository.ErrNotFound
@@ -42,6 +53,11 @@ type AdminService struct {
 	roles    repository.RoleRepository
 	cache    cache.Cache
 	tx       TxManager
+	refunder Refunder
+	history  orderhistory.HistoryRepository
+	bookings BookingConflictChecker
+	pricer   OrderPricer
+	recorder CommissionRecorder
 }
 
 const (
@@ -92,6 +108,42 @@ type TxManager interface {
 // SetTxManager injects a transaction manager.
 func (s *AdminService) SetTxManager(tx TxManager) { s.tx = tx }
 
+// BookingConflictChecker 校验陪玩师预约冲突（由陪玩师日程服务实现）。
+type BookingConflictChecker interface {
+	CheckConflict(ctx context.Context, playerID uint64, start, end time.Time, exceptOrderID uint64) error
+}
+
+// SetBookingChecker 注入陪玩师日程服务，指派陪玩师时拒绝与其他订单时间重叠
+func (s *AdminService) SetBookingChecker(b BookingConflictChecker) { s.bookings = b }
+
+// OrderPricer 统一定价引擎报价（由定价服务实现）。
+type OrderPricer interface {
+	Quote(ctx context.Context, req pricing.QuoteRequest) (*pricing.Quote, error)
+}
+
+// SetPricer 注入定价服务，后台建单按服务项目与抽成规则定价；填写的总价作为手工调价。
+func (s *AdminService) SetPricer(p OrderPricer) { s.pricer = p }
+
+// Refunder 退款单能力（由支付服务实现）。
+type Refunder interface {
+	CreateRefund(ctx context.Context, req paymentservice.CreateRefundRequest) (*model.Refund, error)
+	ListOrderRefunds(ctx context.Context, orderID uint64) ([]model.Refund, error)
+}
+
+// SetRefunder 注入退款服务，后台退款与退款记录查询均基于退款单。
+func (s *AdminService) SetRefunder(r Refunder) { s.refunder = r }
+
+// SetStatusHistory 注入订单状态历史仓储，记录后台发起的状态流转并用于订单时间线。
+func (s *AdminService) SetStatusHistory(h orderhistory.HistoryRepository) { s.history = h }
+
+// CommissionRecorder 订单完成后记录抽成并入账总账与陪玩师钱包（由抽成服务实现）。
+type CommissionRecorder interface {
+	RecordCommission(ctx context.Context, orderID uint64) error
+}
+
+// SetCommissionRecorder 注入抽成服务，后台完成订单与用户确认完成一样记录抽成、入账总账与钱包。
+func (s *AdminService) SetCommissionRecorder(r CommissionRecorder) { s.recorder = r }
+
 // UpdatePlayerSkillTags 替换玩家技能标签集合（需要 TxManager）。
 func (s *AdminService) UpdatePlayerSkillTags(ctx context.Context, playerID uint64, tags []string) error {
 	if s.tx == nil {
@@ -720,6 +772,8 @@ type CreateOrderInput struct {
 	Currency        model.Currency
 	ScheduledStart  *time.Time
 	ScheduledEnd    *time.Time
+	ItemID          uint64 // 可选：服务项目，不填时按游戏与陪玩师匹配
+	Quantity        int
 }
 
 // CreateOrder 新建订单，默认状态为 pending。
@@ -754,6 +808,13 @@ func (s *AdminService) CreateOrder(ctx context.Context, in CreateOrderInput) (*m
 	if in.PlayerID != nil {
 		order.PlayerID = in.PlayerID
 	}
+	if s.pricer != nil {
+		quote, err := s.quoteOrder(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		quote.ApplyTo(order)
+	}
 	if err := s.orders.Create(ctx, order); err != nil {
 		return nil, err
 	}
@@ -762,6 +823,28 @@ func (s *AdminService) CreateOrder(ctx context.Context, in CreateOrderInput) (*m
 	return order, nil
 }
 
+// quoteOrder 通过定价服务为后台订单报价，预约时段作为服务时长。
+func (s *AdminService) quoteOrder(ctx context.Context, in CreateOrderInput) (*pricing.Quote, error) {
+	req := pricing.QuoteRequest{
+		ItemID:      in.ItemID,
+		GameID:      in.GameID,
+		Quantity:    in.Quantity,
+		Currency:    in.Currency,
+		ManualTotal: in.TotalPriceCents,
+	}
+	if in.PlayerID != nil {
+		req.PlayerID = *in.PlayerID
+	}
+	if in.ScheduledStart != nil && in.ScheduledEnd != nil {
+		req.DurationHours = float32(in.ScheduledEnd.Sub(*in.ScheduledStart).Minutes()) / 60
+	}
+	quote, err := s.pricer.Quote(ctx, req)
+	if errors.Is(err, pricing.ErrValidation) || errors.Is(err, pricing.ErrNoServiceItem) {
+		return nil, ErrValidation
+	}
+	return quote, err
+}
+
 // AssignOrder 指派陪玩师。
 func (s *AdminService) AssignOrder(ctx context.Context, id uint64, playerID uint64) (*model.Order, error) {
 	if playerID == 0 {
@@ -774,11 +857,16 @@ func (s *AdminService) AssignOrder(ctx context.Context, id uint64, playerID uint
 	if err != nil {
 		return nil, err
 	}
-	// 不允许在完成/取消/退款后指派
+	// 不允许在提交完成/完成/取消/退款后指派
 	switch order.Status {
-	case model.OrderStatusCompleted, model.OrderStatusCanceled, model.OrderStatusRefunded:
+	case model.OrderStatusPendingConfirmation, model.OrderStatusCompleted, model.OrderStatusCanceled, model.OrderStatusRefunded:
 		return nil, ErrValidation
 	}
+	if s.bookings != nil && order.ScheduledStart != nil && order.ScheduledEnd != nil {
+		if err := s.bookings.CheckConflict(ctx, playerID, *order.ScheduledStart, *order.ScheduledEnd, order.ID); err != nil {
+			return nil, err
+		}
+	}
 	order.SetPlayerID(playerID)
 	if err := s.orders.Update(ctx, order); err != nil {
 		return nil, err
@@ -830,16 +918,20 @@ type OrderTimelineItem struct {
 
 // OrderRefundItem 描述订单退款记录。
 type OrderRefundItem struct {
-	ID          uint64     `json:"id"`
-	OrderID     uint64     `json:"order_id"`
-	PaymentID   uint64     `json:"payment_id"`
-	AmountCents int64      `json:"amount_cents"`
-	Reason      string     `json:"reason,omitempty"`
-	Status      string     `json:"status"`
-	Method      string     `json:"refund_method"`
-	Note        string     `json:"note,omitempty"`
-	RefundedAt  *time.Time `json:"refunded_at,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID               uint64     `json:"id"`
+	OrderID          uint64     `json:"order_id"`
+	PaymentID        uint64     `json:"payment_id"`
+	AmountCents      int64      `json:"amount_cents"`
+	Reason           string     `json:"reason,omitempty"`
+	Status           string     `json:"status"`
+	Method           string     `json:"refund_method"`
+	Note             string     `json:"note,omitempty"`
+	OutRefundNo      string     `json:"out_refund_no,omitempty"`
+	ProviderRefundNo string     `json:"provider_refund_no,omitempty"`
+	Source           string     `json:"source,omitempty"`
+	RetryCount       int        `json:"retry_count,omitempty"`
+	RefundedAt       *time.Time `json:"refunded_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
 }
 
 // ListOrders 列出订单。
@@ -863,12 +955,21 @@ func (s *AdminService) GetOrder(ctx context.Context, id uint64) (*model.Order, e
 }
 
 // UpdateOrder 更新订单信息。
+//
+// 退款会产生退款单、渠道退款与账务冲销，不能在此直接改为已退款，需调用 RefundOrder。
 func (s *AdminService) UpdateOrder(ctx context.Context, id uint64, input UpdateOrderInput) (*model.Order, error) {
 	order, err := s.orders.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if input.Status == model.OrderStatusRefunded && order.Status != model.OrderStatusRefunded {
+		return nil, ErrRefundViaRefundOrder
+	}
+	return s.updateOrder(ctx, order, input)
+}
 
+// updateOrder 校验并写入订单信息，状态变化经状态机流转。
+func (s *AdminService) updateOrder(ctx context.Context, order *model.Order, input UpdateOrderInput) (*model.Order, error) {
 	if !isValidOrderStatus(input.Status) {
 		return nil, ErrValidation
 	}
@@ -883,13 +984,11 @@ func (s *AdminService) UpdateOrder(ctx context.Context, id uint64, input UpdateO
 	}
 
 	// state machine guard
-	if !isAllowedOrderTransition(order.Status, input.Status) {
+	prevStatus := order.Status
+	if prevStatus != input.Status && !orderstate.CanTransit(prevStatus, input.Status, model.OrderActorAdmin) {
 		return nil, ErrOrderInvalidTransition
 	}
 
-	prevStatus := order.Status
-
-	order.Status = input.Status
 	order.TotalPriceCents = input.TotalPriceCents
 	order.Currency = input.Currency
 	order.ScheduledStart = input.ScheduledStart
@@ -911,9 +1010,25 @@ func (s *AdminService) UpdateOrder(ctx context.Context, id uint64, input UpdateO
 		order.RefundedAt = input.RefundedAt
 	}
 
-	if err := s.orders.Update(ctx, order); err != nil {
+	var err error
+	if prevStatus == input.Status {
+		err = s.orders.Update(ctx, order)
+	} else {
+		reason := strings.TrimSpace(input.Note)
+		switch input.Status {
+		case model.OrderStatusCanceled:
+			reason = order.CancelReason
+		case model.OrderStatusRefunded:
+			reason = order.RefundReason
+		}
+		err = orderstate.Transit(ctx, s.orders, s.history, order, input.Status, orderstate.Change{Role: model.OrderActorAdmin, Reason: reason})
+	}
+	if err != nil {
 		return nil, err
 	}
+	if prevStatus != order.Status && order.Status == model.OrderStatusCompleted {
+		s.recordCommission(ctx, order.ID)
+	}
 	s.invalidateCache(ctx, cacheKeyOrders)
 	action := model.OpActionUpdateStatus
 	switch order.Status {
@@ -927,7 +1042,8 @@ func (s *AdminService) UpdateOrder(ctx context.Context, id uint64, input UpdateO
 			action = model.OpActionConfirm
 		case prevStatus == model.OrderStatusConfirmed && order.Status == model.OrderStatusInProgress:
 			action = model.OpActionStart
-		case prevStatus == model.OrderStatusInProgress && order.Status == model.OrderStatusCompleted:
+		case order.Status == model.OrderStatusCompleted &&
+			(prevStatus == model.OrderStatusInProgress || prevStatus == model.OrderStatusPendingConfirmation):
 			action = model.OpActionComplete
 		}
 	}
@@ -965,6 +1081,17 @@ func (s *AdminService) UpdateOrder(ctx context.Context, id uint64, input UpdateO
 	return order, nil
 }
 
+// recordCommission 订单完成后记录抽成；失败不影响订单完成，由抽成补记任务重试
+func (s *AdminService) recordCommission(ctx context.Context, orderID uint64) {
+	if s.recorder == nil {
+		return
+	}
+	err := s.recorder.RecordCommission(ctx, orderID)
+	if err != nil && !errors.Is(err, commissionservice.ErrAlreadyRecorded) {
+		slog.Warn("record commission failed", slog.Uint64("order_id", orderID), slog.String("error", err.Error()))
+	}
+}
+
 // ConfirmOrder 将订单从 pending 确认到 confirmed。
 func (s *AdminService) ConfirmOrder(ctx context.Context, id uint64, note string) (*model.Order, error) {
 	order, err := s.orders.Get(ctx, id)
@@ -1034,7 +1161,57 @@ func (s *AdminService) CompleteOrder(ctx context.Context, id uint64, note string
 }
 
 // RefundOrder 执行退款并记录退款信息。
+//
+// 支持对同一订单多次部分退款（已退款订单仍可退还剩余金额），金额不传时退还全部剩余金额。
 func (s *AdminService) RefundOrder(ctx context.Context, id uint64, input RefundOrderInput) (*model.Order, error) {
+	if s.refunder == nil {
+		return s.refundOrderInPlace(ctx, id, input)
+	}
+	order, err := s.orders.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return nil, ErrValidation
+	}
+	switch order.Status {
+	case model.OrderStatusCompleted, model.OrderStatusPendingConfirmation, model.OrderStatusInProgress,
+		model.OrderStatusConfirmed, model.OrderStatusRefunded:
+		// allowed
+	default:
+		return nil, ErrValidation
+	}
+	req := paymentservice.CreateRefundRequest{OrderID: id, Reason: reason, Source: model.RefundSourceAdmin}
+	if input.AmountCents != nil {
+		if *input.AmountCents <= 0 {
+			return nil, ErrValidation
+		}
+		req.AmountCents = *input.AmountCents
+	}
+	if uid, ok := logging.ActorUserIDFromContext(ctx); ok {
+		req.ActorUserID = &uid
+	}
+	refund, err := s.refunder.CreateRefund(ctx, req)
+	if errors.Is(err, paymentservice.ErrRefundExceedsPaid) || errors.Is(err, paymentservice.ErrRefundAmountInvalid) {
+		return nil, ErrValidation
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateCache(ctx, cacheKeyOrders)
+	s.invalidateCache(ctx, cacheKeyPayments)
+	if note := strings.TrimSpace(input.Note); note != "" {
+		s.appendLogAsync(ctx, string(model.OpEntityOrder), id, string(model.OpActionRefund), map[string]any{
+			"refund_id": refund.ID,
+			"note":      note,
+		})
+	}
+	return s.orders.Get(ctx, id)
+}
+
+// refundOrderInPlace 未注入退款服务时直接在订单上记录退款（不经支付渠道）。
+func (s *AdminService) refundOrderInPlace(ctx context.Context, id uint64, input RefundOrderInput) (*model.Order, error) {
 	order, err := s.orders.Get(ctx, id)
 	if err != nil {
 		return nil, err
@@ -1044,7 +1221,7 @@ func (s *AdminService) RefundOrder(ctx context.Context, id uint64, input RefundO
 		return nil, ErrValidation
 	}
 	switch order.Status {
-	case model.OrderStatusCompleted, model.OrderStatusInProgress, model.OrderStatusConfirmed:
+	case model.OrderStatusCompleted, model.OrderStatusPendingConfirmation, model.OrderStatusInProgress, model.OrderStatusConfirmed:
 		// allowed
 	default:
 		return nil, ErrValidation
@@ -1058,7 +1235,7 @@ func (s *AdminService) RefundOrder(ctx context.Context, id uint64, input RefundO
 	}
 	refundedAt := time.Now().UTC()
 	note := strings.TrimSpace(input.Note)
-	updatedOrder, err := s.UpdateOrder(ctx, id, UpdateOrderInput{
+	updatedOrder, err := s.updateOrder(ctx, order, UpdateOrderInput{
 		Status:            model.OrderStatusRefunded,
 		TotalPriceCents:   order.TotalPriceCents,
 		Currency:          order.Currency,
@@ -1106,12 +1283,25 @@ func (s *AdminService) GetOrderPayments(ctx context.Context, orderID uint64) ([]
 	return s.listPaymentsByOrder(ctx, orderID)
 }
 
-// GetOrderRefunds 汇总订单退款记录（基于支付信息与订单字段）。
+// GetOrderRefunds 返回订单退款记录。
+//
+// 注入退款服务时直接映射退款单；否则基于支付信息与订单字段汇总。
 func (s *AdminService) GetOrderRefunds(ctx context.Context, orderID uint64) ([]OrderRefundItem, error) {
 	order, err := s.orders.Get(ctx, orderID)
 	if err != nil {
 		return nil, err
 	}
+	if s.refunder != nil {
+		refunds, err := s.refunder.ListOrderRefunds(ctx, orderID)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]OrderRefundItem, 0, len(refunds))
+		for i := range refunds {
+			result = append(result, refundItemFromModel(&refunds[i]))
+		}
+		return result, nil
+	}
 	payments, err := s.listPaymentsByOrder(ctx, orderID)
 	if err != nil {
 		return nil, err
@@ -1198,6 +1388,9 @@ func (s *AdminService) GetOrderReviews(ctx context.Context, orderID uint64) ([]m
 }
 
 // GetOrderTimeline 汇总订单的状态流转与关键事件。
+//
+// 注入状态历史仓储且订单有历史记录时，状态流转以状态历史为准；
+// 否则（存量订单）根据操作日志与支付记录推断。
 func (s *AdminService) GetOrderTimeline(ctx context.Context, orderID uint64) ([]OrderTimelineItem, error) {
 	order, err := s.orders.Get(ctx, orderID)
 	if err != nil {
@@ -1207,10 +1400,45 @@ func (s *AdminService) GetOrderTimeline(ctx context.Context, orderID uint64) ([]
 	if err != nil {
 		return nil, err
 	}
+	var history []model.OrderStatusHistory
+	if s.history != nil {
+		if history, err = s.history.ListByOrder(ctx, orderID); err != nil {
+			return nil, err
+		}
+	}
+	fromHistory := len(history) > 0
 
 	userCache := make(map[uint64]*model.User)
-	items := make([]OrderTimelineItem, 0, len(logs))
+	items := make([]OrderTimelineItem, 0, len(logs)+len(history))
+	for _, h := range history {
+		item := OrderTimelineItem{
+			ID:           h.ID*10 + 3,
+			OrderID:      orderID,
+			EventType:    "status_change",
+			Title:        mapStatusTitle(h.ToStatus),
+			Description:  h.Reason,
+			OperatorRole: string(h.ActorRole),
+			StatusBefore: string(h.FromStatus),
+			StatusAfter:  string(h.ToStatus),
+			CreatedAt:    h.CreatedAt,
+		}
+		if h.TraceID != "" {
+			item.Metadata = map[string]any{"trace_id": h.TraceID}
+		}
+		if h.ActorUserID != nil {
+			if user := s.resolveUser(ctx, userCache, *h.ActorUserID); user != nil {
+				item.Operator = user.Name
+				id := user.ID
+				item.OperatorID = &id
+			}
+		}
+		items = append(items, item)
+	}
 	for _, logEntry := range logs {
+		// 状态流转已由状态历史给出，不再重复展示对应的操作日志
+		if fromHistory && mapTimelineEventType(logEntry.Action) == "status_change" {
+			continue
+		}
 		meta := map[string]any{}
 		if len(logEntry.MetadataJSON) > 0 {
 			_ = json.Unmarshal(logEntry.MetadataJSON, &meta)
@@ -1267,7 +1495,7 @@ func (s *AdminService) GetOrderTimeline(ctx context.Context, orderID uint64) ([]
 			}
 			items = append(items, item)
 		}
-		if pay.RefundedAt != nil {
+		if pay.RefundedAt != nil && !fromHistory {
 			item := OrderTimelineItem{
 				ID:          pay.ID*10 + 2,
 				OrderID:     orderID,
@@ -1481,7 +1709,7 @@ func (s *AdminService) appendLogAsync(ctx context.Context, entity string, id uin
 
 func isValidOrderStatus(status model.OrderStatus) bool {
 	switch status {
-	case model.OrderStatusPending, model.OrderStatusConfirmed, model.OrderStatusInProgress,
+	case model.OrderStatusPending, model.OrderStatusConfirmed, model.OrderStatusInProgress, model.OrderStatusPendingConfirmation,
 		model.OrderStatusCompleted, model.OrderStatusCanceled, model.OrderStatusRefunded:
 		return true
 	default:
@@ -1489,26 +1717,6 @@ func isValidOrderStatus(status model.OrderStatus) bool {
 	}
 }
 
-func isAllowedOrderTransition(prev, next model.OrderStatus) bool {
-	if prev == next {
-		return true
-	}
-	switch prev {
-	case model.OrderStatusPending:
-		return next == model.OrderStatusConfirmed || next == model.OrderStatusCanceled || next == model.OrderStatusRefunded
-	case model.OrderStatusConfirmed:
-		return next == model.OrderStatusInProgress || next == model.OrderStatusCanceled || next == model.OrderStatusRefunded
-	case model.OrderStatusInProgress:
-		return next == model.OrderStatusCompleted || next == model.OrderStatusCanceled || next == model.OrderStatusRefunded
-	case model.OrderStatusCompleted:
-		return next == model.OrderStatusRefunded
-	case model.OrderStatusCanceled, model.OrderStatusRefunded:
-		return false
-	default:
-		return false
-	}
-}
-
 func isValidPaymentStatus(status model.PaymentStatus) bool {
 	switch status {
 	case model.PaymentStatusPending, model.PaymentStatusPaid, model.PaymentStatusFailed, model.PaymentStatusRefunded:
@@ -1601,6 +1809,38 @@ func mapUserError(err error) error {
 	return err
 }
 
+func refundItemFromModel(r *model.Refund) OrderRefundItem {
+	item := OrderRefundItem{
+		ID:               r.ID,
+		OrderID:          r.OrderID,
+		AmountCents:      r.AmountCents,
+		Reason:           r.Reason,
+		Status:           mapRefundEntityStatus(r.Status),
+		Method:           string(r.Method),
+		Note:             r.LastError,
+		OutRefundNo:      r.OutRefundNo,
+		ProviderRefundNo: r.ProviderRefundNo,
+		Source:           string(r.Source),
+		RetryCount:       r.RetryCount,
+		RefundedAt:       r.RefundedAt,
+		CreatedAt:        r.CreatedAt,
+	}
+	if r.PaymentID != nil {
+		item.PaymentID = *r.PaymentID
+	}
+	if item.Method == "" {
+		item.Method = "offline"
+	}
+	return item
+}
+
+func mapRefundEntityStatus(status model.RefundStatus) string {
+	if status == model.RefundStatusSucceeded {
+		return "success"
+	}
+	return string(status)
+}
+
 func mapRefundStatus(status model.PaymentStatus) string {
 	switch status {
 	case model.PaymentStatusRefunded:
@@ -1646,11 +1886,40 @@ func mapTimelineTitle(action string) string {
 		return "订单退款"
 	case string(model.OpActionUpdateStatus):
 		return "状态更新"
+	case string(model.OpActionRequestExtension):
+		return "申请加时"
+	case string(model.OpActionAcceptExtension):
+		return "同意加时"
+	case string(model.OpActionDeclineExtension):
+		return "拒绝加时"
+	case string(model.OpActionCancelExtension):
+		return "撤回加时"
+	case string(model.OpActionExtendOrder):
+		return "加时生效"
 	default:
 		return strings.ReplaceAll(action, "_", " ")
 	}
 }
 
+func mapStatusTitle(status model.OrderStatus) string {
+	switch status {
+	case model.OrderStatusConfirmed:
+		return "订单确认"
+	case model.OrderStatusInProgress:
+		return "开始服务"
+	case model.OrderStatusPendingConfirmation:
+		return "提交完成"
+	case model.OrderStatusCompleted:
+		return "完成订单"
+	case model.OrderStatusCanceled:
+		return "订单取消"
+	case model.OrderStatusRefunded:
+		return "订单退款"
+	default:
+		return "状态更新"
+	}
+}
+
 func ptrUint64(id uint64) *uint64 {
 	return &id
 }
@@ -23,6 +23,8 @@ type timelinePayments struct{ items []model.Payment }
 func (p timelinePayments) Create(context.Context, *model.Payment) error { return nil }
 func (p timelinePayments) List(context.Context, repository.PaymentListOptions) ([]model.Payment, int64, error) { return p.items, int64(len(p.items)), nil }
 func (p timelinePayments) Get(context.Context, uint64) (*model.Payment, error) { return nil, repository.ErrNotFound }
+
+func (p timelinePayments) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (p timelinePayments) Update(context.Context, *model.Payment) error { return nil }
 func (p timelinePayments) Delete(context.Context, uint64) error { return nil }
 
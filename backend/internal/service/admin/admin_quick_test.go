@@ -24,6 +24,10 @@ func newFp() *fpRepo { return &fpRepo{m: map[uint64]*model.Payment{}} }
 func (r *fpRepo) Create(ctx context.Context, p *model.Payment) error { if p.ID==0 { p.ID=uint64(len(r.m)+1) } ; r.m[p.ID]=p; return nil }
 func (r *fpRepo) List(ctx context.Context, opts repository.PaymentListOptions) ([]model.Payment, int64, error) { out:=[]model.Payment{}; for _, v:= range r.m { if opts.OrderID!=nil && v.OrderID==*opts.OrderID { out = append(out, *v) } } ; return out, int64(len(out)), nil }
 func (r *fpRepo) Get(ctx context.Context, id uint64) (*model.Payment, error) { v:=r.m[id]; if v==nil { return nil, repository.ErrNotFound } ; return v, nil }
+
+func (r *fpRepo) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (r *fpRepo) Update(ctx context.Context, p *model.Payment) error { r.m[p.ID]=p; return nil }
 func (r *fpRepo) Delete(ctx context.Context, id uint64) error { delete(r.m, id); return nil }
 
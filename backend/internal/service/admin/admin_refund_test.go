@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gamelink/internal/cache"
+	"gamelink/internal/model"
+	paymentservice "gamelink/internal/service/payment"
+)
+
+// stubRefunder 记录退款请求并按已退金额模拟超额校验。
+type stubRefunder struct {
+	paidCents int64
+	refunds   []model.Refund
+	requests  []paymentservice.CreateRefundRequest
+}
+
+func (s *stubRefunder) CreateRefund(_ context.Context, req paymentservice.CreateRefundRequest) (*model.Refund, error) {
+	s.requests = append(s.requests, req)
+	var held int64
+	for _, r := range s.refunds {
+		held += r.AmountCents
+	}
+	amount := req.AmountCents
+	if amount == 0 {
+		amount = s.paidCents - held
+	}
+	if amount <= 0 || held+amount > s.paidCents {
+		return nil, fmt.Errorf("%w: remaining %d", paymentservice.ErrRefundExceedsPaid, s.paidCents-held)
+	}
+	r := model.Refund{
+		Base:        model.Base{ID: uint64(len(s.refunds) + 1), CreatedAt: time.Now()},
+		OrderID:     req.OrderID,
+		AmountCents: amount,
+		Reason:      req.Reason,
+		Source:      req.Source,
+		Status:      model.RefundStatusProcessing,
+		OutRefundNo: fmt.Sprintf("RF%d", len(s.refunds)+1),
+	}
+	s.refunds = append(s.refunds, r)
+	return &r, nil
+}
+
+func (s *stubRefunder) ListOrderRefunds(_ context.Context, orderID uint64) ([]model.Refund, error) {
+	return s.refunds, nil
+}
+
+func TestRefundOrder_WithRefunder_PartialRefunds(t *testing.T) {
+	order := &model.Order{Base: model.Base{ID: 1}, Status: model.OrderStatusCompleted, TotalPriceCents: 10000}
+	refunder := &stubRefunder{paidCents: 10000}
+	s := NewAdminService(&fakeGameRepo{}, &fakeUserRepo{}, &fakePlayerRepo{}, &fakeOrderRepo{obj: order}, &fakePaymentRepo{}, &fakeRoleRepo{}, cache.NewMemory())
+	s.SetRefunder(refunder)
+
+	amount := int64(3000)
+	if _, err := s.RefundOrder(context.Background(), 1, RefundOrderInput{Reason: "partial", AmountCents: &amount}); err != nil {
+		t.Fatalf("first refund: %v", err)
+	}
+	// 金额为空时退还剩余部分
+	if _, err := s.RefundOrder(context.Background(), 1, RefundOrderInput{Reason: "rest"}); err != nil {
+		t.Fatalf("second refund: %v", err)
+	}
+	if len(refunder.requests) != 2 {
+		t.Fatalf("expected 2 refund requests, got %d", len(refunder.requests))
+	}
+	if refunder.requests[0].AmountCents != 3000 || refunder.requests[1].AmountCents != 0 {
+		t.Errorf("unexpected amounts: %+v", refunder.requests)
+	}
+	if refunder.requests[0].Source != model.RefundSourceAdmin {
+		t.Errorf("expected admin source, got %s", refunder.requests[0].Source)
+	}
+
+	// 已全部退还后再退款视为校验失败
+	_, err := s.RefundOrder(context.Background(), 1, RefundOrderInput{Reason: "again", AmountCents: &amount})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("expected ErrValidation, got %v", err)
+	}
+}
+
+func TestRefundOrder_WithRefunder_RejectsInvalidInput(t *testing.T) {
+	order := &model.Order{Base: model.Base{ID: 1}, Status: model.OrderStatusPending, TotalPriceCents: 10000}
+	refunder := &stubRefunder{paidCents: 10000}
+	s := NewAdminService(&fakeGameRepo{}, &fakeUserRepo{}, &fakePlayerRepo{}, &fakeOrderRepo{obj: order}, &fakePaymentRepo{}, &fakeRoleRepo{}, cache.NewMemory())
+	s.SetRefunder(refunder)
+
+	if _, err := s.RefundOrder(context.Background(), 1, RefundOrderInput{Reason: "pending"}); !errors.Is(err, ErrValidation) {
+		t.Errorf("pending order: expected ErrValidation, got %v", err)
+	}
+	order.Status = model.OrderStatusCompleted
+	negative := int64(-1)
+	if _, err := s.RefundOrder(context.Background(), 1, RefundOrderInput{Reason: "neg", AmountCents: &negative}); !errors.Is(err, ErrValidation) {
+		t.Errorf("negative amount: expected ErrValidation, got %v", err)
+	}
+	if _, err := s.RefundOrder(context.Background(), 1, RefundOrderInput{Reason: "  "}); !errors.Is(err, ErrValidation) {
+		t.Errorf("empty reason: expected ErrValidation, got %v", err)
+	}
+	if len(refunder.requests) != 0 {
+		t.Errorf("expected no refund requests, got %d", len(refunder.requests))
+	}
+}
+
+func TestGetOrderRefunds_WithRefunder(t *testing.T) {
+	paymentID := uint64(7)
+	refundedAt := time.Now()
+	refunder := &stubRefunder{refunds: []model.Refund{
+		{Base: model.Base{ID: 1}, OrderID: 1, PaymentID: &paymentID, Method: model.PaymentMethodWeChat, AmountCents: 3000, Status: model.RefundStatusSucceeded, OutRefundNo: "RF1", ProviderRefundNo: "wx_1", RefundedAt: &refundedAt},
+		{Base: model.Base{ID: 2}, OrderID: 1, AmountCents: 2000, Status: model.RefundStatusFailed, LastError: "rejected"},
+	}}
+	s := NewAdminService(&fakeGameRepo{}, &fakeUserRepo{}, &fakePlayerRepo{}, &fakeOrderRepo{obj: &model.Order{Base: model.Base{ID: 1}}}, &fakePaymentRepo{}, &fakeRoleRepo{}, cache.NewMemory())
+	s.SetRefunder(refunder)
+
+	items, err := s.GetOrderRefunds(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetOrderRefunds error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if items[0].Status != "success" || items[0].PaymentID != 7 || items[0].Method != "wechat" || items[0].ProviderRefundNo != "wx_1" {
+		t.Errorf("unexpected first item: %+v", items[0])
+	}
+	if items[1].Status != "failed" || items[1].Method != "offline" || items[1].Note != "rejected" {
+		t.Errorf("unexpected second item: %+v", items[1])
+	}
+}
@@ -81,6 +81,8 @@ type paymentsRepoPending struct{ p model.Payment }
 func (p paymentsRepoPending) Create(context.Context, *model.Payment) error { return nil }
 func (p paymentsRepoPending) List(context.Context, repository.PaymentListOptions) ([]model.Payment, int64, error) { return nil, 0, nil }
 func (p paymentsRepoPending) Get(context.Context, uint64) (*model.Payment, error) { return &p.p, nil }
+
+func (p paymentsRepoPending) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (p paymentsRepoPending) Update(context.Context, *model.Payment) error { return nil }
 func (p paymentsRepoPending) Delete(context.Context, uint64) error { return nil }
 
@@ -137,6 +139,8 @@ func (p paymentsRepo) List(ctx context.Context, opts repository.PaymentListOptio
     return []model.Payment{}, 201, nil
 }
 func (p paymentsRepo) Get(context.Context, uint64) (*model.Payment, error) { return nil, repository.ErrNotFound }
+
+func (p paymentsRepo) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (p paymentsRepo) Update(context.Context, *model.Payment) error { return nil }
 func (p paymentsRepo) Delete(context.Context, uint64) error { return nil }
 
@@ -193,6 +197,8 @@ type paymentsRepoGet struct{ p model.Payment }
 func (p paymentsRepoGet) Create(context.Context, *model.Payment) error { return nil }
 func (p paymentsRepoGet) List(context.Context, repository.PaymentListOptions) ([]model.Payment, int64, error) { return nil, 0, nil }
 func (p paymentsRepoGet) Get(context.Context, uint64) (*model.Payment, error) { return &p.p, nil }
+
+func (p paymentsRepoGet) GetByOutTradeNo(context.Context, string) (*model.Payment, error) { return nil, repository.ErrNotFound }
 func (p paymentsRepoGet) Update(context.Context, *model.Payment) error { return nil }
 func (p paymentsRepoGet) Delete(context.Context, uint64) error { return nil }
 
@@ -3,6 +3,7 @@ package admin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
@@ -167,6 +168,10 @@ func (f *fakePaymentRepo) Get(ctx context.Context, id uint64) (*model.Payment, e
 	}
 	return f.obj, nil
 }
+
+func (f *fakePaymentRepo) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
 func (f *fakePaymentRepo) Update(ctx context.Context, p *model.Payment) error { f.obj = p; return nil }
 func (f *fakePaymentRepo) Delete(ctx context.Context, id uint64) error        { return nil }
 
@@ -1332,7 +1337,7 @@ func TestService_UpdateOrder_EdgeCases(t *testing.T) {
 		t.Errorf("zero price should be allowed, got error: %v", err)
 	}
 
-	// Test: RefundAmountCents and RefundReason
+	// Test: refunding must go through RefundOrder
 	refundAmount := int64(5000)
 	refundedAt := now
 	_, err = s.UpdateOrder(context.Background(), 1, UpdateOrderInput{
@@ -1343,8 +1348,57 @@ func TestService_UpdateOrder_EdgeCases(t *testing.T) {
 		RefundReason:   "Test refund",
 		RefundedAt:     &refundedAt,
 	})
+	if !errors.Is(err, ErrRefundViaRefundOrder) || !errors.Is(err, ErrValidation) {
+		t.Errorf("expected ErrRefundViaRefundOrder, got %v", err)
+	}
+	if order.Status == model.OrderStatusRefunded {
+		t.Error("order must not be marked refunded by UpdateOrder")
+	}
+}
+
+type fakeCommissionRecorder struct {
+	orderIDs []uint64
+	err      error
+}
+
+func (f *fakeCommissionRecorder) RecordCommission(_ context.Context, orderID uint64) error {
+	f.orderIDs = append(f.orderIDs, orderID)
+	return f.err
+}
+
+func TestService_CompleteOrder_RecordsCommission(t *testing.T) {
+	order := &model.Order{
+		Base:            model.Base{ID: 1},
+		Status:          model.OrderStatusPendingConfirmation,
+		TotalPriceCents: 10000,
+		Currency:        model.CurrencyCNY,
+	}
+	s := NewAdminService(&fakeGameRepo{}, &fakeUserRepo{}, &fakePlayerRepo{}, &fakeOrderRepo{obj: order}, &fakePaymentRepo{}, &fakeRoleRepo{}, cache.NewMemory())
+	recorder := &fakeCommissionRecorder{err: errors.New("ledger unavailable")}
+	s.SetCommissionRecorder(recorder)
+
+	// 记录失败不影响订单完成
+	result, err := s.CompleteOrder(context.Background(), 1, "done")
 	if err != nil {
-		t.Errorf("update with refund info should succeed, got error: %v", err)
+		t.Fatalf("CompleteOrder error: %v", err)
+	}
+	if result.Status != model.OrderStatusCompleted {
+		t.Fatalf("expected completed, got %s", result.Status)
+	}
+	if len(recorder.orderIDs) != 1 || recorder.orderIDs[0] != 1 {
+		t.Fatalf("expected commission recorded for order 1, got %v", recorder.orderIDs)
+	}
+
+	// 状态未变化的更新不重复记录
+	if _, err := s.UpdateOrder(context.Background(), 1, UpdateOrderInput{
+		Status:          model.OrderStatusCompleted,
+		TotalPriceCents: 10000,
+		Currency:        model.CurrencyCNY,
+	}); err != nil {
+		t.Fatalf("UpdateOrder error: %v", err)
+	}
+	if len(recorder.orderIDs) != 1 {
+		t.Fatalf("expected no further commission recording, got %v", recorder.orderIDs)
 	}
 }
 
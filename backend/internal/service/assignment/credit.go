@@ -0,0 +1,38 @@
+package assignment
+
+import (
+	"context"
+	"log/slog"
+
+	"gamelink/internal/model"
+)
+
+// CreditRecorder books lost disputes against the player's credit (implemented by the credit service).
+type CreditRecorder interface {
+	RecordDisputeLost(ctx context.Context, dispute *model.OrderDispute, playerID uint64) error
+	RevokeDisputeLost(ctx context.Context, disputeID uint64, reason string, actorUserID *uint64) error
+}
+
+// SetCredit injects the credit service; refund resolutions then count as lost disputes for the player.
+func (s *AssignmentService) SetCredit(c CreditRecorder) { s.credit = c }
+
+// recordDisputeLost books a refund resolution against the player. Failures are logged, not returned,
+// since the refund has already been issued.
+func (s *AssignmentService) recordDisputeLost(ctx context.Context, dispute *model.OrderDispute, playerID uint64) {
+	if s.credit == nil || playerID == 0 {
+		return
+	}
+	if err := s.credit.RecordDisputeLost(ctx, dispute, playerID); err != nil {
+		slog.Warn("record dispute credit failed", "dispute_id", dispute.ID, "error", err)
+	}
+}
+
+// revokeDisputeLost restores the credit deducted for a dispute whose resolution was rolled back.
+func (s *AssignmentService) revokeDisputeLost(ctx context.Context, disputeID uint64, reason string, actorUserID *uint64) {
+	if s.credit == nil {
+		return
+	}
+	if err := s.credit.RevokeDisputeLost(ctx, disputeID, reason, actorUserID); err != nil {
+		slog.Warn("revoke dispute credit failed", "dispute_id", disputeID, "error", err)
+	}
+}
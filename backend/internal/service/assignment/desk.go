@@ -0,0 +1,288 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gamelink/internal/model"
+	disputerepo "gamelink/internal/repository/dispute"
+)
+
+// ErrNoRoster the agent roster is not configured
+var ErrNoRoster = errors.New("dispute agent roster not configured")
+
+// SetAgents injects the CS agent roster used for auto-assignment and SLA escalation.
+func (s *AssignmentService) SetAgents(a disputerepo.AgentRepository) { s.agents = a }
+
+// SetSLA sets the handling deadline of new disputes and the deadline restarted after each escalation.
+// Non-positive values keep the current setting.
+func (s *AssignmentService) SetSLA(initial, escalation time.Duration) {
+	if initial > 0 {
+		s.sla = initial
+	}
+	if escalation > 0 {
+		s.escalationSLA = escalation
+	}
+}
+
+// SLAResult summarizes one scan of the dispute desk.
+type SLAResult struct {
+	Assigned  int // pending disputes handed to an on-duty agent
+	Breached  int // disputes newly marked as SLA breached
+	Escalated int // breached disputes reassigned to a higher tier
+}
+
+// ProcessSLA assigns waiting disputes to on-duty agents, then marks SLA breaches and escalates them.
+func (s *AssignmentService) ProcessSLA(ctx context.Context, limit int) (SLAResult, error) {
+	var res SLAResult
+	assigned, err := s.AssignPendingDisputes(ctx, limit)
+	if err != nil {
+		return res, err
+	}
+	res.Assigned = assigned
+	res.Breached, res.Escalated, err = s.checkSLABreaches(ctx)
+	return res, err
+}
+
+// AssignPendingDisputes auto-assigns up to limit unassigned disputes, oldest first.
+// Rolled-back disputes are left for manual assignment.
+func (s *AssignmentService) AssignPendingDisputes(ctx context.Context, limit int) (int, error) {
+	if s.agents == nil {
+		return 0, nil
+	}
+	pending, _, err := s.disputes.ListPendingAssignment(ctx, 1, limit)
+	if err != nil {
+		return 0, err
+	}
+	assigned := 0
+	for i := range pending {
+		dispute := &pending[i]
+		if dispute.RolledBackAt != nil {
+			continue
+		}
+		var gameID uint64
+		if order, err := s.orders.Get(ctx, dispute.OrderID); err == nil {
+			gameID = order.GetGameID()
+		}
+		ok, err := s.autoAssign(ctx, dispute, gameID)
+		if err != nil {
+			return assigned, err
+		}
+		if ok {
+			assigned++
+		}
+	}
+	return assigned, nil
+}
+
+// autoAssign hands a new dispute to the least-loaded on-duty first-tier agent.
+// Supervisors only receive disputes through escalation.
+func (s *AssignmentService) autoAssign(ctx context.Context, dispute *model.OrderDispute, gameID uint64) (bool, error) {
+	if s.agents == nil {
+		return false, nil
+	}
+	tier := model.DisputeAgentTier(dispute.EscalationLevel + 1)
+	agent, err := s.pickAgent(ctx, gameID, tier, tier)
+	if err != nil || agent == nil {
+		return false, err
+	}
+	if err := s.assignToAgent(ctx, dispute, agent, time.Now()); err != nil {
+		return false, err
+	}
+	s.logOperation(ctx, model.OpEntityDispute, dispute.ID, model.OpActionAssignDispute,
+		fmt.Sprintf("Auto-assigned to user %d via %s", agent.UserID, model.AssignmentSourceSystem), dispute.TraceID, nil)
+	s.sendNotification(ctx, agent.UserID, "New Dispute Assignment",
+		fmt.Sprintf("You have been assigned dispute #%d", dispute.ID), dispute.TraceID)
+	return true, nil
+}
+
+// escalate reassigns a breached dispute to the least-loaded on-duty agent of the next available
+// tier and restarts its SLA. Returns false when no higher-tier agent is on duty.
+func (s *AssignmentService) escalate(ctx context.Context, dispute *model.OrderDispute) (bool, error) {
+	if s.agents == nil {
+		return false, nil
+	}
+	var gameID uint64
+	if order, err := s.orders.Get(ctx, dispute.OrderID); err == nil {
+		gameID = order.GetGameID()
+	}
+	agent, err := s.pickAgent(ctx, gameID, model.DisputeAgentTier(dispute.EscalationLevel+2), model.DisputeTierManager)
+	if err != nil || agent == nil {
+		return false, err
+	}
+
+	previous := dispute.AssignedToUserID
+	now := time.Now()
+	deadline := now.Add(s.escalationSLA)
+	dispute.SLABreached = false // the new tier gets a fresh deadline; SLABreachedAt keeps the last breach
+	dispute.SLABreachedAt = &now
+	dispute.SLADeadline = &deadline
+	dispute.EscalationLevel = int(agent.Tier) - 1
+	dispute.EscalatedAt = &now
+	if err := s.assignToAgent(ctx, dispute, agent, now); err != nil {
+		return false, err
+	}
+
+	s.logOperation(ctx, model.OpEntityDispute, dispute.ID, model.OpActionEscalateDispute,
+		fmt.Sprintf("SLA breached, escalated to tier %d agent %d", agent.Tier, agent.UserID), dispute.TraceID, nil)
+	s.sendNotification(ctx, agent.UserID, "Dispute Escalated",
+		fmt.Sprintf("Dispute #%d has exceeded SLA and was escalated to you", dispute.ID), dispute.TraceID)
+	if previous != nil && *previous != agent.UserID {
+		s.sendNotification(ctx, *previous, "Dispute Escalated",
+			fmt.Sprintf("Dispute #%d has exceeded SLA and was escalated to tier %d", dispute.ID, agent.Tier), dispute.TraceID)
+	}
+	return true, nil
+}
+
+// pickAgent selects an on-duty agent from the lowest tier in [minTier, maxTier] that has one available.
+// Within a tier, agents skilled in the order's game are preferred over generalists; agents skilled only
+// in other games or at capacity are skipped. Ties on open disputes go to the agent idle the longest.
+func (s *AssignmentService) pickAgent(ctx context.Context, gameID uint64, minTier, maxTier model.DisputeAgentTier) (*model.DisputeAgent, error) {
+	agents, err := s.agents.ListOnDuty(ctx)
+	if err != nil || len(agents) == 0 {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(agents))
+	for _, a := range agents {
+		ids = append(ids, a.UserID)
+	}
+	loads, err := s.agents.CountOpen(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for tier := minTier; tier <= maxTier; tier++ {
+		var specialist, generalist *model.DisputeAgent
+		for i := range agents {
+			a := &agents[i]
+			if a.Tier != tier || (a.MaxOpen > 0 && loads[a.UserID] >= int64(a.MaxOpen)) {
+				continue
+			}
+			switch {
+			case len(a.GameIDs) == 0:
+				if generalist == nil || lessBusy(a, generalist, loads) {
+					generalist = a
+				}
+			case gameID != 0 && a.Handles(gameID):
+				if specialist == nil || lessBusy(a, specialist, loads) {
+					specialist = a
+				}
+			}
+		}
+		if specialist != nil {
+			return specialist, nil
+		}
+		if generalist != nil {
+			return generalist, nil
+		}
+	}
+	return nil, nil
+}
+
+// lessBusy reports whether a should be picked before b: fewer open disputes, then longest since last assignment.
+func lessBusy(a, b *model.DisputeAgent, loads map[uint64]int64) bool {
+	if loads[a.UserID] != loads[b.UserID] {
+		return loads[a.UserID] < loads[b.UserID]
+	}
+	switch {
+	case a.LastAssignedAt == nil || b.LastAssignedAt == nil:
+		return a.LastAssignedAt == nil && b.LastAssignedAt != nil
+	case !a.LastAssignedAt.Equal(*b.LastAssignedAt):
+		return a.LastAssignedAt.Before(*b.LastAssignedAt)
+	}
+	return a.UserID < b.UserID
+}
+
+func (s *AssignmentService) assignToAgent(ctx context.Context, dispute *model.OrderDispute, agent *model.DisputeAgent, now time.Time) error {
+	userID := agent.UserID
+	dispute.AssignedToUserID = &userID
+	dispute.AssignmentSource = model.AssignmentSourceSystem
+	dispute.AssignedAt = &now
+	if dispute.Status == model.DisputeStatusPending {
+		dispute.Status = model.DisputeStatusAssigned
+	}
+	if err := s.disputes.Update(ctx, dispute); err != nil {
+		return err
+	}
+	return s.agents.TouchAssigned(ctx, userID, now)
+}
+
+// AgentView is a roster entry with the agent's current open dispute count.
+type AgentView struct {
+	model.DisputeAgent
+	OpenDisputes int64 `json:"openDisputes"`
+}
+
+// UpsertAgentRequest adds a user to the roster or updates their tier, skills and capacity.
+type UpsertAgentRequest struct {
+	Tier    model.DisputeAgentTier `json:"tier" binding:"required,min=1,max=3"`
+	OnDuty  bool                   `json:"onDuty"`
+	GameIDs []uint64               `json:"gameIds"`
+	MaxOpen int                    `json:"maxOpen" binding:"min=0"`
+}
+
+// ListAgents lists the roster with each agent's open dispute count.
+func (s *AssignmentService) ListAgents(ctx context.Context) ([]AgentView, error) {
+	if s.agents == nil {
+		return nil, ErrNoRoster
+	}
+	agents, err := s.agents.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(agents))
+	for _, a := range agents {
+		ids = append(ids, a.UserID)
+	}
+	loads, err := s.agents.CountOpen(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]AgentView, 0, len(agents))
+	for _, a := range agents {
+		views = append(views, AgentView{DisputeAgent: a, OpenDisputes: loads[a.UserID]})
+	}
+	return views, nil
+}
+
+// UpsertAgent adds the user to the roster or updates their settings.
+func (s *AssignmentService) UpsertAgent(ctx context.Context, userID uint64, req UpsertAgentRequest) (*model.DisputeAgent, error) {
+	if s.agents == nil {
+		return nil, ErrNoRoster
+	}
+	if userID == 0 || req.Tier < model.DisputeTierAgent || req.Tier > model.DisputeTierManager || req.MaxOpen < 0 {
+		return nil, ErrValidation
+	}
+	if _, err := s.users.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+	agent := &model.DisputeAgent{
+		UserID:  userID,
+		Tier:    req.Tier,
+		OnDuty:  req.OnDuty,
+		GameIDs: model.IDList(req.GameIDs),
+		MaxOpen: req.MaxOpen,
+	}
+	if err := s.agents.Upsert(ctx, agent); err != nil {
+		return nil, err
+	}
+	return agent, nil
+}
+
+// SetAgentOnDuty starts or ends an agent's shift. Disputes already assigned stay with the agent.
+func (s *AssignmentService) SetAgentOnDuty(ctx context.Context, userID uint64, onDuty bool) error {
+	if s.agents == nil {
+		return ErrNoRoster
+	}
+	return s.agents.SetOnDuty(ctx, userID, onDuty)
+}
+
+// RemoveAgent removes the user from the roster.
+func (s *AssignmentService) RemoveAgent(ctx context.Context, userID uint64) error {
+	if s.agents == nil {
+		return ErrNoRoster
+	}
+	return s.agents.Delete(ctx, userID)
+}
@@ -0,0 +1,158 @@
+package assignment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	disputerepo "gamelink/internal/repository/dispute"
+	notificationrepo "gamelink/internal/repository/notification"
+	operationlogrepo "gamelink/internal/repository/operation_log"
+)
+
+func TestDisputeDesk_AutoAssignAndEscalate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.OrderDispute{}, &model.DisputeAgent{}, &model.OperationLog{}, &model.NotificationEvent{}))
+
+	ctx := context.Background()
+	orders := newMockOrderRepository()
+	users := newMockUserRepository()
+	svc := NewAssignmentService(disputerepo.NewDisputeRepository(db), orders, users,
+		operationlogrepo.NewOperationLogRepository(db), notificationrepo.NewNotificationRepository(db), &mockPaymentRepository{})
+	svc.SetAgents(disputerepo.NewAgentRepository(db))
+	svc.SetSLA(10*time.Minute, 20*time.Minute)
+
+	const (
+		generalist = uint64(100)
+		specialist = uint64(101)
+		otherGame  = uint64(102)
+		offDuty    = uint64(103)
+		supervisor = uint64(200)
+	)
+	for _, id := range []uint64{generalist, specialist, otherGame, offDuty, supervisor} {
+		users.users[id] = &model.User{Base: model.Base{ID: id}}
+	}
+	upsert := func(userID uint64, req UpsertAgentRequest) {
+		_, err := svc.UpsertAgent(ctx, userID, req)
+		require.NoError(t, err)
+	}
+	upsert(generalist, UpsertAgentRequest{Tier: model.DisputeTierAgent, OnDuty: true})
+	upsert(specialist, UpsertAgentRequest{Tier: model.DisputeTierAgent, OnDuty: true, GameIDs: []uint64{7}, MaxOpen: 1})
+	upsert(otherGame, UpsertAgentRequest{Tier: model.DisputeTierAgent, OnDuty: true, GameIDs: []uint64{9}})
+	upsert(offDuty, UpsertAgentRequest{Tier: model.DisputeTierAgent})
+	upsert(supervisor, UpsertAgentRequest{Tier: model.DisputeTierSupervisor})
+	_, err = svc.UpsertAgent(ctx, 999, UpsertAgentRequest{Tier: model.DisputeTierAgent})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	initiate := func(orderID, gameID uint64) *model.OrderDispute {
+		game := gameID
+		require.NoError(t, orders.Create(ctx, &model.Order{Base: model.Base{ID: orderID}, UserID: 1, GameID: &game, Status: model.OrderStatusInProgress}))
+		resp, err := svc.InitiateDispute(ctx, InitiateDisputeRequest{OrderID: orderID, UserID: 1, Reason: "not served"})
+		require.NoError(t, err)
+		d, err := svc.GetDisputeDetail(ctx, resp.DisputeID)
+		require.NoError(t, err)
+		return d
+	}
+
+	// 优先指派给擅长该游戏的客服
+	first := initiate(1, 7)
+	require.NotNil(t, first.AssignedToUserID)
+	assert.Equal(t, specialist, *first.AssignedToUserID)
+	assert.Equal(t, model.DisputeStatusAssigned, first.Status)
+	assert.Equal(t, model.AssignmentSourceSystem, first.AssignmentSource)
+	require.NotNil(t, first.SLADeadline)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), *first.SLADeadline, 5*time.Second)
+
+	// 专长客服已满负载时交给通用客服；只擅长其他游戏的客服不参与
+	second := initiate(2, 7)
+	require.NotNil(t, second.AssignedToUserID)
+	assert.Equal(t, generalist, *second.AssignedToUserID)
+	third := initiate(3, 8)
+	require.NotNil(t, third.AssignedToUserID)
+	assert.Equal(t, generalist, *third.AssignedToUserID)
+
+	agents, err := svc.ListAgents(ctx)
+	require.NoError(t, err)
+	loads := map[uint64]int64{}
+	for _, a := range agents {
+		loads[a.UserID] = a.OpenDisputes
+	}
+	assert.Equal(t, map[uint64]int64{generalist: 2, specialist: 1, otherGame: 0, offDuty: 0, supervisor: 0}, loads)
+
+	// 超过 SLA 且没有值班主管时仅标记超时
+	require.NoError(t, db.Model(&model.OrderDispute{}).Where("id = ?", first.ID).Update("sla_deadline", time.Now().Add(-time.Minute)).Error)
+	res, err := svc.ProcessSLA(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, SLAResult{Breached: 1}, res)
+	got, err := svc.GetDisputeDetail(ctx, first.ID)
+	require.NoError(t, err)
+	assert.True(t, got.SLABreached)
+	assert.Equal(t, specialist, *got.AssignedToUserID)
+
+	// 主管上班后，新超时的争议升级给主管并重新计时
+	require.NoError(t, svc.SetAgentOnDuty(ctx, supervisor, true))
+	require.NoError(t, db.Model(&model.OrderDispute{}).Where("id = ?", second.ID).Update("sla_deadline", time.Now().Add(-time.Minute)).Error)
+	res, err = svc.ProcessSLA(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, SLAResult{Breached: 1, Escalated: 1}, res)
+	got, err = svc.GetDisputeDetail(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, supervisor, *got.AssignedToUserID)
+	assert.Equal(t, 1, got.EscalationLevel)
+	assert.False(t, got.SLABreached)
+	require.NotNil(t, got.SLABreachedAt)
+	require.NotNil(t, got.EscalatedAt)
+	assert.WithinDuration(t, time.Now().Add(20*time.Minute), *got.SLADeadline, 5*time.Second)
+
+	var escalations int64
+	require.NoError(t, db.Model(&model.OperationLog{}).Where("entity_id = ? AND action = ?", second.ID, model.OpActionEscalateDispute).Count(&escalations).Error)
+	assert.EqualValues(t, 1, escalations)
+	var notified int64
+	require.NoError(t, db.Model(&model.NotificationEvent{}).Where("user_id IN ?", []uint64{supervisor, generalist}).
+		Where("title = ?", "Dispute Escalated").Count(&notified).Error)
+	assert.EqualValues(t, 2, notified)
+
+	// 最高层级再次超时不再升级
+	require.NoError(t, db.Model(&model.OrderDispute{}).Where("id = ?", second.ID).Update("sla_deadline", time.Now().Add(-time.Minute)).Error)
+	res, err = svc.ProcessSLA(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, SLAResult{Breached: 1}, res)
+
+	// 无人值班时争议保持待指派，客服上班后由调度补指派
+	for _, id := range []uint64{generalist, specialist, otherGame} {
+		require.NoError(t, svc.SetAgentOnDuty(ctx, id, false))
+	}
+	waiting := initiate(4, 7)
+	assert.Nil(t, waiting.AssignedToUserID)
+	assert.Equal(t, model.DisputeStatusPending, waiting.Status)
+	require.NoError(t, svc.SetAgentOnDuty(ctx, offDuty, true))
+	res, err = svc.ProcessSLA(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Assigned)
+	got, err = svc.GetDisputeDetail(ctx, waiting.ID)
+	require.NoError(t, err)
+	assert.Equal(t, offDuty, *got.AssignedToUserID)
+
+	require.NoError(t, svc.RemoveAgent(ctx, offDuty))
+	assert.ErrorIs(t, svc.SetAgentOnDuty(ctx, offDuty, true), ErrNotFound)
+}
+
+func TestLessBusy_RoundRobinOnTie(t *testing.T) {
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now()
+	a := &model.DisputeAgent{UserID: 1, LastAssignedAt: &later}
+	b := &model.DisputeAgent{UserID: 2, LastAssignedAt: &earlier}
+	c := &model.DisputeAgent{UserID: 3}
+
+	assert.True(t, lessBusy(b, a, nil))
+	assert.True(t, lessBusy(c, b, nil))
+	assert.False(t, lessBusy(a, c, nil))
+	assert.True(t, lessBusy(a, b, map[uint64]int64{1: 0, 2: 3}))
+}
@@ -0,0 +1,68 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gamelink/internal/model"
+	uploadservice "gamelink/internal/service/upload"
+)
+
+// maxEvidenceFiles caps the evidence attached to one dispute.
+const maxEvidenceFiles = 9
+
+// EvidenceStore resolves uploaded evidence files (implemented by the upload service).
+type EvidenceStore interface {
+	// Attach checks the uploads exist and belong to ownerID, returned in input order.
+	Attach(ctx context.Context, ownerID uint64, ids []uint64) ([]model.Upload, error)
+	// URLs issues signed, expiring download URLs keyed by upload ID.
+	URLs(ctx context.Context, ids []uint64) (map[uint64]string, error)
+}
+
+// SetEvidence injects the upload store used for dispute evidence.
+func (s *AssignmentService) SetEvidence(e EvidenceStore) { s.evidence = e }
+
+// checkEvidence validates that evidence uploads are the user's own images or videos.
+func (s *AssignmentService) checkEvidence(ctx context.Context, userID uint64, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if len(ids) > maxEvidenceFiles {
+		return fmt.Errorf("%w: at most %d evidence files", ErrValidation, maxEvidenceFiles)
+	}
+	if s.evidence == nil {
+		return fmt.Errorf("%w: evidence uploads are not supported", ErrValidation)
+	}
+	uploads, err := s.evidence.Attach(ctx, userID, ids)
+	if errors.Is(err, uploadservice.ErrInvalidAttachment) {
+		return fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	if err != nil {
+		return err
+	}
+	for _, u := range uploads {
+		if !u.IsImage() && !u.IsVideo() {
+			return fmt.Errorf("%w: evidence %d must be an image or video", ErrValidation, u.ID)
+		}
+	}
+	return nil
+}
+
+// signEvidence fills the dispute's evidence download URLs in upload order.
+func (s *AssignmentService) signEvidence(ctx context.Context, dispute *model.OrderDispute) error {
+	if s.evidence == nil || len(dispute.EvidenceUploadIDs) == 0 {
+		return nil
+	}
+	urls, err := s.evidence.URLs(ctx, dispute.EvidenceUploadIDs)
+	if err != nil {
+		return err
+	}
+	dispute.EvidenceFileURLs = make([]string, 0, len(dispute.EvidenceUploadIDs))
+	for _, id := range dispute.EvidenceUploadIDs {
+		if url, ok := urls[id]; ok {
+			dispute.EvidenceFileURLs = append(dispute.EvidenceFileURLs, url)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package assignment
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	disputerepo "gamelink/internal/repository/dispute"
+	notificationrepo "gamelink/internal/repository/notification"
+	operationlogrepo "gamelink/internal/repository/operation_log"
+	uploadrepo "gamelink/internal/repository/upload"
+	uploadservice "gamelink/internal/service/upload"
+	"gamelink/internal/storage"
+)
+
+func TestInitiateDispute_EvidenceUploads(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.OrderDispute{}, &model.Upload{}, &model.OperationLog{}, &model.NotificationEvent{}))
+	store, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	uploads := uploadservice.NewService(uploadrepo.NewUploadRepository(db), store, "key", 0)
+
+	ctx := context.Background()
+	orders := newMockOrderRepository()
+	svc := NewAssignmentService(disputerepo.NewDisputeRepository(db), orders, newMockUserRepository(),
+		operationlogrepo.NewOperationLogRepository(db), notificationrepo.NewNotificationRepository(db), &mockPaymentRepository{})
+	for id := uint64(1); id <= 3; id++ {
+		require.NoError(t, orders.Create(ctx, &model.Order{Base: model.Base{ID: id}, UserID: 1, Status: model.OrderStatusInProgress}))
+	}
+
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 2, 2))))
+	shot, err := uploads.Upload(ctx, 1, uploadservice.UploadRequest{Type: model.UploadTypeDisputeEvidence, FileName: "shot.png", Body: bytes.NewReader(img.Bytes())})
+	require.NoError(t, err)
+	note, err := uploads.Upload(ctx, 1, uploadservice.UploadRequest{FileName: "note.txt", Body: strings.NewReader("plain text")})
+	require.NoError(t, err)
+	others, err := uploads.Upload(ctx, 2, uploadservice.UploadRequest{FileName: "other.png", Body: bytes.NewReader(img.Bytes())})
+	require.NoError(t, err)
+
+	initiate := func(orderID uint64, ids ...uint64) (*InitiateDisputeResponse, error) {
+		return svc.InitiateDispute(ctx, InitiateDisputeRequest{OrderID: orderID, UserID: 1, Reason: "no show", EvidenceUploadIDs: ids})
+	}
+
+	// 未注入上传服务时不接受证据
+	_, err = initiate(1, shot.ID)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	svc.SetEvidence(uploads)
+	for _, ids := range [][]uint64{{others.ID}, {note.ID}, {999}} {
+		_, err = initiate(1, ids...)
+		assert.ErrorIs(t, err, ErrValidation, ids)
+	}
+
+	resp, err := initiate(1, shot.ID)
+	require.NoError(t, err)
+	got, err := svc.GetDisputeDetail(ctx, resp.DisputeID)
+	require.NoError(t, err)
+	assert.Equal(t, model.IDList{shot.ID}, got.EvidenceUploadIDs)
+	require.Len(t, got.EvidenceFileURLs, 1)
+	assert.Contains(t, got.EvidenceFileURLs[0], "signature=")
+
+	resp, err = initiate(2)
+	require.NoError(t, err)
+	got, err = svc.GetDisputeDetail(ctx, resp.DisputeID)
+	require.NoError(t, err)
+	assert.Empty(t, got.EvidenceUploadIDs)
+	assert.Empty(t, got.EvidenceFileURLs)
+}
@@ -7,6 +7,7 @@ import (
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	paymentservice "gamelink/internal/service/payment"
 )
 
 // Mock repositories for testing
@@ -244,6 +245,10 @@ func (m *mockPaymentRepository) Get(ctx context.Context, id uint64) (*model.Paym
 	return nil, nil
 }
 
+func (m *mockPaymentRepository) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
+
 func (m *mockPaymentRepository) Update(ctx context.Context, payment *model.Payment) error {
 	return nil
 }
@@ -287,11 +292,10 @@ func TestInitiateDisputeFullFlow(t *testing.T) {
 
 	// Test: Initiate dispute
 	resp, err := svc.InitiateDispute(ctx, InitiateDisputeRequest{
-		OrderID:      1,
-		UserID:       1,
-		Reason:       "Service not provided",
-		Description:  "Player did not show up",
-		EvidenceURLs: []string{"https://example.com/screenshot.jpg"},
+		OrderID:     1,
+		UserID:      1,
+		Reason:      "Service not provided",
+		Description: "Player did not show up",
 	})
 
 	if err != nil {
@@ -532,3 +536,62 @@ func TestSLABreachDetection(t *testing.T) {
 
 	t.Log("✓ SLABreachDetection test passed")
 }
+
+type recordingRefunder struct {
+	requests []paymentservice.CreateRefundRequest
+}
+
+func (r *recordingRefunder) CreateRefund(ctx context.Context, req paymentservice.CreateRefundRequest) (*model.Refund, error) {
+	r.requests = append(r.requests, req)
+	refund := &model.Refund{OrderID: req.OrderID, AmountCents: req.AmountCents, OutRefundNo: "RF-TEST", Status: model.RefundStatusProcessing}
+	if req.InTx != nil {
+		if err := req.InTx(ctx, nil, []*model.Refund{refund}); err != nil {
+			return nil, err
+		}
+	}
+	return refund, nil
+}
+
+func (r *recordingRefunder) CancelRefund(ctx context.Context, refundID uint64, reason string) (*model.Refund, error) {
+	return nil, paymentservice.ErrRefundNotCancelable
+}
+
+func TestResolveDispute_RefundGoesThroughRefunder(t *testing.T) {
+	ctx := context.Background()
+	disputeRepo := newMockDisputeRepository()
+	orderRepo := newMockOrderRepository()
+	svc := NewAssignmentService(disputeRepo, orderRepo, newMockUserRepository(), &mockOperationLogRepository{}, &mockNotificationRepository{}, &mockPaymentRepository{})
+	refunder := &recordingRefunder{}
+	svc.SetRefunder(refunder)
+
+	orderRepo.Create(ctx, &model.Order{Base: model.Base{ID: 1}, UserID: 1, Status: model.OrderStatusCompleted, TotalPriceCents: 10000})
+	disputeRepo.Create(ctx, &model.OrderDispute{Base: model.Base{ID: 1}, OrderID: 1, UserID: 1, Status: model.DisputeStatusMediating, TraceID: "trace-refund"})
+
+	err := svc.ResolveDispute(ctx, ResolveDisputeRequest{
+		DisputeID:        1,
+		Resolution:       model.ResolutionRefund,
+		ResolutionAmount: 4000,
+		ResolutionNotes:  "Partial refund",
+		ActorUserID:      2,
+	})
+	if err != nil {
+		t.Fatalf("ResolveDispute failed: %v", err)
+	}
+
+	if len(refunder.requests) != 1 {
+		t.Fatalf("Expected 1 refund request, got %d", len(refunder.requests))
+	}
+	req := refunder.requests[0]
+	if req.OrderID != 1 || req.AmountCents != 4000 || req.Source != model.RefundSourceDispute {
+		t.Errorf("Unexpected refund request: %+v", req)
+	}
+	if req.ActorUserID == nil || *req.ActorUserID != 2 {
+		t.Error("Expected actor to be forwarded to the refund")
+	}
+
+	// Order settlement is left to the refund service
+	order, _ := orderRepo.Get(ctx, 1)
+	if order.Status != model.OrderStatusCompleted {
+		t.Errorf("Expected order status untouched, got %s", order.Status)
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	disputerepo "gamelink/internal/repository/dispute"
+	orderhistory "gamelink/internal/repository/order_history"
+	"gamelink/internal/service/orderstate"
+	paymentservice "gamelink/internal/service/payment"
 )
 
 var (
@@ -33,15 +39,38 @@ var (
 
 // AssignmentService handles dispute and assignment operations
 type AssignmentService struct {
-	disputes       repository.DisputeRepository
-	orders         repository.OrderRepository
-	users          repository.UserRepository
-	operationLogs  repository.OperationLogRepository
-	notifications  repository.NotificationRepository
-	payments       repository.PaymentRepository
-	defaultSLAMins int // default SLA in minutes (30)
+	disputes      repository.DisputeRepository
+	orders        repository.OrderRepository
+	users         repository.UserRepository
+	operationLogs repository.OperationLogRepository
+	notifications repository.NotificationRepository
+	payments      repository.PaymentRepository
+	refunder      Refunder
+	adjuster      CommissionAdjuster
+	credit        CreditRecorder
+	history       orderhistory.HistoryRepository
+	tx            TxManager
+	agents        disputerepo.AgentRepository
+	evidence      EvidenceStore
+	sla           time.Duration // handling deadline of new disputes
+	escalationSLA time.Duration // deadline restarted after each escalation
 }
 
+// Refunder creates refund records and submits them to the payment provider.
+type Refunder interface {
+	CreateRefund(ctx context.Context, req paymentservice.CreateRefundRequest) (*model.Refund, error)
+	// CancelRefund cancels a refund the provider has not accepted yet.
+	CancelRefund(ctx context.Context, refundID uint64, reason string) (*model.Refund, error)
+}
+
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
+}
+
+// defaultSLA is the default handling deadline of a dispute.
+const defaultSLA = 30 * time.Minute
+
 // NewAssignmentService creates a new assignment service
 func NewAssignmentService(
 	disputes repository.DisputeRepository,
@@ -52,30 +81,42 @@ func NewAssignmentService(
 	payments repository.PaymentRepository,
 ) *AssignmentService {
 	return &AssignmentService{
-		disputes:       disputes,
-		orders:         orders,
-		users:          users,
-		operationLogs:  operationLogs,
-		notifications:  notifications,
-		payments:       payments,
-		defaultSLAMins: 30,
+		disputes:      disputes,
+		orders:        orders,
+		users:         users,
+		operationLogs: operationLogs,
+		notifications: notifications,
+		payments:      payments,
+		sla:           defaultSLA,
+		escalationSLA: defaultSLA,
 	}
 }
 
+// SetRefunder injects the refund service used for refund resolutions.
+func (s *AssignmentService) SetRefunder(r Refunder) { s.refunder = r }
+
+// SetStatusHistory injects the order status history recorded for refund resolutions.
+func (s *AssignmentService) SetStatusHistory(h orderhistory.HistoryRepository) { s.history = h }
+
+// SetTxManager injects the transaction manager used when refunds are not routed through a refund service.
+func (s *AssignmentService) SetTxManager(tx TxManager) { s.tx = tx }
+
 // InitiateDisputeRequest represents a request to initiate a dispute
 type InitiateDisputeRequest struct {
-	OrderID      uint64
-	UserID       uint64
-	Reason       string
-	Description  string
-	EvidenceURLs []string
+	OrderID     uint64
+	UserID      uint64
+	Reason      string
+	Description string
+	// EvidenceUploadIDs are images or videos the user uploaded beforehand (at most maxEvidenceFiles)
+	EvidenceUploadIDs []uint64
 }
 
 // InitiateDisputeResponse represents the response after initiating a dispute
 type InitiateDisputeResponse struct {
-	DisputeID   uint64
-	TraceID     string
-	SLADeadline *time.Time
+	DisputeID        uint64
+	TraceID          string
+	SLADeadline      *time.Time
+	AssignedToUserID *uint64 // set when an on-duty agent was assigned right away
 }
 
 // InitiateDispute creates a new dispute for an order
@@ -88,6 +129,10 @@ func (s *AssignmentService) InitiateDispute(ctx context.Context, req InitiateDis
 		return nil, fmt.Errorf("%w: reason is required", ErrValidation)
 	}
 
+	if err := s.checkEvidence(ctx, req.UserID, req.EvidenceUploadIDs); err != nil {
+		return nil, err
+	}
+
 	// Get order
 	order, err := s.orders.Get(ctx, req.OrderID)
 	if err != nil {
@@ -120,18 +165,20 @@ func (s *AssignmentService) InitiateDispute(ctx context.Context, req InitiateDis
 	traceID := uuid.New().String()
 
 	// Calculate SLA deadline
-	slaDeadline := time.Now().Add(time.Duration(s.defaultSLAMins) * time.Minute)
+	slaDeadline := time.Now().Add(s.sla)
 
 	// Create dispute
 	dispute := &model.OrderDispute{
-		OrderID:      req.OrderID,
-		UserID:       req.UserID,
-		Status:       model.DisputeStatusPending,
-		Reason:       req.Reason,
-		Description:  req.Description,
-		EvidenceURLs: req.EvidenceURLs,
-		SLADeadline:  &slaDeadline,
-		TraceID:      traceID,
+		OrderID:     req.OrderID,
+		UserID:      req.UserID,
+		Status:      model.DisputeStatusPending,
+		Reason:      req.Reason,
+		Description: req.Description,
+		SLADeadline: &slaDeadline,
+		TraceID:     traceID,
+	}
+	if len(req.EvidenceUploadIDs) > 0 {
+		dispute.EvidenceUploadIDs = model.IDList(req.EvidenceUploadIDs)
 	}
 
 	if err := s.disputes.Create(ctx, dispute); err != nil {
@@ -148,10 +195,16 @@ func (s *AssignmentService) InitiateDispute(ctx context.Context, req InitiateDis
 	// Log operation
 	s.logOperation(ctx, model.OpEntityDispute, dispute.ID, model.OpActionInitiateDispute, "User initiated dispute", traceID, &req.UserID)
 
+	// Hand the dispute to an on-duty agent; unassigned disputes are retried by the SLA scheduler
+	if _, err := s.autoAssign(ctx, dispute, order.GetGameID()); err != nil {
+		slog.Warn("auto-assign dispute failed", slog.Uint64("dispute_id", dispute.ID), slog.String("error", err.Error()))
+	}
+
 	return &InitiateDisputeResponse{
-		DisputeID:   dispute.ID,
-		TraceID:     traceID,
-		SLADeadline: &slaDeadline,
+		DisputeID:        dispute.ID,
+		TraceID:          traceID,
+		SLADeadline:      dispute.SLADeadline,
+		AssignedToUserID: dispute.AssignedToUserID,
 	}, nil
 }
 
@@ -216,17 +269,29 @@ func (s *AssignmentService) AssignDispute(ctx context.Context, req AssignDispute
 type ResolveDisputeRequest struct {
 	DisputeID        uint64
 	Resolution       model.DisputeResolution
-	ResolutionAmount int64 // in cents
+	ResolutionAmount int64 // in cents; 0 refunds whatever is still refundable
 	ResolutionNotes  string
 	ActorUserID      uint64 // who is resolving this
+	// PlayerDeductionCents is the share of the refund clawed back from the player's earnings
+	PlayerDeductionCents int64
+	// PlatformCompensationCents is the share borne by the platform; 0 means the rest of the refund
+	PlatformCompensationCents int64
 }
 
-// ResolveDispute resolves a dispute with a decision
+// ResolveDispute resolves a dispute with a decision.
+// Refund and partial resolutions refund the user and split the cost between player and platform.
 func (s *AssignmentService) ResolveDispute(ctx context.Context, req ResolveDisputeRequest) error {
 	// Validate request
-	if req.DisputeID == 0 {
+	if req.DisputeID == 0 || req.ResolutionAmount < 0 || req.PlayerDeductionCents < 0 || req.PlatformCompensationCents < 0 {
 		return ErrValidation
 	}
+	refunds := req.Resolution == model.ResolutionRefund || req.Resolution == model.ResolutionPartial
+	if !refunds && (req.ResolutionAmount > 0 || req.PlayerDeductionCents > 0 || req.PlatformCompensationCents > 0) {
+		return fmt.Errorf("%w: only refund resolutions carry amounts", ErrValidation)
+	}
+	if req.Resolution == model.ResolutionPartial && req.ResolutionAmount == 0 {
+		return fmt.Errorf("%w: partial refunds need an amount", ErrValidation)
+	}
 
 	// Get dispute
 	dispute, err := s.disputes.Get(ctx, req.DisputeID)
@@ -245,29 +310,47 @@ func (s *AssignmentService) ResolveDispute(ctx context.Context, req ResolveDispu
 		return err
 	}
 
-	// Update dispute
-	now := time.Now()
-	dispute.Status = model.DisputeStatusResolved
-	dispute.Resolution = req.Resolution
-	dispute.ResolutionAmount = req.ResolutionAmount
-	dispute.ResolutionNotes = req.ResolutionNotes
-	dispute.ResolvedAt = &now
-	dispute.ResolvedByUserID = &req.ActorUserID
-
-	if err := s.disputes.Update(ctx, dispute); err != nil {
-		return err
-	}
-
-	// Handle resolution based on decision
-	if req.Resolution == model.ResolutionRefund {
-		if err := s.processRefund(ctx, order, dispute, req.ResolutionAmount, &req.ActorUserID); err != nil {
+	// resolve books the split and marks the dispute resolved; refund resolutions run it
+	// in the transaction that creates the refund, so all three commit or roll back together
+	resolve := func(ctx context.Context, r *common.Repos, amount int64) error {
+		if refunds {
+			if err := checkSplit(amount, req); err != nil {
+				return err
+			}
+			if err := s.applySplit(ctx, r, dispute, amount, req); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		dispute.Status = model.DisputeStatusResolved
+		dispute.Resolution = req.Resolution
+		dispute.ResolutionAmount = amount
+		dispute.ResolutionNotes = req.ResolutionNotes
+		dispute.ResolvedAt = &now
+		dispute.ResolvedByUserID = &req.ActorUserID
+		return s.disputeRepo(r).Update(ctx, dispute)
+	}
+
+	if refunds {
+		if req.ResolutionAmount > 0 {
+			if err := checkSplit(req.ResolutionAmount, req); err != nil {
+				return err
+			}
+		}
+		if err := s.refundAndResolve(ctx, order, dispute, req, resolve); err != nil {
 			return err
 		}
+	} else if err := resolve(ctx, nil, 0); err != nil {
+		return err
+	}
+	if refunds {
+		s.recordDisputeLost(ctx, dispute, order.GetPlayerID())
 	}
 
 	// Log operation
 	s.logOperation(ctx, model.OpEntityDispute, dispute.ID, model.OpActionResolveDispute,
-		fmt.Sprintf("Resolved with %s decision", req.Resolution), dispute.TraceID, &req.ActorUserID)
+		fmt.Sprintf("Resolved with %s decision (player %d, platform %d cents)", req.Resolution, dispute.PlayerDeductionCents, dispute.PlatformCompensationCents),
+		dispute.TraceID, &req.ActorUserID)
 
 	// Send notification to user
 	s.sendNotification(ctx, dispute.UserID, "Dispute Resolved",
@@ -283,7 +366,9 @@ type RollbackAssignmentRequest struct {
 	ActorUserID    uint64
 }
 
-// RollbackAssignment rolls back a dispute assignment
+// RollbackAssignment rolls back a dispute assignment.
+// Rolling back a resolved dispute cancels its refund and reverts the player/platform split,
+// which is refused once the payment provider has accepted the refund.
 func (s *AssignmentService) RollbackAssignment(ctx context.Context, req RollbackAssignmentRequest) error {
 	// Validate request
 	if req.DisputeID == 0 {
@@ -297,8 +382,15 @@ func (s *AssignmentService) RollbackAssignment(ctx context.Context, req Rollback
 	}
 
 	// Check if dispute is assigned
-	if dispute.Status != model.DisputeStatusAssigned && dispute.Status != model.DisputeStatusMediating {
-		return fmt.Errorf("%w: dispute is not in assigned or mediating status", ErrInvalidStatus)
+	switch dispute.Status {
+	case model.DisputeStatusAssigned, model.DisputeStatusMediating:
+	case model.DisputeStatusResolved:
+		if err := s.undoResolution(ctx, dispute, req.RollbackReason); err != nil {
+			return err
+		}
+		s.revokeDisputeLost(ctx, dispute.ID, req.RollbackReason, &req.ActorUserID)
+	default:
+		return fmt.Errorf("%w: dispute is not in assigned, mediating or resolved status", ErrInvalidStatus)
 	}
 
 	// Update dispute
@@ -324,7 +416,14 @@ func (s *AssignmentService) RollbackAssignment(ctx context.Context, req Rollback
 
 // GetDisputeDetail retrieves detailed information about a dispute
 func (s *AssignmentService) GetDisputeDetail(ctx context.Context, disputeID uint64) (*model.OrderDispute, error) {
-	return s.disputes.Get(ctx, disputeID)
+	dispute, err := s.disputes.Get(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.signEvidence(ctx, dispute); err != nil {
+		return nil, err
+	}
+	return dispute, nil
 }
 
 // ListPendingDisputes lists disputes pending assignment
@@ -342,51 +441,159 @@ func (s *AssignmentService) ListDisputesByStatus(ctx context.Context, statuses [
 	return s.disputes.List(ctx, opts)
 }
 
-// CheckAndMarkSLABreaches checks for disputes that have breached SLA and marks them
+// ListDisputes lists disputes for the CS workbench with filters applied
+func (s *AssignmentService) ListDisputes(ctx context.Context, opts repository.DisputeListOptions) ([]model.OrderDispute, int64, error) {
+	return s.disputes.List(ctx, opts)
+}
+
+// CheckAndMarkSLABreaches checks for disputes that have breached SLA and marks them.
+// With an agent roster configured, breached disputes are escalated to the next tier.
 func (s *AssignmentService) CheckAndMarkSLABreaches(ctx context.Context) error {
+	_, _, err := s.checkSLABreaches(ctx)
+	return err
+}
+
+func (s *AssignmentService) checkSLABreaches(ctx context.Context) (breached, escalated int, err error) {
 	breachedDisputes, err := s.disputes.ListSLABreached(ctx)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
-	for _, dispute := range breachedDisputes {
+	for i := range breachedDisputes {
+		dispute := &breachedDisputes[i]
 		if err := s.disputes.MarkSLABreached(ctx, dispute.ID); err != nil {
+			slog.Warn("mark dispute sla breached failed", slog.Uint64("dispute_id", dispute.ID), slog.String("error", err.Error()))
 			continue // Log but continue processing others
 		}
+		breached++
 
 		// Log operation
 		s.logOperation(ctx, model.OpEntityDispute, dispute.ID, model.OpActionUpdateStatus,
 			"SLA breached", dispute.TraceID, dispute.AssignedToUserID)
 
-		// Send alert notification
-		s.sendNotification(ctx, *dispute.AssignedToUserID, "SLA Breached",
-			fmt.Sprintf("Dispute #%d has exceeded SLA deadline", dispute.ID), dispute.TraceID)
+		ok, err := s.escalate(ctx, dispute)
+		if err != nil {
+			slog.Warn("escalate dispute failed", slog.Uint64("dispute_id", dispute.ID), slog.String("error", err.Error()))
+		}
+		if ok {
+			escalated++
+			continue
+		}
+
+		// Nobody to escalate to: alert the current assignee, if any
+		if dispute.AssignedToUserID != nil {
+			s.sendNotification(ctx, *dispute.AssignedToUserID, "SLA Breached",
+				fmt.Sprintf("Dispute #%d has exceeded SLA deadline", dispute.ID), dispute.TraceID)
+		}
 	}
 
-	return nil
+	return breached, escalated, nil
 }
 
 // Helper functions
 
-func (s *AssignmentService) processRefund(ctx context.Context, order *model.Order, dispute *model.OrderDispute, amount int64, actorID *uint64) error {
-	// Update order status
-	order.Status = model.OrderStatusRefunded
-	order.RefundAmountCents = amount
-	order.RefundReason = fmt.Sprintf("Dispute resolution: %s", dispute.ResolutionNotes)
-	now := time.Now()
-	order.RefundedAt = &now
-
-	if err := s.orders.Update(ctx, order); err != nil {
+// refundAndResolve refunds the user and resolves the dispute in the same transaction.
+// A zero ResolutionAmount refunds whatever is still refundable: the refund service plans it under
+// the order lock, net of pending and succeeded refunds. If the provider rejects the refund after
+// the resolution was committed, the resolution is undone and the dispute reopened.
+func (s *AssignmentService) refundAndResolve(ctx context.Context, order *model.Order, dispute *model.OrderDispute,
+	req ResolveDisputeRequest, resolve func(ctx context.Context, r *common.Repos, amount int64) error) error {
+	reason := fmt.Sprintf("Dispute resolution: %s", req.ResolutionNotes)
+	actorID := &req.ActorUserID
+	if s.refunder == nil {
+		return s.refundOrderDirectly(ctx, order, dispute, req, reason, resolve)
+	}
+
+	prevStatus := dispute.Status
+	refund, err := s.refunder.CreateRefund(ctx, paymentservice.CreateRefundRequest{
+		OrderID:     order.ID,
+		AmountCents: req.ResolutionAmount,
+		Reason:      reason,
+		Source:      model.RefundSourceDispute,
+		ActorUserID: actorID,
+		InTx: func(ctx context.Context, r *common.Repos, refunds []*model.Refund) error {
+			var amount int64
+			for _, rf := range refunds {
+				amount += rf.AmountCents
+			}
+			dispute.RefundID = &refunds[0].ID
+			return resolve(ctx, r, amount)
+		},
+	})
+	if errors.Is(err, paymentservice.ErrRefundExceedsPaid) {
+		return fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	// Nothing was committed when no refund record came back
+	if err != nil && (refund == nil || refund.ID == 0) {
 		return err
 	}
+	// A refund left pending after a failed submission is retried by the refund service
+	if err != nil && refund.Status != model.RefundStatusPending {
+		if uerr := s.reopenDispute(ctx, dispute, prevStatus, reason); uerr != nil {
+			slog.Warn("reopen dispute after rejected refund failed", "dispute_id", dispute.ID, "error", uerr)
+		}
+		return err
+	}
+	s.logOperation(ctx, model.OpEntityOrder, order.ID, model.OpActionRefund,
+		fmt.Sprintf("Refund %s requested: %d cents", refund.OutRefundNo, refund.AmountCents), dispute.TraceID, actorID)
+	return nil
+}
 
-	// Log refund operation
+// refundOrderDirectly marks the order refunded together with the resolution when no refund service is configured.
+func (s *AssignmentService) refundOrderDirectly(ctx context.Context, order *model.Order, dispute *model.OrderDispute,
+	req ResolveDisputeRequest, reason string, resolve func(ctx context.Context, r *common.Repos, amount int64) error) error {
+	amount := req.ResolutionAmount
+	if amount == 0 {
+		amount = order.TotalPriceCents - order.RefundAmountCents
+	}
+	if amount <= 0 {
+		return fmt.Errorf("%w: nothing left to refund", ErrValidation)
+	}
+	actorID := &req.ActorUserID
+	err := s.withTx(ctx, func(r *common.Repos) error {
+		if err := resolve(ctx, r, amount); err != nil {
+			return err
+		}
+		now := time.Now()
+		order.RefundAmountCents = amount
+		order.RefundReason = reason
+		order.RefundedAt = &now
+		change := orderstate.Change{Role: model.OrderActorAdmin, ActorUserID: actorID, Reason: reason, TraceID: dispute.TraceID, At: now}
+		return orderstate.Transit(ctx, r.Orders, r.OrderHistory, order, model.OrderStatusRefunded, change)
+	})
+	if err != nil {
+		return err
+	}
 	s.logOperation(ctx, model.OpEntityOrder, order.ID, model.OpActionRefund,
 		fmt.Sprintf("Refund processed: %d cents", amount), dispute.TraceID, actorID)
-
 	return nil
 }
 
+// reopenDispute undoes a committed resolution and restores the dispute's previous status.
+func (s *AssignmentService) reopenDispute(ctx context.Context, dispute *model.OrderDispute, status model.DisputeStatus, reason string) error {
+	if err := s.undoResolution(ctx, dispute, reason); err != nil {
+		return err
+	}
+	dispute.Status = status
+	return s.disputes.Update(ctx, dispute)
+}
+
+// disputeRepo returns the dispute repository of the caller's transaction, if any.
+func (s *AssignmentService) disputeRepo(r *common.Repos) repository.DisputeRepository {
+	if r != nil && r.Disputes != nil {
+		return r.Disputes
+	}
+	return s.disputes
+}
+
+// withTx runs fn in a transaction when a TxManager is configured.
+func (s *AssignmentService) withTx(ctx context.Context, fn func(r *common.Repos) error) error {
+	if s.tx != nil {
+		return s.tx.WithTx(ctx, fn)
+	}
+	return fn(&common.Repos{Disputes: s.disputes, Orders: s.orders, OrderHistory: s.history})
+}
+
 func (s *AssignmentService) logOperation(ctx context.Context, entityType model.OperationEntityType, entityID uint64, action model.OperationAction, reason string, traceID string, actorID *uint64) {
 	log := &model.OperationLog{
 		EntityType:  string(entityType),
@@ -0,0 +1,109 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+	commissionservice "gamelink/internal/service/commission"
+	paymentservice "gamelink/internal/service/payment"
+)
+
+// CommissionAdjuster splits dispute refunds between player and platform (implemented by the commission service).
+type CommissionAdjuster interface {
+	// ApplyDisputeSplit deducts the player's share from their commission records and books the rest as platform compensation.
+	// A non-nil r runs it inside the caller's transaction.
+	ApplyDisputeSplit(ctx context.Context, r *common.Repos, split commissionservice.DisputeSplit) ([]model.CommissionAdjustment, error)
+	// RevertDisputeSplit undoes every adjustment made for the dispute.
+	RevertDisputeSplit(ctx context.Context, disputeID uint64) error
+}
+
+// SetCommissionAdjuster injects the commission service used to split refund resolutions.
+func (s *AssignmentService) SetCommissionAdjuster(a CommissionAdjuster) { s.adjuster = a }
+
+// checkSplit validates the requested split of a refund of amount cents.
+// A zero platform share means the platform bears whatever the player does not.
+func checkSplit(amount int64, req ResolveDisputeRequest) error {
+	if req.PlatformCompensationCents > 0 {
+		if req.PlayerDeductionCents+req.PlatformCompensationCents != amount {
+			return fmt.Errorf("%w: player and platform shares must add up to the refund of %d cents", ErrValidation, amount)
+		}
+		return nil
+	}
+	if req.PlayerDeductionCents > amount {
+		return fmt.Errorf("%w: player deduction exceeds the refund of %d cents", ErrValidation, amount)
+	}
+	return nil
+}
+
+// applySplit books the player's and platform's shares of the refund and records them on the dispute.
+func (s *AssignmentService) applySplit(ctx context.Context, r *common.Repos, dispute *model.OrderDispute, amount int64, req ResolveDisputeRequest) error {
+	dispute.PlayerDeductionCents = 0
+	dispute.PlatformCompensationCents = 0
+	if s.adjuster == nil {
+		if req.PlayerDeductionCents > 0 {
+			return fmt.Errorf("%w: player deductions are not supported", ErrValidation)
+		}
+		return nil
+	}
+	adjs, err := s.adjuster.ApplyDisputeSplit(ctx, r, commissionservice.DisputeSplit{
+		DisputeID:            dispute.ID,
+		OrderID:              dispute.OrderID,
+		RefundCents:          amount,
+		PlayerDeductionCents: req.PlayerDeductionCents,
+		Reason:               req.ResolutionNotes,
+	})
+	if errors.Is(err, commissionservice.ErrValidation) {
+		return fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	if err != nil {
+		return err
+	}
+	for _, adj := range adjs {
+		switch adj.Kind {
+		case model.CommissionAdjustmentPlayerDeduction:
+			dispute.PlayerDeductionCents += adj.AmountCents
+		case model.CommissionAdjustmentPlatformCompensation:
+			dispute.PlatformCompensationCents += adj.AmountCents
+		}
+	}
+	return nil
+}
+
+// undoResolution reverses the money movements of a resolved dispute: the refund is
+// canceled while the provider has not accepted it, then the player/platform split is reverted.
+func (s *AssignmentService) undoResolution(ctx context.Context, dispute *model.OrderDispute, reason string) error {
+	if dispute.Resolution == model.ResolutionRefund || dispute.Resolution == model.ResolutionPartial {
+		if dispute.RefundID == nil || s.refunder == nil {
+			return fmt.Errorf("%w: the refund was issued without a refund record and cannot be undone", ErrInvalidStatus)
+		}
+		_, err := s.refunder.CancelRefund(ctx, *dispute.RefundID, reason)
+		if errors.Is(err, paymentservice.ErrRefundNotCancelable) {
+			return fmt.Errorf("%w: %v", ErrInvalidStatus, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := s.revertSplit(ctx, dispute.ID); err != nil {
+		return err
+	}
+	dispute.Resolution = model.ResolutionPending
+	dispute.ResolutionAmount = 0
+	dispute.PlayerDeductionCents = 0
+	dispute.PlatformCompensationCents = 0
+	dispute.RefundID = nil
+	dispute.ResolvedAt = nil
+	dispute.ResolvedByUserID = nil
+	return nil
+}
+
+// revertSplit undoes the player/platform split booked for a dispute, if any.
+func (s *AssignmentService) revertSplit(ctx context.Context, disputeID uint64) error {
+	if s.adjuster == nil {
+		return nil
+	}
+	return s.adjuster.RevertDisputeSplit(ctx, disputeID)
+}
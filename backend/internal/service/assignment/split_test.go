@@ -0,0 +1,212 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+	commissionservice "gamelink/internal/service/commission"
+	paymentservice "gamelink/internal/service/payment"
+)
+
+// pendingRefunder keeps refunds pending until canceled, unless the provider already accepted or rejected them.
+type pendingRefunder struct {
+	refunds    map[uint64]*model.Refund
+	requests   []paymentservice.CreateRefundRequest
+	refundable int64 // refunded when the request leaves the amount to the refund service
+	accepted   bool
+	rejected   bool // the provider rejects the refund after it was created
+	err        error
+}
+
+func (r *pendingRefunder) CreateRefund(ctx context.Context, req paymentservice.CreateRefundRequest) (*model.Refund, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.requests = append(r.requests, req)
+	amount := req.AmountCents
+	if amount == 0 {
+		amount = r.refundable
+	}
+	refund := &model.Refund{Base: model.Base{ID: uint64(len(r.refunds) + 1)}, OrderID: req.OrderID, AmountCents: amount, Status: model.RefundStatusPending}
+	// Like the refund service, the caller's changes commit with the refund record
+	if req.InTx != nil {
+		if err := req.InTx(ctx, nil, []*model.Refund{refund}); err != nil {
+			return nil, err
+		}
+	}
+	r.refunds[refund.ID] = refund
+	if r.rejected {
+		refund.Status = model.RefundStatusFailed
+		return refund, paymentservice.ErrRefundRejected
+	}
+	if r.accepted {
+		refund.Status = model.RefundStatusProcessing
+	}
+	return refund, nil
+}
+
+func (r *pendingRefunder) CancelRefund(ctx context.Context, refundID uint64, reason string) (*model.Refund, error) {
+	refund := r.refunds[refundID]
+	if refund.Status != model.RefundStatusPending && refund.Status != model.RefundStatusFailed {
+		return refund, paymentservice.ErrRefundNotCancelable
+	}
+	refund.Status = model.RefundStatusFailed
+	return refund, nil
+}
+
+type recordingAdjuster struct {
+	splits   []commissionservice.DisputeSplit
+	reverted []uint64
+}
+
+func (a *recordingAdjuster) ApplyDisputeSplit(ctx context.Context, _ *common.Repos, split commissionservice.DisputeSplit) ([]model.CommissionAdjustment, error) {
+	if split.PlayerDeductionCents > 6000 {
+		return nil, commissionservice.ErrValidation
+	}
+	a.splits = append(a.splits, split)
+	return []model.CommissionAdjustment{
+		{Kind: model.CommissionAdjustmentPlayerDeduction, AmountCents: split.PlayerDeductionCents},
+		{Kind: model.CommissionAdjustmentPlatformCompensation, AmountCents: split.RefundCents - split.PlayerDeductionCents},
+	}, nil
+}
+
+func (a *recordingAdjuster) RevertDisputeSplit(ctx context.Context, disputeID uint64) error {
+	a.reverted = append(a.reverted, disputeID)
+	return nil
+}
+
+func newSplitService(t *testing.T) (*AssignmentService, *mockDisputeRepository, *pendingRefunder, *recordingAdjuster) {
+	t.Helper()
+	ctx := context.Background()
+	disputes := newMockDisputeRepository()
+	orders := newMockOrderRepository()
+	svc := NewAssignmentService(disputes, orders, newMockUserRepository(), &mockOperationLogRepository{}, &mockNotificationRepository{}, &mockPaymentRepository{})
+	refunder := &pendingRefunder{refunds: make(map[uint64]*model.Refund), refundable: 10000}
+	adjuster := &recordingAdjuster{}
+	svc.SetRefunder(refunder)
+	svc.SetCommissionAdjuster(adjuster)
+	require.NoError(t, orders.Create(ctx, &model.Order{Base: model.Base{ID: 1}, UserID: 1, Status: model.OrderStatusCompleted, TotalPriceCents: 10000}))
+	require.NoError(t, disputes.Create(ctx, &model.OrderDispute{OrderID: 1, UserID: 1, Status: model.DisputeStatusMediating}))
+	return svc, disputes, refunder, adjuster
+}
+
+func TestResolveDispute_SplitsRefund(t *testing.T) {
+	ctx := context.Background()
+	svc, disputes, _, adjuster := newSplitService(t)
+
+	bad := []ResolveDisputeRequest{
+		{DisputeID: 1, Resolution: model.ResolutionReject, PlayerDeductionCents: 100},
+		{DisputeID: 1, Resolution: model.ResolutionPartial},
+		{DisputeID: 1, Resolution: model.ResolutionPartial, ResolutionAmount: 4000, PlayerDeductionCents: 5000},
+		{DisputeID: 1, Resolution: model.ResolutionPartial, ResolutionAmount: 4000, PlayerDeductionCents: 1000, PlatformCompensationCents: 1000},
+		{DisputeID: 1, Resolution: model.ResolutionRefund, PlayerDeductionCents: 7000},
+	}
+	for _, req := range bad {
+		assert.ErrorIs(t, svc.ResolveDispute(ctx, req), ErrValidation, "%+v", req)
+	}
+
+	require.NoError(t, svc.ResolveDispute(ctx, ResolveDisputeRequest{
+		DisputeID: 1, Resolution: model.ResolutionPartial, ResolutionAmount: 4000,
+		PlayerDeductionCents: 1500, PlatformCompensationCents: 2500, ActorUserID: 2,
+	}))
+	require.Len(t, adjuster.splits, 1)
+	assert.Equal(t, commissionservice.DisputeSplit{DisputeID: 1, OrderID: 1, RefundCents: 4000, PlayerDeductionCents: 1500}, adjuster.splits[0])
+
+	dispute, err := disputes.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusResolved, dispute.Status)
+	assert.Equal(t, int64(4000), dispute.ResolutionAmount)
+	assert.Equal(t, int64(1500), dispute.PlayerDeductionCents)
+	assert.Equal(t, int64(2500), dispute.PlatformCompensationCents)
+	require.NotNil(t, dispute.RefundID)
+}
+
+func TestResolveDispute_RevertsSplitWhenRefundFails(t *testing.T) {
+	ctx := context.Background()
+	svc, disputes, refunder, adjuster := newSplitService(t)
+	refunder.err = errors.New("refund exceeds paid amount")
+
+	err := svc.ResolveDispute(ctx, ResolveDisputeRequest{DisputeID: 1, Resolution: model.ResolutionRefund, PlayerDeductionCents: 3000})
+	require.Error(t, err)
+	// The split runs in the refund transaction, so nothing was booked
+	assert.Empty(t, adjuster.splits)
+
+	dispute, err := disputes.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusMediating, dispute.Status)
+}
+
+func TestResolveDispute_DefaultsToRemainingRefundable(t *testing.T) {
+	ctx := context.Background()
+	svc, disputes, refunder, adjuster := newSplitService(t)
+	// 4000 of the 10000 order total is already held by an earlier refund
+	refunder.refundable = 6000
+
+	require.NoError(t, svc.ResolveDispute(ctx, ResolveDisputeRequest{DisputeID: 1, Resolution: model.ResolutionRefund, PlayerDeductionCents: 3000}))
+	require.Len(t, refunder.requests, 1)
+	assert.Zero(t, refunder.requests[0].AmountCents, "the refund service plans the remaining amount")
+	require.Len(t, adjuster.splits, 1)
+	assert.Equal(t, int64(6000), adjuster.splits[0].RefundCents)
+
+	dispute, err := disputes.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6000), dispute.ResolutionAmount)
+}
+
+func TestResolveDispute_ReopensWhenRefundRejected(t *testing.T) {
+	ctx := context.Background()
+	svc, disputes, refunder, adjuster := newSplitService(t)
+	refunder.rejected = true
+
+	err := svc.ResolveDispute(ctx, ResolveDisputeRequest{DisputeID: 1, Resolution: model.ResolutionRefund, PlayerDeductionCents: 3000})
+	require.ErrorIs(t, err, paymentservice.ErrRefundRejected)
+	require.Len(t, adjuster.splits, 1)
+	assert.Equal(t, []uint64{1}, adjuster.reverted)
+
+	dispute, err := disputes.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusMediating, dispute.Status)
+	assert.Equal(t, model.ResolutionPending, dispute.Resolution)
+	assert.Nil(t, dispute.RefundID)
+}
+
+func TestRollbackAssignment_UndoesResolution(t *testing.T) {
+	ctx := context.Background()
+	svc, disputes, refunder, adjuster := newSplitService(t)
+	require.NoError(t, svc.ResolveDispute(ctx, ResolveDisputeRequest{DisputeID: 1, Resolution: model.ResolutionRefund, PlayerDeductionCents: 3000}))
+
+	require.NoError(t, svc.RollbackAssignment(ctx, RollbackAssignmentRequest{DisputeID: 1, RollbackReason: "wrong call", ActorUserID: 9}))
+	assert.Equal(t, model.RefundStatusFailed, refunder.refunds[1].Status)
+	assert.Equal(t, []uint64{1}, adjuster.reverted)
+
+	dispute, err := disputes.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusPending, dispute.Status)
+	assert.Equal(t, model.ResolutionPending, dispute.Resolution)
+	assert.Zero(t, dispute.ResolutionAmount)
+	assert.Zero(t, dispute.PlayerDeductionCents)
+	assert.Zero(t, dispute.PlatformCompensationCents)
+	assert.Nil(t, dispute.RefundID)
+	assert.Nil(t, dispute.ResolvedAt)
+}
+
+func TestRollbackAssignment_RefusesAcceptedRefund(t *testing.T) {
+	ctx := context.Background()
+	svc, disputes, refunder, adjuster := newSplitService(t)
+	refunder.accepted = true
+	require.NoError(t, svc.ResolveDispute(ctx, ResolveDisputeRequest{DisputeID: 1, Resolution: model.ResolutionRefund}))
+
+	err := svc.RollbackAssignment(ctx, RollbackAssignmentRequest{DisputeID: 1, RollbackReason: "too late"})
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	assert.Empty(t, adjuster.reverted)
+
+	dispute, err := disputes.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeStatusResolved, dispute.Status)
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/mail"
 	"time"
 
@@ -29,6 +30,12 @@ var (
 type AuthService struct {
 	userRepo   repository.UserRepository
 	jwtManager *auth.JWTManager
+	coupons    CouponIssuer
+}
+
+// CouponIssuer 按业务事件自动发放优惠券（由优惠券服务实现）。
+type CouponIssuer interface {
+	IssueForEvent(ctx context.Context, event model.CouponEvent, userID uint64) error
 }
 
 // NewAuthService 创建认证服务
@@ -39,6 +46,9 @@ func NewAuthService(userRepo repository.UserRepository, jwtManager *auth.JWTMana
 	}
 }
 
+// SetCouponIssuer 注入优惠券服务，新用户注册后发放新人券
+func (s *AuthService) SetCouponIssuer(c CouponIssuer) { s.coupons = c }
+
 // GetUser returns a user by id (for /auth/me endpoint).
 func (s *AuthService) GetUser(ctx context.Context, id uint64) (*model.User, error) {
 	return s.userRepo.Get(ctx, id)
@@ -208,6 +218,13 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*Login
 		return nil, err
 	}
 
+	// 发放新人券失败不影响注册
+	if s.coupons != nil {
+		if err := s.coupons.IssueForEvent(ctx, model.CouponEventUserRegistered, user.ID); err != nil {
+			slog.Warn("issue new user coupons failed", slog.Uint64("user_id", user.ID), slog.String("error", err.Error()))
+		}
+	}
+
 	// 生成JWT Token
 	token, err := s.jwtManager.GenerateToken(user.ID, string(user.Role))
 	if err != nil {
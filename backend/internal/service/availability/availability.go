@@ -0,0 +1,312 @@
+// Package availability 陪玩师日程：每周可接单模板、临时不可接单时段、可预约时段查询与预约冲突检测。
+package availability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	availabilityrepo "gamelink/internal/repository/availability"
+)
+
+var (
+	// ErrNotFound 陪玩师或不可接单时段不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrBookingConflict 与陪玩师已确认或服务中的订单时间重叠
+	ErrBookingConflict = errors.New("booking conflicts with another order of the player")
+	// ErrUnavailable 陪玩师在该时段不接单
+	ErrUnavailable = errors.New("player is not available at the requested time")
+)
+
+const (
+	minutesPerDay = 24 * 60
+	// maxSlotRangeDays 单次查询可预约时段的最大天数
+	maxSlotRangeDays = 31
+	// blackoutHorizon 日程中展示的不可接单时段范围
+	blackoutHorizon = 180 * 24 * time.Hour
+)
+
+// WeeklySlot 每周可接单时段，时间格式 HH:MM（结束时间可为 24:00）
+type WeeklySlot struct {
+	Weekday int    `json:"weekday" binding:"min=0,max=6"` // 0=周日 ... 6=周六
+	Start   string `json:"start" binding:"required"`
+	End     string `json:"end" binding:"required"`
+}
+
+// SetWeeklyRequest 整体替换每周模板；为空表示不限制接单时间
+type SetWeeklyRequest struct {
+	Slots []WeeklySlot `json:"slots" binding:"dive"`
+}
+
+// BlackoutRequest 新增不可接单时段
+type BlackoutRequest struct {
+	StartAt time.Time `json:"startAt" binding:"required"`
+	EndAt   time.Time `json:"endAt" binding:"required"`
+	Reason  string    `json:"reason" binding:"max=255"`
+}
+
+// ScheduleResponse 陪玩师日程
+type ScheduleResponse struct {
+	Weekly    []WeeklySlot           `json:"weekly"`
+	Blackouts []model.PlayerBlackout `json:"blackouts"`
+}
+
+// BookableSlot 可预约时段 [Start, End)
+type BookableSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// BookableSlotsResponse 可预约时段查询结果
+type BookableSlotsResponse struct {
+	PlayerID uint64         `json:"playerId"`
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Slots    []BookableSlot `json:"slots"`
+}
+
+// Service 陪玩师日程服务
+//
+// 每周模板按服务端时区解释；陪玩师未发布模板时不限制接单时间，只排除不可接单时段与已占用的订单。
+type Service struct {
+	calendar availabilityrepo.CalendarRepository
+	players  repository.PlayerRepository
+	loc      *time.Location
+	now      func() time.Time
+}
+
+// NewService 创建陪玩师日程服务
+func NewService(calendar availabilityrepo.CalendarRepository, players repository.PlayerRepository) *Service {
+	return &Service{calendar: calendar, players: players, loc: time.Local, now: time.Now}
+}
+
+// GetSchedule 获取当前陪玩师的每周模板与未结束的不可接单时段
+func (s *Service) GetSchedule(ctx context.Context, userID uint64) (*ScheduleResponse, error) {
+	player, err := s.players.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.schedule(ctx, player.ID)
+}
+
+// SetWeekly 整体替换当前陪玩师的每周模板
+func (s *Service) SetWeekly(ctx context.Context, userID uint64, req SetWeeklyRequest) (*ScheduleResponse, error) {
+	player, err := s.players.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	slots := make([]model.PlayerAvailabilitySlot, 0, len(req.Slots))
+	for _, in := range req.Slots {
+		start, err := parseClock(in.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(in.End)
+		if err != nil {
+			return nil, err
+		}
+		if in.Weekday < 0 || in.Weekday > 6 || start >= end {
+			return nil, fmt.Errorf("%w: invalid slot %d %s-%s", ErrValidation, in.Weekday, in.Start, in.End)
+		}
+		slots = append(slots, model.PlayerAvailabilitySlot{Weekday: in.Weekday, StartMinute: start, EndMinute: end})
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].Weekday != slots[j].Weekday {
+			return slots[i].Weekday < slots[j].Weekday
+		}
+		return slots[i].StartMinute < slots[j].StartMinute
+	})
+	for i := 1; i < len(slots); i++ {
+		if slots[i].Weekday == slots[i-1].Weekday && slots[i].StartMinute < slots[i-1].EndMinute {
+			return nil, fmt.Errorf("%w: overlapping slots on weekday %d", ErrValidation, slots[i].Weekday)
+		}
+	}
+	if err := s.calendar.ReplaceWeekly(ctx, player.ID, slots); err != nil {
+		return nil, err
+	}
+	return s.schedule(ctx, player.ID)
+}
+
+// AddBlackout 为当前陪玩师新增不可接单时段
+func (s *Service) AddBlackout(ctx context.Context, userID uint64, req BlackoutRequest) (*model.PlayerBlackout, error) {
+	player, err := s.players.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !req.EndAt.After(req.StartAt) || !req.EndAt.After(s.now()) {
+		return nil, fmt.Errorf("%w: blackout must end after it starts and in the future", ErrValidation)
+	}
+	blackout := &model.PlayerBlackout{
+		PlayerID: player.ID,
+		StartAt:  req.StartAt,
+		EndAt:    req.EndAt,
+		Reason:   strings.TrimSpace(req.Reason),
+	}
+	if err := s.calendar.CreateBlackout(ctx, blackout); err != nil {
+		return nil, err
+	}
+	return blackout, nil
+}
+
+// RemoveBlackout 删除当前陪玩师的不可接单时段
+func (s *Service) RemoveBlackout(ctx context.Context, userID, id uint64) error {
+	player, err := s.players.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.calendar.DeleteBlackout(ctx, player.ID, id)
+}
+
+// ListBookableSlots 返回陪玩师在 [from, to) 内的可预约时段：每周模板（未发布时为全天）
+// 扣除不可接单时段与已确认/服务中的订单，且不早于当前时间。
+func (s *Service) ListBookableSlots(ctx context.Context, playerID uint64, from, to time.Time) (*BookableSlotsResponse, error) {
+	if !to.After(from) || to.Sub(from) > maxSlotRangeDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: date range must be within %d days", ErrValidation, maxSlotRangeDays)
+	}
+	if _, err := s.players.Get(ctx, playerID); err != nil {
+		return nil, err
+	}
+	if now := s.now(); from.Before(now) {
+		from = now
+	}
+	resp := &BookableSlotsResponse{PlayerID: playerID, From: from, To: to, Slots: []BookableSlot{}}
+	if !to.After(from) {
+		return resp, nil
+	}
+
+	open, err := s.openSpans(ctx, playerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	busy, err := s.busySpans(ctx, playerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, sp := range subtract(clip(open, from, to), busy) {
+		resp.Slots = append(resp.Slots, BookableSlot{Start: sp.start, End: sp.end})
+	}
+	return resp, nil
+}
+
+// CheckBookable 校验预约时段：须在陪玩师每周模板内、不在不可接单时段，且不与其他订单重叠
+func (s *Service) CheckBookable(ctx context.Context, playerID uint64, start, end time.Time, exceptOrderID uint64) error {
+	if !end.After(start) {
+		return fmt.Errorf("%w: booking must end after it starts", ErrValidation)
+	}
+	open, err := s.openSpans(ctx, playerID, start, end)
+	if err != nil {
+		return err
+	}
+	if !covers(open, start, end) {
+		return ErrUnavailable
+	}
+	blackouts, err := s.calendar.ListBlackouts(ctx, playerID, start, end)
+	if err != nil {
+		return err
+	}
+	if len(blackouts) > 0 {
+		return ErrUnavailable
+	}
+	return s.CheckConflict(ctx, playerID, start, end, exceptOrderID)
+}
+
+// CheckConflict 校验预约时段不与陪玩师其他已确认/服务中的订单重叠
+func (s *Service) CheckConflict(ctx context.Context, playerID uint64, start, end time.Time, exceptOrderID uint64) error {
+	orders, err := s.calendar.ListBookings(ctx, playerID, start, end, exceptOrderID)
+	if err != nil {
+		return err
+	}
+	if len(orders) > 0 {
+		return fmt.Errorf("%w: order %d", ErrBookingConflict, orders[0].ID)
+	}
+	return nil
+}
+
+func (s *Service) schedule(ctx context.Context, playerID uint64) (*ScheduleResponse, error) {
+	slots, err := s.calendar.ListWeekly(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	blackouts, err := s.calendar.ListBlackouts(ctx, playerID, now, now.Add(blackoutHorizon))
+	if err != nil {
+		return nil, err
+	}
+	resp := &ScheduleResponse{Weekly: make([]WeeklySlot, 0, len(slots)), Blackouts: blackouts}
+	for _, sl := range slots {
+		resp.Weekly = append(resp.Weekly, WeeklySlot{Weekday: sl.Weekday, Start: formatClock(sl.StartMinute), End: formatClock(sl.EndMinute)})
+	}
+	if resp.Blackouts == nil {
+		resp.Blackouts = []model.PlayerBlackout{}
+	}
+	return resp, nil
+}
+
+// openSpans 展开覆盖 [from, to) 的每周模板时段；未发布模板时整段可接单
+func (s *Service) openSpans(ctx context.Context, playerID uint64, from, to time.Time) ([]span, error) {
+	slots, err := s.calendar.ListWeekly(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(slots) == 0 {
+		return []span{{start: from, end: to}}, nil
+	}
+	var out []span
+	f := from.In(s.loc)
+	for day := time.Date(f.Year(), f.Month(), f.Day(), 0, 0, 0, 0, s.loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, sl := range slots {
+			if time.Weekday(sl.Weekday) != day.Weekday() {
+				continue
+			}
+			out = append(out, span{
+				start: day.Add(time.Duration(sl.StartMinute) * time.Minute),
+				end:   day.Add(time.Duration(sl.EndMinute) * time.Minute),
+			})
+		}
+	}
+	return merge(out), nil
+}
+
+// busySpans 不可接单时段与已占用订单
+func (s *Service) busySpans(ctx context.Context, playerID uint64, from, to time.Time) ([]span, error) {
+	blackouts, err := s.calendar.ListBlackouts(ctx, playerID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	busy := make([]span, 0, len(blackouts))
+	for _, b := range blackouts {
+		busy = append(busy, span{start: b.StartAt, end: b.EndAt})
+	}
+	orders, err := s.calendar.ListBookings(ctx, playerID, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orders {
+		busy = append(busy, span{start: *o.ScheduledStart, end: *o.ScheduledEnd})
+	}
+	return merge(busy), nil
+}
+
+// parseClock 解析 HH:MM 为当天分钟数，允许 24:00
+func parseClock(v string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(strings.TrimSpace(v), "%d:%d", &h, &m); err != nil {
+		return 0, fmt.Errorf("%w: invalid time %q", ErrValidation, v)
+	}
+	minutes := h*60 + m
+	if h < 0 || m < 0 || m >= 60 || minutes > minutesPerDay {
+		return 0, fmt.Errorf("%w: invalid time %q", ErrValidation, v)
+	}
+	return minutes, nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,111 @@
+package availability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	availabilityrepo "gamelink/internal/repository/availability"
+	playerrepo "gamelink/internal/repository/player"
+)
+
+func newTestService(t *testing.T) (*Service, *gorm.DB, *model.Player) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.PlayerAvailabilitySlot{}, &model.PlayerBlackout{}))
+	player := &model.Player{UserID: 42, Nickname: "p"}
+	require.NoError(t, db.Create(player).Error)
+
+	svc := NewService(availabilityrepo.NewCalendarRepository(db), playerrepo.NewPlayerRepository(db))
+	svc.loc = time.UTC
+	// 2026-10-19 是周一
+	svc.now = func() time.Time { return time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC) }
+	return svc, db, player
+}
+
+func TestSetWeeklyValidation(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.SetWeekly(ctx, 42, SetWeeklyRequest{Slots: []WeeklySlot{{Weekday: 1, Start: "12:00", End: "10:00"}}})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.SetWeekly(ctx, 42, SetWeeklyRequest{Slots: []WeeklySlot{{Weekday: 1, Start: "25:00", End: "26:00"}}})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.SetWeekly(ctx, 42, SetWeeklyRequest{Slots: []WeeklySlot{
+		{Weekday: 1, Start: "10:00", End: "12:00"},
+		{Weekday: 1, Start: "11:00", End: "13:00"},
+	}})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.SetWeekly(ctx, 7, SetWeeklyRequest{})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	resp, err := svc.SetWeekly(ctx, 42, SetWeeklyRequest{Slots: []WeeklySlot{
+		{Weekday: 2, Start: "20:00", End: "24:00"},
+		{Weekday: 1, Start: "9:30", End: "12:00"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []WeeklySlot{
+		{Weekday: 1, Start: "09:30", End: "12:00"},
+		{Weekday: 2, Start: "20:00", End: "24:00"},
+	}, resp.Weekly)
+}
+
+func TestBookableSlotsAndChecks(t *testing.T) {
+	svc, db, player := newTestService(t)
+	ctx := context.Background()
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC) // 周一
+	at := func(d, h int) time.Time { return day.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour) }
+
+	// 未发布模板时不限制接单时间，只扣除已过去的时间
+	resp, err := svc.ListBookableSlots(ctx, player.ID, day, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, resp.Slots, 1)
+	assert.Equal(t, at(0, 8), resp.Slots[0].Start)
+	assert.Equal(t, at(1, 0), resp.Slots[0].End)
+
+	_, err = svc.SetWeekly(ctx, 42, SetWeeklyRequest{Slots: []WeeklySlot{
+		{Weekday: 1, Start: "10:00", End: "18:00"},
+		{Weekday: 2, Start: "20:00", End: "24:00"},
+		{Weekday: 3, Start: "00:00", End: "02:00"},
+	}})
+	require.NoError(t, err)
+	_, err = svc.AddBlackout(ctx, 42, BlackoutRequest{StartAt: at(0, 12), EndAt: at(0, 13), Reason: "午休"})
+	require.NoError(t, err)
+	pid := player.ID
+	booked := &model.Order{UserID: 1, PlayerID: &pid, Status: model.OrderStatusConfirmed, ScheduledStart: ptr(at(0, 15)), ScheduledEnd: ptr(at(0, 16))}
+	require.NoError(t, db.Create(booked).Error)
+
+	resp, err = svc.ListBookableSlots(ctx, player.ID, day, day.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	assert.Equal(t, []BookableSlot{
+		{Start: at(0, 10), End: at(0, 12)},
+		{Start: at(0, 13), End: at(0, 15)},
+		{Start: at(0, 16), End: at(0, 18)},
+		// 跨零点的相邻时段合并
+		{Start: at(1, 20), End: at(2, 2)},
+	}, resp.Slots)
+
+	assert.NoError(t, svc.CheckBookable(ctx, player.ID, at(0, 10), at(0, 12), 0))
+	assert.NoError(t, svc.CheckBookable(ctx, player.ID, at(1, 23), at(2, 1), 0))
+	assert.ErrorIs(t, svc.CheckBookable(ctx, player.ID, at(0, 8), at(0, 10), 0), ErrUnavailable)
+	assert.ErrorIs(t, svc.CheckBookable(ctx, player.ID, at(0, 11), at(0, 13), 0), ErrUnavailable)
+	assert.ErrorIs(t, svc.CheckBookable(ctx, player.ID, at(0, 14), at(0, 16), 0), ErrBookingConflict)
+	// 校验订单自身时排除该订单
+	assert.NoError(t, svc.CheckBookable(ctx, player.ID, at(0, 15), at(0, 16), booked.ID))
+
+	// 管理员指派只校验订单冲突
+	assert.NoError(t, svc.CheckConflict(ctx, player.ID, at(0, 8), at(0, 10), 0))
+	assert.ErrorIs(t, svc.CheckConflict(ctx, player.ID, at(0, 15), at(0, 17), 0), ErrBookingConflict)
+
+	_, err = svc.ListBookableSlots(ctx, player.ID, day, day.AddDate(0, 0, 40))
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func ptr(t time.Time) *time.Time { return &t }
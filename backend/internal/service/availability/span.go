@@ -0,0 +1,84 @@
+package availability
+
+import (
+	"sort"
+	"time"
+)
+
+// span 半开时间区间 [start, end)
+type span struct {
+	start time.Time
+	end   time.Time
+}
+
+// merge 排序并合并重叠或相邻的区间
+func merge(spans []span) []span {
+	if len(spans) == 0 {
+		return nil
+	}
+	sorted := append([]span(nil), spans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start.Before(sorted[j].start) })
+	out := []span{sorted[0]}
+	for _, sp := range sorted[1:] {
+		last := &out[len(out)-1]
+		if !sp.start.After(last.end) {
+			if sp.end.After(last.end) {
+				last.end = sp.end
+			}
+			continue
+		}
+		out = append(out, sp)
+	}
+	return out
+}
+
+// clip 截取落在 [from, to) 内的部分
+func clip(spans []span, from, to time.Time) []span {
+	var out []span
+	for _, sp := range spans {
+		if sp.start.Before(from) {
+			sp.start = from
+		}
+		if sp.end.After(to) {
+			sp.end = to
+		}
+		if sp.end.After(sp.start) {
+			out = append(out, sp)
+		}
+	}
+	return out
+}
+
+// subtract 从已合并的 open 中扣除已合并的 busy
+func subtract(open, busy []span) []span {
+	var out []span
+	for _, sp := range open {
+		cur := sp
+		for _, b := range busy {
+			if !b.end.After(cur.start) || !b.start.Before(cur.end) {
+				continue
+			}
+			if b.start.After(cur.start) {
+				out = append(out, span{start: cur.start, end: b.start})
+			}
+			cur.start = b.end
+			if !cur.end.After(cur.start) {
+				break
+			}
+		}
+		if cur.end.After(cur.start) {
+			out = append(out, cur)
+		}
+	}
+	return out
+}
+
+// covers 判断已合并的 spans 是否完整覆盖 [start, end)
+func covers(spans []span, start, end time.Time) bool {
+	for _, sp := range spans {
+		if !sp.start.After(start) && !sp.end.Before(end) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,11 +3,13 @@ package chat
 import (
     "testing"
     "context"
+    "fmt"
     "time"
 
     "gamelink/internal/cache"
     "gamelink/internal/model"
     "gamelink/internal/repository"
+    uploadservice "gamelink/internal/service/upload"
 )
 
 type gRepo struct{ g model.ChatGroup }
@@ -145,10 +147,38 @@ func TestSendMessage_WithImage(t *testing.T) {
     _ = mem.Add(context.Background(), &model.ChatGroupMember{GroupID:1, UserID:1, IsActive:true})
     msg := msgRepo{}
     s := NewChatService(grp, mem, msg, &repRepo{}, cache.NewMemory())
+    imageID, docID := uint64(7), uint64(8)
+    // 未注入上传服务时不接受图片
+    if _, err := s.SendMessage(context.Background(), SendMessageInput{GroupID:1, SenderID:1, ImageUploadID:&imageID}); err != ErrInvalidImage { t.Fatalf("expected ErrInvalidImage, got %v", err) }
+    s.SetAttachments(fakeAttachments{imageID: "image/png", docID: "application/pdf"})
     // send message with image but no content
-    m, err := s.SendMessage(context.Background(), SendMessageInput{GroupID:1, SenderID:1, Content:"", ImageURL:"http://example.com/img.jpg"})
+    m, err := s.SendMessage(context.Background(), SendMessageInput{GroupID:1, SenderID:1, Content:"", ImageUploadID:&imageID})
     if err != nil { t.Fatalf("%v", err) }
     if m == nil { t.Fatalf("expected message") }
+    if m.ImageURL != "/signed/7" { t.Fatalf("expected signed image url, got %q", m.ImageURL) }
+    for _, id := range []uint64{docID, 9} {
+        id := id
+        if _, err := s.SendMessage(context.Background(), SendMessageInput{GroupID:1, SenderID:1, ImageUploadID:&id}); err != ErrInvalidImage { t.Fatalf("upload %d: expected ErrInvalidImage, got %v", id, err) }
+    }
+}
+
+// fakeAttachments maps upload IDs owned by user 1 to their MIME types.
+type fakeAttachments map[uint64]string
+
+func (f fakeAttachments) Attach(_ context.Context, ownerID uint64, ids []uint64) ([]model.Upload, error) {
+    out := make([]model.Upload, 0, len(ids))
+    for _, id := range ids {
+        mime, ok := f[id]
+        if !ok || ownerID != 1 { return nil, uploadservice.ErrInvalidAttachment }
+        out = append(out, model.Upload{ID: id, UserID: ownerID, MimeType: mime})
+    }
+    return out, nil
+}
+
+func (f fakeAttachments) URLs(_ context.Context, ids []uint64) (map[uint64]string, error) {
+    urls := map[uint64]string{}
+    for _, id := range ids { urls[id] = fmt.Sprintf("/signed/%d", id) }
+    return urls, nil
 }
 
 func TestLeaveGroup_NotMember(t *testing.T) {
@@ -0,0 +1,145 @@
+package chat
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// EventType enumerates real-time chat events pushed to connected clients.
+type EventType string
+
+// Supported chat event types.
+const (
+	EventMessageNew       EventType = "message.new"
+	EventMessageAudit     EventType = "message.audit"
+	EventMessageDelivered EventType = "message.delivered"
+	EventMessageRead      EventType = "message.read"
+	EventMemberJoined     EventType = "member.joined"
+	EventMemberLeft       EventType = "member.left"
+	EventTyping           EventType = "typing"
+	EventPresence         EventType = "presence"
+)
+
+// Event is a real-time chat event. UserID is the user the event is about
+// (sender, reader, typist, member or the user whose presence changed).
+type Event struct {
+	Type        EventType                    `json:"type"`
+	GroupID     uint64                       `json:"groupId,omitempty"`
+	UserID      uint64                       `json:"userId,omitempty"`
+	MessageID   uint64                       `json:"messageId,omitempty"`
+	Message     *model.ChatMessage           `json:"message,omitempty"`
+	AuditStatus model.ChatMessageAuditStatus `json:"auditStatus,omitempty"`
+	Online      *bool                        `json:"online,omitempty"`
+	At          time.Time                    `json:"at"`
+}
+
+// EventPublisher delivers events to the connected recipients on every server instance
+// (implemented by the chat gateway hub).
+type EventPublisher interface {
+	Publish(ctx context.Context, recipients []uint64, event Event) error
+}
+
+// SetEvents injects the publisher used to push chat events in real time.
+func (s *ChatService) SetEvents(p EventPublisher) { s.events = p }
+
+// publish pushes an event; delivery is best effort and failures are only logged.
+func (s *ChatService) publish(ctx context.Context, recipients []uint64, event Event) {
+	if s.events == nil || len(recipients) == 0 {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	if err := s.events.Publish(ctx, recipients, event); err != nil {
+		slog.Warn("publish chat event failed", slog.String("type", string(event.Type)), slog.Uint64("group_id", event.GroupID), slog.String("error", err.Error()))
+	}
+}
+
+// publishToGroup pushes an event to every active member of the group.
+func (s *ChatService) publishToGroup(ctx context.Context, groupID uint64, event Event) {
+	if s.events == nil {
+		return
+	}
+	recipients, err := s.groupMembers(ctx, groupID)
+	if err != nil {
+		slog.Warn("list chat members failed", slog.Uint64("group_id", groupID), slog.String("error", err.Error()))
+		return
+	}
+	event.GroupID = groupID
+	s.publish(ctx, recipients, event)
+}
+
+// groupMembers returns the user IDs of the group's active members.
+func (s *ChatService) groupMembers(ctx context.Context, groupID uint64) ([]uint64, error) {
+	var ids []uint64
+	for page := 1; ; page++ {
+		members, total, err := s.groups.ListMembers(ctx, groupID, repository.ChatGroupMemberListOptions{Page: page, PageSize: 100})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			if m.IsActive {
+				ids = append(ids, m.UserID)
+			}
+		}
+		if len(members) == 0 || int64(page*100) >= total {
+			return ids, nil
+		}
+	}
+}
+
+// Contacts returns the users sharing an active group with the user, who are told about the user's presence.
+func (s *ChatService) Contacts(ctx context.Context, userID uint64) ([]uint64, error) {
+	seen := map[uint64]struct{}{userID: {}}
+	var contacts []uint64
+	for page := 1; ; page++ {
+		groups, total, err := s.groups.ListByUser(ctx, userID, repository.ChatGroupListOptions{Page: page, PageSize: 100})
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			members, err := s.groupMembers(ctx, g.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range members {
+				if _, ok := seen[id]; !ok {
+					seen[id] = struct{}{}
+					contacts = append(contacts, id)
+				}
+			}
+		}
+		if len(groups) == 0 || int64(page*100) >= total {
+			return contacts, nil
+		}
+	}
+}
+
+// Typing tells the other members of the group that the user is typing.
+func (s *ChatService) Typing(ctx context.Context, groupID, userID uint64) error {
+	if _, err := s.EnsureMembership(ctx, groupID, userID); err != nil {
+		return err
+	}
+	s.publishToGroup(ctx, groupID, Event{Type: EventTyping, UserID: userID})
+	return nil
+}
+
+// MarkDelivered acknowledges that the user's client received a message; the receipt is pushed but not stored.
+func (s *ChatService) MarkDelivered(ctx context.Context, groupID, userID, messageID uint64) error {
+	if _, err := s.EnsureMembership(ctx, groupID, userID); err != nil {
+		return err
+	}
+	msg, err := s.messages.Get(ctx, messageID)
+	if err != nil {
+		return err
+	}
+	if msg.GroupID != groupID {
+		return ErrNotFound
+	}
+	s.publish(ctx, []uint64{msg.SenderID}, Event{Type: EventMessageDelivered, GroupID: groupID, UserID: userID, MessageID: messageID})
+	return nil
+}
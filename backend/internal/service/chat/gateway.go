@@ -0,0 +1,264 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gamelink/internal/cache"
+)
+
+// Presence timing: connected clients refresh their presence key every PresenceHeartbeat,
+// and a user whose key has not been refreshed for presenceTTL counts as offline.
+const (
+	PresenceHeartbeat = 30 * time.Second
+	presenceTTL       = 75 * time.Second
+)
+
+// eventsChannel is the pub/sub channel shared by all gateway instances.
+const eventsChannel = "chat:events"
+
+// clientBuffer is the number of outgoing frames queued per connection; slower clients are dropped.
+const clientBuffer = 64
+
+// ErrUnknownFrame is returned for client frames the gateway does not understand.
+var ErrUnknownFrame = errors.New("chat: unknown frame type")
+
+// envelope carries an event and its recipients between gateway instances.
+type envelope struct {
+	Recipients []uint64 `json:"recipients"`
+	Event      Event    `json:"event"`
+}
+
+// Frame is a message sent by a WebSocket client.
+type Frame struct {
+	Type      string   `json:"type"` // typing / delivered / read / presence / ping
+	GroupID   uint64   `json:"groupId,omitempty"`
+	MessageID uint64   `json:"messageId,omitempty"`
+	UserIDs   []uint64 `json:"userIds,omitempty"` // presence query
+}
+
+// errorFrame reports a failed client frame back to the client.
+type errorFrame struct {
+	Type  string `json:"type"`
+	Frame string `json:"frame"`
+	Error string `json:"error"`
+}
+
+// Client is one WebSocket connection registered with the hub.
+type Client struct {
+	UserID uint64
+	send   chan []byte
+	closed bool
+}
+
+// Send returns the outgoing frames of the connection; it is closed when the hub drops the client.
+func (c *Client) Send() <-chan []byte { return c.send }
+
+// Hub is the real-time chat gateway. It delivers events to the clients connected to this
+// instance and relays them to the other instances over the cache's pub/sub (Redis in
+// production, in-process for the memory cache). Presence is kept in the cache.
+type Hub struct {
+	chat  *ChatService
+	bus   cache.PubSub
+	cache cache.Cache
+
+	mu      sync.Mutex
+	clients map[uint64]map[*Client]struct{}
+}
+
+// NewHub creates the gateway hub; call Run to start receiving events.
+func NewHub(chat *ChatService, bus cache.PubSub, c cache.Cache) *Hub {
+	return &Hub{chat: chat, bus: bus, cache: c, clients: make(map[uint64]map[*Client]struct{})}
+}
+
+// Publish implements EventPublisher by broadcasting the event to every gateway instance.
+func (h *Hub) Publish(ctx context.Context, recipients []uint64, event Event) error {
+	payload, err := json.Marshal(envelope{Recipients: recipients, Event: event})
+	if err != nil {
+		return err
+	}
+	return h.bus.Publish(ctx, eventsChannel, payload)
+}
+
+// Run subscribes to the events channel and delivers events to local clients until ctx is done.
+func (h *Hub) Run(ctx context.Context) error {
+	messages, cancel, err := h.bus.Subscribe(ctx, eventsChannel)
+	if err != nil {
+		return fmt.Errorf("subscribe chat events: %w", err)
+	}
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case payload, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var env envelope
+			if err := json.Unmarshal(payload, &env); err != nil {
+				slog.Warn("decode chat event failed", slog.String("error", err.Error()))
+				continue
+			}
+			h.deliver(env)
+		}
+	}
+}
+
+// deliver queues the event on the recipients' local connections.
+func (h *Hub) deliver(env envelope) {
+	frame, err := json.Marshal(env.Event)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, userID := range env.Recipients {
+		for c := range h.clients[userID] {
+			h.queue(c, frame)
+		}
+	}
+}
+
+// queue sends a frame to a client, dropping the client when its buffer is full. Callers hold h.mu.
+func (h *Hub) queue(c *Client, frame []byte) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- frame:
+	default:
+		h.remove(c)
+	}
+}
+
+// remove unregisters a client and closes its send channel. Callers hold h.mu.
+func (h *Hub) remove(c *Client) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.send)
+	conns := h.clients[c.UserID]
+	delete(conns, c)
+	if len(conns) == 0 {
+		delete(h.clients, c.UserID)
+	}
+}
+
+// Connect registers a new connection of the user and marks the user online.
+func (h *Hub) Connect(ctx context.Context, userID uint64) *Client {
+	c := &Client{UserID: userID, send: make(chan []byte, clientBuffer)}
+	h.mu.Lock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*Client]struct{})
+	}
+	h.clients[userID][c] = struct{}{}
+	h.mu.Unlock()
+	h.Heartbeat(ctx, userID)
+	return c
+}
+
+// Disconnect unregisters a connection; the user goes offline when it was their last one on this instance.
+func (h *Hub) Disconnect(ctx context.Context, c *Client) {
+	h.mu.Lock()
+	h.remove(c)
+	_, connected := h.clients[c.UserID]
+	h.mu.Unlock()
+	if connected {
+		return
+	}
+	_ = h.cache.Delete(ctx, presenceKey(c.UserID))
+	h.publishPresence(ctx, c.UserID, false)
+}
+
+// Heartbeat refreshes the user's presence; contacts are told when the user comes online.
+// Connections on other instances re-announce the user after another instance's last connection closed.
+func (h *Hub) Heartbeat(ctx context.Context, userID uint64) {
+	_, online, _ := h.cache.Get(ctx, presenceKey(userID))
+	if err := h.cache.Set(ctx, presenceKey(userID), "1", presenceTTL); err != nil {
+		slog.Warn("refresh chat presence failed", slog.Uint64("user_id", userID), slog.String("error", err.Error()))
+		return
+	}
+	if !online {
+		h.publishPresence(ctx, userID, true)
+	}
+}
+
+// Online reports which of the users are connected to any gateway instance.
+func (h *Hub) Online(ctx context.Context, userIDs []uint64) map[uint64]bool {
+	result := make(map[uint64]bool, len(userIDs))
+	for _, id := range userIDs {
+		_, ok, _ := h.cache.Get(ctx, presenceKey(id))
+		result[id] = ok
+	}
+	return result
+}
+
+func (h *Hub) publishPresence(ctx context.Context, userID uint64, online bool) {
+	contacts, err := h.chat.Contacts(ctx, userID)
+	if err != nil {
+		slog.Warn("list chat contacts failed", slog.Uint64("user_id", userID), slog.String("error", err.Error()))
+		return
+	}
+	h.chat.publish(ctx, contacts, Event{Type: EventPresence, UserID: userID, Online: &online})
+}
+
+// Handle processes a frame sent by the client; failures are reported back to the client.
+func (h *Hub) Handle(ctx context.Context, c *Client, data []byte) {
+	var frame Frame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		h.reply(c, errorFrame{Type: "error", Error: err.Error()})
+		return
+	}
+	if err := h.handle(ctx, c, frame); err != nil {
+		h.reply(c, errorFrame{Type: "error", Frame: frame.Type, Error: err.Error()})
+	}
+}
+
+func (h *Hub) handle(ctx context.Context, c *Client, frame Frame) error {
+	switch frame.Type {
+	case "ping":
+		h.Heartbeat(ctx, c.UserID)
+		return nil
+	case "typing":
+		return h.chat.Typing(ctx, frame.GroupID, c.UserID)
+	case "delivered":
+		return h.chat.MarkDelivered(ctx, frame.GroupID, c.UserID, frame.MessageID)
+	case "read":
+		if frame.MessageID == 0 {
+			return ErrNotFound
+		}
+		if _, err := h.chat.EnsureMembership(ctx, frame.GroupID, c.UserID); err != nil {
+			return err
+		}
+		return h.chat.MarkRead(ctx, frame.GroupID, c.UserID, frame.MessageID)
+	case "presence":
+		now := time.Now()
+		for id, online := range h.Online(ctx, frame.UserIDs) {
+			h.reply(c, Event{Type: EventPresence, UserID: id, Online: &online, At: now})
+		}
+		return nil
+	default:
+		return ErrUnknownFrame
+	}
+}
+
+func (h *Hub) reply(c *Client, v any) {
+	frame, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	h.queue(c, frame)
+	h.mu.Unlock()
+}
+
+func presenceKey(userID uint64) string {
+	return fmt.Sprintf("chat:presence:u:%d", userID)
+}
@@ -0,0 +1,169 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/cache"
+	"gamelink/internal/model"
+	chatrepo "gamelink/internal/repository/chat"
+)
+
+type gatewayEnv struct {
+	svc   *ChatService
+	hub   *Hub
+	db    *gorm.DB
+	group *model.ChatGroup
+}
+
+// newGatewayEnv 两个成员（用户 1、2）的订单群，网关已启动
+func newGatewayEnv(t *testing.T) *gatewayEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.ChatGroup{}, &model.ChatGroupMember{}, &model.ChatMessage{}, &model.ChatReport{}))
+
+	group := &model.ChatGroup{GroupName: "order", GroupType: model.ChatGroupTypeOrder, CreatedBy: 1, IsActive: true, Settings: "{}"}
+	require.NoError(t, db.Create(group).Error)
+	for _, uid := range []uint64{1, 2} {
+		require.NoError(t, db.Create(&model.ChatGroupMember{GroupID: group.ID, UserID: uid, JoinedAt: time.Now(), IsActive: true}).Error)
+	}
+
+	mem := cache.NewMemory()
+	svc := NewChatService(chatrepo.NewChatGroupRepository(db), chatrepo.NewChatMemberRepository(db),
+		chatrepo.NewChatMessageRepository(db), chatrepo.NewChatReportRepository(db), mem)
+	hub := NewHub(svc, mem.(cache.PubSub), mem)
+	svc.SetEvents(hub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = hub.Run(ctx) }()
+	// 等待网关完成订阅：向探针连接投递事件直到收到
+	probe := hub.Connect(ctx, 999)
+	require.Eventually(t, func() bool {
+		require.NoError(t, hub.Publish(ctx, []uint64{999}, Event{Type: EventPresence}))
+		select {
+		case <-probe.Send():
+			return true
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+	hub.Disconnect(ctx, probe)
+	return &gatewayEnv{svc: svc, hub: hub, db: db, group: group}
+}
+
+// next 读取下一条指定类型的事件，跳过其他事件
+func next(t *testing.T, c *Client, typ EventType) Event {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case frame := <-c.Send():
+			var event Event
+			require.NoError(t, json.Unmarshal(frame, &event))
+			if event.Type == typ {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", typ)
+		}
+	}
+}
+
+func TestHub_PushesMessagesAndReceipts(t *testing.T) {
+	e := newGatewayEnv(t)
+	ctx := context.Background()
+	alice := e.hub.Connect(ctx, 1)
+	bob := e.hub.Connect(ctx, 2)
+
+	msg, err := e.svc.SendMessage(ctx, SendMessageInput{GroupID: e.group.ID, SenderID: 1, Content: "hi", MessageType: model.ChatMessageTypeText})
+	require.NoError(t, err)
+	event := next(t, bob, EventMessageNew)
+	require.NotNil(t, event.Message)
+	assert.Equal(t, "hi", event.Message.Content)
+	next(t, alice, EventMessageNew)
+
+	e.hub.Handle(ctx, bob, []byte(`{"type":"typing","groupId":`+itoa(e.group.ID)+`}`))
+	assert.Equal(t, uint64(2), next(t, alice, EventTyping).UserID)
+
+	e.hub.Handle(ctx, bob, []byte(`{"type":"delivered","groupId":`+itoa(e.group.ID)+`,"messageId":`+itoa(msg.ID)+`}`))
+	delivered := next(t, alice, EventMessageDelivered)
+	assert.Equal(t, msg.ID, delivered.MessageID)
+
+	e.hub.Handle(ctx, bob, []byte(`{"type":"read","groupId":`+itoa(e.group.ID)+`,"messageId":`+itoa(msg.ID)+`}`))
+	read := next(t, alice, EventMessageRead)
+	assert.Equal(t, uint64(2), read.UserID)
+	var member model.ChatGroupMember
+	require.NoError(t, e.db.Where("group_id = ? AND user_id = ?", e.group.ID, 2).First(&member).Error)
+	require.NotNil(t, member.LastReadMessageID)
+	assert.Equal(t, msg.ID, *member.LastReadMessageID)
+
+	e.hub.Handle(ctx, bob, []byte(`{"type":"typing","groupId":999}`))
+	var failed errorFrame
+	for failed.Type != "error" {
+		select {
+		case frame := <-bob.Send():
+			require.NoError(t, json.Unmarshal(frame, &failed))
+		case <-time.After(time.Second):
+			t.Fatal("no error frame")
+		}
+	}
+	assert.Equal(t, "typing", failed.Frame)
+	assert.NotEmpty(t, failed.Error)
+}
+
+func TestHub_PresenceFollowsConnections(t *testing.T) {
+	e := newGatewayEnv(t)
+	ctx := context.Background()
+	alice := e.hub.Connect(ctx, 1)
+
+	bob := e.hub.Connect(ctx, 2)
+	event := next(t, alice, EventPresence)
+	assert.Equal(t, uint64(2), event.UserID)
+	require.NotNil(t, event.Online)
+	assert.True(t, *event.Online)
+
+	second := e.hub.Connect(ctx, 2)
+	e.hub.Disconnect(ctx, bob)
+	assert.True(t, e.hub.Online(ctx, []uint64{2})[2], "still connected on another tab")
+
+	e.hub.Disconnect(ctx, second)
+	event = next(t, alice, EventPresence)
+	assert.False(t, *event.Online)
+	assert.False(t, e.hub.Online(ctx, []uint64{2})[2])
+	_, open := <-second.Send()
+	assert.False(t, open)
+}
+
+func TestHub_PendingMessagesOnlyReachSender(t *testing.T) {
+	e := newGatewayEnv(t)
+	ctx := context.Background()
+	require.NoError(t, e.db.Model(e.group).Update("group_type", model.ChatGroupTypePublic).Error)
+	alice := e.hub.Connect(ctx, 1)
+	bob := e.hub.Connect(ctx, 2)
+
+	msg, err := e.svc.SendMessage(ctx, SendMessageInput{GroupID: e.group.ID, SenderID: 1, Content: "hi", MessageType: model.ChatMessageTypeText})
+	require.NoError(t, err)
+	assert.Equal(t, model.ChatMessageAuditPending, next(t, alice, EventMessageNew).Message.AuditStatus)
+
+	require.NoError(t, e.svc.ApproveMessage(ctx, msg.ID, 9))
+	audit := next(t, bob, EventMessageAudit)
+	assert.Equal(t, model.ChatMessageAuditApproved, audit.AuditStatus)
+	assert.Equal(t, msg.ID, audit.Message.ID)
+}
+
+func itoa(v uint64) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
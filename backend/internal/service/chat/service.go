@@ -9,6 +9,7 @@ import (
 	"gamelink/internal/cache"
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	uploadservice "gamelink/internal/service/upload"
 )
 
 // Errors specific to chat domain.
@@ -18,6 +19,7 @@ var (
 	ErrInactiveGroup   = errors.New("chat: group is inactive")
 	ErrMessageTooLarge = errors.New("chat: message exceeds length limit")
 	ErrThrottled       = errors.New("chat: message throttled, please wait")
+	ErrInvalidImage    = errors.New("chat: image must be an uploaded image owned by the sender")
 )
 
 // SendMessageInput represents payload for sending chat messages.
@@ -27,20 +29,56 @@ type SendMessageInput struct {
 	Content     string
 	MessageType model.ChatMessageType
 	ReplyToID   *uint64
-	ImageURL    string
+	// ImageUploadID references an image the sender uploaded beforehand.
+	ImageUploadID *uint64
 }
 
-// ApproveMessage sets audit status to approved.
+// Attachments resolves uploaded files referenced by messages (implemented by the upload service).
+type Attachments interface {
+	Attach(ctx context.Context, ownerID uint64, ids []uint64) ([]model.Upload, error)
+	URLs(ctx context.Context, ids []uint64) (map[uint64]string, error)
+}
+
+// ApproveMessage sets audit status to approved and pushes the message to the group.
 func (s *ChatService) ApproveMessage(ctx context.Context, messageID uint64, moderatorID uint64) error {
-	return s.messages.UpdateAuditStatus(ctx, messageID, model.ChatMessageAuditApproved, &moderatorID, "")
+	if err := s.messages.UpdateAuditStatus(ctx, messageID, model.ChatMessageAuditApproved, &moderatorID, ""); err != nil {
+		return err
+	}
+	s.publishAudit(ctx, messageID)
+	return nil
 }
 
-// RejectMessage sets audit status to rejected with reason.
+// RejectMessage sets audit status to rejected with reason and tells the sender.
 func (s *ChatService) RejectMessage(ctx context.Context, messageID uint64, moderatorID uint64, reason string) error {
 	if reason == "" {
 		reason = "rejected"
 	}
-	return s.messages.UpdateAuditStatus(ctx, messageID, model.ChatMessageAuditRejected, &moderatorID, reason)
+	if err := s.messages.UpdateAuditStatus(ctx, messageID, model.ChatMessageAuditRejected, &moderatorID, reason); err != nil {
+		return err
+	}
+	s.publishAudit(ctx, messageID)
+	return nil
+}
+
+// publishAudit pushes an audit status change: approved messages go to the whole group,
+// rejected ones only to their sender.
+func (s *ChatService) publishAudit(ctx context.Context, messageID uint64) {
+	if s.events == nil {
+		return
+	}
+	msg, err := s.messages.Get(ctx, messageID)
+	if err != nil {
+		return
+	}
+	if err := s.signImages(ctx, msg); err != nil {
+		return
+	}
+	event := Event{Type: EventMessageAudit, GroupID: msg.GroupID, UserID: msg.SenderID, MessageID: msg.ID, Message: msg, AuditStatus: msg.AuditStatus}
+	if msg.AuditStatus == model.ChatMessageAuditApproved {
+		s.publishToGroup(ctx, msg.GroupID, event)
+		return
+	}
+	s.publish(ctx, []uint64{msg.SenderID}, event)
 }
 
 // ReportMessage creates a report record for moderation.
@@ -79,6 +117,9 @@ type ChatService struct {
 	messages repository.ChatMessageRepository
 	reports  repository.ChatReportRepository
 	cache    cache.Cache
+
+	attachments Attachments
+	events      EventPublisher
 }
 
 // NewChatService constructs a ChatService instance.
@@ -98,6 +139,9 @@ func NewChatService(
 	}
 }
 
+// SetAttachments injects the upload store used for image messages.
+func (s *ChatService) SetAttachments(a Attachments) { s.attachments = a }
+
 // ListUserGroups returns groups joined by the user with pagination.
 func (s *ChatService) ListUserGroups(ctx context.Context, userID uint64, page, pageSize int) ([]model.ChatGroup, int64, error) {
 	if page < 1 {
@@ -166,12 +210,19 @@ func (s *ChatService) ListMessages(ctx context.Context, userID, groupID uint64,
 	if err != nil {
 		return nil, 0, fmt.Errorf("list chat messages: %w", err)
 	}
+	ptrs := make([]*model.ChatMessage, 0, len(messages))
+	for i := range messages {
+		ptrs = append(ptrs, &messages[i])
+	}
+	if err := s.signImages(ctx, ptrs...); err != nil {
+		return nil, 0, err
+	}
 	return messages, total, nil
 }
 
 // SendMessage persists chat message and returns saved entity.
 func (s *ChatService) SendMessage(ctx context.Context, input SendMessageInput) (*model.ChatMessage, error) {
-	if input.Content == "" && input.ImageURL == "" {
+	if input.Content == "" && input.ImageUploadID == nil {
 		return nil, ErrMessageTooLarge
 	}
 	if len([]rune(input.Content)) > 2000 {
@@ -189,6 +240,11 @@ func (s *ChatService) SendMessage(ctx context.Context, input SendMessageInput) (
 	if !group.IsActive {
 		return nil, ErrInactiveGroup
 	}
+	if input.ImageUploadID != nil {
+		if err := s.checkImage(ctx, input.SenderID, *input.ImageUploadID); err != nil {
+			return nil, err
+		}
+	}
 
 	// 公共群发言限流
 	if group.GroupType == model.ChatGroupTypePublic {
@@ -201,13 +257,13 @@ func (s *ChatService) SendMessage(ctx context.Context, input SendMessageInput) (
 	}
 
 	msg := &model.ChatMessage{
-		GroupID:     input.GroupID,
-		SenderID:    input.SenderID,
-		Content:     input.Content,
-		MessageType: input.MessageType,
-		ReplyToID:   input.ReplyToID,
-		ImageURL:    input.ImageURL,
-		Metadata:    "{}",
+		GroupID:       input.GroupID,
+		SenderID:      input.SenderID,
+		Content:       input.Content,
+		MessageType:   input.MessageType,
+		ReplyToID:     input.ReplyToID,
+		ImageUploadID: input.ImageUploadID,
+		Metadata:      "{}",
 	}
 
 	// 公共群消息默认 pending，订单群直接 approved（如需严格也可全部 pending）
@@ -220,10 +276,65 @@ func (s *ChatService) SendMessage(ctx context.Context, input SendMessageInput) (
 	if err := s.messages.Create(ctx, msg); err != nil {
 		return nil, fmt.Errorf("create chat message: %w", err)
 	}
+	if err := s.signImages(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	// 待审核消息只推送给发送者，审核通过后再推送给全群
+	event := Event{Type: EventMessageNew, GroupID: msg.GroupID, UserID: msg.SenderID, MessageID: msg.ID, Message: msg}
+	if msg.AuditStatus == model.ChatMessageAuditApproved {
+		s.publishToGroup(ctx, msg.GroupID, event)
+	} else {
+		s.publish(ctx, []uint64{msg.SenderID}, event)
+	}
 
 	return msg, nil
 }
 
+// checkImage verifies the referenced upload is an image owned by the sender.
+func (s *ChatService) checkImage(ctx context.Context, senderID, uploadID uint64) error {
+	if s.attachments == nil {
+		return ErrInvalidImage
+	}
+	uploads, err := s.attachments.Attach(ctx, senderID, []uint64{uploadID})
+	if errors.Is(err, uploadservice.ErrInvalidAttachment) {
+		return ErrInvalidImage
+	}
+	if err != nil {
+		return fmt.Errorf("attach chat image: %w", err)
+	}
+	if !uploads[0].IsImage() {
+		return ErrInvalidImage
+	}
+	return nil
+}
+
+// signImages fills ImageURL of image messages with a fresh signed download URL.
+func (s *ChatService) signImages(ctx context.Context, messages ...*model.ChatMessage) error {
+	if s.attachments == nil {
+		return nil
+	}
+	var ids []uint64
+	for _, m := range messages {
+		if m.ImageUploadID != nil {
+			ids = append(ids, *m.ImageUploadID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	urls, err := s.attachments.URLs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("sign chat images: %w", err)
+	}
+	for _, m := range messages {
+		if m.ImageUploadID != nil {
+			m.ImageURL = urls[*m.ImageUploadID]
+		}
+	}
+	return nil
+}
+
 // JoinGroup marks user as active member of group (creates if needed).
 func (s *ChatService) JoinGroup(ctx context.Context, groupID, userID uint64, nickname string) error {
 	group, err := s.groups.Get(ctx, groupID)
@@ -245,7 +356,11 @@ func (s *ChatService) JoinGroup(ctx context.Context, groupID, userID uint64, nic
 				JoinedAt: time.Now(),
 				IsActive: true,
 			}
-			return s.members.Add(ctx, m)
+			if err := s.members.Add(ctx, m); err != nil {
+				return err
+			}
+			s.publishToGroup(ctx, groupID, Event{Type: EventMemberJoined, UserID: userID})
+			return nil
 		}
 		return fmt.Errorf("get chat member: %w", err)
 	}
@@ -253,7 +368,11 @@ func (s *ChatService) JoinGroup(ctx context.Context, groupID, userID uint64, nic
 	// Reactivate existing member.
 	member.IsActive = true
 	member.Nickname = nickname
-	return s.members.Update(ctx, member)
+	if err := s.members.Update(ctx, member); err != nil {
+		return err
+	}
+	s.publishToGroup(ctx, groupID, Event{Type: EventMemberJoined, UserID: userID})
+	return nil
 }
 
 // LeaveGroup marks the user's membership as inactive.
@@ -266,10 +385,18 @@ func (s *ChatService) LeaveGroup(ctx context.Context, groupID, userID uint64) er
 		return fmt.Errorf("get chat member: %w", err)
 	}
 	member.IsActive = false
-	return s.members.Update(ctx, member)
+	if err := s.members.Update(ctx, member); err != nil {
+		return err
+	}
+	event := Event{Type: EventMemberLeft, UserID: userID}
+	s.publishToGroup(ctx, groupID, event)
+	event.GroupID = groupID
+	s.publish(ctx, []uint64{userID}, event)
+	return nil
 }
 
-// MarkRead updates last read pointer for membership.
+// MarkRead advances the member's last read pointer and pushes a read receipt to the group.
+// Older message IDs than the current pointer are ignored.
 func (s *ChatService) MarkRead(ctx context.Context, groupID, userID, messageID uint64) error {
 	member, err := s.members.Get(ctx, groupID, userID)
 	if err != nil {
@@ -278,8 +405,15 @@ func (s *ChatService) MarkRead(ctx context.Context, groupID, userID, messageID u
 		}
 		return fmt.Errorf("get chat member: %w", err)
 	}
+	if member.LastReadMessageID != nil && *member.LastReadMessageID >= messageID {
+		return nil
+	}
 	member.LastReadMessageID = &messageID
 	now := time.Now()
 	member.LastReadAt = &now
-	return s.members.Update(ctx, member)
+	if err := s.members.Update(ctx, member); err != nil {
+		return err
+	}
+	s.publishToGroup(ctx, groupID, Event{Type: EventMessageRead, UserID: userID, MessageID: messageID, At: now})
+	return nil
 }
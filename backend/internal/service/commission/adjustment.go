@@ -0,0 +1,385 @@
+package commission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gamelink/internal/model"
+	commissionrepo "gamelink/internal/repository/commission"
+	"gamelink/internal/repository/common"
+)
+
+// ErrAdjustmentsNotConfigured 未注入抽成调整仓储
+var ErrAdjustmentsNotConfigured = errors.New("commission adjustment repository not configured")
+
+// SetAdjustments 注入抽成调整仓储，争议裁决可按责任冲减陪玩师收入
+func (s *CommissionService) SetAdjustments(repo commissionrepo.AdjustmentRepository) {
+	s.adjustments = repo
+}
+
+// SetTxManager 注入事务管理器，调整、抽成记录、钱包与总账在同一事务内更新
+func (s *CommissionService) SetTxManager(tx TxManager) { s.tx = tx }
+
+// DisputeSplit 争议退款的责任拆分
+type DisputeSplit struct {
+	DisputeID uint64
+	OrderID   uint64
+	// RefundCents 退还用户的金额
+	RefundCents int64
+	// PlayerDeductionCents 由陪玩师承担、从其收入中扣除的部分，其余由平台承担
+	PlayerDeductionCents int64
+	Reason               string
+}
+
+// ApplyDisputeSplit 按责任拆分争议退款，返回生成的调整。
+//
+// 订单尚未记录抽成时退款直接冲减预收账款，不生成调整，也不能扣陪玩师收入。已记录抽成时，
+// 陪玩师承担的部分按收入比例分摊到各条抽成记录：记录未月结时直接减少收入与钱包待结算，
+// 已月结时结转到下一次月结扣除；其余部分记为平台赔付。
+// r 非空时在调用方事务内执行（如与争议退款单一起提交）；抽成记录加锁重读，不会覆盖并发的月结。
+func (s *CommissionService) ApplyDisputeSplit(ctx context.Context, r *common.Repos, split DisputeSplit) ([]model.CommissionAdjustment, error) {
+	if split.DisputeID == 0 || split.OrderID == 0 || split.RefundCents < 0 ||
+		split.PlayerDeductionCents < 0 || split.PlayerDeductionCents > split.RefundCents {
+		return nil, ErrValidation
+	}
+	if s.adjustments == nil {
+		return nil, ErrAdjustmentsNotConfigured
+	}
+
+	orderID := split.OrderID
+	now := time.Now()
+	var adjs []model.CommissionAdjustment
+	err := s.inTx(ctx, r, func(r *common.Repos) error {
+		records, err := lockOrderRecords(ctx, r.Commissions, orderID)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			if split.PlayerDeductionCents > 0 {
+				return fmt.Errorf("%w: order %d has no player income to deduct", ErrValidation, orderID)
+			}
+			return nil
+		}
+		shares, err := deductionShares(records, split.PlayerDeductionCents)
+		if err != nil {
+			return err
+		}
+
+		for i := range records {
+			if shares[i] == 0 {
+				continue
+			}
+			record := &records[i]
+			recordID := record.ID
+			adj := model.CommissionAdjustment{
+				DisputeID:   split.DisputeID,
+				OrderID:     orderID,
+				RecordID:    &recordID,
+				PlayerID:    record.PlayerID,
+				Kind:        model.CommissionAdjustmentPlayerDeduction,
+				AmountCents: shares[i],
+				Currency:    record.Currency.OrDefault(),
+				Reason:      split.Reason,
+			}
+			if record.SettlementStatus == "settled" {
+				adj.Status = model.CommissionAdjustmentPending
+				adj.CarryOver = true
+				adj.SettlementMonth = now.Format("2006-01")
+			} else {
+				adj.Status = model.CommissionAdjustmentApplied
+				adj.SettlementMonth = record.SettlementMonth
+				adj.AppliedAt = &now
+				record.PlayerIncomeCents -= shares[i]
+				if err := r.Commissions.UpdateRecord(ctx, record); err != nil {
+					return err
+				}
+			}
+			if err := r.Adjustments.Create(ctx, &adj); err != nil {
+				return err
+			}
+			if !adj.CarryOver && s.wallet != nil {
+				if err := s.wallet.DeductIncome(ctx, r, &adj, false); err != nil {
+					return fmt.Errorf("deduct wallet for record %d: %w", record.ID, err)
+				}
+			}
+			if err := s.postAdjustment(ctx, r, &adj); err != nil {
+				return err
+			}
+			adjs = append(adjs, adj)
+		}
+
+		if compensation := split.RefundCents - split.PlayerDeductionCents; compensation > 0 {
+			adj := model.CommissionAdjustment{
+				DisputeID:       split.DisputeID,
+				OrderID:         orderID,
+				Kind:            model.CommissionAdjustmentPlatformCompensation,
+				AmountCents:     compensation,
+				Currency:        records[0].Currency.OrDefault(),
+				Status:          model.CommissionAdjustmentApplied,
+				SettlementMonth: now.Format("2006-01"),
+				Reason:          split.Reason,
+				AppliedAt:       &now,
+			}
+			if err := r.Adjustments.Create(ctx, &adj); err != nil {
+				return err
+			}
+			if err := s.postAdjustment(ctx, r, &adj); err != nil {
+				return err
+			}
+			adjs = append(adjs, adj)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return adjs, nil
+}
+
+// RevertDisputeSplit 撤销争议产生的全部调整：退回陪玩师扣款并冲销凭证；已撤销的调整跳过。
+func (s *CommissionService) RevertDisputeSplit(ctx context.Context, disputeID uint64) error {
+	if disputeID == 0 {
+		return ErrValidation
+	}
+	if s.adjustments == nil {
+		return ErrAdjustmentsNotConfigured
+	}
+	adjs, err := s.adjustments.ListByDispute(ctx, disputeID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.withTx(ctx, func(r *common.Repos) error {
+		for i := range adjs {
+			adj := &adjs[i]
+			if adj.Status == model.CommissionAdjustmentReverted {
+				continue
+			}
+			if adj.Kind == model.CommissionAdjustmentPlayerDeduction {
+				if err := s.restoreDeduction(ctx, r, adj); err != nil {
+					return err
+				}
+			}
+			if s.ledger != nil {
+				if err := s.ledger.ReverseCommissionAdjustment(ctx, r, adj); err != nil {
+					return fmt.Errorf("reverse ledger for adjustment %d: %w", adj.ID, err)
+				}
+			}
+			adj.Status = model.CommissionAdjustmentReverted
+			adj.RevertedAt = &now
+			if err := r.Adjustments.Update(ctx, adj); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// restoreDeduction 按扣款生效的位置退回：待结转的直接作废；已在月结中扣除的退回该期结算与可提现；
+// 直接冲减抽成记录的恢复记录收入，记录此后已月结时一并调整当期结算并退回可提现。
+func (s *CommissionService) restoreDeduction(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment) error {
+	if adj.Status == model.CommissionAdjustmentPending {
+		return nil
+	}
+
+	var settlement *model.MonthlySettlement
+	settled := true
+	if adj.CarryOver {
+		if adj.SettlementID == nil {
+			return fmt.Errorf("adjustment %d has no settlement", adj.ID)
+		}
+		var err error
+		if settlement, err = r.Commissions.GetSettlement(ctx, *adj.SettlementID); err != nil {
+			return err
+		}
+		settlement.DeductionCents -= adj.AmountCents
+	} else {
+		if adj.RecordID == nil {
+			return fmt.Errorf("adjustment %d has no commission record", adj.ID)
+		}
+		record, err := r.Commissions.GetRecord(ctx, *adj.RecordID)
+		if err != nil {
+			return err
+		}
+		if record, err = lockRecord(ctx, r.Commissions, record); err != nil {
+			return err
+		}
+		record.PlayerIncomeCents += adj.AmountCents
+		if err := r.Commissions.UpdateRecord(ctx, record); err != nil {
+			return err
+		}
+		settled = record.SettlementStatus == "settled"
+		if settled {
+			if settlement, err = findSettlement(ctx, r.Commissions, record.PlayerID, record.SettlementMonth, record.Currency); err != nil {
+				return err
+			}
+			if settlement != nil {
+				settlement.TotalIncomeCents += adj.AmountCents
+			}
+		}
+	}
+	if settlement != nil {
+		settlement.FinalIncomeCents += adj.AmountCents
+		if err := r.Commissions.UpdateSettlement(ctx, settlement); err != nil {
+			return err
+		}
+	}
+
+	if s.wallet != nil {
+		if err := s.wallet.RestoreIncome(ctx, r, adj, settled); err != nil {
+			return fmt.Errorf("restore wallet for adjustment %d: %w", adj.ID, err)
+		}
+	}
+	return nil
+}
+
+// applyCarryOver 从本期结算中扣除结转的争议扣款。
+//
+// 陪玩师本期没有结算、结算收入不足以扣除或钱包扣款失败时，继续结转到下一次月结。
+func (s *CommissionService) applyCarryOver(ctx context.Context, month string, settlements map[settleKey]*model.MonthlySettlement) {
+	if s.adjustments == nil {
+		return
+	}
+	carried, err := s.adjustments.ListCarryOver(ctx, month)
+	if err != nil {
+		slog.Warn("list carried commission adjustments failed", slog.String("month", month), slog.String("error", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	for i := range carried {
+		adj := carried[i]
+		settlement, ok := settlements[settleKey{playerID: adj.PlayerID, currency: adj.Currency.OrDefault()}]
+		if !ok || settlement.FinalIncomeCents < adj.AmountCents {
+			continue
+		}
+		updated := *settlement
+		err := s.withTx(ctx, func(r *common.Repos) error {
+			updated.DeductionCents += adj.AmountCents
+			updated.FinalIncomeCents -= adj.AmountCents
+			if err := r.Commissions.UpdateSettlement(ctx, &updated); err != nil {
+				return err
+			}
+			adj.Status = model.CommissionAdjustmentApplied
+			adj.SettlementID = &updated.ID
+			adj.SettlementMonth = month
+			adj.AppliedAt = &now
+			if err := r.Adjustments.Update(ctx, &adj); err != nil {
+				return err
+			}
+			if s.wallet != nil {
+				return s.wallet.DeductIncome(ctx, r, &adj, true)
+			}
+			return nil
+		})
+		if err != nil {
+			slog.Warn("apply carried commission adjustment failed",
+				slog.Uint64("adjustment_id", adj.ID), slog.String("month", month), slog.String("error", err.Error()))
+			continue
+		}
+		*settlement = updated
+	}
+}
+
+func (s *CommissionService) postAdjustment(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment) error {
+	if s.ledger == nil {
+		return nil
+	}
+	if err := s.ledger.PostCommissionAdjustment(ctx, r, adj); err != nil {
+		return fmt.Errorf("post ledger for adjustment %d: %w", adj.ID, err)
+	}
+	return nil
+}
+
+// deductionShares 按收入比例把扣款分摊到各条抽成记录，尾差计入最后一条有收入的记录。
+func deductionShares(records []model.CommissionRecord, amount int64) ([]int64, error) {
+	var income int64
+	last := -1
+	for i, r := range records {
+		if r.PlayerIncomeCents > 0 {
+			income += r.PlayerIncomeCents
+			last = i
+		}
+	}
+	if amount > income {
+		return nil, fmt.Errorf("%w: deduction %d exceeds player income %d", ErrValidation, amount, income)
+	}
+	shares := make([]int64, len(records))
+	if amount == 0 {
+		return shares, nil
+	}
+	var assigned int64
+	for i, r := range records {
+		if r.PlayerIncomeCents <= 0 {
+			continue
+		}
+		if i == last {
+			shares[i] = amount - assigned
+			break
+		}
+		shares[i] = amount * r.PlayerIncomeCents / income
+		assigned += shares[i]
+	}
+	return shares, nil
+}
+
+// findSettlement 查找陪玩师某月指定币种的结算，不存在时返回 nil。
+func findSettlement(ctx context.Context, repo commissionrepo.CommissionRepository, playerID uint64, month string, currency model.Currency) (*model.MonthlySettlement, error) {
+	settlements, _, err := repo.ListSettlements(ctx, commissionrepo.SettlementListOptions{
+		PlayerID:        &playerID,
+		SettlementMonth: &month,
+		Page:            1,
+		PageSize:        10,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range settlements {
+		if settlements[i].Currency.OrDefault() == currency.OrDefault() {
+			return &settlements[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// withTx 未注入事务管理器时直接使用服务自身的仓储（各步骤不在同一事务内）
+// inTx 优先使用调用方事务内的仓储。
+func (s *CommissionService) inTx(ctx context.Context, r *common.Repos, fn func(r *common.Repos) error) error {
+	if r != nil && r.Commissions != nil && r.Adjustments != nil {
+		return fn(r)
+	}
+	return s.withTx(ctx, fn)
+}
+
+// lockOrderRecords 在事务内按 ID 顺序加锁重读订单的抽成记录。
+func lockOrderRecords(ctx context.Context, repo commissionrepo.CommissionRepository, orderID uint64) ([]model.CommissionRecord, error) {
+	records, _, err := repo.ListRecords(ctx, commissionrepo.CommissionRecordListOptions{
+		OrderID:  &orderID,
+		Page:     1,
+		PageSize: 100,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	for i := range records {
+		locked, err := lockRecord(ctx, repo, &records[i])
+		if err != nil {
+			return nil, err
+		}
+		records[i] = *locked
+	}
+	return records, nil
+}
+
+func (s *CommissionService) withTx(ctx context.Context, fn func(r *common.Repos) error) error {
+	if s.tx != nil {
+		return s.tx.WithTx(ctx, fn)
+	}
+	return fn(&common.Repos{Commissions: s.commissions, Adjustments: s.adjustments, Orders: s.orders})
+}
@@ -0,0 +1,178 @@
+package commission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	commissionrepo "gamelink/internal/repository/commission"
+	"gamelink/internal/repository/common"
+	ledgerrepo "gamelink/internal/repository/ledger"
+	walletrepo "gamelink/internal/repository/wallet"
+	"gamelink/internal/service/ledger"
+	"gamelink/internal/service/wallet"
+)
+
+type adjustmentEnv struct {
+	db      *gorm.DB
+	svc     *CommissionService
+	repo    commissionrepo.CommissionRepository
+	wallet  *wallet.WalletService
+	ledger  *ledger.LedgerService
+	adjusts commissionrepo.AdjustmentRepository
+}
+
+func newAdjustmentEnv(t *testing.T) *adjustmentEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(
+		&model.Order{}, &model.CommissionRecord{}, &model.MonthlySettlement{}, &model.CommissionAdjustment{},
+		&model.PlayerWallet{}, &model.WalletTransaction{},
+		&model.FinancialAccount{}, &model.FinancialVoucher{}, &model.FinancialVoucherEntry{}, &model.FinancialTransaction{}, &model.FinancialPeriod{},
+	))
+	uow := common.NewUnitOfWork(db)
+
+	walletSvc := wallet.NewWalletService(walletrepo.NewWalletRepository(db))
+	walletSvc.SetTxManager(uow)
+	ledgerSvc := ledger.NewLedgerService(ledgerrepo.NewLedgerRepository(db))
+	ledgerSvc.SetTxManager(uow)
+	require.NoError(t, ledgerSvc.EnsureSystemAccounts(context.Background()))
+
+	repo := commissionrepo.NewCommissionRepository(db)
+	adjusts := commissionrepo.NewAdjustmentRepository(db)
+	svc := NewCommissionService(repo, nil, nil)
+	svc.SetWallet(walletSvc)
+	svc.SetLedger(ledgerSvc)
+	svc.SetAdjustments(adjusts)
+	svc.SetTxManager(uow)
+	return &adjustmentEnv{db: db, svc: svc, repo: repo, wallet: walletSvc, ledger: ledgerSvc, adjusts: adjusts}
+}
+
+// record 记录订单抽成并与 RecordCommission 一样记账、记入钱包。
+func (e *adjustmentEnv) record(t *testing.T, orderID uint64, income int64, month string) *model.CommissionRecord {
+	t.Helper()
+	ctx := context.Background()
+	rec := &model.CommissionRecord{OrderID: orderID, PlayerID: 7, TotalAmountCents: income + 2000, CommissionCents: 2000,
+		PlayerIncomeCents: income, SettlementStatus: "pending", SettlementMonth: month}
+	require.NoError(t, e.repo.CreateRecord(ctx, rec))
+	require.NoError(t, e.ledger.PostCommission(ctx, nil, rec))
+	require.NoError(t, e.wallet.CreditIncome(ctx, nil, rec))
+	return rec
+}
+
+func (e *adjustmentEnv) balances(t *testing.T) (w *model.PlayerWallet, accounts map[string]int64) {
+	t.Helper()
+	ctx := context.Background()
+	w, err := e.wallet.GetWallet(ctx, 7, model.CurrencyCNY)
+	require.NoError(t, err)
+	list, err := e.ledger.ListAccounts(ctx)
+	require.NoError(t, err)
+	accounts = make(map[string]int64, len(list))
+	for _, a := range list {
+		accounts[a.Code] = a.CurrentBalance
+	}
+	return w, accounts
+}
+
+func TestApplyDisputeSplit_DeductsPendingRecordAndReverts(t *testing.T) {
+	e := newAdjustmentEnv(t)
+	ctx := context.Background()
+	rec := e.record(t, 10, 8000, time.Now().Format("2006-01"))
+
+	_, err := e.svc.ApplyDisputeSplit(ctx, nil, DisputeSplit{DisputeID: 1, OrderID: 10, RefundCents: 2000, PlayerDeductionCents: 3000})
+	assert.ErrorIs(t, err, ErrValidation, "deduction cannot exceed the refund")
+	_, err = e.svc.ApplyDisputeSplit(ctx, nil, DisputeSplit{DisputeID: 1, OrderID: 10, RefundCents: 9000, PlayerDeductionCents: 9000})
+	assert.ErrorIs(t, err, ErrValidation, "deduction cannot exceed the player income")
+	_, err = e.svc.ApplyDisputeSplit(ctx, nil, DisputeSplit{DisputeID: 1, OrderID: 99, RefundCents: 1000, PlayerDeductionCents: 500})
+	assert.ErrorIs(t, err, ErrValidation, "no commission to deduct from")
+
+	adjs, err := e.svc.ApplyDisputeSplit(ctx, nil, DisputeSplit{DisputeID: 1, OrderID: 10, RefundCents: 5000, PlayerDeductionCents: 3000, Reason: "late"})
+	require.NoError(t, err)
+	require.Len(t, adjs, 2)
+	assert.Equal(t, model.CommissionAdjustmentPlayerDeduction, adjs[0].Kind)
+	assert.Equal(t, int64(3000), adjs[0].AmountCents)
+	assert.Equal(t, model.CommissionAdjustmentApplied, adjs[0].Status)
+	assert.Equal(t, model.CommissionAdjustmentPlatformCompensation, adjs[1].Kind)
+	assert.Equal(t, int64(2000), adjs[1].AmountCents)
+
+	stored, err := e.repo.GetRecord(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), stored.PlayerIncomeCents)
+	w, accounts := e.balances(t)
+	assert.Equal(t, int64(5000), w.PendingCents)
+	assert.Equal(t, int64(5000), w.TotalIncomeCents)
+	assert.Equal(t, int64(5000), accounts[model.FinancialAccountPlayerPayable])
+	assert.Equal(t, int64(2000), accounts[model.FinancialAccountCompensation])
+
+	require.NoError(t, e.svc.RevertDisputeSplit(ctx, 1))
+	require.NoError(t, e.svc.RevertDisputeSplit(ctx, 1), "reverting twice is a no-op")
+
+	stored, err = e.repo.GetRecord(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8000), stored.PlayerIncomeCents)
+	w, accounts = e.balances(t)
+	assert.Equal(t, int64(8000), w.PendingCents)
+	assert.Equal(t, int64(8000), w.TotalIncomeCents)
+	assert.Equal(t, int64(8000), accounts[model.FinancialAccountPlayerPayable])
+	assert.Zero(t, accounts[model.FinancialAccountCompensation])
+	reverted, err := e.adjusts.ListByDispute(ctx, 1)
+	require.NoError(t, err)
+	for _, adj := range reverted {
+		assert.Equal(t, model.CommissionAdjustmentReverted, adj.Status)
+	}
+}
+
+func TestApplyDisputeSplit_CarriesIntoNextSettlement(t *testing.T) {
+	e := newAdjustmentEnv(t)
+	ctx := context.Background()
+	e.record(t, 10, 8000, "2020-01")
+	require.NoError(t, e.svc.SettleMonth(ctx, "2020-01"))
+
+	adjs, err := e.svc.ApplyDisputeSplit(ctx, nil, DisputeSplit{DisputeID: 1, OrderID: 10, RefundCents: 3000, PlayerDeductionCents: 3000})
+	require.NoError(t, err)
+	require.Len(t, adjs, 1)
+	assert.True(t, adjs[0].CarryOver)
+	assert.Equal(t, model.CommissionAdjustmentPending, adjs[0].Status)
+	w, _ := e.balances(t)
+	assert.Equal(t, int64(8000), w.AvailableCents, "settled income is untouched until the next settlement")
+
+	month := time.Now().Format("2006-01")
+	e.record(t, 11, 4000, month)
+	require.NoError(t, e.svc.SettleMonth(ctx, month))
+
+	settlements, _, err := e.repo.ListSettlements(ctx, commissionrepo.SettlementListOptions{SettlementMonth: &month, Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, settlements, 1)
+	assert.Equal(t, int64(3000), settlements[0].DeductionCents)
+	assert.Equal(t, int64(1000), settlements[0].FinalIncomeCents)
+	w, _ = e.balances(t)
+	assert.Equal(t, int64(8000+4000-3000), w.AvailableCents)
+
+	require.NoError(t, e.svc.RevertDisputeSplit(ctx, 1))
+	settlements, _, err = e.repo.ListSettlements(ctx, commissionrepo.SettlementListOptions{SettlementMonth: &month, Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	assert.Zero(t, settlements[0].DeductionCents)
+	assert.Equal(t, int64(4000), settlements[0].FinalIncomeCents)
+	w, _ = e.balances(t)
+	assert.Equal(t, int64(12000), w.AvailableCents)
+}
+
+func TestDeductionShares_ProportionalWithRemainder(t *testing.T) {
+	records := []model.CommissionRecord{{PlayerIncomeCents: 3000}, {PlayerIncomeCents: 0}, {PlayerIncomeCents: 6000}}
+	shares, err := deductionShares(records, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{333, 0, 667}, shares)
+
+	_, err = deductionShares(records, 9001)
+	assert.ErrorIs(t, err, ErrValidation)
+}
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math"
+	"sort"
 	"time"
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
 	commissionrepo "gamelink/internal/repository/commission"
+	"gamelink/internal/repository/common"
+	"gamelink/internal/service/orderstate"
 )
 
 var (
@@ -29,6 +33,54 @@ type CommissionService struct {
 	commissions commissionrepo.CommissionRepository
 	orders      repository.OrderRepository
 	players     repository.PlayerRepository
+	ledger      CommissionLedger
+	wallet      CommissionWallet
+	fx          CurrencyConverter
+	splitter    IncomeSplitter
+	items       ServiceItemReader
+	adjustments commissionrepo.AdjustmentRepository
+	tx          TxManager
+	credit      CreditTiers
+}
+
+// ServiceItemReader 读取订单的服务项目（由服务项目仓储实现）。
+type ServiceItemReader interface {
+	Get(ctx context.Context, id uint64) (*model.ServiceItem, error)
+}
+
+// IncomeSplitter 车队订单的抽成记录按出车成员拆分（由车队服务实现）。
+type IncomeSplitter interface {
+	SplitCommission(ctx context.Context, order *model.Order, record *model.CommissionRecord) ([]*model.CommissionRecord, error)
+}
+
+// CurrencyConverter 按汇率换算金额（由汇率服务实现）。
+type CurrencyConverter interface {
+	Convert(ctx context.Context, amountCents int64, from, to model.Currency) (int64, error)
+}
+
+// CommissionLedger 订单完成后确认抽成收入与应付陪玩师，争议退款按责任拆分记账（由总账服务实现）。
+type CommissionLedger interface {
+	PostCommission(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error
+	PostCommissionAdjustment(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment) error
+	ReverseCommissionAdjustment(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment) error
+}
+
+// CommissionWallet 抽成确认后记入陪玩师钱包待结算，月结时转入可提现；争议扣款冲减收入（由钱包服务实现）。
+type CommissionWallet interface {
+	CreditIncome(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error
+	SettleIncome(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error
+	DeductIncome(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment, settled bool) error
+	RestoreIncome(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment, settled bool) error
+}
+
+// CreditTiers 查询陪玩师信用等级（由信用服务实现）。
+type CreditTiers interface {
+	TierPolicy(ctx context.Context, playerID uint64) (model.CreditTierPolicy, error)
+}
+
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
 }
 
 // NewCommissionService 创建抽成服务
@@ -44,6 +96,24 @@ func NewCommissionService(
 	}
 }
 
+// SetLedger 注入总账服务，记录抽成时同步记账
+func (s *CommissionService) SetLedger(l CommissionLedger) { s.ledger = l }
+
+// SetWallet 注入钱包服务，记录抽成与月结时同步更新陪玩师钱包
+func (s *CommissionService) SetWallet(w CommissionWallet) { s.wallet = w }
+
+// SetFX 注入汇率服务，平台统计可折算为报表币种
+func (s *CommissionService) SetFX(fx CurrencyConverter) { s.fx = fx }
+
+// SetIncomeSplitter 注入车队收入拆分，车队订单按出车成员分别记录抽成
+func (s *CommissionService) SetIncomeSplitter(sp IncomeSplitter) { s.splitter = sp }
+
+// SetServiceItems 注入服务项目仓储，服务项目抽成参与三层取最低
+func (s *CommissionService) SetServiceItems(items ServiceItemReader) { s.items = items }
+
+// SetCredit 注入信用服务，选定的抽成比例按陪玩师信用等级加减
+func (s *CommissionService) SetCredit(c CreditTiers) { s.credit = c }
+
 // CalculateCommission 计算订单抽成（便捷方法：通过orderID）
 func (s *CommissionService) CalculateCommission(ctx context.Context, orderID uint64) (*CommissionCalculation, error) {
 	// 获取订单
@@ -56,56 +126,128 @@ func (s *CommissionService) CalculateCommission(ctx context.Context, orderID uin
 	return s.CalculateOrderCommission(ctx, order)
 }
 
-// RecordCommission 记录订单抽成
+// RecordCommission 记录订单抽成。
+//
+// 抽成按订单报价时确定的拆分记录（含优惠与加时），未保存拆分的订单按当前规则计算；
+// 抽成记录、总账凭证与钱包入账在同一事务内提交，任一步失败整体回滚，可重新记录。
+// 已记录时返回 ErrAlreadyRecorded。
 func (s *CommissionService) RecordCommission(ctx context.Context, orderID uint64) error {
-	// 1. 检查是否已记录
-	existing, _ := s.commissions.GetRecordByOrderID(ctx, orderID)
-	if existing != nil {
-		return ErrAlreadyRecorded
-	}
+	return s.withTx(ctx, func(r *common.Repos) error {
+		// 锁定订单后再检查，订单完成与补记任务并发记录时只有一方写入
+		order, err := orderstate.Lock(ctx, r.Orders, orderID)
+		if err != nil {
+			return err
+		}
+		if _, err := r.Commissions.GetRecordByOrderID(ctx, orderID); err == nil {
+			return ErrAlreadyRecorded
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		playerID := order.GetPlayerID()
+		if playerID == 0 {
+			return errors.New("order has no player assigned")
+		}
 
-	// 2. 计算抽成
-	calc, err := s.CalculateCommission(ctx, orderID)
-	if err != nil {
-		return err
-	}
+		record := &model.CommissionRecord{
+			OrderID:           orderID,
+			PlayerID:          playerID,
+			TotalAmountCents:  order.TotalPriceCents,
+			CommissionRate:    order.CommissionRate,
+			CommissionCents:   order.CommissionCents,
+			PlayerIncomeCents: order.PlayerIncomeCents,
+			SubsidyCents:      order.SubsidyCents,
+			Currency:          order.Currency.OrDefault(),
+			SettlementStatus:  "pending",
+			SettlementMonth:   time.Now().Format("2006-01"),
+		}
+		if order.CommissionCents == 0 && order.PlayerIncomeCents == 0 {
+			calc, err := s.CalculateOrderCommission(ctx, order)
+			if err != nil {
+				return err
+			}
+			record.CommissionRate = calc.CommissionRate
+			record.CommissionCents = calc.CommissionCents
+			record.PlayerIncomeCents = calc.PlayerIncomeCents
+			record.SubsidyCents = calc.SubsidyCents
+		}
 
-	// 3. 获取订单信息
-	order, err := s.orders.Get(ctx, orderID)
-	if err != nil {
-		return err
-	}
+		records := []*model.CommissionRecord{record}
+		if s.splitter != nil && order.IsTeamOrder() {
+			if records, err = s.splitter.SplitCommission(ctx, order, record); err != nil {
+				return err
+			}
+		}
+		for _, rec := range records {
+			if err := r.Commissions.CreateRecord(ctx, rec); err != nil {
+				return err
+			}
+			if s.ledger != nil {
+				if err := s.ledger.PostCommission(ctx, r, rec); err != nil {
+					return err
+				}
+			}
+			if s.wallet != nil {
+				if err := s.wallet.CreditIncome(ctx, r, rec); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
 
-	// 4. 创建抽成记录
-	now := time.Now()
-	playerID := order.GetPlayerID()
-	if playerID == 0 {
-		return errors.New("order has no player assigned")
+// missingLookback 抽成补记只回看近期完成的订单，历史订单不自动补记
+const missingLookback = 7 * 24 * time.Hour
+
+// RecordMissingCommissions 为近期已完成但尚未记录抽成的订单补记抽成，返回补记的订单数。
+//
+// 订单完成在状态流转提交后才记录抽成，记录失败的订单由此重试；重复执行幂等。
+func (s *CommissionService) RecordMissingCommissions(ctx context.Context, limit int) (int, error) {
+	finder, ok := s.commissions.(commissionrepo.MissingRecordFinder)
+	if !ok {
+		return 0, nil
+	}
+	ids, err := finder.ListCompletedWithoutRecord(ctx, time.Now().Add(-missingLookback), limit)
+	if err != nil {
+		return 0, err
 	}
-	
-	record := &model.CommissionRecord{
-		OrderID:            orderID,
-		PlayerID:           playerID,
-		TotalAmountCents:   calc.TotalAmountCents,
-		CommissionRate:     calc.CommissionRate,
-		CommissionCents:    calc.CommissionCents,
-		PlayerIncomeCents:  calc.PlayerIncomeCents,
-		SettlementStatus:   "pending",
-		SettlementMonth:    now.Format("2006-01"),
+	recorded := 0
+	for _, id := range ids {
+		if err := s.RecordCommission(ctx, id); err != nil {
+			if !errors.Is(err, ErrAlreadyRecorded) {
+				slog.Warn("record missing commission failed", slog.Uint64("order_id", id), slog.String("error", err.Error()))
+			}
+			continue
+		}
+		recorded++
 	}
+	return recorded, nil
+}
 
-	return s.commissions.CreateRecord(ctx, record)
+// lockRecord 在事务内加行锁重新读取抽成记录，读到并发扣款后的最新收入；仓储不支持加锁时沿用 record。
+func lockRecord(ctx context.Context, repo commissionrepo.CommissionRepository, record *model.CommissionRecord) (*model.CommissionRecord, error) {
+	if l, ok := repo.(commissionrepo.RecordLocker); ok {
+		return l.GetRecordForUpdate(ctx, record.ID)
+	}
+	return record, nil
 }
 
-// PlayerMonthStats 玩家月度统计
+// PlayerMonthStats 玩家月度统计（单一币种）
 type PlayerMonthStats struct {
 	PlayerID             uint64
+	Currency             model.Currency
 	OrderCount           int64
 	TotalAmountCents     int64
 	TotalCommissionCents int64
 	TotalIncomeCents     int64
 }
 
+// settleKey 月度结算按陪玩师、币种区分
+type settleKey struct {
+	playerID uint64
+	currency model.Currency
+}
+
 // SettleMonth 月度结算
 func (s *CommissionService) SettleMonth(ctx context.Context, month string) error {
 	// 1. 检查是否已经结算过
@@ -137,13 +279,48 @@ func (s *CommissionService) SettleMonth(ctx context.Context, month string) error
 		return fmt.Errorf("no records to settle for month %s", month)
 	}
 
-	// 3. 按陪玩师分组统计
-	playerStats := make(map[uint64]*PlayerMonthStats)
-	for _, record := range records {
-		stats, exists := playerStats[record.PlayerID]
+	// 3. 逐条结算抽成记录：加锁重读后更新状态并转入钱包可提现，记录与钱包在同一事务内提交
+	now := time.Now()
+	settled := make([]model.CommissionRecord, 0, len(records))
+	for i := range records {
+		var record *model.CommissionRecord
+		err := s.withTx(ctx, func(r *common.Repos) error {
+			var err error
+			if record, err = lockRecord(ctx, r.Commissions, &records[i]); err != nil {
+				return err
+			}
+			if record.SettlementStatus == "settled" {
+				record = nil
+				return nil
+			}
+			record.SettlementStatus = "settled"
+			record.SettledAt = &now
+			if err := r.Commissions.UpdateRecord(ctx, record); err != nil {
+				return err
+			}
+			if s.wallet != nil {
+				if err := s.wallet.SettleIncome(ctx, r, record); err != nil {
+					return fmt.Errorf("settle wallet: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to settle record %d: %w", records[i].ID, err)
+		}
+		if record != nil {
+			settled = append(settled, *record)
+		}
+	}
+
+	// 4. 按陪玩师、币种分组统计：不同币种分别结算，不做换算
+	playerStats := make(map[settleKey]*PlayerMonthStats)
+	for _, record := range settled {
+		key := settleKey{playerID: record.PlayerID, currency: record.Currency.OrDefault()}
+		stats, exists := playerStats[key]
 		if !exists {
-			stats = &PlayerMonthStats{PlayerID: record.PlayerID}
-			playerStats[record.PlayerID] = stats
+			stats = &PlayerMonthStats{PlayerID: key.playerID, Currency: key.currency}
+			playerStats[key] = stats
 		}
 		stats.OrderCount++
 		stats.TotalAmountCents += record.TotalAmountCents
@@ -151,11 +328,13 @@ func (s *CommissionService) SettleMonth(ctx context.Context, month string) error
 		stats.TotalIncomeCents += record.PlayerIncomeCents
 	}
 
-	// 4. 为每个陪玩师创建月度结算记录
-	for _, stats := range playerStats {
+	// 5. 为每个陪玩师创建月度结算记录
+	created := make(map[settleKey]*model.MonthlySettlement, len(playerStats))
+	for key, stats := range playerStats {
 		settlement := &model.MonthlySettlement{
 			PlayerID:             stats.PlayerID,
 			SettlementMonth:      month,
+			Currency:             stats.Currency,
 			TotalOrderCount:      stats.OrderCount,
 			TotalAmountCents:     stats.TotalAmountCents,
 			TotalCommissionCents: stats.TotalCommissionCents,
@@ -167,19 +346,13 @@ func (s *CommissionService) SettleMonth(ctx context.Context, month string) error
 
 		err := s.commissions.CreateSettlement(ctx, settlement)
 		if err != nil {
-			return fmt.Errorf("failed to create settlement for player %d: %w", stats.PlayerID, err)
+			return fmt.Errorf("failed to create %s settlement for player %d: %w", stats.Currency, stats.PlayerID, err)
 		}
+		created[key] = settlement
 	}
 
-	// 5. 更新抽成记录状态
-	now := time.Now()
-	for _, record := range records {
-		record.SettlementStatus = "settled"
-		record.SettledAt = &now
-		if err := s.commissions.UpdateRecord(ctx, &record); err != nil {
-			return fmt.Errorf("failed to update record %d: %w", record.ID, err)
-		}
-	}
+	// 6. 结转的争议扣款从本期结算中扣除
+	s.applyCarryOver(ctx, month, created)
 
 	return nil
 }
@@ -254,6 +427,7 @@ func (s *CommissionService) GetCommissionRecords(ctx context.Context, playerID u
 			CommissionRate:     r.CommissionRate,
 			CommissionCents:    r.CommissionCents,
 			PlayerIncomeCents:  r.PlayerIncomeCents,
+			Currency:           r.Currency.OrDefault(),
 			SettlementStatus:   r.SettlementStatus,
 			SettlementMonth:    r.SettlementMonth,
 			CreatedAt:          r.CreatedAt,
@@ -274,6 +448,7 @@ type CommissionRecordDTO struct {
 	CommissionRate     int       `json:"commissionRate"`
 	CommissionCents    int64     `json:"commissionCents"`
 	PlayerIncomeCents  int64     `json:"playerIncomeCents"`
+	Currency           model.Currency `json:"currency"`
 	SettlementStatus   string    `json:"settlementStatus"`
 	SettlementMonth    string    `json:"settlementMonth"`
 	CreatedAt          time.Time `json:"createdAt"`
@@ -302,6 +477,7 @@ func (s *CommissionService) GetMonthlySettlements(ctx context.Context, playerID
 		settlementDTOs = append(settlementDTOs, SettlementDTO{
 			ID:                   s.ID,
 			SettlementMonth:      s.SettlementMonth,
+			Currency:             s.Currency.OrDefault(),
 			TotalOrderCount:      s.TotalOrderCount,
 			TotalAmountCents:     s.TotalAmountCents,
 			TotalCommissionCents: s.TotalCommissionCents,
@@ -323,8 +499,9 @@ func (s *CommissionService) GetMonthlySettlements(ctx context.Context, playerID
 // SettlementDTO 结算DTO
 type SettlementDTO struct {
 	ID                   uint64     `json:"id"`
-	SettlementMonth      string     `json:"settlementMonth"`
-	TotalOrderCount      int64      `json:"totalOrderCount"`
+	SettlementMonth      string         `json:"settlementMonth"`
+	Currency             model.Currency `json:"currency"`
+	TotalOrderCount      int64          `json:"totalOrderCount"`
 	TotalAmountCents     int64      `json:"totalAmountCents"`
 	TotalCommissionCents int64      `json:"totalCommissionCents"`
 	TotalIncomeCents     int64      `json:"totalIncomeCents"`
@@ -412,7 +589,13 @@ type UpdateCommissionRuleRequest struct {
 }
 
 // GetPlatformStats 获取平台统计（管理员）
-func (s *CommissionService) GetPlatformStats(ctx context.Context, month string) (*PlatformStatsResponse, error) {
+//
+// reportCurrency 为空时直接汇总各币种金额（历史行为，仅适用于单币种数据）；
+// 指定报表币种时按币种分别汇总，再按当前汇率折算合计。
+func (s *CommissionService) GetPlatformStats(ctx context.Context, month string, reportCurrency model.Currency) (*PlatformStatsResponse, error) {
+	if reportCurrency != "" {
+		return s.platformStatsIn(ctx, month, reportCurrency)
+	}
 	stats, err := s.commissions.GetMonthlyStats(ctx, month)
 	if err != nil {
 		return nil, err
@@ -427,13 +610,87 @@ func (s *CommissionService) GetPlatformStats(ctx context.Context, month string)
 	}, nil
 }
 
-// PlatformStatsResponse 平台统计响应
+func (s *CommissionService) platformStatsIn(ctx context.Context, month string, reportCurrency model.Currency) (*PlatformStatsResponse, error) {
+	if !model.IsValidCurrency(reportCurrency) {
+		return nil, fmt.Errorf("%w: unsupported currency %s", ErrValidation, reportCurrency)
+	}
+	status := "settled"
+	records, _, err := s.commissions.ListRecords(ctx, commissionrepo.CommissionRecordListOptions{
+		SettlementMonth:  &month,
+		SettlementStatus: &status,
+		Page:             1,
+		PageSize:         10000,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &PlatformStatsResponse{Month: month, Currency: reportCurrency, ByCurrency: []CurrencyStats{}}
+	index := make(map[model.Currency]int)
+	for _, r := range records {
+		c := r.Currency.OrDefault()
+		i, ok := index[c]
+		if !ok {
+			i = len(resp.ByCurrency)
+			index[c] = i
+			resp.ByCurrency = append(resp.ByCurrency, CurrencyStats{Currency: c})
+		}
+		cs := &resp.ByCurrency[i]
+		cs.TotalOrders++
+		cs.TotalIncome += r.TotalAmountCents
+		cs.TotalCommission += r.CommissionCents
+		cs.TotalPlayerIncome += r.PlayerIncomeCents
+	}
+	sort.Slice(resp.ByCurrency, func(i, j int) bool { return resp.ByCurrency[i].Currency < resp.ByCurrency[j].Currency })
+
+	for _, cs := range resp.ByCurrency {
+		resp.TotalOrders += cs.TotalOrders
+		for _, pair := range []struct {
+			dst    *int64
+			amount int64
+		}{
+			{&resp.TotalIncome, cs.TotalIncome},
+			{&resp.TotalCommission, cs.TotalCommission},
+			{&resp.TotalPlayerIncome, cs.TotalPlayerIncome},
+		} {
+			converted, err := s.convert(ctx, pair.amount, cs.Currency, reportCurrency)
+			if err != nil {
+				return nil, err
+			}
+			*pair.dst += converted
+		}
+	}
+	return resp, nil
+}
+
+func (s *CommissionService) convert(ctx context.Context, amount int64, from, to model.Currency) (int64, error) {
+	if from == to || amount == 0 {
+		return amount, nil
+	}
+	if s.fx == nil {
+		return 0, fmt.Errorf("%w: no exchange rate service for %s/%s", ErrValidation, from, to)
+	}
+	return s.fx.Convert(ctx, amount, from, to)
+}
+
+// PlatformStatsResponse 平台统计响应；指定报表币种时合计为折算值，ByCurrency 为各币种原币金额。
 type PlatformStatsResponse struct {
-	Month             string `json:"month"`
-	TotalOrders       int64  `json:"totalOrders"`
-	TotalIncome       int64  `json:"totalIncome"`
-	TotalCommission   int64  `json:"totalCommission"`
-	TotalPlayerIncome int64  `json:"totalPlayerIncome"`
+	Month             string          `json:"month"`
+	Currency          model.Currency  `json:"currency,omitempty"`
+	TotalOrders       int64           `json:"totalOrders"`
+	TotalIncome       int64           `json:"totalIncome"`
+	TotalCommission   int64           `json:"totalCommission"`
+	TotalPlayerIncome int64           `json:"totalPlayerIncome"`
+	ByCurrency        []CurrencyStats `json:"byCurrency,omitempty"`
+}
+
+// CurrencyStats 单一币种的平台统计
+type CurrencyStats struct {
+	Currency          model.Currency `json:"currency"`
+	TotalOrders       int64          `json:"totalOrders"`
+	TotalIncome       int64          `json:"totalIncome"`
+	TotalCommission   int64          `json:"totalCommission"`
+	TotalPlayerIncome int64          `json:"totalPlayerIncome"`
 }
 
 // ============================================================================
@@ -447,7 +704,7 @@ type PlatformStatsResponse struct {
 // 2. 陪玩师专属抽成 (commission_rules WHERE player_id = ?)
 // 3. 排名抽成 (基于上月排名，前N名享受优惠)
 //
-// 实际抽成 = MIN(服务项目抽成, 陪玩师抽成, 排名抽成)
+// 实际抽成 = MIN(服务项目抽成, 陪玩师抽成, 排名抽成) + 信用等级加减（限制在 0-100）
 //
 // 注意：礼物订单不参与排名优惠
 func (s *CommissionService) CalculateOrderCommission(ctx context.Context, order *model.Order) (*CommissionCalculation, error) {
@@ -455,10 +712,10 @@ func (s *CommissionService) CalculateOrderCommission(ctx context.Context, order
 
 	// 1. 获取服务项目抽成（基础抽成）
 	serviceItem, err := s.getServiceItemForOrder(ctx, order.ItemID)
-	if err == nil && serviceItem != nil {
+	if err == nil && serviceItem != nil && serviceItem.CommissionRate > 0 {
 		candidateRates = append(candidateRates, CommissionCandidate{
 			Source: "服务项目",
-			Rate:   int(serviceItem.CommissionRate * 100),
+			Rate:   int(math.Round(serviceItem.CommissionRate * 100)),
 			Detail: serviceItem.Name,
 		})
 	}
@@ -507,21 +764,36 @@ func (s *CommissionService) CalculateOrderCommission(ctx context.Context, order
 		})
 	}
 
-	// 取最低抽成比例
+	// 取最低抽成比例，再按信用等级加减
 	finalRate := selectLowestRate(candidateRates)
+	rate := finalRate.Rate
+	var tier model.CreditTier
+	var creditDelta int
+	if playerID > 0 && s.credit != nil {
+		policy, err := s.credit.TierPolicy(ctx, playerID)
+		if err != nil {
+			slog.Warn("load credit tier failed", slog.Uint64("player_id", playerID), slog.String("error", err.Error()))
+		} else {
+			tier = policy.Tier
+			rate = policy.AdjustCommissionRate(rate)
+			creditDelta = rate - finalRate.Rate
+		}
+	}
 	totalAmount := order.TotalPriceCents
-	commissionCents := totalAmount * int64(finalRate.Rate) / 100
-	playerIncome := totalAmount - commissionCents
+	commissionCents, playerIncome, subsidy := model.SplitDiscounted(totalAmount, order.DiscountCents, rate, order.DiscountBearer)
 
 	return &CommissionCalculation{
 		OrderID:           order.ID,
 		TotalAmountCents:  totalAmount,
-		CommissionRate:    finalRate.Rate,
+		CommissionRate:    rate,
 		CommissionCents:   commissionCents,
 		PlayerIncomeCents: playerIncome,
+		SubsidyCents:      subsidy,
 		AppliedRule:       finalRate.Source,
 		AppliedRuleDetail: finalRate.Detail,
 		CandidateRates:    candidateRates,
+		CreditTier:        tier,
+		CreditRateDelta:   creditDelta,
 	}, nil
 }
 
@@ -539,9 +811,12 @@ type CommissionCalculation struct {
 	CommissionRate    int                   `json:"commissionRate"`    // 实际使用的抽成比例
 	CommissionCents   int64                 `json:"commissionCents"`   // 平台抽成
 	PlayerIncomeCents int64                 `json:"playerIncomeCents"` // 陪玩师收入
+	SubsidyCents      int64                 `json:"subsidyCents"`      // 平台优惠补贴
 	AppliedRule       string                `json:"appliedRule"`       // 实际应用的规则
 	AppliedRuleDetail string                `json:"appliedRuleDetail"` // 规则详情
 	CandidateRates    []CommissionCandidate `json:"candidateRates"`    // 所有候选抽成
+	CreditTier        model.CreditTier      `json:"creditTier,omitempty"`      // 陪玩师信用等级
+	CreditRateDelta   int                   `json:"creditRateDelta,omitempty"` // 信用等级带来的抽成加减（百分点）
 }
 
 // getRankingCommissionRate 获取陪玩师的排名抽成比例
@@ -575,9 +850,10 @@ func (s *CommissionService) getRankingCommissionRate(ctx context.Context, player
 
 // getServiceItemForOrder 获取订单的服务项
 func (s *CommissionService) getServiceItemForOrder(ctx context.Context, itemID uint64) (*model.ServiceItem, error) {
-	// TODO: 需要注入 ServiceItemRepository
-	// return s.serviceItems.Get(ctx, itemID)
-	return nil, nil // 暂时返回nil
+	if s.items == nil || itemID == 0 {
+		return nil, nil
+	}
+	return s.items.Get(ctx, itemID)
 }
 
 // selectLowestRate 选择最低抽成比例
@@ -207,13 +207,51 @@ func TestCommissionService_GetPlatformStats(t *testing.T) {
 
 	commissionRepo.On("GetMonthlyStats", ctx, "2025-01").Return(stats, nil)
 
-	resp, err := svc.GetPlatformStats(ctx, "2025-01")
+	resp, err := svc.GetPlatformStats(ctx, "2025-01", "")
 	assert.NoError(t, err)
 	assert.Equal(t, stats.TotalOrders, resp.TotalOrders)
 	assert.Equal(t, stats.TotalCommission, resp.TotalCommission)
 	assert.Equal(t, "2025-01", resp.Month)
 }
 
+type fixedRateConverter map[model.Currency]float64
+
+func (f fixedRateConverter) Convert(_ context.Context, amount int64, from, to model.Currency) (int64, error) {
+	return int64(float64(amount) * f[from] / f[to]), nil
+}
+
+func TestCommissionService_GetPlatformStats_ReportCurrency(t *testing.T) {
+	ctx := context.Background()
+	commissionRepo := new(MockCommissionRepo)
+	svc := NewCommissionService(commissionRepo, new(MockOrderRepo), new(MockPlayerRepo))
+
+	commissionRepo.
+		On("ListRecords", ctx, mock.MatchedBy(func(opts commissionrepo.CommissionRecordListOptions) bool {
+			return opts.SettlementMonth != nil && *opts.SettlementMonth == "2025-01"
+		})).
+		Return([]model.CommissionRecord{
+			{TotalAmountCents: 10000, CommissionCents: 2000, PlayerIncomeCents: 8000},
+			{TotalAmountCents: 1000, CommissionCents: 200, PlayerIncomeCents: 800, Currency: model.CurrencyUSD},
+		}, int64(2), nil)
+
+	// 未注入汇率服务时不能折算外币
+	_, err := svc.GetPlatformStats(ctx, "2025-01", model.CurrencyCNY)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	svc.SetFX(fixedRateConverter{model.CurrencyCNY: 1, model.CurrencyUSD: 7})
+	resp, err := svc.GetPlatformStats(ctx, "2025-01", model.CurrencyCNY)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), resp.TotalOrders)
+	assert.Equal(t, int64(17000), resp.TotalIncome)
+	assert.Equal(t, int64(3400), resp.TotalCommission)
+	assert.Len(t, resp.ByCurrency, 2)
+	assert.Equal(t, model.CurrencyUSD, resp.ByCurrency[1].Currency)
+	assert.Equal(t, int64(1000), resp.ByCurrency[1].TotalIncome)
+
+	_, err = svc.GetPlatformStats(ctx, "2025-01", "JPY")
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
 func TestSelectLowestRate(t *testing.T) {
 	defaultCandidate := selectLowestRate(nil)
 	assert.Equal(t, 20, defaultCandidate.Rate)
@@ -340,6 +340,7 @@ func TestCommissionService_RecordCommission(t *testing.T) {
 			OrderID: 1001,
 		}
 
+		orderRepo.On("Get", ctx, uint64(1001)).Return(&model.Order{}, nil)
 		commissionRepo.On("GetRecordByOrderID", ctx, uint64(1001)).
 			Return(existingRecord, nil)
 
@@ -414,6 +415,28 @@ func TestCommissionService_SettleMonth(t *testing.T) {
 		commissionRepo.AssertNumberOfCalls(t, "UpdateRecord", 3)
 	})
 
+	t.Run("不同币种分别结算", func(t *testing.T) {
+		commissionRepo := new(MockCommissionRepo)
+		svc := NewCommissionService(commissionRepo, new(MockOrderRepo), new(MockPlayerRepo))
+		month := "2024-12"
+
+		commissionRepo.On("ListSettlements", ctx, mock.Anything).Return([]model.MonthlySettlement{}, int64(0), nil)
+		commissionRepo.On("ListRecords", ctx, mock.Anything).Return([]model.CommissionRecord{
+			{ID: 1, PlayerID: 5, TotalAmountCents: 50000, CommissionCents: 10000, PlayerIncomeCents: 40000, Currency: model.CurrencyCNY},
+			{ID: 2, PlayerID: 5, TotalAmountCents: 1000, CommissionCents: 200, PlayerIncomeCents: 800, Currency: model.CurrencyUSD},
+		}, int64(2), nil)
+
+		settled := map[model.Currency]int64{}
+		commissionRepo.On("CreateSettlement", ctx, mock.MatchedBy(func(settlement *model.MonthlySettlement) bool {
+			settled[settlement.Currency] = settlement.TotalIncomeCents
+			return true
+		})).Return(nil)
+		commissionRepo.On("UpdateRecord", ctx, mock.AnythingOfType("*model.CommissionRecord")).Return(nil)
+
+		assert.NoError(t, svc.SettleMonth(ctx, month))
+		assert.Equal(t, map[model.Currency]int64{model.CurrencyCNY: 40000, model.CurrencyUSD: 800}, settled)
+	})
+
 	t.Run("月份已经结算过", func(t *testing.T) {
 		commissionRepo := new(MockCommissionRepo)
 		orderRepo := new(MockOrderRepo)
@@ -0,0 +1,123 @@
+package commission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+)
+
+// flakyWallet 钱包入账失败一次
+type flakyWallet struct {
+	CommissionWallet
+	fail bool
+}
+
+func (w *flakyWallet) CreditIncome(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error {
+	if w.fail {
+		w.fail = false
+		return errors.New("wallet unavailable")
+	}
+	return w.CommissionWallet.CreditIncome(ctx, r, record)
+}
+
+func TestRecordCommission_UsesQuotedSplitAtomically(t *testing.T) {
+	e := newAdjustmentEnv(t)
+	ctx := context.Background()
+	playerID := uint64(7)
+	order := &model.Order{UserID: 1, PlayerID: &playerID, Status: model.OrderStatusCompleted, Currency: model.CurrencyCNY,
+		TotalPriceCents: 9000, DiscountCents: 1000, CommissionRate: 15, CommissionCents: 500, PlayerIncomeCents: 8500}
+	require.NoError(t, e.db.Create(order).Error)
+	e.svc.SetWallet(&flakyWallet{CommissionWallet: e.wallet, fail: true})
+
+	// 钱包入账失败时抽成记录与总账凭证一并回滚，之后可重新记录
+	require.Error(t, e.svc.RecordCommission(ctx, order.ID))
+	var count int64
+	require.NoError(t, e.db.Model(&model.CommissionRecord{}).Where("order_id = ?", order.ID).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, e.db.Model(&model.FinancialVoucher{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	require.NoError(t, e.svc.RecordCommission(ctx, order.ID))
+	rec, err := e.repo.GetRecordByOrderID(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 15, rec.CommissionRate)
+	assert.Equal(t, int64(500), rec.CommissionCents)
+	assert.Equal(t, int64(8500), rec.PlayerIncomeCents)
+	w, _ := e.balances(t)
+	assert.Equal(t, int64(8500), w.PendingCents)
+
+	assert.ErrorIs(t, e.svc.RecordCommission(ctx, order.ID), ErrAlreadyRecorded)
+}
+
+func TestRecordMissingCommissions_RecordsRecentCompletedOrdersOnce(t *testing.T) {
+	e := newAdjustmentEnv(t)
+	ctx := context.Background()
+	playerID := uint64(7)
+	now := time.Now()
+	old := now.AddDate(0, 0, -30)
+	newOrder := func(status model.OrderStatus, completedAt *time.Time) *model.Order {
+		o := &model.Order{UserID: 1, PlayerID: &playerID, Status: status, Currency: model.CurrencyCNY,
+			TotalPriceCents: 10000, CommissionRate: 20, CommissionCents: 2000, PlayerIncomeCents: 8000, CompletedAt: completedAt}
+		require.NoError(t, e.db.Create(o).Error)
+		return o
+	}
+	missing := newOrder(model.OrderStatusCompleted, &now)
+	recorded := newOrder(model.OrderStatusCompleted, &now)
+	e.record(t, recorded.ID, 8000, now.Format("2006-01"))
+	historical := newOrder(model.OrderStatusCompleted, &old)
+	inProgress := newOrder(model.OrderStatusInProgress, nil)
+
+	n, err := e.svc.RecordMissingCommissions(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = e.repo.GetRecordByOrderID(ctx, missing.ID)
+	assert.NoError(t, err)
+	for _, id := range []uint64{historical.ID, inProgress.ID} {
+		_, err = e.repo.GetRecordByOrderID(ctx, id)
+		assert.Error(t, err)
+	}
+
+	// 再次执行不重复记录
+	n, err = e.svc.RecordMissingCommissions(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+// settleFailWallet 月结转入可提现失败
+type settleFailWallet struct {
+	CommissionWallet
+}
+
+func (settleFailWallet) SettleIncome(context.Context, *common.Repos, *model.CommissionRecord) error {
+	return errors.New("wallet unavailable")
+}
+
+func TestSettleMonth_RecordStatusAndWalletCommitTogether(t *testing.T) {
+	e := newAdjustmentEnv(t)
+	ctx := context.Background()
+	month := "2025-03"
+	rec := e.record(t, 1, 8000, month)
+
+	// 钱包转入失败时抽成记录保持待结算，可重新月结
+	e.svc.SetWallet(settleFailWallet{CommissionWallet: e.wallet})
+	require.Error(t, e.svc.SettleMonth(ctx, month))
+	got, err := e.repo.GetRecord(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", got.SettlementStatus)
+
+	e.svc.SetWallet(e.wallet)
+	require.NoError(t, e.svc.SettleMonth(ctx, month))
+	got, err = e.repo.GetRecord(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "settled", got.SettlementStatus)
+	w, _ := e.balances(t)
+	assert.Equal(t, int64(8000), w.AvailableCents)
+	assert.Zero(t, w.PendingCents)
+}
@@ -0,0 +1,422 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	couponrepo "gamelink/internal/repository/coupon"
+)
+
+var (
+	// ErrNotFound 优惠券或模板不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrSoldOut 模板发放总量已用完
+	ErrSoldOut = couponrepo.ErrSoldOut
+	// ErrUnavailable 优惠券已使用、已锁定、已过期或模板已停用
+	ErrUnavailable = errors.New("coupon unavailable")
+	// ErrNotApplicable 订单不满足优惠券的使用条件
+	ErrNotApplicable = errors.New("coupon not applicable to this order")
+	// ErrUsageLimit 超过每人使用次数
+	ErrUsageLimit = couponrepo.ErrUsageLimit
+)
+
+// staleLockAfter 下单锁定后超过该时长仍未关联订单的优惠券视为下单失败，自动释放
+const staleLockAfter = 30 * time.Minute
+
+// Service 优惠券服务：模板管理、发放、下单锁定与取消/退款释放
+type Service struct {
+	coupons couponrepo.CouponRepository
+	orders  repository.OrderRepository
+	tx      TxManager
+	now     func() time.Time
+}
+
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
+}
+
+// NewService 创建优惠券服务
+func NewService(coupons couponrepo.CouponRepository, orders repository.OrderRepository) *Service {
+	return &Service{coupons: coupons, orders: orders, now: time.Now}
+}
+
+// SetTxManager 注入事务管理器，下单时锁券、写订单与关联订单在同一事务内完成
+func (s *Service) SetTxManager(tx TxManager) { s.tx = tx }
+
+// TemplateRequest 创建或更新优惠券模板
+type TemplateRequest struct {
+	Name             string             `json:"name" binding:"required,max=128"`
+	Description      string             `json:"description"`
+	Type             model.CouponType   `json:"type" binding:"required,oneof=fixed percent threshold"`
+	AmountCents      int64              `json:"amountCents" binding:"min=0"`
+	Percent          int                `json:"percent" binding:"min=0,max=100"`
+	MaxDiscountCents int64              `json:"maxDiscountCents" binding:"min=0"`
+	ThresholdCents   int64              `json:"thresholdCents" binding:"min=0"`
+	Currency         model.Currency     `json:"currency" binding:"omitempty,oneof=CNY USD EUR"`
+	GameID           *uint64            `json:"gameId"`
+	ItemID           *uint64            `json:"itemId"`
+	NewUserOnly      bool               `json:"newUserOnly"`
+	PerUserLimit     *int               `json:"perUserLimit" binding:"omitempty,min=0"`
+	TotalQuantity    int                `json:"totalQuantity" binding:"min=0"`
+	ValidDays        int                `json:"validDays" binding:"min=0"`
+	ValidUntil       *time.Time         `json:"validUntil"`
+	Bearer           model.CouponBearer `json:"bearer" binding:"omitempty,oneof=platform player"`
+	TriggerEvent     model.CouponEvent  `json:"triggerEvent" binding:"omitempty,oneof=user_registered"`
+	IsActive         *bool              `json:"isActive"`
+}
+
+// IssueRequest 发放优惠券
+type IssueRequest struct {
+	UserIDs []uint64 `json:"userIds" binding:"required,min=1,max=1000"`
+}
+
+// CreateTemplate 创建优惠券模板
+func (s *Service) CreateTemplate(ctx context.Context, req TemplateRequest) (*model.CouponTemplate, error) {
+	tmpl := &model.CouponTemplate{PerUserLimit: 1, IsActive: true}
+	if err := applyTemplate(tmpl, req); err != nil {
+		return nil, err
+	}
+	if err := s.coupons.CreateTemplate(ctx, tmpl); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// UpdateTemplate 更新优惠券模板，已发放的优惠券按新规则使用
+func (s *Service) UpdateTemplate(ctx context.Context, id uint64, req TemplateRequest) (*model.CouponTemplate, error) {
+	tmpl, err := s.coupons.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyTemplate(tmpl, req); err != nil {
+		return nil, err
+	}
+	if tmpl.TotalQuantity > 0 && tmpl.TotalQuantity < tmpl.IssuedCount {
+		return nil, fmt.Errorf("%w: total quantity is below issued count %d", ErrValidation, tmpl.IssuedCount)
+	}
+	if err := s.coupons.UpdateTemplate(ctx, tmpl); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// ListTemplates 分页列出优惠券模板
+func (s *Service) ListTemplates(ctx context.Context, page, pageSize int) ([]model.CouponTemplate, int64, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	return s.coupons.ListTemplates(ctx, page, pageSize)
+}
+
+// Issue 向指定用户发放优惠券，单个用户为手动发放，多个用户为批量发放
+func (s *Service) Issue(ctx context.Context, templateID uint64, userIDs []uint64) ([]model.UserCoupon, error) {
+	if len(userIDs) == 0 {
+		return nil, ErrValidation
+	}
+	tmpl, err := s.coupons.GetTemplate(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if !tmpl.IsActive {
+		return nil, ErrUnavailable
+	}
+	source := model.CouponSourceManual
+	if len(userIDs) > 1 {
+		source = model.CouponSourceBatch
+	}
+	coupons := make([]model.UserCoupon, 0, len(userIDs))
+	for _, userID := range userIDs {
+		coupons = append(coupons, s.newCoupon(tmpl, userID, source))
+	}
+	if err := s.coupons.IssueCoupons(ctx, tmpl.ID, coupons); err != nil {
+		return nil, err
+	}
+	return coupons, nil
+}
+
+// IssueForEvent 按事件自动发券，同一模板每位用户只发放一次；额度用完的模板跳过
+func (s *Service) IssueForEvent(ctx context.Context, event model.CouponEvent, userID uint64) error {
+	templates, err := s.coupons.ListEventTemplates(ctx, event)
+	if err != nil {
+		return err
+	}
+	for i := range templates {
+		tmpl := &templates[i]
+		n, err := s.coupons.CountIssued(ctx, tmpl.ID, userID, model.CouponSourceEvent)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		err = s.coupons.IssueCoupons(ctx, tmpl.ID, []model.UserCoupon{s.newCoupon(tmpl, userID, model.CouponSourceEvent)})
+		if err != nil && !errors.Is(err, ErrSoldOut) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListMyCoupons 列出用户的优惠券
+func (s *Service) ListMyCoupons(ctx context.Context, userID uint64, status model.CouponStatus, page, pageSize int) ([]model.UserCoupon, int64, error) {
+	page, pageSize = normalizePage(page, pageSize)
+	return s.coupons.ListUserCoupons(ctx, userID, status, page, pageSize)
+}
+
+// Redeem 下单时校验并锁定优惠券，按优惠后金额改写订单总价。
+//
+// 锁定为条件更新，同一张券并发下单只有一个成功；每人使用次数在锁定时持模板行锁校验。
+// 下单请使用 Checkout，锁券与订单写入同时提交。
+func (s *Service) Redeem(ctx context.Context, userID, couponID uint64, order *model.Order) error {
+	return s.redeem(ctx, s.coupons, userID, couponID, order)
+}
+
+// Checkout 在同一事务内锁定优惠券、由 create 写入订单并关联订单，任一步失败整体回滚
+func (s *Service) Checkout(ctx context.Context, userID, couponID uint64, order *model.Order,
+	create func(ctx context.Context, orders repository.OrderRepository) error) error {
+	return s.withTx(ctx, func(r *common.Repos) error {
+		if err := s.redeem(ctx, r.Coupons, userID, couponID, order); err != nil {
+			return err
+		}
+		if err := create(ctx, r.Orders); err != nil {
+			return err
+		}
+		return r.Coupons.BindOrder(ctx, couponID, order.ID)
+	})
+}
+
+func (s *Service) redeem(ctx context.Context, coupons couponrepo.CouponRepository, userID, couponID uint64, order *model.Order) error {
+	coupon, err := coupons.GetUserCoupon(ctx, couponID)
+	if err != nil {
+		return err
+	}
+	if coupon.UserID != userID {
+		return ErrNotFound
+	}
+	tmpl := coupon.Template
+	now := s.now()
+	if tmpl == nil || !tmpl.IsActive || coupon.Status != model.CouponStatusAvailable ||
+		(coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(now)) {
+		return ErrUnavailable
+	}
+	discount, err := s.applicable(ctx, tmpl, order)
+	if err != nil {
+		return err
+	}
+	ok, err := coupons.LockCoupon(ctx, couponID, userID, now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnavailable
+	}
+
+	id := couponID
+	order.CouponID = &id
+	order.DiscountCents = discount
+	order.DiscountBearer = tmpl.Bearer
+	order.TotalPriceCents -= discount
+	return nil
+}
+
+// Bind 将锁定的优惠券关联到已创建的订单
+func (s *Service) Bind(ctx context.Context, couponID, orderID uint64) error {
+	return s.coupons.BindOrder(ctx, couponID, orderID)
+}
+
+// Release 释放下单失败时锁定的优惠券
+func (s *Service) Release(ctx context.Context, couponID uint64) error {
+	coupon, err := s.coupons.GetUserCoupon(ctx, couponID)
+	if err != nil {
+		return err
+	}
+	_, err = s.restore(ctx, coupon)
+	return err
+}
+
+// ReleaseForOrder 订单取消或退款后退回优惠券，过期的券直接作废
+func (s *Service) ReleaseForOrder(ctx context.Context, order *model.Order) error {
+	if order.CouponID == nil {
+		return nil
+	}
+	return s.Release(ctx, *order.CouponID)
+}
+
+// Settle 结转优惠券：过期未用的券作废；订单取消/退款的券退回；订单完成的券核销；
+// 锁定后长时间未关联订单的券释放。返回处理的张数。
+func (s *Service) Settle(ctx context.Context, limit int) (int, error) {
+	now := s.now()
+	expired, err := s.coupons.ExpireCoupons(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	coupons, err := s.coupons.ListSettleable(ctx, now.Add(-staleLockAfter), limit)
+	if err != nil {
+		return int(expired), err
+	}
+	n := int(expired)
+	for i := range coupons {
+		coupon := &coupons[i]
+		var ok bool
+		if coupon.OrderID != nil && coupon.Status == model.CouponStatusLocked {
+			order, err := s.orders.Get(ctx, *coupon.OrderID)
+			if err != nil {
+				return n, err
+			}
+			if order.Status == model.OrderStatusCompleted {
+				ok, err = s.coupons.TransitCoupon(ctx, coupon.ID, []model.CouponStatus{model.CouponStatusLocked}, model.CouponStatusUsed, now)
+				if err != nil {
+					return n, err
+				}
+				if ok {
+					n++
+				}
+				continue
+			}
+		}
+		if ok, err = s.restore(ctx, coupon); err != nil {
+			return n, err
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// restore 将锁定或已核销的券退回可用，已过期的券作废
+func (s *Service) restore(ctx context.Context, coupon *model.UserCoupon) (bool, error) {
+	now := s.now()
+	to := model.CouponStatusAvailable
+	if coupon.ExpiresAt != nil && !coupon.ExpiresAt.After(now) {
+		to = model.CouponStatusExpired
+	}
+	return s.coupons.TransitCoupon(ctx, coupon.ID, []model.CouponStatus{model.CouponStatusLocked, model.CouponStatusUsed}, to, now)
+}
+
+// applicable 校验币种、游戏、服务项目、门槛与首单限制，返回可减免金额
+func (s *Service) applicable(ctx context.Context, tmpl *model.CouponTemplate, order *model.Order) (int64, error) {
+	if tmpl.Currency.OrDefault() != order.Currency.OrDefault() {
+		return 0, fmt.Errorf("%w: coupon is only valid for %s orders", ErrNotApplicable, tmpl.Currency.OrDefault())
+	}
+	if tmpl.GameID != nil && (order.GameID == nil || *order.GameID != *tmpl.GameID) {
+		return 0, fmt.Errorf("%w: coupon is limited to another game", ErrNotApplicable)
+	}
+	if tmpl.ItemID != nil && *tmpl.ItemID != order.ItemID {
+		return 0, fmt.Errorf("%w: coupon is limited to another service item", ErrNotApplicable)
+	}
+	discount := tmpl.Discount(order.TotalPriceCents)
+	if discount <= 0 {
+		return 0, fmt.Errorf("%w: order amount is below the coupon threshold", ErrNotApplicable)
+	}
+	// 实付为 0 的订单无法发起支付，不允许优惠抵扣全部金额
+	if discount >= order.TotalPriceCents {
+		return 0, fmt.Errorf("%w: coupon cannot cover the whole order amount", ErrNotApplicable)
+	}
+	if tmpl.NewUserOnly {
+		_, total, err := s.orders.List(ctx, repository.OrderListOptions{
+			Page:     1,
+			PageSize: 1,
+			UserID:   &order.UserID,
+			Statuses: []model.OrderStatus{model.OrderStatusPending, model.OrderStatusConfirmed,
+				model.OrderStatusInProgress, model.OrderStatusPendingConfirmation, model.OrderStatusCompleted},
+		})
+		if err != nil {
+			return 0, err
+		}
+		if total > 0 {
+			return 0, fmt.Errorf("%w: coupon is for first orders only", ErrNotApplicable)
+		}
+	}
+	return discount, nil
+}
+
+func (s *Service) newCoupon(tmpl *model.CouponTemplate, userID uint64, source model.CouponSource) model.UserCoupon {
+	coupon := model.UserCoupon{TemplateID: tmpl.ID, UserID: userID, Status: model.CouponStatusAvailable, Source: source}
+	if tmpl.ValidDays > 0 {
+		expires := s.now().AddDate(0, 0, tmpl.ValidDays)
+		coupon.ExpiresAt = &expires
+	}
+	if tmpl.ValidUntil != nil && (coupon.ExpiresAt == nil || tmpl.ValidUntil.Before(*coupon.ExpiresAt)) {
+		until := *tmpl.ValidUntil
+		coupon.ExpiresAt = &until
+	}
+	return coupon
+}
+
+func applyTemplate(tmpl *model.CouponTemplate, req TemplateRequest) error {
+	tmpl.Name = strings.TrimSpace(req.Name)
+	tmpl.Description = strings.TrimSpace(req.Description)
+	tmpl.Type = req.Type
+	tmpl.AmountCents = req.AmountCents
+	tmpl.Percent = req.Percent
+	tmpl.MaxDiscountCents = req.MaxDiscountCents
+	tmpl.ThresholdCents = req.ThresholdCents
+	tmpl.Currency = req.Currency.OrDefault()
+	tmpl.GameID = req.GameID
+	tmpl.ItemID = req.ItemID
+	tmpl.NewUserOnly = req.NewUserOnly
+	if req.PerUserLimit != nil {
+		tmpl.PerUserLimit = *req.PerUserLimit
+	}
+	tmpl.TotalQuantity = req.TotalQuantity
+	tmpl.ValidDays = req.ValidDays
+	tmpl.ValidUntil = req.ValidUntil
+	tmpl.Bearer = req.Bearer
+	if tmpl.Bearer == "" {
+		tmpl.Bearer = model.CouponBearerPlatform
+	}
+	tmpl.TriggerEvent = req.TriggerEvent
+	if req.IsActive != nil {
+		tmpl.IsActive = *req.IsActive
+	}
+
+	if tmpl.Name == "" || !model.IsValidCurrency(tmpl.Currency) {
+		return ErrValidation
+	}
+	switch tmpl.Type {
+	case model.CouponTypeFixed:
+		if tmpl.AmountCents <= 0 {
+			return fmt.Errorf("%w: amountCents is required", ErrValidation)
+		}
+	case model.CouponTypeThreshold:
+		if tmpl.AmountCents <= 0 || tmpl.ThresholdCents <= tmpl.AmountCents {
+			return fmt.Errorf("%w: thresholdCents must exceed amountCents", ErrValidation)
+		}
+	case model.CouponTypePercent:
+		if tmpl.Percent <= 0 || tmpl.Percent >= 100 {
+			return fmt.Errorf("%w: percent must be between 1 and 99", ErrValidation)
+		}
+	default:
+		return fmt.Errorf("%w: unknown coupon type %s", ErrValidation, tmpl.Type)
+	}
+	if tmpl.Bearer != model.CouponBearerPlatform && tmpl.Bearer != model.CouponBearerPlayer {
+		return fmt.Errorf("%w: unknown bearer %s", ErrValidation, tmpl.Bearer)
+	}
+	return nil
+}
+
+func normalizePage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// withTx 未注入事务管理器时直接使用服务自身的仓储执行
+func (s *Service) withTx(ctx context.Context, fn func(r *common.Repos) error) error {
+	if s.tx != nil {
+		return s.tx.WithTx(ctx, fn)
+	}
+	return fn(&common.Repos{Coupons: s.coupons, Orders: s.orders})
+}
@@ -0,0 +1,251 @@
+package coupon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	couponrepo "gamelink/internal/repository/coupon"
+	orderrepo "gamelink/internal/repository/order"
+)
+
+func newTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	// 内存库每个连接各自独立，并发锁券须共用同一连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Order{}, &model.CouponTemplate{}, &model.UserCoupon{}))
+	return NewService(couponrepo.NewCouponRepository(db), orderrepo.NewOrderRepository(db)), db
+}
+
+func createTemplate(t *testing.T, svc *Service, req TemplateRequest) *model.CouponTemplate {
+	t.Helper()
+	tmpl, err := svc.CreateTemplate(context.Background(), req)
+	require.NoError(t, err)
+	return tmpl
+}
+
+func TestService_CreateTemplateValidation(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.CreateTemplate(ctx, TemplateRequest{Name: "满减", Type: model.CouponTypeThreshold, AmountCents: 500, ThresholdCents: 500})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.CreateTemplate(ctx, TemplateRequest{Name: "折扣", Type: model.CouponTypePercent, Percent: 100})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	tmpl := createTemplate(t, svc, TemplateRequest{Name: "折扣", Type: model.CouponTypePercent, Percent: 10})
+	assert.Equal(t, model.CouponBearerPlatform, tmpl.Bearer)
+	assert.Equal(t, model.CurrencyCNY, tmpl.Currency)
+	assert.Equal(t, 1, tmpl.PerUserLimit)
+	assert.True(t, tmpl.IsActive)
+}
+
+func TestService_RedeemAndRelease(t *testing.T) {
+	svc, db := newTestService(t)
+	ctx := context.Background()
+	gameID := uint64(3)
+	tmpl := createTemplate(t, svc, TemplateRequest{Name: "满100减20", Type: model.CouponTypeThreshold,
+		AmountCents: 2000, ThresholdCents: 10000, GameID: &gameID, ValidDays: 7})
+	issued, err := svc.Issue(ctx, tmpl.ID, []uint64{1})
+	require.NoError(t, err)
+	require.Len(t, issued, 1)
+	assert.Equal(t, model.CouponSourceManual, issued[0].Source)
+	require.NotNil(t, issued[0].ExpiresAt)
+	couponID := issued[0].ID
+
+	// 门槛、游戏与归属校验
+	err = svc.Redeem(ctx, 1, couponID, &model.Order{UserID: 1, GameID: &gameID, TotalPriceCents: 9000})
+	assert.ErrorIs(t, err, ErrNotApplicable)
+	other := uint64(4)
+	err = svc.Redeem(ctx, 1, couponID, &model.Order{UserID: 1, GameID: &other, TotalPriceCents: 12000})
+	assert.ErrorIs(t, err, ErrNotApplicable)
+	err = svc.Redeem(ctx, 2, couponID, &model.Order{UserID: 2, GameID: &gameID, TotalPriceCents: 12000})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	order := &model.Order{UserID: 1, GameID: &gameID, TotalPriceCents: 12000, Status: model.OrderStatusPending}
+	require.NoError(t, svc.Redeem(ctx, 1, couponID, order))
+	assert.EqualValues(t, 10000, order.TotalPriceCents)
+	assert.EqualValues(t, 2000, order.DiscountCents)
+	assert.Equal(t, model.CouponBearerPlatform, order.DiscountBearer)
+	require.NotNil(t, order.CouponID)
+
+	// 锁定后不能重复使用
+	err = svc.Redeem(ctx, 1, couponID, &model.Order{UserID: 1, GameID: &gameID, TotalPriceCents: 12000})
+	assert.ErrorIs(t, err, ErrUnavailable)
+
+	require.NoError(t, db.Create(order).Error)
+	require.NoError(t, svc.Bind(ctx, couponID, order.ID))
+	require.NoError(t, svc.ReleaseForOrder(ctx, order))
+
+	var coupon model.UserCoupon
+	require.NoError(t, db.First(&coupon, couponID).Error)
+	assert.Equal(t, model.CouponStatusAvailable, coupon.Status)
+	assert.Nil(t, coupon.OrderID)
+}
+
+func TestService_CheckoutBindsOrRollsBack(t *testing.T) {
+	svc, db := newTestService(t)
+	svc.SetTxManager(common.NewUnitOfWork(db))
+	ctx := context.Background()
+	tmpl := createTemplate(t, svc, TemplateRequest{Name: "立减", Type: model.CouponTypeFixed, AmountCents: 500})
+	issued, err := svc.Issue(ctx, tmpl.ID, []uint64{1})
+	require.NoError(t, err)
+	couponID := issued[0].ID
+
+	// 订单写入失败时锁券一并回滚
+	failed := &model.Order{UserID: 1, TotalPriceCents: 3000}
+	err = svc.Checkout(ctx, 1, couponID, failed, func(context.Context, repository.OrderRepository) error {
+		return errors.New("insert failed")
+	})
+	require.Error(t, err)
+	var coupon model.UserCoupon
+	require.NoError(t, db.First(&coupon, couponID).Error)
+	assert.Equal(t, model.CouponStatusAvailable, coupon.Status)
+
+	order := &model.Order{UserID: 1, TotalPriceCents: 3000, Status: model.OrderStatusPending}
+	require.NoError(t, svc.Checkout(ctx, 1, couponID, order, func(ctx context.Context, orders repository.OrderRepository) error {
+		return orders.Create(ctx, order)
+	}))
+	require.NoError(t, db.First(&coupon, couponID).Error)
+	assert.Equal(t, model.CouponStatusLocked, coupon.Status)
+	require.NotNil(t, coupon.OrderID)
+	assert.Equal(t, order.ID, *coupon.OrderID)
+	assert.EqualValues(t, 2500, order.TotalPriceCents)
+}
+
+func TestService_RedeemConcurrentLocksOnce(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	tmpl := createTemplate(t, svc, TemplateRequest{Name: "立减", Type: model.CouponTypeFixed, AmountCents: 500})
+	issued, err := svc.Issue(ctx, tmpl.ID, []uint64{1})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if svc.Redeem(ctx, 1, issued[0].ID, &model.Order{UserID: 1, TotalPriceCents: 3000}) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestService_RedeemRejectsFullDiscount(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	tmpl := createTemplate(t, svc, TemplateRequest{Name: "立减", Type: model.CouponTypeFixed, AmountCents: 500})
+	issued, err := svc.Issue(ctx, tmpl.ID, []uint64{1})
+	require.NoError(t, err)
+
+	// 优惠抵扣全部金额的订单无法支付，不锁定优惠券
+	err = svc.Redeem(ctx, 1, issued[0].ID, &model.Order{UserID: 1, TotalPriceCents: 500})
+	assert.ErrorIs(t, err, ErrNotApplicable)
+	order := &model.Order{UserID: 1, TotalPriceCents: 501}
+	require.NoError(t, svc.Redeem(ctx, 1, issued[0].ID, order))
+	assert.EqualValues(t, 1, order.TotalPriceCents)
+}
+
+func TestService_RedeemUsageLimitAndNewUser(t *testing.T) {
+	svc, db := newTestService(t)
+	ctx := context.Background()
+	tmpl := createTemplate(t, svc, TemplateRequest{Name: "首单", Type: model.CouponTypePercent, Percent: 50,
+		MaxDiscountCents: 1000, NewUserOnly: true})
+	issued, err := svc.Issue(ctx, tmpl.ID, []uint64{1, 1})
+	require.NoError(t, err)
+	assert.Equal(t, model.CouponSourceBatch, issued[0].Source)
+
+	order := &model.Order{UserID: 1, TotalPriceCents: 5000}
+	require.NoError(t, svc.Redeem(ctx, 1, issued[0].ID, order))
+	assert.EqualValues(t, 1000, order.DiscountCents)
+
+	err = svc.Redeem(ctx, 1, issued[1].ID, &model.Order{UserID: 1, TotalPriceCents: 5000})
+	assert.ErrorIs(t, err, ErrUsageLimit)
+
+	// 已有有效订单的用户不再是首单
+	require.NoError(t, svc.Release(ctx, issued[0].ID))
+	require.NoError(t, db.Create(&model.Order{UserID: 1, Status: model.OrderStatusCompleted, TotalPriceCents: 100}).Error)
+	err = svc.Redeem(ctx, 1, issued[1].ID, &model.Order{UserID: 1, TotalPriceCents: 5000})
+	assert.ErrorIs(t, err, ErrNotApplicable)
+}
+
+func TestService_IssueForEventOncePerUser(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	createTemplate(t, svc, TemplateRequest{Name: "新人券", Type: model.CouponTypeFixed, AmountCents: 500,
+		TriggerEvent: model.CouponEventUserRegistered})
+	createTemplate(t, svc, TemplateRequest{Name: "限量", Type: model.CouponTypeFixed, AmountCents: 800,
+		TriggerEvent: model.CouponEventUserRegistered, TotalQuantity: 1})
+
+	require.NoError(t, svc.IssueForEvent(ctx, model.CouponEventUserRegistered, 1))
+	require.NoError(t, svc.IssueForEvent(ctx, model.CouponEventUserRegistered, 1))
+	require.NoError(t, svc.IssueForEvent(ctx, model.CouponEventUserRegistered, 2))
+
+	_, total, err := svc.ListMyCoupons(ctx, 1, "", 1, 20)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	_, total, err = svc.ListMyCoupons(ctx, 2, "", 1, 20)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total, "限量模板已发完时跳过")
+}
+
+func TestService_Settle(t *testing.T) {
+	svc, db := newTestService(t)
+	ctx := context.Background()
+	tmpl := createTemplate(t, svc, TemplateRequest{Name: "立减", Type: model.CouponTypeFixed, AmountCents: 500, PerUserLimit: new(int)})
+	issued, err := svc.Issue(ctx, tmpl.ID, []uint64{1, 1, 1})
+	require.NoError(t, err)
+
+	redeem := func(couponID uint64, status model.OrderStatus) *model.Order {
+		order := &model.Order{UserID: 1, TotalPriceCents: 3000}
+		require.NoError(t, svc.Redeem(ctx, 1, couponID, order))
+		order.Status = status
+		require.NoError(t, db.Create(order).Error)
+		require.NoError(t, svc.Bind(ctx, couponID, order.ID))
+		return order
+	}
+	redeem(issued[0].ID, model.OrderStatusCompleted)
+	redeem(issued[1].ID, model.OrderStatusRefunded)
+	// 锁定后下单失败且未释放的券
+	require.NoError(t, svc.Redeem(ctx, 1, issued[2].ID, &model.Order{UserID: 1, TotalPriceCents: 3000}))
+
+	svc.now = func() time.Time { return time.Now().Add(time.Hour) }
+	n, err := svc.Settle(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	statuses := map[uint64]model.CouponStatus{}
+	var coupons []model.UserCoupon
+	require.NoError(t, db.Find(&coupons).Error)
+	for _, c := range coupons {
+		statuses[c.ID] = c.Status
+	}
+	assert.Equal(t, model.CouponStatusUsed, statuses[issued[0].ID])
+	assert.Equal(t, model.CouponStatusAvailable, statuses[issued[1].ID])
+	assert.Equal(t, model.CouponStatusAvailable, statuses[issued[2].ID])
+
+	n, err = svc.Settle(ctx, 100)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
@@ -0,0 +1,381 @@
+package credit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	creditrepo "gamelink/internal/repository/credit"
+)
+
+var (
+	// ErrNotFound 规则或记录不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+)
+
+// Service 陪玩师信用服务
+//
+// 功能：
+// 1. 按可配置的计分规则记录信用事件：接单后取消、爽约、争议败诉、时效投诉扣分，好评、连续完成加分
+// 2. 信用分映射为等级，等级决定抢单资格、抽成加减与列表曝光
+// 3. 管理员上报违约、调整信用分或锁定等级，所有变动都写入信用记录
+//
+// 带业务单据的事件按（事件类型, 单据）幂等；违约事件会清零连续完成数。
+type Service struct {
+	credits creditrepo.CreditRepository
+	players repository.PlayerRepository
+}
+
+// NewService 创建信用服务
+func NewService(credits creditrepo.CreditRepository, players repository.PlayerRepository) *Service {
+	return &Service{credits: credits, players: players}
+}
+
+// EnsureDefaultRules 补齐默认计分规则，已调整过的规则保持不变
+func (s *Service) EnsureDefaultRules(ctx context.Context) error {
+	return s.credits.EnsureRules(ctx, model.DefaultCreditRules())
+}
+
+// ListRules 列出计分规则
+func (s *Service) ListRules(ctx context.Context) ([]model.CreditRule, error) {
+	return s.credits.ListRules(ctx)
+}
+
+// UpdateRuleRequest 调整计分规则，未填写的字段保持不变
+type UpdateRuleRequest struct {
+	Delta       *int    `json:"delta" binding:"omitempty,min=-100,max=100"`
+	Threshold   *int    `json:"threshold" binding:"omitempty,min=0"`
+	IsActive    *bool   `json:"isActive"`
+	Description *string `json:"description" binding:"omitempty,max=255"`
+}
+
+// UpdateRule 调整事件类型的计分规则
+func (s *Service) UpdateRule(ctx context.Context, eventType model.CreditEventType, req UpdateRuleRequest) (*model.CreditRule, error) {
+	rule, err := s.credits.GetRule(ctx, eventType)
+	if err != nil {
+		return nil, err
+	}
+	if req.Delta != nil {
+		rule.Delta = *req.Delta
+	}
+	if req.Threshold != nil {
+		rule.Threshold = *req.Threshold
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	if req.Description != nil {
+		rule.Description = strings.TrimSpace(*req.Description)
+	}
+	if err := s.credits.UpdateRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// GetCredit 获取陪玩师信用，尚无记录时返回初始信用（不落库）
+func (s *Service) GetCredit(ctx context.Context, playerID uint64) (*model.PlayerCredit, error) {
+	if playerID == 0 {
+		return nil, ErrValidation
+	}
+	credit, err := s.credits.GetCredit(ctx, playerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		credit = &model.PlayerCredit{PlayerID: playerID, Score: model.CreditInitialScore}
+		credit.Refresh()
+		return credit, nil
+	}
+	return credit, err
+}
+
+// ListEvents 分页列出陪玩师的信用变动（最新在前）
+func (s *Service) ListEvents(ctx context.Context, playerID uint64, page, pageSize int) ([]model.CreditEvent, int64, error) {
+	if playerID == 0 {
+		return nil, 0, ErrValidation
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return s.credits.ListEvents(ctx, playerID, page, pageSize)
+}
+
+// CreditOverview 陪玩师信用概览：信用分、等级策略与一页变动记录
+type CreditOverview struct {
+	Credit *model.PlayerCredit    `json:"credit"`
+	Policy model.CreditTierPolicy `json:"policy"`
+	Events []model.CreditEvent    `json:"events"`
+}
+
+// Overview 获取陪玩师的信用概览（管理端）
+func (s *Service) Overview(ctx context.Context, playerID uint64, page, pageSize int) (*CreditOverview, int64, error) {
+	if _, err := s.players.Get(ctx, playerID); err != nil {
+		return nil, 0, err
+	}
+	credit, err := s.GetCredit(ctx, playerID)
+	if err != nil {
+		return nil, 0, err
+	}
+	events, total, err := s.ListEvents(ctx, playerID, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &CreditOverview{Credit: credit, Policy: credit.Policy(), Events: events}, total, nil
+}
+
+// MyOverview 获取当前陪玩师自己的信用概览
+func (s *Service) MyOverview(ctx context.Context, userID uint64, page, pageSize int) (*CreditOverview, int64, error) {
+	player, err := s.players.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.Overview(ctx, player.ID, page, pageSize)
+}
+
+// TierPolicy 返回陪玩师生效等级的策略
+func (s *Service) TierPolicy(ctx context.Context, playerID uint64) (model.CreditTierPolicy, error) {
+	credit, err := s.GetCredit(ctx, playerID)
+	if err != nil {
+		return model.CreditTierPolicy{}, err
+	}
+	return credit.Policy(), nil
+}
+
+// TierPolicies 批量返回陪玩师生效等级的策略，没有记录的陪玩师按初始分处理
+func (s *Service) TierPolicies(ctx context.Context, playerIDs []uint64) (map[uint64]model.CreditTierPolicy, error) {
+	credits, err := s.credits.ListCredits(ctx, playerIDs)
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[uint64]model.CreditTierPolicy, len(playerIDs))
+	for _, id := range playerIDs {
+		policies[id] = model.DefaultCreditTierPolicy()
+	}
+	for i := range credits {
+		policies[credits[i].PlayerID] = credits[i].Policy()
+	}
+	return policies, nil
+}
+
+// IncidentRequest 上报陪玩师违约：接单后取消、爽约或时效投诉
+type IncidentRequest struct {
+	PlayerID    uint64                `json:"-"`
+	Type        model.CreditEventType `json:"type" binding:"required,oneof=cancel_after_accept no_show sla_complaint"`
+	OrderID     *uint64               `json:"orderId"`
+	Reason      string                `json:"reason" binding:"required,max=500"`
+	ActorUserID *uint64               `json:"-"`
+}
+
+// ReportIncident 记录人工上报的违约；同一订单的同类违约只记一次，规则停用时返回 nil
+func (s *Service) ReportIncident(ctx context.Context, req IncidentRequest) (*model.CreditEvent, error) {
+	if req.PlayerID == 0 || !req.Type.IsIncident() || strings.TrimSpace(req.Reason) == "" {
+		return nil, ErrValidation
+	}
+	if _, err := s.players.Get(ctx, req.PlayerID); err != nil {
+		return nil, err
+	}
+	var key *string
+	if req.OrderID != nil {
+		key = businessKey(req.Type, "order", *req.OrderID)
+	}
+	return s.record(ctx, req.PlayerID, req.Type, key, &model.CreditEvent{
+		OrderID:     req.OrderID,
+		Reason:      strings.TrimSpace(req.Reason),
+		ActorUserID: req.ActorUserID,
+	})
+}
+
+// RecordDisputeLost 争议裁决为退款时扣减订单陪玩师的信用分
+func (s *Service) RecordDisputeLost(ctx context.Context, dispute *model.OrderDispute, playerID uint64) error {
+	if playerID == 0 {
+		return nil
+	}
+	_, err := s.record(ctx, playerID, model.CreditEventDisputeLost, businessKey(model.CreditEventDisputeLost, "dispute", dispute.ID), &model.CreditEvent{
+		OrderID:     &dispute.OrderID,
+		DisputeID:   &dispute.ID,
+		Reason:      dispute.ResolutionNotes,
+		ActorUserID: dispute.ResolvedByUserID,
+	})
+	return err
+}
+
+// RevokeDisputeLost 争议裁决被回退时撤销对应的扣分
+func (s *Service) RevokeDisputeLost(ctx context.Context, disputeID uint64, reason string, actorUserID *uint64) error {
+	_, err := s.Revoke(ctx, *businessKey(model.CreditEventDisputeLost, "dispute", disputeID), reason, actorUserID)
+	return err
+}
+
+// RecordReview 评分达到好评规则阈值时为陪玩师加分
+func (s *Service) RecordReview(ctx context.Context, review *model.Review) error {
+	if review.PlayerID == 0 {
+		return nil
+	}
+	rule, err := s.activeRule(ctx, model.CreditEventGoodReview)
+	if err != nil || rule == nil || int(review.Score) < rule.Threshold {
+		return err
+	}
+	_, err = s.record(ctx, review.PlayerID, model.CreditEventGoodReview, businessKey(model.CreditEventGoodReview, "review", review.ID), &model.CreditEvent{
+		OrderID:  &review.OrderID,
+		ReviewID: &review.ID,
+	})
+	return err
+}
+
+// RecordCompletion 订单完成时累计连续完成数，每达到规则阈值加分一次
+func (s *Service) RecordCompletion(ctx context.Context, order *model.Order) error {
+	playerID := order.GetPlayerID()
+	if playerID == 0 {
+		return nil
+	}
+	rule, err := s.activeRule(ctx, model.CreditEventCompletionStreak)
+	if err != nil {
+		return err
+	}
+	_, err = s.credits.ApplyEvent(ctx, playerID, nil, func(credit *model.PlayerCredit) *model.CreditEvent {
+		credit.CompletionStreak++
+		if rule == nil || rule.Threshold <= 0 || credit.CompletionStreak%rule.Threshold != 0 {
+			return nil
+		}
+		return applyDelta(credit, model.CreditEventCompletionStreak, rule.Delta, &model.CreditEvent{
+			OrderID: &order.ID,
+			Reason:  fmt.Sprintf("连续完成 %d 单", credit.CompletionStreak),
+		})
+	})
+	return err
+}
+
+// OverrideRequest 管理员调整信用分或锁定等级；Tier 为空字符串时解除锁定，未填写时保持不变
+type OverrideRequest struct {
+	PlayerID    uint64            `json:"-"`
+	Score       *int              `json:"score" binding:"omitempty,min=0,max=100"`
+	Tier        *model.CreditTier `json:"tier"`
+	Reason      string            `json:"reason" binding:"required,max=500"`
+	ActorUserID *uint64           `json:"-"`
+}
+
+// Override 管理员直接设置信用分或锁定等级，变动写入信用记录
+func (s *Service) Override(ctx context.Context, req OverrideRequest) (*model.PlayerCredit, error) {
+	if req.PlayerID == 0 || strings.TrimSpace(req.Reason) == "" || (req.Score == nil && req.Tier == nil) {
+		return nil, ErrValidation
+	}
+	if req.Score != nil && (*req.Score < model.CreditMinScore || *req.Score > model.CreditMaxScore) {
+		return nil, fmt.Errorf("%w: score must be between %d and %d", ErrValidation, model.CreditMinScore, model.CreditMaxScore)
+	}
+	if req.Tier != nil && *req.Tier != "" {
+		if _, ok := model.CreditTierPolicyOf(*req.Tier); !ok {
+			return nil, fmt.Errorf("%w: unknown tier %q", ErrValidation, *req.Tier)
+		}
+	}
+	if _, err := s.players.Get(ctx, req.PlayerID); err != nil {
+		return nil, err
+	}
+	var result model.PlayerCredit
+	_, err := s.credits.ApplyEvent(ctx, req.PlayerID, nil, func(credit *model.PlayerCredit) *model.CreditEvent {
+		delta := 0
+		if req.Score != nil {
+			delta = *req.Score - credit.Score
+		}
+		if req.Tier != nil {
+			credit.PinnedTier = nil
+			if *req.Tier != "" {
+				tier := *req.Tier
+				credit.PinnedTier = &tier
+			}
+		}
+		event := applyDelta(credit, model.CreditEventOverride, delta, &model.CreditEvent{
+			Reason:      strings.TrimSpace(req.Reason),
+			ActorUserID: req.ActorUserID,
+		})
+		result = *credit
+		return event
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Revoke 撤销业务键对应的事件并写入反向变动；事件不存在或已撤销时返回 nil
+func (s *Service) Revoke(ctx context.Context, key, reason string, actorUserID *uint64) (*model.CreditEvent, error) {
+	original, err := s.credits.GetEventByKey(ctx, key)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if original.RevokedAt != nil {
+		return nil, nil
+	}
+	event, err := s.credits.ApplyEvent(ctx, original.PlayerID, nil, func(credit *model.PlayerCredit) *model.CreditEvent {
+		return applyDelta(credit, model.CreditEventRevoke, -original.Delta, &model.CreditEvent{
+			OrderID:     original.OrderID,
+			DisputeID:   original.DisputeID,
+			ReviewID:    original.ReviewID,
+			RevokesID:   &original.ID,
+			Reason:      reason,
+			ActorUserID: actorUserID,
+		})
+	})
+	if errors.Is(err, creditrepo.ErrDuplicateEvent) {
+		return nil, nil
+	}
+	return event, err
+}
+
+// record 按规则记录一次事件；规则停用时返回 nil，业务键重复时返回 nil
+func (s *Service) record(ctx context.Context, playerID uint64, eventType model.CreditEventType, key *string, tmpl *model.CreditEvent) (*model.CreditEvent, error) {
+	rule, err := s.activeRule(ctx, eventType)
+	if err != nil || rule == nil {
+		return nil, err
+	}
+	event, err := s.credits.ApplyEvent(ctx, playerID, key, func(credit *model.PlayerCredit) *model.CreditEvent {
+		if rule.Delta < 0 {
+			credit.CompletionStreak = 0
+		}
+		return applyDelta(credit, eventType, rule.Delta, tmpl)
+	})
+	if errors.Is(err, creditrepo.ErrDuplicateEvent) {
+		return nil, nil
+	}
+	return event, err
+}
+
+// activeRule 返回启用的计分规则，规则不存在或已停用时返回 nil
+func (s *Service) activeRule(ctx context.Context, eventType model.CreditEventType) (*model.CreditRule, error) {
+	rule, err := s.credits.GetRule(ctx, eventType)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !rule.IsActive {
+		return nil, nil
+	}
+	return rule, nil
+}
+
+// applyDelta 在信用分上加减 delta（截断到上下限），返回记录实际变动的事件
+func applyDelta(credit *model.PlayerCredit, eventType model.CreditEventType, delta int, event *model.CreditEvent) *model.CreditEvent {
+	before := credit.Score
+	credit.Score += delta
+	credit.Refresh()
+	event.Type = eventType
+	event.Delta = credit.Score - before
+	event.ScoreAfter = credit.Score
+	event.TierAfter = credit.Tier
+	return event
+}
+
+// businessKey 事件的幂等键，例如 dispute_lost:dispute:12
+func businessKey(eventType model.CreditEventType, ref string, id uint64) *string {
+	key := fmt.Sprintf("%s:%s:%d", eventType, ref, id)
+	return &key
+}
@@ -0,0 +1,149 @@
+package credit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	creditrepo "gamelink/internal/repository/credit"
+	playerrepo "gamelink/internal/repository/player"
+)
+
+func newTestService(t *testing.T) (*Service, uint64) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.PlayerCredit{}, &model.CreditEvent{}, &model.CreditRule{}))
+	player := &model.Player{UserID: 11, Nickname: "p"}
+	require.NoError(t, db.Create(player).Error)
+	svc := NewService(creditrepo.NewCreditRepository(db), playerrepo.NewPlayerRepository(db))
+	require.NoError(t, svc.EnsureDefaultRules(context.Background()))
+	return svc, player.ID
+}
+
+func completedOrder(id, playerID uint64) *model.Order {
+	return &model.Order{Base: model.Base{ID: id}, PlayerID: &playerID, Status: model.OrderStatusCompleted}
+}
+
+func TestService_IncidentDeductsOnceAndResetsStreak(t *testing.T) {
+	svc, playerID := newTestService(t)
+	ctx := context.Background()
+
+	for i := uint64(1); i <= 3; i++ {
+		require.NoError(t, svc.RecordCompletion(ctx, completedOrder(i, playerID)))
+	}
+	orderID := uint64(9)
+	req := IncidentRequest{PlayerID: playerID, Type: model.CreditEventNoShow, OrderID: &orderID, Reason: "未上线"}
+	event, err := svc.ReportIncident(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, -15, event.Delta)
+
+	event, err = svc.ReportIncident(ctx, req)
+	require.NoError(t, err)
+	assert.Nil(t, event, "the same order is reported once")
+
+	_, err = svc.ReportIncident(ctx, IncidentRequest{PlayerID: playerID, Type: model.CreditEventGoodReview, Reason: "x"})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.ReportIncident(ctx, IncidentRequest{PlayerID: 999, Type: model.CreditEventNoShow, Reason: "x"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	credit, err := svc.GetCredit(ctx, playerID)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditInitialScore-15, credit.Score)
+	assert.Zero(t, credit.CompletionStreak)
+	assert.Equal(t, model.CreditTierFair, credit.Tier)
+}
+
+func TestService_CompletionStreakAndGoodReview(t *testing.T) {
+	svc, playerID := newTestService(t)
+	ctx := context.Background()
+
+	for i := uint64(1); i <= 10; i++ {
+		require.NoError(t, svc.RecordCompletion(ctx, completedOrder(i, playerID)))
+	}
+	require.NoError(t, svc.RecordReview(ctx, &model.Review{Base: model.Base{ID: 1}, PlayerID: playerID, Score: 4}))
+	require.NoError(t, svc.RecordReview(ctx, &model.Review{Base: model.Base{ID: 2}, PlayerID: playerID, Score: 5}))
+	require.NoError(t, svc.RecordReview(ctx, &model.Review{Base: model.Base{ID: 2}, PlayerID: playerID, Score: 5}))
+
+	overview, total, err := svc.Overview(ctx, playerID, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, model.CreditInitialScore+3+1, overview.Credit.Score)
+	assert.Equal(t, 10, overview.Credit.CompletionStreak)
+	assert.Equal(t, model.CreditEventGoodReview, overview.Events[0].Type)
+	assert.Equal(t, model.CreditEventCompletionStreak, overview.Events[1].Type)
+}
+
+func TestService_DisputeLostRevokedOnRollback(t *testing.T) {
+	svc, playerID := newTestService(t)
+	ctx := context.Background()
+
+	dispute := &model.OrderDispute{Base: model.Base{ID: 4}, OrderID: 8}
+	require.NoError(t, svc.RecordDisputeLost(ctx, dispute, playerID))
+	require.NoError(t, svc.RecordDisputeLost(ctx, dispute, playerID))
+	credit, err := svc.GetCredit(ctx, playerID)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditInitialScore-10, credit.Score)
+
+	require.NoError(t, svc.RevokeDisputeLost(ctx, dispute.ID, "裁决回退", nil))
+	require.NoError(t, svc.RevokeDisputeLost(ctx, dispute.ID, "裁决回退", nil))
+	credit, err = svc.GetCredit(ctx, playerID)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditInitialScore, credit.Score)
+
+	require.NoError(t, svc.RecordDisputeLost(ctx, dispute, playerID), "a re-resolved dispute is booked again")
+	credit, err = svc.GetCredit(ctx, playerID)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditInitialScore-10, credit.Score)
+}
+
+func TestService_RuleChangesApplyToLaterEvents(t *testing.T) {
+	svc, playerID := newTestService(t)
+	ctx := context.Background()
+
+	inactive := false
+	_, err := svc.UpdateRule(ctx, model.CreditEventSLAComplaint, UpdateRuleRequest{IsActive: &inactive})
+	require.NoError(t, err)
+	event, err := svc.ReportIncident(ctx, IncidentRequest{PlayerID: playerID, Type: model.CreditEventSLAComplaint, Reason: "响应慢"})
+	require.NoError(t, err)
+	assert.Nil(t, event, "inactive rules record nothing")
+
+	_, err = svc.UpdateRule(ctx, "unknown", UpdateRuleRequest{IsActive: &inactive})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestService_OverridePinsTier(t *testing.T) {
+	svc, playerID := newTestService(t)
+	ctx := context.Background()
+
+	score := 30
+	tier := model.CreditTierGood
+	credit, err := svc.Override(ctx, OverrideRequest{PlayerID: playerID, Score: &score, Tier: &tier, Reason: "申诉通过"})
+	require.NoError(t, err)
+	assert.Equal(t, 30, credit.Score)
+	assert.Equal(t, model.CreditTierGood, credit.Tier)
+	policy, err := svc.TierPolicy(ctx, playerID)
+	require.NoError(t, err)
+	assert.True(t, policy.CanGrab)
+
+	unpin := model.CreditTier("")
+	credit, err = svc.Override(ctx, OverrideRequest{PlayerID: playerID, Tier: &unpin, Reason: "解除锁定"})
+	require.NoError(t, err)
+	assert.Nil(t, credit.PinnedTier)
+	assert.Equal(t, model.CreditTierRestricted, credit.Tier)
+
+	bogus := model.CreditTier("gold")
+	_, err = svc.Override(ctx, OverrideRequest{PlayerID: playerID, Tier: &bogus, Reason: "x"})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	policies, err := svc.TierPolicies(ctx, []uint64{playerID, 404})
+	require.NoError(t, err)
+	assert.False(t, policies[playerID].Listed)
+	assert.Equal(t, model.DefaultCreditTierPolicy(), policies[404])
+}
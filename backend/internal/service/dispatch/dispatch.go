@@ -0,0 +1,456 @@
+// Package dispatch 智能派单：为已支付订单给候选陪玩师打分，按模式推送邀请或直接指派。
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gamelink/internal/logging"
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	dispatchrepo "gamelink/internal/repository/dispatch"
+	"gamelink/internal/service/orderstate"
+)
+
+var (
+	// ErrNotFound 订单、陪玩师或派单邀请不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrNotDispatchable 订单不是待接单状态
+	ErrNotDispatchable = errors.New("order is not awaiting a player")
+	// ErrNoCandidate 没有符合条件的陪玩师
+	ErrNoCandidate = errors.New("no eligible player for the order")
+	// ErrOfferClosed 派单邀请已过期、已响应或订单已被接走
+	ErrOfferClosed = errors.New("dispatch offer is no longer open")
+)
+
+// Policy 派单策略
+type Policy struct {
+	Mode         model.DispatchMode
+	TopN         int           // 推送模式下邀请的陪玩师数
+	OfferTimeout time.Duration // 推送邀请的响应时限
+}
+
+// DefaultPolicy 默认派单策略：订单进入抢单大厅
+var DefaultPolicy = Policy{Mode: model.DispatchModePool, TopN: 3, OfferTimeout: 2 * time.Minute}
+
+// PresenceChecker 陪玩师在线状态（由陪玩师服务实现）
+type PresenceChecker interface {
+	IsPlayerOnline(ctx context.Context, playerID uint64) bool
+}
+
+// ConflictChecker 预约冲突检测（由日程服务实现），有冲突的陪玩师不参与派单
+type ConflictChecker interface {
+	CheckConflict(ctx context.Context, playerID uint64, start, end time.Time, exceptOrderID uint64) error
+}
+
+// DispatchRequest 手动派单请求，未填写的字段取默认策略
+type DispatchRequest struct {
+	Mode           model.DispatchMode `json:"mode" binding:"omitempty,oneof=pool push auto"`
+	TopN           int                `json:"topN" binding:"omitempty,min=1,max=20"`
+	TimeoutSeconds int                `json:"timeoutSeconds" binding:"omitempty,min=10,max=3600"`
+}
+
+// DispatchResult 派单结果
+type DispatchResult struct {
+	OrderID    uint64                 `json:"orderId"`
+	Mode       model.DispatchMode     `json:"mode"`
+	Offers     []model.DispatchOffer  `json:"offers"`
+	Assignment *model.OrderAssignment `json:"assignment,omitempty"`
+}
+
+// ProcessResult 一轮派单调度的结果
+type ProcessResult struct {
+	Expired    int // 过期的邀请数
+	Dispatched int // 新派出的订单数
+}
+
+// Service 派单服务
+type Service struct {
+	dispatch      dispatchrepo.DispatchRepository
+	orders        repository.OrderRepository
+	players       repository.PlayerRepository
+	history       orderstate.HistoryAppender
+	tags          repository.PlayerTagRepository
+	presence      PresenceChecker
+	bookings      ConflictChecker
+	notifications repository.NotificationRepository
+	policy        Policy
+	now           func() time.Time
+}
+
+// NewService 创建派单服务
+func NewService(dispatch dispatchrepo.DispatchRepository, orders repository.OrderRepository, players repository.PlayerRepository) *Service {
+	return &Service{dispatch: dispatch, orders: orders, players: players, policy: DefaultPolicy, now: time.Now}
+}
+
+// SetPolicy 设置默认派单策略
+func (s *Service) SetPolicy(p Policy) { s.policy = p }
+
+// SetStatusHistory 注入订单状态历史，接受邀请时记录流转
+func (s *Service) SetStatusHistory(h orderstate.HistoryAppender) { s.history = h }
+
+// SetTags 注入陪玩师技能标签仓储，用于标签匹配打分
+func (s *Service) SetTags(t repository.PlayerTagRepository) { s.tags = t }
+
+// SetPresence 注入在线状态查询，用于在线打分
+func (s *Service) SetPresence(p PresenceChecker) { s.presence = p }
+
+// SetBookingChecker 注入预约冲突检测
+func (s *Service) SetBookingChecker(c ConflictChecker) { s.bookings = c }
+
+// SetNotifications 注入通知仓储，派单后通知陪玩师
+func (s *Service) SetNotifications(n repository.NotificationRepository) { s.notifications = n }
+
+// Recommend 为订单推荐候选陪玩师（评分从高到低），limit<=0 时返回全部
+func (s *Service) Recommend(ctx context.Context, orderID uint64, limit int) ([]Candidate, error) {
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.rank(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// Dispatch 按指定模式派出订单；上下文中有操作人时记录为手动触发，否则为调度器自动派单。
+// 推送模式会取消订单此前未响应的邀请。
+func (s *Service) Dispatch(ctx context.Context, orderID uint64, req DispatchRequest) (*DispatchResult, error) {
+	policy := s.policy
+	if req.Mode != "" {
+		policy.Mode = req.Mode
+	}
+	if req.TopN > 0 {
+		policy.TopN = req.TopN
+	}
+	if req.TimeoutSeconds > 0 {
+		policy.OfferTimeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+	switch policy.Mode {
+	case model.DispatchModePool, model.DispatchModePush, model.DispatchModeAuto:
+	default:
+		return nil, fmt.Errorf("%w: unknown dispatch mode %q", ErrValidation, policy.Mode)
+	}
+
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != model.OrderStatusConfirmed || order.IsGiftOrder() || order.IsTeamOrder() {
+		return nil, ErrNotDispatchable
+	}
+	now := s.now()
+	if err := s.dispatch.CancelPendingOffers(ctx, order.ID, now); err != nil {
+		return nil, err
+	}
+	result := &DispatchResult{OrderID: order.ID, Mode: policy.Mode, Offers: []model.DispatchOffer{}}
+	if policy.Mode == model.DispatchModePool {
+		return result, nil
+	}
+
+	candidates, err := s.rank(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoCandidate
+	}
+
+	if policy.Mode == model.DispatchModeAuto {
+		var actorUserID *uint64
+		if uid, ok := logging.ActorUserIDFromContext(ctx); ok {
+			actorUserID = &uid
+		}
+		best := candidates[0]
+		from := order.Status
+		order.SetPlayerID(best.PlayerID)
+		if err := s.saveAssigned(ctx, order, from); err != nil {
+			return nil, err
+		}
+		assignment := &model.OrderAssignment{
+			OrderID:     order.ID,
+			PlayerID:    best.PlayerID,
+			Source:      model.AssignmentSourceSystem,
+			Mode:        model.DispatchModeAuto,
+			Score:       best.Score,
+			ActorUserID: actorUserID,
+		}
+		if err := s.dispatch.CreateAssignment(ctx, assignment); err != nil {
+			return nil, err
+		}
+		result.Assignment = assignment
+		s.notifyPlayer(ctx, best.PlayerID, order, "新订单已指派", fmt.Sprintf("订单 %s 已自动指派给你，请按时开始服务", orderLabel(order)))
+		return result, nil
+	}
+
+	topN := policy.TopN
+	if topN <= 0 {
+		topN = DefaultPolicy.TopN
+	}
+	if len(candidates) > topN {
+		candidates = candidates[:topN]
+	}
+	timeout := policy.OfferTimeout
+	if timeout <= 0 {
+		timeout = DefaultPolicy.OfferTimeout
+	}
+	offers := make([]model.DispatchOffer, 0, len(candidates))
+	for i, c := range candidates {
+		offers = append(offers, model.DispatchOffer{
+			OrderID:   order.ID,
+			PlayerID:  c.PlayerID,
+			Rank:      i + 1,
+			Score:     c.Score,
+			Status:    model.DispatchOfferPending,
+			ExpiresAt: now.Add(timeout),
+		})
+	}
+	if err := s.dispatch.CreateOffers(ctx, offers); err != nil {
+		return nil, err
+	}
+	for _, o := range offers {
+		s.notifyPlayer(ctx, o.PlayerID, order, "新的派单邀请",
+			fmt.Sprintf("订单 %s 邀请你接单，请在 %s 内响应", orderLabel(order), timeout.Round(time.Second)))
+	}
+	result.Offers = offers
+	return result, nil
+}
+
+// ListMyOffers 列出当前陪玩师收到的派单邀请，status 为空时返回全部
+func (s *Service) ListMyOffers(ctx context.Context, userID uint64, status model.DispatchOfferStatus) ([]model.DispatchOffer, error) {
+	player, err := s.players.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	offers, err := s.dispatch.ListOffersByPlayer(ctx, player.ID, status)
+	if err != nil {
+		return nil, err
+	}
+	if offers == nil {
+		offers = []model.DispatchOffer{}
+	}
+	return offers, nil
+}
+
+// AcceptOffer 陪玩师接受派单邀请：订单指派给该陪玩师并开始服务，其余邀请随之取消
+func (s *Service) AcceptOffer(ctx context.Context, userID, offerID uint64) (*model.OrderAssignment, error) {
+	player, offer, err := s.openOffer(ctx, userID, offerID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.orders.Get(ctx, offer.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if order.Status != model.OrderStatusConfirmed {
+		_, _ = s.dispatch.TransitOffer(ctx, offer.ID, model.DispatchOfferPending, model.DispatchOfferCanceled, now)
+		return nil, ErrOfferClosed
+	}
+	if err := s.checkConflict(ctx, player.ID, order); err != nil {
+		return nil, err
+	}
+	ok, err := s.dispatch.TransitOffer(ctx, offer.ID, model.DispatchOfferPending, model.DispatchOfferAccepted, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrOfferClosed
+	}
+
+	order.SetPlayerID(player.ID)
+	err = orderstate.Transit(ctx, s.orders, s.history, order, model.OrderStatusInProgress, orderstate.Change{
+		Role:        model.OrderActorPlayer,
+		ActorUserID: &userID,
+		Reason:      "接受派单邀请",
+	})
+	if err != nil {
+		// 订单已被并发接走或取消，邀请作废
+		_, _ = s.dispatch.TransitOffer(ctx, offer.ID, model.DispatchOfferAccepted, model.DispatchOfferCanceled, now)
+		if errors.Is(err, orderstate.ErrStatusConflict) {
+			return nil, ErrOfferClosed
+		}
+		return nil, err
+	}
+	if err := s.dispatch.CancelPendingOffers(ctx, order.ID, now); err != nil {
+		slog.Warn("cancel remaining dispatch offers failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+	}
+	assignment := &model.OrderAssignment{
+		OrderID:     order.ID,
+		PlayerID:    player.ID,
+		Source:      model.AssignmentSourceSystem,
+		Mode:        model.DispatchModePush,
+		Score:       offer.Score,
+		OfferID:     &offer.ID,
+		ActorUserID: &userID,
+	}
+	if err := s.dispatch.CreateAssignment(ctx, assignment); err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// DeclineOffer 陪玩师拒绝派单邀请
+func (s *Service) DeclineOffer(ctx context.Context, userID, offerID uint64) error {
+	_, offer, err := s.openOffer(ctx, userID, offerID)
+	if err != nil {
+		return err
+	}
+	ok, err := s.dispatch.TransitOffer(ctx, offer.ID, model.DispatchOfferPending, model.DispatchOfferDeclined, s.now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOfferClosed
+	}
+	return nil
+}
+
+// IsReserved 订单是否仍在派单邀请期内且该陪玩师未被邀请；playerID 为 0 时只要有有效邀请即视为保留。
+// 邀请全部过期或被拒绝后订单回落抢单大厅。
+func (s *Service) IsReserved(ctx context.Context, orderID, playerID uint64) (bool, error) {
+	offers, err := s.dispatch.ListOffersByOrder(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	now := s.now()
+	reserved := false
+	for _, o := range offers {
+		if o.Status != model.DispatchOfferPending || !o.ExpiresAt.After(now) {
+			continue
+		}
+		if playerID != 0 && o.PlayerID == playerID {
+			return false, nil
+		}
+		reserved = true
+	}
+	return reserved, nil
+}
+
+// ProcessDispatch 过期超时未响应的邀请，并按默认策略派出尚未派单的订单。
+// 单个订单失败只记录日志，不影响其他订单。
+func (s *Service) ProcessDispatch(ctx context.Context, limit int) (ProcessResult, error) {
+	var res ProcessResult
+	now := s.now()
+	expired, err := s.dispatch.ListExpiredOffers(ctx, now, limit)
+	if err != nil {
+		return res, err
+	}
+	for _, o := range expired {
+		ok, err := s.dispatch.TransitOffer(ctx, o.ID, model.DispatchOfferPending, model.DispatchOfferExpired, now)
+		if err != nil {
+			slog.Warn("expire dispatch offer failed", slog.Uint64("offer_id", o.ID), slog.String("error", err.Error()))
+			continue
+		}
+		if ok {
+			res.Expired++
+		}
+	}
+
+	if s.policy.Mode == model.DispatchModePool {
+		return res, nil
+	}
+	orders, err := s.dispatch.ListUndispatchedOrders(ctx, limit)
+	if err != nil {
+		return res, err
+	}
+	for i := range orders {
+		_, err := s.Dispatch(ctx, orders[i].ID, DispatchRequest{})
+		switch {
+		case err == nil:
+			res.Dispatched++
+		case errors.Is(err, ErrNoCandidate), errors.Is(err, ErrNotDispatchable):
+			// 暂无合适陪玩师，订单留在抢单大厅，下一轮重试
+		default:
+			slog.Warn("dispatch order failed", slog.Uint64("order_id", orders[i].ID), slog.String("error", err.Error()))
+		}
+	}
+	return res, nil
+}
+
+// openOffer 校验邀请属于当前陪玩师且仍待响应；已超时的邀请就地过期
+func (s *Service) openOffer(ctx context.Context, userID, offerID uint64) (*model.Player, *model.DispatchOffer, error) {
+	player, err := s.players.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	offer, err := s.dispatch.GetOffer(ctx, offerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offer.PlayerID != player.ID {
+		return nil, nil, ErrNotFound
+	}
+	if offer.Status != model.DispatchOfferPending {
+		return nil, nil, ErrOfferClosed
+	}
+	if now := s.now(); !offer.ExpiresAt.After(now) {
+		_, _ = s.dispatch.TransitOffer(ctx, offer.ID, model.DispatchOfferPending, model.DispatchOfferExpired, now)
+		return nil, nil, ErrOfferClosed
+	}
+	return player, offer, nil
+}
+
+// saveAssigned 仅当订单状态未被并发修改时保存指派
+func (s *Service) saveAssigned(ctx context.Context, order *model.Order, from model.OrderStatus) error {
+	cu, ok := s.orders.(orderstate.ConditionalUpdater)
+	if !ok {
+		return s.orders.Update(ctx, order)
+	}
+	updated, err := cu.UpdateIfStatus(ctx, order, from)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrNotDispatchable
+	}
+	return nil
+}
+
+// checkConflict 订单有预约时间时校验陪玩师没有重叠的订单
+func (s *Service) checkConflict(ctx context.Context, playerID uint64, order *model.Order) error {
+	if s.bookings == nil || order.ScheduledStart == nil || order.ScheduledEnd == nil {
+		return nil
+	}
+	return s.bookings.CheckConflict(ctx, playerID, *order.ScheduledStart, *order.ScheduledEnd, order.ID)
+}
+
+// notifyPlayer 通知陪玩师，失败只记录日志
+func (s *Service) notifyPlayer(ctx context.Context, playerID uint64, order *model.Order, title, message string) {
+	if s.notifications == nil {
+		return
+	}
+	player, err := s.players.Get(ctx, playerID)
+	if err != nil || player.UserID == 0 {
+		return
+	}
+	id := order.ID
+	err = s.notifications.Create(ctx, &model.NotificationEvent{
+		UserID:        player.UserID,
+		Title:         title,
+		Message:       message,
+		Priority:      model.NotificationPriorityHigh,
+		ReferenceType: string(model.OpEntityOrder),
+		ReferenceID:   &id,
+	})
+	if err != nil {
+		slog.Warn("notify dispatch failed", slog.Uint64("order_id", order.ID), slog.Uint64("player_id", playerID), slog.String("error", err.Error()))
+	}
+}
+
+// orderLabel 通知中展示的订单标识
+func orderLabel(order *model.Order) string {
+	if order.OrderNo != "" {
+		return order.OrderNo
+	}
+	return fmt.Sprintf("#%d", order.ID)
+}
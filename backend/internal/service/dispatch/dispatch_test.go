@@ -0,0 +1,231 @@
+package dispatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	dispatchrepo "gamelink/internal/repository/dispatch"
+	notificationrepo "gamelink/internal/repository/notification"
+	orderrepo "gamelink/internal/repository/order"
+	orderhistory "gamelink/internal/repository/order_history"
+	playerrepo "gamelink/internal/repository/player"
+	playertagrepo "gamelink/internal/repository/player_tag"
+	"gamelink/internal/service/availability"
+)
+
+type stubPresence map[uint64]bool
+
+func (p stubPresence) IsPlayerOnline(_ context.Context, playerID uint64) bool { return p[playerID] }
+
+type dispatchEnv struct {
+	db  *gorm.DB
+	svc *Service
+	now time.Time
+	// 评分从高到低：top 主玩该游戏、在线、评分高、标签命中；mid 擅长该游戏；busy 有其他订单
+	top, mid, busy *model.Player
+}
+
+func newDispatchEnv(t *testing.T) *dispatchEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.PlayerGame{}, &model.PlayerSkillTag{}, &model.Order{},
+		&model.OrderStatusHistory{}, &model.DispatchOffer{}, &model.OrderAssignment{}, &model.NotificationEvent{}))
+
+	env := &dispatchEnv{db: db, now: time.Now()}
+	env.top = &model.Player{UserID: 101, Nickname: "top", MainGameID: 7, RatingAverage: 5, HourlyRateCents: 5000, VerificationStatus: model.VerificationVerified}
+	env.mid = &model.Player{UserID: 102, Nickname: "mid", MainGameID: 8, RatingAverage: 4, HourlyRateCents: 5000, VerificationStatus: model.VerificationVerified}
+	env.busy = &model.Player{UserID: 103, Nickname: "busy", MainGameID: 7, RatingAverage: 4, HourlyRateCents: 20000, VerificationStatus: model.VerificationVerified}
+	for _, p := range []*model.Player{env.top, env.mid, env.busy} {
+		require.NoError(t, db.Create(p).Error)
+	}
+	require.NoError(t, db.Create(&model.PlayerGame{PlayerID: env.mid.ID, GameID: 7}).Error)
+	require.NoError(t, db.Create(&model.PlayerSkillTag{PlayerID: env.top.ID, Tag: "上分"}).Error)
+	busyOrder := &model.Order{UserID: 9, Status: model.OrderStatusInProgress, TotalPriceCents: 100}
+	busyOrder.SetPlayerID(env.busy.ID)
+	require.NoError(t, db.Create(busyOrder).Error)
+
+	orders := orderrepo.NewOrderRepository(db)
+	env.svc = NewService(dispatchrepo.NewDispatchRepository(db), orders, playerrepo.NewPlayerRepository(db))
+	env.svc.SetStatusHistory(orderhistory.NewHistoryRepository(db))
+	env.svc.SetTags(playertagrepo.NewPlayerTagRepository(db))
+	env.svc.SetPresence(stubPresence{env.top.ID: true})
+	env.svc.SetNotifications(notificationrepo.NewNotificationRepository(db))
+	env.svc.now = func() time.Time { return env.now }
+	return env
+}
+
+func (e *dispatchEnv) newOrder(t *testing.T) *model.Order {
+	t.Helper()
+	gameID := uint64(7)
+	start := e.now.Add(24 * time.Hour)
+	end := start.Add(2 * time.Hour)
+	o := &model.Order{UserID: 1, Status: model.OrderStatusConfirmed, GameID: &gameID, Title: "上分陪练",
+		TotalPriceCents: 10000, ScheduledStart: &start, ScheduledEnd: &end}
+	require.NoError(t, e.db.Create(o).Error)
+	return o
+}
+
+func (e *dispatchEnv) order(t *testing.T, id uint64) model.Order {
+	t.Helper()
+	var o model.Order
+	require.NoError(t, e.db.First(&o, id).Error)
+	return o
+}
+
+func TestRecommend(t *testing.T) {
+	env := newDispatchEnv(t)
+	order := env.newOrder(t)
+
+	candidates, err := env.svc.Recommend(context.Background(), order.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, candidates, 3)
+	assert.Equal(t, []uint64{env.top.ID, env.mid.ID, env.busy.ID},
+		[]uint64{candidates[0].PlayerID, candidates[1].PlayerID, candidates[2].PlayerID})
+
+	top := candidates[0]
+	assert.True(t, top.Online)
+	assert.Equal(t, []string{"上分"}, top.MatchedTags)
+	assert.InDelta(t, 1.0, top.Breakdown.Game, 1e-9)
+	assert.InDelta(t, secondaryGameScore, candidates[1].Breakdown.Game, 1e-9)
+	// busy 有一单服务中，时薪 200 元 × 2 小时远高于订单金额
+	assert.EqualValues(t, 1, candidates[2].ActiveOrders)
+	assert.InDelta(t, 0.5, candidates[2].Breakdown.Load, 1e-9)
+	assert.InDelta(t, 0.25, candidates[2].Breakdown.Price, 1e-9)
+
+	limited, err := env.svc.Recommend(context.Background(), order.ID, 1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+}
+
+func TestDispatch_PushAndAccept(t *testing.T) {
+	env := newDispatchEnv(t)
+	ctx := context.Background()
+	order := env.newOrder(t)
+
+	res, err := env.svc.Dispatch(ctx, order.ID, DispatchRequest{Mode: model.DispatchModePush, TopN: 2})
+	require.NoError(t, err)
+	require.Len(t, res.Offers, 2)
+	assert.Equal(t, env.top.ID, res.Offers[0].PlayerID)
+	assert.Equal(t, env.now.Add(DefaultPolicy.OfferTimeout), res.Offers[0].ExpiresAt)
+
+	// 邀请期内订单只保留给被邀请的陪玩师
+	reserved, err := env.svc.IsReserved(ctx, order.ID, env.busy.ID)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	reserved, err = env.svc.IsReserved(ctx, order.ID, env.mid.ID)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+
+	// 非本人的邀请不可操作
+	_, err = env.svc.AcceptOffer(ctx, env.top.UserID, res.Offers[1].ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assignment, err := env.svc.AcceptOffer(ctx, env.mid.UserID, res.Offers[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AssignmentSourceSystem, assignment.Source)
+	assert.Equal(t, model.DispatchModePush, assignment.Mode)
+	require.NotNil(t, assignment.OfferID)
+	assert.Equal(t, res.Offers[1].ID, *assignment.OfferID)
+
+	saved := env.order(t, order.ID)
+	assert.Equal(t, model.OrderStatusInProgress, saved.Status)
+	assert.Equal(t, env.mid.ID, saved.GetPlayerID())
+
+	// 其余邀请被取消，再接受返回已失效
+	_, err = env.svc.AcceptOffer(ctx, env.top.UserID, res.Offers[0].ID)
+	assert.ErrorIs(t, err, ErrOfferClosed)
+	offers, err := env.svc.ListMyOffers(ctx, env.top.UserID, "")
+	require.NoError(t, err)
+	require.Len(t, offers, 1)
+	assert.Equal(t, model.DispatchOfferCanceled, offers[0].Status)
+
+	var notices int64
+	require.NoError(t, env.db.Model(&model.NotificationEvent{}).Count(&notices).Error)
+	assert.EqualValues(t, 2, notices)
+}
+
+func TestDispatch_Auto(t *testing.T) {
+	env := newDispatchEnv(t)
+	ctx := context.Background()
+	order := env.newOrder(t)
+
+	res, err := env.svc.Dispatch(ctx, order.ID, DispatchRequest{Mode: model.DispatchModeAuto})
+	require.NoError(t, err)
+	require.NotNil(t, res.Assignment)
+	assert.Equal(t, env.top.ID, res.Assignment.PlayerID)
+	assert.Equal(t, model.AssignmentSourceSystem, res.Assignment.Source)
+	assert.Nil(t, res.Assignment.ActorUserID)
+
+	saved := env.order(t, order.ID)
+	assert.Equal(t, model.OrderStatusConfirmed, saved.Status)
+	assert.Equal(t, env.top.ID, saved.GetPlayerID())
+
+	_, err = env.svc.Dispatch(ctx, order.ID, DispatchRequest{Mode: "random"})
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestDispatch_SkipsConflictingPlayers(t *testing.T) {
+	env := newDispatchEnv(t)
+	env.svc.SetBookingChecker(conflictWith{env.top.ID})
+	order := env.newOrder(t)
+
+	candidates, err := env.svc.Recommend(context.Background(), order.ID, 0)
+	require.NoError(t, err)
+	for _, c := range candidates {
+		assert.NotEqual(t, env.top.ID, c.PlayerID)
+	}
+}
+
+func TestProcessDispatch(t *testing.T) {
+	env := newDispatchEnv(t)
+	ctx := context.Background()
+	env.svc.SetPolicy(Policy{Mode: model.DispatchModePush, TopN: 1, OfferTimeout: time.Minute})
+	order := env.newOrder(t)
+
+	res, err := env.svc.ProcessDispatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, ProcessResult{Dispatched: 1}, res)
+
+	// 拒绝后不会重复派单
+	offers, err := env.svc.ListMyOffers(ctx, env.top.UserID, model.DispatchOfferPending)
+	require.NoError(t, err)
+	require.Len(t, offers, 1)
+	require.NoError(t, env.svc.DeclineOffer(ctx, env.top.UserID, offers[0].ID))
+	assert.ErrorIs(t, env.svc.DeclineOffer(ctx, env.top.UserID, offers[0].ID), ErrOfferClosed)
+
+	res, err = env.svc.ProcessDispatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, res.Dispatched)
+
+	// 邀请超时后过期，订单回落抢单大厅
+	second := env.newOrder(t)
+	_, err = env.svc.ProcessDispatch(ctx, 10)
+	require.NoError(t, err)
+	env.now = env.now.Add(2 * time.Minute)
+	res, err = env.svc.ProcessDispatch(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Expired)
+	reserved, err := env.svc.IsReserved(ctx, second.ID, 0)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, model.OrderStatusConfirmed, env.order(t, order.ID).Status)
+}
+
+type conflictWith []uint64
+
+func (c conflictWith) CheckConflict(_ context.Context, playerID uint64, _, _ time.Time, _ uint64) error {
+	for _, id := range c {
+		if id == playerID {
+			return availability.ErrBookingConflict
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/service/availability"
+)
+
+// 各评分项权重，合计为 1
+const (
+	weightGame       = 0.25
+	weightTags       = 0.10
+	weightRating     = 0.20
+	weightOnline     = 0.15
+	weightLoad       = 0.10
+	weightAcceptance = 0.10
+	weightPrice      = 0.10
+
+	// secondaryGameScore 擅长但非主玩游戏的匹配分
+	secondaryGameScore = 0.6
+	// tagsForFullScore 命中该数量的技能标签即得满分
+	tagsForFullScore = 2
+)
+
+// ScoreBreakdown 各评分项得分（0~1，未加权）
+type ScoreBreakdown struct {
+	Game       float64 `json:"game"`
+	Tags       float64 `json:"tags"`
+	Rating     float64 `json:"rating"`
+	Online     float64 `json:"online"`
+	Load       float64 `json:"load"`
+	Acceptance float64 `json:"acceptance"`
+	Price      float64 `json:"price"`
+}
+
+// Total 加权总分
+func (b ScoreBreakdown) Total() float64 {
+	total := b.Game*weightGame + b.Tags*weightTags + b.Rating*weightRating + b.Online*weightOnline +
+		b.Load*weightLoad + b.Acceptance*weightAcceptance + b.Price*weightPrice
+	return math.Round(total*10000) / 10000
+}
+
+// Candidate 候选陪玩师
+type Candidate struct {
+	PlayerID     uint64         `json:"playerId"`
+	Nickname     string         `json:"nickname"`
+	Score        float64        `json:"score"`
+	Breakdown    ScoreBreakdown `json:"breakdown"`
+	Online       bool           `json:"online"`
+	ActiveOrders int64          `json:"activeOrders"`
+	MatchedTags  []string       `json:"matchedTags,omitempty"`
+}
+
+// rank 为订单的候选陪玩师打分并排序。
+// 候选范围为主玩或擅长该游戏的已认证陪玩师，排除下单用户本人与预约时间冲突的陪玩师。
+func (s *Service) rank(ctx context.Context, order *model.Order) ([]Candidate, error) {
+	gameID := order.GetGameID()
+	players, err := s.dispatch.ListCandidates(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	eligible := make([]model.Player, 0, len(players))
+	for _, p := range players {
+		if p.UserID == order.UserID {
+			continue
+		}
+		if err := s.checkConflict(ctx, p.ID, order); err != nil {
+			if isBookingConflict(err) {
+				continue
+			}
+			return nil, err
+		}
+		eligible = append(eligible, p)
+	}
+	if len(eligible) == 0 {
+		return []Candidate{}, nil
+	}
+
+	ids := make([]uint64, 0, len(eligible))
+	for _, p := range eligible {
+		ids = append(ids, p.ID)
+	}
+	stats, err := s.dispatch.AcceptanceStats(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ToLower(order.Title + " " + order.Description)
+
+	out := make([]Candidate, 0, len(eligible))
+	for i := range eligible {
+		p := &eligible[i]
+		load, err := s.activeOrders(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		c := Candidate{PlayerID: p.ID, Nickname: p.Nickname, ActiveOrders: load}
+		c.Breakdown.Game = 1
+		if gameID != 0 && p.MainGameID != gameID {
+			c.Breakdown.Game = secondaryGameScore
+		}
+		if s.tags != nil {
+			if tags, err := s.tags.GetTags(ctx, p.ID); err == nil {
+				for _, tag := range tags {
+					if t := strings.ToLower(strings.TrimSpace(tag)); t != "" && strings.Contains(text, t) {
+						c.MatchedTags = append(c.MatchedTags, tag)
+					}
+				}
+				c.Breakdown.Tags = math.Min(1, float64(len(c.MatchedTags))/tagsForFullScore)
+			}
+		}
+		c.Breakdown.Rating = math.Min(1, float64(p.RatingAverage)/5)
+		if s.presence != nil && s.presence.IsPlayerOnline(ctx, p.ID) {
+			c.Online = true
+			c.Breakdown.Online = 1
+		}
+		c.Breakdown.Load = 1 / float64(1+load)
+		st := stats[p.ID]
+		// 拉普拉斯平滑，新陪玩师按 50% 接单率计
+		c.Breakdown.Acceptance = float64(st.Accepted+1) / float64(st.Total+2)
+		c.Breakdown.Price = priceFit(order, p)
+		c.Score = c.Breakdown.Total()
+		out = append(out, c)
+	}
+	sortCandidates(out)
+	return out, nil
+}
+
+// activeOrders 陪玩师已指派或服务中的订单数
+func (s *Service) activeOrders(ctx context.Context, playerID uint64) (int64, error) {
+	_, total, err := s.orders.List(ctx, repository.OrderListOptions{
+		PlayerID: &playerID,
+		Statuses: []model.OrderStatus{model.OrderStatusConfirmed, model.OrderStatusInProgress},
+		Page:     1,
+		PageSize: 1,
+	})
+	return total, err
+}
+
+// priceFit 订单金额能否覆盖陪玩师时薪：订单金额 / (时薪 × 预约时长)，最高为 1。
+// 未设置时薪的陪玩师视为完全匹配；未设置预约时长按 1 小时计。
+func priceFit(order *model.Order, p *model.Player) float64 {
+	if p.HourlyRateCents <= 0 {
+		return 1
+	}
+	hours := 1.0
+	if order.ScheduledStart != nil && order.ScheduledEnd != nil && order.ScheduledEnd.After(*order.ScheduledStart) {
+		hours = order.ScheduledEnd.Sub(*order.ScheduledStart).Hours()
+	}
+	expected := float64(p.HourlyRateCents) * hours
+	return math.Min(1, float64(order.TotalPriceCents)/expected)
+}
+
+// isBookingConflict 陪玩师该时段已有订单，跳过而非报错
+func isBookingConflict(err error) bool {
+	return errors.Is(err, availability.ErrBookingConflict)
+}
+
+// sortCandidates 按评分从高到低排序，同分时按陪玩师 ID
+func sortCandidates(cs []Candidate) {
+	sort.SliceStable(cs, func(i, j int) bool {
+		if cs[i].Score != cs[j].Score {
+			return cs[i].Score > cs[j].Score
+		}
+		return cs[i].PlayerID < cs[j].PlayerID
+	})
+}
@@ -7,7 +7,10 @@ import (
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	walletrepo "gamelink/internal/repository/wallet"
 	withdrawrepo "gamelink/internal/repository/withdraw"
+	"gamelink/internal/service/wallet"
 )
 
 var (
@@ -19,6 +22,8 @@ var (
 	ErrInsufficientBalance = errors.New("insufficient balance")
 	// ErrUnauthorized 无权操作
 	ErrUnauthorized = errors.New("unauthorized")
+	// ErrWalletBusy 钱包并发更新冲突，稍后重试
+	ErrWalletBusy = wallet.ErrConcurrentUpdate
 )
 
 // WithdrawStatus 提现状态
@@ -40,11 +45,31 @@ const (
 // 功能：
 // 1. 收益概览
 // 2. 收益趋势
-// 3. 提现管理
+// 3. 提现管理：注入钱包后，申请提现与冻结余额在同一事务内完成
 type EarningsService struct {
 	players   repository.PlayerRepository
 	orders    repository.OrderRepository
 	withdraws withdrawrepo.WithdrawRepository
+	wallet    EarningsWallet
+	tx        TxManager
+	fx        CurrencyConverter
+}
+
+// EarningsWallet 陪玩师钱包（由钱包服务实现）
+type EarningsWallet interface {
+	ListWallets(ctx context.Context, playerID uint64) ([]model.PlayerWallet, error)
+	ListTransactions(ctx context.Context, opts walletrepo.TransactionListOptions) ([]model.WalletTransaction, int64, error)
+	FreezeWithdraw(ctx context.Context, r *common.Repos, withdraw *model.Withdraw) error
+}
+
+// CurrencyConverter 按汇率换算金额（由汇率服务实现）
+type CurrencyConverter interface {
+	Convert(ctx context.Context, amountCents int64, from, to model.Currency) (int64, error)
+}
+
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
 }
 
 // NewEarningsService 创建收益服务
@@ -60,14 +85,38 @@ func NewEarningsService(
 	}
 }
 
+// SetWallet 注入钱包服务，余额以钱包为准
+func (s *EarningsService) SetWallet(w EarningsWallet) { s.wallet = w }
+
+// SetTxManager 注入事务管理器，提现记录与余额冻结同时提交
+func (s *EarningsService) SetTxManager(tx TxManager) { s.tx = tx }
+
+// SetFX 注入汇率服务，收益统计把外币订单折算为本位币
+func (s *EarningsService) SetFX(fx CurrencyConverter) { s.fx = fx }
+
 // EarningsSummaryResponse 收益概览响应
+//
+// 金额字段以 Currency（本位币）计价：收益按汇率折算，余额取本位币钱包；各币种钱包余额见 Balances。
 type EarningsSummaryResponse struct {
-	TodayEarnings    int64 `json:"todayEarnings"`    // 今日收益（分）
-	MonthEarnings    int64 `json:"monthEarnings"`    // 本月收益
-	TotalEarnings    int64 `json:"totalEarnings"`    // 累计收益
-	AvailableBalance int64 `json:"availableBalance"` // 可提现余额
-	PendingBalance   int64 `json:"pendingBalance"`   // 待结算余额
-	WithdrawTotal    int64 `json:"withdrawTotal"`    // 累计提现
+	Currency         model.Currency       `json:"currency"`
+	TodayEarnings    int64                `json:"todayEarnings"`    // 今日收益（分）
+	MonthEarnings    int64                `json:"monthEarnings"`    // 本月收益
+	TotalEarnings    int64                `json:"totalEarnings"`    // 累计收益
+	AvailableBalance int64                `json:"availableBalance"` // 可提现余额
+	PendingBalance   int64                `json:"pendingBalance"`   // 待结算余额
+	FrozenBalance    int64                `json:"frozenBalance"`    // 提现冻结中
+	WithdrawTotal    int64                `json:"withdrawTotal"`    // 累计提现
+	Balances         []CurrencyBalanceDTO `json:"balances"`
+}
+
+// CurrencyBalanceDTO 单一币种钱包余额
+type CurrencyBalanceDTO struct {
+	Currency         model.Currency `json:"currency"`
+	AvailableBalance int64          `json:"availableBalance"`
+	PendingBalance   int64          `json:"pendingBalance"`
+	FrozenBalance    int64          `json:"frozenBalance"`
+	TotalIncome      int64          `json:"totalIncome"`
+	WithdrawTotal    int64          `json:"withdrawTotal"`
 }
 
 // DailyEarningDTO 每日收益
@@ -84,7 +133,8 @@ type EarningsTrendResponse struct {
 
 // WithdrawRequest 提现请求
 type WithdrawRequest struct {
-	AmountCents int64  `json:"amountCents" binding:"required,min=10000"` // 最低100元
+	AmountCents int64  `json:"amountCents" binding:"required,min=10000"`       // 最低100元
+	Currency    string `json:"currency" binding:"omitempty,oneof=CNY USD EUR"` // 从该币种钱包提现，默认 CNY
 	Method      string `json:"method" binding:"required,oneof=alipay wechat bank"`
 	AccountInfo string `json:"accountInfo" binding:"required"` // 账号信息
 }
@@ -97,12 +147,13 @@ type WithdrawResponse struct {
 
 // WithdrawRecordDTO 提现记录
 type WithdrawRecordDTO struct {
-	ID          uint64     `json:"id"`
-	AmountCents int64      `json:"amountCents"`
-	Method      string     `json:"method"`
-	Status      string     `json:"status"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	ProcessedAt *time.Time `json:"processedAt"`
+	ID          uint64         `json:"id"`
+	AmountCents int64          `json:"amountCents"`
+	Currency    model.Currency `json:"currency"`
+	Method      string         `json:"method"`
+	Status      string         `json:"status"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	ProcessedAt *time.Time     `json:"processedAt"`
 }
 
 // WithdrawHistoryResponse 提现记录响应
@@ -163,14 +214,41 @@ func (s *EarningsService) GetEarningsSummary(ctx context.Context, userID uint64)
 		}
 	}
 
-	return &EarningsSummaryResponse{
+	resp := &EarningsSummaryResponse{
+		Currency:         model.DefaultCurrency,
 		TodayEarnings:    todayEarnings,
 		MonthEarnings:    monthEarnings,
 		TotalEarnings:    balance.TotalEarnings,
 		AvailableBalance: balance.AvailableBalance,
 		PendingBalance:   balance.PendingBalance,
+		FrozenBalance:    balance.PendingWithdraw,
 		WithdrawTotal:    balance.WithdrawTotal,
-	}, nil
+		Balances:         []CurrencyBalanceDTO{},
+	}
+	if s.wallet != nil {
+		wallets, err := s.wallet.ListWallets(ctx, player.ID)
+		if err != nil {
+			return nil, err
+		}
+		resp.AvailableBalance, resp.PendingBalance, resp.FrozenBalance, resp.WithdrawTotal = 0, 0, 0, 0
+		for _, w := range wallets {
+			resp.Balances = append(resp.Balances, CurrencyBalanceDTO{
+				Currency:         w.Currency,
+				AvailableBalance: w.AvailableCents,
+				PendingBalance:   w.PendingCents,
+				FrozenBalance:    w.FrozenCents,
+				TotalIncome:      w.TotalIncomeCents,
+				WithdrawTotal:    w.TotalWithdrawnCents,
+			})
+			if w.Currency.OrDefault() == model.DefaultCurrency {
+				resp.AvailableBalance = w.AvailableCents
+				resp.PendingBalance = w.PendingCents
+				resp.FrozenBalance = w.FrozenCents
+				resp.WithdrawTotal = w.TotalWithdrawnCents
+			}
+		}
+	}
+	return resp, nil
 }
 
 // GetEarningsTrend 获取收益趋势
@@ -227,15 +305,13 @@ func (s *EarningsService) RequestWithdraw(ctx context.Context, userID uint64, re
 		return nil, err
 	}
 
-	// 获取可提现余额
-	summary, err := s.GetEarningsSummary(ctx, userID)
-	if err != nil {
-		return nil, err
+	currency := model.Currency(req.Currency).OrDefault()
+	if !model.IsValidCurrency(currency) {
+		return nil, ErrValidation
 	}
-
-	// 检查余额
-	if summary.AvailableBalance < req.AmountCents {
-		return nil, ErrInsufficientBalance
+	if s.wallet == nil && currency != model.DefaultCurrency {
+		// 未接入钱包时余额按本位币估算，无法校验外币余额
+		return nil, ErrValidation
 	}
 
 	// 创建提现记录
@@ -243,11 +319,40 @@ func (s *EarningsService) RequestWithdraw(ctx context.Context, userID uint64, re
 		PlayerID:    player.ID,
 		UserID:      userID,
 		AmountCents: req.AmountCents,
+		Currency:    currency,
 		Method:      model.WithdrawMethod(req.Method),
-		AccountInfo: req.AccountInfo, // TODO: 需要加密存储敏感信息
+		AccountInfo: req.AccountInfo, // 由模型序列化器加密存储
 		Status:      model.WithdrawStatusPending,
 	}
 
+	if s.wallet != nil {
+		// 提现记录与冻结一起提交：冻结时锁定钱包行，并发申请不会超额
+		err = s.withTx(ctx, func(r *common.Repos) error {
+			if err := r.Withdraws.Create(ctx, withdraw); err != nil {
+				return err
+			}
+			return s.wallet.FreezeWithdraw(ctx, r, withdraw)
+		})
+		if errors.Is(err, wallet.ErrInsufficientBalance) {
+			return nil, ErrInsufficientBalance
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &WithdrawResponse{WithdrawID: withdraw.ID, Status: string(withdraw.Status)}, nil
+	}
+
+	// 获取可提现余额
+	summary, err := s.GetEarningsSummary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查余额
+	if summary.AvailableBalance < req.AmountCents {
+		return nil, ErrInsufficientBalance
+	}
+
 	if err := s.withdraws.Create(ctx, withdraw); err != nil {
 		return nil, err
 	}
@@ -258,6 +363,41 @@ func (s *EarningsService) RequestWithdraw(ctx context.Context, userID uint64, re
 	}, nil
 }
 
+// WalletTransactionsResponse 钱包流水响应
+type WalletTransactionsResponse struct {
+	Records []model.WalletTransaction `json:"records"`
+	Total   int64                     `json:"total"`
+}
+
+// GetWalletTransactions 获取钱包流水
+func (s *EarningsService) GetWalletTransactions(ctx context.Context, userID uint64, page, pageSize int) (*WalletTransactionsResponse, error) {
+	if s.wallet == nil {
+		return &WalletTransactionsResponse{Records: []model.WalletTransaction{}}, nil
+	}
+	player, err := s.findPlayerByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	records, total, err := s.wallet.ListTransactions(ctx, walletrepo.TransactionListOptions{
+		PlayerID: player.ID,
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		records = []model.WalletTransaction{}
+	}
+	return &WalletTransactionsResponse{Records: records, Total: total}, nil
+}
+
 // GetWithdrawHistory 获取提现记录
 func (s *EarningsService) GetWithdrawHistory(ctx context.Context, userID uint64, page, pageSize int) (*WithdrawHistoryResponse, error) {
 	// 查找陪玩师
@@ -291,6 +431,7 @@ func (s *EarningsService) GetWithdrawHistory(ctx context.Context, userID uint64,
 		records = append(records, WithdrawRecordDTO{
 			ID:          w.ID,
 			AmountCents: w.AmountCents,
+			Currency:    w.Currency.OrDefault(),
 			Method:      string(w.Method),
 			Status:      string(w.Status),
 			CreatedAt:   w.CreatedAt,
@@ -321,7 +462,13 @@ func (s *EarningsService) calculateEarnings(ctx context.Context, playerID *uint6
 
 	var total int64
 	for _, o := range orders {
-		total += o.TotalPriceCents
+		amount := o.TotalPriceCents
+		if c := o.Currency.OrDefault(); c != model.DefaultCurrency && s.fx != nil {
+			if amount, err = s.fx.Convert(ctx, amount, c, model.DefaultCurrency); err != nil {
+				return 0, err
+			}
+		}
+		total += amount
 	}
 
 	return total, nil
@@ -359,3 +506,11 @@ func (s *EarningsService) findPlayerByUserID(ctx context.Context, userID uint64)
 
 	return nil, ErrNotFound
 }
+
+// withTx 未注入事务管理器时直接使用当前仓储（提现记录与冻结不在同一事务内）
+func (s *EarningsService) withTx(ctx context.Context, fn func(r *common.Repos) error) error {
+	if s.tx != nil {
+		return s.tx.WithTx(ctx, fn)
+	}
+	return fn(&common.Repos{Withdraws: s.withdraws})
+}
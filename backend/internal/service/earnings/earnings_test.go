@@ -5,9 +5,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	walletrepo "gamelink/internal/repository/wallet"
 	withdrawrepo "gamelink/internal/repository/withdraw"
+	"gamelink/internal/service/wallet"
 )
 
 // Mock repositories
@@ -374,6 +380,64 @@ func TestRequestWithdrawInsufficientBalance(t *testing.T) {
 	}
 }
 
+func TestRequestWithdrawWithWallet(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.Withdraw{}, &model.PlayerWallet{}, &model.WalletTransaction{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	uow := common.NewUnitOfWork(db)
+	walletSvc := wallet.NewWalletService(walletrepo.NewWalletRepository(db))
+	walletSvc.SetTxManager(uow)
+	if err := walletSvc.SettleIncome(ctx, nil, &model.CommissionRecord{ID: 1, PlayerID: 1, PlayerIncomeCents: 15000}); err != nil {
+		t.Fatal(err)
+	}
+
+	withdraws := withdrawrepo.NewWithdrawRepository(db)
+	svc := NewEarningsService(&mockPlayerRepository{}, newMockOrderRepository(), withdraws)
+	svc.SetWallet(walletSvc)
+	svc.SetTxManager(uow)
+
+	req := WithdrawRequest{AmountCents: 10000, Method: "alipay", AccountInfo: "test@example.com"}
+	resp, err := svc.RequestWithdraw(ctx, 1, req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.WithdrawID == 0 {
+		t.Fatal("expected withdraw ID")
+	}
+
+	// 剩余 50 元，第二笔提现失败且不留下提现记录
+	if _, err := svc.RequestWithdraw(ctx, 1, req); err != ErrInsufficientBalance {
+		t.Fatalf("expected ErrInsufficientBalance, got %v", err)
+	}
+	_, total, err := withdraws.List(ctx, withdrawrepo.WithdrawListOptions{})
+	if err != nil || total != 1 {
+		t.Fatalf("expected 1 withdraw, got %d (%v)", total, err)
+	}
+
+	summary, err := svc.GetEarningsSummary(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.AvailableBalance != 5000 || summary.FrozenBalance != 10000 {
+		t.Errorf("expected available 5000 / frozen 10000, got %d / %d", summary.AvailableBalance, summary.FrozenBalance)
+	}
+
+	txs, err := svc.GetWalletTransactions(ctx, 1, 1, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txs.Total != 3 || txs.Records[0].Type != model.WalletTxWithdrawFreeze {
+		t.Errorf("unexpected wallet transactions: %+v", txs)
+	}
+}
+
 func TestGetWithdrawHistory(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	withdrawRepo := newMockWithdrawRepository()
@@ -0,0 +1,441 @@
+// Package extension 订单加时：服务中的订单由用户申请加时、陪玩师确认，补款成功后延长预约结束时间并计入订单金额与抽成。
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	extensionrepo "gamelink/internal/repository/extension"
+	"gamelink/internal/service/availability"
+	"gamelink/internal/service/orderstate"
+	"gamelink/internal/service/pricing"
+)
+
+var (
+	// ErrNotFound 订单或加时申请不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrForbidden 不是订单用户或服务陪玩师
+	ErrForbidden = errors.New("permission denied")
+	// ErrNotPlayer 用户不是陪玩师
+	ErrNotPlayer = errors.New("user is not a player")
+	// ErrOrderNotInProgress 只有服务中的订单可以加时
+	ErrOrderNotInProgress = errors.New("order is not in progress")
+	// ErrExtensionPending 订单已有待处理或待补款的加时申请
+	ErrExtensionPending = errors.New("order already has a pending extension")
+	// ErrInvalidStatus 加时申请当前状态不允许该操作
+	ErrInvalidStatus = errors.New("invalid extension status")
+	// ErrBookingConflict 加时后的时段与陪玩师其他订单重叠
+	ErrBookingConflict = availability.ErrBookingConflict
+)
+
+// OrderPricer 按服务项目、陪玩师时薪与抽成规则报价（由定价服务实现）
+type OrderPricer interface {
+	Quote(ctx context.Context, req pricing.QuoteRequest) (*pricing.Quote, error)
+}
+
+// BookingChecker 校验加时后的时段是否与陪玩师其他订单冲突（由陪玩师日程服务实现）
+type BookingChecker interface {
+	CheckBookable(ctx context.Context, playerID uint64, start, end time.Time, exceptOrderID uint64) error
+}
+
+// RequestExtensionRequest 用户申请加时
+type RequestExtensionRequest struct {
+	DurationHours float32 `json:"durationHours" binding:"required,min=0.5,max=12"`
+	Note          string  `json:"note" binding:"max=500"`
+}
+
+// RespondExtensionRequest 陪玩师处理加时申请
+type RespondExtensionRequest struct {
+	Accept bool   `json:"accept"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// Service 订单加时服务
+type Service struct {
+	extensions    extensionrepo.ExtensionRepository
+	orders        repository.OrderRepository
+	players       repository.PlayerRepository
+	pricer        OrderPricer
+	bookings      BookingChecker
+	opLogs        repository.OperationLogRepository
+	notifications repository.NotificationRepository
+	now           func() time.Time
+}
+
+// NewService 创建订单加时服务
+func NewService(extensions extensionrepo.ExtensionRepository, orders repository.OrderRepository, players repository.PlayerRepository) *Service {
+	return &Service{extensions: extensions, orders: orders, players: players, now: time.Now}
+}
+
+// SetPricer 注入定价服务；未注入时按陪玩师时薪与默认抽成计价
+func (s *Service) SetPricer(p OrderPricer) { s.pricer = p }
+
+// SetBookingChecker 注入日程校验，陪玩师同意加时前检查后续订单冲突
+func (s *Service) SetBookingChecker(b BookingChecker) { s.bookings = b }
+
+// SetOperationLogs 注入操作日志，加时申请与处理写入订单时间线
+func (s *Service) SetOperationLogs(l repository.OperationLogRepository) { s.opLogs = l }
+
+// SetNotifications 注入站内通知
+func (s *Service) SetNotifications(n repository.NotificationRepository) { s.notifications = n }
+
+// RequestExtension 用户为服务中的订单申请加时，按订单的服务项目与陪玩师报价
+func (s *Service) RequestExtension(ctx context.Context, userID, orderID uint64, req RequestExtensionRequest) (*model.OrderExtension, error) {
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		return nil, ErrForbidden
+	}
+	if order.Status != model.OrderStatusInProgress {
+		return nil, ErrOrderNotInProgress
+	}
+	if order.IsTeamOrder() || order.IsGiftOrder() || order.GetPlayerID() == 0 {
+		return nil, fmt.Errorf("%w: only escort orders served by a single player can be extended", ErrValidation)
+	}
+	if _, err := s.extensions.GetOpenByOrder(ctx, orderID); err == nil {
+		return nil, ErrExtensionPending
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	ext := &model.OrderExtension{
+		OrderID:         order.ID,
+		UserID:          userID,
+		PlayerID:        order.GetPlayerID(),
+		DurationMinutes: int(pricing.DurationMinutes(req.DurationHours)),
+		Currency:        order.Currency.OrDefault(),
+		Status:          model.OrderExtensionRequested,
+		Note:            strings.TrimSpace(req.Note),
+	}
+	if err := s.price(ctx, order, req.DurationHours, ext); err != nil {
+		return nil, err
+	}
+	if err := s.extensions.Create(ctx, ext); err != nil {
+		return nil, err
+	}
+
+	s.appendLog(ctx, order.ID, &userID, model.OpActionRequestExtension, ext.Note, ext)
+	if player, err := s.players.Get(ctx, ext.PlayerID); err == nil {
+		s.notify(ctx, player.UserID, order, "加时申请", fmt.Sprintf("订单 %s 申请加时 %d 分钟，请确认", orderLabel(order), ext.DurationMinutes))
+	}
+	return ext, nil
+}
+
+// RespondExtension 服务陪玩师同意或拒绝加时申请。
+//
+// 同意即表示陪玩师愿意在可接单时段外继续服务，因此只检查加时后的时段不与其他订单冲突。
+func (s *Service) RespondExtension(ctx context.Context, playerUserID, orderID, extensionID uint64, req RespondExtensionRequest) (*model.OrderExtension, error) {
+	player, err := s.players.GetByUserID(ctx, playerUserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotPlayer
+	}
+	if err != nil {
+		return nil, err
+	}
+	ext, err := s.orderExtension(ctx, orderID, extensionID)
+	if err != nil {
+		return nil, err
+	}
+	if ext.PlayerID != player.ID {
+		return nil, ErrForbidden
+	}
+	if ext.Status != model.OrderExtensionRequested {
+		return nil, ErrInvalidStatus
+	}
+	order, err := s.orders.Get(ctx, ext.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != model.OrderStatusInProgress {
+		return nil, ErrOrderNotInProgress
+	}
+
+	now := s.now()
+	ext.RespondedAt = &now
+	action := model.OpActionDeclineExtension
+	if req.Accept {
+		if s.bookings != nil {
+			end := scheduledEnd(order, now)
+			err := s.bookings.CheckBookable(ctx, player.ID, end, end.Add(time.Duration(ext.DurationMinutes)*time.Minute), order.ID)
+			if err != nil && !errors.Is(err, availability.ErrUnavailable) {
+				return nil, err
+			}
+		}
+		ext.Status = model.OrderExtensionAccepted
+		action = model.OpActionAcceptExtension
+	} else {
+		ext.Status = model.OrderExtensionDeclined
+		ext.DeclineReason = strings.TrimSpace(req.Reason)
+	}
+	ok, err := s.extensions.Transit(ctx, ext, model.OrderExtensionRequested)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidStatus
+	}
+
+	s.appendLog(ctx, order.ID, &playerUserID, action, ext.DeclineReason, ext)
+	if req.Accept {
+		s.notify(ctx, order.UserID, order, "加时已同意", fmt.Sprintf("陪玩师已同意订单 %s 加时 %d 分钟，请完成补款", orderLabel(order), ext.DurationMinutes))
+	} else {
+		s.notify(ctx, order.UserID, order, "加时被拒绝", fmt.Sprintf("陪玩师拒绝了订单 %s 的加时申请", orderLabel(order)))
+	}
+	return ext, nil
+}
+
+// CancelExtension 用户撤回尚未补款的加时申请
+func (s *Service) CancelExtension(ctx context.Context, userID, orderID, extensionID uint64) (*model.OrderExtension, error) {
+	ext, err := s.orderExtension(ctx, orderID, extensionID)
+	if err != nil {
+		return nil, err
+	}
+	if ext.UserID != userID {
+		return nil, ErrForbidden
+	}
+	if !ext.IsOpen() {
+		return nil, ErrInvalidStatus
+	}
+	from := ext.Status
+	ext.Status = model.OrderExtensionCanceled
+	ok, err := s.extensions.Transit(ctx, ext, from)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidStatus
+	}
+	s.appendLog(ctx, ext.OrderID, &userID, model.OpActionCancelExtension, "", ext)
+	return ext, nil
+}
+
+// ListExtensions 订单用户或服务陪玩师查看订单的加时记录
+func (s *Service) ListExtensions(ctx context.Context, userID, orderID uint64) ([]model.OrderExtension, error) {
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != userID {
+		player, err := s.players.GetByUserID(ctx, userID)
+		if err != nil || player.ID != order.GetPlayerID() {
+			return nil, ErrForbidden
+		}
+	}
+	return s.extensions.ListByOrder(ctx, orderID)
+}
+
+// PayableExtension 校验补款对应的加时申请：属于该订单、陪玩师已同意且订单仍在服务中
+func (s *Service) PayableExtension(ctx context.Context, order *model.Order, extensionID uint64) (*model.OrderExtension, error) {
+	ext, err := s.extensions.Get(ctx, extensionID)
+	if err != nil {
+		return nil, err
+	}
+	if ext.OrderID != order.ID {
+		return nil, ErrNotFound
+	}
+	if ext.Status != model.OrderExtensionAccepted {
+		return nil, ErrInvalidStatus
+	}
+	if order.Status != model.OrderStatusInProgress {
+		return nil, ErrOrderNotInProgress
+	}
+	return ext, nil
+}
+
+// SettleExtension 补款成功（支付事务内调用）：标记加时已支付，延长预约结束时间，
+// 并将金额与抽成累加到订单，订单完成时一并计入同一条抽成记录。
+//
+// 订单行加锁后先以条件更新将加时申请标记为已支付，再累加订单；加时申请已失效（含并发撤回）
+// 或订单已不在服务中时不做任何改动并返回 false，由支付服务将补款整笔退回。
+func (s *Service) SettleExtension(ctx context.Context, r *common.Repos, payment *model.Payment, paidAt time.Time) (bool, error) {
+	if payment.ExtensionID == nil {
+		return false, nil
+	}
+	exts := s.extensions
+	orders := s.orders
+	if r != nil && r.Extensions != nil {
+		exts, orders = r.Extensions, r.Orders
+	}
+	ext, err := exts.Get(ctx, *payment.ExtensionID)
+	if err != nil {
+		return false, err
+	}
+	order, err := orderstate.Lock(ctx, orders, ext.OrderID)
+	if err != nil {
+		return false, err
+	}
+	if ext.Status != model.OrderExtensionAccepted || order.Status != model.OrderStatusInProgress {
+		slog.Warn("extension payment received for closed extension",
+			slog.Uint64("extension_id", ext.ID), slog.Uint64("payment_id", payment.ID),
+			slog.String("extension_status", string(ext.Status)), slog.String("order_status", string(order.Status)))
+		return false, nil
+	}
+
+	paymentID := payment.ID
+	ext.Status = model.OrderExtensionPaid
+	ext.PaymentID = &paymentID
+	ext.PaidAt = &paidAt
+	ok, err := exts.Transit(ctx, ext, model.OrderExtensionAccepted)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		slog.Warn("extension withdrawn before payment settled",
+			slog.Uint64("extension_id", ext.ID), slog.Uint64("payment_id", payment.ID))
+		return false, nil
+	}
+
+	end := scheduledEnd(order, paidAt).Add(time.Duration(ext.DurationMinutes) * time.Minute)
+	order.ScheduledEnd = &end
+	order.TotalPriceCents += ext.AmountCents
+	order.CommissionCents += ext.CommissionCents
+	order.PlayerIncomeCents += ext.PlayerIncomeCents
+	ok = true
+	if cu, isCAS := orders.(orderstate.ConditionalUpdater); isCAS {
+		ok, err = cu.UpdateIfStatus(ctx, order, model.OrderStatusInProgress)
+	} else {
+		err = orders.Update(ctx, order)
+	}
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		// 订单行已加锁，仅在未提供行锁的仓储下可能发生；回滚支付事务由渠道重新通知
+		return false, orderstate.ErrStatusConflict
+	}
+
+	opLogs := s.opLogs
+	if r != nil && r.OpLogs != nil {
+		opLogs = r.OpLogs
+	}
+	s.appendLogTo(ctx, opLogs, order.ID, &ext.UserID, model.OpActionExtendOrder, "", ext)
+	return true, nil
+}
+
+// orderExtension 读取订单下的加时申请
+func (s *Service) orderExtension(ctx context.Context, orderID, extensionID uint64) (*model.OrderExtension, error) {
+	ext, err := s.extensions.Get(ctx, extensionID)
+	if err != nil {
+		return nil, err
+	}
+	if ext.OrderID != orderID {
+		return nil, ErrNotFound
+	}
+	return ext, nil
+}
+
+// price 计算加时金额与抽成
+func (s *Service) price(ctx context.Context, order *model.Order, hours float32, ext *model.OrderExtension) error {
+	if s.pricer != nil {
+		quote, err := s.pricer.Quote(ctx, pricing.QuoteRequest{
+			ItemID:        order.ItemID,
+			GameID:        order.GetGameID(),
+			PlayerID:      order.GetPlayerID(),
+			DurationHours: hours,
+			Currency:      ext.Currency,
+		})
+		if errors.Is(err, pricing.ErrValidation) || errors.Is(err, pricing.ErrNoServiceItem) {
+			return fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+		if err != nil {
+			return err
+		}
+		ext.AmountCents = quote.TotalPriceCents
+		ext.CommissionRate = quote.CommissionRate
+		ext.CommissionCents = quote.CommissionCents
+		ext.PlayerIncomeCents = quote.PlayerIncomeCents
+		return nil
+	}
+
+	// 陪玩师时薪以人民币计价，其他币种须由定价服务换算
+	if ext.Currency != model.CurrencyCNY {
+		return fmt.Errorf("%w: currency %s is not available", ErrValidation, ext.Currency)
+	}
+	player, err := s.players.Get(ctx, ext.PlayerID)
+	if err != nil {
+		return err
+	}
+	ext.AmountCents = pricing.HourlyAmount(player.HourlyRateCents, int64(ext.DurationMinutes))
+	if ext.AmountCents <= 0 {
+		return fmt.Errorf("%w: player has no hourly rate", ErrValidation)
+	}
+	ext.CommissionRate = pricing.DefaultCommissionRate
+	ext.CommissionCents, ext.PlayerIncomeCents = pricing.SplitCommission(ext.AmountCents, ext.CommissionRate)
+	return nil
+}
+
+// scheduledEnd 当前预约结束时间；未设置或已超时时从 now 起算
+func scheduledEnd(order *model.Order, now time.Time) time.Time {
+	if order.ScheduledEnd != nil && order.ScheduledEnd.After(now) {
+		return *order.ScheduledEnd
+	}
+	return now
+}
+
+func (s *Service) appendLog(ctx context.Context, orderID uint64, actor *uint64, action model.OperationAction, reason string, ext *model.OrderExtension) {
+	s.appendLogTo(ctx, s.opLogs, orderID, actor, action, reason, ext)
+}
+
+// appendLogTo 写入订单操作日志，供订单时间线展示
+func (s *Service) appendLogTo(ctx context.Context, opLogs repository.OperationLogRepository, orderID uint64, actor *uint64, action model.OperationAction, reason string, ext *model.OrderExtension) {
+	if opLogs == nil {
+		return
+	}
+	meta, _ := json.Marshal(map[string]any{
+		"extensionId":     ext.ID,
+		"durationMinutes": ext.DurationMinutes,
+		"amountCents":     ext.AmountCents,
+		"currency":        ext.Currency,
+		"status":          ext.Status,
+	})
+	err := opLogs.Append(ctx, &model.OperationLog{
+		EntityType:   string(model.OpEntityOrder),
+		EntityID:     orderID,
+		ActorUserID:  actor,
+		Action:       string(action),
+		Reason:       reason,
+		MetadataJSON: meta,
+	})
+	if err != nil {
+		slog.Warn("append extension log failed", slog.Uint64("order_id", orderID), slog.String("error", err.Error()))
+	}
+}
+
+// notify 通知用户，失败只记录日志
+func (s *Service) notify(ctx context.Context, userID uint64, order *model.Order, title, message string) {
+	if s.notifications == nil {
+		return
+	}
+	id := order.ID
+	err := s.notifications.Create(ctx, &model.NotificationEvent{
+		UserID:        userID,
+		Title:         title,
+		Message:       message,
+		Priority:      model.NotificationPriorityHigh,
+		ReferenceType: string(model.OpEntityOrder),
+		ReferenceID:   &id,
+	})
+	if err != nil {
+		slog.Warn("notify order extension failed", slog.Uint64("order_id", order.ID), slog.Uint64("user_id", userID), slog.String("error", err.Error()))
+	}
+}
+
+// orderLabel 通知中展示的订单标识
+func orderLabel(order *model.Order) string {
+	if order.OrderNo != "" {
+		return order.OrderNo
+	}
+	return fmt.Sprintf("#%d", order.ID)
+}
@@ -0,0 +1,245 @@
+package extension
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+	extensionrepo "gamelink/internal/repository/extension"
+	operationlogrepo "gamelink/internal/repository/operation_log"
+	orderrepo "gamelink/internal/repository/order"
+	paymentrepo "gamelink/internal/repository/payment"
+	playerrepo "gamelink/internal/repository/player"
+	"gamelink/internal/service/availability"
+	paymentservice "gamelink/internal/service/payment"
+)
+
+type extensionEnv struct {
+	db      *gorm.DB
+	svc     *Service
+	payment *paymentservice.PaymentService
+	sandbox *paymentservice.SandboxServer
+	order   *model.Order
+	player  *model.Player
+}
+
+func newExtensionEnv(t *testing.T) *extensionEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	// 内存库每个连接各自独立，支付事务须共用同一连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.OrderExtension{}, &model.Payment{},
+		&model.PaymentCallback{}, &model.Refund{}, &model.OperationLog{}))
+
+	env := &extensionEnv{db: db}
+	env.player = &model.Player{UserID: 20, HourlyRateCents: 6000, VerificationStatus: model.VerificationVerified}
+	require.NoError(t, db.Create(env.player).Error)
+	started := time.Now().Add(-30 * time.Minute)
+	end := started.Add(time.Hour)
+	env.order = &model.Order{UserID: 10, PlayerID: &env.player.ID, ItemID: 1, Status: model.OrderStatusInProgress,
+		TotalPriceCents: 6000, CommissionCents: 1200, PlayerIncomeCents: 4800, Currency: model.CurrencyCNY,
+		StartedAt: &started, ScheduledStart: &started, ScheduledEnd: &end}
+	require.NoError(t, db.Create(env.order).Error)
+
+	orders := orderrepo.NewOrderRepository(db)
+	env.svc = NewService(extensionrepo.NewExtensionRepository(db), orders, playerrepo.NewPlayerRepository(db))
+	env.svc.SetOperationLogs(operationlogrepo.NewOperationLogRepository(db))
+
+	env.payment = paymentservice.NewPaymentService(paymentrepo.NewPaymentRepository(db), orders)
+	env.payment.SetTxManager(common.NewUnitOfWork(db))
+	env.payment.SetRefundRepository(paymentrepo.NewRefundRepository(db))
+	env.sandbox = paymentservice.NewSandboxServer("ext-secret")
+	env.payment.SetGateway(paymentservice.NewInProcessSandboxGateway(model.PaymentMethodWeChat, env.sandbox))
+	env.payment.SetExtensions(env.svc)
+	return env
+}
+
+// pay 创建加时补款并模拟用户在渠道完成付款
+func (e *extensionEnv) pay(t *testing.T, ext *model.OrderExtension) *model.Payment {
+	t.Helper()
+	ctx := context.Background()
+	resp, err := e.payment.CreatePayment(ctx, e.order.UserID, paymentservice.CreatePaymentRequest{
+		OrderID: e.order.ID, Method: model.PaymentMethodWeChat, ExtensionID: &ext.ID})
+	require.NoError(t, err)
+	var payment model.Payment
+	require.NoError(t, e.db.First(&payment, resp.PaymentID).Error)
+	require.NotNil(t, payment.ExtensionID)
+	require.Equal(t, ext.AmountCents, payment.AmountCents)
+
+	require.NoError(t, e.sandbox.Pay(ctx, payment.OutTradeNo))
+	// 未配置回调地址时通过主动查单确认支付结果
+	_, err = e.payment.GetPaymentStatus(ctx, payment.ID)
+	require.NoError(t, err)
+	require.NoError(t, e.db.First(&payment, resp.PaymentID).Error)
+	return &payment
+}
+
+func TestExtension_RequestAcceptAndPay(t *testing.T) {
+	env := newExtensionEnv(t)
+	ctx := context.Background()
+	originalEnd := *env.order.ScheduledEnd
+
+	ext, err := env.svc.RequestExtension(ctx, env.order.UserID, env.order.ID, RequestExtensionRequest{DurationHours: 1, Note: "再来一局"})
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderExtensionRequested, ext.Status)
+	assert.Equal(t, 60, ext.DurationMinutes)
+	assert.EqualValues(t, 6000, ext.AmountCents)
+	assert.EqualValues(t, 1200, ext.CommissionCents)
+	assert.EqualValues(t, 4800, ext.PlayerIncomeCents)
+
+	// 同一订单同时只能有一个待处理的加时
+	_, err = env.svc.RequestExtension(ctx, env.order.UserID, env.order.ID, RequestExtensionRequest{DurationHours: 1})
+	assert.ErrorIs(t, err, ErrExtensionPending)
+
+	// 未同意前不能补款
+	_, err = env.payment.CreatePayment(ctx, env.order.UserID, paymentservice.CreatePaymentRequest{
+		OrderID: env.order.ID, Method: model.PaymentMethodWeChat, ExtensionID: &ext.ID})
+	assert.ErrorIs(t, err, paymentservice.ErrInvalidOrderStatus)
+
+	_, err = env.svc.RespondExtension(ctx, 99, env.order.ID, ext.ID, RespondExtensionRequest{Accept: true})
+	assert.ErrorIs(t, err, ErrNotPlayer)
+	ext, err = env.svc.RespondExtension(ctx, env.player.UserID, env.order.ID, ext.ID, RespondExtensionRequest{Accept: true})
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderExtensionAccepted, ext.Status)
+	require.NotNil(t, ext.RespondedAt)
+
+	payment := env.pay(t, ext)
+	assert.Equal(t, model.PaymentStatusPaid, payment.Status)
+
+	var order model.Order
+	require.NoError(t, env.db.First(&order, env.order.ID).Error)
+	assert.Equal(t, model.OrderStatusInProgress, order.Status)
+	assert.EqualValues(t, 12000, order.TotalPriceCents)
+	assert.EqualValues(t, 2400, order.CommissionCents)
+	assert.EqualValues(t, 9600, order.PlayerIncomeCents)
+	require.NotNil(t, order.ScheduledEnd)
+	assert.WithinDuration(t, originalEnd.Add(time.Hour), *order.ScheduledEnd, time.Second)
+
+	exts, err := env.svc.ListExtensions(ctx, env.player.UserID, env.order.ID)
+	require.NoError(t, err)
+	require.Len(t, exts, 1)
+	assert.Equal(t, model.OrderExtensionPaid, exts[0].Status)
+	require.NotNil(t, exts[0].PaymentID)
+	assert.Equal(t, payment.ID, *exts[0].PaymentID)
+
+	var logs int64
+	require.NoError(t, env.db.Model(&model.OperationLog{}).
+		Where("entity_type = ? AND entity_id = ? AND action IN ?", model.OpEntityOrder, env.order.ID,
+			[]string{string(model.OpActionRequestExtension), string(model.OpActionAcceptExtension), string(model.OpActionExtendOrder)}).
+		Count(&logs).Error)
+	assert.EqualValues(t, 3, logs)
+
+	// 已生效后可以再次申请
+	_, err = env.svc.RequestExtension(ctx, env.order.UserID, env.order.ID, RequestExtensionRequest{DurationHours: 0.5})
+	require.NoError(t, err)
+}
+
+func TestExtension_DeclineAndCancel(t *testing.T) {
+	env := newExtensionEnv(t)
+	ctx := context.Background()
+
+	_, err := env.svc.RequestExtension(ctx, 11, env.order.ID, RequestExtensionRequest{DurationHours: 1})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	ext, err := env.svc.RequestExtension(ctx, env.order.UserID, env.order.ID, RequestExtensionRequest{DurationHours: 1})
+	require.NoError(t, err)
+	ext, err = env.svc.RespondExtension(ctx, env.player.UserID, env.order.ID, ext.ID, RespondExtensionRequest{Reason: "下一单快开始了"})
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderExtensionDeclined, ext.Status)
+	assert.Equal(t, "下一单快开始了", ext.DeclineReason)
+
+	_, err = env.svc.CancelExtension(ctx, env.order.UserID, env.order.ID, ext.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	ext, err = env.svc.RequestExtension(ctx, env.order.UserID, env.order.ID, RequestExtensionRequest{DurationHours: 1})
+	require.NoError(t, err)
+	_, err = env.svc.CancelExtension(ctx, env.order.UserID, env.order.ID+1, ext.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	ext, err = env.svc.CancelExtension(ctx, env.order.UserID, env.order.ID, ext.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderExtensionCanceled, ext.Status)
+}
+
+type stubBookings struct{ err error }
+
+func (s stubBookings) CheckBookable(context.Context, uint64, time.Time, time.Time, uint64) error {
+	return s.err
+}
+
+func TestExtension_AcceptChecksBookingConflict(t *testing.T) {
+	env := newExtensionEnv(t)
+	ctx := context.Background()
+	ext, err := env.svc.RequestExtension(ctx, env.order.UserID, env.order.ID, RequestExtensionRequest{DurationHours: 1})
+	require.NoError(t, err)
+
+	env.svc.SetBookingChecker(stubBookings{err: availability.ErrBookingConflict})
+	_, err = env.svc.RespondExtension(ctx, env.player.UserID, env.order.ID, ext.ID, RespondExtensionRequest{Accept: true})
+	assert.ErrorIs(t, err, ErrBookingConflict)
+
+	// 陪玩师同意即视为愿意在可接单时段外服务
+	env.svc.SetBookingChecker(stubBookings{err: availability.ErrUnavailable})
+	_, err = env.svc.RespondExtension(ctx, env.player.UserID, env.order.ID, ext.ID, RespondExtensionRequest{Accept: true})
+	require.NoError(t, err)
+}
+
+func TestExtension_PaymentForClosedExtensionIsRefunded(t *testing.T) {
+	cases := []struct {
+		name  string
+		close func(t *testing.T, env *extensionEnv, ext *model.OrderExtension)
+	}{
+		{"订单已完成", func(t *testing.T, env *extensionEnv, _ *model.OrderExtension) {
+			require.NoError(t, env.db.Model(&model.Order{}).Where("id = ?", env.order.ID).Update("status", model.OrderStatusCompleted).Error)
+		}},
+		{"加时已撤回", func(t *testing.T, env *extensionEnv, ext *model.OrderExtension) {
+			_, err := env.svc.CancelExtension(context.Background(), env.order.UserID, env.order.ID, ext.ID)
+			require.NoError(t, err)
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newExtensionEnv(t)
+			ctx := context.Background()
+			ext, err := env.svc.RequestExtension(ctx, env.order.UserID, env.order.ID, RequestExtensionRequest{DurationHours: 1})
+			require.NoError(t, err)
+			ext, err = env.svc.RespondExtension(ctx, env.player.UserID, env.order.ID, ext.ID, RespondExtensionRequest{Accept: true})
+			require.NoError(t, err)
+			resp, err := env.payment.CreatePayment(ctx, env.order.UserID, paymentservice.CreatePaymentRequest{
+				OrderID: env.order.ID, Method: model.PaymentMethodWeChat, ExtensionID: &ext.ID})
+			require.NoError(t, err)
+			tc.close(t, env, ext)
+
+			// 款项到账：支付照常记为已支付，补款整笔退回，订单金额不变
+			var payment model.Payment
+			require.NoError(t, env.db.First(&payment, resp.PaymentID).Error)
+			require.NoError(t, env.sandbox.Pay(ctx, payment.OutTradeNo))
+			status, err := env.payment.GetPaymentStatus(ctx, payment.ID)
+			require.NoError(t, err)
+			assert.Equal(t, model.PaymentStatusPaid, status.Status)
+
+			refunds, err := env.payment.ListOrderRefunds(ctx, env.order.ID)
+			require.NoError(t, err)
+			require.Len(t, refunds, 1)
+			assert.Equal(t, model.RefundSourceLatePayment, refunds[0].Source)
+			assert.Equal(t, ext.AmountCents, refunds[0].AmountCents)
+			require.NotNil(t, refunds[0].PaymentID)
+			assert.Equal(t, payment.ID, *refunds[0].PaymentID)
+
+			var order model.Order
+			require.NoError(t, env.db.First(&order, env.order.ID).Error)
+			assert.EqualValues(t, 6000, order.TotalPriceCents)
+			got, err := env.svc.extensions.Get(ctx, ext.ID)
+			require.NoError(t, err)
+			assert.NotEqual(t, model.OrderExtensionPaid, got.Status)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package feed
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"gamelink/internal/pkg/safety"
 	"gamelink/internal/repository"
 	"gamelink/internal/service"
+	uploadservice "gamelink/internal/service/upload"
 )
 
 const (
@@ -22,8 +24,15 @@ const (
 
 // Service orchestrates feed publishing, listing and moderation.
 type Service struct {
-	repo       repository.FeedRepository
-	moderation ModerationEngine
+	repo        repository.FeedRepository
+	moderation  ModerationEngine
+	attachments Attachments
+}
+
+// Attachments resolves uploaded images referenced by feeds (implemented by the upload service).
+type Attachments interface {
+	Attach(ctx context.Context, ownerID uint64, ids []uint64) ([]model.Upload, error)
+	URLs(ctx context.Context, ids []uint64) (map[uint64]string, error)
 }
 
 // NewService builds a feed service instance.
@@ -34,6 +43,9 @@ func NewService(repo repository.FeedRepository, moderation ModerationEngine) *Se
 	return &Service{repo: repo, moderation: moderation}
 }
 
+// SetAttachments injects the upload store used for feed images.
+func (s *Service) SetAttachments(a Attachments) { s.attachments = a }
+
 // CreateFeedRequest describes payload for creating feeds.
 type CreateFeedRequest struct {
 	Content    string               `json:"content"`
@@ -41,12 +53,9 @@ type CreateFeedRequest struct {
 	Images     []FeedImageInput     `json:"images"`
 }
 
-// FeedImageInput describes uploaded image metadata.
+// FeedImageInput references an image the author uploaded beforehand.
 type FeedImageInput struct {
-	URL       string `json:"url"`
-	Width     int    `json:"width"`
-	Height    int    `json:"height"`
-	SizeBytes int64  `json:"sizeBytes"`
+	UploadID uint64 `json:"uploadId"`
 }
 
 // FeedView is a DTO for returning feed information.
@@ -63,6 +72,7 @@ type FeedView struct {
 
 // FeedImageView is serialized feed image.
 type FeedImageView struct {
+	UploadID  uint64 `json:"uploadId,omitempty"`
 	URL       string `json:"url"`
 	Width     int    `json:"width"`
 	Height    int    `json:"height"`
@@ -94,24 +104,17 @@ func (s *Service) CreateFeed(ctx context.Context, authorID uint64, req CreateFee
 		return nil, fmt.Errorf("%w: 图片数量超过限制", service.ErrValidation)
 	}
 
-	images := make([]model.FeedImage, 0, len(req.Images))
-	imageURLs := make([]string, 0, len(req.Images))
-	for idx, img := range req.Images {
-		url := strings.TrimSpace(img.URL)
-		if url == "" {
-			return nil, fmt.Errorf("%w: 第 %d 张图片URL为空", service.ErrValidation, idx+1)
-		}
-		if img.SizeBytes > maxFeedImageSize {
-			return nil, fmt.Errorf("%w: 第 %d 张图片超过 10MB", service.ErrValidation, idx+1)
-		}
-		images = append(images, model.FeedImage{
-			URL:       url,
-			Width:     img.Width,
-			Height:    img.Height,
-			SizeBytes: img.SizeBytes,
-			Order:     idx,
-		})
-		imageURLs = append(imageURLs, url)
+	images, err := s.attachImages(ctx, authorID, req.Images)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := s.imageURLs(ctx, images)
+	if err != nil {
+		return nil, err
+	}
+	imageURLs := make([]string, 0, len(images))
+	for _, img := range images {
+		imageURLs = append(imageURLs, urls[img.UploadID])
 	}
 
 	feed := &model.Feed{
@@ -152,7 +155,59 @@ func (s *Service) CreateFeed(ctx context.Context, authorID uint64, req CreateFee
 		// no-op, keep pending
 	}
 
-	return toFeedView(feed), nil
+	return toFeedView(feed, urls), nil
+}
+
+// attachImages 校验配图均为作者本人上传的图片，尺寸与大小取自上传记录
+func (s *Service) attachImages(ctx context.Context, authorID uint64, inputs []FeedImageInput) ([]model.FeedImage, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	if s.attachments == nil {
+		return nil, fmt.Errorf("%w: 暂不支持图片", service.ErrValidation)
+	}
+	ids := make([]uint64, 0, len(inputs))
+	for _, img := range inputs {
+		ids = append(ids, img.UploadID)
+	}
+	uploads, err := s.attachments.Attach(ctx, authorID, ids)
+	if errors.Is(err, uploadservice.ErrInvalidAttachment) {
+		return nil, fmt.Errorf("%w: %v", service.ErrValidation, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	images := make([]model.FeedImage, 0, len(uploads))
+	for idx, u := range uploads {
+		if !u.IsImage() {
+			return nil, fmt.Errorf("%w: 第 %d 张图片格式不支持", service.ErrValidation, idx+1)
+		}
+		if u.FileSize > maxFeedImageSize {
+			return nil, fmt.Errorf("%w: 第 %d 张图片超过 10MB", service.ErrValidation, idx+1)
+		}
+		images = append(images, model.FeedImage{
+			UploadID:  u.ID,
+			Width:     u.Width,
+			Height:    u.Height,
+			SizeBytes: u.FileSize,
+			Order:     idx,
+		})
+	}
+	return images, nil
+}
+
+// imageURLs 为按上传引用的配图签发下载链接
+func (s *Service) imageURLs(ctx context.Context, images []model.FeedImage) (map[uint64]string, error) {
+	var ids []uint64
+	for _, img := range images {
+		if img.UploadID != 0 {
+			ids = append(ids, img.UploadID)
+		}
+	}
+	if len(ids) == 0 || s.attachments == nil {
+		return nil, nil
+	}
+	return s.attachments.URLs(ctx, ids)
 }
 
 // ListFeeds returns timeline for user.
@@ -175,10 +230,19 @@ func (s *Service) ListFeeds(ctx context.Context, userID uint64, req ListFeedsReq
 		return nil, err
 	}
 
+	var images []model.FeedImage
+	for _, f := range feeds {
+		images = append(images, f.Images...)
+	}
+	urls, err := s.imageURLs(ctx, images)
+	if err != nil {
+		return nil, err
+	}
+
 	resp := &ListFeedsResponse{Items: make([]FeedView, 0, len(feeds))}
 	for _, f := range feeds {
 		feedCopy := f
-		resp.Items = append(resp.Items, *toFeedView(&feedCopy))
+		resp.Items = append(resp.Items, *toFeedView(&feedCopy, urls))
 	}
 	if len(feeds) > 0 {
 		last := feeds[len(feeds)-1]
@@ -214,11 +278,16 @@ func validateVisibility(visibility model.FeedVisibility) error {
 	}
 }
 
-func toFeedView(feed *model.Feed) *FeedView {
+func toFeedView(feed *model.Feed, urls map[uint64]string) *FeedView {
 	images := make([]FeedImageView, 0, len(feed.Images))
 	for _, img := range feed.Images {
+		url := img.URL
+		if img.UploadID != 0 {
+			url = urls[img.UploadID]
+		}
 		images = append(images, FeedImageView{
-			URL:       img.URL,
+			UploadID:  img.UploadID,
+			URL:       url,
 			Width:     img.Width,
 			Height:    img.Height,
 			SizeBytes: img.SizeBytes,
@@ -2,12 +2,15 @@ package feed
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	"gamelink/internal/service"
+	uploadservice "gamelink/internal/service/upload"
 )
 
 // Mock repository for feed service tests
@@ -51,11 +54,42 @@ func (m *mockFeedRepoForService) CreateReport(ctx context.Context, report *model
 	return nil
 }
 
+// mockAttachments 模拟上传服务：ID 1-100 为用户 1 上传的图片，101 为 PDF，102 为超大图片
+type mockAttachments struct{}
+
+func (mockAttachments) Attach(ctx context.Context, ownerID uint64, ids []uint64) ([]model.Upload, error) {
+	uploads := make([]model.Upload, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || id > 102 || ownerID != 1 {
+			return nil, uploadservice.ErrInvalidAttachment
+		}
+		u := model.Upload{ID: id, UserID: ownerID, MimeType: "image/png", FileSize: 102400, Width: 800, Height: 600}
+		switch id {
+		case 101:
+			u.MimeType = "application/pdf"
+		case 102:
+			u.FileSize = maxFeedImageSize + 1
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, nil
+}
+
+func (mockAttachments) URLs(ctx context.Context, ids []uint64) (map[uint64]string, error) {
+	urls := make(map[uint64]string, len(ids))
+	for _, id := range ids {
+		urls[id] = fmt.Sprintf("/api/v1/uploads/%d/content?signature=x", id)
+	}
+	return urls, nil
+}
+
 func setupFeedService(t *testing.T) *Service {
 	t.Helper()
 	repo := &mockFeedRepoForService{feeds: make(map[uint64]*model.Feed)}
 	moderation := NewDefaultModerationEngine()
-	return NewService(repo, moderation)
+	svc := NewService(repo, moderation)
+	svc.SetAttachments(mockAttachments{})
+	return svc
 }
 
 func TestFeedService_CreateFeed_Success(t *testing.T) {
@@ -65,14 +99,7 @@ func TestFeedService_CreateFeed_Success(t *testing.T) {
 	req := CreateFeedRequest{
 		Content:    "Hello world",
 		Visibility: model.FeedVisibilityPublic,
-		Images: []FeedImageInput{
-			{
-				URL:       "https://example.com/image.jpg",
-				Width:     800,
-				Height:    600,
-				SizeBytes: 102400,
-			},
-		},
+		Images:     []FeedImageInput{{UploadID: 1}},
 	}
 
 	feed, err := svc.CreateFeed(ctx, 1, req)
@@ -80,6 +107,22 @@ func TestFeedService_CreateFeed_Success(t *testing.T) {
 	assert.NotNil(t, feed)
 	assert.Equal(t, "Hello world", feed.Content)
 	assert.Equal(t, model.FeedVisibilityPublic, feed.Visibility)
+	if assert.Len(t, feed.Images, 1) {
+		assert.Equal(t, FeedImageView{UploadID: 1, URL: "/api/v1/uploads/1/content?signature=x", Width: 800, Height: 600, SizeBytes: 102400}, feed.Images[0])
+	}
+}
+
+func TestFeedService_CreateFeed_InvalidImageUpload(t *testing.T) {
+	svc := setupFeedService(t)
+	ctx := context.Background()
+
+	for _, id := range []uint64{0, 101, 102, 999} {
+		_, err := svc.CreateFeed(ctx, 1, CreateFeedRequest{Content: "hi", Images: []FeedImageInput{{UploadID: id}}})
+		assert.ErrorIs(t, err, service.ErrValidation, id)
+	}
+	// 引用他人的上传
+	_, err := svc.CreateFeed(ctx, 2, CreateFeedRequest{Content: "hi", Images: []FeedImageInput{{UploadID: 1}}})
+	assert.ErrorIs(t, err, service.ErrValidation)
 }
 
 func TestFeedService_CreateFeed_TooManyImages(t *testing.T) {
@@ -88,12 +131,7 @@ func TestFeedService_CreateFeed_TooManyImages(t *testing.T) {
 
 	images := make([]FeedImageInput, 10)
 	for i := 0; i < 10; i++ {
-		images[i] = FeedImageInput{
-			URL:       "https://example.com/image.jpg",
-			Width:     800,
-			Height:    600,
-			SizeBytes: 102400,
-		}
+		images[i] = FeedImageInput{UploadID: uint64(i + 1)}
 	}
 
 	req := CreateFeedRequest{
@@ -0,0 +1,201 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	fxrepo "gamelink/internal/repository/fx"
+)
+
+var (
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrRateUnavailable 没有可用于换算的汇率
+	ErrRateUnavailable = errors.New("exchange rate unavailable")
+	// ErrProviderNotConfigured 未配置汇率数据源
+	ErrProviderNotConfigured = errors.New("exchange rate provider not configured")
+)
+
+// SourceManual 管理员手工录入的汇率
+const SourceManual = "manual"
+
+// RateProvider 汇率数据源
+type RateProvider interface {
+	// Name 数据源名称，写入汇率表的 source 字段
+	Name() string
+	// FetchRates 返回 1 单位 base 可兑换的各币种数量
+	FetchRates(ctx context.Context, base model.Currency) (map[model.Currency]float64, error)
+}
+
+// ExchangeRateService 汇率服务
+//
+// 汇率以本位币为基准从数据源定期刷新，也可由管理员手工录入。
+// 换算依次尝试直接汇率、反向汇率，以及经本位币的交叉汇率。
+type ExchangeRateService struct {
+	rates    fxrepo.RateRepository
+	provider RateProvider
+	now      func() time.Time
+}
+
+// NewExchangeRateService 创建汇率服务
+func NewExchangeRateService(rates fxrepo.RateRepository) *ExchangeRateService {
+	return &ExchangeRateService{rates: rates, now: time.Now}
+}
+
+// SetProvider 注入汇率数据源
+func (s *ExchangeRateService) SetProvider(p RateProvider) { s.provider = p }
+
+// RefreshRates 从数据源拉取本位币对其他受支持币种的汇率并追加保存。
+func (s *ExchangeRateService) RefreshRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	if s.provider == nil {
+		return nil, ErrProviderNotConfigured
+	}
+	base := model.DefaultCurrency
+	fetched, err := s.provider.FetchRates(ctx, base)
+	if err != nil {
+		return nil, fmt.Errorf("fetch exchange rates from %s: %w", s.provider.Name(), err)
+	}
+	now := s.now()
+	var rates []model.ExchangeRate
+	for _, quote := range model.SupportedCurrencies() {
+		rate, ok := fetched[quote]
+		if quote == base || !ok {
+			continue
+		}
+		if !validRate(rate) {
+			return nil, fmt.Errorf("%w: invalid rate %v for %s/%s from %s", ErrValidation, rate, base, quote, s.provider.Name())
+		}
+		rates = append(rates, model.ExchangeRate{
+			BaseCurrency:  base,
+			QuoteCurrency: quote,
+			Rate:          rate,
+			Source:        s.provider.Name(),
+			EffectiveAt:   now,
+		})
+	}
+	if err := s.rates.CreateRates(ctx, rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// SetRateRequest 手工录入汇率请求
+type SetRateRequest struct {
+	Base        model.Currency
+	Quote       model.Currency
+	Rate        float64
+	EffectiveAt *time.Time // 为空表示立即生效
+}
+
+// SetRate 手工录入汇率。
+func (s *ExchangeRateService) SetRate(ctx context.Context, req SetRateRequest) (*model.ExchangeRate, error) {
+	if !model.IsValidCurrency(req.Base) || !model.IsValidCurrency(req.Quote) || req.Base == req.Quote || !validRate(req.Rate) {
+		return nil, ErrValidation
+	}
+	rate := model.ExchangeRate{
+		BaseCurrency:  req.Base,
+		QuoteCurrency: req.Quote,
+		Rate:          req.Rate,
+		Source:        SourceManual,
+		EffectiveAt:   s.now(),
+	}
+	if req.EffectiveAt != nil {
+		rate.EffectiveAt = *req.EffectiveAt
+	}
+	if err := s.rates.CreateRates(ctx, []model.ExchangeRate{rate}); err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// ListRates 列出当前生效的汇率。
+func (s *ExchangeRateService) ListRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	rates, err := s.rates.ListLatest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].BaseCurrency != rates[j].BaseCurrency {
+			return rates[i].BaseCurrency < rates[j].BaseCurrency
+		}
+		return rates[i].QuoteCurrency < rates[j].QuoteCurrency
+	})
+	return rates, nil
+}
+
+// ListHistory 查询币种对的历史汇率。
+func (s *ExchangeRateService) ListHistory(ctx context.Context, base, quote model.Currency, limit int) ([]model.ExchangeRate, error) {
+	if !model.IsValidCurrency(base) || !model.IsValidCurrency(quote) {
+		return nil, ErrValidation
+	}
+	return s.rates.ListHistory(ctx, base, quote, limit)
+}
+
+// Rate 返回当前 1 单位 from 可兑换的 to 数量。
+func (s *ExchangeRateService) Rate(ctx context.Context, from, to model.Currency) (float64, error) {
+	from, to = from.OrDefault(), to.OrDefault()
+	if from == to {
+		return 1, nil
+	}
+	if !model.IsValidCurrency(from) || !model.IsValidCurrency(to) {
+		return 0, fmt.Errorf("%w: unsupported currency %s/%s", ErrValidation, from, to)
+	}
+	at := s.now()
+	rate, err := s.pairRate(ctx, from, to, at)
+	if !errors.Is(err, ErrRateUnavailable) {
+		return rate, err
+	}
+	// 经本位币交叉换算
+	base := model.DefaultCurrency
+	if from == base || to == base {
+		return 0, err
+	}
+	toBase, err := s.pairRate(ctx, from, base, at)
+	if err != nil {
+		return 0, err
+	}
+	fromBase, err := s.pairRate(ctx, base, to, at)
+	if err != nil {
+		return 0, err
+	}
+	return toBase * fromBase, nil
+}
+
+// Convert 把 from 币种的金额（分）按当前汇率换算为 to 币种，四舍五入到分。
+func (s *ExchangeRateService) Convert(ctx context.Context, amountCents int64, from, to model.Currency) (int64, error) {
+	rate, err := s.Rate(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(float64(amountCents) * rate)), nil
+}
+
+// pairRate 优先使用直接汇率，没有时取反向汇率的倒数。
+func (s *ExchangeRateService) pairRate(ctx context.Context, from, to model.Currency, at time.Time) (float64, error) {
+	direct, err := s.rates.Latest(ctx, from, to, at)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return 0, err
+	}
+	inverse, ierr := s.rates.Latest(ctx, to, from, at)
+	if ierr != nil && !errors.Is(ierr, repository.ErrNotFound) {
+		return 0, ierr
+	}
+	switch {
+	case direct != nil && (inverse == nil || !inverse.EffectiveAt.After(direct.EffectiveAt)):
+		return direct.Rate, nil
+	case inverse != nil:
+		return 1 / inverse.Rate, nil
+	default:
+		return 0, fmt.Errorf("%w: %s/%s", ErrRateUnavailable, from, to)
+	}
+}
+
+func validRate(rate float64) bool {
+	return rate > 0 && !math.IsInf(rate, 0) && !math.IsNaN(rate)
+}
@@ -0,0 +1,108 @@
+package fx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	fxrepo "gamelink/internal/repository/fx"
+)
+
+func newTestFX(t *testing.T) *ExchangeRateService {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.ExchangeRate{}))
+	return NewExchangeRateService(fxrepo.NewRateRepository(db))
+}
+
+func writeRates(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestRefreshRates_LocalFile(t *testing.T) {
+	svc := newTestFX(t)
+	ctx := context.Background()
+
+	_, err := svc.RefreshRates(ctx)
+	assert.ErrorIs(t, err, ErrProviderNotConfigured)
+
+	// 文件以美元为基准，刷新时换算为以人民币为基准
+	svc.SetProvider(NewLocalFileRateProvider(writeRates(t, `{"base": "usd", "rates": {"CNY": 8, "EUR": 0.8, "JPY": 150}}`)))
+	rates, err := svc.RefreshRates(ctx)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	for _, r := range rates {
+		assert.Equal(t, model.CurrencyCNY, r.BaseCurrency)
+		assert.Equal(t, "local_file", r.Source)
+	}
+
+	rate, err := svc.Rate(ctx, model.CurrencyCNY, model.CurrencyUSD)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.125, rate, 1e-9)
+	// 反向汇率
+	amount, err := svc.Convert(ctx, 1000, model.CurrencyUSD, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8000), amount)
+	// 经人民币交叉换算
+	amount, err = svc.Convert(ctx, 1000, model.CurrencyUSD, model.CurrencyEUR)
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), amount)
+
+	listed, err := svc.ListRates(ctx)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, model.CurrencyEUR, listed[0].QuoteCurrency)
+}
+
+func TestSetRate_LatestWins(t *testing.T) {
+	svc := newTestFX(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := svc.Convert(ctx, 100, model.CurrencyUSD, model.CurrencyCNY)
+	assert.ErrorIs(t, err, ErrRateUnavailable)
+
+	_, err = svc.SetRate(ctx, SetRateRequest{Base: model.CurrencyUSD, Quote: model.CurrencyUSD, Rate: 1})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.SetRate(ctx, SetRateRequest{Base: model.CurrencyUSD, Quote: model.CurrencyCNY, Rate: 0})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	earlier := now.Add(-time.Hour)
+	_, err = svc.SetRate(ctx, SetRateRequest{Base: model.CurrencyUSD, Quote: model.CurrencyCNY, Rate: 7, EffectiveAt: &earlier})
+	require.NoError(t, err)
+	// 较新的反向汇率优先于旧的直接汇率
+	_, err = svc.SetRate(ctx, SetRateRequest{Base: model.CurrencyCNY, Quote: model.CurrencyUSD, Rate: 0.125})
+	require.NoError(t, err)
+	// 尚未生效的汇率不参与换算
+	later := now.Add(time.Hour)
+	_, err = svc.SetRate(ctx, SetRateRequest{Base: model.CurrencyUSD, Quote: model.CurrencyCNY, Rate: 100, EffectiveAt: &later})
+	require.NoError(t, err)
+
+	amount, err := svc.Convert(ctx, 333, model.CurrencyUSD, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2664), amount)
+	amount, err = svc.Convert(ctx, 10, model.CurrencyCNY, model.CurrencyUSD)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), amount) // 1.25 四舍五入
+
+	history, err := svc.ListHistory(ctx, model.CurrencyUSD, model.CurrencyCNY, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+	_, err = svc.ListHistory(ctx, "XXX", model.CurrencyCNY, 10)
+	assert.ErrorIs(t, err, ErrValidation)
+}
@@ -0,0 +1,62 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gamelink/internal/model"
+)
+
+// LocalFileRateProvider 从本地 JSON 文件读取汇率（开发、测试或无外部数据源时使用）。
+//
+// 文件格式：{"base": "CNY", "rates": {"USD": 0.1389, "EUR": 0.1282}}，
+// 表示 1 单位 base 可兑换的各币种数量；请求其他基准币种时按交叉汇率换算。
+type LocalFileRateProvider struct {
+	path string
+}
+
+// NewLocalFileRateProvider 创建本地文件汇率数据源
+func NewLocalFileRateProvider(path string) *LocalFileRateProvider {
+	return &LocalFileRateProvider{path: path}
+}
+
+// Name 数据源名称
+func (p *LocalFileRateProvider) Name() string { return "local_file" }
+
+type rateFile struct {
+	Base  model.Currency             `json:"base"`
+	Rates map[model.Currency]float64 `json:"rates"`
+}
+
+// FetchRates 每次调用都重新读取文件，修改文件后下次刷新即生效。
+func (p *LocalFileRateProvider) FetchRates(_ context.Context, base model.Currency) (map[model.Currency]float64, error) {
+	raw, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	var file rateFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", p.path, err)
+	}
+	file.Base = model.Currency(strings.ToUpper(string(file.Base))).OrDefault()
+	rates := make(map[model.Currency]float64, len(file.Rates)+1)
+	for c, r := range file.Rates {
+		rates[model.Currency(strings.ToUpper(string(c)))] = r
+	}
+	rates[file.Base] = 1
+	if base == file.Base {
+		return rates, nil
+	}
+	pivot, ok := rates[base]
+	if !ok || !validRate(pivot) {
+		return nil, fmt.Errorf("%w: %s not found in %s", ErrRateUnavailable, base, p.path)
+	}
+	out := make(map[model.Currency]float64, len(rates))
+	for c, r := range rates {
+		out[c] = r / pivot
+	}
+	return out, nil
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
 	commissionrepo "gamelink/internal/repository/commission"
+	"gamelink/internal/repository/common"
+	orderhistory "gamelink/internal/repository/order_history"
 	serviceitemrepo "gamelink/internal/repository/serviceitem"
+	itemservice "gamelink/internal/service/item"
+	"gamelink/internal/service/orderstate"
+	"gamelink/internal/service/pricing"
 )
 
 var (
@@ -27,6 +33,31 @@ type GiftService struct {
 	orders      repository.OrderRepository
 	players     repository.PlayerRepository
 	commissions commissionrepo.CommissionRepository
+	ledger      CommissionLedger
+	wallet      CommissionWallet
+	pricer      ItemPricer
+	quoter      OrderQuoter
+	history     orderhistory.HistoryRepository
+}
+
+// OrderQuoter 统一定价引擎报价，含抽成规则（由定价服务实现）。
+type OrderQuoter interface {
+	Quote(ctx context.Context, req pricing.QuoteRequest) (*pricing.Quote, error)
+}
+
+// ItemPricer 计算礼物在指定币种下的单价（由服务项目服务实现）。
+type ItemPricer interface {
+	QuotePrice(ctx context.Context, item *model.ServiceItem, currency model.Currency) (*itemservice.PriceQuote, error)
+}
+
+// CommissionLedger 订单完成后确认抽成收入与应付陪玩师（由总账服务实现）。
+type CommissionLedger interface {
+	PostCommission(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error
+}
+
+// CommissionWallet 礼物收入记入陪玩师钱包待结算（由钱包服务实现）。
+type CommissionWallet interface {
+	CreditIncome(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error
 }
 
 // NewGiftService 创建礼物服务
@@ -44,14 +75,30 @@ func NewGiftService(
 	}
 }
 
+// SetLedger 注入总账服务，礼物送达后记账
+func (s *GiftService) SetLedger(l CommissionLedger) { s.ledger = l }
+
+// SetWallet 注入钱包服务，礼物送达后记入陪玩师待结算收入
+func (s *GiftService) SetWallet(w CommissionWallet) { s.wallet = w }
+
+// SetPricer 注入定价服务，支持以非基础币种赠送礼物
+func (s *GiftService) SetPricer(p ItemPricer) { s.pricer = p }
+
+// SetQuoter 注入定价服务，礼物价格与抽成由统一定价引擎计算
+func (s *GiftService) SetQuoter(q OrderQuoter) { s.quoter = q }
+
+// SetStatusHistory 注入订单状态历史仓储，记录礼物送达
+func (s *GiftService) SetStatusHistory(h orderhistory.HistoryRepository) { s.history = h }
+
 // SendGiftRequest 赠送礼物请�?
 type SendGiftRequest struct {
-	PlayerID    uint64  `json:"playerId" binding:"required"`              // 接收礼物的陪玩师
-	GiftItemID  uint64  `json:"giftItemId" binding:"required"`            // 礼物项目ID
-	Quantity    int     `json:"quantity" binding:"required,min=1,max=99"` // 数量
-	Message     string  `json:"message" binding:"max=200"`                // 留言
-	IsAnonymous bool    `json:"isAnonymous"`                              // 是否匿名
-	OrderID     *uint64 `json:"orderId"`                                  // 关联的护航订单（可选）
+	PlayerID    uint64         `json:"playerId" binding:"required"`                    // 接收礼物的陪玩师
+	GiftItemID  uint64         `json:"giftItemId" binding:"required"`                  // 礼物项目ID
+	Quantity    int            `json:"quantity" binding:"required,min=1,max=99"`       // 数量
+	Message     string         `json:"message" binding:"max=200"`                      // 留言
+	IsAnonymous bool           `json:"isAnonymous"`                                    // 是否匿名
+	OrderID     *uint64        `json:"orderId"`                                        // 关联的护航订单（可选）
+	Currency    model.Currency `json:"currency" binding:"omitempty,oneof=CNY USD EUR"` // 支付币种，默认礼物基础币种
 }
 
 // SendGift 赠送礼�?
@@ -79,8 +126,10 @@ func (s *GiftService) SendGift(ctx context.Context, userID uint64, req SendGiftR
 	}
 
 	// 3. 计算价格和抽�?
-	platformCommission, playerIncome := giftItem.CalculateCommission(req.Quantity)
-	totalPrice := giftItem.BasePriceCents * int64(req.Quantity)
+	quote, err := s.quote(ctx, giftItem, req)
+	if err != nil {
+		return nil, err
+	}
 
 	// 4. 生成订单�?
 	orderNo := generateOrderNo("GIFT")
@@ -89,20 +138,15 @@ func (s *GiftService) SendGift(ctx context.Context, userID uint64, req SendGiftR
 	order := &model.Order{
 		OrderNo:           orderNo,
 		UserID:            userID,
-		ItemID:            req.GiftItemID,
 		PlayerID:          &req.PlayerID, // 礼物订单的PlayerID就是接收�?
 		RecipientPlayerID: &req.PlayerID, // 明确标识接收�?
-		Quantity:          req.Quantity,
-		UnitPriceCents:    giftItem.BasePriceCents,
-		TotalPriceCents:   totalPrice,
-		CommissionCents:   platformCommission,
-		PlayerIncomeCents: playerIncome,
 		Status:            model.OrderStatusPending,
 		Title:             fmt.Sprintf("赠送礼物：%s", giftItem.Name),
 		Description:       fmt.Sprintf("�?%s 赠�?%s x%d", player.Nickname, giftItem.Name, req.Quantity),
 		GiftMessage:       req.Message,
 		IsAnonymous:       req.IsAnonymous,
 	}
+	quote.ApplyTo(order)
 
 	// 创建订单
 	if err := s.orders.Create(ctx, order); err != nil {
@@ -121,21 +165,62 @@ func (s *GiftService) SendGift(ctx context.Context, userID uint64, req SendGiftR
 		PlayerID:    req.PlayerID,
 		GiftName:    giftItem.Name,
 		Quantity:    req.Quantity,
-		TotalPrice:  totalPrice,
+		TotalPrice:  order.TotalPriceCents,
+		Currency:    string(order.Currency),
 		Status:      string(order.Status),
 		DeliveredAt: order.DeliveredAt,
 	}, nil
 }
 
+// quote 计算礼物报价：优先使用统一定价引擎，否则按礼物价格与服务项目抽成比例拆分
+func (s *GiftService) quote(ctx context.Context, giftItem *model.ServiceItem, req SendGiftRequest) (*pricing.Quote, error) {
+	currency := req.Currency
+	if currency == "" {
+		currency = giftItem.Currency.OrDefault()
+	}
+	if s.quoter != nil {
+		quote, err := s.quoter.Quote(ctx, pricing.QuoteRequest{
+			ItemID:   giftItem.ID,
+			PlayerID: req.PlayerID,
+			Quantity: req.Quantity,
+			Currency: currency,
+			Gift:     true,
+		})
+		if errors.Is(err, pricing.ErrValidation) {
+			return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+		return quote, err
+	}
+	unitPrice := giftItem.BasePriceCents
+	if currency != giftItem.Currency.OrDefault() {
+		if s.pricer == nil {
+			return nil, fmt.Errorf("%w: gift is priced in %s", ErrValidation, giftItem.Currency.OrDefault())
+		}
+		price, err := s.pricer.QuotePrice(ctx, giftItem, currency)
+		if err != nil {
+			return nil, err
+		}
+		currency, unitPrice = price.Currency, price.PriceCents
+	}
+	quote := &pricing.Quote{
+		ItemID:          giftItem.ID,
+		Quantity:        req.Quantity,
+		Currency:        currency,
+		UnitPriceCents:  unitPrice,
+		TotalPriceCents: unitPrice * int64(req.Quantity),
+		CommissionRate:  pricing.ItemCommissionRate(giftItem),
+	}
+	quote.CommissionCents, quote.PlayerIncomeCents = giftItem.SplitAmount(quote.TotalPriceCents)
+	return quote, nil
+}
+
 // deliverGift 送达礼物
 func (s *GiftService) deliverGift(ctx context.Context, order *model.Order) error {
 	// 更新订单状态为已完�?
 	now := time.Now()
-	order.Status = model.OrderStatusCompleted
 	order.DeliveredAt = &now
-	order.CompletedAt = &now
-
-	if err := s.orders.Update(ctx, order); err != nil {
+	change := orderstate.Change{Role: model.OrderActorSystem, ActorUserID: &order.UserID, Reason: "礼物已送达", At: now}
+	if err := orderstate.Transit(ctx, s.orders, s.history, order, model.OrderStatusCompleted, change); err != nil {
 		return err
 	}
 
@@ -147,6 +232,7 @@ func (s *GiftService) deliverGift(ctx context.Context, order *model.Order) error
 		CommissionRate:    int(order.CommissionCents * 100 / order.TotalPriceCents),
 		CommissionCents:   order.CommissionCents,
 		PlayerIncomeCents: order.PlayerIncomeCents,
+		Currency:          order.Currency.OrDefault(),
 		SettlementStatus:  "pending",
 		SettlementMonth:   now.Format("2006-01"),
 	}
@@ -154,6 +240,18 @@ func (s *GiftService) deliverGift(ctx context.Context, order *model.Order) error
 	if err := s.commissions.CreateRecord(ctx, record); err != nil {
 		// 记录抽成失败不影响礼物送达
 		// TODO: 记录日志
+	} else {
+		if s.ledger != nil {
+			if err := s.ledger.PostCommission(ctx, nil, record); err != nil {
+				slog.Warn("post gift commission to ledger failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+			}
+		}
+		if s.wallet != nil {
+			// 入账失败不影响礼物送达，月结时会补记
+			if err := s.wallet.CreditIncome(ctx, nil, record); err != nil {
+				slog.Warn("credit gift income to wallet failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+			}
+		}
 	}
 
 	// TODO: 发送通知给陪玩师
@@ -169,6 +267,7 @@ type GiftOrderResponse struct {
 	GiftName    string     `json:"giftName"`
 	Quantity    int        `json:"quantity"`
 	TotalPrice  int64      `json:"totalPrice"`
+	Currency    string     `json:"currency"`
 	Status      string     `json:"status"`
 	DeliveredAt *time.Time `json:"deliveredAt"`
 }
@@ -23,6 +23,13 @@ type ServiceItemService struct {
 	items  serviceitemrepo.ServiceItemRepository
 	games  repository.GameRepository
 	players repository.PlayerRepository
+	prices  serviceitemrepo.PriceRepository
+	fx      CurrencyConverter
+}
+
+// CurrencyConverter 按汇率换算金额（由汇率服务实现）。
+type CurrencyConverter interface {
+	Convert(ctx context.Context, amountCents int64, from, to model.Currency) (int64, error)
 }
 
 // NewServiceItemService 创建服务项目服务
@@ -38,6 +45,12 @@ func NewServiceItemService(
 	}
 }
 
+// SetPrices 注入多币种定价仓储
+func (s *ServiceItemService) SetPrices(prices serviceitemrepo.PriceRepository) { s.prices = prices }
+
+// SetFX 注入汇率服务，未单独定价的币种按汇率换算
+func (s *ServiceItemService) SetFX(fx CurrencyConverter) { s.fx = fx }
+
 // CreateServiceItemRequest 创建服务项目请求
 type CreateServiceItemRequest struct {
 	ItemCode        string                         `json:"itemCode" binding:"required,max=32"`
@@ -48,6 +61,7 @@ type CreateServiceItemRequest struct {
 	PlayerID        *uint64                        `json:"playerId"`
 	RankLevel       string                         `json:"rankLevel"`
 	BasePriceCents  int64                          `json:"basePriceCents" binding:"required,min=0"`
+	Currency        model.Currency                 `json:"currency" binding:"omitempty,oneof=CNY USD EUR"` // 基础价格币种，默认 CNY
 	ServiceHours    int                            `json:"serviceHours" binding:"min=0"`
 	CommissionRate  float64                        `json:"commissionRate" binding:"required,min=0,max=1"`
 	MinUsers        int                            `json:"minUsers" binding:"min=1"`
@@ -80,6 +94,10 @@ func (s *ServiceItemService) CreateServiceItem(ctx context.Context, req CreateSe
 		return nil, errors.New("gift items must have service_hours = 0")
 	}
 
+	if req.Currency != "" && !model.IsValidCurrency(req.Currency) {
+		return nil, fmt.Errorf("%w: unsupported currency %s", ErrValidation, req.Currency)
+	}
+
 	item := &model.ServiceItem{
 		ItemCode:       req.ItemCode,
 		Name:           req.Name,
@@ -90,6 +108,7 @@ func (s *ServiceItemService) CreateServiceItem(ctx context.Context, req CreateSe
 		PlayerID:       req.PlayerID,
 		RankLevel:      req.RankLevel,
 		BasePriceCents: req.BasePriceCents,
+		Currency:       req.Currency.OrDefault(),
 		ServiceHours:   req.ServiceHours,
 		CommissionRate: req.CommissionRate,
 		MinUsers:       req.MinUsers,
@@ -197,6 +216,7 @@ type ServiceItemDTO struct {
 	PlayerNickname string    `json:"playerNickname,omitempty"`
 	RankLevel      string    `json:"rankLevel"`
 	BasePriceCents int64     `json:"basePriceCents"`
+	Currency       string    `json:"currency"`
 	ServiceHours   int       `json:"serviceHours"`
 	CommissionRate float64   `json:"commissionRate"`
 	MinUsers       int       `json:"minUsers"`
@@ -295,6 +315,7 @@ func (s *ServiceItemService) toDTO(ctx context.Context, item *model.ServiceItem)
 		PlayerID:       item.PlayerID,
 		RankLevel:      item.RankLevel,
 		BasePriceCents: item.BasePriceCents,
+		Currency:       string(item.Currency.OrDefault()),
 		ServiceHours:   item.ServiceHours,
 		CommissionRate: item.CommissionRate,
 		MinUsers:       item.MinUsers,
@@ -354,3 +375,92 @@ func (s *ServiceItemService) BatchUpdatePrice(ctx context.Context, req BatchUpda
 	return s.items.BatchUpdatePrice(ctx, req.IDs, req.BasePriceCents)
 }
 
+// PriceQuote 服务项目在指定币种下的报价
+type PriceQuote struct {
+	ItemID     uint64         `json:"itemId"`
+	Currency   model.Currency `json:"currency"`
+	PriceCents int64          `json:"priceCents"`
+	Source     string         `json:"source"` // base：基础价格；override：币种定价；fx：按汇率换算
+}
+
+// QuotePrice 计算服务项目在指定币种下的单价。
+//
+// 依次使用：基础价格（币种一致）、管理员设置的币种定价、按当前汇率换算。
+func (s *ServiceItemService) QuotePrice(ctx context.Context, item *model.ServiceItem, currency model.Currency) (*PriceQuote, error) {
+	currency = currency.OrDefault()
+	if !model.IsValidCurrency(currency) {
+		return nil, fmt.Errorf("%w: unsupported currency %s", ErrValidation, currency)
+	}
+	quote := &PriceQuote{ItemID: item.ID, Currency: currency}
+	base := item.Currency.OrDefault()
+	if base == currency {
+		quote.PriceCents, quote.Source = item.BasePriceCents, "base"
+		return quote, nil
+	}
+	if s.prices != nil {
+		price, err := s.prices.GetPrice(ctx, item.ID, currency)
+		if err == nil {
+			quote.PriceCents, quote.Source = price.PriceCents, "override"
+			return quote, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+	if s.fx == nil {
+		return nil, fmt.Errorf("%w: item %d has no %s price", ErrValidation, item.ID, currency)
+	}
+	converted, err := s.fx.Convert(ctx, item.BasePriceCents, base, currency)
+	if err != nil {
+		return nil, err
+	}
+	quote.PriceCents, quote.Source = converted, "fx"
+	return quote, nil
+}
+
+// ListPrices 列出服务项目的币种定价
+func (s *ServiceItemService) ListPrices(ctx context.Context, itemID uint64) ([]model.ServiceItemPrice, error) {
+	if _, err := s.items.Get(ctx, itemID); err != nil {
+		return nil, err
+	}
+	if s.prices == nil {
+		return []model.ServiceItemPrice{}, nil
+	}
+	return s.prices.ListPrices(ctx, itemID)
+}
+
+// SetPriceRequest 设置币种定价请求
+type SetPriceRequest struct {
+	Currency   model.Currency `json:"currency" binding:"required,oneof=CNY USD EUR"`
+	PriceCents int64          `json:"priceCents" binding:"min=0"`
+}
+
+// SetPrice 设置服务项目在某一币种下的固定价格（覆盖汇率换算）
+func (s *ServiceItemService) SetPrice(ctx context.Context, itemID uint64, req SetPriceRequest) (*model.ServiceItemPrice, error) {
+	if s.prices == nil {
+		return nil, errors.New("price repository not configured")
+	}
+	if !model.IsValidCurrency(req.Currency) || req.PriceCents < 0 {
+		return nil, ErrValidation
+	}
+	item, err := s.items.Get(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.Currency.OrDefault() == req.Currency {
+		return nil, fmt.Errorf("%w: %s is the base currency, update basePriceCents instead", ErrValidation, req.Currency)
+	}
+	price := &model.ServiceItemPrice{ItemID: itemID, Currency: req.Currency, PriceCents: req.PriceCents}
+	if err := s.prices.UpsertPrice(ctx, price); err != nil {
+		return nil, err
+	}
+	return price, nil
+}
+
+// DeletePrice 删除币种定价，之后该币种按汇率换算
+func (s *ServiceItemService) DeletePrice(ctx context.Context, itemID uint64, currency model.Currency) error {
+	if s.prices == nil {
+		return ErrNotFound
+	}
+	return s.prices.DeletePrice(ctx, itemID, currency)
+}
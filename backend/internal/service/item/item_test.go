@@ -502,4 +502,67 @@ func TestServiceItemService_ListServiceItems(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.Len(t, result.Items, 2)
 	assert.Equal(t, int64(2), result.Total)
-}
\ No newline at end of file
+}
+type memPriceRepo map[model.Currency]int64
+
+func (m memPriceRepo) ListPrices(ctx context.Context, itemID uint64) ([]model.ServiceItemPrice, error) {
+	return nil, nil
+}
+
+func (m memPriceRepo) GetPrice(ctx context.Context, itemID uint64, currency model.Currency) (*model.ServiceItemPrice, error) {
+	price, ok := m[currency]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &model.ServiceItemPrice{ItemID: itemID, Currency: currency, PriceCents: price}, nil
+}
+
+func (m memPriceRepo) UpsertPrice(ctx context.Context, price *model.ServiceItemPrice) error {
+	m[price.Currency] = price.PriceCents
+	return nil
+}
+
+func (m memPriceRepo) DeletePrice(ctx context.Context, itemID uint64, currency model.Currency) error {
+	delete(m, currency)
+	return nil
+}
+
+type fixedConverter float64
+
+func (f fixedConverter) Convert(ctx context.Context, amount int64, from, to model.Currency) (int64, error) {
+	return int64(float64(amount) * float64(f)), nil
+}
+
+func TestQuotePrice(t *testing.T) {
+	ctx := context.Background()
+	items := new(MockServiceItemRepo)
+	svc := NewServiceItemService(items, new(MockGameRepo), new(MockPlayerRepo))
+	it := &model.ServiceItem{ID: 1, BasePriceCents: 7000, Currency: model.CurrencyCNY}
+
+	q, err := svc.QuotePrice(ctx, it, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7000), q.PriceCents)
+	assert.Equal(t, "base", q.Source)
+
+	// 没有币种定价也没有汇率服务
+	_, err = svc.QuotePrice(ctx, it, model.CurrencyUSD)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	prices := memPriceRepo{}
+	svc.SetPrices(prices)
+	svc.SetFX(fixedConverter(0.125))
+	q, err = svc.QuotePrice(ctx, it, model.CurrencyUSD)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(875), q.PriceCents)
+	assert.Equal(t, "fx", q.Source)
+
+	items.On("Get", ctx, uint64(1)).Return(it, nil)
+	_, err = svc.SetPrice(ctx, 1, SetPriceRequest{Currency: model.CurrencyCNY, PriceCents: 100})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.SetPrice(ctx, 1, SetPriceRequest{Currency: model.CurrencyUSD, PriceCents: 999})
+	assert.NoError(t, err)
+	q, err = svc.QuotePrice(ctx, it, model.CurrencyUSD)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(999), q.PriceCents)
+	assert.Equal(t, "override", q.Source)
+}
@@ -0,0 +1,206 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	ledgerrepo "gamelink/internal/repository/ledger"
+)
+
+// ErrPeriodClosed 会计期间已结账
+var ErrPeriodClosed = errors.New("accounting period is closed")
+
+// periodKey 返回日期所属会计期间（YYYY-MM，本地时区）。
+func periodKey(t time.Time) string {
+	return t.In(time.Local).Format("2006-01")
+}
+
+// ParsePeriod 解析 YYYY-MM 形式的会计期间，返回 [start, end)。
+func ParsePeriod(period string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", period, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid period %q", ErrValidation, period)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// ListPeriods 列出已结账期间。
+func (s *LedgerService) ListPeriods(ctx context.Context) ([]model.FinancialPeriod, error) {
+	return s.ledger.ListPeriods(ctx)
+}
+
+// ClosePeriod 期末结账：把收入、费用科目截至期末的余额结转到本年利润，并锁定该期间。
+//
+// 期间必须已经结束且没有未过账凭证；损益为零时只记录结账、不生成凭证。
+// 各币种分别生成结转凭证，期间净利润按当前汇率折算为本位币。
+func (s *LedgerService) ClosePeriod(ctx context.Context, period string, closedBy uint64) (*model.FinancialPeriod, error) {
+	start, end, err := ParsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	if closedBy == 0 {
+		return nil, ErrValidation
+	}
+	if end.After(time.Now()) {
+		return nil, fmt.Errorf("%w: period %s has not ended", ErrValidation, period)
+	}
+
+	var out *model.FinancialPeriod
+	err = s.withTx(ctx, func(repo ledgerrepo.LedgerRepository) error {
+		if _, err := repo.GetPeriod(ctx, period); err == nil {
+			return ErrPeriodClosed
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		pending, err := repo.CountUnposted(ctx, start, end)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("%w: %d unposted vouchers in %s", ErrInvalidStatus, pending, period)
+		}
+
+		sets, err := closingLines(ctx, repo, end)
+		if err != nil {
+			return err
+		}
+		record := &model.FinancialPeriod{
+			Period:      period,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			Status:      model.FinancialPeriodStatusClosed,
+			ClosedBy:    closedBy,
+			ClosedAt:    time.Now(),
+		}
+		for _, set := range sets {
+			profit, err := s.convert(ctx, set.profit, set.currency, model.DefaultCurrency)
+			if err != nil {
+				return err
+			}
+			record.NetProfit += profit
+
+			voucher, err := buildVoucher(model.FinancialVoucherTypeClosing, end.Add(-time.Second), fmt.Sprintf("%s 期末结转损益", period), set.currency, set.lines)
+			if err != nil {
+				return err
+			}
+			voucher.BusinessType = model.LedgerBusinessPeriodClosing
+			voucher.BusinessNo = period
+			voucher.CreatedBy = closedBy
+			if err := repo.CreateVoucher(ctx, voucher); err != nil {
+				return err
+			}
+			if err := approve(ctx, repo, voucher, nil); err != nil {
+				return err
+			}
+			if err := post(ctx, repo, voucher, &closedBy); err != nil {
+				return err
+			}
+			// 多币种时以本位币结转凭证（没有则取第一张）作为期间的结转凭证
+			if record.ClosingVoucherID == nil || set.currency == model.DefaultCurrency {
+				id := voucher.ID
+				record.ClosingVoucherID = &id
+			}
+		}
+		if err := repo.CreatePeriod(ctx, record); err != nil {
+			return err
+		}
+		out = record
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// closingSet 单一币种的结转分录
+type closingSet struct {
+	currency model.Currency
+	lines    []VoucherLine
+	profit   int64
+}
+
+// closingLines 按币种生成结转分录：收入借记、费用贷记，差额记入本年利润。
+//
+// 科目期初余额视为本位币；收入与费用恰好相抵的币种分录自身已平衡。
+func closingLines(ctx context.Context, repo ledgerrepo.LedgerRepository, end time.Time) ([]closingSet, error) {
+	accounts, err := repo.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := repo.SumEntries(ctx, ledgerrepo.EntrySumOptions{To: end})
+	if err != nil {
+		return nil, err
+	}
+	byCurrency := map[model.Currency]map[string]ledgerrepo.AccountSum{model.DefaultCurrency: {}}
+	for _, sum := range sums {
+		c := sum.Currency.OrDefault()
+		if byCurrency[c] == nil {
+			byCurrency[c] = make(map[string]ledgerrepo.AccountSum)
+		}
+		byCurrency[c][sum.AccountCode] = sum
+	}
+	currencies := make([]model.Currency, 0, len(byCurrency))
+	for c := range byCurrency {
+		currencies = append(currencies, c)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i] < currencies[j] })
+
+	var sets []closingSet
+	for _, c := range currencies {
+		set := closingSet{currency: c}
+		for _, a := range accounts {
+			if a.Type != model.FinancialAccountTypeRevenue && a.Type != model.FinancialAccountTypeExpense {
+				continue
+			}
+			balance := balanceOf(a, byCurrency[c][a.Code])
+			if c == model.DefaultCurrency {
+				balance += a.OpeningBalance
+			}
+			if balance == 0 {
+				continue
+			}
+			// 把科目余额冲平：余额在贷方则借记，在借方则贷记
+			net := balance
+			if a.Direction == model.FinancialAccountDirectionDebit {
+				net = -balance
+			}
+			line := VoucherLine{AccountCode: a.Code}
+			if net > 0 {
+				line.DebitCents = net
+			} else {
+				line.CreditCents = -net
+			}
+			set.lines = append(set.lines, line)
+			set.profit += net
+		}
+		if len(set.lines) == 0 {
+			continue
+		}
+		line := VoucherLine{AccountCode: model.FinancialAccountCurrentProfit}
+		if set.profit > 0 {
+			line.CreditCents = set.profit
+			set.lines = append(set.lines, line)
+		} else if set.profit < 0 {
+			line.DebitCents = -set.profit
+			set.lines = append(set.lines, line)
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// checkPeriodOpen 手工凭证日期不能落在已结账期间。
+func checkPeriodOpen(ctx context.Context, repo ledgerrepo.LedgerRepository, date time.Time) error {
+	if _, err := repo.GetPeriod(ctx, periodKey(date)); err == nil {
+		return fmt.Errorf("%w: %s", ErrPeriodClosed, periodKey(date))
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package ledger
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gamelink/internal/model"
+)
+
+// 报表导出格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// ReportFile 导出的报表文件
+type ReportFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// exportCell 导出单元格；Numeric 为真时在 XLSX 中写为数值。
+type exportCell struct {
+	Value   string
+	Numeric bool
+}
+
+func textCell(v string) exportCell { return exportCell{Value: v} }
+
+func amountCell(cents int64) exportCell {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return exportCell{Value: fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100), Numeric: true}
+}
+
+// ExportReport 将已生成的报表导出为 CSV 或 XLSX（金额单位：元，外币报表为该币种）。
+func (s *LedgerService) ExportReport(ctx context.Context, id uint64, format string) (*ReportFile, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = ExportFormatCSV
+	}
+	if format != ExportFormatCSV && format != ExportFormatXLSX {
+		return nil, fmt.Errorf("%w: unsupported export format %q", ErrValidation, format)
+	}
+	report, err := s.ledger.GetReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := reportTable(report)
+	if err != nil {
+		return nil, err
+	}
+	unit := "单位：元"
+	if c := report.Currency.OrDefault(); c != model.CurrencyCNY {
+		unit = "单位：" + string(c)
+	}
+	rows = append([][]exportCell{{textCell(report.ReportName)}, {textCell(unit)}}, rows...)
+
+	file := &ReportFile{Name: report.ReportNo + "." + format}
+	if format == ExportFormatCSV {
+		file.ContentType = "text/csv; charset=utf-8"
+		file.Data, err = writeCSV(rows)
+	} else {
+		file.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		file.Data, err = writeXLSX(reportNames[report.Type], rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// reportTable 把报表数据展开为二维表。
+func reportTable(report *model.FinancialReport) ([][]exportCell, error) {
+	var rows [][]exportCell
+	switch report.Type {
+	case model.FinancialReportTypeTrialBalance:
+		var tb TrialBalance
+		if err := json.Unmarshal(report.ReportData, &tb); err != nil {
+			return nil, err
+		}
+		rows = append(rows, []exportCell{textCell("科目编码"), textCell("科目名称"), textCell("科目类型"), textCell("余额方向"), textCell("期初余额"), textCell("本期借方"), textCell("本期贷方"), textCell("期末余额")})
+		for _, r := range tb.Rows {
+			rows = append(rows, []exportCell{textCell(r.AccountCode), textCell(r.AccountName), textCell(string(r.AccountType)), textCell(string(r.Direction)),
+				amountCell(r.OpeningBalance), amountCell(r.PeriodDebit), amountCell(r.PeriodCredit), amountCell(r.ClosingBalance)})
+		}
+		rows = append(rows, []exportCell{textCell("合计"), textCell(""), textCell(""), textCell(""), textCell(""), amountCell(tb.TotalDebit), amountCell(tb.TotalCredit), textCell("")})
+	case model.FinancialReportTypeIncomeStatement:
+		var is IncomeStatement
+		if err := json.Unmarshal(report.ReportData, &is); err != nil {
+			return nil, err
+		}
+		rows = append(rows, []exportCell{textCell("项目"), textCell("科目编码"), textCell("金额")})
+		rows = appendLines(rows, "营业收入", is.Revenue)
+		rows = append(rows, []exportCell{textCell("收入合计"), textCell(""), amountCell(is.TotalRevenue)})
+		rows = appendLines(rows, "费用", is.Expenses)
+		rows = append(rows, []exportCell{textCell("费用合计"), textCell(""), amountCell(is.TotalExpense)})
+		rows = append(rows, []exportCell{textCell("净利润"), textCell(""), amountCell(is.NetProfit)})
+	case model.FinancialReportTypeBalanceSheet:
+		var bs BalanceSheet
+		if err := json.Unmarshal(report.ReportData, &bs); err != nil {
+			return nil, err
+		}
+		rows = append(rows, []exportCell{textCell("项目"), textCell("科目编码"), textCell("金额")})
+		rows = appendLines(rows, "资产", bs.Assets)
+		rows = append(rows, []exportCell{textCell("资产合计"), textCell(""), amountCell(bs.TotalAssets)})
+		rows = appendLines(rows, "负债", bs.Liabilities)
+		rows = append(rows, []exportCell{textCell("负债合计"), textCell(""), amountCell(bs.TotalLiabilities)})
+		rows = appendLines(rows, "所有者权益", bs.Equity)
+		rows = append(rows, []exportCell{textCell("所有者权益合计"), textCell(""), amountCell(bs.TotalEquity)})
+		rows = append(rows, []exportCell{textCell("负债和所有者权益合计"), textCell(""), amountCell(bs.TotalLiabilities + bs.TotalEquity)})
+	case model.FinancialReportTypeCashFlow:
+		var cf CashFlowStatement
+		if err := json.Unmarshal(report.ReportData, &cf); err != nil {
+			return nil, err
+		}
+		rows = append(rows, []exportCell{textCell("项目"), textCell("业务类型"), textCell("金额")})
+		rows = append(rows, []exportCell{textCell("期初现金余额"), textCell(""), amountCell(cf.OpeningCash)})
+		rows = append(rows, []exportCell{textCell("现金流入")})
+		for _, l := range cf.Inflows {
+			rows = append(rows, []exportCell{textCell("  " + l.Label), textCell(l.BusinessType), amountCell(l.Amount)})
+		}
+		rows = append(rows, []exportCell{textCell("现金流入小计"), textCell(""), amountCell(cf.TotalInflow)})
+		rows = append(rows, []exportCell{textCell("现金流出")})
+		for _, l := range cf.Outflows {
+			rows = append(rows, []exportCell{textCell("  " + l.Label), textCell(l.BusinessType), amountCell(l.Amount)})
+		}
+		rows = append(rows, []exportCell{textCell("现金流出小计"), textCell(""), amountCell(cf.TotalOutflow)})
+		rows = append(rows, []exportCell{textCell("现金净增加额"), textCell(""), amountCell(cf.NetChange)})
+		rows = append(rows, []exportCell{textCell("期末现金余额"), textCell(""), amountCell(cf.ClosingCash)})
+	default:
+		return nil, fmt.Errorf("%w: unsupported report type %q", ErrValidation, report.Type)
+	}
+	return rows, nil
+}
+
+func appendLines(rows [][]exportCell, section string, lines []StatementLine) [][]exportCell {
+	rows = append(rows, []exportCell{textCell(section)})
+	for _, l := range lines {
+		rows = append(rows, []exportCell{textCell("  " + l.AccountName), textCell(l.AccountCode), amountCell(l.Amount)})
+	}
+	return rows
+}
+
+func writeCSV(rows [][]exportCell) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xEF, 0xBB, 0xBF}) // Excel 识别 UTF-8
+	w := csv.NewWriter(&buf)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, c := range row {
+			record[i] = c.Value
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// writeXLSX 生成只含一个工作表的最小 XLSX 文件（内联字符串，无样式）。
+func writeXLSX(sheetName string, rows [][]exportCell) ([]byte, error) {
+	var sheet bytes.Buffer
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			if cell.Numeric {
+				fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, cell.Value)
+				continue
+			}
+			fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(&sheet, []byte(cell.Value)); err != nil {
+				return nil, err
+			}
+			sheet.WriteString(`</t></is></c>`)
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var name bytes.Buffer
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+	parts := []struct{ path, body string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, p := range parts {
+		w, err := zw.Create(p.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(p.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// columnName 把从 0 开始的列序号转换为 A、B、…、AA 形式。
+func columnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,706 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	ledgerrepo "gamelink/internal/repository/ledger"
+)
+
+var (
+	// ErrNotFound 凭证或科目不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrUnbalanced 借贷不平衡
+	ErrUnbalanced = errors.New("voucher is not balanced")
+	// ErrInvalidStatus 凭证状态不允许该操作
+	ErrInvalidStatus = errors.New("invalid voucher status")
+	// ErrSelfReview 制单人不能审核自己的凭证
+	ErrSelfReview = errors.New("voucher creator cannot review it")
+	// ErrAlreadyReversed 凭证已被冲销
+	ErrAlreadyReversed = errors.New("voucher already reversed")
+)
+
+// LedgerService 总账服务（复式记账）
+//
+// 功能：
+// 1. 手工凭证：草稿 → 审核 → 过账，过账时更新科目余额并写入科目流水
+// 2. 红字冲销：对已过账凭证生成金额取负的冲销凭证，同样需审核过账
+// 3. 自动凭证：收款、确认抽成、确认应付陪玩师、提现打款、退款，按业务单号幂等
+// 4. 报表与结账：按已过账分录生成试算平衡表、利润表、资产负债表、现金流量表；按月结转损益
+//
+// 所有凭证在创建时校验借贷平衡，不平衡的分录一律拒绝。
+type LedgerService struct {
+	ledger ledgerrepo.LedgerRepository
+	tx     TxManager
+	fx     CurrencyConverter
+}
+
+// CurrencyConverter 按汇率换算金额（由汇率服务实现）。
+type CurrencyConverter interface {
+	Convert(ctx context.Context, amountCents int64, from, to model.Currency) (int64, error)
+}
+
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
+}
+
+// NewLedgerService 创建总账服务
+func NewLedgerService(ledger ledgerrepo.LedgerRepository) *LedgerService {
+	return &LedgerService{ledger: ledger}
+}
+
+// SetTxManager 注入事务管理器，过账与余额更新在同一事务内完成。
+func (s *LedgerService) SetTxManager(tx TxManager) { s.tx = tx }
+
+// SetFX 注入汇率服务，报表与结账时把外币分录折算为报表币种。
+func (s *LedgerService) SetFX(fx CurrencyConverter) { s.fx = fx }
+
+// EnsureSystemAccounts 补齐系统科目。
+func (s *LedgerService) EnsureSystemAccounts(ctx context.Context) error {
+	for _, account := range model.SystemFinancialAccounts() {
+		account := account
+		if err := s.ledger.EnsureAccount(ctx, &account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VoucherLine 凭证分录输入；借贷金额必须且只能填写一方。
+type VoucherLine struct {
+	AccountCode     string
+	Abstract        string
+	DebitCents      int64
+	CreditCents     int64
+	RelatedEntity   string
+	RelatedEntityID *uint64
+}
+
+// CreateVoucherRequest 手工凭证请求
+type CreateVoucherRequest struct {
+	Date            time.Time
+	Type            model.FinancialVoucherType // manual（默认）或 adjust
+	Abstract        string
+	BusinessType    string
+	BusinessNo      string
+	Currency        model.Currency
+	AttachmentCount int
+	Lines           []VoucherLine
+	CreatedBy       uint64
+}
+
+// CreateVoucher 创建手工凭证（草稿）。
+func (s *LedgerService) CreateVoucher(ctx context.Context, req CreateVoucherRequest) (*model.FinancialVoucher, error) {
+	if req.Type == "" {
+		req.Type = model.FinancialVoucherTypeManual
+	}
+	if req.Type != model.FinancialVoucherTypeManual && req.Type != model.FinancialVoucherTypeAdjust {
+		return nil, fmt.Errorf("%w: voucher type %q cannot be created manually", ErrValidation, req.Type)
+	}
+	if strings.TrimSpace(req.Abstract) == "" || req.CreatedBy == 0 {
+		return nil, ErrValidation
+	}
+	voucher, err := buildVoucher(req.Type, req.Date, strings.TrimSpace(req.Abstract), req.Currency, req.Lines)
+	if err != nil {
+		return nil, err
+	}
+	voucher.BusinessType = req.BusinessType
+	voucher.BusinessNo = req.BusinessNo
+	voucher.AttachmentCount = req.AttachmentCount
+	voucher.CreatedBy = req.CreatedBy
+
+	err = s.withTx(ctx, func(repo ledgerrepo.LedgerRepository) error {
+		if err := checkPeriodOpen(ctx, repo, voucher.VoucherDate); err != nil {
+			return err
+		}
+		if err := checkAccounts(ctx, repo, voucher.Entries); err != nil {
+			return err
+		}
+		return repo.CreateVoucher(ctx, voucher)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return voucher, nil
+}
+
+// GetVoucher 获取凭证详情（含分录）。
+func (s *LedgerService) GetVoucher(ctx context.Context, id uint64) (*model.FinancialVoucher, error) {
+	return s.ledger.GetVoucher(ctx, id)
+}
+
+// ListVouchers 查询凭证。
+func (s *LedgerService) ListVouchers(ctx context.Context, opts ledgerrepo.VoucherListOptions) ([]model.FinancialVoucher, int64, error) {
+	return s.ledger.ListVouchers(ctx, opts)
+}
+
+// ListAccounts 返回科目及当前余额。
+func (s *LedgerService) ListAccounts(ctx context.Context) ([]model.FinancialAccount, error) {
+	return s.ledger.ListAccounts(ctx)
+}
+
+// ListTransactions 查询科目流水。
+func (s *LedgerService) ListTransactions(ctx context.Context, opts ledgerrepo.TransactionListOptions) ([]model.FinancialTransaction, int64, error) {
+	return s.ledger.ListTransactions(ctx, opts)
+}
+
+// ApproveVoucher 审核凭证（草稿/待审核 → 已审核）。
+func (s *LedgerService) ApproveVoucher(ctx context.Context, id uint64, reviewerID uint64) (*model.FinancialVoucher, error) {
+	var voucher *model.FinancialVoucher
+	err := s.withTx(ctx, func(repo ledgerrepo.LedgerRepository) error {
+		v, err := repo.GetVoucher(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := approve(ctx, repo, v, &reviewerID); err != nil {
+			return err
+		}
+		voucher = v
+		return nil
+	})
+	return voucher, err
+}
+
+// RejectVoucher 驳回凭证（草稿/待审核 → 已驳回）。
+func (s *LedgerService) RejectVoucher(ctx context.Context, id uint64, reviewerID uint64) (*model.FinancialVoucher, error) {
+	var voucher *model.FinancialVoucher
+	err := s.withTx(ctx, func(repo ledgerrepo.LedgerRepository) error {
+		v, err := repo.GetVoucher(ctx, id)
+		if err != nil {
+			return err
+		}
+		if v.Status != model.FinancialVoucherStatusDraft && v.Status != model.FinancialVoucherStatusPending {
+			return fmt.Errorf("%w: cannot reject %s voucher", ErrInvalidStatus, v.Status)
+		}
+		now := time.Now()
+		v.Status = model.FinancialVoucherStatusRejected
+		v.ReviewedBy = &reviewerID
+		v.ReviewedAt = &now
+		voucher = v
+		return repo.UpdateVoucherStatus(ctx, v)
+	})
+	return voucher, err
+}
+
+// PostVoucher 过账（已审核 → 已过账），更新科目余额并写入流水。
+func (s *LedgerService) PostVoucher(ctx context.Context, id uint64, posterID uint64) (*model.FinancialVoucher, error) {
+	var voucher *model.FinancialVoucher
+	err := s.withTx(ctx, func(repo ledgerrepo.LedgerRepository) error {
+		v, err := repo.GetVoucher(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := post(ctx, repo, v, &posterID); err != nil {
+			return err
+		}
+		voucher = v
+		return nil
+	})
+	return voucher, err
+}
+
+// ReverseVoucher 为已过账凭证生成红字冲销凭证（草稿），每张凭证只能冲销一次。
+func (s *LedgerService) ReverseVoucher(ctx context.Context, id uint64, actorID uint64, reason string) (*model.FinancialVoucher, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || actorID == 0 {
+		return nil, ErrValidation
+	}
+	var reversal *model.FinancialVoucher
+	err := s.withTx(ctx, func(repo ledgerrepo.LedgerRepository) error {
+		v, err := repo.GetVoucher(ctx, id)
+		if err != nil {
+			return err
+		}
+		if v.Status != model.FinancialVoucherStatusPosted {
+			return fmt.Errorf("%w: only posted vouchers can be reversed", ErrInvalidStatus)
+		}
+		if v.Type == model.FinancialVoucherTypeReversal {
+			return fmt.Errorf("%w: reversal vouchers cannot be reversed", ErrInvalidStatus)
+		}
+		if _, err := repo.FindReversal(ctx, v.ID); err == nil {
+			return ErrAlreadyReversed
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		reversal = reversalOf(v, reason)
+		reversal.CreatedBy = actorID
+		return repo.CreateVoucher(ctx, reversal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// PostPaymentReceived 收款：借 渠道资金，贷 预收账款。
+//
+// r 不为空时在调用方事务内记账，否则自行开启事务；同一支付只记账一次。
+func (s *LedgerService) PostPaymentReceived(ctx context.Context, r *common.Repos, payment *model.Payment) error {
+	if payment.AmountCents <= 0 {
+		return nil
+	}
+	businessNo := payment.OutTradeNo
+	if businessNo == "" {
+		businessNo = "PAY" + strconv.FormatUint(payment.ID, 10)
+	}
+	date := time.Now()
+	if payment.PaidAt != nil {
+		date = *payment.PaidAt
+	}
+	return s.inTx(ctx, r, func(repo ledgerrepo.LedgerRepository) error {
+		return postAuto(ctx, repo, autoVoucher{
+			businessType: model.LedgerBusinessPaymentReceived,
+			businessNo:   businessNo,
+			abstract:     fmt.Sprintf("收到订单 %d 支付款", payment.OrderID),
+			date:         date,
+			currency:     payment.Currency,
+			entity:       string(model.OpEntityPayment),
+			entityID:     payment.ID,
+			debit:        model.FinancialAccountChannelFunds,
+			credit:       model.FinancialAccountAdvanceReceipt,
+			amount:       payment.AmountCents,
+		})
+	})
+}
+
+// PostCommission 订单完成：确认平台抽成收入与应付陪玩师收益；
+// 平台承担的优惠超过抽成时，补贴部分另记 借 销售费用-优惠补贴，贷 应付陪玩师。
+func (s *LedgerService) PostCommission(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error {
+	businessNo := strconv.FormatUint(record.OrderID, 10)
+	if record.TeamID != nil {
+		// 车队订单每位出车成员一条分成记录，分别入账
+		businessNo += "-" + strconv.FormatUint(record.PlayerID, 10)
+	}
+	return s.inTx(ctx, r, func(repo ledgerrepo.LedgerRepository) error {
+		if err := postAuto(ctx, repo, autoVoucher{
+			businessType: model.LedgerBusinessCommission,
+			businessNo:   businessNo,
+			abstract:     fmt.Sprintf("确认订单 %d 平台抽成", record.OrderID),
+			date:         time.Now(),
+			currency:     record.Currency,
+			entity:       string(model.OpEntityOrder),
+			entityID:     record.OrderID,
+			debit:        model.FinancialAccountAdvanceReceipt,
+			credit:       model.FinancialAccountCommission,
+			amount:       record.CommissionCents,
+		}); err != nil {
+			return err
+		}
+		if err := postAuto(ctx, repo, autoVoucher{
+			businessType: model.LedgerBusinessPlayerPayable,
+			businessNo:   businessNo,
+			abstract:     fmt.Sprintf("确认订单 %d 应付陪玩师 %d 收益", record.OrderID, record.PlayerID),
+			date:         time.Now(),
+			currency:     record.Currency,
+			entity:       string(model.OpEntityPlayer),
+			entityID:     record.PlayerID,
+			debit:        model.FinancialAccountAdvanceReceipt,
+			credit:       model.FinancialAccountPlayerPayable,
+			amount:       record.PlayerIncomeCents - record.SubsidyCents,
+		}); err != nil {
+			return err
+		}
+		return postAuto(ctx, repo, autoVoucher{
+			businessType: model.LedgerBusinessCouponSubsidy,
+			businessNo:   businessNo,
+			abstract:     fmt.Sprintf("订单 %d 平台优惠补贴陪玩师 %d", record.OrderID, record.PlayerID),
+			date:         time.Now(),
+			currency:     record.Currency,
+			entity:       string(model.OpEntityPlayer),
+			entityID:     record.PlayerID,
+			debit:        model.FinancialAccountCouponSubsidy,
+			credit:       model.FinancialAccountPlayerPayable,
+			amount:       record.SubsidyCents,
+		})
+	})
+}
+
+// PostWithdrawalPaid 提现打款：借 应付陪玩师，贷 渠道资金。
+func (s *LedgerService) PostWithdrawalPaid(ctx context.Context, r *common.Repos, withdraw *model.Withdraw) error {
+	date := time.Now()
+	if withdraw.CompletedAt != nil {
+		date = *withdraw.CompletedAt
+	}
+	return s.inTx(ctx, r, func(repo ledgerrepo.LedgerRepository) error {
+		return postAuto(ctx, repo, autoVoucher{
+			businessType: model.LedgerBusinessWithdrawalPaid,
+			businessNo:   strconv.FormatUint(withdraw.ID, 10),
+			abstract:     fmt.Sprintf("陪玩师 %d 提现打款", withdraw.PlayerID),
+			date:         date,
+			currency:     withdraw.Currency,
+			entity:       string(model.OpEntityWithdraw),
+			entityID:     withdraw.ID,
+			debit:        model.FinancialAccountPlayerPayable,
+			credit:       model.FinancialAccountChannelFunds,
+			amount:       withdraw.AmountCents,
+		})
+	})
+}
+
+// PostRefundIssued 退款成功：借 预收账款，贷 渠道资金。
+//
+// 已确认抽成的订单退款后预收账款会出现借方余额，由财务通过调整凭证处理。
+func (s *LedgerService) PostRefundIssued(ctx context.Context, r *common.Repos, refund *model.Refund) error {
+	if refund.Status != model.RefundStatusSucceeded {
+		return fmt.Errorf("%w: refund %s is %s", ErrValidation, refund.OutRefundNo, refund.Status)
+	}
+	date := time.Now()
+	if refund.RefundedAt != nil {
+		date = *refund.RefundedAt
+	}
+	return s.inTx(ctx, r, func(repo ledgerrepo.LedgerRepository) error {
+		return postAuto(ctx, repo, autoVoucher{
+			businessType: model.LedgerBusinessRefundIssued,
+			businessNo:   refund.OutRefundNo,
+			abstract:     fmt.Sprintf("订单 %d 退款", refund.OrderID),
+			date:         date,
+			currency:     refund.Currency,
+			entity:       string(model.OpEntityRefund),
+			entityID:     refund.ID,
+			debit:        model.FinancialAccountAdvanceReceipt,
+			credit:       model.FinancialAccountChannelFunds,
+			amount:       refund.AmountCents,
+		})
+	})
+}
+
+// PostCommissionAdjustment 争议退款责任拆分：陪玩师扣款 借 应付陪玩师，平台赔付 借 营业外支出，均贷 预收账款，
+// 冲平退款后预收账款的借方余额。
+func (s *LedgerService) PostCommissionAdjustment(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment) error {
+	a := autoVoucher{
+		businessType: model.LedgerBusinessDisputeAdjust,
+		businessNo:   strconv.FormatUint(adj.ID, 10),
+		date:         time.Now(),
+		currency:     adj.Currency,
+		credit:       model.FinancialAccountAdvanceReceipt,
+		amount:       adj.AmountCents,
+	}
+	switch adj.Kind {
+	case model.CommissionAdjustmentPlayerDeduction:
+		a.abstract = fmt.Sprintf("订单 %d 争议退款由陪玩师 %d 承担", adj.OrderID, adj.PlayerID)
+		a.entity, a.entityID = string(model.OpEntityPlayer), adj.PlayerID
+		a.debit = model.FinancialAccountPlayerPayable
+	case model.CommissionAdjustmentPlatformCompensation:
+		a.abstract = fmt.Sprintf("订单 %d 争议退款由平台承担", adj.OrderID)
+		a.entity, a.entityID = string(model.OpEntityOrder), adj.OrderID
+		a.debit = model.FinancialAccountCompensation
+	default:
+		return fmt.Errorf("%w: unknown adjustment kind %q", ErrValidation, adj.Kind)
+	}
+	return s.inTx(ctx, r, func(repo ledgerrepo.LedgerRepository) error {
+		return postAuto(ctx, repo, a)
+	})
+}
+
+// ReverseCommissionAdjustment 争议回退：对调整凭证生成红字冲销并自动过账；未记账或已冲销时直接返回。
+func (s *LedgerService) ReverseCommissionAdjustment(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment) error {
+	return s.inTx(ctx, r, func(repo ledgerrepo.LedgerRepository) error {
+		found, err := repo.FindVoucherByBusiness(ctx, model.LedgerBusinessDisputeAdjust, strconv.FormatUint(adj.ID, 10))
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := repo.FindReversal(ctx, found.ID); err == nil {
+			return nil
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		v, err := repo.GetVoucher(ctx, found.ID)
+		if err != nil {
+			return err
+		}
+		reversal := reversalOf(v, fmt.Sprintf("争议 %d 回退", adj.DisputeID))
+		if err := repo.CreateVoucher(ctx, reversal); err != nil {
+			return err
+		}
+		if err := approve(ctx, repo, reversal, nil); err != nil {
+			return err
+		}
+		return post(ctx, repo, reversal, nil)
+	})
+}
+
+// autoVoucher 自动凭证（一借一贷）。
+type autoVoucher struct {
+	businessType string
+	businessNo   string
+	abstract     string
+	date         time.Time
+	currency     model.Currency
+	entity       string
+	entityID     uint64
+	debit        string
+	credit       string
+	amount       int64
+}
+
+// postAuto 创建并立即审核、过账自动凭证；同一业务已记账时直接返回。
+func postAuto(ctx context.Context, repo ledgerrepo.LedgerRepository, a autoVoucher) error {
+	if a.amount == 0 {
+		return nil
+	}
+	if _, err := repo.FindVoucherByBusiness(ctx, a.businessType, a.businessNo); err == nil {
+		return nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	// 业务日期所在期间已结账时记入当前日期
+	if err := checkPeriodOpen(ctx, repo, a.date); errors.Is(err, ErrPeriodClosed) {
+		a.date = time.Now()
+	} else if err != nil {
+		return err
+	}
+
+	entityID := a.entityID
+	lines := []VoucherLine{
+		{AccountCode: a.debit, Abstract: a.abstract, DebitCents: a.amount, RelatedEntity: a.entity, RelatedEntityID: &entityID},
+		{AccountCode: a.credit, Abstract: a.abstract, CreditCents: a.amount, RelatedEntity: a.entity, RelatedEntityID: &entityID},
+	}
+	voucher, err := buildVoucher(model.FinancialVoucherTypeAuto, a.date, a.abstract, a.currency, lines)
+	if err != nil {
+		return err
+	}
+	voucher.BusinessType = a.businessType
+	voucher.BusinessNo = a.businessNo
+	if err := checkAccounts(ctx, repo, voucher.Entries); err != nil {
+		return err
+	}
+	if err := repo.CreateVoucher(ctx, voucher); err != nil {
+		return err
+	}
+	if err := approve(ctx, repo, voucher, nil); err != nil {
+		return err
+	}
+	return post(ctx, repo, voucher, nil)
+}
+
+// buildVoucher 校验分录并组装草稿凭证。
+func buildVoucher(typ model.FinancialVoucherType, date time.Time, abstract string, currency model.Currency, lines []VoucherLine) (*model.FinancialVoucher, error) {
+	if date.IsZero() {
+		date = time.Now()
+	}
+	if currency == "" {
+		currency = model.CurrencyCNY
+	}
+	voucher := &model.FinancialVoucher{
+		VoucherNo:   model.GenerateOrderNo("PZ"),
+		VoucherDate: date,
+		Type:        typ,
+		Abstract:    abstract,
+		Status:      model.FinancialVoucherStatusDraft,
+	}
+	for i, line := range lines {
+		abs := strings.TrimSpace(line.Abstract)
+		if abs == "" {
+			abs = abstract
+		}
+		voucher.Entries = append(voucher.Entries, model.FinancialVoucherEntry{
+			LineNo:          i + 1,
+			AccountCode:     strings.TrimSpace(line.AccountCode),
+			Abstract:        abs,
+			DebitAmount:     line.DebitCents,
+			CreditAmount:    line.CreditCents,
+			Currency:        currency,
+			RelatedEntity:   line.RelatedEntity,
+			RelatedEntityID: line.RelatedEntityID,
+		})
+	}
+	total, err := checkBalanced(voucher.Entries, false)
+	if err != nil {
+		return nil, err
+	}
+	voucher.TotalAmount = total
+	return voucher, nil
+}
+
+// reversalOf 生成红字冲销凭证：分录方向不变、金额取负。
+func reversalOf(v *model.FinancialVoucher, reason string) *model.FinancialVoucher {
+	reversalOfID := v.ID
+	reversal := &model.FinancialVoucher{
+		VoucherNo:    model.GenerateOrderNo("PZ"),
+		VoucherDate:  time.Now(),
+		Type:         model.FinancialVoucherTypeReversal,
+		Abstract:     fmt.Sprintf("冲销 %s：%s", v.VoucherNo, reason),
+		Status:       model.FinancialVoucherStatusDraft,
+		TotalAmount:  -v.TotalAmount,
+		BusinessType: model.LedgerBusinessReversal,
+		BusinessNo:   v.VoucherNo,
+		ReversalOfID: &reversalOfID,
+	}
+	for _, e := range v.Entries {
+		reversal.Entries = append(reversal.Entries, model.FinancialVoucherEntry{
+			LineNo:          e.LineNo,
+			AccountCode:     e.AccountCode,
+			Abstract:        "冲销：" + e.Abstract,
+			DebitAmount:     -e.DebitAmount,
+			CreditAmount:    -e.CreditAmount,
+			Currency:        e.Currency,
+			BusinessType:    e.BusinessType,
+			BusinessNo:      e.BusinessNo,
+			RelatedEntity:   e.RelatedEntity,
+			RelatedEntityID: e.RelatedEntityID,
+		})
+	}
+	return reversal
+}
+
+// checkBalanced 校验分录借贷平衡并返回借方合计。
+//
+// 普通凭证金额必须为正；红字凭证金额必须为负。每行只能填写借方或贷方之一。
+func checkBalanced(entries []model.FinancialVoucherEntry, red bool) (int64, error) {
+	if len(entries) < 2 {
+		return 0, fmt.Errorf("%w: at least two entries required", ErrUnbalanced)
+	}
+	var debit, credit int64
+	for _, e := range entries {
+		if e.AccountCode == "" {
+			return 0, fmt.Errorf("%w: line %d has no account", ErrValidation, e.LineNo)
+		}
+		if (e.DebitAmount == 0) == (e.CreditAmount == 0) {
+			return 0, fmt.Errorf("%w: line %d must have exactly one of debit or credit", ErrUnbalanced, e.LineNo)
+		}
+		amount := e.DebitAmount + e.CreditAmount
+		if (red && amount > 0) || (!red && amount < 0) {
+			return 0, fmt.Errorf("%w: line %d has an amount with the wrong sign", ErrValidation, e.LineNo)
+		}
+		debit += e.DebitAmount
+		credit += e.CreditAmount
+	}
+	if debit != credit {
+		return 0, fmt.Errorf("%w: debit %d != credit %d", ErrUnbalanced, debit, credit)
+	}
+	return debit, nil
+}
+
+// checkAccounts 校验分录科目存在且启用。
+func checkAccounts(ctx context.Context, repo ledgerrepo.LedgerRepository, entries []model.FinancialVoucherEntry) error {
+	for _, e := range entries {
+		account, err := repo.GetAccount(ctx, e.AccountCode)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: account %s not found", ErrValidation, e.AccountCode)
+		}
+		if err != nil {
+			return err
+		}
+		if account.Status == model.FinancialAccountStatusInactive {
+			return fmt.Errorf("%w: account %s is inactive", ErrValidation, e.AccountCode)
+		}
+	}
+	return nil
+}
+
+// approve 审核凭证；reviewerID 为空表示系统自动审核。
+func approve(ctx context.Context, repo ledgerrepo.LedgerRepository, v *model.FinancialVoucher, reviewerID *uint64) error {
+	if v.Status != model.FinancialVoucherStatusDraft && v.Status != model.FinancialVoucherStatusPending {
+		return fmt.Errorf("%w: cannot approve %s voucher", ErrInvalidStatus, v.Status)
+	}
+	if reviewerID != nil && v.CreatedBy != 0 && v.CreatedBy == *reviewerID {
+		return ErrSelfReview
+	}
+	now := time.Now()
+	v.Status = model.FinancialVoucherStatusApproved
+	v.ReviewedBy = reviewerID
+	v.ReviewedAt = &now
+	return repo.UpdateVoucherStatus(ctx, v)
+}
+
+// post 过账：重新校验平衡，逐行更新科目余额并写入流水。
+func post(ctx context.Context, repo ledgerrepo.LedgerRepository, v *model.FinancialVoucher, posterID *uint64) error {
+	if v.Status != model.FinancialVoucherStatusApproved {
+		return fmt.Errorf("%w: cannot post %s voucher", ErrInvalidStatus, v.Status)
+	}
+	if _, err := checkBalanced(v.Entries, v.Type == model.FinancialVoucherTypeReversal); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	txs := make([]model.FinancialTransaction, 0, len(v.Entries))
+	for i := range v.Entries {
+		e := &v.Entries[i]
+		account, err := repo.GetAccount(ctx, e.AccountCode)
+		if err != nil {
+			return err
+		}
+		direction := model.FinancialAccountDirectionDebit
+		amount := e.DebitAmount
+		if e.CreditAmount != 0 {
+			direction = model.FinancialAccountDirectionCredit
+			amount = e.CreditAmount
+		}
+		// 与科目余额方向一致时增加余额，否则减少
+		delta := amount
+		if direction != account.Direction {
+			delta = -amount
+		}
+		after, err := repo.ApplyBalance(ctx, e.AccountCode, delta)
+		if err != nil {
+			return err
+		}
+		voucherID, entryID := v.ID, e.ID
+		txs = append(txs, model.FinancialTransaction{
+			TransactionNo:   fmt.Sprintf("%s-%02d", v.VoucherNo, e.LineNo),
+			TransactionDate: v.VoucherDate,
+			AccountCode:     e.AccountCode,
+			Type:            transactionType(v, account),
+			Direction:       direction,
+			Amount:          amount,
+			BalanceBefore:   after - delta,
+			BalanceAfter:    after,
+			Currency:        e.Currency,
+			Abstract:        e.Abstract,
+			BusinessType:    v.BusinessType,
+			BusinessNo:      v.BusinessNo,
+			VoucherID:       &voucherID,
+			VoucherEntryID:  &entryID,
+		})
+	}
+	if err := repo.CreateTransactions(ctx, txs); err != nil {
+		return err
+	}
+
+	v.Status = model.FinancialVoucherStatusPosted
+	v.PostedBy = posterID
+	v.PostedAt = &now
+	return repo.UpdateVoucherStatus(ctx, v)
+}
+
+func transactionType(v *model.FinancialVoucher, account *model.FinancialAccount) model.FinancialTransactionType {
+	switch {
+	case v.Type == model.FinancialVoucherTypeReversal || v.Type == model.FinancialVoucherTypeAdjust:
+		return model.FinancialTransactionTypeAdjustment
+	case account.Type == model.FinancialAccountTypeRevenue:
+		return model.FinancialTransactionTypeRevenue
+	case account.Type == model.FinancialAccountTypeExpense:
+		return model.FinancialTransactionTypeExpense
+	default:
+		return model.FinancialTransactionTypeTransfer
+	}
+}
+
+// withTx 在事务中执行 fn；未注入 TxManager 时直接使用服务自身的仓储。
+func (s *LedgerService) withTx(ctx context.Context, fn func(repo ledgerrepo.LedgerRepository) error) error {
+	if s.tx != nil {
+		return s.tx.WithTx(ctx, func(r *common.Repos) error { return fn(r.Ledger) })
+	}
+	return fn(s.ledger)
+}
+
+// inTx 优先使用调用方事务内的仓储记账。
+func (s *LedgerService) inTx(ctx context.Context, r *common.Repos, fn func(repo ledgerrepo.LedgerRepository) error) error {
+	if r != nil && r.Ledger != nil {
+		return fn(r.Ledger)
+	}
+	return s.withTx(ctx, fn)
+}
@@ -0,0 +1,284 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+	ledgerrepo "gamelink/internal/repository/ledger"
+)
+
+func newTestLedger(t *testing.T) (*LedgerService, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.FinancialAccount{}, &model.FinancialVoucher{}, &model.FinancialVoucherEntry{}, &model.FinancialTransaction{}, &model.FinancialReport{}, &model.FinancialPeriod{}))
+
+	svc := NewLedgerService(ledgerrepo.NewLedgerRepository(db))
+	svc.SetTxManager(common.NewUnitOfWork(db))
+	require.NoError(t, svc.EnsureSystemAccounts(context.Background()))
+	return svc, db
+}
+
+func balances(t *testing.T, svc *LedgerService) map[string]int64 {
+	t.Helper()
+	accounts, err := svc.ListAccounts(context.Background())
+	require.NoError(t, err)
+	out := make(map[string]int64, len(accounts))
+	for _, a := range accounts {
+		out[a.Code] = a.CurrentBalance
+	}
+	return out
+}
+
+func TestCreateVoucher_RejectsUnbalanced(t *testing.T) {
+	svc, _ := newTestLedger(t)
+	ctx := context.Background()
+
+	cases := map[string][]VoucherLine{
+		"unbalanced": {
+			{AccountCode: model.FinancialAccountChannelFunds, DebitCents: 1000},
+			{AccountCode: model.FinancialAccountAdvanceReceipt, CreditCents: 900},
+		},
+		"single line": {
+			{AccountCode: model.FinancialAccountChannelFunds, DebitCents: 1000},
+		},
+		"both sides": {
+			{AccountCode: model.FinancialAccountChannelFunds, DebitCents: 1000, CreditCents: 1000},
+			{AccountCode: model.FinancialAccountAdvanceReceipt, CreditCents: 0},
+		},
+	}
+	for name, lines := range cases {
+		_, err := svc.CreateVoucher(ctx, CreateVoucherRequest{Abstract: name, Lines: lines, CreatedBy: 1})
+		assert.ErrorIs(t, err, ErrUnbalanced, name)
+	}
+
+	_, err := svc.CreateVoucher(ctx, CreateVoucherRequest{Abstract: "negative", CreatedBy: 1, Lines: []VoucherLine{
+		{AccountCode: model.FinancialAccountChannelFunds, DebitCents: -100},
+		{AccountCode: model.FinancialAccountAdvanceReceipt, CreditCents: -100},
+	}})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	_, err = svc.CreateVoucher(ctx, CreateVoucherRequest{Abstract: "unknown account", CreatedBy: 1, Lines: []VoucherLine{
+		{AccountCode: "9999", DebitCents: 100},
+		{AccountCode: model.FinancialAccountAdvanceReceipt, CreditCents: 100},
+	}})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	_, err = svc.CreateVoucher(ctx, CreateVoucherRequest{Abstract: "auto", Type: model.FinancialVoucherTypeAuto, CreatedBy: 1, Lines: []VoucherLine{
+		{AccountCode: model.FinancialAccountChannelFunds, DebitCents: 100},
+		{AccountCode: model.FinancialAccountAdvanceReceipt, CreditCents: 100},
+	}})
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestManualVoucher_Workflow(t *testing.T) {
+	svc, db := newTestLedger(t)
+	ctx := context.Background()
+
+	v, err := svc.CreateVoucher(ctx, CreateVoucherRequest{Abstract: "期初资金", CreatedBy: 1, Lines: []VoucherLine{
+		{AccountCode: model.FinancialAccountChannelFunds, DebitCents: 5000},
+		{AccountCode: model.FinancialAccountAdvanceReceipt, CreditCents: 3000},
+		{AccountCode: model.FinancialAccountPlayerPayable, CreditCents: 2000},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, model.FinancialVoucherStatusDraft, v.Status)
+	assert.Equal(t, int64(5000), v.TotalAmount)
+
+	// 未审核不能过账，制单人不能审核自己的凭证
+	_, err = svc.PostVoucher(ctx, v.ID, 2)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	_, err = svc.ApproveVoucher(ctx, v.ID, 1)
+	assert.ErrorIs(t, err, ErrSelfReview)
+
+	v, err = svc.ApproveVoucher(ctx, v.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, model.FinancialVoucherStatusApproved, v.Status)
+	assert.Zero(t, balances(t, svc)[model.FinancialAccountChannelFunds], "approval must not touch balances")
+
+	v, err = svc.PostVoucher(ctx, v.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, model.FinancialVoucherStatusPosted, v.Status)
+	require.NotNil(t, v.PostedAt)
+
+	b := balances(t, svc)
+	assert.Equal(t, int64(5000), b[model.FinancialAccountChannelFunds])
+	assert.Equal(t, int64(3000), b[model.FinancialAccountAdvanceReceipt])
+	assert.Equal(t, int64(2000), b[model.FinancialAccountPlayerPayable])
+
+	txs, total, err := svc.ListTransactions(ctx, ledgerrepo.TransactionListOptions{AccountCode: model.FinancialAccountChannelFunds})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	assert.Equal(t, int64(0), txs[0].BalanceBefore)
+	assert.Equal(t, int64(5000), txs[0].BalanceAfter)
+	assert.Equal(t, model.FinancialAccountDirectionDebit, txs[0].Direction)
+
+	// 重复过账被拒绝
+	_, err = svc.PostVoucher(ctx, v.ID, 2)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	var n int64
+	require.NoError(t, db.Model(&model.FinancialTransaction{}).Count(&n).Error)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestReverseVoucher_RedLetter(t *testing.T) {
+	svc, _ := newTestLedger(t)
+	ctx := context.Background()
+
+	v, err := svc.CreateVoucher(ctx, CreateVoucherRequest{Abstract: "错账", CreatedBy: 1, Lines: []VoucherLine{
+		{AccountCode: model.FinancialAccountChannelFunds, DebitCents: 800},
+		{AccountCode: model.FinancialAccountCommission, CreditCents: 800},
+	}})
+	require.NoError(t, err)
+
+	// 未过账的凭证不能冲销
+	_, err = svc.ReverseVoucher(ctx, v.ID, 2, "录错")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	_, err = svc.ApproveVoucher(ctx, v.ID, 2)
+	require.NoError(t, err)
+	_, err = svc.PostVoucher(ctx, v.ID, 2)
+	require.NoError(t, err)
+
+	reversal, err := svc.ReverseVoucher(ctx, v.ID, 2, "录错")
+	require.NoError(t, err)
+	assert.Equal(t, model.FinancialVoucherTypeReversal, reversal.Type)
+	require.NotNil(t, reversal.ReversalOfID)
+	assert.Equal(t, v.ID, *reversal.ReversalOfID)
+	require.Len(t, reversal.Entries, 2)
+	assert.Equal(t, int64(-800), reversal.Entries[0].DebitAmount)
+	assert.Equal(t, int64(-800), reversal.Entries[1].CreditAmount)
+
+	// 每张凭证只能冲销一次
+	_, err = svc.ReverseVoucher(ctx, v.ID, 3, "again")
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+
+	_, err = svc.ApproveVoucher(ctx, reversal.ID, 3)
+	require.NoError(t, err)
+	_, err = svc.PostVoucher(ctx, reversal.ID, 3)
+	require.NoError(t, err)
+
+	b := balances(t, svc)
+	assert.Zero(t, b[model.FinancialAccountChannelFunds])
+	assert.Zero(t, b[model.FinancialAccountCommission])
+
+	// 冲销凭证本身不能再冲销
+	_, err = svc.ReverseVoucher(ctx, reversal.ID, 3, "x")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestAutoPostings_LifecycleBalances(t *testing.T) {
+	svc, _ := newTestLedger(t)
+	ctx := context.Background()
+	paidAt := time.Now()
+
+	payment := &model.Payment{Base: model.Base{ID: 1}, OrderID: 10, OutTradeNo: "PAY-1", AmountCents: 10000, Currency: model.CurrencyCNY, PaidAt: &paidAt}
+	require.NoError(t, svc.PostPaymentReceived(ctx, nil, payment))
+	// 重复通知不会重复记账
+	require.NoError(t, svc.PostPaymentReceived(ctx, nil, payment))
+
+	record := &model.CommissionRecord{OrderID: 10, PlayerID: 7, TotalAmountCents: 10000, CommissionCents: 2000, PlayerIncomeCents: 8000}
+	require.NoError(t, svc.PostCommission(ctx, nil, record))
+	require.NoError(t, svc.PostCommission(ctx, nil, record))
+
+	completedAt := time.Now()
+	withdraw := &model.Withdraw{ID: 3, PlayerID: 7, AmountCents: 5000, CompletedAt: &completedAt}
+	require.NoError(t, svc.PostWithdrawalPaid(ctx, nil, withdraw))
+
+	refund := &model.Refund{Base: model.Base{ID: 4}, OrderID: 11, OutRefundNo: "RF-1", AmountCents: 1000, Status: model.RefundStatusPending}
+	assert.ErrorIs(t, svc.PostRefundIssued(ctx, nil, refund), ErrValidation)
+	refund.Status = model.RefundStatusSucceeded
+	require.NoError(t, svc.PostRefundIssued(ctx, nil, refund))
+
+	b := balances(t, svc)
+	assert.Equal(t, int64(10000-5000-1000), b[model.FinancialAccountChannelFunds])
+	assert.Equal(t, int64(10000-2000-8000-1000), b[model.FinancialAccountAdvanceReceipt])
+	assert.Equal(t, int64(8000-5000), b[model.FinancialAccountPlayerPayable])
+	assert.Equal(t, int64(2000), b[model.FinancialAccountCommission])
+
+	posted := model.FinancialVoucherStatusPosted
+	vouchers, total, err := svc.ListVouchers(ctx, ledgerrepo.VoucherListOptions{Status: &posted})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	for _, v := range vouchers {
+		assert.Equal(t, model.FinancialVoucherTypeAuto, v.Type)
+		assert.Nil(t, v.ReviewedBy)
+		assert.NotNil(t, v.PostedAt)
+	}
+}
+
+func TestPostCommission_BooksCouponSubsidy(t *testing.T) {
+	svc, _ := newTestLedger(t)
+	ctx := context.Background()
+	paidAt := time.Now()
+
+	// 原价 10000，平台承担 5000 优惠：陪玩师按原价得 8000，其中 3000 为平台补贴
+	require.NoError(t, svc.PostPaymentReceived(ctx, nil, &model.Payment{Base: model.Base{ID: 1}, OrderID: 10, OutTradeNo: "PAY-1", AmountCents: 5000, PaidAt: &paidAt}))
+	record := &model.CommissionRecord{OrderID: 10, PlayerID: 7, TotalAmountCents: 5000, PlayerIncomeCents: 8000, SubsidyCents: 3000}
+	require.NoError(t, svc.PostCommission(ctx, nil, record))
+	require.NoError(t, svc.PostCommission(ctx, nil, record))
+
+	b := balances(t, svc)
+	assert.Zero(t, b[model.FinancialAccountAdvanceReceipt])
+	assert.Zero(t, b[model.FinancialAccountCommission])
+	assert.Equal(t, int64(8000), b[model.FinancialAccountPlayerPayable])
+	assert.Equal(t, int64(3000), b[model.FinancialAccountCouponSubsidy])
+}
+
+func TestCommissionAdjustment_ClearsRefundAndReverses(t *testing.T) {
+	svc, _ := newTestLedger(t)
+	ctx := context.Background()
+	paidAt := time.Now()
+
+	require.NoError(t, svc.PostPaymentReceived(ctx, nil, &model.Payment{Base: model.Base{ID: 1}, OrderID: 10, OutTradeNo: "PAY-1", AmountCents: 10000, PaidAt: &paidAt}))
+	require.NoError(t, svc.PostCommission(ctx, nil, &model.CommissionRecord{OrderID: 10, PlayerID: 7, CommissionCents: 2000, PlayerIncomeCents: 8000}))
+	require.NoError(t, svc.PostRefundIssued(ctx, nil, &model.Refund{Base: model.Base{ID: 4}, OrderID: 10, OutRefundNo: "RF-1", AmountCents: 3000, Status: model.RefundStatusSucceeded}))
+
+	deduction := &model.CommissionAdjustment{ID: 1, DisputeID: 5, OrderID: 10, PlayerID: 7, Kind: model.CommissionAdjustmentPlayerDeduction, AmountCents: 2000}
+	compensation := &model.CommissionAdjustment{ID: 2, DisputeID: 5, OrderID: 10, Kind: model.CommissionAdjustmentPlatformCompensation, AmountCents: 1000}
+	require.NoError(t, svc.PostCommissionAdjustment(ctx, nil, deduction))
+	require.NoError(t, svc.PostCommissionAdjustment(ctx, nil, deduction))
+	require.NoError(t, svc.PostCommissionAdjustment(ctx, nil, compensation))
+	assert.ErrorIs(t, svc.PostCommissionAdjustment(ctx, nil, &model.CommissionAdjustment{ID: 3, Kind: "bonus", AmountCents: 1}), ErrValidation)
+
+	b := balances(t, svc)
+	assert.Zero(t, b[model.FinancialAccountAdvanceReceipt], "split covers the refunded amount")
+	assert.Equal(t, int64(6000), b[model.FinancialAccountPlayerPayable])
+	assert.Equal(t, int64(1000), b[model.FinancialAccountCompensation])
+
+	require.NoError(t, svc.ReverseCommissionAdjustment(ctx, nil, deduction))
+	require.NoError(t, svc.ReverseCommissionAdjustment(ctx, nil, deduction))
+	require.NoError(t, svc.ReverseCommissionAdjustment(ctx, nil, &model.CommissionAdjustment{ID: 9}), "nothing posted, nothing to reverse")
+
+	b = balances(t, svc)
+	assert.Equal(t, int64(8000), b[model.FinancialAccountPlayerPayable])
+	assert.Equal(t, int64(-2000), b[model.FinancialAccountAdvanceReceipt])
+}
+
+func TestAutoPostings_UseCallerTransaction(t *testing.T) {
+	svc, db := newTestLedger(t)
+	ctx := context.Background()
+	uow := common.NewUnitOfWork(db)
+
+	payment := &model.Payment{Base: model.Base{ID: 2}, OrderID: 20, OutTradeNo: "PAY-2", AmountCents: 500}
+	err := uow.WithTx(ctx, func(r *common.Repos) error {
+		require.NoError(t, svc.PostPaymentReceived(ctx, r, payment))
+		return assert.AnError
+	})
+	require.ErrorIs(t, err, assert.AnError)
+
+	var n int64
+	require.NoError(t, db.Model(&model.FinancialVoucher{}).Count(&n).Error)
+	assert.Zero(t, n, "voucher must roll back with the caller's transaction")
+	assert.Zero(t, balances(t, svc)[model.FinancialAccountChannelFunds])
+}
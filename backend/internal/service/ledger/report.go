@@ -0,0 +1,449 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+	ledgerrepo "gamelink/internal/repository/ledger"
+)
+
+// GenerateReportRequest 生成报表请求；期间为 [PeriodStart, PeriodEnd)。
+type GenerateReportRequest struct {
+	Type        model.FinancialReportType
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Currency    model.Currency // 报表币种，默认 CNY
+	GeneratedBy uint64
+}
+
+// TrialBalanceRow 试算平衡表行
+type TrialBalanceRow struct {
+	AccountCode    string                          `json:"accountCode"`
+	AccountName    string                          `json:"accountName"`
+	AccountType    model.FinancialAccountType      `json:"accountType"`
+	Direction      model.FinancialAccountDirection `json:"direction"`
+	OpeningBalance int64                           `json:"openingBalance"`
+	PeriodDebit    int64                           `json:"periodDebit"`
+	PeriodCredit   int64                           `json:"periodCredit"`
+	ClosingBalance int64                           `json:"closingBalance"`
+}
+
+// TrialBalance 试算平衡表：本期借方合计必须等于贷方合计。
+//
+// 外币分录按当前汇率折算为报表币种，Balanced 按原币逐币种校验，不受折算尾差影响。
+type TrialBalance struct {
+	Currency    model.Currency    `json:"currency"`
+	Rows        []TrialBalanceRow `json:"rows"`
+	TotalDebit  int64             `json:"totalDebit"`
+	TotalCredit int64             `json:"totalCredit"`
+	Balanced    bool              `json:"balanced"`
+}
+
+// StatementLine 报表项目（按科目）
+type StatementLine struct {
+	AccountCode string `json:"accountCode,omitempty"`
+	AccountName string `json:"accountName"`
+	Amount      int64  `json:"amount"`
+}
+
+// IncomeStatement 利润表（不含结账凭证的本期发生额）
+type IncomeStatement struct {
+	Currency     model.Currency  `json:"currency"`
+	Revenue      []StatementLine `json:"revenue"`
+	Expenses     []StatementLine `json:"expenses"`
+	TotalRevenue int64           `json:"totalRevenue"`
+	TotalExpense int64           `json:"totalExpense"`
+	NetProfit    int64           `json:"netProfit"`
+}
+
+// BalanceSheet 资产负债表（期末余额）；未结转的损益列入所有者权益。
+type BalanceSheet struct {
+	Currency         model.Currency  `json:"currency"`
+	Assets           []StatementLine `json:"assets"`
+	Liabilities      []StatementLine `json:"liabilities"`
+	Equity           []StatementLine `json:"equity"`
+	TotalAssets      int64           `json:"totalAssets"`
+	TotalLiabilities int64           `json:"totalLiabilities"`
+	TotalEquity      int64           `json:"totalEquity"`
+	Balanced         bool            `json:"balanced"`
+}
+
+// CashFlowLine 现金流量项目（按业务类型）
+type CashFlowLine struct {
+	BusinessType string `json:"businessType"`
+	Label        string `json:"label"`
+	Amount       int64  `json:"amount"`
+}
+
+// CashFlowStatement 现金流量表（直接法，按凭证业务类型归集货币资金科目的收支）
+type CashFlowStatement struct {
+	Currency     model.Currency `json:"currency"`
+	OpeningCash  int64          `json:"openingCash"`
+	Inflows      []CashFlowLine `json:"inflows"`
+	Outflows     []CashFlowLine `json:"outflows"`
+	TotalInflow  int64          `json:"totalInflow"`
+	TotalOutflow int64          `json:"totalOutflow"`
+	NetChange    int64          `json:"netChange"`
+	ClosingCash  int64          `json:"closingCash"`
+}
+
+// unclosedProfitName 资产负债表中未结转损益的项目名称
+const unclosedProfitName = "未结转损益"
+
+var reportNames = map[model.FinancialReportType]string{
+	model.FinancialReportTypeTrialBalance:    "试算平衡表",
+	model.FinancialReportTypeIncomeStatement: "利润表",
+	model.FinancialReportTypeBalanceSheet:    "资产负债表",
+	model.FinancialReportTypeCashFlow:        "现金流量表",
+}
+
+var cashFlowLabels = map[string]string{
+	model.LedgerBusinessPaymentReceived: "用户支付收款",
+	model.LedgerBusinessRefundIssued:    "订单退款",
+	model.LedgerBusinessWithdrawalPaid:  "陪玩师提现",
+	model.LedgerBusinessReversal:        "冲销调整",
+	"":                                  "其他",
+}
+
+// GenerateReport 根据已过账分录生成报表并保存。
+func (s *LedgerService) GenerateReport(ctx context.Context, req GenerateReportRequest) (*model.FinancialReport, error) {
+	name, ok := reportNames[req.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported report type %q", ErrValidation, req.Type)
+	}
+	if req.PeriodStart.IsZero() || !req.PeriodEnd.After(req.PeriodStart) {
+		return nil, fmt.Errorf("%w: invalid period", ErrValidation)
+	}
+	currency := req.Currency.OrDefault()
+	if !model.IsValidCurrency(currency) {
+		return nil, fmt.Errorf("%w: unsupported currency %s", ErrValidation, req.Currency)
+	}
+
+	var data any
+	var err error
+	switch req.Type {
+	case model.FinancialReportTypeTrialBalance:
+		data, err = s.TrialBalance(ctx, req.PeriodStart, req.PeriodEnd, currency)
+	case model.FinancialReportTypeIncomeStatement:
+		data, err = s.IncomeStatement(ctx, req.PeriodStart, req.PeriodEnd, currency)
+	case model.FinancialReportTypeBalanceSheet:
+		data, err = s.BalanceSheet(ctx, req.PeriodEnd, currency)
+	case model.FinancialReportTypeCashFlow:
+		data, err = s.CashFlow(ctx, req.PeriodStart, req.PeriodEnd, currency)
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	report := &model.FinancialReport{
+		ReportNo:    model.GenerateOrderNo("BB"),
+		ReportName:  fmt.Sprintf("%s %s ~ %s", name, req.PeriodStart.Format("2006-01-02"), req.PeriodEnd.Add(-time.Nanosecond).Format("2006-01-02")),
+		Type:        req.Type,
+		PeriodStart: req.PeriodStart,
+		PeriodEnd:   req.PeriodEnd,
+		Currency:    currency,
+		ReportData:  raw,
+		Status:      model.FinancialReportStatusGenerated,
+		GeneratedAt: &now,
+	}
+	if req.GeneratedBy != 0 {
+		by := req.GeneratedBy
+		report.GeneratedBy = &by
+	}
+	if err := s.ledger.CreateReport(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetReport 获取报表（含数据）。
+func (s *LedgerService) GetReport(ctx context.Context, id uint64) (*model.FinancialReport, error) {
+	return s.ledger.GetReport(ctx, id)
+}
+
+// ListReports 查询报表。
+func (s *LedgerService) ListReports(ctx context.Context, opts ledgerrepo.ReportListOptions) ([]model.FinancialReport, int64, error) {
+	return s.ledger.ListReports(ctx, opts)
+}
+
+// TrialBalance 计算试算平衡表。
+func (s *LedgerService) TrialBalance(ctx context.Context, from, to time.Time, currency model.Currency) (*TrialBalance, error) {
+	sums, err := s.periodSums(ctx, from, to, false, currency)
+	if err != nil {
+		return nil, err
+	}
+	tb := &TrialBalance{Currency: sums.currency, Rows: make([]TrialBalanceRow, 0, len(sums.accounts))}
+	for _, a := range sums.accounts {
+		o, p := sums.opening[a.Code], sums.period[a.Code]
+		opening, err := s.openingBalance(ctx, a, sums.currency)
+		if err != nil {
+			return nil, err
+		}
+		row := TrialBalanceRow{
+			AccountCode:    a.Code,
+			AccountName:    a.Name,
+			AccountType:    a.Type,
+			Direction:      a.Direction,
+			OpeningBalance: opening + balanceOf(a, o),
+			PeriodDebit:    p.Debit,
+			PeriodCredit:   p.Credit,
+		}
+		row.ClosingBalance = row.OpeningBalance + balanceOf(a, p)
+		tb.TotalDebit += p.Debit
+		tb.TotalCredit += p.Credit
+		tb.Rows = append(tb.Rows, row)
+	}
+	tb.Balanced = balancedNative(nil, sums.periodRaw)
+	return tb, nil
+}
+
+// IncomeStatement 计算利润表。
+func (s *LedgerService) IncomeStatement(ctx context.Context, from, to time.Time, currency model.Currency) (*IncomeStatement, error) {
+	sums, err := s.periodSums(ctx, from, to, true, currency)
+	if err != nil {
+		return nil, err
+	}
+	is := &IncomeStatement{Currency: sums.currency, Revenue: []StatementLine{}, Expenses: []StatementLine{}}
+	for _, a := range sums.accounts {
+		amount := balanceOf(a, sums.period[a.Code])
+		switch a.Type {
+		case model.FinancialAccountTypeRevenue:
+			is.Revenue = append(is.Revenue, StatementLine{AccountCode: a.Code, AccountName: a.Name, Amount: amount})
+			is.TotalRevenue += amount
+		case model.FinancialAccountTypeExpense:
+			is.Expenses = append(is.Expenses, StatementLine{AccountCode: a.Code, AccountName: a.Name, Amount: amount})
+			is.TotalExpense += amount
+		}
+	}
+	is.NetProfit = is.TotalRevenue - is.TotalExpense
+	return is, nil
+}
+
+// BalanceSheet 计算截至 asOf（不含）的资产负债表。
+func (s *LedgerService) BalanceSheet(ctx context.Context, asOf time.Time, currency model.Currency) (*BalanceSheet, error) {
+	currency = currency.OrDefault()
+	accounts, err := s.ledger.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := s.ledger.SumEntries(ctx, ledgerrepo.EntrySumOptions{To: asOf})
+	if err != nil {
+		return nil, err
+	}
+	byCode, err := s.foldSums(ctx, sums, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	bs := &BalanceSheet{Currency: currency, Assets: []StatementLine{}, Liabilities: []StatementLine{}, Equity: []StatementLine{}}
+	var unclosed int64
+	for _, a := range accounts {
+		opening, err := s.openingBalance(ctx, a, currency)
+		if err != nil {
+			return nil, err
+		}
+		balance := opening + balanceOf(a, byCode[a.Code])
+		line := StatementLine{AccountCode: a.Code, AccountName: a.Name, Amount: balance}
+		switch a.Type {
+		case model.FinancialAccountTypeAsset:
+			bs.Assets = append(bs.Assets, line)
+			bs.TotalAssets += balance
+		case model.FinancialAccountTypeLiability:
+			bs.Liabilities = append(bs.Liabilities, line)
+			bs.TotalLiabilities += balance
+		case model.FinancialAccountTypeEquity:
+			bs.Equity = append(bs.Equity, line)
+			bs.TotalEquity += balance
+		case model.FinancialAccountTypeRevenue:
+			unclosed += balance
+		case model.FinancialAccountTypeExpense:
+			unclosed -= balance
+		}
+	}
+	if unclosed != 0 {
+		bs.Equity = append(bs.Equity, StatementLine{AccountName: unclosedProfitName, Amount: unclosed})
+		bs.TotalEquity += unclosed
+	}
+	bs.Balanced = balancedNative(accounts, sums)
+	return bs, nil
+}
+
+// CashFlow 计算现金流量表；货币资金科目为编码以 10 开头的资产科目。
+func (s *LedgerService) CashFlow(ctx context.Context, from, to time.Time, currency model.Currency) (*CashFlowStatement, error) {
+	periodSums, err := s.periodSums(ctx, from, to, false, currency)
+	if err != nil {
+		return nil, err
+	}
+	currency = periodSums.currency
+	var codes []string
+	cf := &CashFlowStatement{Currency: currency, Inflows: []CashFlowLine{}, Outflows: []CashFlowLine{}}
+	for _, a := range periodSums.accounts {
+		if !isCashAccount(a) {
+			continue
+		}
+		codes = append(codes, a.Code)
+		opening, err := s.openingBalance(ctx, a, currency)
+		if err != nil {
+			return nil, err
+		}
+		cf.OpeningCash += opening + balanceOf(a, periodSums.opening[a.Code])
+	}
+	sums, err := s.ledger.SumEntriesByBusiness(ctx, codes, from, to)
+	if err != nil {
+		return nil, err
+	}
+	// 同一业务类型的各币种发生额折算后合并
+	var merged []ledgerrepo.BusinessSum
+	index := make(map[string]int)
+	for _, sum := range sums {
+		debit, err := s.convert(ctx, sum.Debit, sum.Currency, currency)
+		if err != nil {
+			return nil, err
+		}
+		credit, err := s.convert(ctx, sum.Credit, sum.Currency, currency)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[sum.BusinessType]
+		if !ok {
+			i = len(merged)
+			index[sum.BusinessType] = i
+			merged = append(merged, ledgerrepo.BusinessSum{BusinessType: sum.BusinessType, Currency: currency})
+		}
+		merged[i].Debit += debit
+		merged[i].Credit += credit
+	}
+	for _, sum := range merged {
+		label, ok := cashFlowLabels[sum.BusinessType]
+		if !ok {
+			label = sum.BusinessType
+		}
+		if sum.Debit != 0 {
+			cf.Inflows = append(cf.Inflows, CashFlowLine{BusinessType: sum.BusinessType, Label: label, Amount: sum.Debit})
+			cf.TotalInflow += sum.Debit
+		}
+		if sum.Credit != 0 {
+			cf.Outflows = append(cf.Outflows, CashFlowLine{BusinessType: sum.BusinessType, Label: label, Amount: sum.Credit})
+			cf.TotalOutflow += sum.Credit
+		}
+	}
+	cf.NetChange = cf.TotalInflow - cf.TotalOutflow
+	cf.ClosingCash = cf.OpeningCash + cf.NetChange
+	return cf, nil
+}
+
+// reportSums 科目表与折算为报表币种的期初累计发生额、本期发生额。
+type reportSums struct {
+	currency  model.Currency
+	accounts  []model.FinancialAccount
+	opening   map[string]ledgerrepo.AccountSum
+	period    map[string]ledgerrepo.AccountSum
+	periodRaw []ledgerrepo.AccountSum // 本期原币发生额
+}
+
+// periodSums 返回科目表、期初累计发生额与本期发生额。
+func (s *LedgerService) periodSums(ctx context.Context, from, to time.Time, excludeClosing bool, currency model.Currency) (*reportSums, error) {
+	out := &reportSums{currency: currency.OrDefault()}
+	accounts, err := s.ledger.ListAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.ledger.SumEntries(ctx, ledgerrepo.EntrySumOptions{To: from})
+	if err != nil {
+		return nil, err
+	}
+	period, err := s.ledger.SumEntries(ctx, ledgerrepo.EntrySumOptions{From: &from, To: to, ExcludeClosing: excludeClosing})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Code < accounts[j].Code })
+	out.accounts, out.periodRaw = accounts, period
+	if out.opening, err = s.foldSums(ctx, before, out.currency); err != nil {
+		return nil, err
+	}
+	if out.period, err = s.foldSums(ctx, period, out.currency); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// foldSums 把各币种发生额按当前汇率折算为报表币种后按科目合并。
+func (s *LedgerService) foldSums(ctx context.Context, sums []ledgerrepo.AccountSum, currency model.Currency) (map[string]ledgerrepo.AccountSum, error) {
+	out := make(map[string]ledgerrepo.AccountSum, len(sums))
+	for _, sum := range sums {
+		debit, err := s.convert(ctx, sum.Debit, sum.Currency, currency)
+		if err != nil {
+			return nil, err
+		}
+		credit, err := s.convert(ctx, sum.Credit, sum.Currency, currency)
+		if err != nil {
+			return nil, err
+		}
+		folded := out[sum.AccountCode]
+		folded.AccountCode, folded.Currency = sum.AccountCode, currency
+		folded.Debit += debit
+		folded.Credit += credit
+		out[sum.AccountCode] = folded
+	}
+	return out, nil
+}
+
+// openingBalance 科目期初余额以本位币录入，按报表币种折算。
+func (s *LedgerService) openingBalance(ctx context.Context, a model.FinancialAccount, currency model.Currency) (int64, error) {
+	return s.convert(ctx, a.OpeningBalance, model.DefaultCurrency, currency)
+}
+
+// convert 折算金额；同币种或金额为零时不需要汇率。
+func (s *LedgerService) convert(ctx context.Context, amount int64, from, to model.Currency) (int64, error) {
+	from, to = from.OrDefault(), to.OrDefault()
+	if from == to || amount == 0 {
+		return amount, nil
+	}
+	if s.fx == nil {
+		return 0, fmt.Errorf("%w: no exchange rate service to convert %s to %s", ErrValidation, from, to)
+	}
+	return s.fx.Convert(ctx, amount, from, to)
+}
+
+// balancedNative 按原币逐币种校验借贷平衡（期初余额视为本位币），不受折算尾差影响。
+func balancedNative(accounts []model.FinancialAccount, sums []ledgerrepo.AccountSum) bool {
+	net := make(map[model.Currency]int64)
+	for _, a := range accounts {
+		if a.Direction == model.FinancialAccountDirectionCredit {
+			net[model.DefaultCurrency] -= a.OpeningBalance
+		} else {
+			net[model.DefaultCurrency] += a.OpeningBalance
+		}
+	}
+	for _, sum := range sums {
+		net[sum.Currency.OrDefault()] += sum.Debit - sum.Credit
+	}
+	for _, v := range net {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// balanceOf 按科目余额方向把借贷发生额折算为余额变动。
+func balanceOf(a model.FinancialAccount, sum ledgerrepo.AccountSum) int64 {
+	if a.Direction == model.FinancialAccountDirectionCredit {
+		return sum.Credit - sum.Debit
+	}
+	return sum.Debit - sum.Credit
+}
+
+func isCashAccount(a model.FinancialAccount) bool {
+	return a.Type == model.FinancialAccountTypeAsset && strings.HasPrefix(a.Code, "10")
+}
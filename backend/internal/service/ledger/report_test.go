@@ -0,0 +1,298 @@
+package ledger
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+	ledgerrepo "gamelink/internal/repository/ledger"
+)
+
+const costAccount = "6401"
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2024, month, d, 12, 0, 0, 0, time.Local)
+}
+
+func postManual(t *testing.T, svc *LedgerService, date time.Time, lines ...VoucherLine) {
+	t.Helper()
+	ctx := context.Background()
+	v, err := svc.CreateVoucher(ctx, CreateVoucherRequest{Date: date, Abstract: "test", CreatedBy: 1, Lines: lines})
+	require.NoError(t, err)
+	_, err = svc.ApproveVoucher(ctx, v.ID, 2)
+	require.NoError(t, err)
+	_, err = svc.PostVoucher(ctx, v.ID, 2)
+	require.NoError(t, err)
+}
+
+// seedApril 四月：收款 100 元、确认抽成 20 元、支付成本 5 元；五月退款 10 元。
+func seedApril(t *testing.T) *LedgerService {
+	t.Helper()
+	svc, db := newTestLedger(t)
+	ctx := context.Background()
+	require.NoError(t, ledgerrepo.NewLedgerRepository(db).EnsureAccount(ctx, &model.FinancialAccount{
+		Code: costAccount, Name: "主营业务成本", Type: model.FinancialAccountTypeExpense, Level: model.FinancialAccountLevel1,
+		Direction: model.FinancialAccountDirectionDebit, Status: model.FinancialAccountStatusActive,
+	}))
+
+	paidAt := day(time.April, 10)
+	require.NoError(t, svc.PostPaymentReceived(ctx, nil, &model.Payment{Base: model.Base{ID: 1}, OrderID: 1, OutTradeNo: "PAY1", AmountCents: 10000, PaidAt: &paidAt}))
+	postManual(t, svc, day(time.April, 12),
+		VoucherLine{AccountCode: model.FinancialAccountAdvanceReceipt, DebitCents: 2000},
+		VoucherLine{AccountCode: model.FinancialAccountCommission, CreditCents: 2000})
+	postManual(t, svc, day(time.April, 15),
+		VoucherLine{AccountCode: costAccount, DebitCents: 500},
+		VoucherLine{AccountCode: model.FinancialAccountChannelFunds, CreditCents: 500})
+	refundedAt := day(time.May, 5)
+	require.NoError(t, svc.PostRefundIssued(ctx, nil, &model.Refund{Base: model.Base{ID: 1}, OrderID: 1, OutRefundNo: "RF1", AmountCents: 1000, Status: model.RefundStatusSucceeded, RefundedAt: &refundedAt}))
+	return svc
+}
+
+func lineAmount(lines []StatementLine, name string) int64 {
+	for _, l := range lines {
+		if l.AccountCode == name || l.AccountName == name {
+			return l.Amount
+		}
+	}
+	return 0
+}
+
+func TestReports_FromPostedEntries(t *testing.T) {
+	svc := seedApril(t)
+	ctx := context.Background()
+	aprilStart, mayStart := day(time.April, 1).Add(-12*time.Hour), day(time.May, 1).Add(-12*time.Hour)
+
+	tb, err := svc.TrialBalance(ctx, aprilStart, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.True(t, tb.Balanced)
+	assert.Equal(t, int64(12500), tb.TotalDebit)
+	for _, row := range tb.Rows {
+		if row.AccountCode == model.FinancialAccountChannelFunds {
+			assert.Equal(t, int64(0), row.OpeningBalance)
+			assert.Equal(t, int64(9500), row.ClosingBalance)
+		}
+	}
+
+	// 五月期初承接四月期末
+	tbMay, err := svc.TrialBalance(ctx, mayStart, mayStart.AddDate(0, 1, 0), model.CurrencyCNY)
+	require.NoError(t, err)
+	for _, row := range tbMay.Rows {
+		if row.AccountCode == model.FinancialAccountChannelFunds {
+			assert.Equal(t, int64(9500), row.OpeningBalance)
+			assert.Equal(t, int64(8500), row.ClosingBalance)
+		}
+	}
+
+	is, err := svc.IncomeStatement(ctx, aprilStart, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), is.TotalRevenue)
+	assert.Equal(t, int64(500), is.TotalExpense)
+	assert.Equal(t, int64(1500), is.NetProfit)
+
+	bs, err := svc.BalanceSheet(ctx, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.True(t, bs.Balanced)
+	assert.Equal(t, int64(9500), bs.TotalAssets)
+	assert.Equal(t, int64(8000), bs.TotalLiabilities)
+	assert.Equal(t, int64(1500), lineAmount(bs.Equity, unclosedProfitName))
+
+	cf, err := svc.CashFlow(ctx, aprilStart, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), cf.TotalInflow)
+	assert.Equal(t, int64(500), cf.TotalOutflow)
+	assert.Equal(t, int64(9500), cf.ClosingCash)
+	require.Len(t, cf.Inflows, 1)
+	assert.Equal(t, model.LedgerBusinessPaymentReceived, cf.Inflows[0].BusinessType)
+}
+
+func TestClosePeriod(t *testing.T) {
+	svc := seedApril(t)
+	ctx := context.Background()
+	aprilStart, mayStart := day(time.April, 1).Add(-12*time.Hour), day(time.May, 1).Add(-12*time.Hour)
+
+	period, err := svc.ClosePeriod(ctx, "2024-04", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), period.NetProfit)
+	require.NotNil(t, period.ClosingVoucherID)
+
+	closing, err := svc.GetVoucher(ctx, *period.ClosingVoucherID)
+	require.NoError(t, err)
+	assert.Equal(t, model.FinancialVoucherTypeClosing, closing.Type)
+	assert.Equal(t, model.FinancialVoucherStatusPosted, closing.Status)
+	assert.Equal(t, "2024-04", periodKey(closing.VoucherDate))
+
+	// 利润表不受结账凭证影响；资产负债表中利润已转入本年利润
+	is, err := svc.IncomeStatement(ctx, aprilStart, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), is.NetProfit)
+	bs, err := svc.BalanceSheet(ctx, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.True(t, bs.Balanced)
+	assert.Equal(t, int64(1500), lineAmount(bs.Equity, model.FinancialAccountCurrentProfit))
+	assert.Zero(t, lineAmount(bs.Equity, unclosedProfitName))
+
+	_, err = svc.ClosePeriod(ctx, "2024-04", 1)
+	assert.ErrorIs(t, err, ErrPeriodClosed)
+
+	// 已结账期间不能再录入手工凭证
+	_, err = svc.CreateVoucher(ctx, CreateVoucherRequest{Date: day(time.April, 20), Abstract: "late", CreatedBy: 1, Lines: []VoucherLine{
+		{AccountCode: costAccount, DebitCents: 100},
+		{AccountCode: model.FinancialAccountChannelFunds, CreditCents: 100},
+	}})
+	assert.ErrorIs(t, err, ErrPeriodClosed)
+
+	// 迟到的自动凭证记入当前日期
+	paidAt := day(time.April, 20)
+	require.NoError(t, svc.PostPaymentReceived(ctx, nil, &model.Payment{Base: model.Base{ID: 2}, OrderID: 2, OutTradeNo: "PAY2", AmountCents: 300, PaidAt: &paidAt}))
+	vouchers, _, err := svc.ListVouchers(ctx, ledgerrepo.VoucherListOptions{BusinessType: model.LedgerBusinessPaymentReceived})
+	require.NoError(t, err)
+	for _, v := range vouchers {
+		if v.BusinessNo == "PAY2" {
+			assert.NotEqual(t, "2024-04", periodKey(v.VoucherDate))
+		}
+	}
+
+	// 有未过账凭证、期间未结束、格式错误都不能结账
+	_, err = svc.CreateVoucher(ctx, CreateVoucherRequest{Date: day(time.May, 10), Abstract: "draft", CreatedBy: 1, Lines: []VoucherLine{
+		{AccountCode: costAccount, DebitCents: 100},
+		{AccountCode: model.FinancialAccountChannelFunds, CreditCents: 100},
+	}})
+	require.NoError(t, err)
+	_, err = svc.ClosePeriod(ctx, "2024-05", 1)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	_, err = svc.ClosePeriod(ctx, periodKey(time.Now()), 1)
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.ClosePeriod(ctx, "2024/05", 1)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	periods, err := svc.ListPeriods(ctx)
+	require.NoError(t, err)
+	require.Len(t, periods, 1)
+	assert.Equal(t, "2024-04", periods[0].Period)
+}
+
+type fixedRates map[model.Currency]float64
+
+func (f fixedRates) Convert(_ context.Context, amount int64, from, to model.Currency) (int64, error) {
+	return int64(float64(amount) * f[from] / f[to]), nil
+}
+
+func TestReports_ReportingCurrency(t *testing.T) {
+	svc := seedApril(t)
+	ctx := context.Background()
+	aprilStart, mayStart := day(time.April, 1).Add(-12*time.Hour), day(time.May, 1).Add(-12*time.Hour)
+
+	// 四月另有一笔美元收款 10 美元，确认抽成 2 美元
+	paidAt := day(time.April, 11)
+	require.NoError(t, svc.PostPaymentReceived(ctx, nil, &model.Payment{Base: model.Base{ID: 3}, OrderID: 3, OutTradeNo: "PAY-USD", AmountCents: 1000, Currency: model.CurrencyUSD, PaidAt: &paidAt}))
+	v, err := svc.CreateVoucher(ctx, CreateVoucherRequest{Date: day(time.April, 12), Abstract: "usd commission", Currency: model.CurrencyUSD, CreatedBy: 1, Lines: []VoucherLine{
+		{AccountCode: model.FinancialAccountAdvanceReceipt, DebitCents: 200},
+		{AccountCode: model.FinancialAccountCommission, CreditCents: 200},
+	}})
+	require.NoError(t, err)
+	_, err = svc.ApproveVoucher(ctx, v.ID, 2)
+	require.NoError(t, err)
+	_, err = svc.PostVoucher(ctx, v.ID, 2)
+	require.NoError(t, err)
+
+	// 没有汇率服务时不能折算
+	_, err = svc.IncomeStatement(ctx, aprilStart, mayStart, model.CurrencyCNY)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	svc.SetFX(fixedRates{model.CurrencyCNY: 1, model.CurrencyUSD: 7})
+	is, err := svc.IncomeStatement(ctx, aprilStart, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, model.CurrencyCNY, is.Currency)
+	assert.Equal(t, int64(2000+1400), is.TotalRevenue)
+	assert.Equal(t, int64(1500+1400), is.NetProfit)
+
+	tb, err := svc.TrialBalance(ctx, aprilStart, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.True(t, tb.Balanced)
+	assert.Equal(t, tb.TotalDebit, tb.TotalCredit)
+
+	cf, err := svc.CashFlow(ctx, aprilStart, mayStart, model.CurrencyUSD)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000/7+1000), cf.TotalInflow)
+
+	report, err := svc.GenerateReport(ctx, GenerateReportRequest{Type: model.FinancialReportTypeBalanceSheet, PeriodStart: aprilStart, PeriodEnd: mayStart, Currency: model.CurrencyUSD})
+	require.NoError(t, err)
+	assert.Equal(t, model.CurrencyUSD, report.Currency)
+
+	// 结账按币种分别结转，净利润折算为本位币
+	period, err := svc.ClosePeriod(ctx, "2024-04", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500+1400), period.NetProfit)
+	closing, err := svc.GetVoucher(ctx, *period.ClosingVoucherID)
+	require.NoError(t, err)
+	assert.Equal(t, model.CurrencyCNY, closing.Entries[0].Currency)
+	vouchers, _, err := svc.ListVouchers(ctx, ledgerrepo.VoucherListOptions{BusinessType: model.LedgerBusinessPeriodClosing})
+	require.NoError(t, err)
+	assert.Len(t, vouchers, 2)
+
+	bs, err := svc.BalanceSheet(ctx, mayStart, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.True(t, bs.Balanced)
+	assert.Zero(t, lineAmount(bs.Equity, unclosedProfitName))
+}
+
+func TestGenerateAndExportReport(t *testing.T) {
+	svc := seedApril(t)
+	ctx := context.Background()
+	aprilStart, mayStart := day(time.April, 1).Add(-12*time.Hour), day(time.May, 1).Add(-12*time.Hour)
+
+	_, err := svc.GenerateReport(ctx, GenerateReportRequest{Type: model.FinancialReportTypeCustom, PeriodStart: aprilStart, PeriodEnd: mayStart})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.GenerateReport(ctx, GenerateReportRequest{Type: model.FinancialReportTypeTrialBalance, PeriodStart: mayStart, PeriodEnd: aprilStart})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	for _, typ := range []model.FinancialReportType{
+		model.FinancialReportTypeTrialBalance, model.FinancialReportTypeIncomeStatement,
+		model.FinancialReportTypeBalanceSheet, model.FinancialReportTypeCashFlow,
+	} {
+		report, err := svc.GenerateReport(ctx, GenerateReportRequest{Type: typ, PeriodStart: aprilStart, PeriodEnd: mayStart, GeneratedBy: 1})
+		require.NoError(t, err, typ)
+		assert.Equal(t, model.FinancialReportStatusGenerated, report.Status)
+		assert.NotEmpty(t, report.ReportData)
+
+		file, err := svc.ExportReport(ctx, report.ID, "csv")
+		require.NoError(t, err, typ)
+		assert.True(t, strings.HasSuffix(file.Name, ".csv"))
+		assert.Contains(t, string(file.Data), reportNames[typ])
+
+		file, err = svc.ExportReport(ctx, report.ID, "xlsx")
+		require.NoError(t, err, typ)
+		zr, err := zip.NewReader(bytes.NewReader(file.Data), int64(len(file.Data)))
+		require.NoError(t, err)
+		var sheet []byte
+		for _, f := range zr.File {
+			if f.Name == "xl/worksheets/sheet1.xml" {
+				rc, err := f.Open()
+				require.NoError(t, err)
+				sheet, _ = io.ReadAll(rc)
+				rc.Close()
+			}
+		}
+		assert.Contains(t, string(sheet), reportNames[typ])
+	}
+
+	reports, total, err := svc.ListReports(ctx, ledgerrepo.ReportListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Empty(t, reports[0].ReportData)
+
+	file, err := svc.ExportReport(ctx, reports[len(reports)-1].ID, "csv")
+	require.NoError(t, err)
+	assert.Contains(t, string(file.Data), "95.00")
+
+	_, err = svc.ExportReport(ctx, reports[0].ID, "pdf")
+	assert.ErrorIs(t, err, ErrValidation)
+}
@@ -0,0 +1,76 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/service/orderstate"
+)
+
+// SubmitCompletionRequest 陪玩师提交完成请求
+type SubmitCompletionRequest struct {
+	ProofURLs []string `json:"proofUrls" binding:"omitempty,max=9,dive,url"` // 完成凭证截图
+	Note      string   `json:"note" binding:"max=500"`
+}
+
+// ObjectCompletionRequest 用户对完成提出异议请求
+type ObjectCompletionRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// ObjectCompletion 用户对陪玩师提交的完成提出异议（用户端）
+//
+// 订单退回服务中，陪玩师需继续服务后重新提交；对服务结果有争议时用户可另行发起争议。
+func (s *OrderService) ObjectCompletion(ctx context.Context, userID uint64, orderID uint64, req ObjectCompletionRequest) error {
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.UserID != userID {
+		return ErrUnauthorized
+	}
+	if order.Status != model.OrderStatusPendingConfirmation {
+		return ErrInvalidTransition
+	}
+	if err := s.transit(ctx, order, model.OrderStatusInProgress, orderstate.Change{Role: model.OrderActorUser, ActorUserID: &userID, Reason: req.Reason}); err != nil {
+		return err
+	}
+	if playerID := order.GetPlayerID(); playerID != 0 {
+		if player, err := s.players.Get(ctx, playerID); err == nil && player.UserID != 0 {
+			s.notifyUser(ctx, order, player.UserID, "用户对订单完成提出异议",
+				fmt.Sprintf("用户对订单 %s 的完成提出异议：%s，请沟通后继续服务并重新提交", orderLabel(order), req.Reason))
+		}
+	}
+	return nil
+}
+
+// confirmDeadline 待确认订单的自动确认截止时间；未开启自动确认时为空
+func (s *OrderService) confirmDeadline(order *model.Order) *time.Time {
+	if order.Status != model.OrderStatusPendingConfirmation || order.CompletionSubmittedAt == nil || s.timeouts.ConfirmWindow <= 0 {
+		return nil
+	}
+	deadline := order.CompletionSubmittedAt.Add(s.timeouts.ConfirmWindow)
+	return &deadline
+}
+
+// notifyUser 通知单个用户，失败只记录日志
+func (s *OrderService) notifyUser(ctx context.Context, order *model.Order, userID uint64, title, message string) {
+	if s.notifications == nil || userID == 0 {
+		return
+	}
+	id := order.ID
+	err := s.notifications.Create(ctx, &model.NotificationEvent{
+		UserID:        userID,
+		Title:         title,
+		Message:       message,
+		Priority:      model.NotificationPriorityHigh,
+		ReferenceType: string(model.OpEntityOrder),
+		ReferenceID:   &id,
+	})
+	if err != nil {
+		slog.Warn("notify order completion failed", slog.Uint64("order_id", order.ID), slog.Uint64("user_id", userID), slog.String("error", err.Error()))
+	}
+}
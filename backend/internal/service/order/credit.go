@@ -0,0 +1,44 @@
+package order
+
+import (
+	"context"
+	"log/slog"
+
+	"gamelink/internal/model"
+)
+
+// CreditTracker 陪玩师信用：抢单资格按信用等级，订单完成累计连续完成数（由信用服务实现）。
+type CreditTracker interface {
+	TierPolicy(ctx context.Context, playerID uint64) (model.CreditTierPolicy, error)
+	RecordCompletion(ctx context.Context, order *model.Order) error
+}
+
+// SetCredit 注入信用服务，抢单大厅与接单按信用等级限制
+func (s *OrderService) SetCredit(c CreditTracker) { s.credit = c }
+
+// grabPolicy 返回陪玩师的抢单策略；未注入信用服务或查询失败时返回 false，不限制接单
+func (s *OrderService) grabPolicy(ctx context.Context, playerUserID uint64) (model.CreditTierPolicy, bool) {
+	if s.credit == nil || playerUserID == 0 {
+		return model.CreditTierPolicy{}, false
+	}
+	player, err := s.players.GetByUserID(ctx, playerUserID)
+	if err != nil {
+		return model.CreditTierPolicy{}, false
+	}
+	policy, err := s.credit.TierPolicy(ctx, player.ID)
+	if err != nil {
+		slog.Warn("load credit tier failed", slog.Uint64("player_id", player.ID), slog.String("error", err.Error()))
+		return model.CreditTierPolicy{}, false
+	}
+	return policy, true
+}
+
+// recordCompletionCredit 订单完成后累计陪玩师连续完成数，失败不影响订单完成
+func (s *OrderService) recordCompletionCredit(ctx context.Context, order *model.Order) {
+	if s.credit == nil {
+		return
+	}
+	if err := s.credit.RecordCompletion(ctx, order); err != nil {
+		slog.Warn("record completion credit failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+	}
+}
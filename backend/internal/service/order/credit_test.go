@@ -0,0 +1,53 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+	userrepo "gamelink/internal/repository/user"
+)
+
+type fakeCreditTracker struct {
+	policy    model.CreditTierPolicy
+	completed []uint64
+}
+
+func (f *fakeCreditTracker) TierPolicy(context.Context, uint64) (model.CreditTierPolicy, error) {
+	return f.policy, nil
+}
+
+func (f *fakeCreditTracker) RecordCompletion(_ context.Context, order *model.Order) error {
+	f.completed = append(f.completed, order.ID)
+	return nil
+}
+
+func TestAcceptOrder_CreditTierLimits(t *testing.T) {
+	svc, db, order := newClaimFixture(t, 1)
+	ctx := context.Background()
+	require.NoError(t, db.AutoMigrate(&model.User{}))
+	svc.users = userrepo.NewUserRepository(db)
+	restricted, _ := model.CreditTierPolicyOf(model.CreditTierRestricted)
+	credit := &fakeCreditTracker{policy: restricted}
+	svc.SetCredit(credit)
+
+	_, _, err := svc.GetAvailableOrders(ctx, AvailableOrdersRequest{PlayerUserID: 101})
+	assert.ErrorIs(t, err, ErrCreditRestricted)
+	assert.ErrorIs(t, svc.AcceptOrder(ctx, 101, order.ID), ErrCreditRestricted)
+
+	// 较差等级只能接 200 元以内的订单
+	credit.policy, _ = model.CreditTierPolicyOf(model.CreditTierPoor)
+	big := &model.Order{UserID: 1, Status: model.OrderStatusConfirmed, TotalPriceCents: 30000}
+	require.NoError(t, db.Create(big).Error)
+	orders, total, err := svc.GetAvailableOrders(ctx, AvailableOrdersRequest{PlayerUserID: 101})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, orders, 1)
+	assert.Equal(t, order.ID, orders[0].ID)
+	assert.ErrorIs(t, svc.AcceptOrder(ctx, 101, big.ID), ErrCreditRestricted)
+
+	require.NoError(t, svc.AcceptOrder(ctx, 101, order.ID))
+}
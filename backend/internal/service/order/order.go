@@ -3,11 +3,23 @@ package order
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
 	"time"
 
+	"gamelink/internal/metrics"
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
 	commissionrepo "gamelink/internal/repository/commission"
+	orderhistory "gamelink/internal/repository/order_history"
+	"gamelink/internal/service/availability"
+	commissionservice "gamelink/internal/service/commission"
+	couponservice "gamelink/internal/service/coupon"
+	"gamelink/internal/service/orderstate"
+	paymentservice "gamelink/internal/service/payment"
+	"gamelink/internal/service/pricing"
 )
 
 var (
@@ -16,11 +28,34 @@ var (
 	// ErrValidation 表示输入校验失败
 	ErrValidation = errors.New("validation failed")
 	// ErrInvalidTransition 订单状态流转不合法
-	ErrInvalidTransition = errors.New("invalid order status transition")
+	ErrInvalidTransition = orderstate.ErrInvalidTransition
 	// ErrUnauthorized 无权操作
 	ErrUnauthorized = errors.New("unauthorized")
+	// ErrOrderTaken 订单已被其他陪玩师接走
+	ErrOrderTaken = errors.New("order already taken")
+	// ErrOrderBusy 其他陪玩师正在接该订单，稍后可重试
+	ErrOrderBusy = errors.New("order is being claimed, please retry")
+	// ErrOrderReserved 订单处于派单邀请期，仅被邀请的陪玩师可接
+	ErrOrderReserved = errors.New("order is reserved for dispatched players")
+	// ErrCreditRestricted 陪玩师信用等级不允许接该订单
+	ErrCreditRestricted = errors.New("credit tier does not allow taking this order")
+	// ErrBookingConflict 预约时间与陪玩师其他订单重叠
+	ErrBookingConflict = availability.ErrBookingConflict
+	// ErrPlayerUnavailable 陪玩师在预约时段不接单
+	ErrPlayerUnavailable = availability.ErrUnavailable
+	// ErrCouponUnavailable 优惠券已使用、已锁定或已过期
+	ErrCouponUnavailable = couponservice.ErrUnavailable
+	// ErrCouponNotApplicable 订单不满足优惠券使用条件
+	ErrCouponNotApplicable = couponservice.ErrNotApplicable
+	// ErrCouponUsageLimit 超过优惠券每人使用次数
+	ErrCouponUsageLimit = couponservice.ErrUsageLimit
+	// ErrRefundUnavailable 未接入退款服务，无法取消已支付订单
+	ErrRefundUnavailable = errors.New("refund service not configured")
 )
 
+// claimLockTTL 抢单锁的最长持有时间，防止实例崩溃后锁无法释放
+const claimLockTTL = 5 * time.Second
+
 // OrderService 订单服务
 //
 // 功能：
@@ -37,6 +72,74 @@ type OrderService struct {
 	commissions commissionrepo.CommissionRepository
 	// optional: for order chat auto-destroy
 	chatGroups repository.ChatGroupRepository
+	// records commission, ledger vouchers and wallet income on completion
+	recorder CommissionRecorder
+	// optional: converts CNY hourly rates for orders in other currencies
+	fx CurrencyConverter
+	// optional: records status transitions and drives the order timeline
+	history orderhistory.HistoryRepository
+	// optional: distributed lock fronting order claims
+	claimLock ClaimLocker
+	// optional: rejects bookings outside the player's calendar or overlapping other orders
+	bookings BookingChecker
+	// optional: hides orders reserved for pushed dispatch offers from the pool
+	dispatchHold DispatchHold
+	// optional: quotes orders from service items, player rates and commission rules
+	pricer OrderPricer
+	// optional: locks coupons at checkout and releases them on cancel
+	coupons CouponRedeemer
+	// optional: lists paid extensions for the order timeline
+	extensions ExtensionLister
+	// optional: gates order grabbing by the player's credit tier
+	credit CreditTracker
+	// lifecycle timeouts handled by ProcessTimeouts
+	timeouts        TimeoutPolicy
+	refunder        Refunder
+	paymentCanceler PaymentCanceler
+	notifications   repository.NotificationRepository
+}
+
+// ClaimLocker 抢单分布式锁（由 Redis 缓存实现）。
+type ClaimLocker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// DispatchHold 派单邀请期内的订单保留给被邀请的陪玩师（由派单服务实现）。
+type DispatchHold interface {
+	IsReserved(ctx context.Context, orderID, playerID uint64) (bool, error)
+}
+
+// OrderPricer 按服务项目、段位、时长与陪玩师时薪报价（由定价服务实现）。
+type OrderPricer interface {
+	Quote(ctx context.Context, req pricing.QuoteRequest) (*pricing.Quote, error)
+}
+
+// CouponRedeemer 下单锁定优惠券、取消时退回（由优惠券服务实现）。
+// Checkout 在同一事务内锁券、调用 create 写入订单并关联订单。
+type CouponRedeemer interface {
+	Checkout(ctx context.Context, userID, couponID uint64, order *model.Order,
+		create func(ctx context.Context, orders repository.OrderRepository) error) error
+	ReleaseForOrder(ctx context.Context, order *model.Order) error
+}
+
+// ExtensionLister 列出订单的加时记录（由加时申请仓储实现）。
+type ExtensionLister interface {
+	ListByOrder(ctx context.Context, orderID uint64) ([]model.OrderExtension, error)
+}
+
+// BookingChecker 校验陪玩师预约时段（由陪玩师日程服务实现）。
+type BookingChecker interface {
+	CheckBookable(ctx context.Context, playerID uint64, start, end time.Time, exceptOrderID uint64) error
+}
+
+// CurrencyConverter 按汇率换算金额（由汇率服务实现）。
+type CurrencyConverter interface {
+	Convert(ctx context.Context, amountCents int64, from, to model.Currency) (int64, error)
+}
+
+// CommissionRecorder 订单完成后按报价拆分记录抽成，并同步入账总账与陪玩师钱包（由抽成服务实现）。
+type CommissionRecorder interface {
+	RecordCommission(ctx context.Context, orderID uint64) error
 }
 
 // NewOrderService 创建订单服务
@@ -57,6 +160,8 @@ func NewOrderService(
 		payments:    payments,
 		reviews:     reviews,
 		commissions: commissions,
+		recorder:    commissionservice.NewCommissionService(commissions, orders, players),
+		timeouts:    DefaultTimeoutPolicy,
 	}
 }
 
@@ -65,6 +170,39 @@ func (s *OrderService) SetChatGroupRepository(chatGroups repository.ChatGroupRep
 	s.chatGroups = chatGroups
 }
 
+// SetCommissionRecorder 注入抽成服务，订单完成时记录抽成并入账总账与钱包；
+// 默认仅按订单仓储与抽成仓储记录抽成，不入账。
+func (s *OrderService) SetCommissionRecorder(r CommissionRecorder) { s.recorder = r }
+
+// SetFX 注入汇率服务，支持以人民币以外的币种下单
+func (s *OrderService) SetFX(fx CurrencyConverter) { s.fx = fx }
+
+// SetStatusHistory 注入订单状态历史仓储，记录每次状态流转并用于构建时间线
+func (s *OrderService) SetStatusHistory(h orderhistory.HistoryRepository) { s.history = h }
+
+// SetClaimLocker 注入抢单锁；多实例部署时在数据库条件更新之前挡住并发抢单
+func (s *OrderService) SetClaimLocker(l ClaimLocker) { s.claimLock = l }
+
+// SetBookingChecker 注入陪玩师日程服务，下单时校验可接单时段与预约冲突
+func (s *OrderService) SetBookingChecker(b BookingChecker) { s.bookings = b }
+
+// SetDispatchHold 注入派单服务，邀请期内的订单不进入抢单大厅
+func (s *OrderService) SetDispatchHold(h DispatchHold) { s.dispatchHold = h }
+
+// SetPricer 注入定价服务，下单价格与抽成由统一定价引擎计算
+func (s *OrderService) SetPricer(p OrderPricer) { s.pricer = p }
+
+// SetCoupons 注入优惠券服务，下单可使用优惠券，取消后退回
+func (s *OrderService) SetCoupons(c CouponRedeemer) { s.coupons = c }
+
+// SetExtensions 注入加时记录，已生效的加时展示在订单时间线中
+func (s *OrderService) SetExtensions(l ExtensionLister) { s.extensions = l }
+
+// transit 通过状态机流转订单并记录状态历史
+func (s *OrderService) transit(ctx context.Context, order *model.Order, to model.OrderStatus, c orderstate.Change) error {
+	return orderstate.Transit(ctx, s.orders, s.history, order, to, c)
+}
+
 // deactivateOrderChat best-effort deactivates the chat group bound to the order.
 func (s *OrderService) deactivateOrderChat(ctx context.Context, orderID uint64) {
 	if s.chatGroups == nil {
@@ -81,20 +219,25 @@ func (s *OrderService) deactivateOrderChat(ctx context.Context, orderID uint64)
 
 // CreateOrderRequest 创建订单请求
 type CreateOrderRequest struct {
-	PlayerID       uint64     `json:"playerId" binding:"required"`
-	GameID         uint64     `json:"gameId" binding:"required"`
-	ServiceID      *uint64    `json:"serviceId"`           // 可选：关联服务
-	Title          string     `json:"title" binding:"required,max=128"`
-	Description    string     `json:"description"`
-	ScheduledStart *time.Time `json:"scheduledStart" binding:"required"`
-	DurationHours  float32    `json:"durationHours" binding:"required,min=0.5,max=24"`
+	PlayerID       uint64         `json:"playerId" binding:"required"`
+	GameID         uint64         `json:"gameId" binding:"required"`
+	ServiceID      *uint64        `json:"serviceId"`                  // 可选：关联服务
+	RankLevel      string         `json:"rankLevel" binding:"max=32"` // 可选：段位，默认取陪玩师段位
+	CouponID       *uint64        `json:"couponId"`                   // 可选：使用的优惠券
+	Title          string         `json:"title" binding:"required,max=128"`
+	Description    string         `json:"description"`
+	ScheduledStart *time.Time     `json:"scheduledStart" binding:"required"`
+	DurationHours  float32        `json:"durationHours" binding:"required,min=0.5,max=24"`
+	Currency       model.Currency `json:"currency" binding:"omitempty,oneof=CNY USD EUR"` // 下单币种，默认 CNY
 }
 
 // CreateOrderResponse 创建订单响应
 type CreateOrderResponse struct {
-	OrderID     uint64 `json:"orderId"`
-	PriceCents  int64  `json:"priceCents"`
-	NeedPayment bool   `json:"needPayment"`
+	OrderID       uint64         `json:"orderId"`
+	PriceCents    int64          `json:"priceCents"`
+	DiscountCents int64          `json:"discountCents,omitempty"`
+	Currency      model.Currency `json:"currency"`
+	NeedPayment   bool           `json:"needPayment"`
 }
 
 // OrderCardDTO 订单卡片信息（列表展示）
@@ -111,6 +254,7 @@ type OrderCardDTO struct {
 	CanPay         bool              `json:"canPay"`
 	CanCancel      bool              `json:"canCancel"`
 	CanComplete    bool              `json:"canComplete"`
+	CanObject      bool              `json:"canObject"` // 可对陪玩师提交的完成提出异议
 	CanReview      bool              `json:"canReview"`
 }
 
@@ -124,6 +268,12 @@ type OrderDetailDTO struct {
 	CancelReason string     `json:"cancelReason"`
 	RefundAmount int64      `json:"refundAmount"`
 	RefundReason string     `json:"refundReason"`
+
+	// 陪玩师提交的完成凭证与自动确认截止时间
+	CompletionSubmittedAt *time.Time `json:"completionSubmittedAt,omitempty"`
+	CompletionProofURLs   []string   `json:"completionProofUrls,omitempty"`
+	CompletionNote        string     `json:"completionNote,omitempty"`
+	ConfirmDeadline       *time.Time `json:"confirmDeadline,omitempty"`
 }
 
 // OrderTimelineDTO 订单时间线
@@ -131,6 +281,7 @@ type OrderTimelineDTO struct {
 	Time    time.Time `json:"time"`
 	Status  string    `json:"status"`
 	Message string    `json:"message"`
+	Actor   string    `json:"actor,omitempty"` // 操作方：user/player/admin/system
 }
 
 // PaymentDTO 支付信息
@@ -205,52 +356,116 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uint64, req Creat
 		return nil, err
 	}
 
-	// 从陪玩师时薪计算价格（简化版本）
-	// TODO: 后续集成 ServiceItem，从 service_items 表获取价格
-	hourlyRate := player.HourlyRateCents
-	totalPrice := int64(float32(hourlyRate) * req.DurationHours)
-
-	// 默认抽成20%
-	commissionRate := 20
-	commissionCents := totalPrice * int64(commissionRate) / 100
-	playerIncomeCents := totalPrice - commissionCents
+	quote, err := s.quote(ctx, player, req)
+	if err != nil {
+		return nil, err
+	}
 
 	// 计算结束时间
-	scheduledEnd := req.ScheduledStart.Add(time.Duration(req.DurationHours * float32(time.Hour)))
+	scheduledEnd := req.ScheduledStart.Add(time.Duration(pricing.DurationMinutes(req.DurationHours)) * time.Minute)
+
+	// 预约时段须在陪玩师可接单时间内，且不与其已确认/服务中的订单重叠
+	if s.bookings != nil {
+		if err := s.bookings.CheckBookable(ctx, req.PlayerID, *req.ScheduledStart, scheduledEnd, 0); err != nil {
+			return nil, err
+		}
+	}
 
 	// 创建订单（使用新的 Order 结构）
 	playerID := req.PlayerID
 	gameID := req.GameID
 	order := &model.Order{
-		OrderNo:           model.GenerateEscortOrderNo(),
-		UserID:            userID,
-		ItemID:            1, // TODO: 需要从 service_items 选择对应的服务项
-		PlayerID:          &playerID,
-		GameID:            &gameID,
-		Quantity:          1,
-		UnitPriceCents:    totalPrice,
-		TotalPriceCents:   totalPrice,
-		CommissionCents:   commissionCents,
-		PlayerIncomeCents: playerIncomeCents,
-		Currency:          model.CurrencyCNY,
-		Status:            model.OrderStatusPending,
-		Title:             req.Title,
-		Description:       req.Description,
-		ScheduledStart:    req.ScheduledStart,
-		ScheduledEnd:      &scheduledEnd,
-	}
-
-	if err := s.orders.Create(ctx, order); err != nil {
+		OrderNo:        model.GenerateEscortOrderNo(),
+		UserID:         userID,
+		PlayerID:       &playerID,
+		GameID:         &gameID,
+		Status:         model.OrderStatusPending,
+		Title:          req.Title,
+		Description:    req.Description,
+		ScheduledStart: req.ScheduledStart,
+		ScheduledEnd:   &scheduledEnd,
+	}
+	quote.ApplyTo(order)
+
+	// 使用优惠券：锁券、写订单与关联订单同一事务提交，锁定后按优惠承担方重新拆分抽成
+	if req.CouponID != nil {
+		if s.coupons == nil {
+			return nil, fmt.Errorf("%w: coupons are not available", ErrValidation)
+		}
+		err := s.coupons.Checkout(ctx, userID, *req.CouponID, order, func(ctx context.Context, orders repository.OrderRepository) error {
+			order.CommissionCents, order.PlayerIncomeCents, order.SubsidyCents = model.SplitDiscounted(
+				order.TotalPriceCents, order.DiscountCents, quote.CommissionRate, order.DiscountBearer)
+			return orders.Create(ctx, order)
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if err := s.orders.Create(ctx, order); err != nil {
 		return nil, err
 	}
 
 	return &CreateOrderResponse{
-		OrderID:     order.ID,
-		PriceCents:  totalPrice,
-		NeedPayment: true,
+		OrderID:       order.ID,
+		PriceCents:    order.TotalPriceCents,
+		DiscountCents: order.DiscountCents,
+		Currency:      order.Currency,
+		NeedPayment:   true,
 	}, nil
 }
 
+// quote 通过定价服务报价；未注入定价服务时按陪玩师时薪与默认抽成计价
+func (s *OrderService) quote(ctx context.Context, player *model.Player, req CreateOrderRequest) (*pricing.Quote, error) {
+	if s.pricer != nil {
+		qr := pricing.QuoteRequest{
+			GameID:        req.GameID,
+			PlayerID:      req.PlayerID,
+			RankLevel:     req.RankLevel,
+			DurationHours: req.DurationHours,
+			Currency:      req.Currency,
+		}
+		if req.ServiceID != nil {
+			qr.ItemID = *req.ServiceID
+		}
+		quote, err := s.pricer.Quote(ctx, qr)
+		if errors.Is(err, pricing.ErrValidation) || errors.Is(err, pricing.ErrNoServiceItem) {
+			return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+		return quote, err
+	}
+
+	// 陪玩师时薪以人民币计价，其他币种按当前汇率换算
+	currency := req.Currency.OrDefault()
+	if !model.IsValidCurrency(currency) {
+		return nil, fmt.Errorf("%w: unsupported currency %s", ErrValidation, currency)
+	}
+	minutes := pricing.DurationMinutes(req.DurationHours)
+	total := pricing.HourlyAmount(player.HourlyRateCents, minutes)
+	if currency != model.CurrencyCNY {
+		if s.fx == nil {
+			return nil, fmt.Errorf("%w: currency %s is not available", ErrValidation, currency)
+		}
+		converted, err := s.fx.Convert(ctx, total, model.CurrencyCNY, currency)
+		if err != nil {
+			return nil, err
+		}
+		total = converted
+	}
+	quote := &pricing.Quote{
+		ItemID:          1,
+		Quantity:        1,
+		DurationMinutes: minutes,
+		Currency:        currency,
+		UnitPriceCents:  total,
+		TotalPriceCents: total,
+		CommissionRate:  pricing.DefaultCommissionRate,
+	}
+	if req.ServiceID != nil {
+		quote.ItemID = *req.ServiceID
+	}
+	quote.CommissionCents, quote.PlayerIncomeCents = pricing.SplitCommission(total, quote.CommissionRate)
+	return quote, nil
+}
+
 // GetMyOrders 获取我的订单列表（用户端）
 func (s *OrderService) GetMyOrders(ctx context.Context, userID uint64, req MyOrderListRequest) (*MyOrderListResponse, error) {
 	// 默认分页参数
@@ -332,10 +547,9 @@ func (s *OrderService) GetOrderDetail(ctx context.Context, userID uint64, orderI
 	payments, _, err := s.payments.List(ctx, repository.PaymentListOptions{
 		OrderID:  orderIDPtr,
 		Page:     1,
-		PageSize: 1,
+		PageSize: 20,
 	})
-	if err == nil && len(payments) > 0 {
-		p := payments[0]
+	if p := orderPayment(payments); err == nil && p != nil {
 		paymentDTO = &PaymentDTO{
 			ID:          p.ID,
 			Method:      p.Method,
@@ -364,7 +578,7 @@ func (s *OrderService) GetOrderDetail(ctx context.Context, userID uint64, orderI
 	}
 
 	// 构建时间线
-	timeline := s.buildOrderTimeline(order)
+	timeline := s.buildOrderTimeline(ctx, order)
 
 	// 构建订单详情
 	card, err := s.toOrderCardDTO(ctx, order, userID)
@@ -381,6 +595,11 @@ func (s *OrderService) GetOrderDetail(ctx context.Context, userID uint64, orderI
 		CancelReason: order.CancelReason,
 		RefundAmount: order.RefundAmountCents,
 		RefundReason: order.RefundReason,
+
+		CompletionSubmittedAt: order.CompletionSubmittedAt,
+		CompletionProofURLs:   order.CompletionProofURLs,
+		CompletionNote:        order.CompletionNote,
+		ConfirmDeadline:       s.confirmDeadline(order),
 	}
 
 	return &OrderDetailResponse{
@@ -405,43 +624,45 @@ func (s *OrderService) CancelOrder(ctx context.Context, userID uint64, orderID u
 		return ErrUnauthorized
 	}
 
-	// 状态检查：只有 pending 或 confirmed 状态可以取消
-	if order.Status != model.OrderStatusPending && order.Status != model.OrderStatusConfirmed {
-		return ErrInvalidTransition
-	}
-
-	// 保存原始状态
-	originalStatus := order.Status
-
-	// 更新订单状态
-	order.Status = model.OrderStatusCanceled
 	order.CancelReason = req.Reason
-
-	// 如果已支付，需要退款
-	if originalStatus == model.OrderStatusConfirmed {
-		// 查找支付记录并执行退款
-		orderIDPtr := &orderID
-		payments, _, err := s.payments.List(ctx, repository.PaymentListOptions{
-			OrderID:  orderIDPtr,
-			Page:     1,
-			PageSize: 1,
+	change := orderstate.Change{Role: model.OrderActorUser, ActorUserID: &userID, Reason: req.Reason}
+	var payment *model.Payment
+	if order.Status == model.OrderStatusConfirmed {
+		if payment, err = s.primaryPaidPayment(ctx, orderID); err != nil {
+			return err
+		}
+	}
+	if payment == nil {
+		if err := s.transit(ctx, order, model.OrderStatusCanceled, change); err != nil {
+			return err
+		}
+	} else {
+		// 已支付订单：取消与退款单在同一事务内提交，退款结算后由支付服务流转为已退款
+		if s.refunder == nil {
+			return ErrRefundUnavailable
+		}
+		refund, err := s.refunder.CreateRefund(ctx, paymentservice.CreateRefundRequest{
+			OrderID:     orderID,
+			PaymentID:   &payment.ID,
+			Reason:      "用户取消订单",
+			Source:      model.RefundSourceSystem,
+			ActorUserID: &userID,
+			Cancel:      &change,
 		})
-		if err == nil && len(payments) > 0 {
-			payment := payments[0]
-			// 如果支付已完成，执行退款
-			if payment.Status == model.PaymentStatusPaid {
-				// 注意：这里直接更新订单状态，实际退款由支付服务的RefundPayment处理
-				now := time.Now()
-				order.RefundAmountCents = order.TotalPriceCents
-				order.RefundReason = "用户取消订单"
-				order.RefundedAt = &now
-				order.Status = model.OrderStatusRefunded
+		if err != nil {
+			if refund == nil {
+				return err
 			}
+			// 退款单已创建，渠道提交失败由退款调度器重试
+			slog.Error("submit refund for canceled order failed", slog.Uint64("order_id", orderID), slog.String("error", err.Error()))
 		}
 	}
 
-	if err := s.orders.Update(ctx, order); err != nil {
-		return err
+	// 退回优惠券；失败时由优惠券结转任务补偿
+	if s.coupons != nil {
+		if err := s.coupons.ReleaseForOrder(ctx, order); err != nil {
+			slog.Warn("release coupon for canceled order", slog.Uint64("order_id", orderID), slog.String("error", err.Error()))
+		}
 	}
 
 	// auto-destroy order chat group
@@ -449,6 +670,38 @@ func (s *OrderService) CancelOrder(ctx context.Context, userID uint64, orderID u
 	return nil
 }
 
+// primaryPaidPayment 订单本身的已支付记录：不含加时补款，取最早到账的一笔（之后到账的重复支付自动退回）
+func (s *OrderService) primaryPaidPayment(ctx context.Context, orderID uint64) (*model.Payment, error) {
+	status := model.PaymentStatusPaid
+	payments, _, err := s.payments.List(ctx, repository.PaymentListOptions{
+		OrderID:  &orderID,
+		Status:   &status,
+		Page:     1,
+		PageSize: 100,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var primary *model.Payment
+	for i := range payments {
+		p := &payments[i]
+		if p.Status != model.PaymentStatusPaid || p.ExtensionID != nil {
+			continue
+		}
+		if primary == nil || paidBefore(p, primary) {
+			primary = p
+		}
+	}
+	return primary, nil
+}
+
+func paidBefore(a, b *model.Payment) bool {
+	if a.PaidAt != nil && b.PaidAt != nil && !a.PaidAt.Equal(*b.PaidAt) {
+		return a.PaidAt.Before(*b.PaidAt)
+	}
+	return a.ID < b.ID
+}
+
 // CompleteOrder 确认完成订单（用户端）
 func (s *OrderService) CompleteOrder(ctx context.Context, userID uint64, orderID uint64) error {
 	// 获取订单
@@ -462,81 +715,29 @@ func (s *OrderService) CompleteOrder(ctx context.Context, userID uint64, orderID
 		return ErrUnauthorized
 	}
 
-	// 状态检查：只有 in_progress 状态可以完成
-	if order.Status != model.OrderStatusInProgress {
-		return ErrInvalidTransition
-	}
-
-	// 更新订单状态
-	now := time.Now()
-	order.Status = model.OrderStatusCompleted
-	order.CompletedAt = &now
-
-	if err := s.orders.Update(ctx, order); err != nil {
+	if err := s.transit(ctx, order, model.OrderStatusCompleted, orderstate.Change{Role: model.OrderActorUser, ActorUserID: &userID}); err != nil {
 		return err
 	}
 
 	// 订单完成后，自动记录抽成
-	if err := s.recordCommissionAsync(ctx, orderID); err != nil {
-		// 记录日志但不影响订单完成
-		// TODO: 使用日志系统
-		// log.Printf("Failed to record commission for order %d: %v", orderID, err)
+	if err := s.recordCommission(ctx, orderID); err != nil {
+		// 不影响订单完成，由抽成补记任务重试
+		slog.Warn("record commission failed", slog.Uint64("order_id", orderID), slog.String("error", err.Error()))
 	}
+	s.recordCompletionCredit(ctx, order)
 
 	// auto-destroy order chat group
 	s.deactivateOrderChat(ctx, orderID)
 	return nil
 }
 
-// recordCommissionAsync 异步记录抽成
-func (s *OrderService) recordCommissionAsync(ctx context.Context, orderID uint64) error {
-	// 检查是否已记录
-	existing, _ := s.commissions.GetRecordByOrderID(ctx, orderID)
-	if existing != nil {
-		return nil // 已记录，跳过
-	}
-
-	// 获取订单
-	order, err := s.orders.Get(ctx, orderID)
-	if err != nil {
-		return err
-	}
-
-	// 获取抽成规则
-	rule, err := s.commissions.GetRuleForOrder(ctx, order.GameID, order.PlayerID, nil)
-	if err != nil || rule == nil {
-		// 使用默认规则
-		rule, err = s.commissions.GetDefaultRule(ctx)
-		if err != nil {
-			rule = &model.CommissionRule{Rate: 20} // 默认20%
-		}
-	}
-
-	// 计算抽成
-	totalAmount := order.TotalPriceCents
-	commissionRate := rule.Rate
-	commissionAmount := totalAmount * int64(commissionRate) / 100
-	playerIncome := totalAmount - commissionAmount
-
-	// 创建抽成记录
-	now := time.Now()
-	playerID := order.GetPlayerID()
-	if playerID == 0 {
-		return errors.New("order has no player assigned")
+// recordCommission 订单完成后记录抽成，已记录时跳过
+func (s *OrderService) recordCommission(ctx context.Context, orderID uint64) error {
+	err := s.recorder.RecordCommission(ctx, orderID)
+	if errors.Is(err, commissionservice.ErrAlreadyRecorded) {
+		return nil
 	}
-	
-	record := &model.CommissionRecord{
-		OrderID:            orderID,
-		PlayerID:           playerID,
-		TotalAmountCents:   totalAmount,
-		CommissionRate:     commissionRate,
-		CommissionCents:    commissionAmount,
-		PlayerIncomeCents:  playerIncome,
-		SettlementStatus:   "pending",
-		SettlementMonth:    now.Format("2006-01"),
-	}
-
-	return s.commissions.CreateRecord(ctx, record)
+	return err
 }
 
 // toOrderCardDTO 转换为订单卡�?DTO
@@ -567,8 +768,9 @@ func (s *OrderService) toOrderCardDTO(ctx context.Context, order *model.Order, u
 
 	// 判断操作权限
 	canPay := order.Status == model.OrderStatusPending && order.UserID == userID
-	canCancel := (order.Status == model.OrderStatusPending || order.Status == model.OrderStatusConfirmed) && order.UserID == userID
-	canComplete := order.Status == model.OrderStatusInProgress && order.UserID == userID
+	canCancel := orderstate.CanTransit(order.Status, model.OrderStatusCanceled, model.OrderActorUser) && order.UserID == userID
+	canComplete := orderstate.CanTransit(order.Status, model.OrderStatusCompleted, model.OrderActorUser) && order.UserID == userID
+	canObject := order.Status == model.OrderStatusPendingConfirmation && order.UserID == userID
 	canReview := order.Status == model.OrderStatusCompleted && order.UserID == userID
 
 	// 检查是否已评价
@@ -597,18 +799,26 @@ func (s *OrderService) toOrderCardDTO(ctx context.Context, order *model.Order, u
 		CanPay:         canPay,
 		CanCancel:      canCancel,
 		CanComplete:    canComplete,
+		CanObject:      canObject,
 		CanReview:      canReview,
 	}, nil
 }
 
 // buildOrderTimeline 构建订单时间线
-func (s *OrderService) buildOrderTimeline(order *model.Order) []OrderTimelineDTO {
-	ctx := context.Background()
+//
+// 优先使用状态历史；没有历史记录的存量订单按订单字段推断。
+func (s *OrderService) buildOrderTimeline(ctx context.Context, order *model.Order) []OrderTimelineDTO {
+	if s.history != nil {
+		rows, err := s.history.ListByOrder(ctx, order.ID)
+		if err == nil && len(rows) > 0 {
+			return s.withExtensions(ctx, order, historyTimeline(order, rows))
+		}
+	}
 	timeline := []OrderTimelineDTO{
 		{
 			Time:    order.CreatedAt,
 			Status:  string(model.OrderStatusPending),
-				Message: "订单已创建",
+			Message: "订单已创建",
 		},
 	}
 
@@ -619,10 +829,9 @@ func (s *OrderService) buildOrderTimeline(order *model.Order) []OrderTimelineDTO
 		payments, _, err := s.payments.List(ctx, repository.PaymentListOptions{
 			OrderID:  orderIDPtr,
 			Page:     1,
-			PageSize: 1,
+			PageSize: 20,
 		})
-		if err == nil && len(payments) > 0 {
-			payment := payments[0]
+		if payment := orderPayment(payments); err == nil && payment != nil {
 			if payment.PaidAt != nil {
 				paidTime = *payment.PaidAt
 			}
@@ -643,6 +852,14 @@ func (s *OrderService) buildOrderTimeline(order *model.Order) []OrderTimelineDTO
 		})
 	}
 
+	if order.CompletionSubmittedAt != nil {
+		timeline = append(timeline, OrderTimelineDTO{
+			Time:    *order.CompletionSubmittedAt,
+			Status:  string(model.OrderStatusPendingConfirmation),
+			Message: "陪玩师已提交完成，待用户确认",
+		})
+	}
+
 	if order.CompletedAt != nil {
 		timeline = append(timeline, OrderTimelineDTO{
 			Time:    *order.CompletedAt,
@@ -667,6 +884,90 @@ func (s *OrderService) buildOrderTimeline(order *model.Order) []OrderTimelineDTO
 		})
 	}
 
+	return s.withExtensions(ctx, order, timeline)
+}
+
+// withExtensions 在时间线中插入已生效的加时
+func (s *OrderService) withExtensions(ctx context.Context, order *model.Order, timeline []OrderTimelineDTO) []OrderTimelineDTO {
+	if s.extensions == nil {
+		return timeline
+	}
+	exts, err := s.extensions.ListByOrder(ctx, order.ID)
+	if err != nil {
+		slog.Warn("list order extensions failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+		return timeline
+	}
+	added := false
+	for _, ext := range exts {
+		if ext.Status != model.OrderExtensionPaid || ext.PaidAt == nil {
+			continue
+		}
+		timeline = append(timeline, OrderTimelineDTO{
+			Time:    *ext.PaidAt,
+			Status:  string(model.OrderStatusInProgress),
+			Message: fmt.Sprintf("订单已加时 %d 分钟", ext.DurationMinutes),
+			Actor:   string(model.OrderActorUser),
+		})
+		added = true
+	}
+	if added {
+		sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].Time.Before(timeline[j].Time) })
+	}
+	return timeline
+}
+
+// orderPayment 订单本身的支付记录（最新在前的列表中第一条非加时补款）
+func orderPayment(payments []model.Payment) *model.Payment {
+	for i := range payments {
+		if payments[i].ExtensionID == nil {
+			return &payments[i]
+		}
+	}
+	return nil
+}
+
+// historyTimeline 按状态历史构建时间线
+func historyTimeline(order *model.Order, rows []model.OrderStatusHistory) []OrderTimelineDTO {
+	timeline := make([]OrderTimelineDTO, 0, len(rows)+1)
+	timeline = append(timeline, OrderTimelineDTO{
+		Time:    order.CreatedAt,
+		Status:  string(model.OrderStatusPending),
+		Message: "订单已创建",
+	})
+	for _, h := range rows {
+		var message string
+		switch h.ToStatus {
+		case model.OrderStatusConfirmed:
+			message = "订单已确认"
+			if h.ActorRole == model.OrderActorSystem {
+				message = "订单已支付"
+			}
+		case model.OrderStatusInProgress:
+			message = "订单进行中"
+			if h.FromStatus == model.OrderStatusPendingConfirmation {
+				message = "用户对完成提出异议"
+			}
+		case model.OrderStatusPendingConfirmation:
+			message = "陪玩师已提交完成，待用户确认"
+		case model.OrderStatusCompleted:
+			message = "订单已完成"
+		case model.OrderStatusCanceled:
+			message = "订单已取消"
+		case model.OrderStatusRefunded:
+			message = "订单已退款"
+		default:
+			message = "订单状态变更为 " + string(h.ToStatus)
+		}
+		if h.Reason != "" {
+			message += "： " + h.Reason
+		}
+		timeline = append(timeline, OrderTimelineDTO{
+			Time:    h.CreatedAt,
+			Status:  string(h.ToStatus),
+			Message: message,
+			Actor:   string(h.ActorRole),
+		})
+	}
 	return timeline
 }
 
@@ -675,6 +976,8 @@ type AvailableOrdersRequest struct {
 	GameID   *uint64 `form:"gameId"`
 	Page     int     `form:"page"`
 	PageSize int     `form:"pageSize"`
+	// PlayerUserID 当前陪玩师的用户ID，用于按信用等级过滤可接订单
+	PlayerUserID uint64 `form:"-"`
 }
 
 // AvailableOrderDTO 可接订单信息
@@ -702,10 +1005,17 @@ func (s *OrderService) GetAvailableOrders(ctx context.Context, req AvailableOrde
 
 	// 构建查询条件：查询已支付但未接单的订单
 	opts := repository.OrderListOptions{
-		Statuses: []model.OrderStatus{model.OrderStatusConfirmed},
-		GameID:   req.GameID,
-		Page:     req.Page,
-		PageSize: req.PageSize,
+		Statuses:  []model.OrderStatus{model.OrderStatusConfirmed},
+		GameID:    req.GameID,
+		QueueType: model.OrderQueueTypeSolo,
+		Page:      req.Page,
+		PageSize:  req.PageSize,
+	}
+
+	// 信用等级不允许抢单的陪玩师看不到订单大厅
+	policy, limited := s.grabPolicy(ctx, req.PlayerUserID)
+	if limited && !policy.CanGrab {
+		return nil, 0, ErrCreditRestricted
 	}
 
 	orders, total, err := s.orders.List(ctx, opts)
@@ -716,6 +1026,10 @@ func (s *OrderService) GetAvailableOrders(ctx context.Context, req AvailableOrde
 	// 转换�?DTO
 	availableOrders := make([]AvailableOrderDTO, 0, len(orders))
 	for _, o := range orders {
+		if s.isReserved(ctx, o.ID, 0) || (limited && !policy.AllowsOrder(&o)) {
+			total--
+			continue
+		}
 		// 获取游戏信息
 		var gameName string
 		gameID := o.GetGameID()
@@ -785,21 +1099,74 @@ func (s *OrderService) AcceptOrder(ctx context.Context, playerUserID uint64, ord
 	}
 
 	// 状态检查：只有 confirmed 状态可以接单
-	if order.Status != model.OrderStatusConfirmed {
+	switch order.Status {
+	case model.OrderStatusConfirmed:
+	case model.OrderStatusInProgress:
+		metrics.ObserveOrderClaim("taken")
+		return ErrOrderTaken
+	default:
 		return ErrInvalidTransition
 	}
 
-	// 接单
+	// 车队订单只能由车队队长在车队大厅抢单
+	if order.IsTeamOrder() || s.isReserved(ctx, orderID, playerID) {
+		metrics.ObserveOrderClaim("reserved")
+		return ErrOrderReserved
+	}
+
+	if policy, limited := s.grabPolicy(ctx, playerUserID); limited && !policy.AllowsOrder(order) {
+		metrics.ObserveOrderClaim("credit_restricted")
+		return ErrCreditRestricted
+	}
+
+	if s.claimLock != nil {
+		unlock, ok, err := s.claimLock.TryLock(ctx, fmt.Sprintf("order:claim:%d", orderID), claimLockTTL)
+		switch {
+		case err != nil:
+			// 锁不可用时仍由数据库条件更新保证只有一人接单成功
+			slog.Warn("acquire order claim lock failed", slog.Uint64("order_id", orderID), slog.String("error", err.Error()))
+		case !ok:
+			// 持锁者可能接单失败，订单未必已被接走
+			metrics.ObserveOrderClaim("lock_busy")
+			return ErrOrderBusy
+		default:
+			defer unlock()
+		}
+	}
+
+	// 接单：按状态条件更新，并发接单时只有一人成功
 	order.SetPlayerID(playerID)
-	order.Status = model.OrderStatusInProgress
-	now := time.Now()
-	order.StartedAt = &now
+	err = s.transit(ctx, order, model.OrderStatusInProgress, orderstate.Change{Role: model.OrderActorPlayer, ActorUserID: &playerUserID})
+	switch {
+	case err == nil:
+		metrics.ObserveOrderClaim("claimed")
+		return nil
+	case errors.Is(err, orderstate.ErrStatusConflict):
+		metrics.ObserveOrderClaim("taken")
+		return ErrOrderTaken
+	default:
+		metrics.ObserveOrderClaim("error")
+		return err
+	}
+}
 
-	return s.orders.Update(ctx, order)
+// isReserved 订单是否保留给其他被邀请的陪玩师；查询失败时不阻止接单
+func (s *OrderService) isReserved(ctx context.Context, orderID, playerID uint64) bool {
+	if s.dispatchHold == nil {
+		return false
+	}
+	reserved, err := s.dispatchHold.IsReserved(ctx, orderID, playerID)
+	if err != nil {
+		slog.Warn("check dispatch reservation failed", slog.Uint64("order_id", orderID), slog.String("error", err.Error()))
+		return false
+	}
+	return reserved
 }
 
-// CompleteOrderByPlayer 完成订单（陪玩师端）
-func (s *OrderService) CompleteOrderByPlayer(ctx context.Context, playerUserID uint64, orderID uint64) error {
+// CompleteOrderByPlayer 陪玩师提交完成（陪玩师端）
+//
+// 订单进入待确认状态，由用户确认或确认窗口到期后自动完成，抽成在最终完成时记录。
+func (s *OrderService) CompleteOrderByPlayer(ctx context.Context, playerUserID uint64, orderID uint64, req SubmitCompletionRequest) error {
 	// 查找陪玩师
 	players, _, err := s.players.ListPaged(ctx, 1, 100)
 	if err != nil {
@@ -829,26 +1196,18 @@ func (s *OrderService) CompleteOrderByPlayer(ctx context.Context, playerUserID u
 		return ErrUnauthorized
 	}
 
-	// 状态检查：只有 in_progress 状态可以完成
-	if order.Status != model.OrderStatusInProgress {
-		return ErrInvalidTransition
-	}
-
-	// 完成订单
-	now := time.Now()
-	order.Status = model.OrderStatusCompleted
-	order.CompletedAt = &now
-
-	if err := s.orders.Update(ctx, order); err != nil {
+	// 提交完成，等待用户确认
+	order.CompletionProofURLs = model.EvidenceURLArray(req.ProofURLs)
+	order.CompletionNote = strings.TrimSpace(req.Note)
+	if err := s.transit(ctx, order, model.OrderStatusPendingConfirmation, orderstate.Change{Role: model.OrderActorPlayer, ActorUserID: &playerUserID, Reason: order.CompletionNote}); err != nil {
 		return err
 	}
 
-	// 订单完成后，自动记录抽成
-	if err := s.recordCommissionAsync(ctx, orderID); err != nil {
-		// 记录日志但不影响订单完成
-		// TODO: 使用日志系统
-		// log.Printf("Failed to record commission for order %d: %v", orderID, err)
+	message := fmt.Sprintf("陪玩师已提交订单 %s 的完成确认，请确认服务结果或提出异议", orderLabel(order))
+	if deadline := s.confirmDeadline(order); deadline != nil {
+		message = fmt.Sprintf("陪玩师已提交订单 %s 的完成确认，请在 %s 前确认或提出异议，逾期将自动确认完成",
+			orderLabel(order), deadline.Format("2006-01-02 15:04"))
 	}
-
+	s.notifyUser(ctx, order, order.UserID, "请确认订单完成", message)
 	return nil
 }
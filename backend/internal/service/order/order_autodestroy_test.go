@@ -80,7 +80,7 @@ func TestCompleteOrderByPlayer_NoAutoDeactivateOrderChat(t *testing.T) {
 	chatRepo := &mockChatGroupRepo{ group: &model.ChatGroup{ Base: model.Base{ID: 88}, GroupType: model.ChatGroupTypeOrder, IsActive: true } }
 	svc.SetChatGroupRepository(chatRepo)
 
-    if err := svc.CompleteOrderByPlayer(context.Background(), 1, 3); err != nil {
+    if err := svc.CompleteOrderByPlayer(context.Background(), 1, 3, SubmitCompletionRequest{}); err != nil {
         t.Fatalf("complete by player: %v", err)
     }
     if chatRepo.lastDeactivatedID != 0 {
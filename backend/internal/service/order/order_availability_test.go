@@ -8,6 +8,7 @@ import (
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	commissionservice "gamelink/internal/service/commission"
 )
 
 func TestOrderService_GetAvailableOrders_DefaultsAndMapping(t *testing.T) {
@@ -230,7 +231,7 @@ func TestOrderService_BuildOrderTimeline_FullFlow(t *testing.T) {
 		RefundedAt:     &refunded,
 	}
 
-	timeline := svc.buildOrderTimeline(order)
+	timeline := svc.buildOrderTimeline(context.Background(), order)
 	if len(timeline) != 6 {
 		t.Fatalf("expected 6 timeline entries, got %d", len(timeline))
 	}
@@ -285,7 +286,7 @@ func TestOrderService_BuildOrderTimeline_Fallbacks(t *testing.T) {
 		Status: model.OrderStatusConfirmed,
 	}
 
-	timeline := svc.buildOrderTimeline(order)
+	timeline := svc.buildOrderTimeline(context.Background(), order)
 	if len(timeline) != 2 {
 		t.Fatalf("expected 2 timeline entries, got %d", len(timeline))
 	}
@@ -300,13 +301,13 @@ func TestOrderService_BuildOrderTimeline_Fallbacks(t *testing.T) {
 		},
 		Status: model.OrderStatusPending,
 	}
-	pendingTimeline := svc.buildOrderTimeline(pending)
+	pendingTimeline := svc.buildOrderTimeline(context.Background(), pending)
 	if len(pendingTimeline) != 1 {
 		t.Fatalf("expected only creation entry for pending order, got %d", len(pendingTimeline))
 	}
 }
 
-func TestOrderService_RecordCommissionAsync(t *testing.T) {
+func TestOrderService_RecordCommission(t *testing.T) {
 	t.Helper()
 
 	orderRepo := newMockOrderRepository()
@@ -334,10 +335,11 @@ func TestOrderService_RecordCommissionAsync(t *testing.T) {
 		payments:    &mockPaymentRepository{},
 		reviews:     &mockReviewRepository{},
 		commissions: commissionRepo,
+		recorder:    commissionservice.NewCommissionService(commissionRepo, orderRepo, nil),
 	}
 
-	if err := svc.recordCommissionAsync(context.Background(), order.ID); err != nil {
-		t.Fatalf("recordCommissionAsync returned error: %v", err)
+	if err := svc.recordCommission(context.Background(), order.ID); err != nil {
+		t.Fatalf("recordCommission returned error: %v", err)
 	}
 
 	if len(commissionRepo.createdRecords) != 1 {
@@ -359,7 +361,7 @@ func TestOrderService_RecordCommissionAsync(t *testing.T) {
 	}
 }
 
-func TestOrderService_RecordCommissionAsync_EdgeCases(t *testing.T) {
+func TestOrderService_RecordCommission_EdgeCases(t *testing.T) {
 	t.Helper()
 
 	t.Run("skips when record exists", func(t *testing.T) {
@@ -384,9 +386,10 @@ func TestOrderService_RecordCommissionAsync_EdgeCases(t *testing.T) {
 			payments:    &mockPaymentRepository{},
 			reviews:     &mockReviewRepository{},
 			commissions: commissionRepo,
+			recorder:    commissionservice.NewCommissionService(commissionRepo, orderRepo, nil),
 		}
 
-		if err := svc.recordCommissionAsync(context.Background(), order.ID); err != nil {
+		if err := svc.recordCommission(context.Background(), order.ID); err != nil {
 			t.Fatalf("expected nil error when record already exists, got %v", err)
 		}
 		if len(commissionRepo.createdRecords) != 0 {
@@ -402,6 +405,7 @@ func TestOrderService_RecordCommissionAsync_EdgeCases(t *testing.T) {
 		}
 		orderRepo.orders[orderWithoutPlayer.ID] = orderWithoutPlayer
 
+		commissionRepo := &recordingCommissionRepository{mockCommissionRepository: &mockCommissionRepository{}}
 		svc := &OrderService{
 			orders:      orderRepo,
 			players:     &mockPlayerRepository{},
@@ -409,13 +413,50 @@ func TestOrderService_RecordCommissionAsync_EdgeCases(t *testing.T) {
 			games:       &mockGameRepository{},
 			payments:    &mockPaymentRepository{},
 			reviews:     &mockReviewRepository{},
-			commissions: &recordingCommissionRepository{mockCommissionRepository: &mockCommissionRepository{}},
+			commissions: commissionRepo,
+			recorder:    commissionservice.NewCommissionService(commissionRepo, orderRepo, nil),
 		}
 
-		if err := svc.recordCommissionAsync(context.Background(), orderWithoutPlayer.ID); err == nil {
+		if err := svc.recordCommission(context.Background(), orderWithoutPlayer.ID); err == nil {
 			t.Fatal("expected error when order has no player assigned")
 		}
 	})
+
+	t.Run("uses the quoted split", func(t *testing.T) {
+		orderRepo := newMockOrderRepository()
+		order := &model.Order{
+			Base:              model.Base{ID: 4},
+			TotalPriceCents:   9000,
+			CommissionRate:    10,
+			CommissionCents:   1000,
+			PlayerIncomeCents: 8000,
+			DiscountCents:     1000,
+			DiscountBearer:    model.CouponBearerPlatform,
+		}
+		order.SetPlayerID(1)
+		orderRepo.orders[order.ID] = order
+
+		// 当前规则与报价时不同，仍按报价拆分记录
+		commissionRepo := &recordingCommissionRepository{
+			mockCommissionRepository: &mockCommissionRepository{},
+			defaultRule:              &model.CommissionRule{Rate: 30},
+		}
+		svc := &OrderService{
+			orders:   orderRepo,
+			recorder: commissionservice.NewCommissionService(commissionRepo, orderRepo, nil),
+		}
+
+		if err := svc.recordCommission(context.Background(), order.ID); err != nil {
+			t.Fatalf("recordCommission returned error: %v", err)
+		}
+		if len(commissionRepo.createdRecords) != 1 {
+			t.Fatalf("expected commission record to be created, got %d", len(commissionRepo.createdRecords))
+		}
+		record := commissionRepo.createdRecords[0]
+		if record.CommissionRate != 10 || record.CommissionCents != 1000 || record.PlayerIncomeCents != 8000 {
+			t.Fatalf("expected quoted split 10%%/1000/8000, got %d%%/%d/%d", record.CommissionRate, record.CommissionCents, record.PlayerIncomeCents)
+		}
+	})
 }
 
 type spyAvailableOrderRepository struct {
@@ -503,6 +544,9 @@ type recordingCommissionRepository struct {
 }
 
 func (r *recordingCommissionRepository) GetRecordByOrderID(ctx context.Context, orderID uint64) (*model.CommissionRecord, error) {
+	if r.existingRecord == nil {
+		return nil, repository.ErrNotFound
+	}
 	return r.existingRecord, nil
 }
 
@@ -0,0 +1,180 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	commissionrepo "gamelink/internal/repository/commission"
+	"gamelink/internal/repository/common"
+	orderrepo "gamelink/internal/repository/order"
+	orderhistory "gamelink/internal/repository/order_history"
+	paymentrepo "gamelink/internal/repository/payment"
+	playerrepo "gamelink/internal/repository/player"
+	paymentservice "gamelink/internal/service/payment"
+)
+
+// refundEnv 订单服务接入真实支付服务与进程内沙箱的退款测试环境
+type refundEnv struct {
+	db       *gorm.DB
+	svc      *OrderService
+	payments *paymentservice.PaymentService
+	sandbox  *paymentservice.SandboxServer
+}
+
+func newRefundEnv(t *testing.T) *refundEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.OrderStatusHistory{}, &model.Payment{},
+		&model.PaymentCallback{}, &model.Refund{}, &model.OperationLog{}, &model.NotificationEvent{},
+		&model.CommissionRule{}, &model.CommissionRecord{}))
+
+	orders := orderrepo.NewOrderRepository(db)
+	payments := paymentrepo.NewPaymentRepository(db)
+	paymentSvc := paymentservice.NewPaymentService(payments, orders)
+	paymentSvc.SetTxManager(common.NewUnitOfWork(db))
+	paymentSvc.SetRefundRepository(paymentrepo.NewRefundRepository(db))
+	sandbox := paymentservice.NewSandboxServer("order-secret")
+	paymentSvc.SetGateway(paymentservice.NewInProcessSandboxGateway(model.PaymentMethodWeChat, sandbox))
+
+	svc := NewOrderService(orders, playerrepo.NewPlayerRepository(db), nil, nil, payments, nil, commissionrepo.NewCommissionRepository(db))
+	svc.SetStatusHistory(orderhistory.NewHistoryRepository(db))
+	svc.SetRefunder(paymentSvc)
+	return &refundEnv{db: db, svc: svc, payments: paymentSvc, sandbox: sandbox}
+}
+
+// paidOrder 创建经沙箱支付确认的订单
+func (e *refundEnv) paidOrder(t *testing.T) (*model.Order, *model.Payment) {
+	t.Helper()
+	ctx := context.Background()
+	order := &model.Order{UserID: 1, Status: model.OrderStatusPending, TotalPriceCents: 6000, Currency: model.CurrencyCNY}
+	require.NoError(t, e.db.Create(order).Error)
+	resp, err := e.payments.CreatePayment(ctx, 1, paymentservice.CreatePaymentRequest{OrderID: order.ID, Method: model.PaymentMethodWeChat})
+	require.NoError(t, err)
+	var payment model.Payment
+	require.NoError(t, e.db.First(&payment, resp.PaymentID).Error)
+	require.NoError(t, e.sandbox.Pay(ctx, payment.OutTradeNo))
+	status, err := e.payments.GetPaymentStatus(ctx, payment.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.PaymentStatusPaid, status.Status)
+	require.NoError(t, e.db.First(order, order.ID).Error)
+	require.Equal(t, model.OrderStatusConfirmed, order.Status)
+	return order, &payment
+}
+
+func (e *refundEnv) reload(t *testing.T, id uint64) *model.Order {
+	t.Helper()
+	var o model.Order
+	require.NoError(t, e.db.First(&o, id).Error)
+	return &o
+}
+
+func (e *refundEnv) refunds(t *testing.T, orderID uint64) []model.Refund {
+	t.Helper()
+	var rows []model.Refund
+	require.NoError(t, e.db.Where("order_id = ?", orderID).Order("id").Find(&rows).Error)
+	return rows
+}
+
+func TestCancelOrder_PaidOrderIsRefundedThroughRefundEntity(t *testing.T) {
+	e := newRefundEnv(t)
+	ctx := context.Background()
+	order, payment := e.paidOrder(t)
+	// 晚于订单支付到账的其他支付不是订单本身的支付
+	later := time.Now().Add(time.Minute)
+	extensionID := uint64(7)
+	require.NoError(t, e.db.Create(&model.Payment{OrderID: order.ID, UserID: 1, ExtensionID: &extensionID, AmountCents: 500,
+		Status: model.PaymentStatusPaid, PaidAt: &later, Method: model.PaymentMethodWeChat}).Error)
+
+	require.NoError(t, e.svc.CancelOrder(ctx, 1, order.ID, CancelOrderRequest{Reason: "plans changed"}))
+
+	refunds := e.refunds(t, order.ID)
+	require.Len(t, refunds, 1)
+	require.NotNil(t, refunds[0].PaymentID)
+	assert.Equal(t, payment.ID, *refunds[0].PaymentID)
+	assert.Equal(t, int64(6000), refunds[0].AmountCents)
+	assert.Equal(t, model.RefundStatusSucceeded, refunds[0].Status)
+
+	saved := e.reload(t, order.ID)
+	assert.Equal(t, model.OrderStatusRefunded, saved.Status)
+	assert.Equal(t, int64(6000), saved.RefundAmountCents)
+	assert.Equal(t, "plans changed", saved.CancelReason)
+
+	var history []model.OrderStatusHistory
+	require.NoError(t, e.db.Where("order_id = ?", order.ID).Order("id").Find(&history).Error)
+	require.Len(t, history, 3)
+	assert.Equal(t, model.OrderStatusCanceled, history[1].ToStatus)
+	assert.Equal(t, model.OrderActorUser, history[1].ActorRole)
+	assert.Equal(t, model.OrderStatusRefunded, history[2].ToStatus)
+}
+
+// refusingGateway 渠道退款接口不可用
+type refusingGateway struct{ paymentservice.Gateway }
+
+func (refusingGateway) Refund(context.Context, paymentservice.RefundRequest) (*paymentservice.RefundResult, error) {
+	return nil, errors.New("provider unavailable")
+}
+
+func TestCancelOrder_PaidOrderRefundRetriedAfterProviderFailure(t *testing.T) {
+	e := newRefundEnv(t)
+	ctx := context.Background()
+	order, _ := e.paidOrder(t)
+	gw, _ := e.payments.Gateway(model.PaymentMethodWeChat)
+	e.payments.SetGateway(refusingGateway{Gateway: gw})
+
+	require.NoError(t, e.svc.CancelOrder(ctx, 1, order.ID, CancelOrderRequest{Reason: "x"}))
+	assert.Equal(t, model.OrderStatusCanceled, e.reload(t, order.ID).Status)
+	refunds := e.refunds(t, order.ID)
+	require.Len(t, refunds, 1)
+	assert.Equal(t, model.RefundStatusPending, refunds[0].Status)
+
+	e.payments.SetGateway(gw)
+	n, err := e.payments.ProcessPendingRefunds(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, model.OrderStatusRefunded, e.reload(t, order.ID).Status)
+}
+
+func TestCancelOrder_PaidOrderStaysConfirmedWhenRefundCannotBeCreated(t *testing.T) {
+	e := newRefundEnv(t)
+	order, _ := e.paidOrder(t)
+	require.NoError(t, e.db.Migrator().DropTable(&model.Refund{}))
+
+	assert.Error(t, e.svc.CancelOrder(context.Background(), 1, order.ID, CancelOrderRequest{Reason: "x"}))
+	assert.Equal(t, model.OrderStatusConfirmed, e.reload(t, order.ID).Status)
+}
+
+func TestProcessTimeouts_UnacceptedOrderRetriedWhenRefundCannotBeCreated(t *testing.T) {
+	e := newRefundEnv(t)
+	ctx := context.Background()
+	order, _ := e.paidOrder(t)
+	e.svc.SetTimeoutPolicy(TimeoutPolicy{AcceptTimeout: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+
+	// 退款单无法创建时订单不取消，仍留在待接单超时扫描范围内
+	require.NoError(t, e.db.Migrator().DropTable(&model.Refund{}))
+	res, err := e.svc.ProcessTimeouts(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, res.Refunded)
+	assert.Equal(t, model.OrderStatusConfirmed, e.reload(t, order.ID).Status)
+
+	require.NoError(t, e.db.AutoMigrate(&model.Refund{}))
+	res, err = e.svc.ProcessTimeouts(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Refunded)
+	assert.Equal(t, model.OrderStatusRefunded, e.reload(t, order.ID).Status)
+	refunds := e.refunds(t, order.ID)
+	require.Len(t, refunds, 1)
+	assert.Equal(t, model.RefundSourceSystem, refunds[0].Source)
+}
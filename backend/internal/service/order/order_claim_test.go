@@ -0,0 +1,112 @@
+package order
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	orderrepo "gamelink/internal/repository/order"
+	orderhistory "gamelink/internal/repository/order_history"
+	playerrepo "gamelink/internal/repository/player"
+)
+
+func newClaimFixture(t *testing.T, players int) (*OrderService, *gorm.DB, *model.Order) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.OrderStatusHistory{}))
+
+	for i := 1; i <= players; i++ {
+		require.NoError(t, db.Create(&model.Player{UserID: uint64(100 + i), Nickname: "p"}).Error)
+	}
+	order := &model.Order{UserID: 1, Status: model.OrderStatusConfirmed, TotalPriceCents: 5000}
+	require.NoError(t, db.Create(order).Error)
+
+	svc := NewOrderService(orderrepo.NewOrderRepository(db), playerrepo.NewPlayerRepository(db), nil, nil, nil, nil, nil)
+	svc.SetStatusHistory(orderhistory.NewHistoryRepository(db))
+	return svc, db, order
+}
+
+func TestAcceptOrder_ConcurrentClaimsHaveOneWinner(t *testing.T) {
+	const players = 32
+	svc, db, order := newClaimFixture(t, players)
+
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		errs  = make([]error, players)
+	)
+	for i := 0; i < players; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = svc.AcceptOrder(context.Background(), uint64(101+i), order.ID)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	winners := 0
+	var winner uint64
+	for i, err := range errs {
+		if err == nil {
+			winners++
+			winner = uint64(101 + i)
+			continue
+		}
+		assert.ErrorIs(t, err, ErrOrderTaken)
+	}
+	require.Equal(t, 1, winners)
+
+	var saved model.Order
+	require.NoError(t, db.First(&saved, order.ID).Error)
+	assert.Equal(t, model.OrderStatusInProgress, saved.Status)
+	var player model.Player
+	require.NoError(t, db.Where("user_id = ?", winner).First(&player).Error)
+	require.NotNil(t, saved.PlayerID)
+	assert.Equal(t, player.ID, *saved.PlayerID)
+
+	// 只有胜者写入了状态历史
+	var history []model.OrderStatusHistory
+	require.NoError(t, db.Where("order_id = ?", order.ID).Find(&history).Error)
+	require.Len(t, history, 1)
+	require.NotNil(t, history[0].ActorUserID)
+	assert.Equal(t, winner, *history[0].ActorUserID)
+}
+
+type busyLocker struct{ held bool }
+
+func (l *busyLocker) TryLock(context.Context, string, time.Duration) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	l.held = true
+	return func() { l.held = false }, true, nil
+}
+
+func TestAcceptOrder_ClaimLock(t *testing.T) {
+	svc, _, order := newClaimFixture(t, 2)
+	locker := &busyLocker{held: true}
+	svc.SetClaimLocker(locker)
+
+	// 其他实例正持有锁：可重试，而非已被接走
+	assert.ErrorIs(t, svc.AcceptOrder(context.Background(), 101, order.ID), ErrOrderBusy)
+
+	locker.held = false
+	require.NoError(t, svc.AcceptOrder(context.Background(), 101, order.ID))
+	assert.False(t, locker.held, "lock should be released after the claim")
+
+	// 已被接走的订单直接返回已被接单
+	assert.ErrorIs(t, svc.AcceptOrder(context.Background(), 102, order.ID), ErrOrderTaken)
+}
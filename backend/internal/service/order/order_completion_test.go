@@ -0,0 +1,117 @@
+package order
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	commissionrepo "gamelink/internal/repository/commission"
+	notificationrepo "gamelink/internal/repository/notification"
+	orderrepo "gamelink/internal/repository/order"
+	orderhistory "gamelink/internal/repository/order_history"
+	paymentrepo "gamelink/internal/repository/payment"
+	playerrepo "gamelink/internal/repository/player"
+)
+
+func TestCompletionConfirmation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.OrderStatusHistory{}, &model.Payment{},
+		&model.NotificationEvent{}, &model.CommissionRule{}, &model.CommissionRecord{}))
+
+	ctx := context.Background()
+	player := &model.Player{UserID: 200, Nickname: "p"}
+	require.NoError(t, db.Create(player).Error)
+	newOrder := func() *model.Order {
+		o := &model.Order{UserID: 1, Status: model.OrderStatusInProgress, TotalPriceCents: 10000}
+		o.SetPlayerID(player.ID)
+		require.NoError(t, db.Create(o).Error)
+		return o
+	}
+
+	svc := NewOrderService(orderrepo.NewOrderRepository(db), playerrepo.NewPlayerRepository(db), &mockUserRepository{}, &mockGameRepository{},
+		paymentrepo.NewPaymentRepository(db), &mockReviewRepository{}, commissionrepo.NewCommissionRepository(db))
+	svc.SetStatusHistory(orderhistory.NewHistoryRepository(db))
+	svc.SetNotifications(notificationrepo.NewNotificationRepository(db))
+
+	load := func(id uint64) model.Order {
+		var o model.Order
+		require.NoError(t, db.First(&o, id).Error)
+		return o
+	}
+	hasCommission := func(id uint64) bool {
+		var n int64
+		require.NoError(t, db.Model(&model.CommissionRecord{}).Where("order_id = ?", id).Count(&n).Error)
+		return n > 0
+	}
+
+	// 陪玩师提交完成后待用户确认，此时不记录抽成
+	order := newOrder()
+	proof := SubmitCompletionRequest{ProofURLs: []string{"https://cdn.example.com/done.png"}, Note: "已上分"}
+	require.NoError(t, svc.CompleteOrderByPlayer(ctx, player.UserID, order.ID, proof))
+	got := load(order.ID)
+	assert.Equal(t, model.OrderStatusPendingConfirmation, got.Status)
+	require.NotNil(t, got.CompletionSubmittedAt)
+	assert.Equal(t, []string{"https://cdn.example.com/done.png"}, []string(got.CompletionProofURLs))
+	assert.Equal(t, "已上分", got.CompletionNote)
+	assert.Nil(t, got.CompletedAt)
+	assert.False(t, hasCommission(order.ID))
+	assert.True(t, model.CanInitiateDispute(&got))
+
+	detail, err := svc.GetOrderDetail(ctx, 1, order.ID)
+	require.NoError(t, err)
+	assert.True(t, detail.Order.CanComplete)
+	assert.True(t, detail.Order.CanObject)
+	require.NotNil(t, detail.Order.ConfirmDeadline)
+	assert.WithinDuration(t, got.CompletionSubmittedAt.Add(model.DisputeWindow), *detail.Order.ConfirmDeadline, time.Second)
+
+	// 陪玩师不能自行完成，其他用户不能提出异议
+	assert.ErrorIs(t, svc.ObjectCompletion(ctx, 2, order.ID, ObjectCompletionRequest{Reason: "x"}), ErrUnauthorized)
+
+	// 用户提出异议后退回服务中，陪玩师可重新提交
+	require.NoError(t, svc.ObjectCompletion(ctx, 1, order.ID, ObjectCompletionRequest{Reason: "还差一局"}))
+	got = load(order.ID)
+	assert.Equal(t, model.OrderStatusInProgress, got.Status)
+	assert.Nil(t, got.CompletionSubmittedAt)
+	assert.ErrorIs(t, svc.ObjectCompletion(ctx, 1, order.ID, ObjectCompletionRequest{Reason: "x"}), ErrInvalidTransition)
+	require.NoError(t, svc.CompleteOrderByPlayer(ctx, player.UserID, order.ID, SubmitCompletionRequest{}))
+
+	// 用户确认后完成并记录抽成
+	require.NoError(t, svc.CompleteOrder(ctx, 1, order.ID))
+	got = load(order.ID)
+	assert.Equal(t, model.OrderStatusCompleted, got.Status)
+	require.NotNil(t, got.CompletedAt)
+	assert.True(t, hasCommission(order.ID))
+
+	// 确认窗口到期自动完成；窗口内或已发起争议的订单保持待确认
+	expired := newOrder()
+	require.NoError(t, svc.CompleteOrderByPlayer(ctx, player.UserID, expired.ID, SubmitCompletionRequest{}))
+	disputed := newOrder()
+	require.NoError(t, svc.CompleteOrderByPlayer(ctx, player.UserID, disputed.ID, SubmitCompletionRequest{}))
+	fresh := newOrder()
+	require.NoError(t, svc.CompleteOrderByPlayer(ctx, player.UserID, fresh.ID, SubmitCompletionRequest{}))
+	old := time.Now().Add(-model.DisputeWindow - time.Minute)
+	require.NoError(t, db.Model(&model.Order{}).Where("id IN ?", []uint64{expired.ID, disputed.ID}).
+		Updates(map[string]any{"created_at": old.Add(-time.Hour), "completion_submitted_at": old}).Error)
+	require.NoError(t, db.Model(&model.Order{}).Where("id = ?", disputed.ID).Update("has_dispute", true).Error)
+
+	res, err := svc.ProcessTimeouts(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Confirmed)
+	assert.Equal(t, model.OrderStatusCompleted, load(expired.ID).Status)
+	assert.True(t, hasCommission(expired.ID))
+	assert.Equal(t, model.OrderStatusPendingConfirmation, load(disputed.ID).Status)
+	assert.False(t, hasCommission(disputed.ID))
+	assert.Equal(t, model.OrderStatusPendingConfirmation, load(fresh.ID).Status)
+
+	var hist model.OrderStatusHistory
+	require.NoError(t, db.Where("order_id = ? AND to_status = ?", expired.ID, model.OrderStatusCompleted).First(&hist).Error)
+	assert.Equal(t, model.OrderActorSystem, hist.ActorRole)
+	assert.Equal(t, model.OrderStatusPendingConfirmation, hist.FromStatus)
+}
@@ -2,12 +2,14 @@ package order
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
 	"gamelink/internal/repository/commission"
+	"gamelink/internal/service/pricing"
 )
 
 // Mock repositories (reusing some from player service tests)
@@ -207,6 +209,10 @@ func (m *mockPaymentRepository) Get(ctx context.Context, id uint64) (*model.Paym
 	return &model.Payment{}, nil
 }
 
+func (m *mockPaymentRepository) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
+
 func (m *mockPaymentRepository) Update(ctx context.Context, payment *model.Payment) error {
 	return nil
 }
@@ -349,6 +355,121 @@ func TestCreateOrder(t *testing.T) {
 	}
 }
 
+type stubPricer struct{ req pricing.QuoteRequest }
+
+func (p *stubPricer) Quote(_ context.Context, req pricing.QuoteRequest) (*pricing.Quote, error) {
+	p.req = req
+	if req.ItemID == 404 {
+		return nil, pricing.ErrNoServiceItem
+	}
+	return &pricing.Quote{ItemID: 9, Quantity: 1, Currency: model.CurrencyCNY, UnitPriceCents: 12345, TotalPriceCents: 12345,
+		CommissionRate: 10, CommissionCents: 1234, PlayerIncomeCents: 11111}, nil
+}
+
+func TestCreateOrderUsesPricer(t *testing.T) {
+	orders := newMockOrderRepository()
+	svc := NewOrderService(orders, &mockPlayerRepository{}, &mockUserRepository{}, &mockGameRepository{},
+		&mockPaymentRepository{}, &mockReviewRepository{}, &mockCommissionRepository{})
+	pricer := &stubPricer{}
+	svc.SetPricer(pricer)
+
+	start := time.Now().Add(24 * time.Hour)
+	serviceID := uint64(9)
+	resp, err := svc.CreateOrder(context.Background(), 1, CreateOrderRequest{
+		PlayerID:       1,
+		GameID:         1,
+		ServiceID:      &serviceID,
+		RankLevel:      "王者",
+		Title:          "Test Order",
+		ScheduledStart: &start,
+		DurationHours:  2,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.PriceCents != 12345 {
+		t.Errorf("expected quoted price 12345, got %d", resp.PriceCents)
+	}
+	if pricer.req.ItemID != 9 || pricer.req.RankLevel != "王者" || pricer.req.DurationHours != 2 {
+		t.Errorf("unexpected quote request %+v", pricer.req)
+	}
+	order := orders.orders[resp.OrderID]
+	if order.ItemID != 9 || order.CommissionCents != 1234 || order.PlayerIncomeCents != 11111 {
+		t.Errorf("expected order to carry the quote, got %+v", order)
+	}
+
+	serviceID = 404
+	_, err = svc.CreateOrder(context.Background(), 1, CreateOrderRequest{
+		PlayerID:       1,
+		GameID:         1,
+		ServiceID:      &serviceID,
+		Title:          "Test Order",
+		ScheduledStart: &start,
+		DurationHours:  2,
+	})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+type stubBookingChecker struct {
+	err   error
+	start time.Time
+	end   time.Time
+}
+
+func (b *stubBookingChecker) CheckBookable(_ context.Context, _ uint64, start, end time.Time, _ uint64) error {
+	b.start, b.end = start, end
+	return b.err
+}
+
+func TestCreateOrderRejectsBookingConflict(t *testing.T) {
+	orders := newMockOrderRepository()
+	svc := NewOrderService(orders, &mockPlayerRepository{}, &mockUserRepository{}, &mockGameRepository{},
+		&mockPaymentRepository{}, &mockReviewRepository{}, &mockCommissionRepository{})
+	checker := &stubBookingChecker{err: ErrBookingConflict}
+	svc.SetBookingChecker(checker)
+
+	start := time.Now().Add(24 * time.Hour)
+	_, err := svc.CreateOrder(context.Background(), 1, CreateOrderRequest{
+		PlayerID:       1,
+		GameID:         1,
+		Title:          "Test Order",
+		ScheduledStart: &start,
+		DurationHours:  1.5,
+	})
+	if !errors.Is(err, ErrBookingConflict) {
+		t.Fatalf("expected booking conflict, got %v", err)
+	}
+	if d := checker.end.Sub(start.Add(90 * time.Minute)); d < -time.Second || d > time.Second {
+		t.Errorf("expected checked slot to end at %v, got %v", start.Add(90*time.Minute), checker.end)
+	}
+	if len(orders.orders) != 0 {
+		t.Errorf("expected no order to be created, got %d", len(orders.orders))
+	}
+}
+
+type stubDispatchHold struct{ reservedFor uint64 }
+
+func (h stubDispatchHold) IsReserved(_ context.Context, _ uint64, playerID uint64) (bool, error) {
+	return playerID != h.reservedFor, nil
+}
+
+func TestAcceptOrderRejectsReservedOrder(t *testing.T) {
+	orders := newMockOrderRepository()
+	svc := NewOrderService(orders, &mockPlayerRepository{}, &mockUserRepository{}, &mockGameRepository{},
+		&mockPaymentRepository{}, &mockReviewRepository{}, &mockCommissionRepository{})
+	svc.SetDispatchHold(stubDispatchHold{reservedFor: 99})
+	orders.orders[1] = &model.Order{Base: model.Base{ID: 1}, UserID: 2, Status: model.OrderStatusConfirmed, TotalPriceCents: 10000}
+
+	if err := svc.AcceptOrder(context.Background(), 1, 1); !errors.Is(err, ErrOrderReserved) {
+		t.Fatalf("expected reserved order, got %v", err)
+	}
+	if orders.orders[1].Status != model.OrderStatusConfirmed {
+		t.Errorf("expected order to stay confirmed, got %s", orders.orders[1].Status)
+	}
+}
+
 func TestGetMyOrders(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	svc := NewOrderService(
@@ -436,6 +557,80 @@ func TestCancelOrder(t *testing.T) {
 	}
 }
 
+type memoryStatusHistory struct{ rows []model.OrderStatusHistory }
+
+func (m *memoryStatusHistory) Append(_ context.Context, h *model.OrderStatusHistory) error {
+	h.ID = uint64(len(m.rows) + 1)
+	m.rows = append(m.rows, *h)
+	return nil
+}
+
+func (m *memoryStatusHistory) ListByOrder(_ context.Context, orderID uint64) ([]model.OrderStatusHistory, error) {
+	var out []model.OrderStatusHistory
+	for _, h := range m.rows {
+		if h.OrderID == orderID {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func TestOrderStatusHistoryDrivesTimeline(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	svc := NewOrderService(
+		orderRepo,
+		&mockPlayerRepository{},
+		&mockUserRepository{},
+		&mockGameRepository{},
+		&mockPaymentRepository{},
+		&mockReviewRepository{},
+		&mockCommissionRepository{},
+	)
+	history := &memoryStatusHistory{}
+	svc.SetStatusHistory(history)
+
+	created := time.Now().Add(-time.Hour)
+	orderRepo.orders[1] = &model.Order{
+		Base:            model.Base{ID: 1, CreatedAt: created},
+		UserID:          1,
+		Status:          model.OrderStatusPending,
+		TotalPriceCents: 10000,
+	}
+
+	if err := svc.CancelOrder(context.Background(), 1, 1, CancelOrderRequest{Reason: "plan changed"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(history.rows) != 1 {
+		t.Fatalf("expected 1 history row, got %d", len(history.rows))
+	}
+	row := history.rows[0]
+	if row.FromStatus != model.OrderStatusPending || row.ToStatus != model.OrderStatusCanceled || row.ActorRole != model.OrderActorUser {
+		t.Fatalf("unexpected history row: %+v", row)
+	}
+	if row.ActorUserID == nil || *row.ActorUserID != 1 || row.Reason != "plan changed" {
+		t.Fatalf("expected actor and reason to be recorded, got %+v", row)
+	}
+
+	// 终态订单不能再次取消，也不会追加历史
+	if err := svc.CancelOrder(context.Background(), 1, 1, CancelOrderRequest{Reason: "again"}); err != ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+	if len(history.rows) != 1 {
+		t.Fatalf("expected history to stay at 1 row, got %d", len(history.rows))
+	}
+
+	timeline := svc.buildOrderTimeline(context.Background(), orderRepo.orders[1])
+	if len(timeline) != 2 {
+		t.Fatalf("expected 2 timeline entries, got %d", len(timeline))
+	}
+	if timeline[1].Status != string(model.OrderStatusCanceled) || timeline[1].Actor != string(model.OrderActorUser) {
+		t.Fatalf("unexpected timeline entry: %+v", timeline[1])
+	}
+	if !timeline[1].Time.Equal(row.CreatedAt) {
+		t.Fatalf("expected timeline to use history time, got %v", timeline[1].Time)
+	}
+}
+
 func TestCancelOrderUnauthorized(t *testing.T) {
 	orderRepo := newMockOrderRepository()
 	svc := NewOrderService(
@@ -723,16 +918,19 @@ func TestCompleteOrderByPlayer(t *testing.T) {
 	}
 	orderRepo.orders[1] = order
 
-	// Player 1 completes the order
-	err := svc.CompleteOrderByPlayer(context.Background(), 1, 1)
+	// Player 1 submits completion; the order waits for the user's confirmation
+	err := svc.CompleteOrderByPlayer(context.Background(), 1, 1, SubmitCompletionRequest{})
 
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 
 	updatedOrder := orderRepo.orders[1]
-	if updatedOrder.Status != model.OrderStatusCompleted {
-		t.Errorf("expected completed status, got %s", updatedOrder.Status)
+	if updatedOrder.Status != model.OrderStatusPendingConfirmation {
+		t.Errorf("expected pending_confirmation status, got %s", updatedOrder.Status)
+	}
+	if updatedOrder.CompletionSubmittedAt == nil {
+		t.Error("expected completion submitted time")
 	}
 }
 
@@ -762,7 +960,7 @@ func TestCompleteOrderByPlayer_Unauthorized(t *testing.T) {
 	orderRepo.orders[1] = order
 
 	// Player 1 tries to complete player 2's order (should fail)
-	err := svc.CompleteOrderByPlayer(context.Background(), 1, 1)
+	err := svc.CompleteOrderByPlayer(context.Background(), 1, 1, SubmitCompletionRequest{})
 
 	if err != ErrUnauthorized {
 		t.Errorf("expected ErrUnauthorized, got %v", err)
@@ -972,7 +1170,7 @@ func TestCompleteOrderByPlayer_NotFound(t *testing.T) {
 	)
 
 	// Try to complete non-existent order
-	err := svc.CompleteOrderByPlayer(context.Background(), 1, 9999)
+	err := svc.CompleteOrderByPlayer(context.Background(), 1, 9999, SubmitCompletionRequest{})
 
 	if err != ErrNotFound {
 		t.Errorf("expected ErrNotFound, got %v", err)
@@ -1056,7 +1254,7 @@ func TestCompleteOrderByPlayer_InvalidStatus(t *testing.T) {
 	orderRepo.orders[1] = order
 
 	// Try to complete (should fail)
-	err := svc.CompleteOrderByPlayer(context.Background(), 1, 1)
+	err := svc.CompleteOrderByPlayer(context.Background(), 1, 1, SubmitCompletionRequest{})
 
 	if err == nil {
 		t.Error("expected error for invalid status transition")
@@ -1332,6 +1530,10 @@ func (m *mockPaymentRepositoryWithData) Get(ctx context.Context, id uint64) (*mo
 	return nil, repository.ErrNotFound
 }
 
+func (m *mockPaymentRepositoryWithData) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	return nil, repository.ErrNotFound
+}
+
 func (m *mockPaymentRepositoryWithData) Update(ctx context.Context, payment *model.Payment) error {
 	return nil
 }
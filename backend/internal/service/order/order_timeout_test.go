@@ -0,0 +1,185 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	commissionrepo "gamelink/internal/repository/commission"
+	notificationrepo "gamelink/internal/repository/notification"
+	orderrepo "gamelink/internal/repository/order"
+	orderhistory "gamelink/internal/repository/order_history"
+	paymentrepo "gamelink/internal/repository/payment"
+	playerrepo "gamelink/internal/repository/player"
+	commissionservice "gamelink/internal/service/commission"
+	paymentservice "gamelink/internal/service/payment"
+)
+
+// fakeRefunder 记录退款请求，并按请求取消订单
+type fakeRefunder struct {
+	db   *gorm.DB
+	reqs []paymentservice.CreateRefundRequest
+}
+
+func (f *fakeRefunder) CreateRefund(_ context.Context, req paymentservice.CreateRefundRequest) (*model.Refund, error) {
+	f.reqs = append(f.reqs, req)
+	if req.Cancel != nil {
+		if err := f.db.Model(&model.Order{}).Where("id = ?", req.OrderID).Update("status", model.OrderStatusCanceled).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &model.Refund{OrderID: req.OrderID, Status: model.RefundStatusPending}, nil
+}
+
+func TestProcessTimeouts(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.OrderStatusHistory{}, &model.Payment{},
+		&model.NotificationEvent{}, &model.CommissionRule{}, &model.CommissionRecord{}))
+
+	ctx := context.Background()
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time { t := now.Add(-d); return &t }
+
+	player := &model.Player{UserID: 200, Nickname: "p"}
+	require.NoError(t, db.Create(player).Error)
+	newOrder := func(status model.OrderStatus, createdAgo time.Duration) *model.Order {
+		o := &model.Order{UserID: 1, Status: status, TotalPriceCents: 10000}
+		o.CreatedAt = *ago(createdAgo)
+		if status == model.OrderStatusInProgress {
+			o.SetPlayerID(player.ID)
+		}
+		require.NoError(t, db.Create(o).Error)
+		return o
+	}
+
+	unpaid := newOrder(model.OrderStatusPending, time.Hour)
+	freshUnpaid := newOrder(model.OrderStatusPending, time.Minute)
+	payment := &model.Payment{OrderID: unpaid.ID, UserID: 1, AmountCents: 10000, Status: model.PaymentStatusPending}
+	require.NoError(t, db.Create(payment).Error)
+
+	unaccepted := newOrder(model.OrderStatusConfirmed, 4*time.Hour)
+	require.NoError(t, db.Create(&model.OrderStatusHistory{OrderID: unaccepted.ID, FromStatus: model.OrderStatusPending,
+		ToStatus: model.OrderStatusConfirmed, ActorRole: model.OrderActorSystem, CreatedAt: *ago(3 * time.Hour)}).Error)
+	// 下单较早但刚支付，尚未超过接单窗口
+	recentlyPaid := newOrder(model.OrderStatusConfirmed, 4*time.Hour)
+	require.NoError(t, db.Create(&model.OrderStatusHistory{OrderID: recentlyPaid.ID, FromStatus: model.OrderStatusPending,
+		ToStatus: model.OrderStatusConfirmed, ActorRole: model.OrderActorSystem, CreatedAt: *ago(10 * time.Minute)}).Error)
+
+	overdue := newOrder(model.OrderStatusInProgress, 3*24*time.Hour)
+	overdue.ScheduledEnd = ago(48 * time.Hour)
+	require.NoError(t, db.Save(overdue).Error)
+	withinGrace := newOrder(model.OrderStatusInProgress, 3*24*time.Hour)
+	withinGrace.ScheduledEnd = ago(time.Hour)
+	require.NoError(t, db.Save(withinGrace).Error)
+
+	svc := NewOrderService(orderrepo.NewOrderRepository(db), playerrepo.NewPlayerRepository(db), nil, nil,
+		paymentrepo.NewPaymentRepository(db), nil, commissionrepo.NewCommissionRepository(db))
+	svc.SetStatusHistory(orderhistory.NewHistoryRepository(db))
+	svc.SetNotifications(notificationrepo.NewNotificationRepository(db))
+	refunder := &fakeRefunder{db: db}
+	svc.SetRefunder(refunder)
+
+	res, err := svc.ProcessTimeouts(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, TimeoutResult{Canceled: 1, Refunded: 1, Completed: 1}, res)
+
+	status := func(id uint64) model.OrderStatus {
+		var o model.Order
+		require.NoError(t, db.First(&o, id).Error)
+		return o.Status
+	}
+	assert.Equal(t, model.OrderStatusCanceled, status(unpaid.ID))
+	assert.Equal(t, model.OrderStatusPending, status(freshUnpaid.ID))
+	assert.Equal(t, model.OrderStatusCanceled, status(unaccepted.ID))
+	assert.Equal(t, model.OrderStatusConfirmed, status(recentlyPaid.ID))
+	assert.Equal(t, model.OrderStatusCompleted, status(overdue.ID))
+	assert.Equal(t, model.OrderStatusInProgress, status(withinGrace.ID))
+
+	// 未支付订单的待支付记录被关闭
+	var savedPayment model.Payment
+	require.NoError(t, db.First(&savedPayment, payment.ID).Error)
+	assert.Equal(t, model.PaymentStatusFailed, savedPayment.Status)
+
+	// 未接单订单通过退款单全额退款
+	require.Len(t, refunder.reqs, 1)
+	assert.Equal(t, unaccepted.ID, refunder.reqs[0].OrderID)
+	assert.Equal(t, model.RefundSourceSystem, refunder.reqs[0].Source)
+	require.NotNil(t, refunder.reqs[0].Cancel)
+	assert.Equal(t, model.OrderActorSystem, refunder.reqs[0].Cancel.Role)
+
+	// 自动完成的订单记录抽成
+	var record model.CommissionRecord
+	require.NoError(t, db.Where("order_id = ?", overdue.ID).First(&record).Error)
+	assert.Equal(t, player.ID, record.PlayerID)
+
+	// 状态历史记录系统操作
+	var hist model.OrderStatusHistory
+	require.NoError(t, db.Where("order_id = ? AND to_status = ?", overdue.ID, model.OrderStatusCompleted).First(&hist).Error)
+	assert.Equal(t, model.OrderActorSystem, hist.ActorRole)
+
+	// 通知下单用户与陪玩师
+	var userNotices, playerNotices int64
+	require.NoError(t, db.Model(&model.NotificationEvent{}).Where("user_id = ?", 1).Count(&userNotices).Error)
+	require.NoError(t, db.Model(&model.NotificationEvent{}).Where("user_id = ?", player.UserID).Count(&playerNotices).Error)
+	assert.EqualValues(t, 3, userNotices)
+	assert.EqualValues(t, 1, playerNotices)
+
+	// 再次执行不会重复处理
+	res, err = svc.ProcessTimeouts(ctx, 100)
+	require.NoError(t, err)
+	assert.Zero(t, res.Total())
+}
+
+// failingRecorder 抽成记录总是失败
+type failingRecorder struct{}
+
+func (failingRecorder) RecordCommission(context.Context, uint64) error {
+	return errors.New("ledger unavailable")
+}
+
+func TestProcessTimeouts_CommissionSweptAfterRecordFailure(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.OrderStatusHistory{}, &model.Payment{},
+		&model.NotificationEvent{}, &model.CommissionRule{}, &model.CommissionRecord{}))
+	ctx := context.Background()
+
+	player := &model.Player{UserID: 200, Nickname: "p"}
+	require.NoError(t, db.Create(player).Error)
+	submittedAt := time.Now().Add(-72 * time.Hour)
+	order := &model.Order{UserID: 1, Status: model.OrderStatusPendingConfirmation, TotalPriceCents: 10000,
+		CommissionRate: 20, CommissionCents: 2000, PlayerIncomeCents: 8000, CompletionSubmittedAt: &submittedAt}
+	order.CreatedAt = submittedAt
+	order.SetPlayerID(player.ID)
+	require.NoError(t, db.Create(order).Error)
+
+	orders := orderrepo.NewOrderRepository(db)
+	commissions := commissionrepo.NewCommissionRepository(db)
+	svc := NewOrderService(orders, playerrepo.NewPlayerRepository(db), nil, nil,
+		paymentrepo.NewPaymentRepository(db), nil, commissions)
+	svc.SetStatusHistory(orderhistory.NewHistoryRepository(db))
+	svc.SetCommissionRecorder(failingRecorder{})
+
+	// 抽成记录失败不影响自动完成
+	res, err := svc.ProcessTimeouts(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Confirmed)
+	_, err = commissions.GetRecordByOrderID(ctx, order.ID)
+	require.Error(t, err)
+
+	// 抽成补记任务为其补记
+	n, err := commissionservice.NewCommissionService(commissions, orders, nil).RecordMissingCommissions(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	record, err := commissions.GetRecordByOrderID(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8000), record.PlayerIncomeCents)
+}
@@ -0,0 +1,344 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/service/orderstate"
+	paymentservice "gamelink/internal/service/payment"
+)
+
+// TimeoutPolicy 订单生命周期超时窗口；为 0 的窗口不自动处理。
+type TimeoutPolicy struct {
+	PaymentTimeout time.Duration // 下单后未支付自动取消
+	AcceptTimeout  time.Duration // 支付后无人接单自动退款
+	CompleteGrace  time.Duration // 预约结束后用户未确认自动完成
+	ConfirmWindow  time.Duration // 陪玩师提交完成后用户未确认自动完成
+}
+
+// DefaultTimeoutPolicy 默认超时窗口
+var DefaultTimeoutPolicy = TimeoutPolicy{
+	PaymentTimeout: 30 * time.Minute,
+	AcceptTimeout:  2 * time.Hour,
+	CompleteGrace:  24 * time.Hour,
+	ConfirmWindow:  model.DisputeWindow,
+}
+
+// TimeoutResult 一轮超时处理的结果
+type TimeoutResult struct {
+	Canceled  int
+	Refunded  int
+	Completed int
+	Confirmed int // 确认窗口到期自动确认完成
+}
+
+// Total 本轮处理的订单总数
+func (r TimeoutResult) Total() int { return r.Canceled + r.Refunded + r.Completed + r.Confirmed }
+
+// Refunder 退款单能力（由支付服务实现）。
+type Refunder interface {
+	CreateRefund(ctx context.Context, req paymentservice.CreateRefundRequest) (*model.Refund, error)
+}
+
+// PaymentCanceler 渠道关单并关闭待支付记录（由支付服务实现）。
+type PaymentCanceler interface {
+	CancelPayment(ctx context.Context, userID uint64, paymentID uint64) error
+}
+
+// SetTimeoutPolicy 设置订单超时窗口
+func (s *OrderService) SetTimeoutPolicy(p TimeoutPolicy) { s.timeouts = p }
+
+// SetRefunder 注入退款服务，超时未接单的订单通过退款单原路退回
+func (s *OrderService) SetRefunder(r Refunder) { s.refunder = r }
+
+// SetPaymentCanceler 注入支付服务，超时未支付的订单取消前先关闭渠道交易
+func (s *OrderService) SetPaymentCanceler(c PaymentCanceler) { s.paymentCanceler = c }
+
+// SetNotifications 注入通知仓储，超时处理后通知用户与陪玩师
+func (s *OrderService) SetNotifications(n repository.NotificationRepository) { s.notifications = n }
+
+// ProcessTimeouts 处理超时订单：未支付自动取消、未接单自动退款、服务结束后未确认自动完成、
+// 陪玩师提交完成后用户未确认自动完成。
+// 每类最多处理 limit 个订单，单个订单失败只记录日志，不影响其他订单。
+func (s *OrderService) ProcessTimeouts(ctx context.Context, limit int) (TimeoutResult, error) {
+	var (
+		res  TimeoutResult
+		errs []error
+	)
+	now := time.Now()
+	if s.timeouts.PaymentTimeout > 0 {
+		n, err := s.cancelUnpaidOrders(ctx, now.Add(-s.timeouts.PaymentTimeout), limit)
+		res.Canceled = n
+		errs = append(errs, err)
+	}
+	if s.timeouts.AcceptTimeout > 0 {
+		n, err := s.refundUnacceptedOrders(ctx, now.Add(-s.timeouts.AcceptTimeout), limit)
+		res.Refunded = n
+		errs = append(errs, err)
+	}
+	if s.timeouts.CompleteGrace > 0 {
+		n, err := s.completeOverdueOrders(ctx, now.Add(-s.timeouts.CompleteGrace), limit)
+		res.Completed = n
+		errs = append(errs, err)
+	}
+	if s.timeouts.ConfirmWindow > 0 {
+		n, err := s.confirmSubmittedOrders(ctx, now.Add(-s.timeouts.ConfirmWindow), limit)
+		res.Confirmed = n
+		errs = append(errs, err)
+	}
+	return res, errors.Join(errs...)
+}
+
+// cancelUnpaidOrders 取消 cutoff 之前创建且仍未支付的订单，并关闭其待支付记录
+func (s *OrderService) cancelUnpaidOrders(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	orders, err := s.listOverdue(ctx, model.OrderStatusPending, cutoff, limit, nil)
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i := range orders {
+		order := &orders[i]
+		// 先关单再取消，避免订单取消后用户仍能完成付款
+		if err := s.closePendingPayments(ctx, order.ID); err != nil {
+			slog.Warn("close payments of unpaid order failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+			continue
+		}
+		if !s.transitOnTimeout(ctx, order, model.OrderStatusCanceled, "支付超时自动取消") {
+			continue
+		}
+		done++
+		s.deactivateOrderChat(ctx, order.ID)
+		s.notifyParties(ctx, order, "订单已自动取消", fmt.Sprintf("订单 %s 超时未支付，已自动取消", orderLabel(order)))
+	}
+	return done, nil
+}
+
+// refundUnacceptedOrders 已支付但在 cutoff 之前仍无人接单的订单取消并全额退款
+func (s *OrderService) refundUnacceptedOrders(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	orders, err := s.listOverdue(ctx, model.OrderStatusConfirmed, cutoff, limit, func(o *model.Order) bool {
+		return !o.HasDispute && !s.confirmedAt(ctx, o).After(cutoff)
+	})
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i := range orders {
+		order := &orders[i]
+		const reason = "超时未接单自动退款"
+		if s.refunder == nil {
+			order.RefundAmountCents = order.TotalPriceCents
+			if !s.transitOnTimeout(ctx, order, model.OrderStatusRefunded, reason) {
+				continue
+			}
+		} else if !s.cancelWithRefund(ctx, order, reason) {
+			continue
+		}
+		done++
+		s.deactivateOrderChat(ctx, order.ID)
+		s.notifyParties(ctx, order, "订单已自动退款", fmt.Sprintf("订单 %s 超时无人接单，已取消并原路退款", orderLabel(order)))
+	}
+	return done, nil
+}
+
+// cancelWithRefund 取消订单并在同一事务内创建全额退款单，退款结算后由支付服务流转为已退款。
+// 退款单创建失败时订单保持已支付，下一轮重新处理；已创建的退款单提交失败由退款调度器重试。
+func (s *OrderService) cancelWithRefund(ctx context.Context, order *model.Order, reason string) bool {
+	change := orderstate.Change{Role: model.OrderActorSystem, Reason: reason}
+	refund, err := s.refunder.CreateRefund(ctx, paymentservice.CreateRefundRequest{
+		OrderID: order.ID,
+		Reason:  reason,
+		Source:  model.RefundSourceSystem,
+		Cancel:  &change,
+	})
+	if err != nil {
+		if refund == nil {
+			if !errors.Is(err, orderstate.ErrStatusConflict) {
+				slog.Error("create refund for unaccepted order failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+			}
+			return false
+		}
+		slog.Error("submit refund for unaccepted order failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+	}
+	order.Status = model.OrderStatusCanceled
+	return true
+}
+
+// completeOverdueOrders 预约结束时间早于 cutoff 且用户仍未确认的服务中订单自动完成并记录抽成。
+// 未设置预约结束时间或存在争议的订单不自动完成。
+func (s *OrderService) completeOverdueOrders(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	orders, err := s.listOverdue(ctx, model.OrderStatusInProgress, cutoff, limit, func(o *model.Order) bool {
+		return !o.HasDispute && o.ScheduledEnd != nil && !o.ScheduledEnd.After(cutoff)
+	})
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i := range orders {
+		order := &orders[i]
+		if !s.transitOnTimeout(ctx, order, model.OrderStatusCompleted, "超时未确认自动完成") {
+			continue
+		}
+		done++
+		s.afterAutoComplete(ctx, order, fmt.Sprintf("订单 %s 服务结束后超时未确认，已自动完成", orderLabel(order)))
+	}
+	return done, nil
+}
+
+// confirmSubmittedOrders 陪玩师在 cutoff 之前提交完成且用户仍未确认的订单自动完成并记录抽成。
+// 确认窗口与争议窗口一致，用户已发起争议的订单不自动完成。
+func (s *OrderService) confirmSubmittedOrders(ctx context.Context, cutoff time.Time, limit int) (int, error) {
+	orders, err := s.listOverdue(ctx, model.OrderStatusPendingConfirmation, cutoff, limit, func(o *model.Order) bool {
+		return !o.HasDispute && o.CompletionSubmittedAt != nil && !o.CompletionSubmittedAt.After(cutoff)
+	})
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i := range orders {
+		order := &orders[i]
+		if !s.transitOnTimeout(ctx, order, model.OrderStatusCompleted, "确认超时自动完成") {
+			continue
+		}
+		done++
+		s.afterAutoComplete(ctx, order, fmt.Sprintf("订单 %s 提交完成后用户超时未确认，已自动完成", orderLabel(order)))
+	}
+	return done, nil
+}
+
+// afterAutoComplete 自动完成后记录抽成与信用并通知双方。
+// 抽成记录在状态流转提交之后，失败不回滚完成状态，由抽成补记任务重试。
+func (s *OrderService) afterAutoComplete(ctx context.Context, order *model.Order, notice string) {
+	if err := s.recordCommission(ctx, order.ID); err != nil {
+		slog.Warn("record commission failed, left to commission sweep", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+	}
+	s.recordCompletionCredit(ctx, order)
+	s.deactivateOrderChat(ctx, order.ID)
+	s.notifyParties(ctx, order, "订单已自动完成", notice)
+}
+
+// listOverdue 分页查找 cutoff 之前创建的指定状态订单，due 为空时全部视为超时
+func (s *OrderService) listOverdue(ctx context.Context, status model.OrderStatus, cutoff time.Time, limit int, due func(*model.Order) bool) ([]model.Order, error) {
+	pageSize := repository.NormalizePageSize(limit)
+	var out []model.Order
+	for page := 1; len(out) < limit; page++ {
+		rows, _, err := s.orders.List(ctx, repository.OrderListOptions{
+			Page:     page,
+			PageSize: pageSize,
+			Statuses: []model.OrderStatus{status},
+			DateTo:   &cutoff,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			if due == nil || due(&rows[i]) {
+				out = append(out, rows[i])
+				if len(out) == limit {
+					break
+				}
+			}
+		}
+		if len(rows) < pageSize {
+			break
+		}
+	}
+	return out, nil
+}
+
+// transitOnTimeout 以系统身份流转订单；订单已被并发处理时跳过
+func (s *OrderService) transitOnTimeout(ctx context.Context, order *model.Order, to model.OrderStatus, reason string) bool {
+	err := s.transit(ctx, order, to, orderstate.Change{Role: model.OrderActorSystem, Reason: reason})
+	if err == nil {
+		return true
+	}
+	if !errors.Is(err, orderstate.ErrStatusConflict) {
+		slog.Warn("order timeout transition failed",
+			slog.Uint64("order_id", order.ID),
+			slog.String("to", string(to)),
+			slog.String("error", err.Error()))
+	}
+	return false
+}
+
+// confirmedAt 订单支付确认时间：优先取状态历史，否则以最后更新时间近似
+func (s *OrderService) confirmedAt(ctx context.Context, order *model.Order) time.Time {
+	if s.history != nil {
+		rows, err := s.history.ListByOrder(ctx, order.ID)
+		if err == nil {
+			for i := len(rows) - 1; i >= 0; i-- {
+				if rows[i].ToStatus == model.OrderStatusConfirmed {
+					return rows[i].CreatedAt
+				}
+			}
+		}
+	}
+	return order.UpdatedAt
+}
+
+// closePendingPayments 关闭订单下所有待支付记录
+func (s *OrderService) closePendingPayments(ctx context.Context, orderID uint64) error {
+	status := model.PaymentStatusPending
+	payments, _, err := s.payments.List(ctx, repository.PaymentListOptions{
+		OrderID:  &orderID,
+		Status:   &status,
+		Page:     1,
+		PageSize: 100,
+	})
+	if err != nil {
+		return err
+	}
+	for i := range payments {
+		p := &payments[i]
+		if s.paymentCanceler != nil {
+			if err := s.paymentCanceler.CancelPayment(ctx, p.UserID, p.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		p.Status = model.PaymentStatusFailed
+		if err := s.payments.Update(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyParties 通知下单用户与服务陪玩师，失败只记录日志
+func (s *OrderService) notifyParties(ctx context.Context, order *model.Order, title, message string) {
+	if s.notifications == nil {
+		return
+	}
+	recipients := []uint64{order.UserID}
+	if playerID := order.GetPlayerID(); playerID != 0 {
+		if player, err := s.players.Get(ctx, playerID); err == nil && player.UserID != 0 && player.UserID != order.UserID {
+			recipients = append(recipients, player.UserID)
+		}
+	}
+	id := order.ID
+	for _, uid := range recipients {
+		err := s.notifications.Create(ctx, &model.NotificationEvent{
+			UserID:        uid,
+			Title:         title,
+			Message:       message,
+			Priority:      model.NotificationPriorityNormal,
+			ReferenceType: string(model.OpEntityOrder),
+			ReferenceID:   &id,
+		})
+		if err != nil {
+			slog.Warn("notify order timeout failed", slog.Uint64("order_id", order.ID), slog.Uint64("user_id", uid), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// orderLabel 通知中展示的订单标识
+func orderLabel(order *model.Order) string {
+	if order.OrderNo != "" {
+		return order.OrderNo
+	}
+	return fmt.Sprintf("#%d", order.ID)
+}
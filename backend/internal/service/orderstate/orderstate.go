@@ -0,0 +1,278 @@
+// Package orderstate 订单状态机：集中声明允许的状态流转、守卫条件与副作用，
+// 所有修改订单状态的服务都通过 Transit 完成流转并记录状态历史。
+package orderstate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gamelink/internal/logging"
+	"gamelink/internal/model"
+)
+
+var (
+	// ErrInvalidTransition 状态流转不合法（表中没有该流转、操作方无权或守卫不通过）
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	// ErrStatusConflict 保存时订单状态已被并发修改
+	ErrStatusConflict = errors.New("order status changed concurrently")
+)
+
+// Change 描述一次状态变更的操作方与上下文
+type Change struct {
+	Role        model.OrderActorRole
+	ActorUserID *uint64   // 为空时取上下文中的操作人
+	Reason      string    // 取消/退款原因或备注
+	TraceID     string    // 为空时取上下文中的请求 ID
+	At          time.Time // 为空时取当前时间
+}
+
+// Guard 流转前的额外校验，返回错误则拒绝流转
+type Guard func(order *model.Order, c Change) error
+
+// Effect 流转时对订单字段的副作用
+type Effect func(order *model.Order, c Change)
+
+// Transition 一条允许的状态流转
+type Transition struct {
+	From   model.OrderStatus
+	To     model.OrderStatus
+	Roles  []model.OrderActorRole // 允许发起该流转的操作方
+	Guard  Guard
+	Effect Effect
+}
+
+var (
+	user   = model.OrderActorUser
+	player = model.OrderActorPlayer
+	admin  = model.OrderActorAdmin
+	system = model.OrderActorSystem
+)
+
+// transitions 订单状态流转表；canceled 仅允许退款结算，refunded 仅允许继续部分退款
+var transitions = []Transition{
+	{From: model.OrderStatusPending, To: model.OrderStatusConfirmed, Roles: roles(admin, system)},
+	{From: model.OrderStatusPending, To: model.OrderStatusCanceled, Roles: roles(user, admin, system), Effect: markCanceled},
+	{From: model.OrderStatusPending, To: model.OrderStatusRefunded, Roles: roles(admin, system), Effect: markRefunded},
+	// 礼物订单下单即送达
+	{From: model.OrderStatusPending, To: model.OrderStatusCompleted, Roles: roles(system), Guard: requireGift, Effect: markCompleted},
+
+	{From: model.OrderStatusConfirmed, To: model.OrderStatusInProgress, Roles: roles(player, admin), Guard: requireAssignedPlayer, Effect: markStarted},
+	// 用户取消已支付订单时先取消，退款结算后由系统流转为已退款
+	{From: model.OrderStatusConfirmed, To: model.OrderStatusCanceled, Roles: roles(user, admin, system), Effect: markCanceled},
+	{From: model.OrderStatusConfirmed, To: model.OrderStatusRefunded, Roles: roles(admin, system), Effect: markRefunded},
+
+	// 陪玩师提交完成后等待用户确认；用户可提前确认，服务超时未确认时由系统自动完成
+	{From: model.OrderStatusInProgress, To: model.OrderStatusPendingConfirmation, Roles: roles(player, admin), Effect: markSubmitted},
+	{From: model.OrderStatusInProgress, To: model.OrderStatusCompleted, Roles: roles(user, admin, system), Effect: markCompleted},
+	{From: model.OrderStatusInProgress, To: model.OrderStatusCanceled, Roles: roles(admin), Effect: markCanceled},
+	{From: model.OrderStatusInProgress, To: model.OrderStatusRefunded, Roles: roles(admin, system), Effect: markRefunded},
+
+	// 用户确认或确认窗口到期后完成；用户提出异议时退回服务中
+	{From: model.OrderStatusPendingConfirmation, To: model.OrderStatusCompleted, Roles: roles(user, admin, system), Effect: markCompleted},
+	{From: model.OrderStatusPendingConfirmation, To: model.OrderStatusInProgress, Roles: roles(user, admin), Effect: markObjected},
+	{From: model.OrderStatusPendingConfirmation, To: model.OrderStatusCanceled, Roles: roles(admin), Effect: markCanceled},
+	{From: model.OrderStatusPendingConfirmation, To: model.OrderStatusRefunded, Roles: roles(admin, system), Effect: markRefunded},
+
+	{From: model.OrderStatusCompleted, To: model.OrderStatusRefunded, Roles: roles(admin, system), Effect: markRefunded},
+	// 已支付订单取消后由退款结算流转
+	{From: model.OrderStatusCanceled, To: model.OrderStatusRefunded, Roles: roles(system), Effect: markRefunded},
+	// 同一订单可多次部分退款
+	{From: model.OrderStatusRefunded, To: model.OrderStatusRefunded, Roles: roles(admin, system), Effect: markRefunded},
+}
+
+func roles(r ...model.OrderActorRole) []model.OrderActorRole { return r }
+
+// Lookup 查找 from → to 的流转定义
+func Lookup(from, to model.OrderStatus) (*Transition, bool) {
+	for i := range transitions {
+		if transitions[i].From == from && transitions[i].To == to {
+			return &transitions[i], true
+		}
+	}
+	return nil, false
+}
+
+// Transitions 返回完整的状态流转表（副本）
+func Transitions() []Transition {
+	out := make([]Transition, len(transitions))
+	copy(out, transitions)
+	return out
+}
+
+// Allows 判断操作方是否可以发起该流转
+func (t *Transition) Allows(role model.OrderActorRole) bool {
+	for _, r := range t.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransit 判断操作方能否把 from 状态的订单流转到 to（不含守卫校验）
+func CanTransit(from, to model.OrderStatus, role model.OrderActorRole) bool {
+	t, ok := Lookup(from, to)
+	return ok && t.Allows(role)
+}
+
+// Apply 校验并执行流转：修改订单状态与相关字段，返回待保存的状态历史。
+// 校验失败时订单保持不变。
+func Apply(order *model.Order, to model.OrderStatus, c Change) (*model.OrderStatusHistory, error) {
+	t, ok := Lookup(order.Status, to)
+	if !ok || !t.Allows(c.Role) {
+		return nil, ErrInvalidTransition
+	}
+	if c.At.IsZero() {
+		c.At = time.Now()
+	}
+	c.Reason = strings.TrimSpace(c.Reason)
+	if t.Guard != nil {
+		if err := t.Guard(order, c); err != nil {
+			return nil, err
+		}
+	}
+	from := order.Status
+	order.Status = to
+	if t.Effect != nil {
+		t.Effect(order, c)
+	}
+	return &model.OrderStatusHistory{
+		OrderID:     order.ID,
+		FromStatus:  from,
+		ToStatus:    to,
+		ActorUserID: c.ActorUserID,
+		ActorRole:   c.Role,
+		Reason:      c.Reason,
+		TraceID:     c.TraceID,
+		CreatedAt:   c.At,
+	}, nil
+}
+
+// OrderUpdater 保存订单
+type OrderUpdater interface {
+	Update(ctx context.Context, order *model.Order) error
+}
+
+// ConditionalUpdater 按状态比较并交换地保存订单（由订单仓储实现）
+type ConditionalUpdater interface {
+	UpdateIfStatus(ctx context.Context, order *model.Order, from model.OrderStatus) (bool, error)
+}
+
+// OrderGetter 读取订单
+type OrderGetter interface {
+	Get(ctx context.Context, id uint64) (*model.Order, error)
+}
+
+// RowLocker 在事务内加行锁读取订单（由订单仓储实现）
+type RowLocker interface {
+	GetForUpdate(ctx context.Context, id uint64) (*model.Order, error)
+}
+
+// Lock 读取订单；仓储支持 RowLocker 时加行锁，同一订单的并发事务在此排队。
+// 需在事务内以事务仓储调用，锁随事务提交释放。
+func Lock(ctx context.Context, orders OrderGetter, id uint64) (*model.Order, error) {
+	if l, ok := orders.(RowLocker); ok {
+		return l.GetForUpdate(ctx, id)
+	}
+	return orders.Get(ctx, id)
+}
+
+// HistoryAppender 追加状态历史
+type HistoryAppender interface {
+	Append(ctx context.Context, entry *model.OrderStatusHistory) error
+}
+
+// Transit 执行流转并保存订单与状态历史；history 为空时只保存订单。
+// 订单仓储支持 ConditionalUpdater 时仅在状态未被并发修改时保存，否则返回 ErrStatusConflict。
+// 需要原子性时调用方应传入同一事务内的仓储。
+func Transit(ctx context.Context, orders OrderUpdater, history HistoryAppender, order *model.Order, to model.OrderStatus, c Change) error {
+	if c.ActorUserID == nil && c.Role != system {
+		if uid, ok := logging.ActorUserIDFromContext(ctx); ok {
+			c.ActorUserID = &uid
+		}
+	}
+	if c.TraceID == "" {
+		if rid, ok := logging.RequestIDFromContext(ctx); ok {
+			c.TraceID = rid
+		}
+	}
+	from := order.Status
+	entry, err := Apply(order, to, c)
+	if err != nil {
+		return err
+	}
+	if cu, ok := orders.(ConditionalUpdater); ok {
+		updated, err := cu.UpdateIfStatus(ctx, order, from)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			order.Status = from
+			return ErrStatusConflict
+		}
+	} else if err := orders.Update(ctx, order); err != nil {
+		return err
+	}
+	if history == nil {
+		return nil
+	}
+	return history.Append(ctx, entry)
+}
+
+func requireGift(order *model.Order, _ Change) error {
+	if !order.IsGiftOrder() {
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+// requireAssignedPlayer 陪玩师开始服务前订单必须已指派给该陪玩师
+func requireAssignedPlayer(order *model.Order, c Change) error {
+	if c.Role == player && order.GetPlayerID() == 0 {
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+func markStarted(order *model.Order, c Change) {
+	if order.StartedAt == nil {
+		at := c.At
+		order.StartedAt = &at
+	}
+}
+
+func markCompleted(order *model.Order, c Change) {
+	if order.CompletedAt == nil {
+		at := c.At
+		order.CompletedAt = &at
+	}
+}
+
+// markSubmitted 记录陪玩师提交完成的时间，确认窗口从此刻起算
+func markSubmitted(order *model.Order, c Change) {
+	at := c.At
+	order.CompletionSubmittedAt = &at
+}
+
+// markObjected 用户提出异议后清除提交时间，陪玩师需重新提交
+func markObjected(order *model.Order, _ Change) {
+	order.CompletionSubmittedAt = nil
+}
+
+func markCanceled(order *model.Order, c Change) {
+	if order.CancelReason == "" {
+		order.CancelReason = c.Reason
+	}
+}
+
+func markRefunded(order *model.Order, c Change) {
+	if order.RefundedAt == nil {
+		at := c.At
+		order.RefundedAt = &at
+	}
+	if order.RefundReason == "" {
+		order.RefundReason = c.Reason
+	}
+}
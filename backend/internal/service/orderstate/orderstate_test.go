@@ -0,0 +1,136 @@
+package orderstate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/logging"
+	"gamelink/internal/model"
+)
+
+type fakeOrders struct{ saved []model.Order }
+
+func (f *fakeOrders) Update(_ context.Context, o *model.Order) error {
+	f.saved = append(f.saved, *o)
+	return nil
+}
+
+type fakeHistory struct{ rows []model.OrderStatusHistory }
+
+func (f *fakeHistory) Append(_ context.Context, h *model.OrderStatusHistory) error {
+	f.rows = append(f.rows, *h)
+	return nil
+}
+
+func TestCanTransit(t *testing.T) {
+	cases := []struct {
+		from, to model.OrderStatus
+		role     model.OrderActorRole
+		want     bool
+	}{
+		{model.OrderStatusPending, model.OrderStatusConfirmed, model.OrderActorSystem, true},
+		{model.OrderStatusPending, model.OrderStatusConfirmed, model.OrderActorUser, false},
+		{model.OrderStatusPending, model.OrderStatusCanceled, model.OrderActorUser, true},
+		{model.OrderStatusConfirmed, model.OrderStatusInProgress, model.OrderActorPlayer, true},
+		{model.OrderStatusConfirmed, model.OrderStatusInProgress, model.OrderActorUser, false},
+		{model.OrderStatusInProgress, model.OrderStatusCanceled, model.OrderActorUser, false},
+		{model.OrderStatusInProgress, model.OrderStatusCanceled, model.OrderActorAdmin, true},
+		{model.OrderStatusInProgress, model.OrderStatusCompleted, model.OrderActorPlayer, false},
+		{model.OrderStatusInProgress, model.OrderStatusPendingConfirmation, model.OrderActorPlayer, true},
+		{model.OrderStatusPendingConfirmation, model.OrderStatusCompleted, model.OrderActorSystem, true},
+		{model.OrderStatusPendingConfirmation, model.OrderStatusCompleted, model.OrderActorPlayer, false},
+		{model.OrderStatusPendingConfirmation, model.OrderStatusInProgress, model.OrderActorUser, true},
+		{model.OrderStatusConfirmed, model.OrderStatusRefunded, model.OrderActorUser, false},
+		{model.OrderStatusCompleted, model.OrderStatusRefunded, model.OrderActorAdmin, true},
+		{model.OrderStatusCompleted, model.OrderStatusCanceled, model.OrderActorAdmin, false},
+		{model.OrderStatusCanceled, model.OrderStatusRefunded, model.OrderActorSystem, true},
+		{model.OrderStatusCanceled, model.OrderStatusRefunded, model.OrderActorAdmin, false},
+		{model.OrderStatusRefunded, model.OrderStatusRefunded, model.OrderActorSystem, true},
+		{model.OrderStatusCanceled, model.OrderStatusPending, model.OrderActorAdmin, false},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, CanTransit(tc.from, tc.to, tc.role), "%s -> %s by %s", tc.from, tc.to, tc.role)
+	}
+}
+
+func TestApply_GuardsAndEffects(t *testing.T) {
+	// 非礼物订单不能跳过服务直接完成
+	order := &model.Order{Status: model.OrderStatusPending}
+	_, err := Apply(order, model.OrderStatusCompleted, Change{Role: model.OrderActorSystem})
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	assert.Equal(t, model.OrderStatusPending, order.Status)
+
+	recipient := uint64(3)
+	gift := &model.Order{Status: model.OrderStatusPending, RecipientPlayerID: &recipient}
+	_, err = Apply(gift, model.OrderStatusCompleted, Change{Role: model.OrderActorSystem})
+	require.NoError(t, err)
+	assert.NotNil(t, gift.CompletedAt)
+
+	// 陪玩师开始服务前必须已指派
+	order = &model.Order{Status: model.OrderStatusConfirmed}
+	_, err = Apply(order, model.OrderStatusInProgress, Change{Role: model.OrderActorPlayer})
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	order.SetPlayerID(9)
+	entry, err := Apply(order, model.OrderStatusInProgress, Change{Role: model.OrderActorPlayer, At: at})
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusInProgress, order.Status)
+	require.NotNil(t, order.StartedAt)
+	assert.Equal(t, at, *order.StartedAt)
+	assert.Equal(t, model.OrderStatusConfirmed, entry.FromStatus)
+	assert.Equal(t, at, entry.CreatedAt)
+
+	// 提交完成记录提交时间，用户异议后清除
+	entry, err = Apply(order, model.OrderStatusPendingConfirmation, Change{Role: model.OrderActorPlayer, At: at})
+	require.NoError(t, err)
+	require.NotNil(t, order.CompletionSubmittedAt)
+	assert.Equal(t, at, *order.CompletionSubmittedAt)
+	_, err = Apply(order, model.OrderStatusInProgress, Change{Role: model.OrderActorUser, Reason: "还没打完"})
+	require.NoError(t, err)
+	assert.Nil(t, order.CompletionSubmittedAt)
+	assert.Nil(t, order.CompletedAt)
+
+	// 已有原因不被覆盖
+	order = &model.Order{Status: model.OrderStatusPending, CancelReason: "preset"}
+	entry, err = Apply(order, model.OrderStatusCanceled, Change{Role: model.OrderActorUser, Reason: "  changed mind "})
+	require.NoError(t, err)
+	assert.Equal(t, "preset", order.CancelReason)
+	assert.Equal(t, "changed mind", entry.Reason)
+}
+
+func TestTransit_PersistsOrderAndHistory(t *testing.T) {
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	ctx = logging.WithActorUserID(ctx, 42)
+	orders, history := &fakeOrders{}, &fakeHistory{}
+	order := &model.Order{Base: model.Base{ID: 5}, Status: model.OrderStatusInProgress}
+
+	require.NoError(t, Transit(ctx, orders, history, order, model.OrderStatusCanceled, Change{Role: model.OrderActorAdmin, Reason: "no show"}))
+	require.Len(t, orders.saved, 1)
+	assert.Equal(t, model.OrderStatusCanceled, orders.saved[0].Status)
+	require.Len(t, history.rows, 1)
+	row := history.rows[0]
+	assert.Equal(t, uint64(5), row.OrderID)
+	assert.Equal(t, model.OrderStatusInProgress, row.FromStatus)
+	assert.Equal(t, model.OrderStatusCanceled, row.ToStatus)
+	assert.Equal(t, model.OrderActorAdmin, row.ActorRole)
+	assert.Equal(t, "req-1", row.TraceID)
+	require.NotNil(t, row.ActorUserID)
+	assert.Equal(t, uint64(42), *row.ActorUserID)
+
+	// 终态不可再取消，不写任何记录
+	err := Transit(ctx, orders, history, order, model.OrderStatusCanceled, Change{Role: model.OrderActorAdmin})
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+	assert.Len(t, orders.saved, 1)
+	assert.Len(t, history.rows, 1)
+
+	// 未注入历史仓储时只保存订单
+	order = &model.Order{Status: model.OrderStatusPending}
+	require.NoError(t, Transit(context.Background(), orders, nil, order, model.OrderStatusConfirmed, Change{Role: model.OrderActorSystem}))
+	assert.Len(t, orders.saved, 2)
+}
@@ -0,0 +1,366 @@
+package payment
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+)
+
+const (
+	alipayDefaultGatewayURL = "https://openapi.alipay.com/gateway.do"
+	alipayTimeLayout        = "2006-01-02 15:04:05"
+	alipayCodeSuccess       = "10000"
+)
+
+// alipayLocation 支付宝接口时间使用北京时间。
+var alipayLocation = time.FixedZone("CST", 8*3600)
+
+// AlipayConfig 支付宝（当面付预下单）配置。
+type AlipayConfig struct {
+	AppID string
+	// PrivateKey 应用私钥，用于请求签名（RSA2）
+	PrivateKey *rsa.PrivateKey
+	// AlipayPublicKey 支付宝公钥，用于验证应答与异步通知
+	AlipayPublicKey *rsa.PublicKey
+	GatewayURL      string
+	HTTPClient      *http.Client
+}
+
+// AlipayGateway 支付宝开放平台网关。
+type AlipayGateway struct {
+	cfg    AlipayConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewAlipayGateway 创建支付宝网关。
+func NewAlipayGateway(cfg AlipayConfig) *AlipayGateway {
+	if cfg.GatewayURL == "" {
+		cfg.GatewayURL = alipayDefaultGatewayURL
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &AlipayGateway{cfg: cfg, client: client, now: time.Now}
+}
+
+// alipayResponse 支付宝接口通用应答字段。
+type alipayResponse struct {
+	Code        string `json:"code"`
+	Msg         string `json:"msg"`
+	SubCode     string `json:"sub_code"`
+	SubMsg      string `json:"sub_msg"`
+	OutTradeNo  string `json:"out_trade_no"`
+	TradeNo     string `json:"trade_no"`
+	TradeStatus string `json:"trade_status"`
+	TotalAmount string `json:"total_amount"`
+	SendPayDate string `json:"send_pay_date"`
+	QRCode      string `json:"qr_code"`
+	RefundFee   string `json:"refund_fee"`
+	GmtRefund   string `json:"gmt_refund_pay"`
+	// 退款相关
+	FundChange   string `json:"fund_change"`
+	OutRequestNo string `json:"out_request_no"`
+	RefundStatus string `json:"refund_status"`
+}
+
+// Method implements Gateway.
+func (g *AlipayGateway) Method() model.PaymentMethod { return model.PaymentMethodAlipay }
+
+// CreatePrepay implements Gateway（alipay.trade.precreate，返回 qr_code）。
+func (g *AlipayGateway) CreatePrepay(ctx context.Context, req PrepayRequest) (*PrepayResult, error) {
+	if currencyOrDefault(req.Currency) != model.CurrencyCNY {
+		return nil, fmt.Errorf("alipay: unsupported currency %s", req.Currency)
+	}
+	biz := map[string]interface{}{
+		"out_trade_no": req.OutTradeNo,
+		"total_amount": formatYuan(req.AmountCents),
+		"subject":      req.Description,
+	}
+	if !req.ExpireAt.IsZero() {
+		biz["time_expire"] = req.ExpireAt.In(alipayLocation).Format(alipayTimeLayout)
+	}
+	resp, raw, err := g.call(ctx, "alipay.trade.precreate", req.NotifyURL, biz)
+	if err != nil {
+		return nil, err
+	}
+	return &PrepayResult{
+		PayInfo: map[string]interface{}{"qr_code": resp.QRCode},
+		Raw:     raw,
+	}, nil
+}
+
+// Query implements Gateway.
+func (g *AlipayGateway) Query(ctx context.Context, outTradeNo string) (*TradeResult, error) {
+	resp, raw, err := g.call(ctx, "alipay.trade.query", "", map[string]interface{}{"out_trade_no": outTradeNo})
+	if err != nil {
+		// 预下单后用户未扫码时，支付宝侧交易尚未创建
+		if strings.Contains(err.Error(), "ACQ.TRADE_NOT_EXIST") {
+			return &TradeResult{OutTradeNo: outTradeNo, State: TradeStatePending}, nil
+		}
+		return nil, err
+	}
+	return g.toResult(resp.OutTradeNo, resp.TradeNo, resp.TradeStatus, resp.TotalAmount, resp.SendPayDate, raw)
+}
+
+// Close implements Gateway.
+func (g *AlipayGateway) Close(ctx context.Context, outTradeNo string) error {
+	_, _, err := g.call(ctx, "alipay.trade.close", "", map[string]interface{}{"out_trade_no": outTradeNo})
+	if err != nil && strings.Contains(err.Error(), "ACQ.TRADE_NOT_EXIST") {
+		return nil
+	}
+	return err
+}
+
+// Refund implements ProviderClient（out_request_no 标识一笔部分退款）。
+//
+// fund_change=Y 表示本次请求已退款成功；否则需通过退款查询确认。
+func (g *AlipayGateway) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	resp, raw, err := g.call(ctx, "alipay.trade.refund", "", map[string]interface{}{
+		"out_trade_no":   req.OutTradeNo,
+		"refund_amount":  formatYuan(req.AmountCents),
+		"refund_reason":  req.Reason,
+		"out_request_no": req.OutRefundNo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &RefundResult{
+		OutRefundNo:      req.OutRefundNo,
+		ProviderRefundNo: resp.TradeNo,
+		State:            RefundStateProcessing,
+		Raw:              raw,
+	}
+	if resp.FundChange == "Y" {
+		result.State = RefundStateSucceeded
+		refundedAt := g.now()
+		if t, err := time.ParseInLocation(alipayTimeLayout, resp.GmtRefund, alipayLocation); err == nil {
+			refundedAt = t
+		}
+		result.RefundedAt = &refundedAt
+	}
+	return result, nil
+}
+
+// QueryRefund implements ProviderClient（alipay.trade.fastpay.refund.query）。
+func (g *AlipayGateway) QueryRefund(ctx context.Context, outTradeNo, outRefundNo string) (*RefundResult, error) {
+	resp, raw, err := g.call(ctx, "alipay.trade.fastpay.refund.query", "", map[string]interface{}{
+		"out_trade_no":   outTradeNo,
+		"out_request_no": outRefundNo,
+		"query_options":  []string{"gmt_refund_pay"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &RefundResult{
+		OutRefundNo:      outRefundNo,
+		ProviderRefundNo: resp.TradeNo,
+		State:            RefundStateProcessing,
+		Raw:              raw,
+	}
+	if resp.RefundStatus == "REFUND_SUCCESS" {
+		result.State = RefundStateSucceeded
+		if t, err := time.ParseInLocation(alipayTimeLayout, resp.GmtRefund, alipayLocation); err == nil {
+			result.RefundedAt = &t
+		}
+	}
+	return result, nil
+}
+
+// VerifyNotification implements Gateway：异步通知为表单格式，按参数排序验签。
+func (g *AlipayGateway) VerifyNotification(_ context.Context, _ http.Header, body []byte) (*TradeResult, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("alipay: decode notification: %w", err)
+	}
+	if values.Get("sign_type") != "" && values.Get("sign_type") != "RSA2" {
+		return nil, fmt.Errorf("%w: unsupported sign_type", ErrInvalidSignature)
+	}
+	if err := g.verify(alipaySignContent(values, "sign", "sign_type"), values.Get("sign")); err != nil {
+		return nil, err
+	}
+	if g.cfg.AppID != "" && values.Get("app_id") != g.cfg.AppID {
+		return nil, fmt.Errorf("alipay: app_id mismatch")
+	}
+	raw, _ := json.Marshal(flattenValues(values))
+	return g.toResult(values.Get("out_trade_no"), values.Get("trade_no"), values.Get("trade_status"),
+		values.Get("total_amount"), values.Get("gmt_payment"), raw)
+}
+
+// NotificationAck implements Gateway（支付宝要求返回纯文本 success）。
+func (g *AlipayGateway) NotificationAck(ok bool) (int, string, []byte) {
+	if ok {
+		return http.StatusOK, "text/plain; charset=utf-8", []byte("success")
+	}
+	return http.StatusOK, "text/plain; charset=utf-8", []byte("failure")
+}
+
+// call 调用开放平台接口并校验应答签名。
+func (g *AlipayGateway) call(ctx context.Context, method, notifyURL string, biz map[string]interface{}) (*alipayResponse, json.RawMessage, error) {
+	if g.cfg.PrivateKey == nil || g.cfg.AppID == "" {
+		return nil, nil, ErrGatewayNotConfigured
+	}
+	bizContent, err := json.Marshal(biz)
+	if err != nil {
+		return nil, nil, err
+	}
+	params := url.Values{}
+	params.Set("app_id", g.cfg.AppID)
+	params.Set("method", method)
+	params.Set("format", "JSON")
+	params.Set("charset", "utf-8")
+	params.Set("sign_type", "RSA2")
+	params.Set("timestamp", g.now().In(alipayLocation).Format(alipayTimeLayout))
+	params.Set("version", "1.0")
+	params.Set("biz_content", string(bizContent))
+	if notifyURL != "" {
+		params.Set("notify_url", notifyURL)
+	}
+	sign, err := g.sign(alipaySignContent(params, "sign"))
+	if err != nil {
+		return nil, nil, err
+	}
+	params.Set("sign", sign)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.cfg.GatewayURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("alipay: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, nil, fmt.Errorf("alipay: http %d", resp.StatusCode)
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("alipay: decode response: %w", err)
+	}
+	node := envelope[strings.ReplaceAll(method, ".", "_")+"_response"]
+	if len(node) == 0 {
+		return nil, nil, fmt.Errorf("alipay: missing response node")
+	}
+	var sign64 string
+	_ = json.Unmarshal(envelope["sign"], &sign64)
+	if err := g.verify(string(node), sign64); err != nil {
+		return nil, nil, fmt.Errorf("alipay: response %w", err)
+	}
+	var out alipayResponse
+	if err := json.Unmarshal(node, &out); err != nil {
+		return nil, nil, fmt.Errorf("alipay: decode response: %w", err)
+	}
+	if out.Code != alipayCodeSuccess {
+		return nil, nil, fmt.Errorf("alipay: %s %s: %s %s", out.Code, out.Msg, out.SubCode, out.SubMsg)
+	}
+	return &out, json.RawMessage(node), nil
+}
+
+func (g *AlipayGateway) sign(content string) (string, error) {
+	digest := sha256.Sum256([]byte(content))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, g.cfg.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+func (g *AlipayGateway) verify(content, sign64 string) error {
+	if g.cfg.AlipayPublicKey == nil {
+		return ErrGatewayNotConfigured
+	}
+	sig, err := base64.StdEncoding.DecodeString(sign64)
+	if err != nil || len(sig) == 0 {
+		return ErrInvalidSignature
+	}
+	digest := sha256.Sum256([]byte(content))
+	if err := rsa.VerifyPKCS1v15(g.cfg.AlipayPublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (g *AlipayGateway) toResult(outTradeNo, tradeNo, status, totalAmount, paidAt string, raw json.RawMessage) (*TradeResult, error) {
+	state := TradeStatePending
+	switch status {
+	case "TRADE_SUCCESS", "TRADE_FINISHED":
+		state = TradeStatePaid
+	case "TRADE_CLOSED":
+		state = TradeStateClosed
+	}
+	result := &TradeResult{
+		OutTradeNo:      outTradeNo,
+		ProviderTradeNo: tradeNo,
+		State:           state,
+		Currency:        model.CurrencyCNY,
+		Raw:             raw,
+	}
+	if totalAmount != "" {
+		cents, err := parseYuan(totalAmount)
+		if err != nil {
+			return nil, fmt.Errorf("alipay: %w", err)
+		}
+		result.AmountCents = cents
+	}
+	if t, err := time.ParseInLocation(alipayTimeLayout, paidAt, alipayLocation); err == nil {
+		result.PaidAt = &t
+	}
+	return result, nil
+}
+
+// alipaySignContent 生成待签名串：按 key 排序的 k=v，以 & 连接，跳过空值与排除字段。
+func alipaySignContent(values url.Values, exclude ...string) string {
+	skip := make(map[string]struct{}, len(exclude))
+	for _, k := range exclude {
+		skip[k] = struct{}{}
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if _, ok := skip[k]; ok || values.Get(k) == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(values.Get(k))
+	}
+	return b.String()
+}
+
+func flattenValues(values url.Values) map[string]string {
+	out := make(map[string]string, len(values))
+	for k := range values {
+		out[k] = values.Get(k)
+	}
+	return out
+}
+
+var _ Gateway = (*AlipayGateway)(nil)
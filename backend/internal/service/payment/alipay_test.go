@@ -0,0 +1,47 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlipayGateway_VerifyNotification(t *testing.T) {
+	alipayKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	gw := NewAlipayGateway(AlipayConfig{AppID: "2021000000000001", AlipayPublicKey: &alipayKey.PublicKey})
+	// 使用支付宝私钥签名以模拟平台推送
+	signer := NewAlipayGateway(AlipayConfig{PrivateKey: alipayKey})
+
+	values := url.Values{}
+	values.Set("app_id", "2021000000000001")
+	values.Set("out_trade_no", "PAY003")
+	values.Set("trade_no", "2088000003")
+	values.Set("trade_status", "TRADE_SUCCESS")
+	values.Set("total_amount", "12.50")
+	values.Set("gmt_payment", "2025-01-02 15:04:05")
+	values.Set("sign_type", "RSA2")
+	sign, err := signer.sign(alipaySignContent(values, "sign", "sign_type"))
+	require.NoError(t, err)
+	values.Set("sign", sign)
+
+	ctx := context.Background()
+	result, err := gw.VerifyNotification(ctx, nil, []byte(values.Encode()))
+	require.NoError(t, err)
+	assert.Equal(t, "PAY003", result.OutTradeNo)
+	assert.Equal(t, TradeStatePaid, result.State)
+	assert.Equal(t, int64(1250), result.AmountCents)
+	require.NotNil(t, result.PaidAt)
+
+	values.Set("total_amount", "0.01")
+	_, err = gw.VerifyNotification(ctx, nil, []byte(values.Encode()))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, _, ack := gw.NotificationAck(true)
+	assert.Equal(t, "success", string(ack))
+}
@@ -0,0 +1,230 @@
+package payment
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+)
+
+// ErrInvalidBill 渠道对账单格式不正确。
+var ErrInvalidBill = errors.New("invalid provider bill")
+
+// billLocation 微信、支付宝账单时间均为北京时间。
+var billLocation = time.FixedZone("CST", 8*3600)
+
+// BillLineKind 对账单行类型。
+type BillLineKind string
+
+// BillLineKind values.
+const (
+	BillLinePayment BillLineKind = "payment"
+	BillLineRefund  BillLineKind = "refund"
+)
+
+// BillLine 渠道对账单中的一笔交易（已统一为分、正数金额）。
+type BillLine struct {
+	LineNo           int // 文件中的行号（从 1 开始）
+	Kind             BillLineKind
+	ProviderTradeNo  string // 微信订单号 / 支付宝交易号
+	OutTradeNo       string // 商户订单号
+	ProviderRefundNo string // 微信退款单号（支付宝无）
+	OutRefundNo      string // 商户退款单号 / 退款请求号
+	AmountCents      int64
+	OccurredAt       time.Time
+}
+
+// ParseBill 按渠道格式解析对账单 CSV（UTF-8 编码）。
+func ParseBill(method model.PaymentMethod, r io.Reader) ([]BillLine, error) {
+	switch method {
+	case model.PaymentMethodWeChat:
+		return ParseWeChatBill(r)
+	case model.PaymentMethodAlipay:
+		return ParseAlipayBill(r)
+	default:
+		return nil, fmt.Errorf("%w: unsupported provider %q", ErrInvalidBill, method)
+	}
+}
+
+// ParseWeChatBill 解析微信支付交易账单（bill_type=ALL）。
+//
+// 每个字段以 ` 开头；交易状态 SUCCESS 为支付、REFUND 为退款，REVOKED 等跳过；
+// 明细之后的“总交易单数”汇总行及其后内容忽略。
+func ParseWeChatBill(r io.Reader) ([]BillLine, error) {
+	rows, err := readBillRows(r, "交易时间", "总交易单数")
+	if err != nil {
+		return nil, err
+	}
+	col := rows.columns
+	for _, name := range []string{"交易时间", "微信订单号", "商户订单号", "交易状态"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %s", ErrInvalidBill, name)
+		}
+	}
+
+	var lines []BillLine
+	for _, row := range rows.records {
+		at, err := time.ParseInLocation("2006-01-02 15:04:05", row.get(col, "交易时间"), billLocation)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid time", ErrInvalidBill, row.lineNo)
+		}
+		line := BillLine{
+			LineNo:          row.lineNo,
+			ProviderTradeNo: row.get(col, "微信订单号"),
+			OutTradeNo:      row.get(col, "商户订单号"),
+			OccurredAt:      at,
+		}
+		var amount string
+		switch strings.ToUpper(row.get(col, "交易状态")) {
+		case "SUCCESS":
+			line.Kind = BillLinePayment
+			amount = row.first(col, "订单金额", "应结订单金额")
+		case "REFUND":
+			line.Kind = BillLineRefund
+			line.ProviderRefundNo = row.get(col, "微信退款单号")
+			line.OutRefundNo = row.get(col, "商户退款单号")
+			amount = row.first(col, "申请退款金额", "退款金额")
+		default:
+			continue
+		}
+		if line.AmountCents, err = parseYuan(amount); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidBill, row.lineNo, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// ParseAlipayBill 解析支付宝业务明细账单。
+//
+// 以 # 开头的说明行与合计行忽略；业务类型“交易”为支付、“退款”为退款（金额为负）。
+func ParseAlipayBill(r io.Reader) ([]BillLine, error) {
+	rows, err := readBillRows(r, "支付宝交易号", "")
+	if err != nil {
+		return nil, err
+	}
+	col := rows.columns
+	for _, name := range []string{"支付宝交易号", "商户订单号", "业务类型", "订单金额（元）"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %s", ErrInvalidBill, name)
+		}
+	}
+
+	var lines []BillLine
+	for _, row := range rows.records {
+		line := BillLine{
+			LineNo:          row.lineNo,
+			ProviderTradeNo: row.get(col, "支付宝交易号"),
+			OutTradeNo:      row.get(col, "商户订单号"),
+		}
+		switch row.get(col, "业务类型") {
+		case "交易":
+			line.Kind = BillLinePayment
+		case "退款":
+			line.Kind = BillLineRefund
+			line.OutRefundNo = row.get(col, "退款批次号/请求号")
+		default:
+			continue
+		}
+		at, err := time.ParseInLocation("2006-01-02 15:04:05", row.first(col, "完成时间", "创建时间"), billLocation)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: invalid time", ErrInvalidBill, row.lineNo)
+		}
+		line.OccurredAt = at
+		amount, err := parseYuan(row.get(col, "订单金额（元）"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidBill, row.lineNo, err)
+		}
+		if amount < 0 {
+			amount = -amount
+		}
+		line.AmountCents = amount
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+type billRow struct {
+	lineNo int
+	fields []string
+}
+
+func (r billRow) get(col map[string]int, name string) string {
+	idx, ok := col[name]
+	if !ok || idx >= len(r.fields) {
+		return ""
+	}
+	return r.fields[idx]
+}
+
+func (r billRow) first(col map[string]int, names ...string) string {
+	for _, name := range names {
+		if v := r.get(col, name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+type billRows struct {
+	columns map[string]int
+	records []billRow
+}
+
+// readBillRows 读取表头（首列为 headerFirst）之后的明细行，遇到首列为 footerFirst 的行停止。
+func readBillRows(r io.Reader, headerFirst, footerFirst string) (*billRows, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	out := &billRows{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		reader := csv.NewReader(strings.NewReader(text))
+		reader.LazyQuotes = true
+		reader.FieldsPerRecord = -1
+		fields, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidBill, lineNo, err)
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(fields[i]), "`"))
+		}
+		if out.columns == nil {
+			if fields[0] != headerFirst {
+				continue
+			}
+			out.columns = make(map[string]int, len(fields))
+			for i, name := range fields {
+				out.columns[name] = i
+			}
+			continue
+		}
+		if footerFirst != "" && fields[0] == footerFirst {
+			break
+		}
+		out.records = append(out.records, billRow{lineNo: lineNo, fields: fields})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if out.columns == nil {
+		return nil, fmt.Errorf("%w: header not found", ErrInvalidBill)
+	}
+	return out, nil
+}
@@ -0,0 +1,82 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gamelink/internal/model"
+)
+
+const wechatBillSample = "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\n" +
+	"`2024-05-01 10:00:00,`wx123,`1900000001,`0,`,`4200000001,`PAY001,`oUser,`JSAPI,`SUCCESS,`CMB_DEBIT,`CNY,`99.00,`0.00,`0,`0,`0.00,`0.00,`,`,`陪玩订单,`,`0.59,`0.60%,`99.00,`0.00,`\n" +
+	"`2024-05-01 12:00:00,`wx123,`1900000001,`0,`,`4200000001,`PAY001,`oUser,`JSAPI,`REFUND,`CMB_DEBIT,`CNY,`0.00,`0.00,`5030000001,`RF001,`30.00,`0.00,`ORIGINAL,`SUCCESS,`陪玩订单,`,`-0.18,`0.60%,`0.00,`30.00,`\n" +
+	"`2024-05-01 13:00:00,`wx123,`1900000001,`0,`,`4200000002,`PAY002,`oUser,`JSAPI,`REVOKED,`CMB_DEBIT,`CNY,`0.00,`0.00,`0,`0,`0.00,`0.00,`,`,`陪玩订单,`,`0.00,`0.60%,`10.00,`0.00,`\n" +
+	"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\n" +
+	"`2,`99.00,`30.00,`0.00,`0.41,`99.00,`30.00\n"
+
+const alipayBillSample = "#支付宝业务明细查询\n" +
+	"#账号：[20880000000000000156]\n" +
+	"#起始日期：[2024年05月01日 00:00:00]   终止日期：[2024年05月02日 00:00:00]\n" +
+	"#-----------------------------------------业务明细列表----------------------------------------\n" +
+	"支付宝交易号,商户订单号,业务类型,商品名称,创建时间,完成时间,门店编号,门店名称,操作员,终端号,对方账户,订单金额（元）,商家实收（元）,支付宝红包（元）,集分宝（元）,支付宝优惠（元）,商家优惠（元）,券核销金额（元）,券名称,商家红包消费金额（元）,卡消费金额（元）,退款批次号/请求号,服务费（元）,分润（元）,备注\n" +
+	"2024050122001400001\t,PAY101\t,交易,陪玩订单,2024-05-01 09:00:00,2024-05-01 09:00:05,,,,,buyer@example.com,50.00,50.00,0.00,0.00,0.00,0.00,0.00,,0.00,0.00,,-0.30,0.00,\n" +
+	"2024050122001400001\t,PAY101\t,退款,陪玩订单,2024-05-01 11:00:00,2024-05-01 11:00:01,,,,,buyer@example.com,-20.00,-20.00,0.00,0.00,0.00,0.00,0.00,,0.00,0.00,RF101\t,0.12,0.00,\n" +
+	"#-----------------------------------------业务明细列表结束------------------------------------\n" +
+	"#交易合计：1笔，商家实收：50.00元，商家优惠：0.00元\n"
+
+func TestParseWeChatBill(t *testing.T) {
+	lines, err := ParseBill(model.PaymentMethodWeChat, strings.NewReader("\xef\xbb\xbf"+wechatBillSample))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines (REVOKED skipped), got %d: %+v", len(lines), lines)
+	}
+	pay, refund := lines[0], lines[1]
+	if pay.Kind != BillLinePayment || pay.ProviderTradeNo != "4200000001" || pay.OutTradeNo != "PAY001" || pay.AmountCents != 9900 {
+		t.Fatalf("unexpected payment line: %+v", pay)
+	}
+	if pay.LineNo != 2 || pay.OccurredAt.Hour() != 10 {
+		t.Fatalf("unexpected line metadata: %+v", pay)
+	}
+	if refund.Kind != BillLineRefund || refund.ProviderRefundNo != "5030000001" || refund.OutRefundNo != "RF001" || refund.AmountCents != 3000 {
+		t.Fatalf("unexpected refund line: %+v", refund)
+	}
+}
+
+func TestParseAlipayBill(t *testing.T) {
+	lines, err := ParseBill(model.PaymentMethodAlipay, strings.NewReader(alipayBillSample))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[0].Kind != BillLinePayment || lines[0].ProviderTradeNo != "2024050122001400001" || lines[0].OutTradeNo != "PAY101" || lines[0].AmountCents != 5000 {
+		t.Fatalf("unexpected payment line: %+v", lines[0])
+	}
+	if lines[1].Kind != BillLineRefund || lines[1].OutRefundNo != "RF101" || lines[1].AmountCents != 2000 {
+		t.Fatalf("unexpected refund line: %+v", lines[1])
+	}
+}
+
+func TestParseBill_Invalid(t *testing.T) {
+	cases := map[string]struct {
+		method model.PaymentMethod
+		body   string
+	}{
+		"unknown provider": {model.PaymentMethod("paypal"), wechatBillSample},
+		"no header":        {model.PaymentMethodWeChat, "foo,bar\n1,2\n"},
+		"missing column":   {model.PaymentMethodAlipay, "支付宝交易号,商户订单号\n1,2\n"},
+		"bad amount": {model.PaymentMethodWeChat, "交易时间,微信订单号,商户订单号,交易状态,订单金额\n" +
+			"`2024-05-01 10:00:00,`42,`PAY,`SUCCESS,`abc\n"},
+		"bad time": {model.PaymentMethodWeChat, "交易时间,微信订单号,商户订单号,交易状态,订单金额\n" +
+			"`yesterday,`42,`PAY,`SUCCESS,`1.00\n"},
+	}
+	for name, tc := range cases {
+		if _, err := ParseBill(tc.method, strings.NewReader(tc.body)); !errors.Is(err, ErrInvalidBill) {
+			t.Errorf("%s: expected ErrInvalidBill, got %v", name, err)
+		}
+	}
+}
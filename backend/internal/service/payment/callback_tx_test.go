@@ -0,0 +1,276 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	orderrepo "gamelink/internal/repository/order"
+	paymentrepo "gamelink/internal/repository/payment"
+)
+
+// txFixture 基于 SQLite + UnitOfWork 的回调处理测试环境。
+type txFixture struct {
+	db      *gorm.DB
+	svc     *PaymentService
+	sandbox *SandboxServer
+	order   *model.Order
+	payment *model.Payment
+}
+
+func newTxFixture(t *testing.T) *txFixture {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// 内存库每个连接相互独立，限制为单连接
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Order{}, &model.Payment{}, &model.PaymentCallback{}, &model.Refund{}, &model.OperationLog{}, &model.OrderStatusHistory{}))
+
+	ctx := context.Background()
+	orders := orderrepo.NewOrderRepository(db)
+	payments := paymentrepo.NewPaymentRepository(db)
+	svc := NewPaymentService(payments, orders)
+	svc.SetTxManager(common.NewUnitOfWork(db))
+	svc.SetRefundRepository(paymentrepo.NewRefundRepository(db))
+	sandbox := NewSandboxServer("tx-secret")
+	svc.SetGateway(NewInProcessSandboxGateway(model.PaymentMethodWeChat, sandbox))
+
+	order := &model.Order{UserID: 1, OrderNo: "ESC-TX", Status: model.OrderStatusPending, TotalPriceCents: 2500, Currency: model.CurrencyCNY}
+	require.NoError(t, orders.Create(ctx, order))
+	resp, err := svc.CreatePayment(ctx, 1, CreatePaymentRequest{OrderID: order.ID, Method: model.PaymentMethodWeChat})
+	require.NoError(t, err)
+	payment, err := payments.Get(ctx, resp.PaymentID)
+	require.NoError(t, err)
+
+	return &txFixture{db: db, svc: svc, sandbox: sandbox, order: order, payment: payment}
+}
+
+func (f *txFixture) notification(t *testing.T, amountCents int64) ([]byte, http.Header) {
+	t.Helper()
+	body := []byte(`{"method":"wechat","out_trade_no":"` + f.payment.OutTradeNo + `","trade_no":"4200000000099","status":"SUCCESS","amount_cents":` +
+		strconv.FormatInt(amountCents, 10) + `,"currency":"CNY"}`)
+	return body, SignSandboxPayload([]byte("tx-secret"), body, time.Now())
+}
+
+func (f *txFixture) reload(t *testing.T) (*model.Payment, *model.Order) {
+	t.Helper()
+	var p model.Payment
+	require.NoError(t, f.db.First(&p, f.payment.ID).Error)
+	var o model.Order
+	require.NoError(t, f.db.First(&o, f.order.ID).Error)
+	return &p, &o
+}
+
+func (f *txFixture) countLogs(t *testing.T, entity model.OperationEntityType, action model.OperationAction) int64 {
+	t.Helper()
+	var n int64
+	require.NoError(t, f.db.Model(&model.OperationLog{}).Where("entity_type = ? AND action = ?", entity, action).Count(&n).Error)
+	return n
+}
+
+func TestHandleNotification_Transactional_Dedupes(t *testing.T) {
+	f := newTxFixture(t)
+	ctx := context.Background()
+	body, header := f.notification(t, 2500)
+
+	require.NoError(t, f.svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, body))
+	require.NoError(t, f.svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, body))
+
+	p, o := f.reload(t)
+	assert.Equal(t, model.PaymentStatusPaid, p.Status)
+	assert.Equal(t, "4200000000099", p.ProviderTradeNo)
+	assert.Equal(t, model.OrderStatusConfirmed, o.Status)
+
+	var callbacks []model.PaymentCallback
+	require.NoError(t, f.db.Find(&callbacks).Error)
+	require.Len(t, callbacks, 1)
+	assert.Equal(t, model.PaymentCallbackStatusProcessed, callbacks[0].Status)
+	assert.Equal(t, callbackSourceNotify, callbacks[0].Source)
+	assert.NotNil(t, callbacks[0].ProcessedAt)
+
+	assert.Equal(t, int64(1), f.countLogs(t, model.OpEntityPayment, model.OpActionCapture))
+	assert.Equal(t, int64(1), f.countLogs(t, model.OpEntityOrder, model.OpActionConfirm))
+
+	var history []model.OrderStatusHistory
+	require.NoError(t, f.db.Where("order_id = ?", f.order.ID).Find(&history).Error)
+	require.Len(t, history, 1)
+	assert.Equal(t, model.OrderStatusPending, history[0].FromStatus)
+	assert.Equal(t, model.OrderStatusConfirmed, history[0].ToStatus)
+	assert.Equal(t, model.OrderActorSystem, history[0].ActorRole)
+}
+
+func TestHandleNotification_Transactional_Concurrent(t *testing.T) {
+	f := newTxFixture(t)
+	ctx := context.Background()
+	body, header := f.notification(t, 2500)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- f.svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, body)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	_, o := f.reload(t)
+	assert.Equal(t, model.OrderStatusConfirmed, o.Status)
+	assert.Equal(t, int64(1), f.countLogs(t, model.OpEntityOrder, model.OpActionConfirm))
+	assert.Equal(t, int64(1), f.countLogs(t, model.OpEntityPayment, model.OpActionCapture))
+}
+
+func TestHandleNotification_Transactional_RejectsAmountMismatch(t *testing.T) {
+	f := newTxFixture(t)
+	ctx := context.Background()
+	body, header := f.notification(t, 1)
+
+	err := f.svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, body)
+	assert.ErrorIs(t, err, ErrAmountMismatch)
+
+	p, o := f.reload(t)
+	assert.Equal(t, model.PaymentStatusPending, p.Status)
+	assert.Equal(t, model.OrderStatusPending, o.Status)
+
+	var cb model.PaymentCallback
+	require.NoError(t, f.db.First(&cb).Error)
+	assert.Equal(t, model.PaymentCallbackStatusRejected, cb.Status)
+	assert.Contains(t, cb.Error, "amount mismatch")
+	assert.Equal(t, int64(1), f.countLogs(t, model.OpEntityPayment, model.OpActionRejectCallback))
+
+	// 相同交易号的重放保持拒绝，不会再次写入审计
+	err = f.svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, body)
+	assert.ErrorIs(t, err, ErrCallbackRejected)
+	assert.Equal(t, int64(1), f.countLogs(t, model.OpEntityPayment, model.OpActionRejectCallback))
+}
+
+// failingOrdersTx 在事务内替换订单仓储，模拟订单更新失败。
+type failingOrdersTx struct {
+	inner *common.UnitOfWork
+	fail  bool
+}
+
+type failingOrderRepo struct{ repository.OrderRepository }
+
+func (failingOrderRepo) Update(context.Context, *model.Order) error {
+	return errors.New("order update failed")
+}
+
+func (m *failingOrdersTx) WithTx(ctx context.Context, fn func(r *common.Repos) error) error {
+	return m.inner.WithTx(ctx, func(r *common.Repos) error {
+		if m.fail {
+			r.Orders = failingOrderRepo{r.Orders}
+		}
+		return fn(r)
+	})
+}
+
+func TestHandleNotification_Transactional_RollsBackOnFailure(t *testing.T) {
+	f := newTxFixture(t)
+	ctx := context.Background()
+	tx := &failingOrdersTx{inner: common.NewUnitOfWork(f.db), fail: true}
+	f.svc.SetTxManager(tx)
+	body, header := f.notification(t, 2500)
+
+	err := f.svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, body)
+	require.Error(t, err)
+
+	p, o := f.reload(t)
+	assert.Equal(t, model.PaymentStatusPending, p.Status, "payment update must roll back with the order")
+	assert.Equal(t, model.OrderStatusPending, o.Status)
+	var n int64
+	require.NoError(t, f.db.Model(&model.PaymentCallback{}).Count(&n).Error)
+	assert.Zero(t, n, "inbox row must roll back so the provider retry is processed")
+
+	// 渠道重试时正常生效
+	tx.fail = false
+	require.NoError(t, f.svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, body))
+	p, o = f.reload(t)
+	assert.Equal(t, model.PaymentStatusPaid, p.Status)
+	assert.Equal(t, model.OrderStatusConfirmed, o.Status)
+}
+
+func TestHandlePaymentCallback_PaidAfterFailedIsRefunded(t *testing.T) {
+	f := newTxFixture(t)
+	ctx := context.Background()
+	require.NoError(t, f.db.Model(&model.Payment{}).Where("id = ?", f.payment.ID).Update("status", model.PaymentStatusFailed).Error)
+
+	require.NoError(t, f.svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
+		"payment_id":   float64(f.payment.ID),
+		"amount_cents": float64(2500),
+		"trade_no":     "4200000000100",
+	}))
+
+	p, o := f.reload(t)
+	assert.Equal(t, model.PaymentStatusPaid, p.Status)
+	assert.Equal(t, "4200000000100", p.ProviderTradeNo)
+	assert.Equal(t, model.OrderStatusPending, o.Status)
+	refunds, err := f.svc.ListOrderRefunds(ctx, f.order.ID)
+	require.NoError(t, err)
+	require.Len(t, refunds, 1)
+	assert.Equal(t, model.RefundSourceLatePayment, refunds[0].Source)
+	assert.Equal(t, int64(2500), refunds[0].AmountCents)
+}
+
+func TestGetPaymentStatus_QuerySyncGoesThroughInbox(t *testing.T) {
+	f := newTxFixture(t)
+	ctx := context.Background()
+	require.NoError(t, f.sandbox.Pay(ctx, f.payment.OutTradeNo))
+
+	status, err := f.svc.GetPaymentStatus(ctx, f.payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusPaid, status.Status)
+
+	var cb model.PaymentCallback
+	require.NoError(t, f.db.First(&cb).Error)
+	assert.Equal(t, callbackSourceQuery, cb.Source)
+	assert.Equal(t, model.PaymentCallbackStatusProcessed, cb.Status)
+	assert.Equal(t, int64(1), f.countLogs(t, model.OpEntityOrder, model.OpActionConfirm))
+}
+
+func TestHandleNotification_LatePaymentIsRefunded(t *testing.T) {
+	f := newTxFixture(t)
+	ctx := context.Background()
+	require.NoError(t, f.db.Model(&model.Order{}).Where("id = ?", f.order.ID).Update("status", model.OrderStatusCanceled).Error)
+	require.NoError(t, f.sandbox.Pay(ctx, f.payment.OutTradeNo))
+
+	body, header := f.notification(t, 2500)
+	require.NoError(t, f.svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, body))
+
+	p, o := f.reload(t)
+	assert.Equal(t, model.PaymentStatusPaid, p.Status)
+	assert.Equal(t, model.OrderStatusCanceled, o.Status)
+	refunds, err := f.svc.ListOrderRefunds(ctx, f.order.ID)
+	require.NoError(t, err)
+	require.Len(t, refunds, 1)
+	assert.Equal(t, model.RefundSourceLatePayment, refunds[0].Source)
+	assert.Equal(t, model.RefundStatusPending, refunds[0].Status)
+	assert.Equal(t, int64(2500), refunds[0].AmountCents)
+
+	n, err := f.svc.ProcessPendingRefunds(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	p, o = f.reload(t)
+	assert.Equal(t, model.PaymentStatusRefunded, p.Status)
+	assert.Equal(t, model.OrderStatusCanceled, o.Status, "late payment refunds do not touch the order")
+	assert.Zero(t, o.RefundAmountCents)
+}
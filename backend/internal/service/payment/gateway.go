@@ -0,0 +1,203 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+)
+
+var (
+	// ErrGatewayNotConfigured 支付渠道未配置
+	ErrGatewayNotConfigured = errors.New("payment gateway not configured")
+	// ErrInvalidSignature 回调签名校验失败
+	ErrInvalidSignature = errors.New("invalid callback signature")
+	// ErrAmountMismatch 渠道金额与本地支付金额不一致
+	ErrAmountMismatch = errors.New("payment amount mismatch")
+	// ErrCurrencyMismatch 渠道币种与本地支付币种不一致
+	ErrCurrencyMismatch = errors.New("payment currency mismatch")
+)
+
+// callbackMaxSkew 回调时间戳允许的最大偏差，用于防重放。
+const callbackMaxSkew = 5 * time.Minute
+
+// TradeState 渠道侧交易状态（已归一化）。
+type TradeState string
+
+// TradeState values normalise provider-specific trade states.
+const (
+	TradeStatePending  TradeState = "pending"
+	TradeStatePaid     TradeState = "paid"
+	TradeStateClosed   TradeState = "closed"
+	TradeStateFailed   TradeState = "failed"
+	TradeStateRefunded TradeState = "refunded"
+)
+
+// settlesPayment 报告该状态是否需要推进待支付记录（支付成功或关单/失败）。
+func (st TradeState) settlesPayment() bool {
+	switch st {
+	case TradeStatePaid, TradeStateClosed, TradeStateFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// PrepayRequest 统一下单参数。
+type PrepayRequest struct {
+	OutTradeNo  string
+	Description string
+	AmountCents int64
+	Currency    model.Currency
+	NotifyURL   string
+	ExpireAt    time.Time
+}
+
+// PrepayResult 统一下单结果。
+type PrepayResult struct {
+	// PayInfo 返回给客户端拉起支付的参数（二维码链接等）
+	PayInfo map[string]interface{}
+	// Raw 渠道原始响应，落库到 Payment.ProviderRaw
+	Raw json.RawMessage
+}
+
+// TradeResult 交易查询结果或已验签的回调通知。
+type TradeResult struct {
+	OutTradeNo      string
+	ProviderTradeNo string
+	State           TradeState
+	AmountCents     int64
+	Currency        model.Currency
+	PaidAt          *time.Time
+	Raw             json.RawMessage
+}
+
+// RefundRequest 渠道退款参数。
+//
+// 同一 OutRefundNo 重复提交在渠道侧是幂等的，失败重试必须沿用原单号。
+type RefundRequest struct {
+	OutTradeNo  string
+	OutRefundNo string
+	TotalCents  int64 // 原交易金额
+	AmountCents int64 // 本次退款金额
+	Currency    model.Currency
+	Reason      string
+}
+
+// RefundState 渠道侧退款状态（已归一化）。
+type RefundState string
+
+// RefundState values normalise provider-specific refund states.
+const (
+	RefundStateProcessing RefundState = "processing"
+	RefundStateSucceeded  RefundState = "succeeded"
+	RefundStateFailed     RefundState = "failed"
+)
+
+// RefundResult 渠道退款受理或查询结果。
+type RefundResult struct {
+	OutRefundNo      string
+	ProviderRefundNo string
+	State            RefundState
+	RefundedAt       *time.Time
+	Raw              json.RawMessage
+}
+
+// ProviderClient 渠道退款能力。
+type ProviderClient interface {
+	// Refund 提交（部分）退款，渠道可能同步成功，也可能返回处理中
+	Refund(ctx context.Context, req RefundRequest) (*RefundResult, error)
+	// QueryRefund 查询退款结果，用于异步确认处理中的退款
+	QueryRefund(ctx context.Context, outTradeNo, outRefundNo string) (*RefundResult, error)
+}
+
+// Gateway 支付渠道网关。
+//
+// 每个渠道（微信、支付宝、本地沙箱）实现下单、查询、关单、退款以及回调验签，
+// PaymentService 只依赖该接口，不感知具体渠道协议。
+type Gateway interface {
+	ProviderClient
+
+	// Method 返回该网关对应的支付方式
+	Method() model.PaymentMethod
+	// CreatePrepay 向渠道发起预下单
+	CreatePrepay(ctx context.Context, req PrepayRequest) (*PrepayResult, error)
+	// Query 按商户单号查询渠道侧交易状态
+	Query(ctx context.Context, outTradeNo string) (*TradeResult, error)
+	// Close 关闭未支付的交易
+	Close(ctx context.Context, outTradeNo string) error
+	// VerifyNotification 校验回调签名并解析通知内容
+	VerifyNotification(ctx context.Context, header http.Header, body []byte) (*TradeResult, error)
+	// NotificationAck 返回渠道要求的回调应答
+	NotificationAck(ok bool) (status int, contentType string, body []byte)
+}
+
+// checkTimestamp 校验回调时间戳（Unix 秒）是否在允许范围内。
+func checkTimestamp(value string, now time.Time) error {
+	ts, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > callbackMaxSkew {
+		return fmt.Errorf("%w: timestamp expired", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// formatYuan 将分转换为渠道要求的元字符串（两位小数）。
+func formatYuan(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// parseYuan 将元字符串解析为分，避免浮点误差。
+func parseYuan(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, errors.New("empty amount")
+	}
+	neg := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	if len(fracPart) > 2 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	for len(fracPart) < 2 {
+		fracPart += "0"
+	}
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	fen, err := strconv.ParseInt(fracPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	cents := yuan*100 + fen
+	if neg {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// currencyOrDefault 空币种按 CNY 处理。
+func currencyOrDefault(c model.Currency) model.Currency {
+	if c == "" {
+		return model.CurrencyCNY
+	}
+	return c
+}
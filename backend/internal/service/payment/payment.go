@@ -2,12 +2,18 @@ package payment
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
 	"time"
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	"gamelink/internal/service/orderstate"
 )
 
 var (
@@ -19,40 +25,113 @@ var (
 	ErrOrderAlreadyPaid = errors.New("order already paid")
 	// ErrInvalidOrderStatus 订单状态不正确
 	ErrInvalidOrderStatus = errors.New("invalid order status")
+	// ErrProviderMismatch 回调渠道与支付记录不一致
+	ErrProviderMismatch = errors.New("payment provider mismatch")
+	// ErrCallbackRejected 同一渠道交易号的回调此前已被拒绝
+	ErrCallbackRejected = errors.New("payment callback previously rejected")
 )
 
 // PaymentService 支付服务
 //
 // 功能：
-// 1. 创建支付
-// 2. 查询支付状态
-// 3. 取消支付
-// 4. 处理支付回调（Mock版本）
+// 1. 创建支付（向渠道预下单，等待回调确认）
+// 2. 查询支付状态（待支付时主动向渠道查单）
+// 3. 取消支付（渠道关单）
+// 4. 处理已验签的渠道回调
+// 5. 退款（支持多笔部分退款与异步确认，见 refund.go）
 type PaymentService struct {
-    payments repository.PaymentRepository
-    orders   repository.OrderRepository
-    providers map[model.PaymentMethod]ProviderClient
+	payments      repository.PaymentRepository
+	orders        repository.OrderRepository
+	gateways      map[model.PaymentMethod]Gateway
+	notifyBaseURL string
+	payTimeout    time.Duration
+	tx            TxManager
+	refunds       repository.RefundRepository
+	ledger        LedgerPoster
+	extensions    ExtensionSettler
 }
 
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
+}
+
+// SetTxManager 注入事务管理器，回调处理将在单个事务内完成。
+func (s *PaymentService) SetTxManager(tx TxManager) { s.tx = tx }
+
+// LedgerPoster 在支付事务内自动记账（由总账服务实现）。
+type LedgerPoster interface {
+	PostPaymentReceived(ctx context.Context, r *common.Repos, payment *model.Payment) error
+	PostRefundIssued(ctx context.Context, r *common.Repos, refund *model.Refund) error
+}
+
+// SetLedger 注入总账服务，收款与退款成功时与业务状态在同一事务内记账。
+func (s *PaymentService) SetLedger(l LedgerPoster) { s.ledger = l }
+
+// ExtensionSettler 订单加时补款（由订单加时服务实现）。
+type ExtensionSettler interface {
+	PayableExtension(ctx context.Context, order *model.Order, extensionID uint64) (*model.OrderExtension, error)
+	// SettleExtension 返回 false 表示加时申请已失效或订单已不在服务中，补款未生效
+	SettleExtension(ctx context.Context, r *common.Repos, payment *model.Payment, paidAt time.Time) (bool, error)
+}
+
+// SetExtensions 注入订单加时服务，支持服务中订单的加时补款。
+func (s *PaymentService) SetExtensions(e ExtensionSettler) { s.extensions = e }
+
+// defaultPayTimeout 预下单有效期。
+const defaultPayTimeout = 15 * time.Minute
+
 // NewPaymentService 创建支付服务
+//
+// 不注册任何渠道网关，需通过 SetGateway/SetGateways 显式接入真实渠道或沙箱。
 func NewPaymentService(
-    payments repository.PaymentRepository,
-    orders repository.OrderRepository,
+	payments repository.PaymentRepository,
+	orders repository.OrderRepository,
 ) *PaymentService {
-    return &PaymentService{
-        payments: payments,
-        orders:   orders,
-        providers: map[model.PaymentMethod]ProviderClient{
-            model.PaymentMethodWeChat: wechatProvider{},
-            model.PaymentMethodAlipay: alipayProvider{},
-        },
-    }
+	return &PaymentService{
+		payments:   payments,
+		orders:     orders,
+		gateways:   make(map[model.PaymentMethod]Gateway),
+		payTimeout: defaultPayTimeout,
+	}
+}
+
+// SetGateway 注册（或替换）某个支付方式的渠道网关。
+func (s *PaymentService) SetGateway(g Gateway) {
+	s.gateways[g.Method()] = g
+}
+
+// SetGateways 用给定网关替换全部已注册网关。
+func (s *PaymentService) SetGateways(gateways ...Gateway) {
+	s.gateways = make(map[model.PaymentMethod]Gateway, len(gateways))
+	for _, g := range gateways {
+		s.gateways[g.Method()] = g
+	}
+}
+
+// SetNotifyBaseURL 设置回调地址前缀，实际回调地址为 {base}/{method}。
+func (s *PaymentService) SetNotifyBaseURL(base string) {
+	s.notifyBaseURL = trimTrailingSlash(base)
+}
+
+// Gateway 返回支付方式对应的网关。
+func (s *PaymentService) Gateway(method model.PaymentMethod) (Gateway, bool) {
+	g, ok := s.gateways[method]
+	return g, ok
+}
+
+func (s *PaymentService) notifyURL(method model.PaymentMethod) string {
+	if s.notifyBaseURL == "" {
+		return ""
+	}
+	return s.notifyBaseURL + "/" + string(method)
 }
 
 // CreatePaymentRequest 创建支付请求
 type CreatePaymentRequest struct {
-	OrderID uint64              `json:"orderId" binding:"required"`
-	Method  model.PaymentMethod `json:"method" binding:"required,oneof=wechat alipay"`
+	OrderID     uint64              `json:"orderId" binding:"required"`
+	Method      model.PaymentMethod `json:"method" binding:"required,oneof=wechat alipay"`
+	ExtensionID *uint64             `json:"extensionId"` // 加时补款：陪玩师已同意的加时申请
 }
 
 // CreatePaymentResponse 创建支付响应
@@ -82,48 +161,108 @@ func (s *PaymentService) CreatePayment(ctx context.Context, userID uint64, req C
 		return nil, errors.New("unauthorized")
 	}
 
-	// 状态检查：只有 pending 状态可以支付
-	if order.Status != model.OrderStatusPending {
-		return nil, ErrInvalidOrderStatus
-	}
+	amount := order.TotalPriceCents
+	currency := currencyOrDefault(order.Currency)
+	if req.ExtensionID != nil {
+		// 加时补款：金额取自陪玩师已同意的加时申请，关联到同一订单
+		if s.extensions == nil {
+			return nil, ErrValidation
+		}
+		ext, err := s.extensions.PayableExtension(ctx, order, *req.ExtensionID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidOrderStatus, err)
+		}
+		amount = ext.AmountCents
+		currency = currencyOrDefault(ext.Currency)
+	} else {
+		// 状态检查：只有 pending 状态可以支付
+		if order.Status != model.OrderStatusPending {
+			return nil, ErrInvalidOrderStatus
+		}
 
-	// 检查是否已有支付记录
-	orderIDPtr := &req.OrderID
-	existingPayments, _, err := s.payments.List(ctx, repository.PaymentListOptions{
-		OrderID:  orderIDPtr,
-		Page:     1,
-		PageSize: 1,
-	})
-	if err == nil && len(existingPayments) > 0 {
-		// 检查是否已支付
-		if existingPayments[0].Status == model.PaymentStatusPaid {
-			return nil, ErrOrderAlreadyPaid
+		// 检查是否已有支付记录
+		orderIDPtr := &req.OrderID
+		existingPayments, _, err := s.payments.List(ctx, repository.PaymentListOptions{
+			OrderID:  orderIDPtr,
+			Page:     1,
+			PageSize: 1,
+		})
+		if err == nil && len(existingPayments) > 0 {
+			// 检查是否已支付
+			if existingPayments[0].Status == model.PaymentStatusPaid {
+				return nil, ErrOrderAlreadyPaid
+			}
 		}
 	}
 
+	if amount <= 0 {
+		return nil, ErrValidation
+	}
+
+	gateway, ok := s.gateways[req.Method]
+	if !ok {
+		return nil, ErrGatewayNotConfigured
+	}
+
 	// 创建支付记录
 	payment := &model.Payment{
 		OrderID:     req.OrderID,
+		ExtensionID: req.ExtensionID,
 		UserID:      userID,
 		Method:      req.Method,
-		AmountCents: order.TotalPriceCents,
-		Currency:    order.Currency,
+		AmountCents: amount,
+		Currency:    currency,
 		Status:      model.PaymentStatusPending,
+		OutTradeNo:  model.GeneratePaymentOutTradeNo(),
 	}
 
 	if err := s.payments.Create(ctx, payment); err != nil {
 		return nil, err
 	}
 
-	// Mock: 生成支付参数
-	payInfo := s.generateMockPayInfo(payment.ID, req.Method, order.TotalPriceCents)
+	// 向渠道预下单，支付结果以渠道回调（或主动查单）为准
+	description := order.Title
+	if description == "" {
+		description = "GameLink " + order.OrderNo
+	}
+	if req.ExtensionID != nil {
+		description += " 加时"
+	}
+	prepay, err := gateway.CreatePrepay(ctx, PrepayRequest{
+		OutTradeNo:  payment.OutTradeNo,
+		Description: description,
+		AmountCents: payment.AmountCents,
+		Currency:    currency,
+		NotifyURL:   s.notifyURL(req.Method),
+		ExpireAt:    time.Now().Add(s.payTimeout),
+	})
+	if err != nil {
+		payment.Status = model.PaymentStatusFailed
+		if uerr := s.payments.Update(ctx, payment); uerr != nil {
+			slog.Warn("mark payment failed", slog.Uint64("payment_id", payment.ID), slog.String("error", uerr.Error()))
+		}
+		return nil, fmt.Errorf("create prepay: %w", err)
+	}
 
-	// Mock: 自动标记为已支付（仅用于测试）
-	// 在生产环境中，这里应该等待支付回调
-	if err := s.mockPaymentSuccess(ctx, payment.ID, order); err != nil {
+	payment.ProviderRaw = prepay.Raw
+	if err := s.payments.Update(ctx, payment); err != nil {
 		return nil, err
 	}
 
+	payInfo := map[string]interface{}{
+		"paymentId":   payment.ID,
+		"outTradeNo":  payment.OutTradeNo,
+		"method":      string(req.Method),
+		"amountCents": payment.AmountCents,
+		"currency":    string(currency),
+	}
+	for k, v := range prepay.PayInfo {
+		payInfo[k] = v
+	}
+
 	return &CreatePaymentResponse{
 		PaymentID: payment.ID,
 		PayInfo:   payInfo,
@@ -131,12 +270,24 @@ func (s *PaymentService) CreatePayment(ctx context.Context, userID uint64, req C
 }
 
 // GetPaymentStatus 查询支付状态
+//
+// 待支付状态下会主动向渠道查单，以弥补回调丢失。
 func (s *PaymentService) GetPaymentStatus(ctx context.Context, paymentID uint64) (*PaymentStatusResponse, error) {
 	payment, err := s.payments.Get(ctx, paymentID)
 	if err != nil {
 		return nil, err
 	}
 
+	if payment.Status == model.PaymentStatusPending && payment.OutTradeNo != "" {
+		synced, err := s.syncWithGateway(ctx, payment)
+		if err != nil {
+			slog.Warn("payment query failed", slog.Uint64("payment_id", payment.ID), slog.String("error", err.Error()))
+		}
+		if synced != nil {
+			payment = synced
+		}
+	}
+
 	return &PaymentStatusResponse{
 		PaymentID: payment.ID,
 		OrderID:   payment.OrderID,
@@ -162,181 +313,421 @@ func (s *PaymentService) CancelPayment(ctx context.Context, userID uint64, payme
 		return errors.New("cannot cancel payment")
 	}
 
+	// 渠道关单，避免用户取消后仍可完成付款
+	if gateway, ok := s.gateways[payment.Method]; ok && payment.OutTradeNo != "" {
+		if err := gateway.Close(ctx, payment.OutTradeNo); err != nil {
+			return fmt.Errorf("close trade: %w", err)
+		}
+	}
+
 	// 更新支付状态
 	payment.Status = model.PaymentStatusFailed
 
 	return s.payments.Update(ctx, payment)
 }
 
-// generateMockPayInfo 生成 Mock 支付参数
-func (s *PaymentService) generateMockPayInfo(paymentID uint64, method model.PaymentMethod, amountCents int64) map[string]interface{} {
-	payInfo := map[string]interface{}{
-		"paymentId":   paymentID,
-		"method":      string(method),
-		"amountCents": amountCents,
-		"currency":    "CNY",
-		"timestamp":   time.Now().Unix(),
+// HandleNotification 处理渠道回调：验签后在事务中按渠道交易号去重并推进支付与订单状态。
+func (s *PaymentService) HandleNotification(ctx context.Context, method model.PaymentMethod, header http.Header, body []byte) error {
+	gateway, ok := s.gateways[method]
+	if !ok {
+		return ErrGatewayNotConfigured
 	}
-
-	// 根据支付方式生成不同的参数
-	switch method {
-	case model.PaymentMethodWeChat:
-		payInfo["prepay_id"] = fmt.Sprintf("wx_mock_%d", paymentID)
-		payInfo["code_url"] = fmt.Sprintf("weixin://wxpay/bizpayurl?pr=mock%d", paymentID)
-	case model.PaymentMethodAlipay:
-		payInfo["trade_no"] = fmt.Sprintf("ali_mock_%d", paymentID)
-		payInfo["qr_code"] = fmt.Sprintf("https://qr.alipay.com/mock%d", paymentID)
+	result, err := gateway.VerifyNotification(ctx, header, body)
+	if err != nil {
+		return err
 	}
+	_, err = s.processTradeResult(ctx, method, callbackSourceNotify, result)
+	return err
+}
 
-	return payInfo
+// NotificationAck 返回指定渠道要求的回调应答。
+func (s *PaymentService) NotificationAck(method model.PaymentMethod, ok bool) (int, string, []byte) {
+	if gateway, found := s.gateways[method]; found {
+		return gateway.NotificationAck(ok)
+	}
+	if ok {
+		return http.StatusOK, "text/plain; charset=utf-8", []byte("success")
+	}
+	return http.StatusBadRequest, "text/plain; charset=utf-8", []byte("fail")
 }
 
-// mockPaymentSuccess Mock 支付成功（仅用于测试）
-func (s *PaymentService) mockPaymentSuccess(ctx context.Context, paymentID uint64, order *model.Order) error {
-	// 更新支付状态
-	payment, err := s.payments.Get(ctx, paymentID)
+// syncWithGateway 主动查单并应用渠道侧状态，返回最新的支付记录。
+func (s *PaymentService) syncWithGateway(ctx context.Context, payment *model.Payment) (*model.Payment, error) {
+	gateway, ok := s.gateways[payment.Method]
+	if !ok {
+		return nil, ErrGatewayNotConfigured
+	}
+	result, err := gateway.Query(ctx, payment.OutTradeNo)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return s.processTradeResult(ctx, payment.Method, callbackSourceQuery, result)
+}
 
-	now := time.Now()
-	payment.Status = model.PaymentStatusPaid
-	payment.PaidAt = &now
-	payment.ProviderTradeNo = fmt.Sprintf("mock_trade_%d", paymentID)
+// 交易结果来源
+const (
+	callbackSourceNotify = "notify"
+	callbackSourceQuery  = "query"
+)
 
-	if err := s.payments.Update(ctx, payment); err != nil {
-		return err
+// processTradeResult 在单个事务内应用渠道交易结果：
+// 收件箱去重 → 渠道/金额/币种校验 → 更新支付与订单 → 写入审计日志。
+//
+// 校验失败的结果会以 rejected 状态落库并提交（保留审计记录），同时返回校验错误；
+// 重复投递直接返回成功，保证同一渠道交易号最多生效一次。
+func (s *PaymentService) processTradeResult(ctx context.Context, method model.PaymentMethod, source string, result *TradeResult) (*model.Payment, error) {
+	if !result.State.settlesPayment() {
+		return nil, nil
 	}
 
-	// 更新订单状态
-	order.Status = model.OrderStatusConfirmed
-	if err := s.orders.Update(ctx, order); err != nil {
-		return err
+	var (
+		current   *model.Payment
+		rejectErr error
+	)
+	err := s.withTx(ctx, func(r *common.Repos) error {
+		payment, err := r.Payments.GetByOutTradeNo(ctx, result.OutTradeNo)
+		if err != nil {
+			return err
+		}
+		current = payment
+
+		cb := &model.PaymentCallback{
+			Provider:        method,
+			ProviderTradeNo: callbackKey(result),
+			OutTradeNo:      result.OutTradeNo,
+			PaymentID:       &payment.ID,
+			TradeState:      string(result.State),
+			AmountCents:     result.AmountCents,
+			Currency:        result.Currency,
+			Source:          source,
+			Status:          model.PaymentCallbackStatusReceived,
+			Payload:         result.Raw,
+		}
+		if r.Callbacks != nil {
+			created, err := r.Callbacks.CreateIfAbsent(ctx, cb)
+			if err != nil {
+				return err
+			}
+			if !created {
+				existing, err := r.Callbacks.GetByProviderTradeNo(ctx, method, cb.ProviderTradeNo)
+				if err != nil {
+					return err
+				}
+				if existing.Status == model.PaymentCallbackStatusRejected {
+					rejectErr = fmt.Errorf("%w: %s", ErrCallbackRejected, existing.Error)
+				}
+				return nil
+			}
+		}
+
+		if verr := validateTradeResult(payment, method, result); verr != nil {
+			rejectErr = verr
+			s.audit(ctx, r, model.OpEntityPayment, payment.ID, model.OpActionRejectCallback, callbackMeta(cb, verr))
+			return s.finishCallback(ctx, r, cb, model.PaymentCallbackStatusRejected, verr.Error())
+		}
+
+		if payment.Status != model.PaymentStatusPending {
+			// 已由其他途径处理（如主动查单），只记录不重复生效
+			return s.finishCallback(ctx, r, cb, model.PaymentCallbackStatusIgnored, "payment status is "+string(payment.Status))
+		}
+
+		switch result.State {
+		case TradeStatePaid:
+			paidAt := time.Now()
+			if result.PaidAt != nil {
+				paidAt = *result.PaidAt
+			}
+			if err := s.markPaid(ctx, r, payment, result.ProviderTradeNo, paidAt, result.Raw); err != nil {
+				return err
+			}
+			s.audit(ctx, r, model.OpEntityPayment, payment.ID, model.OpActionCapture, callbackMeta(cb, nil))
+		default:
+			payment.Status = model.PaymentStatusFailed
+			if len(result.Raw) > 0 {
+				payment.ProviderRaw = result.Raw
+			}
+			if err := r.Payments.Update(ctx, payment); err != nil {
+				return err
+			}
+			s.audit(ctx, r, model.OpEntityPayment, payment.ID, model.OpActionUpdateStatus, callbackMeta(cb, nil))
+		}
+		return s.finishCallback(ctx, r, cb, model.PaymentCallbackStatusProcessed, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return current, rejectErr
+}
+
+// callbackAmountCents 将回调中的金额统一为分；JSON 解码的数字为 float64，须为整数。
+func callbackAmountCents(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, false
+		}
+		return int64(n), true
+	case json.Number:
+		cents, err := n.Int64()
+		return cents, err == nil
 	}
+	return 0, false
+}
 
+// validateTradeResult 校验渠道交易结果与本地支付记录是否一致。
+func validateTradeResult(payment *model.Payment, method model.PaymentMethod, result *TradeResult) error {
+	if payment.Method != method {
+		return fmt.Errorf("%w: expected %s, got %s", ErrProviderMismatch, payment.Method, method)
+	}
+	if result.State != TradeStatePaid {
+		return nil
+	}
+	if result.AmountCents != payment.AmountCents {
+		return fmt.Errorf("%w: expected %d, got %d", ErrAmountMismatch, payment.AmountCents, result.AmountCents)
+	}
+	if result.Currency != "" && result.Currency != currencyOrDefault(payment.Currency) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrCurrencyMismatch, currencyOrDefault(payment.Currency), result.Currency)
+	}
 	return nil
 }
 
-// HandlePaymentCallback 处理支付回调
-//
-// 注意：这是一个简化版本，生产环境需要：
-// 1. 验证支付提供商签名
-// 2. 防止重复回调
-// 3. 使用事务确保数据一致性
-func (s *PaymentService) HandlePaymentCallback(ctx context.Context, provider string, data map[string]interface{}) error {
-	// 获取支付ID
-	paymentID, ok := data["payment_id"].(uint64)
-	if !ok {
-		// 尝试从其他字段获取
-		if id, ok := data["payment_id"].(float64); ok {
-			paymentID = uint64(id)
-		} else {
-			return errors.New("missing payment_id in callback data")
-		}
+// callbackKey 返回收件箱去重键：优先使用渠道交易号，缺失时（如未支付关单）退化为商户单号 + 状态。
+func callbackKey(result *TradeResult) string {
+	if result.ProviderTradeNo != "" {
+		return result.ProviderTradeNo
 	}
+	return "out:" + result.OutTradeNo + ":" + string(result.State)
+}
 
-	// 获取支付记录
-	payment, err := s.payments.Get(ctx, paymentID)
+func callbackMeta(cb *model.PaymentCallback, err error) map[string]any {
+	meta := map[string]any{
+		"provider":        cb.Provider,
+		"providerTradeNo": cb.ProviderTradeNo,
+		"tradeState":      cb.TradeState,
+		"amountCents":     cb.AmountCents,
+		"currency":        cb.Currency,
+		"source":          cb.Source,
+	}
 	if err != nil {
-		return err
+		meta["error"] = err.Error()
 	}
+	return meta
+}
 
-	// 验证支付状态：只处理待支付的订单
-	if payment.Status != model.PaymentStatusPending {
-		// 已经处理过，返回成功避免重复处理
+// finishCallback 更新收件箱记录的处理结果。
+func (s *PaymentService) finishCallback(ctx context.Context, r *common.Repos, cb *model.PaymentCallback, status model.PaymentCallbackStatus, reason string) error {
+	if r.Callbacks == nil || cb.ID == 0 {
 		return nil
 	}
+	now := time.Now()
+	cb.Status = status
+	cb.Error = reason
+	cb.ProcessedAt = &now
+	return r.Callbacks.Update(ctx, cb)
+}
 
-	// 验证支付提供商
-	expectedProvider := string(payment.Method)
-	if provider != expectedProvider {
-		return fmt.Errorf("provider mismatch: expected %s, got %s", expectedProvider, provider)
+// markPaid 标记支付成功并确认订单（需在同一事务内调用）。
+func (s *PaymentService) markPaid(ctx context.Context, r *common.Repos, payment *model.Payment, providerTradeNo string, paidAt time.Time, raw json.RawMessage) error {
+	order, err := r.Orders.Get(ctx, payment.OrderID)
+	if err != nil {
+		return err
+	}
+	if err := s.capture(ctx, r, payment, providerTradeNo, paidAt, raw); err != nil {
+		return err
 	}
 
-	// 验证金额
-	if amountCents, ok := data["amount_cents"].(int64); ok {
-		if amountCents != payment.AmountCents {
-			return fmt.Errorf("amount mismatch: expected %d, got %d", payment.AmountCents, amountCents)
+	// 加时补款：延长服务时间并累加订单金额与抽成，不改变订单状态；加时已失效时整笔退回
+	if payment.ExtensionID != nil {
+		if s.extensions == nil {
+			return fmt.Errorf("settle extension payment %d: extensions are not configured", payment.ID)
 		}
+		settled, err := s.extensions.SettleExtension(ctx, r, payment, paidAt)
+		if err != nil || settled {
+			return err
+		}
+		return s.refundLatePayment(ctx, r, order, payment)
 	}
 
-	// 获取订单
-	order, err := s.orders.Get(ctx, payment.OrderID)
-	if err != nil {
+	// 订单已关闭或已由其他支付确认：款项已到账，整笔退回
+	if order.Status != model.OrderStatusPending {
+		return s.refundLatePayment(ctx, r, order, payment)
+	}
+
+	// 更新订单状态为已确认
+	if err := orderstate.Transit(ctx, r.Orders, r.OrderHistory, order, model.OrderStatusConfirmed, orderstate.Change{Role: model.OrderActorSystem, Reason: "支付成功", At: paidAt}); err != nil {
 		return err
 	}
+	s.audit(ctx, r, model.OpEntityOrder, order.ID, model.OpActionConfirm, map[string]any{
+		"paymentId":       payment.ID,
+		"providerTradeNo": providerTradeNo,
+	})
+	return nil
+}
 
-	// 更新支付状态
-	now := time.Now()
+// capture 将支付记录标记为已到账并记入总账。
+func (s *PaymentService) capture(ctx context.Context, r *common.Repos, payment *model.Payment, providerTradeNo string, paidAt time.Time, raw json.RawMessage) error {
 	payment.Status = model.PaymentStatusPaid
-	payment.PaidAt = &now
-
-	// 设置第三方交易号
-	if tradeNo, ok := data["trade_no"].(string); ok {
-		payment.ProviderTradeNo = tradeNo
-	} else {
-		payment.ProviderTradeNo = fmt.Sprintf("%s_%d_%d", provider, paymentID, now.Unix())
+	payment.PaidAt = &paidAt
+	payment.ProviderTradeNo = providerTradeNo
+	if len(raw) > 0 {
+		payment.ProviderRaw = raw
 	}
-
-	if err := s.payments.Update(ctx, payment); err != nil {
+	if err := r.Payments.Update(ctx, payment); err != nil {
 		return err
 	}
+	if s.ledger != nil {
+		if err := s.ledger.PostPaymentReceived(ctx, r, payment); err != nil {
+			return fmt.Errorf("post ledger: %w", err)
+		}
+	}
+	return nil
+}
 
-	// 更新订单状态为已确认
-	order.Status = model.OrderStatusConfirmed
-	if err := s.orders.Update(ctx, order); err != nil {
+// refundFailedPayment 已判定失败的支付实际到账：记录到账后不再确认订单，整笔退回。
+func (s *PaymentService) refundFailedPayment(ctx context.Context, r *common.Repos, payment *model.Payment, providerTradeNo string, paidAt time.Time) error {
+	order, err := r.Orders.Get(ctx, payment.OrderID)
+	if err != nil {
+		return err
+	}
+	if err := s.capture(ctx, r, payment, providerTradeNo, paidAt, nil); err != nil {
 		return err
 	}
+	return s.refundLatePayment(ctx, r, order, payment)
+}
 
+// refundLatePayment 为晚到的支付（含加时已失效的补款）创建整笔退款单，由退款定时任务提交渠道。
+func (s *PaymentService) refundLatePayment(ctx context.Context, r *common.Repos, order *model.Order, payment *model.Payment) error {
+	if r.Refunds == nil {
+		return ErrRefundNotConfigured
+	}
+	reason := fmt.Sprintf("支付到账时订单状态为 %s，自动退回", order.Status)
+	if payment.ExtensionID != nil {
+		reason = fmt.Sprintf("加时补款到账时加时申请已失效（订单状态 %s），自动退回", order.Status)
+	}
+	refund := &model.Refund{
+		OrderID:     order.ID,
+		PaymentID:   &payment.ID,
+		Method:      payment.Method,
+		AmountCents: payment.AmountCents,
+		Currency:    currencyOrDefault(payment.Currency),
+		Reason:      reason,
+		Source:      model.RefundSourceLatePayment,
+		Status:      model.RefundStatusPending,
+		OutRefundNo: model.GenerateOutRefundNo(),
+	}
+	if err := r.Refunds.Create(ctx, refund); err != nil {
+		return fmt.Errorf("create late payment refund: %w", err)
+	}
+	slog.Warn("payment captured for closed order, refund scheduled",
+		slog.Uint64("order_id", order.ID), slog.Uint64("payment_id", payment.ID), slog.String("order_status", string(order.Status)))
+	s.audit(ctx, r, model.OpEntityPayment, payment.ID, model.OpActionRefund, refundMeta(refund))
 	return nil
 }
 
-// RefundPayment 退款
-//
-// 注意：这是一个简化版本，生产环境需要：
-// 1. 调用真实的支付提供商退款API
-// 2. 处理部分退款
-// 3. 处理退款失败重试
-func (s *PaymentService) RefundPayment(ctx context.Context, paymentID uint64, reason string) error {
-    payment, err := s.payments.Get(ctx, paymentID)
-    if err != nil {
-        return err
-    }
-
-	// 验证支付状态：只有已支付的订单可以退款
-	if payment.Status != model.PaymentStatusPaid {
-		return fmt.Errorf("payment status must be paid, current: %s", payment.Status)
-	}
-
-    client, ok := s.providers[payment.Method]
-    if !ok {
-        client = genericProvider{}
-    }
-    tradeNo, raw, refundedAt, err := client.Refund(ctx, payment, reason)
-    if err != nil {
-        return err
-    }
+// audit 在当前事务内追加审计日志（系统操作，无操作人）。
+func (s *PaymentService) audit(ctx context.Context, r *common.Repos, entity model.OperationEntityType, id uint64, action model.OperationAction, meta map[string]any) {
+	if r.OpLogs == nil {
+		return
+	}
+	raw, _ := json.Marshal(meta)
+	if err := r.OpLogs.Append(ctx, &model.OperationLog{
+		EntityType:   string(entity),
+		EntityID:     id,
+		Action:       string(action),
+		MetadataJSON: raw,
+	}); err != nil {
+		slog.Warn("append payment audit log failed", slog.Uint64("entity_id", id), slog.String("error", err.Error()))
+	}
+}
 
-	// 更新支付状态
-    payment.Status = model.PaymentStatusRefunded
-    payment.RefundedAt = &refundedAt
-    payment.ProviderTradeNo = tradeNo
-    payment.ProviderRaw = raw
+// withTx 在事务中执行 fn；未注入 TxManager 时（如单元测试）直接使用服务自身的仓储。
+func (s *PaymentService) withTx(ctx context.Context, fn func(r *common.Repos) error) error {
+	if s.tx != nil {
+		return s.tx.WithTx(ctx, fn)
+	}
+	return fn(&common.Repos{Payments: s.payments, Orders: s.orders, Refunds: s.refunds})
+}
 
-	if err := s.payments.Update(ctx, payment); err != nil {
-		return err
+// HandlePaymentCallback 处理已验签的支付回调数据
+//
+// 渠道原始回调请使用 HandleNotification（包含验签与去重）；本方法供内部可信调用方使用。
+func (s *PaymentService) HandlePaymentCallback(ctx context.Context, provider string, data map[string]interface{}) error {
+	// 获取支付ID
+	paymentID, ok := data["payment_id"].(uint64)
+	if !ok {
+		// 尝试从其他字段获取
+		if id, ok := data["payment_id"].(float64); ok {
+			paymentID = uint64(id)
+		} else {
+			return errors.New("missing payment_id in callback data")
+		}
+	}
+
+	amountCents, ok := callbackAmountCents(data["amount_cents"])
+	if !ok {
+		return errors.New("missing amount_cents in callback data")
 	}
 
-	// 更新订单状态
-    order, err := s.orders.Get(ctx, payment.OrderID)
-    if err != nil {
-        return err
-    }
+	return s.withTx(ctx, func(r *common.Repos) error {
+		payment, err := r.Payments.Get(ctx, paymentID)
+		if err != nil {
+			return err
+		}
 
-	order.Status = model.OrderStatusRefunded
-	order.RefundAmountCents = payment.AmountCents
-	order.RefundReason = reason
-    order.RefundedAt = &refundedAt
+		// 验证支付状态：待支付的正常入账；已判定失败（如用户取消）后实际到账的整笔退回
+		late := false
+		switch payment.Status {
+		case model.PaymentStatusPending:
+		case model.PaymentStatusFailed:
+			late = true
+		default:
+			// 已经处理过，返回成功避免重复处理
+			return nil
+		}
 
-    return s.orders.Update(ctx, order)
+		// 验证支付提供商
+		expectedProvider := string(payment.Method)
+		if provider != expectedProvider {
+			return fmt.Errorf("provider mismatch: expected %s, got %s", expectedProvider, provider)
+		}
+
+		// 验证金额
+		if amountCents != payment.AmountCents {
+			return fmt.Errorf("%w: expected %d, got %d", ErrAmountMismatch, payment.AmountCents, amountCents)
+		}
+
+		// 设置第三方交易号
+		now := time.Now()
+		tradeNo, ok := data["trade_no"].(string)
+		if !ok {
+			tradeNo = fmt.Sprintf("%s_%d_%d", provider, paymentID, now.Unix())
+		}
+
+		if late {
+			if err := s.refundFailedPayment(ctx, r, payment, tradeNo, now); err != nil {
+				return err
+			}
+			s.audit(ctx, r, model.OpEntityPayment, payment.ID, model.OpActionCapture, map[string]any{
+				"provider":        provider,
+				"providerTradeNo": tradeNo,
+				"source":          "internal",
+			})
+			return nil
+		}
+		if err := s.markPaid(ctx, r, payment, tradeNo, now, nil); err != nil {
+			return err
+		}
+		s.audit(ctx, r, model.OpEntityPayment, payment.ID, model.OpActionCapture, map[string]any{
+			"provider":        provider,
+			"providerTradeNo": tradeNo,
+			"source":          "internal",
+		})
+		return nil
+	})
 }
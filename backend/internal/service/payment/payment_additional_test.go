@@ -2,6 +2,7 @@ package payment
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -11,7 +12,7 @@ import (
 func TestCreatePayment_DetectsExistingPaidRecord(t *testing.T) {
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	order := &model.Order{
 		UserID:          1,
@@ -42,7 +43,7 @@ func TestCancelPayment_InvalidScenarios(t *testing.T) {
 	ctx := context.Background()
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	t.Run("unauthorized user", func(t *testing.T) {
 		payment := &model.Payment{
@@ -77,7 +78,7 @@ func TestHandlePaymentCallback_Errors(t *testing.T) {
 	ctx := context.Background()
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	order := &model.Order{
 		UserID:          1,
@@ -119,6 +120,24 @@ func TestHandlePaymentCallback_Errors(t *testing.T) {
 		}
 	})
 
+	t.Run("float amount mismatch", func(t *testing.T) {
+		err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
+			"payment_id":   float64(payment.ID),
+			"amount_cents": float64(payment.AmountCents - 1),
+		})
+		if !errors.Is(err, ErrAmountMismatch) {
+			t.Fatalf("expected amount mismatch error, got %v", err)
+		}
+	})
+
+	t.Run("missing amount", func(t *testing.T) {
+		if err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
+			"payment_id": float64(payment.ID),
+		}); err == nil {
+			t.Fatal("expected error for missing amount_cents")
+		}
+	})
+
 	t.Run("missing payment id", func(t *testing.T) {
 		if err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{}); err == nil {
 			t.Fatal("expected error for missing payment_id")
@@ -131,7 +150,8 @@ func TestHandlePaymentCallback_Errors(t *testing.T) {
 		_ = paymentRepo.Update(ctx, stored)
 
 		if err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
-			"payment_id": float64(payment.ID),
+			"payment_id":   float64(payment.ID),
+			"amount_cents": float64(payment.AmountCents),
 		}); err != nil {
 			t.Fatalf("expected nil error when payment already processed, got %v", err)
 		}
@@ -142,7 +162,7 @@ func TestRefundPayment_InvalidStatus(t *testing.T) {
 	ctx := context.Background()
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	order := &model.Order{
 		UserID:          1,
@@ -18,7 +18,7 @@ func TestCreatePayment_EdgeCases(t *testing.T) {
 	t.Run("创建支付成功", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		// 创建待支付订单
 		order := &model.Order{
@@ -45,7 +45,7 @@ func TestCreatePayment_EdgeCases(t *testing.T) {
 	t.Run("订单不存在应该失败", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		req := CreatePaymentRequest{
 			OrderID: 999,
@@ -62,7 +62,7 @@ func TestCreatePayment_EdgeCases(t *testing.T) {
 	t.Run("无权限支付他人订单", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		// 创建用户1的订单
 		order := &model.Order{
@@ -89,7 +89,7 @@ func TestCreatePayment_EdgeCases(t *testing.T) {
 	t.Run("非pending状态订单不能支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		// 创建已确认的订单
 		order := &model.Order{
@@ -115,7 +115,7 @@ func TestCreatePayment_EdgeCases(t *testing.T) {
 	t.Run("订单已支付不能重复支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		// 创建订单
 		order := &model.Order{
@@ -153,7 +153,7 @@ func TestCreatePayment_EdgeCases(t *testing.T) {
 	t.Run("支付金额为0", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		// 创建0元订单
 		order := &model.Order{
@@ -171,15 +171,15 @@ func TestCreatePayment_EdgeCases(t *testing.T) {
 
 		resp, err := svc.CreatePayment(ctx, 1, req)
 
-		// 当前实现允许0元支付，但实际业务可能需要验证
-		assert.NoError(t, err)
-		assert.NotNil(t, resp)
+		// 渠道不接受0元支付
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Nil(t, resp)
 	})
 
 	t.Run("支付金额为极大值", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		// 创建大额订单
 		order := &model.Order{
@@ -209,7 +209,7 @@ func TestGetPaymentStatus_EdgeCases(t *testing.T) {
 	t.Run("查询支付状态成功", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		paidAt := time.Now()
 		payment := &model.Payment{
@@ -234,7 +234,7 @@ func TestGetPaymentStatus_EdgeCases(t *testing.T) {
 	t.Run("查询不存在的支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		resp, err := svc.GetPaymentStatus(ctx, 999)
 
@@ -246,7 +246,7 @@ func TestGetPaymentStatus_EdgeCases(t *testing.T) {
 	t.Run("查询pending状态的支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		payment := &model.Payment{
 			OrderID:     1,
@@ -273,7 +273,7 @@ func TestCancelPayment_EdgeCases(t *testing.T) {
 	t.Run("取消pending状态的支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		payment := &model.Payment{
 			OrderID:     1,
@@ -296,7 +296,7 @@ func TestCancelPayment_EdgeCases(t *testing.T) {
 	t.Run("无权限取消他人支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		payment := &model.Payment{
 			OrderID:     1,
@@ -317,7 +317,7 @@ func TestCancelPayment_EdgeCases(t *testing.T) {
 	t.Run("不能取消已支付的支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		paidAt := time.Now()
 		payment := &model.Payment{
@@ -339,7 +339,7 @@ func TestCancelPayment_EdgeCases(t *testing.T) {
 	t.Run("取消不存在的支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		err := svc.CancelPayment(ctx, 1, 999)
 
@@ -355,7 +355,7 @@ func TestHandlePaymentCallback_EdgeCases(t *testing.T) {
 	t.Run("成功处理支付回调", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		// 创建订单和支付
 		order := &model.Order{
@@ -376,9 +376,10 @@ func TestHandlePaymentCallback_EdgeCases(t *testing.T) {
 
 		// 模拟支付回调
 		callbackData := map[string]interface{}{
-			"payment_id": float64(payment.ID),
-			"status":     "success",
-			"trade_no":   "wx_trade_123",
+			"payment_id":   float64(payment.ID),
+			"amount_cents": float64(10000),
+			"status":       "success",
+			"trade_no":     "wx_trade_123",
 		}
 
 		err := svc.HandlePaymentCallback(ctx, "wechat", callbackData)
@@ -394,7 +395,7 @@ func TestHandlePaymentCallback_EdgeCases(t *testing.T) {
 	t.Run("重复回调应该幂等", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		// 创建已支付的支付
 		order := &model.Order{
@@ -417,8 +418,9 @@ func TestHandlePaymentCallback_EdgeCases(t *testing.T) {
 
 		// 重复回调
 		callbackData := map[string]interface{}{
-			"payment_id": float64(payment.ID),
-			"status":     "success",
+			"payment_id":   float64(payment.ID),
+			"amount_cents": float64(10000),
+			"status":       "success",
 		}
 
 		err := svc.HandlePaymentCallback(ctx, "wechat", callbackData)
@@ -430,7 +432,7 @@ func TestHandlePaymentCallback_EdgeCases(t *testing.T) {
 	t.Run("缺少payment_id应该失败", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		callbackData := map[string]interface{}{
 			"status": "success",
@@ -445,11 +447,12 @@ func TestHandlePaymentCallback_EdgeCases(t *testing.T) {
 	t.Run("支付不存在应该失败", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		callbackData := map[string]interface{}{
-			"payment_id": float64(999),
-			"status":     "success",
+			"payment_id":   float64(999),
+			"amount_cents": float64(10000),
+			"status":       "success",
 		}
 
 		err := svc.HandlePaymentCallback(ctx, "wechat", callbackData)
@@ -461,7 +464,7 @@ func TestHandlePaymentCallback_EdgeCases(t *testing.T) {
 	t.Run("支付方式不匹配应该失败", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		payment := &model.Payment{
 			OrderID:     1,
@@ -473,8 +476,9 @@ func TestHandlePaymentCallback_EdgeCases(t *testing.T) {
 		paymentRepo.Create(ctx, payment)
 
 		callbackData := map[string]interface{}{
-			"payment_id": float64(payment.ID),
-			"status":     "success",
+			"payment_id":   float64(payment.ID),
+			"amount_cents": float64(10000),
+			"status":       "success",
 		}
 
 		// 使用alipay回调wechat支付
@@ -492,7 +496,7 @@ func TestPaymentMethods(t *testing.T) {
 	t.Run("微信支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{
 			UserID:          1,
@@ -511,14 +515,14 @@ func TestPaymentMethods(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.NotNil(t, resp)
-		assert.Contains(t, resp.PayInfo, "prepay_id")
+		assert.Contains(t, resp.PayInfo, "outTradeNo")
 		assert.Contains(t, resp.PayInfo, "code_url")
 	})
 
 	t.Run("支付宝支付", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{
 			UserID:          1,
@@ -2,7 +2,6 @@ package payment
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
@@ -13,6 +12,7 @@ import (
 
 	"gamelink/internal/model"
 	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
 )
 
 func TestPaymentService_CreatePayment_ErrorBranches(t *testing.T) {
@@ -24,7 +24,7 @@ func TestPaymentService_CreatePayment_ErrorBranches(t *testing.T) {
 			return nil, 0, errors.New("db unavailable")
 		}
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{
 			UserID:          7,
@@ -49,7 +49,7 @@ func TestPaymentService_CreatePayment_ErrorBranches(t *testing.T) {
 			return errors.New("insert failed")
 		}
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{
 			UserID:          9,
@@ -72,7 +72,7 @@ func TestPaymentService_CreatePayment_ErrorBranches(t *testing.T) {
 	t.Run("existing pending payment allows retry", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{
 			UserID:          10,
@@ -100,13 +100,11 @@ func TestPaymentService_CreatePayment_ErrorBranches(t *testing.T) {
 		assert.Greater(t, resp.PaymentID, existing.ID)
 	})
 
-	t.Run("mock payment success failure surfaces", func(t *testing.T) {
+	t.Run("prepay failure marks payment failed", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
-		paymentRepo.getHook = func(ctx context.Context, id uint64) (*model.Payment, error) {
-			return nil, errors.New("mock success failed")
-		}
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
+		svc.SetGateway(failingProvider{Gateway: svc.gateways[model.PaymentMethodAlipay]})
 
 		order := &model.Order{
 			UserID:          11,
@@ -123,29 +121,28 @@ func TestPaymentService_CreatePayment_ErrorBranches(t *testing.T) {
 
 		require.Error(t, err)
 		assert.Nil(t, resp)
-		assert.Contains(t, err.Error(), "mock success failed")
+		assert.Contains(t, err.Error(), "provider failure")
+		stored, _ := paymentRepo.Get(ctx, 1)
+		assert.Equal(t, model.PaymentStatusFailed, stored.Status)
 	})
 }
 
-func TestPaymentService_mockPaymentSuccess_ErrorScenarios(t *testing.T) {
+func TestPaymentService_markPaid_ErrorScenarios(t *testing.T) {
 	ctx := context.Background()
 
-	t.Run("payment get error", func(t *testing.T) {
+	t.Run("order get error", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
-		paymentRepo.getHook = func(ctx context.Context, id uint64) (*model.Payment, error) {
-			return nil, repository.ErrNotFound
-		}
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
-		err := svc.mockPaymentSuccess(ctx, 1, &model.Order{})
+		err := svc.markPaid(ctx, &common.Repos{Payments: paymentRepo, Orders: orderRepo}, &model.Payment{OrderID: 404}, "trade", time.Now(), nil)
 		assert.Equal(t, repository.ErrNotFound, err)
 	})
 
 	t.Run("payment update error", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{Status: model.OrderStatusPending}
 		require.NoError(t, orderRepo.Create(ctx, order))
@@ -157,7 +154,7 @@ func TestPaymentService_mockPaymentSuccess_ErrorScenarios(t *testing.T) {
 			return errors.New("update payment failed")
 		}
 
-		err := svc.mockPaymentSuccess(ctx, payment.ID, order)
+		err := svc.markPaid(ctx, &common.Repos{Payments: paymentRepo, Orders: orderRepo}, payment, "trade", time.Now(), nil)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "update payment failed")
 	})
@@ -165,7 +162,7 @@ func TestPaymentService_mockPaymentSuccess_ErrorScenarios(t *testing.T) {
 	t.Run("order update error", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{Status: model.OrderStatusPending}
 		require.NoError(t, orderRepo.Create(ctx, order))
@@ -177,7 +174,7 @@ func TestPaymentService_mockPaymentSuccess_ErrorScenarios(t *testing.T) {
 			return errors.New("update order failed")
 		}
 
-		err := svc.mockPaymentSuccess(ctx, payment.ID, order)
+		err := svc.markPaid(ctx, &common.Repos{Payments: paymentRepo, Orders: orderRepo}, payment, "trade", time.Now(), nil)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "update order failed")
 	})
@@ -189,7 +186,7 @@ func TestPaymentService_HandlePaymentCallback_CompletesBranches(t *testing.T) {
 	baseOrder := func(status model.OrderStatus) (*mockPaymentRepository, *mockOrderRepository, *PaymentService, *model.Order, *model.Payment) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{
 			UserID:          42,
@@ -214,7 +211,8 @@ func TestPaymentService_HandlePaymentCallback_CompletesBranches(t *testing.T) {
 		paymentRepo, orderRepo, svc, order, payment := baseOrder(model.OrderStatusPending)
 
 		err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
-			"payment_id": payment.ID,
+			"payment_id":   payment.ID,
+			"amount_cents": payment.AmountCents,
 		})
 		require.NoError(t, err)
 
@@ -246,10 +244,11 @@ func TestPaymentService_HandlePaymentCallback_CompletesBranches(t *testing.T) {
 	t.Run("payment retrieval failure", func(t *testing.T) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
-			"payment_id": uint64(999),
+			"payment_id":   uint64(999),
+			"amount_cents": int64(2000),
 		})
 		require.Error(t, err)
 		assert.Equal(t, repository.ErrNotFound, err)
@@ -262,7 +261,8 @@ func TestPaymentService_HandlePaymentCallback_CompletesBranches(t *testing.T) {
 		}
 
 		err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
-			"payment_id": payment.ID,
+			"payment_id":   payment.ID,
+			"amount_cents": payment.AmountCents,
 		})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "persist payment error")
@@ -275,7 +275,8 @@ func TestPaymentService_HandlePaymentCallback_CompletesBranches(t *testing.T) {
 		}
 
 		err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
-			"payment_id": payment.ID,
+			"payment_id":   payment.ID,
+			"amount_cents": payment.AmountCents,
 		})
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "persist order error")
@@ -288,7 +289,8 @@ func TestPaymentService_HandlePaymentCallback_CompletesBranches(t *testing.T) {
 		}
 
 		err := svc.HandlePaymentCallback(ctx, "wechat", map[string]interface{}{
-			"payment_id": payment.ID,
+			"payment_id":   payment.ID,
+			"amount_cents": payment.AmountCents,
 		})
 		require.Error(t, err)
 		assert.Equal(t, repository.ErrNotFound, err)
@@ -301,7 +303,7 @@ func TestPaymentService_RefundPayment_FullCoverage(t *testing.T) {
 	newSetup := func(method model.PaymentMethod) (*mockPaymentRepository, *mockOrderRepository, *PaymentService, *model.Payment, *model.Order) {
 		paymentRepo := newMockPaymentRepository()
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc, sandbox := newSandboxPaymentService(paymentRepo, orderRepo)
 
 		order := &model.Order{
 			UserID:          88,
@@ -317,9 +319,16 @@ func TestPaymentService_RefundPayment_FullCoverage(t *testing.T) {
 			Method:      method,
 			AmountCents: order.TotalPriceCents,
 			Status:      model.PaymentStatusPaid,
+			OutTradeNo:  model.GeneratePaymentOutTradeNo(),
 			PaidAt:      &now,
 		}
 		require.NoError(t, paymentRepo.Create(ctx, payment))
+		// 在沙箱渠道登记并完成该笔交易，退款才能被渠道受理
+		if gateway, ok := svc.Gateway(method); ok {
+			_, err := gateway.CreatePrepay(ctx, PrepayRequest{OutTradeNo: payment.OutTradeNo, AmountCents: payment.AmountCents})
+			require.NoError(t, err)
+			require.NoError(t, sandbox.Pay(ctx, payment.OutTradeNo))
+		}
 
 		return paymentRepo, orderRepo, svc, payment, order
 	}
@@ -332,12 +341,19 @@ func TestPaymentService_RefundPayment_FullCoverage(t *testing.T) {
 
 		storedPayment, _ := paymentRepo.Get(ctx, payment.ID)
 		assert.Equal(t, model.PaymentStatusRefunded, storedPayment.Status)
-		assert.True(t, strings.HasPrefix(storedPayment.ProviderTradeNo, "wx_refund_"))
 		assert.NotNil(t, storedPayment.RefundedAt)
 
+		refunds, err := svc.ListOrderRefunds(ctx, order.ID)
+		require.NoError(t, err)
+		require.Len(t, refunds, 1)
+		assert.Equal(t, model.RefundStatusSucceeded, refunds[0].Status)
+		assert.Equal(t, int64(900), refunds[0].AmountCents)
+		assert.True(t, strings.HasPrefix(refunds[0].ProviderRefundNo, "wx_refund_"))
+
 		storedOrder, _ := orderRepo.Get(ctx, order.ID)
 		assert.Equal(t, model.OrderStatusRefunded, storedOrder.Status)
 		assert.Equal(t, "duplicate", storedOrder.RefundReason)
+		assert.Equal(t, int64(900), storedOrder.RefundAmountCents)
 	})
 
 	t.Run("payment retrieval failure", func(t *testing.T) {
@@ -346,7 +362,7 @@ func TestPaymentService_RefundPayment_FullCoverage(t *testing.T) {
 			return nil, repository.ErrNotFound
 		}
 		orderRepo := newMockOrderRepository()
-		svc := NewPaymentService(paymentRepo, orderRepo)
+		svc := newTestPaymentService(paymentRepo, orderRepo)
 
 		err := svc.RefundPayment(ctx, 999, "missing")
 		require.Error(t, err)
@@ -354,28 +370,30 @@ func TestPaymentService_RefundPayment_FullCoverage(t *testing.T) {
 	})
 
 	t.Run("alipay provider", func(t *testing.T) {
-		paymentRepo, _, svc, payment, _ := newSetup(model.PaymentMethodAlipay)
+		_, _, svc, payment, order := newSetup(model.PaymentMethodAlipay)
 
 		err := svc.RefundPayment(ctx, payment.ID, "quality")
 		require.NoError(t, err)
 
-		storedPayment, _ := paymentRepo.Get(ctx, payment.ID)
-		assert.True(t, strings.HasPrefix(storedPayment.ProviderTradeNo, "ali_refund_"))
+		refunds, _ := svc.ListOrderRefunds(ctx, order.ID)
+		require.Len(t, refunds, 1)
+		assert.True(t, strings.HasPrefix(refunds[0].ProviderRefundNo, "ali_refund_"))
 	})
 
 	t.Run("generic provider fallback", func(t *testing.T) {
-		paymentRepo, _, svc, payment, _ := newSetup(model.PaymentMethod("bank_transfer"))
+		_, _, svc, payment, order := newSetup(model.PaymentMethod("bank_transfer"))
 
 		err := svc.RefundPayment(ctx, payment.ID, "manual")
 		require.NoError(t, err)
 
-		storedPayment, _ := paymentRepo.Get(ctx, payment.ID)
-		assert.True(t, strings.HasPrefix(storedPayment.ProviderTradeNo, "refund_"))
+		refunds, _ := svc.ListOrderRefunds(ctx, order.ID)
+		require.Len(t, refunds, 1)
+		assert.True(t, strings.HasPrefix(refunds[0].ProviderRefundNo, "refund_"))
 	})
 
 	t.Run("provider refund failure", func(t *testing.T) {
 		_, _, svc, payment, _ := newSetup(model.PaymentMethodWeChat)
-		svc.providers[model.PaymentMethodWeChat] = failingProvider{}
+		svc.SetGateway(failingProvider{Gateway: svc.gateways[model.PaymentMethodWeChat]})
 
 		err := svc.RefundPayment(ctx, payment.ID, "issue")
 		require.Error(t, err)
@@ -416,8 +434,15 @@ func TestPaymentService_RefundPayment_FullCoverage(t *testing.T) {
 	})
 }
 
-type failingProvider struct{}
+// failingProvider wraps a working gateway but fails prepay and refund calls.
+type failingProvider struct {
+	Gateway
+}
+
+func (failingProvider) CreatePrepay(ctx context.Context, req PrepayRequest) (*PrepayResult, error) {
+	return nil, errors.New("provider failure")
+}
 
-func (failingProvider) Refund(ctx context.Context, p *model.Payment, reason string) (string, json.RawMessage, time.Time, error) {
-	return "", nil, time.Time{}, errors.New("provider failure")
+func (failingProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	return nil, errors.New("provider failure")
 }
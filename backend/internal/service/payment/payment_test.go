@@ -58,6 +58,15 @@ func (m *mockPaymentRepository) Get(ctx context.Context, id uint64) (*model.Paym
 	return nil, repository.ErrNotFound
 }
 
+func (m *mockPaymentRepository) GetByOutTradeNo(ctx context.Context, outTradeNo string) (*model.Payment, error) {
+	for _, p := range m.payments {
+		if p.OutTradeNo == outTradeNo {
+			return p, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
 func (m *mockPaymentRepository) Update(ctx context.Context, payment *model.Payment) error {
 	if m.updateHook != nil {
 		return m.updateHook(ctx, payment)
@@ -137,10 +146,76 @@ func (m *mockOrderRepository) Delete(ctx context.Context, id uint64) error {
 	return nil
 }
 
+type mockRefundRepository struct {
+	refunds map[uint64]model.Refund
+	nextID  uint64
+}
+
+func newMockRefundRepository() *mockRefundRepository {
+	return &mockRefundRepository{refunds: make(map[uint64]model.Refund)}
+}
+
+func (m *mockRefundRepository) Create(ctx context.Context, refund *model.Refund) error {
+	m.nextID++
+	refund.ID = m.nextID
+	m.refunds[refund.ID] = *refund
+	return nil
+}
+
+func (m *mockRefundRepository) Get(ctx context.Context, id uint64) (*model.Refund, error) {
+	if refund, ok := m.refunds[id]; ok {
+		return &refund, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *mockRefundRepository) GetByOutRefundNo(ctx context.Context, outRefundNo string) (*model.Refund, error) {
+	for _, refund := range m.refunds {
+		if refund.OutRefundNo == outRefundNo {
+			return &refund, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *mockRefundRepository) Update(ctx context.Context, refund *model.Refund) error {
+	if _, ok := m.refunds[refund.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	m.refunds[refund.ID] = *refund
+	return nil
+}
+
+func (m *mockRefundRepository) ListByOrder(ctx context.Context, orderID uint64) ([]model.Refund, error) {
+	var result []model.Refund
+	for id := uint64(1); id <= m.nextID; id++ {
+		if refund, ok := m.refunds[id]; ok && refund.OrderID == orderID {
+			result = append(result, refund)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRefundRepository) ListByStatus(ctx context.Context, statuses []model.RefundStatus, limit int) ([]model.Refund, error) {
+	var result []model.Refund
+	for id := uint64(1); id <= m.nextID; id++ {
+		refund, ok := m.refunds[id]
+		if !ok {
+			continue
+		}
+		for _, st := range statuses {
+			if refund.Status == st {
+				result = append(result, refund)
+			}
+		}
+	}
+	return result, nil
+}
+
 func TestCreatePayment(t *testing.T) {
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	// 创建测试订单
 	order := &model.Order{
@@ -188,7 +263,7 @@ func TestCreatePayment(t *testing.T) {
 func TestGetPaymentStatus(t *testing.T) {
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc, sandbox := newSandboxPaymentService(paymentRepo, orderRepo)
 
 	// 创建测试订单和支付
 	order := &model.Order{
@@ -225,16 +300,31 @@ func TestGetPaymentStatus(t *testing.T) {
 		t.Errorf("expected order ID %d, got %d", order.ID, status.OrderID)
 	}
 
-	// Mock 支付应该自动成功
-	if status.Status != model.PaymentStatusPaid {
+	// 未付款前保持待支付
+	if status.Status != model.PaymentStatusPending {
+		t.Errorf("expected status pending, got %s", status.Status)
+	}
+
+	// 渠道侧完成付款后，查询会主动查单并确认订单
+	if err := sandbox.Pay(context.Background(), paymentRepo.payments[resp.PaymentID].OutTradeNo); err != nil {
+		t.Fatalf("sandbox pay: %v", err)
+	}
+	status, err = svc.GetPaymentStatus(context.Background(), resp.PaymentID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if status.Status != model.PaymentStatusPaid || status.PaidAt == nil {
 		t.Errorf("expected status paid, got %s", status.Status)
 	}
+	if order.Status != model.OrderStatusConfirmed {
+		t.Errorf("expected order confirmed, got %s", order.Status)
+	}
 }
 
 func TestCancelPayment(t *testing.T) {
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	// 创建待支付的支付记录
 	payment := &model.Payment{
@@ -262,7 +352,7 @@ func TestCancelPayment(t *testing.T) {
 func TestCreatePaymentInvalidOrderStatus(t *testing.T) {
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	// 创建已支付的订单
 	order := &model.Order{
@@ -289,7 +379,7 @@ func TestCreatePaymentInvalidOrderStatus(t *testing.T) {
 func TestCreatePaymentUnauthorized(t *testing.T) {
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	// 创建其他用户的订单
 	order := &model.Order{
@@ -316,7 +406,8 @@ func TestCreatePaymentUnauthorized(t *testing.T) {
 func TestRefundPayment(t *testing.T) {
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
+	svc.SetRefundRepository(newMockRefundRepository())
 
 	// 先创建订单
 	order := &model.Order{
@@ -363,12 +454,12 @@ func TestRefundPayment(t *testing.T) {
 func TestHandlePaymentCallback(t *testing.T) {
 	paymentRepo := newMockPaymentRepository()
 	orderRepo := newMockOrderRepository()
-	svc := NewPaymentService(paymentRepo, orderRepo)
+	svc := newTestPaymentService(paymentRepo, orderRepo)
 
 	// 创建订单和待支付的支付记录
 	order := &model.Order{
 		UserID:          1,
-		Status:          model.OrderStatusConfirmed,
+		Status:          model.OrderStatusPending,
 		TotalPriceCents: 10000,
 		Currency:        "CNY",
 	}
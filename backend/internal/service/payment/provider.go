@@ -1,59 +1,88 @@
 package payment
 
 import (
-    "context"
-    "encoding/json"
-    "fmt"
-    "time"
-
-    "gamelink/internal/model"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"time"
 )
 
-type ProviderClient interface {
-    Refund(ctx context.Context, p *model.Payment, reason string) (providerTradeNo string, providerRaw json.RawMessage, refundedAt time.Time, err error)
-}
-
-type wechatProvider struct{}
+// genericProvider 处理未接入网关的支付方式（如线下转账），仅记录退款流水并视为立即成功。
+type genericProvider struct{}
 
-func (wechatProvider) Refund(ctx context.Context, p *model.Payment, reason string) (string, json.RawMessage, time.Time, error) {
-    now := time.Now()
-    tradeNo := fmt.Sprintf("wx_refund_%d_%d", p.ID, now.Unix())
-    raw := map[string]interface{}{
-        "channel": "wechat",
-        "payment_id": p.ID,
-        "refund_reason": reason,
-        "refunded_at": now.Unix(),
-    }
-    b, _ := json.Marshal(raw)
-    return tradeNo, json.RawMessage(b), now, nil
+func (genericProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	now := time.Now()
+	raw := map[string]interface{}{
+		"channel":       "generic",
+		"out_trade_no":  req.OutTradeNo,
+		"out_refund_no": req.OutRefundNo,
+		"amount_cents":  req.AmountCents,
+		"refund_reason": req.Reason,
+		"refunded_at":   now.Unix(),
+	}
+	b, _ := json.Marshal(raw)
+	return &RefundResult{
+		OutRefundNo:      req.OutRefundNo,
+		ProviderRefundNo: "refund_" + req.OutRefundNo,
+		State:            RefundStateSucceeded,
+		RefundedAt:       &now,
+		Raw:              json.RawMessage(b),
+	}, nil
 }
 
-type alipayProvider struct{}
-
-func (alipayProvider) Refund(ctx context.Context, p *model.Payment, reason string) (string, json.RawMessage, time.Time, error) {
-    now := time.Now()
-    tradeNo := fmt.Sprintf("ali_refund_%d_%d", p.ID, now.Unix())
-    raw := map[string]interface{}{
-        "channel": "alipay",
-        "payment_id": p.ID,
-        "refund_reason": reason,
-        "refunded_at": now.Unix(),
-    }
-    b, _ := json.Marshal(raw)
-    return tradeNo, json.RawMessage(b), now, nil
+func (genericProvider) QueryRefund(ctx context.Context, outTradeNo, outRefundNo string) (*RefundResult, error) {
+	return &RefundResult{OutRefundNo: outRefundNo, ProviderRefundNo: "refund_" + outRefundNo, State: RefundStateSucceeded}, nil
 }
 
-type genericProvider struct{}
+// ParseRSAPrivateKeyPEM 解析 PKCS#1 或 PKCS#8 格式的 RSA 私钥。
+func ParseRSAPrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
 
-func (genericProvider) Refund(ctx context.Context, p *model.Payment, reason string) (string, json.RawMessage, time.Time, error) {
-    now := time.Now()
-    tradeNo := fmt.Sprintf("refund_%d_%d", p.ID, now.Unix())
-    raw := map[string]interface{}{
-        "channel": "generic",
-        "payment_id": p.ID,
-        "refund_reason": reason,
-        "refunded_at": now.Unix(),
-    }
-    b, _ := json.Marshal(raw)
-    return tradeNo, json.RawMessage(b), now, nil
+// ParseRSAPublicKeyPEM 解析 RSA 公钥，支持 PUBLIC KEY 与 CERTIFICATE 两种 PEM。
+func ParseRSAPublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM public key")
+	}
+	var pub interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub = parsed
+	}
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return key, nil
 }
@@ -0,0 +1,570 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	"gamelink/internal/service/orderstate"
+)
+
+var (
+	// ErrRefundNotConfigured 未注入退款单仓储
+	ErrRefundNotConfigured = errors.New("refund repository not configured")
+	// ErrRefundAmountInvalid 退款金额非法
+	ErrRefundAmountInvalid = errors.New("invalid refund amount")
+	// ErrRefundExceedsPaid 退款金额超过剩余可退金额
+	ErrRefundExceedsPaid = errors.New("refund amount exceeds refundable balance")
+	// ErrRefundRejected 渠道拒绝退款
+	ErrRefundRejected = errors.New("refund rejected by provider")
+	// ErrRefundNotCancelable 退款已被渠道受理或已完成，不能撤销
+	ErrRefundNotCancelable = errors.New("refund already accepted by provider")
+)
+
+// maxRefundRetries 渠道提交失败的最大次数，达到后退款单标记为失败并释放可退额度。
+const maxRefundRetries = 5
+
+// SetRefundRepository 注入退款单仓储（事务内使用 Repos.Refunds）。
+func (s *PaymentService) SetRefundRepository(repo repository.RefundRepository) { s.refunds = repo }
+
+// CreateRefundRequest 发起退款请求。
+type CreateRefundRequest struct {
+	OrderID uint64
+	// PaymentID 为空时按订单全部已支付记录退款：先退主支付，再按支付顺序退加时补款；
+	// 订单没有在线支付时按线下退款处理
+	PaymentID *uint64
+	// AmountCents 为 0 时退还全部剩余可退金额
+	AmountCents int64
+	Reason      string
+	Source      model.RefundSource
+	ActorUserID *uint64
+	// Cancel 非空时在创建退款单的同一事务内先取消订单，退款结算后再流转为已退款
+	Cancel *orderstate.Change
+	// InTx 非空时在创建退款单的同一事务内执行，refunds 为本次创建的退款单；返回错误时整体回滚。
+	// 用于争议裁决等需与退款单一起提交的变更
+	InTx func(ctx context.Context, r *common.Repos, refunds []*model.Refund) error
+}
+
+// refundShare 一张退款单对应的支付记录与金额；payment 为空表示线下退款。
+type refundShare struct {
+	payment *model.Payment
+	amount  int64
+}
+
+// CreateRefund 创建退款单并提交渠道。
+//
+// 同一笔支付可多次部分退款，未失败的退款之和不超过实付金额；未指定支付时退款可跨越订单的
+// 多笔支付，按支付拆分为多张退款单，每张以该笔支付的剩余可退金额为限。剩余额度在订单行锁下
+// 计算，并发退款不会超退。设置 Cancel 时订单取消与退款单在同一事务内提交，二者要么都成功
+// 要么都不生效。渠道同步成功时立即汇总到订单与支付记录；返回处理中时由 ProcessPendingRefunds
+// 查询确认；提交失败的退款单保持 pending，返回错误的同时保留退款单，后续重试沿用同一退款单号。
+// 拆分为多张时返回第一张出错的退款单，全部成功时返回第一张。
+func (s *PaymentService) CreateRefund(ctx context.Context, req CreateRefundRequest) (*model.Refund, error) {
+	if req.AmountCents < 0 {
+		return nil, ErrRefundAmountInvalid
+	}
+	if req.Source == "" {
+		req.Source = model.RefundSourceSystem
+	}
+
+	var (
+		refunds []*model.Refund
+		shares  []refundShare
+	)
+	err := s.withTx(ctx, func(r *common.Repos) error {
+		if r.Refunds == nil {
+			return ErrRefundNotConfigured
+		}
+		// 锁定订单行，同一订单的并发退款依次计算剩余可退金额
+		order, err := orderstate.Lock(ctx, r.Orders, req.OrderID)
+		if err != nil {
+			return err
+		}
+		if req.Cancel != nil {
+			if err := orderstate.Transit(ctx, r.Orders, r.OrderHistory, order, model.OrderStatusCanceled, *req.Cancel); err != nil {
+				return err
+			}
+		}
+		if !orderstate.CanTransit(order.Status, model.OrderStatusRefunded, model.OrderActorSystem) {
+			return ErrInvalidOrderStatus
+		}
+		payments, err := refundablePayments(ctx, r, order, req.PaymentID)
+		if err != nil {
+			return err
+		}
+		existing, err := r.Refunds.ListByOrder(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		shares, err = planRefund(order, payments, existing, req.AmountCents)
+		if err != nil {
+			return err
+		}
+
+		for _, share := range shares {
+			refund := &model.Refund{
+				OrderID:     order.ID,
+				AmountCents: share.amount,
+				Currency:    currencyOrDefault(order.Currency),
+				Reason:      req.Reason,
+				Source:      req.Source,
+				Status:      model.RefundStatusPending,
+				OutRefundNo: model.GenerateOutRefundNo(),
+				RequestedBy: req.ActorUserID,
+			}
+			if share.payment != nil {
+				refund.PaymentID = &share.payment.ID
+				refund.Method = share.payment.Method
+				refund.Currency = currencyOrDefault(share.payment.Currency)
+			}
+			if err := r.Refunds.Create(ctx, refund); err != nil {
+				return err
+			}
+			s.audit(ctx, r, model.OpEntityOrder, order.ID, model.OpActionRefund, refundMeta(refund))
+			refunds = append(refunds, refund)
+		}
+		if req.InTx != nil {
+			return req.InTx(ctx, r, refunds)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	first, firstErr := 0, error(nil)
+	for i := range refunds {
+		updated, err := s.submitRefund(ctx, refunds[i], shares[i].payment)
+		if updated != nil {
+			refunds[i] = updated
+		}
+		if err == nil && refunds[i].Status == model.RefundStatusFailed {
+			err = ErrRefundRejected
+		}
+		if err != nil && firstErr == nil {
+			first, firstErr = i, err
+		}
+	}
+	return refunds[first], firstErr
+}
+
+// RefundPayment 退还支付记录的全部剩余金额。
+func (s *PaymentService) RefundPayment(ctx context.Context, paymentID uint64, reason string) error {
+	payment, err := s.payments.Get(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+
+	// 验证支付状态：只有已支付的订单可以退款
+	if payment.Status != model.PaymentStatusPaid {
+		return fmt.Errorf("payment status must be paid, current: %s", payment.Status)
+	}
+
+	_, err = s.CreateRefund(ctx, CreateRefundRequest{
+		OrderID:   payment.OrderID,
+		PaymentID: &payment.ID,
+		Reason:    reason,
+		Source:    model.RefundSourceSystem,
+	})
+	return err
+}
+
+// CancelRefund 撤销尚未被渠道受理的退款单：标记为失败并释放可退额度；已失败的退款单直接返回。
+func (s *PaymentService) CancelRefund(ctx context.Context, refundID uint64, reason string) (*model.Refund, error) {
+	if s.refunds == nil {
+		return nil, ErrRefundNotConfigured
+	}
+	var refund *model.Refund
+	err := s.withTx(ctx, func(r *common.Repos) error {
+		var err error
+		refund, err = r.Refunds.Get(ctx, refundID)
+		if err != nil {
+			return err
+		}
+		switch refund.Status {
+		case model.RefundStatusFailed:
+			return nil
+		case model.RefundStatusPending:
+		default:
+			return fmt.Errorf("%w: refund %s is %s", ErrRefundNotCancelable, refund.OutRefundNo, refund.Status)
+		}
+		refund.Status = model.RefundStatusFailed
+		refund.LastError = "canceled: " + reason
+		s.audit(ctx, r, model.OpEntityOrder, refund.OrderID, model.OpActionRefund, refundMeta(refund))
+		return r.Refunds.Update(ctx, refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// ListOrderRefunds 返回订单的全部退款单（按创建顺序）。
+func (s *PaymentService) ListOrderRefunds(ctx context.Context, orderID uint64) ([]model.Refund, error) {
+	if s.refunds == nil {
+		return nil, ErrRefundNotConfigured
+	}
+	return s.refunds.ListByOrder(ctx, orderID)
+}
+
+// SyncRefund 推进单笔未完结的退款：pending 重新提交，processing 向渠道查询确认。
+func (s *PaymentService) SyncRefund(ctx context.Context, refundID uint64) (*model.Refund, error) {
+	if s.refunds == nil {
+		return nil, ErrRefundNotConfigured
+	}
+	refund, err := s.refunds.Get(ctx, refundID)
+	if err != nil {
+		return nil, err
+	}
+	return s.advanceRefund(ctx, refund)
+}
+
+// ProcessPendingRefunds 批量推进未完结的退款单（由定时任务调用），返回本次完结的数量。
+func (s *PaymentService) ProcessPendingRefunds(ctx context.Context, limit int) (int, error) {
+	if s.refunds == nil {
+		return 0, ErrRefundNotConfigured
+	}
+	refunds, err := s.refunds.ListByStatus(ctx, []model.RefundStatus{model.RefundStatusPending, model.RefundStatusProcessing}, limit)
+	if err != nil {
+		return 0, err
+	}
+	finished := 0
+	for i := range refunds {
+		updated, err := s.advanceRefund(ctx, &refunds[i])
+		if err != nil {
+			slog.Warn("advance refund failed", slog.Uint64("refund_id", refunds[i].ID), slog.String("error", err.Error()))
+			continue
+		}
+		if updated.Status == model.RefundStatusSucceeded || updated.Status == model.RefundStatusFailed {
+			finished++
+		}
+	}
+	return finished, nil
+}
+
+func (s *PaymentService) advanceRefund(ctx context.Context, refund *model.Refund) (*model.Refund, error) {
+	var payment *model.Payment
+	if refund.PaymentID != nil {
+		p, err := s.payments.Get(ctx, *refund.PaymentID)
+		if err != nil {
+			return refund, err
+		}
+		payment = p
+	}
+	switch refund.Status {
+	case model.RefundStatusPending:
+		return s.submitRefund(ctx, refund, payment)
+	case model.RefundStatusProcessing:
+		outTradeNo := ""
+		if payment != nil {
+			outTradeNo = payment.OutTradeNo
+		}
+		result, err := s.refundClient(refund.Method, outTradeNo).QueryRefund(ctx, outTradeNo, refund.OutRefundNo)
+		if err != nil {
+			return refund, fmt.Errorf("query refund: %w", err)
+		}
+		return s.applyRefundResult(ctx, refund.ID, result)
+	default:
+		return refund, nil
+	}
+}
+
+// refundClient 返回退款渠道；未接入网关或早于网关接入的支付（无商户单号）按线下退款记录。
+func (s *PaymentService) refundClient(method model.PaymentMethod, outTradeNo string) ProviderClient {
+	if outTradeNo != "" {
+		if gateway, ok := s.gateways[method]; ok {
+			return gateway
+		}
+	}
+	return genericProvider{}
+}
+
+// submitRefund 向渠道提交退款并应用结果；提交失败时累计重试次数，达到上限后标记失败。
+func (s *PaymentService) submitRefund(ctx context.Context, refund *model.Refund, payment *model.Payment) (*model.Refund, error) {
+	req := RefundRequest{
+		OutRefundNo: refund.OutRefundNo,
+		TotalCents:  refund.AmountCents,
+		AmountCents: refund.AmountCents,
+		Currency:    refund.Currency,
+		Reason:      refund.Reason,
+	}
+	if payment != nil {
+		req.OutTradeNo = payment.OutTradeNo
+		req.TotalCents = payment.AmountCents
+	}
+	result, err := s.refundClient(refund.Method, req.OutTradeNo).Refund(ctx, req)
+	if err != nil {
+		updated, ferr := s.recordRefundFailure(ctx, refund.ID, err)
+		if ferr != nil {
+			slog.Warn("record refund failure", slog.Uint64("refund_id", refund.ID), slog.String("error", ferr.Error()))
+			updated = refund
+		}
+		return updated, fmt.Errorf("submit refund: %w", err)
+	}
+	return s.applyRefundResult(ctx, refund.ID, result)
+}
+
+// recordRefundFailure 记录一次提交失败。
+func (s *PaymentService) recordRefundFailure(ctx context.Context, refundID uint64, cause error) (*model.Refund, error) {
+	var refund *model.Refund
+	err := s.withTx(ctx, func(r *common.Repos) error {
+		var err error
+		refund, err = r.Refunds.Get(ctx, refundID)
+		if err != nil {
+			return err
+		}
+		if refund.Status != model.RefundStatusPending {
+			return nil
+		}
+		refund.RetryCount++
+		refund.LastError = cause.Error()
+		if refund.RetryCount >= maxRefundRetries {
+			refund.Status = model.RefundStatusFailed
+			s.audit(ctx, r, model.OpEntityOrder, refund.OrderID, model.OpActionRefund, refundMeta(refund))
+		}
+		return r.Refunds.Update(ctx, refund)
+	})
+	return refund, err
+}
+
+// applyRefundResult 在事务内应用渠道退款结果；已完结的退款单不再变更，保证重复确认幂等。
+func (s *PaymentService) applyRefundResult(ctx context.Context, refundID uint64, result *RefundResult) (*model.Refund, error) {
+	var refund *model.Refund
+	err := s.withTx(ctx, func(r *common.Repos) error {
+		var err error
+		refund, err = r.Refunds.Get(ctx, refundID)
+		if err != nil {
+			return err
+		}
+		if refund.Status == model.RefundStatusSucceeded || refund.Status == model.RefundStatusFailed {
+			return nil
+		}
+		if result.ProviderRefundNo != "" {
+			refund.ProviderRefundNo = result.ProviderRefundNo
+		}
+		if len(result.Raw) > 0 {
+			refund.ProviderRaw = result.Raw
+		}
+		refund.LastError = ""
+
+		switch result.State {
+		case RefundStateSucceeded:
+			refundedAt := time.Now()
+			if result.RefundedAt != nil {
+				refundedAt = *result.RefundedAt
+			}
+			refund.Status = model.RefundStatusSucceeded
+			refund.RefundedAt = &refundedAt
+			if err := r.Refunds.Update(ctx, refund); err != nil {
+				return err
+			}
+			return s.settleRefund(ctx, r, refund)
+		case RefundStateFailed:
+			refund.Status = model.RefundStatusFailed
+			refund.LastError = ErrRefundRejected.Error()
+			s.audit(ctx, r, model.OpEntityOrder, refund.OrderID, model.OpActionRefund, refundMeta(refund))
+			return r.Refunds.Update(ctx, refund)
+		default:
+			refund.Status = model.RefundStatusProcessing
+			return r.Refunds.Update(ctx, refund)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// settleRefund 退款成功后汇总记账：订单退款金额为全部成功退款之和，
+// 支付记录全额退完后标记为已退款。
+func (s *PaymentService) settleRefund(ctx context.Context, r *common.Repos, refund *model.Refund) error {
+	// 线下退款未经渠道收款，不记渠道资金
+	if s.ledger != nil && refund.PaymentID != nil {
+		if err := s.ledger.PostRefundIssued(ctx, r, refund); err != nil {
+			return fmt.Errorf("post ledger: %w", err)
+		}
+	}
+	refunds, err := r.Refunds.ListByOrder(ctx, refund.OrderID)
+	if err != nil {
+		return err
+	}
+	var orderTotal, paymentTotal int64
+	for _, rf := range refunds {
+		if rf.Status != model.RefundStatusSucceeded {
+			continue
+		}
+		if refund.PaymentID != nil && rf.PaymentID != nil && *rf.PaymentID == *refund.PaymentID {
+			paymentTotal += rf.AmountCents
+		}
+		if rf.Source != model.RefundSourceLatePayment {
+			orderTotal += rf.AmountCents
+		}
+	}
+
+	if refund.PaymentID != nil {
+		payment, err := r.Payments.Get(ctx, *refund.PaymentID)
+		if err != nil {
+			return err
+		}
+		if paymentTotal >= payment.AmountCents && payment.Status != model.PaymentStatusRefunded {
+			payment.Status = model.PaymentStatusRefunded
+			payment.RefundedAt = refund.RefundedAt
+			if err := r.Payments.Update(ctx, payment); err != nil {
+				return err
+			}
+			s.audit(ctx, r, model.OpEntityPayment, payment.ID, model.OpActionRefund, refundMeta(refund))
+		}
+	}
+	// 晚到支付的退款只退回该笔款项，不影响订单
+	if refund.Source == model.RefundSourceLatePayment {
+		return nil
+	}
+
+	order, err := r.Orders.Get(ctx, refund.OrderID)
+	if err != nil {
+		return err
+	}
+	order.RefundAmountCents = orderTotal
+	order.RefundReason = refund.Reason
+	order.RefundedAt = refund.RefundedAt
+	change := orderstate.Change{Role: model.OrderActorSystem, ActorUserID: refund.RequestedBy, Reason: refund.Reason}
+	if err := orderstate.Transit(ctx, r.Orders, r.OrderHistory, order, model.OrderStatusRefunded, change); err != nil {
+		return err
+	}
+	s.audit(ctx, r, model.OpEntityOrder, order.ID, model.OpActionRefund, refundMeta(refund))
+	return nil
+}
+
+// refundablePayments 确定退款对应的支付记录，按退款顺序排列：指定支付时只含该笔，
+// 否则为订单全部已支付记录，主支付在前、加时补款按支付顺序在后；订单没有在线支付时为空（线下退款）。
+func refundablePayments(ctx context.Context, r *common.Repos, order *model.Order, paymentID *uint64) ([]model.Payment, error) {
+	if paymentID != nil {
+		payment, err := r.Payments.Get(ctx, *paymentID)
+		if err != nil {
+			return nil, err
+		}
+		if payment.OrderID != order.ID {
+			return nil, fmt.Errorf("%w: payment %d does not belong to order %d", ErrValidation, payment.ID, order.ID)
+		}
+		if payment.Status != model.PaymentStatusPaid && payment.Status != model.PaymentStatusRefunded {
+			return nil, fmt.Errorf("payment status must be paid, current: %s", payment.Status)
+		}
+		return []model.Payment{*payment}, nil
+	}
+
+	payments, _, err := r.Payments.List(ctx, repository.PaymentListOptions{
+		OrderID:  &order.ID,
+		Statuses: []model.PaymentStatus{model.PaymentStatusPaid, model.PaymentStatusRefunded},
+		Page:     1,
+		PageSize: 100,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]model.Payment, 0, len(payments))
+	for _, p := range payments {
+		if p.Status == model.PaymentStatusPaid || p.Status == model.PaymentStatusRefunded {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if primary := out[i].ExtensionID == nil; primary != (out[j].ExtensionID == nil) {
+			return primary
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// planRefund 将退款金额依次分摊到各笔支付，每笔以其剩余可退金额为限；amount 为 0 时退还全部剩余。
+// 没有在线支付时按订单金额整笔线下退款。
+func planRefund(order *model.Order, payments []model.Payment, refunds []model.Refund, amount int64) ([]refundShare, error) {
+	if len(payments) == 0 {
+		remaining := refundableCents(order, nil, refunds)
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount <= 0 || amount > remaining {
+			return nil, fmt.Errorf("%w: requested %d, refundable %d", ErrRefundExceedsPaid, amount, remaining)
+		}
+		return []refundShare{{amount: amount}}, nil
+	}
+
+	remainings := make([]int64, len(payments))
+	var total int64
+	for i := range payments {
+		remainings[i] = max(refundableCents(order, &payments[i], refunds), 0)
+		total += remainings[i]
+	}
+	if amount == 0 {
+		amount = total
+	}
+	if amount <= 0 || amount > total {
+		return nil, fmt.Errorf("%w: requested %d, refundable %d", ErrRefundExceedsPaid, amount, total)
+	}
+	var shares []refundShare
+	for i := range payments {
+		if amount == 0 {
+			break
+		}
+		part := min(amount, remainings[i])
+		if part == 0 {
+			continue
+		}
+		shares = append(shares, refundShare{payment: &payments[i], amount: part})
+		amount -= part
+	}
+	return shares, nil
+}
+
+// refundableCents 计算剩余可退金额：实付金额减去未失败的退款（线下退款同样占用额度）。
+func refundableCents(order *model.Order, payment *model.Payment, refunds []model.Refund) int64 {
+	remaining := order.TotalPriceCents
+	if payment != nil {
+		remaining = payment.AmountCents
+	}
+	for _, rf := range refunds {
+		if !rf.Status.Holds() {
+			continue
+		}
+		if payment != nil && rf.PaymentID != nil && *rf.PaymentID != payment.ID {
+			continue
+		}
+		// 晚到支付的退款只占用该笔支付的额度
+		if payment == nil && rf.Source == model.RefundSourceLatePayment {
+			continue
+		}
+		remaining -= rf.AmountCents
+	}
+	return remaining
+}
+
+func refundMeta(refund *model.Refund) map[string]any {
+	meta := map[string]any{
+		"refundId":    refund.ID,
+		"outRefundNo": refund.OutRefundNo,
+		"amountCents": refund.AmountCents,
+		"status":      refund.Status,
+		"source":      refund.Source,
+		"reason":      refund.Reason,
+	}
+	if refund.PaymentID != nil {
+		meta["paymentId"] = *refund.PaymentID
+	}
+	if refund.ProviderRefundNo != "" {
+		meta["providerRefundNo"] = refund.ProviderRefundNo
+	}
+	if refund.RequestedBy != nil {
+		meta["requestedBy"] = *refund.RequestedBy
+	}
+	if refund.LastError != "" {
+		meta["error"] = refund.LastError
+	}
+	return meta
+}
@@ -0,0 +1,312 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+)
+
+// paidTxFixture 返回已在沙箱完成支付并同步到本地的事务测试环境。
+func paidTxFixture(t *testing.T) *txFixture {
+	t.Helper()
+	f := newTxFixture(t)
+	ctx := context.Background()
+	require.NoError(t, f.sandbox.Pay(ctx, f.payment.OutTradeNo))
+	status, err := f.svc.GetPaymentStatus(ctx, f.payment.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.PaymentStatusPaid, status.Status)
+	return f
+}
+
+func TestCreateRefund_MultiplePartialRefunds(t *testing.T) {
+	f := paidTxFixture(t)
+	ctx := context.Background()
+	actor := uint64(9)
+
+	first, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 1000, Reason: "late", Source: model.RefundSourceAdmin, ActorUserID: &actor})
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundStatusSucceeded, first.Status)
+	require.NotNil(t, first.PaymentID)
+	assert.Equal(t, f.payment.ID, *first.PaymentID)
+	assert.NotEmpty(t, first.ProviderRefundNo)
+
+	p, o := f.reload(t)
+	assert.Equal(t, model.PaymentStatusPaid, p.Status, "partially refunded payment stays paid")
+	assert.Equal(t, int64(1000), o.RefundAmountCents)
+	assert.Equal(t, model.OrderStatusRefunded, o.Status)
+
+	_, err = f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 1600, Reason: "too much"})
+	assert.ErrorIs(t, err, ErrRefundExceedsPaid)
+
+	// 不指定金额时退还剩余部分
+	second, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, Reason: "rest"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), second.AmountCents)
+	assert.NotEqual(t, first.OutRefundNo, second.OutRefundNo)
+
+	p, o = f.reload(t)
+	assert.Equal(t, model.PaymentStatusRefunded, p.Status)
+	assert.NotNil(t, p.RefundedAt)
+	assert.Equal(t, int64(2500), o.RefundAmountCents)
+
+	_, err = f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 1})
+	assert.ErrorIs(t, err, ErrRefundExceedsPaid)
+
+	refunds, err := f.svc.ListOrderRefunds(ctx, f.order.ID)
+	require.NoError(t, err)
+	require.Len(t, refunds, 2)
+	assert.Equal(t, "late", refunds[0].Reason)
+	assert.Equal(t, int64(4), f.countLogs(t, model.OpEntityOrder, model.OpActionRefund), "each refund logs creation and settlement")
+	assert.Equal(t, int64(1), f.countLogs(t, model.OpEntityPayment, model.OpActionRefund))
+}
+
+func TestCreateRefund_AsyncConfirmation(t *testing.T) {
+	f := paidTxFixture(t)
+	ctx := context.Background()
+	f.sandbox.SetAsyncRefunds(true)
+
+	refund, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 800, Reason: "async"})
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundStatusProcessing, refund.Status)
+
+	// 处理中的退款占用额度
+	_, err = f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 1701})
+	assert.ErrorIs(t, err, ErrRefundExceedsPaid)
+
+	_, o := f.reload(t)
+	assert.Zero(t, o.RefundAmountCents, "order is not credited before the provider confirms")
+
+	n, err := f.svc.ProcessPendingRefunds(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, f.sandbox.CompleteRefund(refund.OutRefundNo))
+	n, err = f.svc.ProcessPendingRefunds(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	_, o = f.reload(t)
+	assert.Equal(t, int64(800), o.RefundAmountCents)
+	assert.Equal(t, model.OrderStatusRefunded, o.Status)
+
+	// 重复确认幂等
+	synced, err := f.svc.SyncRefund(ctx, refund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundStatusSucceeded, synced.Status)
+	_, o = f.reload(t)
+	assert.Equal(t, int64(800), o.RefundAmountCents)
+}
+
+// flakyRefundGateway 前 failures 次退款提交失败，之后交给内层网关。
+type flakyRefundGateway struct {
+	Gateway
+	failures int
+	calls    []string
+}
+
+func (g *flakyRefundGateway) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	g.calls = append(g.calls, req.OutRefundNo)
+	if len(g.calls) <= g.failures {
+		return nil, errors.New("provider timeout")
+	}
+	return g.Gateway.Refund(ctx, req)
+}
+
+func TestCreateRefund_RetriesWithSameRefundNo(t *testing.T) {
+	f := paidTxFixture(t)
+	ctx := context.Background()
+	gw, _ := f.svc.Gateway(model.PaymentMethodWeChat)
+	flaky := &flakyRefundGateway{Gateway: gw, failures: 1}
+	f.svc.SetGateway(flaky)
+
+	refund, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 500, Reason: "retry"})
+	require.Error(t, err)
+	require.NotNil(t, refund)
+	assert.Equal(t, model.RefundStatusPending, refund.Status)
+	assert.Equal(t, 1, refund.RetryCount)
+	assert.Contains(t, refund.LastError, "provider timeout")
+
+	n, err := f.svc.ProcessPendingRefunds(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, flaky.calls, 2)
+	assert.Equal(t, flaky.calls[0], flaky.calls[1], "retry must reuse the merchant refund number")
+
+	_, o := f.reload(t)
+	assert.Equal(t, int64(500), o.RefundAmountCents)
+}
+
+func TestCancelRefund_OnlyBeforeProviderAccepts(t *testing.T) {
+	f := paidTxFixture(t)
+	ctx := context.Background()
+	gw, _ := f.svc.Gateway(model.PaymentMethodWeChat)
+	f.svc.SetGateway(&flakyRefundGateway{Gateway: gw, failures: 1})
+
+	pending, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 2500})
+	require.Error(t, err)
+	require.Equal(t, model.RefundStatusPending, pending.Status)
+
+	canceled, err := f.svc.CancelRefund(ctx, pending.ID, "dispute rolled back")
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundStatusFailed, canceled.Status)
+	assert.Contains(t, canceled.LastError, "dispute rolled back")
+	_, err = f.svc.CancelRefund(ctx, pending.ID, "again")
+	require.NoError(t, err, "canceling a failed refund is a no-op")
+
+	// 撤销后不再提交渠道，额度已释放
+	n, err := f.svc.ProcessPendingRefunds(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	done, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 2500})
+	require.NoError(t, err)
+	_, err = f.svc.CancelRefund(ctx, done.ID, "too late")
+	assert.ErrorIs(t, err, ErrRefundNotCancelable)
+}
+
+func TestCreateRefund_FailsAfterMaxRetries(t *testing.T) {
+	f := paidTxFixture(t)
+	ctx := context.Background()
+	gw, _ := f.svc.Gateway(model.PaymentMethodWeChat)
+	f.svc.SetGateway(&flakyRefundGateway{Gateway: gw, failures: maxRefundRetries})
+
+	refund, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 2500})
+	require.Error(t, err)
+	for i := 1; i < maxRefundRetries; i++ {
+		_, err = f.svc.ProcessPendingRefunds(ctx, 10)
+		require.NoError(t, err)
+	}
+
+	refund, err = f.svc.SyncRefund(ctx, refund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundStatusFailed, refund.Status)
+	assert.Equal(t, maxRefundRetries, refund.RetryCount)
+
+	// 失败的退款释放额度
+	f.svc.SetGateway(gw)
+	again, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 2500})
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundStatusSucceeded, again.Status)
+}
+
+func TestCreateRefund_OfflineOrderWithoutPayment(t *testing.T) {
+	f := newTxFixture(t)
+	ctx := context.Background()
+	order := &model.Order{UserID: 2, OrderNo: "ESC-OFFLINE", Status: model.OrderStatusCompleted, TotalPriceCents: 3000}
+	require.NoError(t, f.db.Create(order).Error)
+
+	refund, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: order.ID, AmountCents: 1200, Reason: "offline"})
+	require.NoError(t, err)
+	assert.Nil(t, refund.PaymentID)
+	assert.Equal(t, model.RefundStatusSucceeded, refund.Status)
+
+	var stored model.Order
+	require.NoError(t, f.db.First(&stored, order.ID).Error)
+	assert.Equal(t, int64(1200), stored.RefundAmountCents)
+
+	_, err = f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: order.ID, AmountCents: 1801})
+	assert.ErrorIs(t, err, ErrRefundExceedsPaid)
+}
+
+func TestCreateRefund_SpansPrimaryAndExtensionPayments(t *testing.T) {
+	f := paidTxFixture(t)
+	ctx := context.Background()
+	// 加时补款 1000 已到账（线下记录，无商户单号），订单总价随之增加
+	extID := uint64(7)
+	paidAt := time.Now()
+	ext := &model.Payment{OrderID: f.order.ID, ExtensionID: &extID, UserID: 1, Method: model.PaymentMethodWeChat,
+		AmountCents: 1000, Currency: model.CurrencyCNY, Status: model.PaymentStatusPaid, PaidAt: &paidAt}
+	require.NoError(t, f.db.Create(ext).Error)
+	require.NoError(t, f.db.Model(&model.Order{}).Where("id = ?", f.order.ID).Update("total_price_cents", 3500).Error)
+
+	// 未指定支付时先退主支付，超出部分由加时补款承担
+	first, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 3000, Reason: "partial"})
+	require.NoError(t, err)
+	require.NotNil(t, first.PaymentID)
+	assert.Equal(t, f.payment.ID, *first.PaymentID)
+	assert.Equal(t, int64(2500), first.AmountCents)
+
+	refunds, err := f.svc.ListOrderRefunds(ctx, f.order.ID)
+	require.NoError(t, err)
+	require.Len(t, refunds, 2)
+	require.NotNil(t, refunds[1].PaymentID)
+	assert.Equal(t, ext.ID, *refunds[1].PaymentID)
+	assert.Equal(t, int64(500), refunds[1].AmountCents)
+
+	_, err = f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 501})
+	assert.ErrorIs(t, err, ErrRefundExceedsPaid)
+	rest, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, Reason: "rest"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), rest.AmountCents)
+
+	p, o := f.reload(t)
+	assert.Equal(t, model.PaymentStatusRefunded, p.Status)
+	assert.Equal(t, int64(3500), o.RefundAmountCents)
+	var stored model.Payment
+	require.NoError(t, f.db.First(&stored, ext.ID).Error)
+	assert.Equal(t, model.PaymentStatusRefunded, stored.Status)
+}
+
+func TestCreateRefund_ConcurrentPartialRefundsNeverExceedPaid(t *testing.T) {
+	f := paidTxFixture(t)
+	ctx := context.Background()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, AmountCents: 1000, Reason: "concurrent"})
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, ErrRefundExceedsPaid)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, succeeded)
+	refunds, err := f.svc.ListOrderRefunds(ctx, f.order.ID)
+	require.NoError(t, err)
+	var total int64
+	for _, rf := range refunds {
+		total += rf.AmountCents
+	}
+	assert.Equal(t, int64(2000), total)
+	_, o := f.reload(t)
+	assert.Equal(t, int64(2000), o.RefundAmountCents)
+}
+
+func TestCreateRefund_InTxErrorRollsBackRefund(t *testing.T) {
+	f := paidTxFixture(t)
+	ctx := context.Background()
+
+	var planned int64
+	_, err := f.svc.CreateRefund(ctx, CreateRefundRequest{OrderID: f.order.ID, Reason: "dispute",
+		InTx: func(_ context.Context, r *common.Repos, refunds []*model.Refund) error {
+			require.NotNil(t, r)
+			for _, rf := range refunds {
+				planned += rf.AmountCents
+			}
+			return errors.New("dispute update failed")
+		}})
+	require.Error(t, err)
+	assert.Equal(t, f.order.TotalPriceCents, planned, "zero amount plans the whole refundable balance")
+	refunds, err := f.svc.ListOrderRefunds(ctx, f.order.ID)
+	require.NoError(t, err)
+	assert.Empty(t, refunds)
+}
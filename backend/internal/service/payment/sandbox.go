@@ -0,0 +1,658 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"gamelink/internal/model"
+)
+
+// 沙箱回调签名头。
+const (
+	SandboxHeaderTimestamp = "X-Sandbox-Timestamp"
+	SandboxHeaderNonce     = "X-Sandbox-Nonce"
+	SandboxHeaderSignature = "X-Sandbox-Signature"
+)
+
+// DefaultSandboxSecret 本地开发使用的沙箱签名密钥。
+const DefaultSandboxSecret = "gamelink-sandbox-secret"
+
+// sandboxTrade 沙箱交易（JSON 即接口协议）。
+type sandboxTrade struct {
+	Method      model.PaymentMethod `json:"method"`
+	OutTradeNo  string              `json:"out_trade_no"`
+	TradeNo     string              `json:"trade_no"`
+	Status      string              `json:"status"` // NOTPAY / SUCCESS / CLOSED / REFUND
+	AmountCents int64               `json:"amount_cents"`
+	Currency    model.Currency      `json:"currency"`
+	Description string              `json:"description,omitempty"`
+	NotifyURL   string              `json:"notify_url,omitempty"`
+	PaidAt      *time.Time          `json:"paid_at,omitempty"`
+	RefundCents int64               `json:"refund_cents,omitempty"`
+}
+
+// 沙箱交易状态。
+const (
+	sandboxNotPay  = "NOTPAY"
+	sandboxSuccess = "SUCCESS"
+	sandboxClosed  = "CLOSED"
+	sandboxRefund  = "REFUND"
+)
+
+// sandboxRefundRecord 沙箱退款单。
+type sandboxRefundRecord struct {
+	OutTradeNo  string     `json:"out_trade_no"`
+	OutRefundNo string     `json:"out_refund_no"`
+	RefundNo    string     `json:"refund_no"`
+	AmountCents int64      `json:"amount_cents"`
+	Reason      string     `json:"reason,omitempty"`
+	Status      string     `json:"status"` // PROCESSING / SUCCESS
+	RefundedAt  *time.Time `json:"refunded_at,omitempty"`
+}
+
+// 沙箱退款状态。
+const (
+	sandboxRefundProcessing = "PROCESSING"
+	sandboxRefundSuccess    = "SUCCESS"
+)
+
+// SandboxServer 本地沙箱支付渠道（HTTP 桩）。
+//
+// 模拟真实渠道的下单 / 查询 / 关单 / 退款接口，并在模拟用户付款后
+// 以 HMAC-SHA256 签名向 notify_url 推送回调，用于在测试和本地环境
+// 跑通 pending → paid 的完整流程。
+type SandboxServer struct {
+	secret []byte
+	client *http.Client
+	now    func() time.Time
+
+	mu           sync.Mutex
+	trades       map[string]*sandboxTrade
+	refunds      map[string]*sandboxRefundRecord
+	asyncRefunds bool
+	seq          int64
+	mux          *http.ServeMux
+}
+
+// NewSandboxServer 创建沙箱服务。
+func NewSandboxServer(secret string) *SandboxServer {
+	if secret == "" {
+		secret = DefaultSandboxSecret
+	}
+	s := &SandboxServer{
+		secret:  []byte(secret),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+		trades:  make(map[string]*sandboxTrade),
+		refunds: make(map[string]*sandboxRefundRecord),
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /v1/trades", s.handleCreate)
+	s.mux.HandleFunc("GET /v1/trades/{outTradeNo}", s.handleQuery)
+	s.mux.HandleFunc("POST /v1/trades/{outTradeNo}/close", s.handleClose)
+	s.mux.HandleFunc("POST /v1/trades/{outTradeNo}/refund", s.handleRefund)
+	s.mux.HandleFunc("POST /v1/trades/{outTradeNo}/pay", s.handlePay)
+	s.mux.HandleFunc("GET /v1/refunds/{outRefundNo}", s.handleRefundQuery)
+	return s
+}
+
+// SetAsyncRefunds 开启后退款申请只返回处理中，需调用 CompleteRefund 模拟渠道到账。
+func (s *SandboxServer) SetAsyncRefunds(async bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.asyncRefunds = async
+}
+
+// CompleteRefund 模拟渠道完成一笔处理中的退款。
+func (s *SandboxServer) CompleteRefund(outRefundNo string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refund, ok := s.refunds[outRefundNo]
+	if !ok {
+		return fmt.Errorf("sandbox: refund %s not found", outRefundNo)
+	}
+	if refund.Status != sandboxRefundSuccess {
+		now := s.now()
+		refund.Status = sandboxRefundSuccess
+		refund.RefundedAt = &now
+	}
+	return nil
+}
+
+// SetHTTPClient 替换推送回调使用的 HTTP 客户端。
+func (s *SandboxServer) SetHTTPClient(client *http.Client) {
+	if client != nil {
+		s.client = client
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *SandboxServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Pay 模拟用户完成付款并推送签名回调（notify_url 为空时只更新状态）。
+func (s *SandboxServer) Pay(ctx context.Context, outTradeNo string) error {
+	s.mu.Lock()
+	trade, ok := s.trades[outTradeNo]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("sandbox: trade %s not found", outTradeNo)
+	}
+	if trade.Status == sandboxNotPay {
+		now := s.now()
+		trade.Status = sandboxSuccess
+		trade.PaidAt = &now
+	}
+	snapshot := *trade
+	s.mu.Unlock()
+
+	if snapshot.Status != sandboxSuccess {
+		return fmt.Errorf("sandbox: trade %s is %s", outTradeNo, snapshot.Status)
+	}
+	if snapshot.NotifyURL == "" {
+		return nil
+	}
+	return s.notify(ctx, &snapshot)
+}
+
+// notify 推送签名回调。
+func (s *SandboxServer) notify(ctx context.Context, trade *sandboxTrade) error {
+	body, err := json.Marshal(trade)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, trade.NotifyURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range SignSandboxPayload(s.secret, body, s.now()) {
+		req.Header[k] = v
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sandbox: deliver callback: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sandbox: callback rejected: %d %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func (s *SandboxServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var trade sandboxTrade
+	if err := json.NewDecoder(r.Body).Decode(&trade); err != nil || trade.OutTradeNo == "" || trade.AmountCents <= 0 {
+		writeSandboxError(w, http.StatusBadRequest, "PARAM_ERROR", "invalid trade")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.trades[trade.OutTradeNo]; ok {
+		if existing.AmountCents != trade.AmountCents {
+			writeSandboxError(w, http.StatusConflict, "OUT_TRADE_NO_USED", "out_trade_no already used")
+			return
+		}
+		writeSandboxJSON(w, http.StatusOK, existing)
+		return
+	}
+	s.seq++
+	trade.TradeNo = fmt.Sprintf("%s%s%08d", sandboxTradePrefix(trade.Method), s.now().Format("20060102"), s.seq)
+	trade.Status = sandboxNotPay
+	trade.Currency = currencyOrDefault(trade.Currency)
+	s.trades[trade.OutTradeNo] = &trade
+	writeSandboxJSON(w, http.StatusOK, &trade)
+}
+
+func (s *SandboxServer) handleQuery(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trade, ok := s.trades[r.PathValue("outTradeNo")]
+	if !ok {
+		writeSandboxError(w, http.StatusNotFound, "ORDER_NOT_EXIST", "trade not found")
+		return
+	}
+	writeSandboxJSON(w, http.StatusOK, trade)
+}
+
+func (s *SandboxServer) handleClose(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trade, ok := s.trades[r.PathValue("outTradeNo")]
+	if !ok {
+		writeSandboxError(w, http.StatusNotFound, "ORDER_NOT_EXIST", "trade not found")
+		return
+	}
+	if trade.Status == sandboxSuccess || trade.Status == sandboxRefund {
+		writeSandboxError(w, http.StatusConflict, "ORDER_PAID", "trade already paid")
+		return
+	}
+	trade.Status = sandboxClosed
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *SandboxServer) handleRefund(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OutRefundNo string `json:"out_refund_no"`
+		AmountCents int64  `json:"amount_cents"`
+		Reason      string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OutRefundNo == "" {
+		writeSandboxError(w, http.StatusBadRequest, "PARAM_ERROR", "invalid refund")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	trade, ok := s.trades[r.PathValue("outTradeNo")]
+	if !ok {
+		writeSandboxError(w, http.StatusNotFound, "ORDER_NOT_EXIST", "trade not found")
+		return
+	}
+	// 同一退款单号重复提交返回原结果
+	if existing, ok := s.refunds[req.OutRefundNo]; ok {
+		if existing.OutTradeNo != trade.OutTradeNo || existing.AmountCents != req.AmountCents {
+			writeSandboxError(w, http.StatusConflict, "INVALID_REQUEST", "out_refund_no reused with different parameters")
+			return
+		}
+		writeSandboxJSON(w, http.StatusOK, existing)
+		return
+	}
+	if trade.Status != sandboxSuccess && trade.Status != sandboxRefund {
+		writeSandboxError(w, http.StatusConflict, "TRADE_NOT_PAID", "trade not paid")
+		return
+	}
+	if req.AmountCents <= 0 || trade.RefundCents+req.AmountCents > trade.AmountCents {
+		writeSandboxError(w, http.StatusBadRequest, "REFUND_AMOUNT_INVALID", "refund exceeds paid amount")
+		return
+	}
+	now := s.now()
+	trade.RefundCents += req.AmountCents
+	trade.Status = sandboxRefund
+	refund := &sandboxRefundRecord{
+		OutTradeNo:  trade.OutTradeNo,
+		OutRefundNo: req.OutRefundNo,
+		RefundNo:    fmt.Sprintf("%s_refund_%d_%d", sandboxRefundPrefix(trade.Method), now.UnixNano(), trade.RefundCents),
+		AmountCents: req.AmountCents,
+		Reason:      req.Reason,
+		Status:      sandboxRefundSuccess,
+		RefundedAt:  &now,
+	}
+	if s.asyncRefunds {
+		refund.Status = sandboxRefundProcessing
+		refund.RefundedAt = nil
+	}
+	s.refunds[req.OutRefundNo] = refund
+	writeSandboxJSON(w, http.StatusOK, refund)
+}
+
+func (s *SandboxServer) handleRefundQuery(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refund, ok := s.refunds[r.PathValue("outRefundNo")]
+	if !ok {
+		writeSandboxError(w, http.StatusNotFound, "RESOURCE_NOT_EXISTS", "refund not found")
+		return
+	}
+	writeSandboxJSON(w, http.StatusOK, refund)
+}
+
+func (s *SandboxServer) handlePay(w http.ResponseWriter, r *http.Request) {
+	outTradeNo := r.PathValue("outTradeNo")
+	if err := s.Pay(r.Context(), outTradeNo); err != nil {
+		writeSandboxError(w, http.StatusBadGateway, "PAY_FAILED", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeSandboxJSON(w, http.StatusOK, s.trades[outTradeNo])
+}
+
+func sandboxTradePrefix(method model.PaymentMethod) string {
+	switch method {
+	case model.PaymentMethodWeChat:
+		return "4200"
+	case model.PaymentMethodAlipay:
+		return "2088"
+	default:
+		return "9999"
+	}
+}
+
+func sandboxRefundPrefix(method model.PaymentMethod) string {
+	switch method {
+	case model.PaymentMethodWeChat:
+		return "wx"
+	case model.PaymentMethodAlipay:
+		return "ali"
+	default:
+		return "sandbox"
+	}
+}
+
+func writeSandboxJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeSandboxError(w http.ResponseWriter, status int, code, msg string) {
+	writeSandboxJSON(w, status, map[string]string{"code": code, "message": msg})
+}
+
+// SignSandboxPayload 生成沙箱回调签名头：HMAC-SHA256(timestamp\nnonce\nbody\n)。
+func SignSandboxPayload(secret, body []byte, now time.Time) http.Header {
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	n := hex.EncodeToString(nonce)
+	h := http.Header{}
+	h.Set(SandboxHeaderTimestamp, ts)
+	h.Set(SandboxHeaderNonce, n)
+	h.Set(SandboxHeaderSignature, sandboxSignature(secret, ts, n, body))
+	return h
+}
+
+func sandboxSignature(secret []byte, ts, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "\n" + nonce + "\n"))
+	mac.Write(body)
+	mac.Write([]byte("\n"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SandboxConfig 沙箱网关配置。
+type SandboxConfig struct {
+	// BaseURL 沙箱服务地址，例如 http://127.0.0.1:8080/api/v1/sandbox/pay
+	BaseURL    string
+	Secret     string
+	HTTPClient *http.Client
+}
+
+// SandboxGateway 对接 SandboxServer 的网关实现。
+type SandboxGateway struct {
+	method  model.PaymentMethod
+	baseURL string
+	secret  []byte
+	client  *http.Client
+	now     func() time.Time
+}
+
+// NewSandboxGateway 创建指定支付方式的沙箱网关。
+func NewSandboxGateway(method model.PaymentMethod, cfg SandboxConfig) *SandboxGateway {
+	secret := cfg.Secret
+	if secret == "" {
+		secret = DefaultSandboxSecret
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &SandboxGateway{
+		method:  method,
+		baseURL: trimTrailingSlash(cfg.BaseURL),
+		secret:  []byte(secret),
+		client:  client,
+		now:     time.Now,
+	}
+}
+
+// NewInProcessSandboxGateway 创建直接调用 server 的沙箱网关（不经过网络）。
+func NewInProcessSandboxGateway(method model.PaymentMethod, server *SandboxServer) *SandboxGateway {
+	return NewMountedSandboxGateway(method, server, "http://sandbox.local")
+}
+
+// NewMountedSandboxGateway 创建进程内沙箱网关，server 同时挂载在 publicBaseURL 下，
+// 使返回给前端的 pay_url 可以直接访问。
+func NewMountedSandboxGateway(method model.PaymentMethod, server *SandboxServer, publicBaseURL string) *SandboxGateway {
+	var handler http.Handler = server
+	if u, err := url.Parse(publicBaseURL); err == nil && trimTrailingSlash(u.Path) != "" {
+		handler = http.StripPrefix(trimTrailingSlash(u.Path), server)
+	}
+	return NewSandboxGateway(method, SandboxConfig{
+		BaseURL:    publicBaseURL,
+		Secret:     string(server.secret),
+		HTTPClient: &http.Client{Transport: handlerTransport{h: handler}},
+	})
+}
+
+// Method implements Gateway.
+func (g *SandboxGateway) Method() model.PaymentMethod { return g.method }
+
+// CreatePrepay implements Gateway.
+func (g *SandboxGateway) CreatePrepay(ctx context.Context, req PrepayRequest) (*PrepayResult, error) {
+	var trade sandboxTrade
+	raw, err := g.do(ctx, http.MethodPost, "/v1/trades", sandboxTrade{
+		Method:      g.method,
+		OutTradeNo:  req.OutTradeNo,
+		AmountCents: req.AmountCents,
+		Currency:    currencyOrDefault(req.Currency),
+		Description: req.Description,
+		NotifyURL:   req.NotifyURL,
+	}, &trade)
+	if err != nil {
+		return nil, err
+	}
+	payURL := g.baseURL + "/v1/trades/" + url.PathEscape(trade.OutTradeNo) + "/pay"
+	payInfo := map[string]interface{}{
+		"sandbox":  true,
+		"trade_no": trade.TradeNo,
+		"pay_url":  payURL,
+	}
+	switch g.method {
+	case model.PaymentMethodWeChat:
+		payInfo["code_url"] = "weixin://wxpay/bizpayurl?pr=" + trade.TradeNo
+	case model.PaymentMethodAlipay:
+		payInfo["qr_code"] = "https://qr.alipay.com/" + trade.TradeNo
+	}
+	return &PrepayResult{PayInfo: payInfo, Raw: raw}, nil
+}
+
+// Query implements Gateway.
+func (g *SandboxGateway) Query(ctx context.Context, outTradeNo string) (*TradeResult, error) {
+	var trade sandboxTrade
+	raw, err := g.do(ctx, http.MethodGet, "/v1/trades/"+url.PathEscape(outTradeNo), nil, &trade)
+	if err != nil {
+		return nil, err
+	}
+	return trade.toResult(raw), nil
+}
+
+// Close implements Gateway.
+func (g *SandboxGateway) Close(ctx context.Context, outTradeNo string) error {
+	_, err := g.do(ctx, http.MethodPost, "/v1/trades/"+url.PathEscape(outTradeNo)+"/close", nil, nil)
+	return err
+}
+
+// Refund implements ProviderClient.
+func (g *SandboxGateway) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	if req.OutTradeNo == "" {
+		return nil, fmt.Errorf("sandbox: refund %s has no out_trade_no", req.OutRefundNo)
+	}
+	var refund sandboxRefundRecord
+	raw, err := g.do(ctx, http.MethodPost, "/v1/trades/"+url.PathEscape(req.OutTradeNo)+"/refund", map[string]interface{}{
+		"out_refund_no": req.OutRefundNo,
+		"amount_cents":  req.AmountCents,
+		"reason":        req.Reason,
+	}, &refund)
+	if err != nil {
+		return nil, err
+	}
+	return refund.toResult(raw), nil
+}
+
+// QueryRefund implements ProviderClient.
+func (g *SandboxGateway) QueryRefund(ctx context.Context, _ string, outRefundNo string) (*RefundResult, error) {
+	var refund sandboxRefundRecord
+	raw, err := g.do(ctx, http.MethodGet, "/v1/refunds/"+url.PathEscape(outRefundNo), nil, &refund)
+	if err != nil {
+		return nil, err
+	}
+	return refund.toResult(raw), nil
+}
+
+// VerifyNotification implements Gateway.
+func (g *SandboxGateway) VerifyNotification(_ context.Context, header http.Header, body []byte) (*TradeResult, error) {
+	ts := header.Get(SandboxHeaderTimestamp)
+	if err := checkTimestamp(ts, g.now()); err != nil {
+		return nil, err
+	}
+	expected := sandboxSignature(g.secret, ts, header.Get(SandboxHeaderNonce), body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(SandboxHeaderSignature))) {
+		return nil, ErrInvalidSignature
+	}
+	var trade sandboxTrade
+	if err := json.Unmarshal(body, &trade); err != nil {
+		return nil, fmt.Errorf("sandbox: decode notification: %w", err)
+	}
+	if trade.Method != "" && trade.Method != g.method {
+		return nil, fmt.Errorf("sandbox: notification for %s delivered to %s", trade.Method, g.method)
+	}
+	return trade.toResult(json.RawMessage(body)), nil
+}
+
+// NotificationAck implements Gateway.
+func (g *SandboxGateway) NotificationAck(ok bool) (int, string, []byte) {
+	if ok {
+		return http.StatusOK, "application/json", []byte(`{"code":"SUCCESS"}`)
+	}
+	return http.StatusInternalServerError, "application/json", []byte(`{"code":"FAIL"}`)
+}
+
+func (g *SandboxGateway) do(ctx context.Context, method, path string, in, out interface{}) (json.RawMessage, error) {
+	if g.baseURL == "" {
+		return nil, ErrGatewayNotConfigured
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(raw, &e)
+		return nil, fmt.Errorf("sandbox: %s: %s", e.Code, e.Message)
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return nil, fmt.Errorf("sandbox: decode response: %w", err)
+		}
+	}
+	return json.RawMessage(raw), nil
+}
+
+func (t *sandboxTrade) toResult(raw json.RawMessage) *TradeResult {
+	state := TradeStatePending
+	switch t.Status {
+	case sandboxSuccess:
+		state = TradeStatePaid
+	case sandboxClosed:
+		state = TradeStateClosed
+	case sandboxRefund:
+		state = TradeStateRefunded
+	}
+	return &TradeResult{
+		OutTradeNo:      t.OutTradeNo,
+		ProviderTradeNo: t.TradeNo,
+		State:           state,
+		AmountCents:     t.AmountCents,
+		Currency:        currencyOrDefault(t.Currency),
+		PaidAt:          t.PaidAt,
+		Raw:             raw,
+	}
+}
+
+func (r *sandboxRefundRecord) toResult(raw json.RawMessage) *RefundResult {
+	state := RefundStateProcessing
+	if r.Status == sandboxRefundSuccess {
+		state = RefundStateSucceeded
+	}
+	return &RefundResult{
+		OutRefundNo:      r.OutRefundNo,
+		ProviderRefundNo: r.RefundNo,
+		State:            state,
+		RefundedAt:       r.RefundedAt,
+		Raw:              raw,
+	}
+}
+
+// handlerTransport 将请求直接交给 http.Handler 处理（进程内沙箱）。
+type handlerTransport struct {
+	h http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil {
+		req.Body = http.NoBody
+	}
+	rec := newResponseRecorder()
+	t.h.ServeHTTP(rec, req)
+	return &http.Response{
+		StatusCode: rec.status,
+		Status:     http.StatusText(rec.status),
+		Header:     rec.header,
+		Body:       io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+		Request:    req,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}, nil
+}
+
+type responseRecorder struct {
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{status: http.StatusOK, header: http.Header{}}
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+
+func (r *responseRecorder) WriteHeader(status int) { r.status = status }
+
+func trimTrailingSlash(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '/' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+var _ Gateway = (*SandboxGateway)(nil)
@@ -0,0 +1,200 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+)
+
+// newTestPaymentService 返回接入进程内沙箱网关的支付服务。
+func newTestPaymentService(payments repository.PaymentRepository, orders repository.OrderRepository) *PaymentService {
+	svc := NewPaymentService(payments, orders)
+	sandbox := NewSandboxServer("test-secret")
+	svc.SetGateways(
+		NewInProcessSandboxGateway(model.PaymentMethodWeChat, sandbox),
+		NewInProcessSandboxGateway(model.PaymentMethodAlipay, sandbox),
+	)
+	return svc
+}
+
+// newSandboxPaymentService 返回接入独立进程内沙箱的支付服务，便于测试直接驱动渠道状态。
+func newSandboxPaymentService(payments *mockPaymentRepository, orders *mockOrderRepository) (*PaymentService, *SandboxServer) {
+	svc := NewPaymentService(payments, orders)
+	svc.SetRefundRepository(newMockRefundRepository())
+	sandbox := NewSandboxServer("test-secret")
+	svc.SetGateway(NewInProcessSandboxGateway(model.PaymentMethodWeChat, sandbox))
+	svc.SetGateway(NewInProcessSandboxGateway(model.PaymentMethodAlipay, sandbox))
+	return svc, sandbox
+}
+
+// TestSandbox_EndToEndPendingToPaid 通过 HTTP 沙箱跑通 下单 → 付款 → 签名回调 → 订单确认。
+func TestSandbox_EndToEndPendingToPaid(t *testing.T) {
+	ctx := context.Background()
+	paymentRepo := newMockPaymentRepository()
+	orderRepo := newMockOrderRepository()
+	svc := NewPaymentService(paymentRepo, orderRepo)
+
+	const secret = "e2e-secret"
+	sandbox := NewSandboxServer(secret)
+	var lastCallback []byte
+	var lastHeader http.Header
+	mux := http.NewServeMux()
+	mux.Handle("/sandbox/", http.StripPrefix("/sandbox", sandbox))
+	mux.HandleFunc("POST /notify/{method}", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lastCallback, lastHeader = body, r.Header.Clone()
+		method := model.PaymentMethod(r.PathValue("method"))
+		err := svc.HandleNotification(r.Context(), method, r.Header, body)
+		status, contentType, ack := svc.NotificationAck(method, err == nil)
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		_, _ = w.Write(ack)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	svc.SetGateway(NewSandboxGateway(model.PaymentMethodWeChat, SandboxConfig{BaseURL: srv.URL + "/sandbox", Secret: secret}))
+	svc.SetNotifyBaseURL(srv.URL + "/notify/")
+
+	order := &model.Order{UserID: 5, Status: model.OrderStatusPending, TotalPriceCents: 6600, Currency: model.CurrencyCNY, OrderNo: "ESC-E2E"}
+	require.NoError(t, orderRepo.Create(ctx, order))
+
+	resp, err := svc.CreatePayment(ctx, 5, CreatePaymentRequest{OrderID: order.ID, Method: model.PaymentMethodWeChat})
+	require.NoError(t, err)
+	payment := paymentRepo.payments[resp.PaymentID]
+	assert.Equal(t, model.PaymentStatusPending, payment.Status)
+	assert.Equal(t, model.OrderStatusPending, order.Status)
+	assert.NotEmpty(t, payment.OutTradeNo)
+	assert.NotEmpty(t, payment.ProviderRaw)
+
+	// 模拟用户扫码付款，沙箱随后推送签名回调
+	payResp, err := http.Post(resp.PayInfo["pay_url"].(string), "application/json", nil)
+	require.NoError(t, err)
+	_ = payResp.Body.Close()
+	require.Equal(t, http.StatusOK, payResp.StatusCode)
+
+	assert.Equal(t, model.PaymentStatusPaid, payment.Status)
+	assert.True(t, strings.HasPrefix(payment.ProviderTradeNo, "4200"))
+	assert.NotNil(t, payment.PaidAt)
+	assert.Equal(t, model.OrderStatusConfirmed, order.Status)
+
+	t.Run("replayed callback is acknowledged without side effects", func(t *testing.T) {
+		require.NoError(t, svc.HandleNotification(ctx, model.PaymentMethodWeChat, lastHeader, lastCallback))
+		assert.Equal(t, model.PaymentStatusPaid, payment.Status)
+	})
+
+	t.Run("tampered body is rejected", func(t *testing.T) {
+		tampered := bytes.Replace(lastCallback, []byte(`"amount_cents":6600`), []byte(`"amount_cents":1`), 1)
+		err := svc.HandleNotification(ctx, model.PaymentMethodWeChat, lastHeader, tampered)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("stale timestamp is rejected", func(t *testing.T) {
+		header := SignSandboxPayload([]byte(secret), lastCallback, time.Now().Add(-time.Hour))
+		err := svc.HandleNotification(ctx, model.PaymentMethodWeChat, header, lastCallback)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+}
+
+func TestSandbox_CallbackAmountMismatch(t *testing.T) {
+	ctx := context.Background()
+	paymentRepo := newMockPaymentRepository()
+	orderRepo := newMockOrderRepository()
+	svc, _ := newSandboxPaymentService(paymentRepo, orderRepo)
+
+	order := &model.Order{UserID: 1, Status: model.OrderStatusPending, TotalPriceCents: 1000}
+	require.NoError(t, orderRepo.Create(ctx, order))
+	resp, err := svc.CreatePayment(ctx, 1, CreatePaymentRequest{OrderID: order.ID, Method: model.PaymentMethodAlipay})
+	require.NoError(t, err)
+	payment := paymentRepo.payments[resp.PaymentID]
+
+	body, _ := json.Marshal(sandboxTrade{
+		Method:      model.PaymentMethodAlipay,
+		OutTradeNo:  payment.OutTradeNo,
+		TradeNo:     "2088-forged",
+		Status:      sandboxSuccess,
+		AmountCents: 1,
+		Currency:    model.CurrencyCNY,
+	})
+	err = svc.HandleNotification(ctx, model.PaymentMethodAlipay, SignSandboxPayload([]byte("test-secret"), body, time.Now()), body)
+	assert.ErrorIs(t, err, ErrAmountMismatch)
+	assert.Equal(t, model.PaymentStatusPending, payment.Status)
+	assert.Equal(t, model.OrderStatusPending, order.Status)
+}
+
+func TestSandbox_CancelClosesTrade(t *testing.T) {
+	ctx := context.Background()
+	paymentRepo := newMockPaymentRepository()
+	orderRepo := newMockOrderRepository()
+	svc, sandbox := newSandboxPaymentService(paymentRepo, orderRepo)
+
+	order := &model.Order{UserID: 3, Status: model.OrderStatusPending, TotalPriceCents: 500}
+	require.NoError(t, orderRepo.Create(ctx, order))
+	resp, err := svc.CreatePayment(ctx, 3, CreatePaymentRequest{OrderID: order.ID, Method: model.PaymentMethodWeChat})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.CancelPayment(ctx, 3, resp.PaymentID))
+	assert.Equal(t, model.PaymentStatusFailed, paymentRepo.payments[resp.PaymentID].Status)
+
+	// 关单后渠道侧不再允许付款
+	assert.Error(t, sandbox.Pay(ctx, paymentRepo.payments[resp.PaymentID].OutTradeNo))
+}
+
+func TestYuanConversion(t *testing.T) {
+	assert.Equal(t, "0.01", formatYuan(1))
+	assert.Equal(t, "123.45", formatYuan(12345))
+	assert.Equal(t, "-1.50", formatYuan(-150))
+
+	for in, want := range map[string]int64{"0.01": 1, "12": 1200, "12.3": 1230, "12.34": 1234} {
+		got, err := parseYuan(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := parseYuan("1.234")
+	assert.Error(t, err)
+	_, err = parseYuan("abc")
+	assert.Error(t, err)
+}
+
+func TestSandbox_MountedGateway(t *testing.T) {
+	ctx := context.Background()
+	sandbox := NewSandboxServer("mounted")
+	gw := NewMountedSandboxGateway(model.PaymentMethodAlipay, sandbox, "https://api.example.com/api/v1/sandbox/pay/")
+
+	prepay, err := gw.CreatePrepay(ctx, PrepayRequest{OutTradeNo: "PAY-MOUNT", AmountCents: 100})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/api/v1/sandbox/pay/v1/trades/PAY-MOUNT/pay", prepay.PayInfo["pay_url"])
+
+	require.NoError(t, sandbox.Pay(ctx, "PAY-MOUNT"))
+	result, err := gw.Query(ctx, "PAY-MOUNT")
+	require.NoError(t, err)
+	assert.Equal(t, TradeStatePaid, result.State)
+}
+
+func TestPaymentService_SetGatewaysReplacesRegistered(t *testing.T) {
+	orderRepo := newMockOrderRepository()
+	svc := NewPaymentService(newMockPaymentRepository(), orderRepo)
+	svc.SetGateways(NewSandboxGateway(model.PaymentMethodAlipay, SandboxConfig{}))
+	order := &model.Order{UserID: 1, Status: model.OrderStatusPending, TotalPriceCents: 100}
+	require.NoError(t, orderRepo.Create(context.Background(), order))
+
+	_, ok := svc.Gateway(model.PaymentMethodWeChat)
+	assert.False(t, ok)
+	_, ok = svc.Gateway(model.PaymentMethodAlipay)
+	assert.True(t, ok)
+
+	_, err := svc.CreatePayment(context.Background(), 1, CreatePaymentRequest{OrderID: order.ID, Method: model.PaymentMethodWeChat})
+	assert.ErrorIs(t, err, ErrGatewayNotConfigured)
+}
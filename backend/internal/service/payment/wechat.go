@@ -0,0 +1,385 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gamelink/internal/model"
+)
+
+// 微信支付 APIv3 签名相关请求头。
+const (
+	wechatHeaderTimestamp = "Wechatpay-Timestamp"
+	wechatHeaderNonce     = "Wechatpay-Nonce"
+	wechatHeaderSignature = "Wechatpay-Signature"
+	wechatHeaderSerial    = "Wechatpay-Serial"
+
+	wechatDefaultBaseURL = "https://api.mch.weixin.qq.com"
+)
+
+// WeChatConfig 微信支付（APIv3，Native 下单）配置。
+type WeChatConfig struct {
+	AppID string
+	MchID string
+	// MerchantSerialNo 商户 API 证书序列号
+	MerchantSerialNo string
+	// PrivateKey 商户 API 私钥，用于请求签名
+	PrivateKey *rsa.PrivateKey
+	// APIv3Key 用于解密回调报文（32 字节）
+	APIv3Key string
+	// PlatformPublicKey 微信支付平台公钥，用于验证应答与回调签名
+	PlatformPublicKey *rsa.PublicKey
+	// PlatformSerialNo 平台证书/公钥序列号（可选，用于校验 Wechatpay-Serial）
+	PlatformSerialNo string
+	BaseURL          string
+	HTTPClient       *http.Client
+}
+
+// WeChatGateway 微信支付 APIv3 网关。
+type WeChatGateway struct {
+	cfg    WeChatConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWeChatGateway 创建微信支付网关。
+func NewWeChatGateway(cfg WeChatConfig) *WeChatGateway {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = wechatDefaultBaseURL
+	}
+	cfg.BaseURL = trimTrailingSlash(cfg.BaseURL)
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WeChatGateway{cfg: cfg, client: client, now: time.Now}
+}
+
+// wechatAmount 金额结构（单位：分）。
+type wechatAmount struct {
+	Total    int64  `json:"total"`
+	Refund   int64  `json:"refund,omitempty"`
+	Currency string `json:"currency,omitempty"`
+}
+
+// wechatTransaction 查询应答与回调解密后的交易结构。
+type wechatTransaction struct {
+	AppID         string       `json:"appid"`
+	MchID         string       `json:"mchid"`
+	OutTradeNo    string       `json:"out_trade_no"`
+	TransactionID string       `json:"transaction_id"`
+	TradeState    string       `json:"trade_state"`
+	SuccessTime   string       `json:"success_time"`
+	Amount        wechatAmount `json:"amount"`
+}
+
+// Method implements Gateway.
+func (g *WeChatGateway) Method() model.PaymentMethod { return model.PaymentMethodWeChat }
+
+// CreatePrepay implements Gateway（Native 下单，返回 code_url）。
+func (g *WeChatGateway) CreatePrepay(ctx context.Context, req PrepayRequest) (*PrepayResult, error) {
+	body := map[string]interface{}{
+		"appid":        g.cfg.AppID,
+		"mchid":        g.cfg.MchID,
+		"description":  req.Description,
+		"out_trade_no": req.OutTradeNo,
+		"notify_url":   req.NotifyURL,
+		"amount": wechatAmount{
+			Total:    req.AmountCents,
+			Currency: string(currencyOrDefault(req.Currency)),
+		},
+	}
+	if !req.ExpireAt.IsZero() {
+		body["time_expire"] = req.ExpireAt.Format(time.RFC3339)
+	}
+	var resp struct {
+		CodeURL string `json:"code_url"`
+	}
+	raw, err := g.do(ctx, http.MethodPost, "/v3/pay/transactions/native", body, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &PrepayResult{
+		PayInfo: map[string]interface{}{"code_url": resp.CodeURL},
+		Raw:     raw,
+	}, nil
+}
+
+// Query implements Gateway.
+func (g *WeChatGateway) Query(ctx context.Context, outTradeNo string) (*TradeResult, error) {
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(outTradeNo) + "?mchid=" + url.QueryEscape(g.cfg.MchID)
+	var tx wechatTransaction
+	raw, err := g.do(ctx, http.MethodGet, path, nil, &tx)
+	if err != nil {
+		return nil, err
+	}
+	return tx.toResult(raw), nil
+}
+
+// Close implements Gateway.
+func (g *WeChatGateway) Close(ctx context.Context, outTradeNo string) error {
+	path := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(outTradeNo) + "/close"
+	_, err := g.do(ctx, http.MethodPost, path, map[string]string{"mchid": g.cfg.MchID}, nil)
+	return err
+}
+
+// Refund implements ProviderClient（支持部分退款，out_refund_no 由调用方生成）。
+func (g *WeChatGateway) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	body := map[string]interface{}{
+		"out_trade_no":  req.OutTradeNo,
+		"out_refund_no": req.OutRefundNo,
+		"reason":        req.Reason,
+		"amount": wechatAmount{
+			Refund:   req.AmountCents,
+			Total:    req.TotalCents,
+			Currency: string(currencyOrDefault(req.Currency)),
+		},
+	}
+	var resp wechatRefund
+	raw, err := g.do(ctx, http.MethodPost, "/v3/refund/domestic/refunds", body, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.toResult(raw), nil
+}
+
+// QueryRefund implements ProviderClient.
+func (g *WeChatGateway) QueryRefund(ctx context.Context, _ string, outRefundNo string) (*RefundResult, error) {
+	var resp wechatRefund
+	raw, err := g.do(ctx, http.MethodGet, "/v3/refund/domestic/refunds/"+url.PathEscape(outRefundNo), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.toResult(raw), nil
+}
+
+// wechatRefund 退款申请与查询应答。
+type wechatRefund struct {
+	RefundID    string `json:"refund_id"`
+	OutRefundNo string `json:"out_refund_no"`
+	Status      string `json:"status"`
+	SuccessTime string `json:"success_time"`
+}
+
+func (r *wechatRefund) toResult(raw json.RawMessage) *RefundResult {
+	result := &RefundResult{
+		OutRefundNo:      r.OutRefundNo,
+		ProviderRefundNo: r.RefundID,
+		State:            RefundStateProcessing,
+		Raw:              raw,
+	}
+	switch r.Status {
+	case "SUCCESS":
+		result.State = RefundStateSucceeded
+		if t, err := time.Parse(time.RFC3339, r.SuccessTime); err == nil {
+			result.RefundedAt = &t
+		}
+	case "CLOSED", "ABNORMAL":
+		result.State = RefundStateFailed
+	}
+	return result
+}
+
+// VerifyNotification implements Gateway：验签并解密 AEAD_AES_256_GCM 报文。
+func (g *WeChatGateway) VerifyNotification(_ context.Context, header http.Header, body []byte) (*TradeResult, error) {
+	if err := g.verify(header, body); err != nil {
+		return nil, err
+	}
+	var notice struct {
+		EventType string `json:"event_type"`
+		Resource  struct {
+			Algorithm      string `json:"algorithm"`
+			Ciphertext     string `json:"ciphertext"`
+			AssociatedData string `json:"associated_data"`
+			Nonce          string `json:"nonce"`
+		} `json:"resource"`
+	}
+	if err := json.Unmarshal(body, &notice); err != nil {
+		return nil, fmt.Errorf("wechatpay: decode notification: %w", err)
+	}
+	if notice.Resource.Algorithm != "AEAD_AES_256_GCM" {
+		return nil, fmt.Errorf("wechatpay: unsupported algorithm %q", notice.Resource.Algorithm)
+	}
+	plain, err := decryptWeChatResource(g.cfg.APIv3Key, notice.Resource.Nonce, notice.Resource.AssociatedData, notice.Resource.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	var tx wechatTransaction
+	if err := json.Unmarshal(plain, &tx); err != nil {
+		return nil, fmt.Errorf("wechatpay: decode transaction: %w", err)
+	}
+	if g.cfg.MchID != "" && tx.MchID != "" && tx.MchID != g.cfg.MchID {
+		return nil, fmt.Errorf("wechatpay: mchid mismatch")
+	}
+	return tx.toResult(json.RawMessage(plain)), nil
+}
+
+// NotificationAck implements Gateway.
+func (g *WeChatGateway) NotificationAck(ok bool) (int, string, []byte) {
+	if ok {
+		return http.StatusOK, "application/json", []byte(`{"code":"SUCCESS","message":"成功"}`)
+	}
+	return http.StatusInternalServerError, "application/json", []byte(`{"code":"FAIL","message":"失败"}`)
+}
+
+// verify 校验应答或回调签名：SHA256withRSA(timestamp\nnonce\nbody\n)。
+func (g *WeChatGateway) verify(header http.Header, body []byte) error {
+	if g.cfg.PlatformPublicKey == nil {
+		return ErrGatewayNotConfigured
+	}
+	if serial := header.Get(wechatHeaderSerial); g.cfg.PlatformSerialNo != "" && serial != g.cfg.PlatformSerialNo {
+		return fmt.Errorf("%w: unknown platform serial %s", ErrInvalidSignature, serial)
+	}
+	ts := header.Get(wechatHeaderTimestamp)
+	if err := checkTimestamp(ts, g.now()); err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(header.Get(wechatHeaderSignature))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	message := ts + "\n" + header.Get(wechatHeaderNonce) + "\n" + string(body) + "\n"
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(g.cfg.PlatformPublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// authorization 生成 APIv3 请求签名头。
+func (g *WeChatGateway) authorization(method, pathWithQuery string, body []byte) (string, error) {
+	if g.cfg.PrivateKey == nil || g.cfg.MchID == "" {
+		return "", ErrGatewayNotConfigured
+	}
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	ts := strconv.FormatInt(g.now().Unix(), 10)
+	message := method + "\n" + pathWithQuery + "\n" + ts + "\n" + nonce + "\n" + string(body) + "\n"
+	digest := sha256.Sum256([]byte(message))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, g.cfg.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`WECHATPAY2-SHA256-RSA2048 mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		g.cfg.MchID, nonce, base64.StdEncoding.EncodeToString(sig), ts, g.cfg.MerchantSerialNo), nil
+}
+
+func (g *WeChatGateway) do(ctx context.Context, method, pathWithQuery string, in, out interface{}) (json.RawMessage, error) {
+	var payload []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		payload = b
+	}
+	auth, err := g.authorization(method, pathWithQuery, payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.cfg.BaseURL+pathWithQuery, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("wechatpay: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(raw, &e)
+		return nil, fmt.Errorf("wechatpay: %d %s: %s", resp.StatusCode, e.Code, e.Message)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		if err := g.verify(resp.Header, raw); err != nil {
+			return nil, fmt.Errorf("wechatpay: response %w", err)
+		}
+	}
+	if out != nil && len(raw) > 0 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return nil, fmt.Errorf("wechatpay: decode response: %w", err)
+		}
+	}
+	return json.RawMessage(raw), nil
+}
+
+func (tx *wechatTransaction) toResult(raw json.RawMessage) *TradeResult {
+	state := TradeStatePending
+	switch tx.TradeState {
+	case "SUCCESS":
+		state = TradeStatePaid
+	case "CLOSED", "REVOKED":
+		state = TradeStateClosed
+	case "PAYERROR":
+		state = TradeStateFailed
+	case "REFUND":
+		state = TradeStateRefunded
+	}
+	result := &TradeResult{
+		OutTradeNo:      tx.OutTradeNo,
+		ProviderTradeNo: tx.TransactionID,
+		State:           state,
+		AmountCents:     tx.Amount.Total,
+		Currency:        currencyOrDefault(model.Currency(tx.Amount.Currency)),
+		Raw:             raw,
+	}
+	if t, err := time.Parse(time.RFC3339, tx.SuccessTime); err == nil {
+		result.PaidAt = &t
+	}
+	return result
+}
+
+// decryptWeChatResource 解密回调 resource（AEAD_AES_256_GCM）。
+func decryptWeChatResource(apiV3Key, nonce, associatedData, ciphertext string) ([]byte, error) {
+	if len(apiV3Key) != 32 {
+		return nil, ErrGatewayNotConfigured
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("wechatpay: decode ciphertext: %w", err)
+	}
+	block, err := aes.NewCipher([]byte(apiV3Key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, []byte(nonce), data, []byte(associatedData))
+	if err != nil {
+		return nil, fmt.Errorf("%w: decrypt resource", ErrInvalidSignature)
+	}
+	return plain, nil
+}
+
+var _ Gateway = (*WeChatGateway)(nil)
@@ -0,0 +1,198 @@
+package payment
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/model"
+)
+
+const testAPIv3Key = "0123456789abcdef0123456789abcdef"
+
+func wechatSign(t *testing.T, key *rsa.PrivateKey, message string) string {
+	t.Helper()
+	digest := sha256.Sum256([]byte(message))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func wechatSignedHeader(t *testing.T, platformKey *rsa.PrivateKey, body []byte, ts time.Time) http.Header {
+	t.Helper()
+	h := http.Header{}
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	h.Set(wechatHeaderTimestamp, timestamp)
+	h.Set(wechatHeaderNonce, "nonce123")
+	h.Set(wechatHeaderSerial, "PLATFORM1")
+	h.Set(wechatHeaderSignature, wechatSign(t, platformKey, timestamp+"\nnonce123\n"+string(body)+"\n"))
+	return h
+}
+
+func TestWeChatGateway_PrepayAndQuery(t *testing.T) {
+	merchantKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	authRe := regexp.MustCompile(`nonce_str="([^"]+)",signature="([^"]+)",timestamp="([^"]+)"`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		m := authRe.FindStringSubmatch(r.Header.Get("Authorization"))
+		require.Len(t, m, 4)
+		sig, _ := base64.StdEncoding.DecodeString(m[2])
+		digest := sha256.Sum256([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n" + m[3] + "\n" + m[1] + "\n" + string(body) + "\n"))
+		if rsa.VerifyPKCS1v15(&merchantKey.PublicKey, crypto.SHA256, digest[:], sig) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"SIGN_ERROR","message":"bad signature"}`))
+			return
+		}
+
+		var resp []byte
+		switch r.URL.Path {
+		case "/v3/pay/transactions/native":
+			var req map[string]interface{}
+			require.NoError(t, json.Unmarshal(body, &req))
+			assert.Equal(t, "PAY001", req["out_trade_no"])
+			assert.Equal(t, "https://example.com/notify/wechat", req["notify_url"])
+			resp = []byte(`{"code_url":"weixin://wxpay/bizpayurl?pr=abc"}`)
+		case "/v3/pay/transactions/out-trade-no/PAY001":
+			assert.Equal(t, "1900000001", r.URL.Query().Get("mchid"))
+			resp = []byte(`{"mchid":"1900000001","out_trade_no":"PAY001","transaction_id":"4200000001","trade_state":"SUCCESS","success_time":"2025-01-02T15:04:05+08:00","amount":{"total":1999,"currency":"CNY"}}`)
+		case "/v3/refund/domestic/refunds":
+			var req struct {
+				OutRefundNo string       `json:"out_refund_no"`
+				Amount      wechatAmount `json:"amount"`
+			}
+			require.NoError(t, json.Unmarshal(body, &req))
+			assert.Equal(t, "RF001", req.OutRefundNo)
+			assert.Equal(t, int64(500), req.Amount.Refund)
+			assert.Equal(t, int64(1999), req.Amount.Total)
+			resp = []byte(`{"refund_id":"5030000001","out_refund_no":"RF001","status":"PROCESSING"}`)
+		case "/v3/refund/domestic/refunds/RF001":
+			resp = []byte(`{"refund_id":"5030000001","out_refund_no":"RF001","status":"SUCCESS","success_time":"2025-01-03T10:00:00+08:00"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range wechatSignedHeader(t, platformKey, resp, time.Now()) {
+			w.Header()[k] = v
+		}
+		_, _ = w.Write(resp)
+	}))
+	defer srv.Close()
+
+	gw := NewWeChatGateway(WeChatConfig{
+		AppID:             "wx123",
+		MchID:             "1900000001",
+		MerchantSerialNo:  "MERCHANT1",
+		PrivateKey:        merchantKey,
+		APIv3Key:          testAPIv3Key,
+		PlatformPublicKey: &platformKey.PublicKey,
+		PlatformSerialNo:  "PLATFORM1",
+		BaseURL:           srv.URL,
+	})
+
+	ctx := context.Background()
+	prepay, err := gw.CreatePrepay(ctx, PrepayRequest{
+		OutTradeNo:  "PAY001",
+		Description: "test",
+		AmountCents: 1999,
+		NotifyURL:   "https://example.com/notify/wechat",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "weixin://wxpay/bizpayurl?pr=abc", prepay.PayInfo["code_url"])
+
+	result, err := gw.Query(ctx, "PAY001")
+	require.NoError(t, err)
+	assert.Equal(t, TradeStatePaid, result.State)
+	assert.Equal(t, "4200000001", result.ProviderTradeNo)
+	assert.Equal(t, int64(1999), result.AmountCents)
+	require.NotNil(t, result.PaidAt)
+
+	refund, err := gw.Refund(ctx, RefundRequest{OutTradeNo: "PAY001", OutRefundNo: "RF001", TotalCents: 1999, AmountCents: 500})
+	require.NoError(t, err)
+	assert.Equal(t, RefundStateProcessing, refund.State)
+	assert.Equal(t, "5030000001", refund.ProviderRefundNo)
+
+	refund, err = gw.QueryRefund(ctx, "PAY001", "RF001")
+	require.NoError(t, err)
+	assert.Equal(t, RefundStateSucceeded, refund.State)
+	require.NotNil(t, refund.RefundedAt)
+
+	t.Run("unsigned response is rejected", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		bad := NewWeChatGateway(WeChatConfig{MchID: "1900000001", PrivateKey: merchantKey, PlatformPublicKey: &other.PublicKey, BaseURL: srv.URL})
+		_, err = bad.Query(ctx, "PAY001")
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("unconfigured gateway", func(t *testing.T) {
+		_, err := NewWeChatGateway(WeChatConfig{}).Query(ctx, "PAY001")
+		assert.ErrorIs(t, err, ErrGatewayNotConfigured)
+	})
+}
+
+func TestWeChatGateway_VerifyNotification(t *testing.T) {
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	gw := NewWeChatGateway(WeChatConfig{
+		MchID:             "1900000001",
+		APIv3Key:          testAPIv3Key,
+		PlatformPublicKey: &platformKey.PublicKey,
+		PlatformSerialNo:  "PLATFORM1",
+	})
+
+	plain := []byte(`{"mchid":"1900000001","out_trade_no":"PAY002","transaction_id":"4200000002","trade_state":"SUCCESS","success_time":"2025-01-02T15:04:05+08:00","amount":{"total":500,"currency":"CNY"}}`)
+	block, _ := aes.NewCipher([]byte(testAPIv3Key))
+	gcm, _ := cipher.NewGCMWithNonceSize(block, 12)
+	nonce := "abcdefghijkl"
+	ciphertext := gcm.Seal(nil, []byte(nonce), plain, []byte("transaction"))
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":         "EV-1",
+		"event_type": "TRANSACTION.SUCCESS",
+		"resource": map[string]string{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      base64.StdEncoding.EncodeToString(ciphertext),
+			"associated_data": "transaction",
+			"nonce":           nonce,
+		},
+	})
+
+	ctx := context.Background()
+	result, err := gw.VerifyNotification(ctx, wechatSignedHeader(t, platformKey, body, time.Now()), body)
+	require.NoError(t, err)
+	assert.Equal(t, "PAY002", result.OutTradeNo)
+	assert.Equal(t, TradeStatePaid, result.State)
+	assert.Equal(t, int64(500), result.AmountCents)
+	assert.Equal(t, model.CurrencyCNY, result.Currency)
+
+	_, err = gw.VerifyNotification(ctx, wechatSignedHeader(t, platformKey, body, time.Now()), append(body, ' '))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	header := wechatSignedHeader(t, platformKey, body, time.Now())
+	header.Set(wechatHeaderSerial, "UNKNOWN")
+	_, err = gw.VerifyNotification(ctx, header, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	status, _, ack := gw.NotificationAck(true)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, string(ack), "SUCCESS")
+}
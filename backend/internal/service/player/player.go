@@ -36,6 +36,13 @@ type PlayerService struct {
 	reviews    repository.ReviewRepository
 	playerTags repository.PlayerTagRepository
 	cache      cache.Cache
+	// optional: hides players whose credit tier is not listed
+	credit CreditTiers
+}
+
+// CreditTiers 批量查询陪玩师信用等级策略（由信用服务实现）
+type CreditTiers interface {
+	TierPolicies(ctx context.Context, playerIDs []uint64) (map[uint64]model.CreditTierPolicy, error)
 }
 
 // NewPlayerService 创建陪玩师服务
@@ -59,6 +66,9 @@ func NewPlayerService(
 	}
 }
 
+// SetCredit 注入信用服务，信用等级不展示的陪玩师不出现在用户端列表
+func (s *PlayerService) SetCredit(c CreditTiers) { s.credit = c }
+
 // PlayerCardDTO 陪玩师卡片信息（列表展示）
 type PlayerCardDTO struct {
 	ID              uint64  `json:"id"`
@@ -73,6 +83,8 @@ type PlayerCardDTO struct {
 	MainGame        string  `json:"mainGame"`   // 游戏名称
 	IsOnline        bool    `json:"isOnline"`   // 在线状态
 	OrderCount      int64   `json:"orderCount"` // 历史订单数
+
+	CreditTier model.CreditTier `json:"creditTier,omitempty"` // 信用等级
 }
 
 // PlayerDetailDTO 陪玩师详情信息
@@ -172,9 +184,17 @@ func (s *PlayerService) ListPlayers(ctx context.Context, req PlayerListRequest)
 		return nil, err
 	}
 
+	policies := s.creditPolicies(ctx, players)
+
 	// 转换为 DTO
 	playerCards := make([]PlayerCardDTO, 0, len(players))
 	for _, p := range players {
+		policy, hasCredit := policies[p.ID]
+		// 信用等级不展示的陪玩师不出现在列表中
+		if hasCredit && !policy.Listed {
+			total--
+			continue
+		}
 		// 过滤条件
 		if req.GameID != nil && p.MainGameID != *req.GameID {
 			continue
@@ -227,6 +247,7 @@ func (s *PlayerService) ListPlayers(ctx context.Context, req PlayerListRequest)
 			MainGame:        gameName,
 			IsOnline:        s.getPlayerOnlineStatus(ctx, p.ID),
 			OrderCount:      orderCount,
+			CreditTier:      policy.Tier,
 		})
 	}
 
@@ -236,6 +257,22 @@ func (s *PlayerService) ListPlayers(ctx context.Context, req PlayerListRequest)
 	}, nil
 }
 
+// creditPolicies 批量查询陪玩师的信用等级策略；未注入信用服务或查询失败时返回空，不做过滤
+func (s *PlayerService) creditPolicies(ctx context.Context, players []model.Player) map[uint64]model.CreditTierPolicy {
+	if s.credit == nil || len(players) == 0 {
+		return nil
+	}
+	ids := make([]uint64, 0, len(players))
+	for _, p := range players {
+		ids = append(ids, p.ID)
+	}
+	policies, err := s.credit.TierPolicies(ctx, ids)
+	if err != nil {
+		return nil
+	}
+	return policies
+}
+
 // GetPlayerDetail 获取陪玩师详情（用户端）
 func (s *PlayerService) GetPlayerDetail(ctx context.Context, id uint64) (*PlayerDetailResponse, error) {
 	// 获取陪玩师信息
@@ -467,6 +504,11 @@ func (s *PlayerService) SetPlayerOnlineStatus(ctx context.Context, userID uint64
 	return s.cache.Delete(ctx, key)
 }
 
+// IsPlayerOnline 陪玩师当前是否在线（心跳未过期）
+func (s *PlayerService) IsPlayerOnline(ctx context.Context, playerID uint64) bool {
+	return s.getPlayerOnlineStatus(ctx, playerID)
+}
+
 // getPlayerOnlineStatus 获取陪玩师在线状态
 func (s *PlayerService) getPlayerOnlineStatus(ctx context.Context, playerID uint64) bool {
 	key := s.getOnlineStatusKey(playerID)
@@ -588,6 +630,7 @@ func (s *PlayerService) calculateAvgResponseTime(ctx context.Context, playerID u
 		PlayerID: playerIDPtr,
 		Statuses: []model.OrderStatus{
 			model.OrderStatusInProgress,
+			model.OrderStatusPendingConfirmation,
 			model.OrderStatusCompleted,
 		},
 		Page:     1,
@@ -0,0 +1,358 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	serviceitemrepo "gamelink/internal/repository/serviceitem"
+	"gamelink/internal/service/commission"
+	itemservice "gamelink/internal/service/item"
+)
+
+var (
+	// ErrNotFound 服务项目或陪玩师不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrNoServiceItem 没有可用于计价的服务项目
+	ErrNoServiceItem = errors.New("no service item available for pricing")
+)
+
+// DefaultCommissionRate 未配置任何抽成规则时的平台抽成比例（百分比）
+const DefaultCommissionRate = 20
+
+// 报价明细行编码
+const (
+	LineItem       = "item"        // 服务项目基础价格
+	LinePlayerRate = "player_rate" // 陪玩师时薪
+	LineAdjustment = "adjustment"  // 管理员手工调价
+)
+
+// CommissionCalculator 按抽成规则计算订单抽成（由抽成服务实现）。
+type CommissionCalculator interface {
+	CalculateOrderCommission(ctx context.Context, order *model.Order) (*commission.CommissionCalculation, error)
+}
+
+// ItemPricer 计算服务项目在指定币种下的单价（由服务项目服务实现）。
+type ItemPricer interface {
+	QuotePrice(ctx context.Context, item *model.ServiceItem, currency model.Currency) (*itemservice.PriceQuote, error)
+}
+
+// CurrencyConverter 按汇率换算金额（由汇率服务实现）。
+type CurrencyConverter interface {
+	Convert(ctx context.Context, amountCents int64, from, to model.Currency) (int64, error)
+}
+
+// Service 统一定价引擎：护航、礼物与后台建单均由此报价。
+type Service struct {
+	items       serviceitemrepo.ServiceItemRepository
+	players     repository.PlayerRepository
+	commissions CommissionCalculator
+	pricer      ItemPricer
+	fx          CurrencyConverter
+}
+
+// NewService 创建定价服务
+func NewService(items serviceitemrepo.ServiceItemRepository, players repository.PlayerRepository) *Service {
+	return &Service{items: items, players: players}
+}
+
+// SetCommissionCalculator 注入抽成服务，报价按抽成规则拆分平台与陪玩师收入
+func (s *Service) SetCommissionCalculator(c CommissionCalculator) { s.commissions = c }
+
+// SetItemPricer 注入服务项目定价，支持按币种定价或汇率换算
+func (s *Service) SetItemPricer(p ItemPricer) { s.pricer = p }
+
+// SetFX 注入汇率服务，陪玩师时薪（人民币）可换算为下单币种
+func (s *Service) SetFX(fx CurrencyConverter) { s.fx = fx }
+
+// QuoteRequest 报价请求
+type QuoteRequest struct {
+	ItemID        uint64         `json:"itemId"`                                           // 服务项目，不传时按游戏/段位/陪玩师匹配
+	GameID        uint64         `json:"gameId"`                                           // 游戏
+	PlayerID      uint64         `json:"playerId"`                                         // 陪玩师（礼物为接收人）
+	RankLevel     string         `json:"rankLevel" binding:"max=32"`                       // 段位，默认取陪玩师段位
+	Quantity      int            `json:"quantity" binding:"omitempty,min=1,max=99"`        // 数量，默认1
+	DurationHours float32        `json:"durationHours" binding:"omitempty,min=0.5,max=24"` // 时长，默认取服务项目时长
+	Currency      model.Currency `json:"currency" binding:"omitempty,oneof=CNY USD EUR"`   // 下单币种，默认 CNY
+	Gift          bool           `json:"gift"`                                             // 是否为礼物报价
+	ManualTotal   int64          `json:"-"`                                                // 管理员手工定价（分），仍按抽成规则拆分
+}
+
+// QuoteLine 报价明细
+type QuoteLine struct {
+	Code           string `json:"code"`
+	Label          string `json:"label"`
+	UnitPriceCents int64  `json:"unitPriceCents"`
+	Minutes        int64  `json:"minutes,omitempty"`
+	Quantity       int    `json:"quantity"`
+	AmountCents    int64  `json:"amountCents"`
+}
+
+// Quote 订单报价
+type Quote struct {
+	ItemID            uint64                           `json:"itemId"`
+	ItemName          string                           `json:"itemName"`
+	SubCategory       model.ServiceItemSubCategory     `json:"subCategory"`
+	RankLevel         string                           `json:"rankLevel,omitempty"`
+	GameID            uint64                           `json:"gameId,omitempty"`
+	PlayerID          uint64                           `json:"playerId,omitempty"`
+	Quantity          int                              `json:"quantity"`
+	DurationMinutes   int64                            `json:"durationMinutes"`
+	Currency          model.Currency                   `json:"currency"`
+	Lines             []QuoteLine                      `json:"lines"`
+	UnitPriceCents    int64                            `json:"unitPriceCents"`
+	TotalPriceCents   int64                            `json:"totalPriceCents"`
+	CommissionRate    int                              `json:"commissionRate"`
+	CommissionCents   int64                            `json:"commissionCents"`
+	PlayerIncomeCents int64                            `json:"playerIncomeCents"`
+	AppliedRule       string                           `json:"appliedRule"`
+	AppliedRuleDetail string                           `json:"appliedRuleDetail,omitempty"`
+	CandidateRates    []commission.CommissionCandidate `json:"candidateRates,omitempty"`
+}
+
+// Quote 计算订单报价。
+//
+// 单价 = 服务项目价格（按项目时长折算） + 陪玩师时薪 × 时长（礼物不计时薪），
+// 总价 = 单价 × 数量，抽成按抽成服务的规则取最低比例。全程使用整数（分）计算。
+func (s *Service) Quote(ctx context.Context, req QuoteRequest) (*Quote, error) {
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+	if req.Quantity < 0 || req.DurationHours < 0 || req.ManualTotal < 0 {
+		return nil, ErrValidation
+	}
+	currency := req.Currency.OrDefault()
+	if !model.IsValidCurrency(currency) {
+		return nil, fmt.Errorf("%w: unsupported currency %s", ErrValidation, currency)
+	}
+
+	var player *model.Player
+	if req.PlayerID > 0 {
+		p, err := s.players.Get(ctx, req.PlayerID)
+		if err != nil {
+			return nil, err
+		}
+		player = p
+		if req.RankLevel == "" {
+			req.RankLevel = p.Rank
+		}
+	}
+
+	item, err := s.resolveItem(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	minutes := DurationMinutes(req.DurationHours)
+	if minutes == 0 && item.ServiceHours > 0 {
+		minutes = int64(item.ServiceHours) * 60
+	}
+
+	quote := &Quote{
+		ItemID:          item.ID,
+		ItemName:        item.Name,
+		SubCategory:     item.SubCategory,
+		RankLevel:       item.RankLevel,
+		GameID:          req.GameID,
+		PlayerID:        req.PlayerID,
+		Quantity:        req.Quantity,
+		DurationMinutes: minutes,
+		Currency:        currency,
+	}
+	if quote.GameID == 0 && item.GameID != nil {
+		quote.GameID = *item.GameID
+	}
+
+	itemPrice, err := s.itemPrice(ctx, item, currency)
+	if err != nil {
+		return nil, err
+	}
+	itemUnit := itemPrice
+	if item.ServiceHours > 0 && minutes > 0 {
+		itemUnit = Prorate(itemPrice, minutes, int64(item.ServiceHours)*60)
+	}
+	quote.addLine(QuoteLine{Code: LineItem, Label: item.Name, UnitPriceCents: itemPrice, Minutes: minutes, AmountCents: itemUnit})
+
+	if !item.IsGift() && player != nil && player.HourlyRateCents > 0 && minutes > 0 {
+		rate, err := s.convert(ctx, player.HourlyRateCents, model.CurrencyCNY, currency)
+		if err != nil {
+			return nil, err
+		}
+		quote.addLine(QuoteLine{Code: LinePlayerRate, Label: "陪玩师时薪", UnitPriceCents: rate, Minutes: minutes, AmountCents: HourlyAmount(rate, minutes)})
+	}
+
+	for i := range quote.Lines {
+		quote.UnitPriceCents += quote.Lines[i].AmountCents
+		quote.Lines[i].Quantity = req.Quantity
+		quote.Lines[i].AmountCents *= int64(req.Quantity)
+	}
+	quote.TotalPriceCents = quote.UnitPriceCents * int64(req.Quantity)
+	if req.ManualTotal > 0 && req.ManualTotal != quote.TotalPriceCents {
+		quote.addLine(QuoteLine{Code: LineAdjustment, Label: "手工调价", Quantity: 1, AmountCents: req.ManualTotal - quote.TotalPriceCents})
+		quote.TotalPriceCents = req.ManualTotal
+		quote.UnitPriceCents = req.ManualTotal / int64(req.Quantity)
+	}
+
+	if err := s.applyCommission(ctx, quote, item, req); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// resolveItem 取指定的服务项目，或按游戏匹配最贴合陪玩师与段位的服务项目
+func (s *Service) resolveItem(ctx context.Context, req QuoteRequest) (*model.ServiceItem, error) {
+	if req.ItemID > 0 {
+		item, err := s.items.Get(ctx, req.ItemID)
+		if err != nil {
+			return nil, err
+		}
+		if !item.IsActive || item.IsGift() != req.Gift {
+			return nil, ErrNoServiceItem
+		}
+		return item, nil
+	}
+	if req.Gift || req.GameID == 0 {
+		return nil, fmt.Errorf("%w: itemId or gameId is required", ErrValidation)
+	}
+	sub := model.SubCategorySolo
+	items, err := s.items.GetGameServices(ctx, req.GameID, &sub)
+	if err != nil {
+		return nil, err
+	}
+	var best *model.ServiceItem
+	bestScore := -1
+	for i := range items {
+		item := &items[i]
+		score := 0
+		if item.PlayerID != nil {
+			if *item.PlayerID != req.PlayerID {
+				continue
+			}
+			score += 2
+		}
+		if item.RankLevel != "" {
+			if item.RankLevel != req.RankLevel {
+				continue
+			}
+			score++
+		}
+		// 同分时保留排序靠前的项目
+		if score > bestScore {
+			best, bestScore = item, score
+		}
+	}
+	if best == nil {
+		return nil, ErrNoServiceItem
+	}
+	return best, nil
+}
+
+// itemPrice 服务项目在下单币种下的价格
+func (s *Service) itemPrice(ctx context.Context, item *model.ServiceItem, currency model.Currency) (int64, error) {
+	if s.pricer != nil {
+		quote, err := s.pricer.QuotePrice(ctx, item, currency)
+		if err != nil {
+			return 0, err
+		}
+		return quote.PriceCents, nil
+	}
+	return s.convert(ctx, item.BasePriceCents, item.Currency.OrDefault(), currency)
+}
+
+func (s *Service) convert(ctx context.Context, amount int64, from, to model.Currency) (int64, error) {
+	if from == to || amount == 0 {
+		return amount, nil
+	}
+	if s.fx == nil {
+		return 0, fmt.Errorf("%w: currency %s is not available", ErrValidation, to)
+	}
+	return s.fx.Convert(ctx, amount, from, to)
+}
+
+// applyCommission 按抽成规则拆分；未注入抽成服务时使用服务项目抽成比例
+func (s *Service) applyCommission(ctx context.Context, quote *Quote, item *model.ServiceItem, req QuoteRequest) error {
+	rate, rule, detail := ItemCommissionRate(item), "服务项目", item.Name
+	if s.commissions != nil {
+		draft := &model.Order{
+			ItemID:          item.ID,
+			Quantity:        quote.Quantity,
+			UnitPriceCents:  quote.UnitPriceCents,
+			TotalPriceCents: quote.TotalPriceCents,
+			Currency:        quote.Currency,
+		}
+		if req.PlayerID > 0 {
+			playerID := req.PlayerID
+			draft.PlayerID = &playerID
+			if item.IsGift() {
+				draft.RecipientPlayerID = &playerID
+			}
+		}
+		if quote.GameID > 0 {
+			gameID := quote.GameID
+			draft.GameID = &gameID
+		}
+		calc, err := s.commissions.CalculateOrderCommission(ctx, draft)
+		if err != nil {
+			return err
+		}
+		rate, rule, detail = calc.CommissionRate, calc.AppliedRule, calc.AppliedRuleDetail
+		quote.CandidateRates = calc.CandidateRates
+	}
+	quote.CommissionRate, quote.AppliedRule, quote.AppliedRuleDetail = rate, rule, detail
+	quote.CommissionCents, quote.PlayerIncomeCents = SplitCommission(quote.TotalPriceCents, rate)
+	return nil
+}
+
+func (q *Quote) addLine(line QuoteLine) { q.Lines = append(q.Lines, line) }
+
+// ApplyTo 将报价写入订单的服务项目与金额字段
+func (q *Quote) ApplyTo(order *model.Order) {
+	order.ItemID = q.ItemID
+	order.Quantity = q.Quantity
+	order.UnitPriceCents = q.UnitPriceCents
+	order.TotalPriceCents = q.TotalPriceCents
+	order.CommissionCents = q.CommissionCents
+	order.PlayerIncomeCents = q.PlayerIncomeCents
+	order.CommissionRate = q.CommissionRate
+	order.Currency = q.Currency
+}
+
+// DurationMinutes 将小时数换算为整数分钟（四舍五入）
+func DurationMinutes(hours float32) int64 {
+	if hours <= 0 {
+		return 0
+	}
+	return int64(math.Round(float64(hours) * 60))
+}
+
+// HourlyAmount 按时薪与分钟数计算金额（四舍五入到分）
+func HourlyAmount(rateCents, minutes int64) int64 {
+	return Prorate(rateCents, minutes, 60)
+}
+
+// Prorate 按 part/whole 折算金额（四舍五入到分）
+func Prorate(amountCents, part, whole int64) int64 {
+	if whole <= 0 {
+		return amountCents
+	}
+	return (amountCents*part + whole/2) / whole
+}
+
+// SplitCommission 按抽成比例拆分平台抽成与陪玩师收入
+func SplitCommission(totalCents int64, rate int) (commissionCents, playerIncomeCents int64) {
+	commissionCents = totalCents * int64(rate) / 100
+	return commissionCents, totalCents - commissionCents
+}
+
+// ItemCommissionRate 服务项目的抽成比例（百分比），未配置时为默认比例
+func ItemCommissionRate(item *model.ServiceItem) int {
+	if item == nil || item.CommissionRate <= 0 {
+		return DefaultCommissionRate
+	}
+	return int(math.Round(item.CommissionRate * 100))
+}
@@ -0,0 +1,145 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	commissionrepo "gamelink/internal/repository/commission"
+	orderrepo "gamelink/internal/repository/order"
+	playerrepo "gamelink/internal/repository/player"
+	serviceitemrepo "gamelink/internal/repository/serviceitem"
+	"gamelink/internal/service/commission"
+)
+
+type pricingEnv struct {
+	db     *gorm.DB
+	svc    *Service
+	player *model.Player
+	gameID uint64
+}
+
+func newPricingEnv(t *testing.T) *pricingEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.ServiceItem{}, &model.CommissionRule{}))
+
+	env := &pricingEnv{db: db, gameID: 7}
+	env.player = &model.Player{UserID: 1, Rank: "王者", HourlyRateCents: 5000}
+	require.NoError(t, db.Create(env.player).Error)
+
+	items := serviceitemrepo.NewServiceItemRepository(db)
+	players := playerrepo.NewPlayerRepository(db)
+	commissions := commission.NewCommissionService(commissionrepo.NewCommissionRepository(db), orderrepo.NewOrderRepository(db), players)
+	commissions.SetServiceItems(items)
+	env.svc = NewService(items, players)
+	env.svc.SetCommissionCalculator(commissions)
+	return env
+}
+
+func (e *pricingEnv) item(t *testing.T, item model.ServiceItem) *model.ServiceItem {
+	t.Helper()
+	item.ItemCode = item.Name
+	item.IsActive = true
+	if item.SubCategory == "" {
+		item.SubCategory = model.SubCategorySolo
+		item.GameID = &e.gameID
+	}
+	require.NoError(t, e.db.Create(&item).Error)
+	return &item
+}
+
+func TestQuoteEscortOrder(t *testing.T) {
+	env := newPricingEnv(t)
+	env.item(t, model.ServiceItem{Name: "通用陪玩", BasePriceCents: 1000, ServiceHours: 1, CommissionRate: 0.25})
+	rank := env.item(t, model.ServiceItem{Name: "王者陪玩", RankLevel: "王者", BasePriceCents: 3000, ServiceHours: 1, CommissionRate: 0.15})
+	env.item(t, model.ServiceItem{Name: "星耀陪玩", RankLevel: "星耀", BasePriceCents: 2000, ServiceHours: 1, CommissionRate: 0.1})
+
+	quote, err := env.svc.Quote(context.Background(), QuoteRequest{GameID: env.gameID, PlayerID: env.player.ID, DurationHours: 1.5})
+	require.NoError(t, err)
+
+	// 按陪玩师段位匹配服务项目：3000×1.5 + 5000×1.5
+	assert.Equal(t, rank.ID, quote.ItemID)
+	assert.EqualValues(t, 90, quote.DurationMinutes)
+	require.Len(t, quote.Lines, 2)
+	assert.EqualValues(t, 4500, quote.Lines[0].AmountCents)
+	assert.EqualValues(t, 7500, quote.Lines[1].AmountCents)
+	assert.EqualValues(t, 12000, quote.TotalPriceCents)
+	assert.Equal(t, 15, quote.CommissionRate)
+	assert.EqualValues(t, 1800, quote.CommissionCents)
+	assert.EqualValues(t, 10200, quote.PlayerIncomeCents)
+}
+
+func TestQuoteAppliesLowestCommissionRule(t *testing.T) {
+	env := newPricingEnv(t)
+	env.item(t, model.ServiceItem{Name: "通用陪玩", BasePriceCents: 999, ServiceHours: 1, CommissionRate: 0.2})
+	playerID := env.player.ID
+	require.NoError(t, env.db.Create(&model.CommissionRule{Name: "签约陪玩师", Type: "special", Rate: 8, IsActive: true, PlayerID: &playerID}).Error)
+
+	quote, err := env.svc.Quote(context.Background(), QuoteRequest{GameID: env.gameID, PlayerID: playerID, Quantity: 3, DurationHours: 0.5})
+	require.NoError(t, err)
+
+	// (999×30/60 四舍五入 + 5000×30/60) × 3
+	assert.EqualValues(t, 500+2500, quote.UnitPriceCents)
+	assert.EqualValues(t, 9000, quote.TotalPriceCents)
+	assert.Equal(t, 8, quote.CommissionRate)
+	assert.Equal(t, "陪玩师专属", quote.AppliedRule)
+	assert.EqualValues(t, 720, quote.CommissionCents)
+	assert.EqualValues(t, 8280, quote.PlayerIncomeCents)
+}
+
+func TestQuoteGiftAndManualTotal(t *testing.T) {
+	env := newPricingEnv(t)
+	gift := env.item(t, model.ServiceItem{Name: "玫瑰", SubCategory: model.SubCategoryGift, BasePriceCents: 520, CommissionRate: 0.3})
+	ctx := context.Background()
+
+	quote, err := env.svc.Quote(ctx, QuoteRequest{ItemID: gift.ID, PlayerID: env.player.ID, Quantity: 5, Gift: true})
+	require.NoError(t, err)
+	require.Len(t, quote.Lines, 1, "礼物不计陪玩师时薪")
+	assert.EqualValues(t, 2600, quote.TotalPriceCents)
+	assert.EqualValues(t, 780, quote.CommissionCents)
+
+	_, err = env.svc.Quote(ctx, QuoteRequest{ItemID: gift.ID, PlayerID: env.player.ID})
+	assert.True(t, errors.Is(err, ErrNoServiceItem), "礼物项目不能用于护航报价")
+
+	escort := env.item(t, model.ServiceItem{Name: "通用陪玩", BasePriceCents: 1000, ServiceHours: 2, CommissionRate: 0.2})
+	quote, err = env.svc.Quote(ctx, QuoteRequest{ItemID: escort.ID, ManualTotal: 8800})
+	require.NoError(t, err)
+	require.Len(t, quote.Lines, 2)
+	assert.Equal(t, LineAdjustment, quote.Lines[1].Code)
+	assert.EqualValues(t, 7800, quote.Lines[1].AmountCents)
+	assert.EqualValues(t, 8800, quote.TotalPriceCents)
+	assert.EqualValues(t, 1760, quote.CommissionCents)
+}
+
+func TestQuoteWithoutMatchingItem(t *testing.T) {
+	env := newPricingEnv(t)
+	other := uint64(99)
+	env.item(t, model.ServiceItem{Name: "专属陪玩", PlayerID: &other, BasePriceCents: 1000, ServiceHours: 1})
+
+	_, err := env.svc.Quote(context.Background(), QuoteRequest{GameID: env.gameID, PlayerID: env.player.ID, DurationHours: 1})
+	assert.True(t, errors.Is(err, ErrNoServiceItem))
+
+	_, err = env.svc.Quote(context.Background(), QuoteRequest{PlayerID: env.player.ID, DurationHours: 1})
+	assert.True(t, errors.Is(err, ErrValidation))
+}
+
+func TestIntegerMath(t *testing.T) {
+	assert.EqualValues(t, 0, DurationMinutes(0))
+	assert.EqualValues(t, 30, DurationMinutes(0.5))
+	assert.EqualValues(t, 100, DurationMinutes(1.6667))
+	assert.EqualValues(t, 3333, HourlyAmount(3333, 60))
+	assert.EqualValues(t, 1667, HourlyAmount(3333, 30))
+	commissionCents, income := SplitCommission(999, 20)
+	assert.EqualValues(t, 199, commissionCents)
+	assert.EqualValues(t, 800, income)
+	assert.Equal(t, 29, ItemCommissionRate(&model.ServiceItem{CommissionRate: 0.29}))
+	assert.Equal(t, DefaultCommissionRate, ItemCommissionRate(nil))
+}
@@ -0,0 +1,392 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	reconrepo "gamelink/internal/repository/reconciliation"
+	"gamelink/internal/service/payment"
+)
+
+var (
+	// ErrNotFound 对账单或明细不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败（含账单格式错误）
+	ErrValidation = errors.New("validation failed")
+	// ErrInvalidStatus 明细状态不允许该操作
+	ErrInvalidStatus = errors.New("invalid reconciliation detail status")
+)
+
+// ReconciliationService 渠道对账服务
+//
+// 导入微信/支付宝对账单后逐行与平台支付单、退款单核对：
+// 1. 支付行按 Payment.ProviderTradeNo（兜底商户订单号）匹配
+// 2. 退款行按渠道退款单号 / 商户退款单号匹配
+// 3. 期间内平台已入账但账单中没有的记录记为 missing_external
+//
+// 所有明细（含匹配成功的）都会落库，异常明细由财务逐条处理。
+type ReconciliationService struct {
+	repo reconrepo.ReconciliationRepository
+	tx   TxManager
+}
+
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
+}
+
+// NewReconciliationService 创建对账服务
+func NewReconciliationService(repo reconrepo.ReconciliationRepository) *ReconciliationService {
+	return &ReconciliationService{repo: repo}
+}
+
+// SetTxManager 注入事务管理器，对账单与明细在同一事务内写入。
+func (s *ReconciliationService) SetTxManager(tx TxManager) { s.tx = tx }
+
+// ImportRequest 导入渠道对账单
+type ImportRequest struct {
+	Provider    model.PaymentMethod
+	PeriodStart time.Time // 含
+	PeriodEnd   time.Time // 不含
+	FileName    string
+	Bill        io.Reader
+	OperatorID  uint64
+}
+
+// Import 解析渠道账单并与平台记录核对，生成对账单及明细。
+func (s *ReconciliationService) Import(ctx context.Context, req ImportRequest) (*model.Reconciliation, error) {
+	if req.Provider != model.PaymentMethodWeChat && req.Provider != model.PaymentMethodAlipay {
+		return nil, fmt.Errorf("%w: unsupported provider %q", ErrValidation, req.Provider)
+	}
+	if req.PeriodStart.IsZero() || !req.PeriodEnd.After(req.PeriodStart) {
+		return nil, fmt.Errorf("%w: invalid period", ErrValidation)
+	}
+	if req.Bill == nil {
+		return nil, fmt.Errorf("%w: bill file required", ErrValidation)
+	}
+	lines, err := payment.ParseBill(req.Provider, req.Bill)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidBill) {
+			return nil, fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+		return nil, err
+	}
+
+	details, err := s.match(ctx, req, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rec := &model.Reconciliation{
+		ReconciliationNo:   model.GenerateOrderNo("RC"),
+		ReconciliationDate: now,
+		Type:               model.ReconciliationTypePayment,
+		PeriodStart:        req.PeriodStart,
+		PeriodEnd:          req.PeriodEnd,
+		TotalRecords:       len(details),
+		Provider:           req.Provider,
+		SourceFile:         req.FileName,
+		ProcessedAt:        &now,
+	}
+	if req.OperatorID != 0 {
+		op := req.OperatorID
+		rec.ProcessedBy = &op
+	}
+	for _, d := range details {
+		if d.Status == model.ReconciliationDetailMatched {
+			rec.MatchedRecords++
+			continue
+		}
+		rec.ExceptionRecords++
+		rec.DifferenceAmount += d.DifferenceAmount
+	}
+	rec.PendingRecords = rec.ExceptionRecords
+	rec.Status = model.ReconciliationStatusSuccess
+	if rec.ExceptionRecords > 0 {
+		rec.Status = model.ReconciliationStatusException
+	}
+	rec.Abstract = fmt.Sprintf("%s 账单 %d 笔，平台记录比对 %d 条，匹配 %d 条，异常 %d 条",
+		req.Provider, len(lines), rec.TotalRecords, rec.MatchedRecords, rec.ExceptionRecords)
+
+	err = s.withTx(ctx, func(repo reconrepo.ReconciliationRepository) error {
+		if err := repo.Create(ctx, rec); err != nil {
+			return err
+		}
+		for i := range details {
+			details[i].ReconciliationID = rec.ID
+		}
+		return repo.CreateDetails(ctx, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// match 逐行核对账单并补充平台侧多出的记录。
+func (s *ReconciliationService) match(ctx context.Context, req ImportRequest, lines []payment.BillLine) ([]model.ReconciliationDetail, error) {
+	payments, err := s.repo.ListPaymentsPaidBetween(ctx, req.Provider, req.PeriodStart, req.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.repo.ListRefundsSucceededBetween(ctx, req.Provider, req.PeriodStart, req.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+	paymentsByNo := make(map[string]*model.Payment, len(payments)*2)
+	for i := range payments {
+		p := &payments[i]
+		if p.ProviderTradeNo != "" {
+			paymentsByNo["p:"+p.ProviderTradeNo] = p
+		}
+		if p.OutTradeNo != "" {
+			paymentsByNo["o:"+p.OutTradeNo] = p
+		}
+	}
+	refundsByNo := make(map[string]*model.Refund, len(refunds)*2)
+	for i := range refunds {
+		r := &refunds[i]
+		if r.ProviderRefundNo != "" {
+			refundsByNo["p:"+r.ProviderRefundNo] = r
+		}
+		refundsByNo["o:"+r.OutRefundNo] = r
+	}
+
+	seenPayments := make(map[uint64]bool)
+	seenRefunds := make(map[uint64]bool)
+	details := make([]model.ReconciliationDetail, 0, len(lines))
+	for _, line := range lines {
+		occurred := line.OccurredAt
+		d := model.ReconciliationDetail{
+			LineNo:         line.LineNo,
+			ExternalType:   string(line.Kind),
+			ExternalAmount: line.AmountCents,
+			ExternalDate:   &occurred,
+		}
+		switch line.Kind {
+		case payment.BillLinePayment:
+			d.ExternalNo = line.ProviderTradeNo
+			p := lookupPayment(paymentsByNo, line)
+			if p == nil {
+				if p, err = s.repo.FindPayment(ctx, req.Provider, line.ProviderTradeNo, line.OutTradeNo); err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return nil, err
+				}
+			}
+			if p == nil {
+				d.InternalType = string(payment.BillLinePayment)
+				d.InternalNo = line.OutTradeNo
+				classify(&d, false, "平台无此支付单")
+				break
+			}
+			fillPayment(&d, p)
+			switch {
+			case seenPayments[p.ID]:
+				classify(&d, false, "渠道账单重复记录")
+			case p.Status != model.PaymentStatusPaid && p.Status != model.PaymentStatusRefunded:
+				classify(&d, false, fmt.Sprintf("平台支付单状态为 %s", p.Status))
+			default:
+				classify(&d, true, "")
+			}
+			seenPayments[p.ID] = true
+		case payment.BillLineRefund:
+			d.ExternalNo = line.ProviderRefundNo
+			if d.ExternalNo == "" {
+				d.ExternalNo = line.OutRefundNo
+			}
+			r := lookupRefund(refundsByNo, line)
+			if r == nil {
+				if r, err = s.repo.FindRefund(ctx, req.Provider, line.ProviderRefundNo, line.OutRefundNo); err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return nil, err
+				}
+			}
+			if r == nil {
+				d.InternalType = string(payment.BillLineRefund)
+				d.InternalNo = line.OutRefundNo
+				classify(&d, false, "平台无此退款单")
+				break
+			}
+			fillRefund(&d, r)
+			switch {
+			case seenRefunds[r.ID]:
+				classify(&d, false, "渠道账单重复记录")
+			case r.Status != model.RefundStatusSucceeded:
+				classify(&d, false, fmt.Sprintf("平台退款单状态为 %s", r.Status))
+			default:
+				classify(&d, true, "")
+			}
+			seenRefunds[r.ID] = true
+		}
+		details = append(details, d)
+	}
+
+	for i := range payments {
+		if seenPayments[payments[i].ID] {
+			continue
+		}
+		d := model.ReconciliationDetail{ExternalType: string(payment.BillLinePayment)}
+		fillPayment(&d, &payments[i])
+		d.Status = model.ReconciliationDetailMissingExternal
+		d.DifferenceAmount = -d.InternalAmount
+		d.Remark = "渠道账单中无此支付"
+		details = append(details, d)
+	}
+	for i := range refunds {
+		if seenRefunds[refunds[i].ID] {
+			continue
+		}
+		d := model.ReconciliationDetail{ExternalType: string(payment.BillLineRefund)}
+		fillRefund(&d, &refunds[i])
+		d.Status = model.ReconciliationDetailMissingExternal
+		d.DifferenceAmount = -d.InternalAmount
+		d.Remark = "渠道账单中无此退款"
+		details = append(details, d)
+	}
+	return details, nil
+}
+
+func lookupPayment(index map[string]*model.Payment, line payment.BillLine) *model.Payment {
+	if line.ProviderTradeNo != "" {
+		if p, ok := index["p:"+line.ProviderTradeNo]; ok {
+			return p
+		}
+	}
+	if line.OutTradeNo != "" {
+		return index["o:"+line.OutTradeNo]
+	}
+	return nil
+}
+
+func lookupRefund(index map[string]*model.Refund, line payment.BillLine) *model.Refund {
+	if line.ProviderRefundNo != "" {
+		if r, ok := index["p:"+line.ProviderRefundNo]; ok {
+			return r
+		}
+	}
+	if line.OutRefundNo != "" {
+		return index["o:"+line.OutRefundNo]
+	}
+	return nil
+}
+
+func fillPayment(d *model.ReconciliationDetail, p *model.Payment) {
+	id := p.ID
+	d.InternalType = string(payment.BillLinePayment)
+	d.InternalNo = p.OutTradeNo
+	d.InternalID = &id
+	d.InternalAmount = p.AmountCents
+	d.InternalDate = p.PaidAt
+}
+
+func fillRefund(d *model.ReconciliationDetail, r *model.Refund) {
+	id := r.ID
+	d.InternalType = string(payment.BillLineRefund)
+	d.InternalNo = r.OutRefundNo
+	d.InternalID = &id
+	d.InternalAmount = r.AmountCents
+	d.InternalDate = r.RefundedAt
+}
+
+// classify 根据匹配结果与金额确定明细状态；found=false 表示平台侧不可用（缺失或未入账）。
+func classify(d *model.ReconciliationDetail, found bool, remark string) {
+	d.Remark = remark
+	if !found {
+		d.Status = model.ReconciliationDetailMissingInternal
+		d.DifferenceAmount = d.ExternalAmount
+		return
+	}
+	d.DifferenceAmount = d.ExternalAmount - d.InternalAmount
+	if d.DifferenceAmount != 0 {
+		d.Status = model.ReconciliationDetailAmountMismatch
+		return
+	}
+	d.Status = model.ReconciliationDetailMatched
+}
+
+// Get 获取对账单
+func (s *ReconciliationService) Get(ctx context.Context, id uint64) (*model.Reconciliation, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List 查询对账单
+func (s *ReconciliationService) List(ctx context.Context, opts reconrepo.ReconciliationListOptions) ([]model.Reconciliation, int64, error) {
+	return s.repo.List(ctx, opts)
+}
+
+// ListDetails 查询对账明细
+func (s *ReconciliationService) ListDetails(ctx context.Context, opts reconrepo.DetailListOptions) ([]model.ReconciliationDetail, int64, error) {
+	if _, err := s.repo.Get(ctx, opts.ReconciliationID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListDetails(ctx, opts)
+}
+
+// ResolveDetail 处理一条异常明细；全部异常处理完后对账单置为成功。
+func (s *ReconciliationService) ResolveDetail(ctx context.Context, reconciliationID, detailID, operatorID uint64, resolution string) (*model.ReconciliationDetail, error) {
+	resolution = strings.TrimSpace(resolution)
+	if resolution == "" {
+		return nil, fmt.Errorf("%w: resolution required", ErrValidation)
+	}
+	var out *model.ReconciliationDetail
+	err := s.withTx(ctx, func(repo reconrepo.ReconciliationRepository) error {
+		detail, err := repo.GetDetail(ctx, detailID)
+		if err != nil {
+			return err
+		}
+		if detail.ReconciliationID != reconciliationID {
+			return ErrNotFound
+		}
+		if detail.Status == model.ReconciliationDetailMatched || detail.Resolved {
+			return ErrInvalidStatus
+		}
+		now := time.Now()
+		detail.Resolved = true
+		detail.Resolution = resolution
+		detail.ResolvedAt = &now
+		if operatorID != 0 {
+			op := operatorID
+			detail.ResolvedBy = &op
+		}
+		if err := repo.UpdateDetail(ctx, detail); err != nil {
+			return err
+		}
+
+		rec, err := repo.Get(ctx, reconciliationID)
+		if err != nil {
+			return err
+		}
+		pending, err := repo.CountPendingExceptions(ctx, reconciliationID)
+		if err != nil {
+			return err
+		}
+		rec.PendingRecords = int(pending)
+		if pending == 0 {
+			rec.Status = model.ReconciliationStatusSuccess
+			rec.ProcessedAt = &now
+			rec.ProcessedBy = detail.ResolvedBy
+		}
+		if err := repo.Update(ctx, rec); err != nil {
+			return err
+		}
+		out = detail
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *ReconciliationService) withTx(ctx context.Context, fn func(repo reconrepo.ReconciliationRepository) error) error {
+	if s.tx == nil {
+		return fn(s.repo)
+	}
+	return s.tx.WithTx(ctx, func(r *common.Repos) error { return fn(r.Reconciliations) })
+}
@@ -0,0 +1,169 @@
+package reconciliation
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+	reconrepo "gamelink/internal/repository/reconciliation"
+)
+
+var cst = time.FixedZone("CST", 8*3600)
+
+func newTestService(t *testing.T) (*ReconciliationService, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Payment{}, &model.Refund{}, &model.Reconciliation{}, &model.ReconciliationDetail{}))
+
+	svc := NewReconciliationService(reconrepo.NewReconciliationRepository(db))
+	svc.SetTxManager(common.NewUnitOfWork(db))
+	return svc, db
+}
+
+func at(hour int) *time.Time {
+	t := time.Date(2024, 5, 1, hour, 0, 0, 0, cst)
+	return &t
+}
+
+func wechatRow(when, tradeNo, outTradeNo, status, amount, refundNo, outRefundNo, refundAmount string) string {
+	return strings.Join([]string{"`" + when, "`" + tradeNo, "`" + outTradeNo, "`" + status, "`" + amount, "`" + refundNo, "`" + outRefundNo, "`" + refundAmount}, ",") + "\n"
+}
+
+const wechatHeader = "交易时间,微信订单号,商户订单号,交易状态,订单金额,微信退款单号,商户退款单号,申请退款金额\n"
+
+func TestImport_ClassifiesLines(t *testing.T) {
+	svc, db := newTestService(t)
+	ctx := context.Background()
+
+	payments := []model.Payment{
+		{OrderID: 1, Method: model.PaymentMethodWeChat, AmountCents: 9900, Status: model.PaymentStatusPaid, OutTradeNo: "PAY1", ProviderTradeNo: "W1", PaidAt: at(10)},
+		{OrderID: 2, Method: model.PaymentMethodWeChat, AmountCents: 5000, Status: model.PaymentStatusPaid, OutTradeNo: "PAY2", ProviderTradeNo: "W2", PaidAt: at(11)},
+		{OrderID: 3, Method: model.PaymentMethodWeChat, AmountCents: 2000, Status: model.PaymentStatusPaid, OutTradeNo: "PAY3", ProviderTradeNo: "W3", PaidAt: at(12)},
+		{OrderID: 4, Method: model.PaymentMethodWeChat, AmountCents: 1000, Status: model.PaymentStatusPending, OutTradeNo: "PAY4"},
+		// 支付宝与其它日期的记录不参与本次对账
+		{OrderID: 5, Method: model.PaymentMethodAlipay, AmountCents: 700, Status: model.PaymentStatusPaid, OutTradeNo: "PAY5", PaidAt: at(12)},
+	}
+	require.NoError(t, db.Create(&payments).Error)
+	paymentID := payments[0].ID
+	refunds := []model.Refund{
+		{OrderID: 1, PaymentID: &paymentID, Method: model.PaymentMethodWeChat, AmountCents: 3000, Status: model.RefundStatusSucceeded, OutRefundNo: "RF1", ProviderRefundNo: "R1", RefundedAt: at(13)},
+		{OrderID: 1, PaymentID: &paymentID, Method: model.PaymentMethodWeChat, AmountCents: 1000, Status: model.RefundStatusSucceeded, OutRefundNo: "RF2", RefundedAt: at(14)},
+	}
+	require.NoError(t, db.Create(&refunds).Error)
+
+	bill := wechatHeader +
+		wechatRow("2024-05-01 10:00:00", "W1", "PAY1", "SUCCESS", "99.00", "", "", "") + // matched
+		wechatRow("2024-05-01 11:00:00", "W2", "PAY2", "SUCCESS", "49.00", "", "", "") + // amount mismatch
+		wechatRow("2024-05-01 11:30:00", "W4", "PAY4", "SUCCESS", "10.00", "", "", "") + // platform still pending
+		wechatRow("2024-05-01 11:40:00", "W9", "PAY9", "SUCCESS", "8.00", "", "", "") + // unknown payment
+		wechatRow("2024-05-01 13:00:00", "W1", "PAY1", "REFUND", "0.00", "R1", "RF1", "30.00") // matched refund
+	// PAY3 与 RF2 在渠道账单中缺失
+
+	rec, err := svc.Import(ctx, ImportRequest{
+		Provider:    model.PaymentMethodWeChat,
+		PeriodStart: time.Date(2024, 5, 1, 0, 0, 0, 0, cst),
+		PeriodEnd:   time.Date(2024, 5, 2, 0, 0, 0, 0, cst),
+		FileName:    "wechat-20240501.csv",
+		Bill:        strings.NewReader(bill),
+		OperatorID:  9,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.ReconciliationStatusException, rec.Status)
+	assert.Equal(t, 7, rec.TotalRecords)
+	assert.Equal(t, 2, rec.MatchedRecords)
+	assert.Equal(t, 5, rec.ExceptionRecords)
+	assert.Equal(t, 5, rec.PendingRecords)
+	// -100 (金额差) + 1000 + 800 (渠道多) - 2000 - 1000 (平台多)
+	assert.Equal(t, int64(-1300), rec.DifferenceAmount)
+
+	details, total, err := svc.ListDetails(ctx, reconrepo.DetailListOptions{ReconciliationID: rec.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(7), total)
+	byNo := make(map[string]model.ReconciliationDetail)
+	for _, d := range details {
+		byNo[d.InternalType+":"+d.InternalNo] = d
+	}
+	assert.Equal(t, model.ReconciliationDetailMatched, byNo["payment:PAY1"].Status)
+	assert.Equal(t, model.ReconciliationDetailAmountMismatch, byNo["payment:PAY2"].Status)
+	assert.Equal(t, int64(-100), byNo["payment:PAY2"].DifferenceAmount)
+	assert.Equal(t, model.ReconciliationDetailMissingInternal, byNo["payment:PAY4"].Status)
+	assert.NotNil(t, byNo["payment:PAY4"].InternalID)
+	assert.Equal(t, model.ReconciliationDetailMissingInternal, byNo["payment:PAY9"].Status)
+	assert.Nil(t, byNo["payment:PAY9"].InternalID)
+	assert.Equal(t, model.ReconciliationDetailMissingExternal, byNo["payment:PAY3"].Status)
+	assert.Equal(t, model.ReconciliationDetailMatched, byNo["refund:RF1"].Status)
+	assert.Equal(t, model.ReconciliationDetailMissingExternal, byNo["refund:RF2"].Status)
+
+	exceptions, total, err := svc.ListDetails(ctx, reconrepo.DetailListOptions{ReconciliationID: rec.ID, ExceptionsOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+
+	// 逐条处理异常，全部处理后对账单变为成功
+	_, err = svc.ResolveDetail(ctx, rec.ID, byNo["payment:PAY1"].ID, 9, "ok")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+	_, err = svc.ResolveDetail(ctx, rec.ID, exceptions[0].ID, 9, " ")
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.ResolveDetail(ctx, rec.ID+1, exceptions[0].ID, 9, "wrong reconciliation")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for i, d := range exceptions {
+		resolved, err := svc.ResolveDetail(ctx, rec.ID, d.ID, 9, "已人工核实")
+		require.NoError(t, err)
+		assert.True(t, resolved.Resolved)
+		got, err := svc.Get(ctx, rec.ID)
+		require.NoError(t, err)
+		assert.Equal(t, len(exceptions)-i-1, got.PendingRecords)
+	}
+	_, err = svc.ResolveDetail(ctx, rec.ID, exceptions[0].ID, 9, "again")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	got, err := svc.Get(ctx, rec.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ReconciliationStatusSuccess, got.Status)
+}
+
+func TestImport_MatchesPaymentsFromEarlierPeriod(t *testing.T) {
+	svc, db := newTestService(t)
+	ctx := context.Background()
+
+	// 前一日 23:59 支付、渠道计入次日账单
+	paidAt := time.Date(2024, 4, 30, 23, 59, 59, 0, cst)
+	require.NoError(t, db.Create(&model.Payment{OrderID: 1, Method: model.PaymentMethodWeChat, AmountCents: 100, Status: model.PaymentStatusPaid, OutTradeNo: "PAY1", ProviderTradeNo: "W1", PaidAt: &paidAt}).Error)
+
+	bill := wechatHeader + wechatRow("2024-05-01 00:00:01", "W1", "PAY1", "SUCCESS", "1.00", "", "", "")
+	rec, err := svc.Import(ctx, ImportRequest{
+		Provider:    model.PaymentMethodWeChat,
+		PeriodStart: time.Date(2024, 5, 1, 0, 0, 0, 0, cst),
+		PeriodEnd:   time.Date(2024, 5, 2, 0, 0, 0, 0, cst),
+		Bill:        strings.NewReader(bill),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.ReconciliationStatusSuccess, rec.Status)
+	assert.Equal(t, 1, rec.MatchedRecords)
+	assert.Nil(t, rec.ProcessedBy)
+}
+
+func TestImport_Validation(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, cst)
+
+	_, err := svc.Import(ctx, ImportRequest{Provider: "paypal", PeriodStart: start, PeriodEnd: start.AddDate(0, 0, 1), Bill: strings.NewReader(wechatHeader)})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.Import(ctx, ImportRequest{Provider: model.PaymentMethodWeChat, PeriodStart: start, PeriodEnd: start, Bill: strings.NewReader(wechatHeader)})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.Import(ctx, ImportRequest{Provider: model.PaymentMethodWeChat, PeriodStart: start, PeriodEnd: start.AddDate(0, 0, 1), Bill: strings.NewReader("not,a,bill\n")})
+	assert.ErrorIs(t, err, ErrValidation)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"gamelink/internal/model"
@@ -37,6 +38,13 @@ type ReviewService struct {
 	players repository.PlayerRepository
 	users   repository.UserRepository
 	replies repository.ReviewReplyRepository
+	// optional: credits players for good reviews
+	credit CreditRecorder
+}
+
+// CreditRecorder 好评计入陪玩师信用（由信用服务实现）
+type CreditRecorder interface {
+	RecordReview(ctx context.Context, review *model.Review) error
 }
 
 // NewReviewService 创建评价服务
@@ -56,6 +64,9 @@ func NewReviewService(
 	}
 }
 
+// SetCredit 注入信用服务，好评为陪玩师加信用分
+func (s *ReviewService) SetCredit(c CreditRecorder) { s.credit = c }
+
 // CreateReviewRequest 创建评价请求
 type CreateReviewRequest struct {
 	OrderID   uint64   `json:"orderId" binding:"required"`
@@ -153,6 +164,11 @@ func (s *ReviewService) CreateReview(ctx context.Context, userID uint64, req Cre
 		if err := s.updatePlayerRating(ctx, playerID); err != nil {
 			// 更新评分失败不影响评价创建
 		}
+		if s.credit != nil {
+			if err := s.credit.RecordReview(ctx, review); err != nil {
+				slog.Warn("record review credit failed", slog.Uint64("review_id", review.ID), slog.String("error", err.Error()))
+			}
+		}
 	}
 
 	return &CreateReviewResponse{
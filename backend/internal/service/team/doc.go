@@ -1,3 +0,0 @@
-// Package team provides team-related services. This placeholder file ensures
-// the package is buildable without the experimental team_service build tag.
-package team
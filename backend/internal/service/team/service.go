@@ -1,222 +1,799 @@
-//go:build team_service
-// +build team_service
-
+// Package team 车队：车队与成员管理、车队订单的抢单与组队、队长收入分配以及组队超时释放。
 package team
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"strings"
 	"time"
 
 	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	teamrepo "gamelink/internal/repository/team"
+	"gamelink/internal/service/orderstate"
 )
 
-// Service provides team order assignment and payout operations.
-type Service struct {
-	teams             teamRepository
-	members           teamMemberRepository
-	assignments       assignmentRepository
-	assignmentMembers assignmentMemberRepository
-	payoutPlans       payoutPlanRepository
-	orders            orderRepository
-	opLogs            opLogRepository
-
-	// now is injectable time provider for tests.
-	now func() time.Time
-}
-
-// --- repositories (minimal interfaces required by tests) ---
+var (
+	// ErrNotFound 车队、成员、订单或车队接单记录不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrForbidden 不是车队队长或成员
+	ErrForbidden = errors.New("permission denied")
+	// ErrNotPlayer 用户不是已认证的陪玩师
+	ErrNotPlayer = errors.New("user is not a verified player")
+	// ErrOrderUnavailable 订单不在车队大厅或已被其他车队抢走
+	ErrOrderUnavailable = errors.New("order is not open for team snatching")
+	// ErrAssignmentClosed 车队接单已完成组队、已释放或订单已结束
+	ErrAssignmentClosed = errors.New("team assignment is no longer editable")
+)
 
-type teamRepository interface{}
+// DefaultDispatchWindow 抢单后队长组队并让成员确认的时限
+const DefaultDispatchWindow = 10 * time.Minute
 
-type teamMemberRepository interface {
-	GetByTeamAndUser(ctx context.Context, teamID, userID uint64) (*model.TeamMember, error)
+// ItemReader 服务项目查询
+type ItemReader interface {
+	Get(ctx context.Context, id uint64) (*model.ServiceItem, error)
 }
 
-type assignmentRepository interface {
-	Create(ctx context.Context, a *model.TeamOrderAssignment) error
-	GetByOrderID(ctx context.Context, orderID uint64) (*model.TeamOrderAssignment, error)
-	Update(ctx context.Context, a *model.TeamOrderAssignment) error
-	ListExpired(ctx context.Context, now time.Time) ([]model.TeamOrderAssignment, error)
+// CreateTeamRequest 创建车队请求
+type CreateTeamRequest struct {
+	Name        string  `json:"name" binding:"required,max=64"`
+	GameID      *uint64 `json:"gameId"`
+	Description string  `json:"description" binding:"max=500"`
 }
 
-type assignmentMemberRepository interface {
-	Replace(ctx context.Context, assignmentID uint64, members []*model.TeamAssignmentMember) error
+// AddMemberRequest 队长添加成员请求
+type AddMemberRequest struct {
+	UserID             uint64 `json:"userId" binding:"required"`
+	ProfitShareDefault int    `json:"profitShareDefault" binding:"min=0,max=100"`
 }
 
-type payoutPlanRepository interface {
-	Upsert(ctx context.Context, plan *model.TeamPayoutPlan) error
-	GetByAssignment(ctx context.Context, assignmentID uint64) (*model.TeamPayoutPlan, error)
+// TeamDetail 车队详情
+type TeamDetail struct {
+	Team    model.Team         `json:"team"`
+	Members []model.TeamMember `json:"members"`
 }
 
-type orderRepository interface {
-	AcquireForSnatch(ctx context.Context, orderID uint64) (*model.Order, error)
-	UpdateColumns(ctx context.Context, orderID uint64, values map[string]any) error
+// CreateTeamOrderRequest 用户下车队订单请求，价格、人数与时长取自团队护航服务项目
+type CreateTeamOrderRequest struct {
+	ItemID         uint64     `json:"itemId" binding:"required"`
+	Title          string     `json:"title" binding:"max=128"`
+	Description    string     `json:"description"`
+	ScheduledStart *time.Time `json:"scheduledStart" binding:"required"`
 }
 
-type opLogRepository interface {
-	Append(ctx context.Context, log *model.OperationLog) error
+// TeamOrderResponse 车队订单下单结果
+type TeamOrderResponse struct {
+	OrderID         uint64         `json:"orderId"`
+	PriceCents      int64          `json:"priceCents"`
+	Currency        model.Currency `json:"currency"`
+	RequiredMembers int            `json:"requiredMembers"`
+	NeedPayment     bool           `json:"needPayment"`
 }
 
-// --- request/response types used by tests ---
+// PoolRequest 车队大厅查询
+type PoolRequest struct {
+	GameID   *uint64 `form:"gameId"`
+	Page     int     `form:"page"`
+	PageSize int     `form:"pageSize"`
+}
 
+// SnatchRequest 车队抢单请求
 type SnatchRequest struct {
-	OrderID uint64
-	TeamID  uint64
+	TeamID uint64 `json:"teamId" binding:"required"`
 }
 
-type SnatchResponse struct {
-	AssignmentID uint64
+// LineupRequest 队长设置出车成员，人数须与订单所需人数一致且包含队长
+type LineupRequest struct {
+	MemberIDs []uint64 `json:"memberIds" binding:"required,min=1"`
 }
 
+// PayoutShare 出车成员分成比例（%）
 type PayoutShare struct {
-	MemberID uint64
-	Percent  int
+	MemberID uint64 `json:"memberId"`
+	Percent  int    `json:"percent"`
 }
 
+// PayoutPlanRequest 队长设置收入分配方案；自定义比例须覆盖全部出车成员且合计 100
 type PayoutPlanRequest struct {
-	OrderID    uint64
-	TeamID     uint64
-	ProfitMode model.TeamProfitMode
-	Shares     []PayoutShare
+	ProfitMode model.TeamProfitMode `json:"profitMode" binding:"required,oneof=equal custom"`
+	Shares     []PayoutShare        `json:"shares"`
+}
+
+// AssignmentDetail 车队接单详情
+type AssignmentDetail struct {
+	Assignment model.TeamOrderAssignment    `json:"assignment"`
+	Members    []model.TeamAssignmentMember `json:"members"`
+	ProfitMode model.TeamProfitMode         `json:"profitMode"`
+}
+
+// Service 车队服务
+type Service struct {
+	teams         teamrepo.TeamRepository
+	orders        repository.OrderRepository
+	players       repository.PlayerRepository
+	items         ItemReader
+	history       orderstate.HistoryAppender
+	opLogs        repository.OperationLogRepository
+	notifications repository.NotificationRepository
+	window        time.Duration
+	now           func() time.Time
+}
+
+// NewService 创建车队服务
+func NewService(teams teamrepo.TeamRepository, orders repository.OrderRepository, players repository.PlayerRepository, items ItemReader) *Service {
+	return &Service{teams: teams, orders: orders, players: players, items: items, window: DefaultDispatchWindow, now: time.Now}
 }
 
-type PayoutPlanResponse struct {
-	ProfitMode model.TeamProfitMode
-	Shares     []PayoutShare
+// SetDispatchWindow 设置抢单后的组队时限
+func (s *Service) SetDispatchWindow(d time.Duration) {
+	if d > 0 {
+		s.window = d
+	}
 }
 
-func (s *Service) nowTime() time.Time {
-	if s.now != nil {
-		return s.now()
+// SetStatusHistory 注入订单状态历史，组队完成开始服务时记录流转
+func (s *Service) SetStatusHistory(h orderstate.HistoryAppender) { s.history = h }
+
+// SetOperationLogs 注入操作日志仓储，记录车队抢单
+func (s *Service) SetOperationLogs(l repository.OperationLogRepository) { s.opLogs = l }
+
+// SetNotifications 注入通知仓储，通知出车成员与队长
+func (s *Service) SetNotifications(n repository.NotificationRepository) { s.notifications = n }
+
+// CreateTeam 陪玩师创建车队并成为队长
+func (s *Service) CreateTeam(ctx context.Context, userID uint64, req CreateTeamRequest) (*TeamDetail, error) {
+	player, err := s.player(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: team name is required", ErrValidation)
 	}
-	return time.Now().UTC()
+	team := &model.Team{
+		Name:         name,
+		LeaderUserID: userID,
+		GameID:       req.GameID,
+		Description:  strings.TrimSpace(req.Description),
+		Status:       model.TeamStatusActive,
+	}
+	leader := &model.TeamMember{
+		UserID:   userID,
+		PlayerID: player.ID,
+		Role:     model.TeamRoleLeader,
+		Status:   model.TeamMemberStatusActive,
+	}
+	if err := s.teams.CreateTeam(ctx, team, leader); err != nil {
+		return nil, err
+	}
+	return &TeamDetail{Team: *team, Members: []model.TeamMember{*leader}}, nil
 }
 
-// SnatchOrder allows a team leader to claim an order for team dispatching.
-func (s *Service) SnatchOrder(ctx context.Context, leaderID uint64, req SnatchRequest) (*SnatchResponse, error) {
-	// validate leader role
-	member, err := s.members.GetByTeamAndUser(ctx, req.TeamID, leaderID)
+// ListMyTeams 列出用户所在的车队
+func (s *Service) ListMyTeams(ctx context.Context, userID uint64) ([]model.Team, error) {
+	return s.teams.ListTeamsByUser(ctx, userID)
+}
+
+// GetTeam 车队详情，仅车队成员可查看
+func (s *Service) GetTeam(ctx context.Context, userID, teamID uint64) (*TeamDetail, error) {
+	team, err := s.activeTeam(ctx, teamID)
 	if err != nil {
 		return nil, err
 	}
-	if member.Role != model.TeamRoleLeader || member.Status != model.TeamMemberStatusActive {
-		return nil, errors.New("permission denied: not active team leader")
+	member, err := s.teams.GetMemberByUser(ctx, teamID, userID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && member.Status != model.TeamMemberStatusActive) {
+		return nil, ErrForbidden
 	}
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.teams.ListMembers(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return &TeamDetail{Team: *team, Members: members}, nil
+}
 
-	// acquire order for snatch
-	order, err := s.orders.AcquireForSnatch(ctx, req.OrderID)
+// AddMember 队长添加陪玩师为成员；已退出的成员重新入队
+func (s *Service) AddMember(ctx context.Context, leaderUserID, teamID uint64, req AddMemberRequest) (*model.TeamMember, error) {
+	if _, _, err := s.leader(ctx, leaderUserID, teamID); err != nil {
+		return nil, err
+	}
+	player, err := s.player(ctx, req.UserID)
 	if err != nil {
 		return nil, err
 	}
-	// basic validation according to test data
-	if order.Status != model.OrderStatusPending {
-		return nil, fmt.Errorf("invalid order status: %s", order.Status)
+	member, err := s.teams.GetMemberByUser(ctx, teamID, req.UserID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		member = &model.TeamMember{TeamID: teamID, UserID: req.UserID}
+	case err != nil:
+		return nil, err
+	case member.Status == model.TeamMemberStatusActive:
+		return nil, fmt.Errorf("%w: user is already a team member", ErrValidation)
+	}
+	member.PlayerID = player.ID
+	member.Role = model.TeamRoleMember
+	member.Status = model.TeamMemberStatusActive
+	member.ProfitShareDefault = req.ProfitShareDefault
+	if err := s.teams.SaveMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember 队长移出成员，或成员主动退出；队长不能退出自己的车队
+func (s *Service) RemoveMember(ctx context.Context, actorUserID, teamID, memberID uint64) error {
+	member, err := s.teams.GetMember(ctx, memberID)
+	if err != nil {
+		return err
+	}
+	if member.TeamID != teamID || member.Status != model.TeamMemberStatusActive {
+		return ErrNotFound
 	}
-	if order.QueueType != model.OrderQueueTypeTeam {
-		return nil, errors.New("order is not in team queue")
+	if member.UserID != actorUserID {
+		if _, _, err := s.leader(ctx, actorUserID, teamID); err != nil {
+			return err
+		}
 	}
+	if member.Role == model.TeamRoleLeader {
+		return fmt.Errorf("%w: the leader cannot leave the team", ErrValidation)
+	}
+	member.Status = model.TeamMemberStatusRemoved
+	return s.teams.SaveMember(ctx, member)
+}
 
-	// create assignment (first wins, others will fail inside repo)
-	now := s.nowTime()
+// CreateTeamOrder 用户按团队护航服务项目下车队订单，支付后进入车队大厅
+func (s *Service) CreateTeamOrder(ctx context.Context, userID uint64, req CreateTeamOrderRequest) (*TeamOrderResponse, error) {
+	item, err := s.items.Get(ctx, req.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.SubCategory != model.SubCategoryTeam || !item.IsActive {
+		return nil, fmt.Errorf("%w: item is not an active team service", ErrValidation)
+	}
+	if item.MaxPlayers < 2 {
+		return nil, fmt.Errorf("%w: team service must require at least 2 players", ErrValidation)
+	}
+	hours := item.ServiceHours
+	if hours <= 0 {
+		hours = 1
+	}
+	start := *req.ScheduledStart
+	end := start.Add(time.Duration(hours) * time.Hour)
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = item.Name
+	}
+	price := item.BasePriceCents
+	commission, income := item.SplitAmount(price)
+	order := &model.Order{
+		OrderNo:           model.GenerateEscortOrderNo(),
+		UserID:            userID,
+		ItemID:            item.ID,
+		GameID:            item.GameID,
+		Quantity:          1,
+		UnitPriceCents:    price,
+		TotalPriceCents:   price,
+		CommissionCents:   commission,
+		PlayerIncomeCents: income,
+		CommissionRate:    int(math.Round(item.CommissionRate * 100)),
+		Currency:          item.Currency.OrDefault(),
+		Status:            model.OrderStatusPending,
+		Title:             title,
+		Description:       req.Description,
+		ScheduledStart:    &start,
+		ScheduledEnd:      &end,
+		QueueType:         model.OrderQueueTypeTeam,
+		RequiredMembers:   item.MaxPlayers,
+	}
+	if err := s.orders.Create(ctx, order); err != nil {
+		return nil, err
+	}
+	return &TeamOrderResponse{
+		OrderID:         order.ID,
+		PriceCents:      price,
+		Currency:        order.Currency,
+		RequiredMembers: order.RequiredMembers,
+		NeedPayment:     true,
+	}, nil
+}
+
+// ListPool 车队大厅：已支付且尚未被车队抢走的车队订单
+func (s *Service) ListPool(ctx context.Context, req PoolRequest) ([]model.Order, int64, error) {
+	return s.teams.ListPoolOrders(ctx, req.GameID, req.Page, req.PageSize)
+}
+
+// SnatchOrder 队长为车队抢单；同一订单只有一个车队能抢到，须在组队时限内完成组队
+func (s *Service) SnatchOrder(ctx context.Context, leaderUserID, orderID uint64, req SnatchRequest) (*model.TeamOrderAssignment, error) {
+	team, _, err := s.leader(ctx, leaderUserID, req.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !order.IsTeamOrder() || order.Status != model.OrderStatusConfirmed || order.AssignedTeamID != nil {
+		return nil, ErrOrderUnavailable
+	}
+	members, err := s.teams.ListMembers(ctx, team.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) < order.RequiredMembers {
+		return nil, fmt.Errorf("%w: team has %d members, order requires %d", ErrValidation, len(members), order.RequiredMembers)
+	}
+
+	now := s.now()
 	assignment := &model.TeamOrderAssignment{
-		OrderID:          req.OrderID,
-		TeamID:           req.TeamID,
+		OrderID:          order.ID,
+		TeamID:           team.ID,
+		LeaderUserID:     leaderUserID,
 		Status:           model.TeamAssignmentStatusDispatching,
-		DispatchDeadline: now.Add(10 * time.Minute),
-		LockedAt:         &now,
+		DispatchDeadline: now.Add(s.window),
+		LockedAt:         now,
 	}
-	if err := s.assignments.Create(ctx, assignment); err != nil {
+	ok, err := s.teams.ClaimOrder(ctx, assignment)
+	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		return nil, ErrOrderUnavailable
+	}
+	if s.opLogs != nil {
+		actor := leaderUserID
+		err := s.opLogs.Append(ctx, &model.OperationLog{
+			EntityType:  string(model.OpEntityOrder),
+			EntityID:    order.ID,
+			ActorUserID: &actor,
+			Action:      string(model.OpActionAssignPlayer),
+			Reason:      fmt.Sprintf("车队 %d 抢单", team.ID),
+		})
+		if err != nil {
+			slog.Warn("append team snatch log failed", slog.Uint64("order_id", order.ID), slog.String("error", err.Error()))
+		}
+	}
+	return assignment, nil
+}
 
-	// update order assignment fields
-	if err := s.orders.UpdateColumns(ctx, req.OrderID, map[string]any{
-		"assigned_team_id":  req.TeamID,
-		"assignment_source": model.OrderAssignmentSourceTeam,
-	}); err != nil {
+// SetLineup 队长设置出车成员。队长自动确认，其余成员收到通知后确认；重设成员会清空自定义分配比例。
+func (s *Service) SetLineup(ctx context.Context, leaderUserID, orderID uint64, req LineupRequest) (*AssignmentDetail, error) {
+	order, assignment, err := s.leaderAssignment(ctx, leaderUserID, orderID)
+	if err != nil {
 		return nil, err
 	}
+	if assignment.Status != model.TeamAssignmentStatusDispatching {
+		return nil, ErrAssignmentClosed
+	}
+	members, err := s.teams.ListMembers(ctx, assignment.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]model.TeamMember, len(members))
+	for _, m := range members {
+		byID[m.ID] = m
+	}
 
-	// append simple op log (best-effort)
-	_ = s.opLogs.Append(ctx, &model.OperationLog{
-		EntityType: string(model.OpEntityOrder),
-		EntityID:   req.OrderID,
-		Action:     string(model.OpActionAssignPlayer),
-	})
+	now := s.now()
+	seen := make(map[uint64]bool, len(req.MemberIDs))
+	lineup := make([]model.TeamAssignmentMember, 0, len(req.MemberIDs))
+	hasLeader := false
+	for _, id := range req.MemberIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		m, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %d is not in the team", ErrValidation, id)
+		}
+		row := model.TeamAssignmentMember{
+			AssignmentID: assignment.ID,
+			MemberID:     m.ID,
+			UserID:       m.UserID,
+			PlayerID:     m.PlayerID,
+			State:        model.TeamAssignmentMemberPending,
+		}
+		if m.UserID == assignment.LeaderUserID {
+			hasLeader = true
+			row.State = model.TeamAssignmentMemberConfirmed
+			row.ConfirmedAt = &now
+		}
+		lineup = append(lineup, row)
+	}
+	if len(lineup) != order.RequiredMembers {
+		return nil, fmt.Errorf("%w: lineup must have exactly %d members", ErrValidation, order.RequiredMembers)
+	}
+	if !hasLeader {
+		return nil, fmt.Errorf("%w: lineup must include the leader", ErrValidation)
+	}
+	if err := s.teams.ReplaceAssignmentMembers(ctx, assignment.ID, lineup); err != nil {
+		return nil, err
+	}
+	if plan, err := s.teams.GetPayoutPlan(ctx, assignment.ID); err == nil && plan.ProfitMode == model.TeamProfitModeCustom {
+		plan.ProfitMode = model.TeamProfitModeEqual
+		if err := s.teams.SavePayoutPlan(ctx, plan, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range lineup {
+		if m.State == model.TeamAssignmentMemberPending {
+			s.notifyUser(ctx, m.UserID, order, "车队出车邀请",
+				fmt.Sprintf("队长邀请你参与订单 %s，请在 %s 前确认", orderLabel(order), assignment.DispatchDeadline.Format("15:04")))
+		}
+	}
+	if err := s.tryStart(ctx, order, assignment); err != nil {
+		return nil, err
+	}
+	return s.assignmentDetail(ctx, orderID)
+}
+
+// ConfirmAssignment 出车成员确认参与；全部确认后订单指派给队长并开始服务
+func (s *Service) ConfirmAssignment(ctx context.Context, userID, orderID uint64) (*AssignmentDetail, error) {
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	assignment, err := s.teams.GetActiveAssignment(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if assignment.Status != model.TeamAssignmentStatusDispatching {
+		return nil, ErrAssignmentClosed
+	}
+	ok, err := s.teams.ConfirmAssignmentMember(ctx, assignment.ID, userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		members, err := s.teams.ListAssignmentMembers(ctx, assignment.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !inLineup(members, userID) {
+			return nil, ErrForbidden
+		}
+	}
+	if err := s.tryStart(ctx, order, assignment); err != nil {
+		return nil, err
+	}
+	return s.assignmentDetail(ctx, orderID)
+}
+
+// UpsertPayoutPlan 队长设置本单收入分配方案，订单完成前可修改
+func (s *Service) UpsertPayoutPlan(ctx context.Context, leaderUserID, orderID uint64, req PayoutPlanRequest) (*AssignmentDetail, error) {
+	order, assignment, err := s.leaderAssignment(ctx, leaderUserID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	switch order.Status {
+	case model.OrderStatusConfirmed, model.OrderStatusInProgress, model.OrderStatusPendingConfirmation:
+	default:
+		return nil, ErrAssignmentClosed
+	}
+	members, err := s.teams.ListAssignmentMembers(ctx, assignment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("%w: set the lineup before the payout plan", ErrValidation)
+	}
+
+	var shares map[uint64]int
+	switch req.ProfitMode {
+	case model.TeamProfitModeEqual:
+	case model.TeamProfitModeCustom:
+		shares = make(map[uint64]int, len(req.Shares))
+		sum := 0
+		for _, sh := range req.Shares {
+			if sh.Percent < 0 || sh.Percent > 100 {
+				return nil, fmt.Errorf("%w: share percent must be between 0 and 100", ErrValidation)
+			}
+			shares[sh.MemberID] += sh.Percent
+			sum += sh.Percent
+		}
+		if sum != 100 {
+			return nil, fmt.Errorf("%w: share percents must sum to 100", ErrValidation)
+		}
+		if len(shares) != len(members) {
+			return nil, fmt.Errorf("%w: shares must cover every lineup member", ErrValidation)
+		}
+		for _, m := range members {
+			if _, ok := shares[m.MemberID]; !ok {
+				return nil, fmt.Errorf("%w: shares must cover every lineup member", ErrValidation)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown profit mode %q", ErrValidation, req.ProfitMode)
+	}
+
+	plan := &model.TeamPayoutPlan{AssignmentID: assignment.ID, ProfitMode: req.ProfitMode}
+	if err := s.teams.SavePayoutPlan(ctx, plan, shares); err != nil {
+		return nil, err
+	}
+	return s.assignmentDetail(ctx, orderID)
+}
 
-	return &SnatchResponse{AssignmentID: assignment.ID}, nil
+// GetAssignment 车队接单详情，车队成员可查看
+func (s *Service) GetAssignment(ctx context.Context, userID, orderID uint64) (*AssignmentDetail, error) {
+	assignment, err := s.teams.GetActiveAssignment(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.teams.GetMemberByUser(ctx, assignment.TeamID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrForbidden
+	}
+	if err != nil {
+		return nil, err
+	}
+	if member.Status != model.TeamMemberStatusActive {
+		return nil, ErrForbidden
+	}
+	return s.assignmentDetail(ctx, orderID)
 }
 
-// ReleaseExpiredAssignments releases timed-out team assignments and clears order assignment.
-func (s *Service) ReleaseExpiredAssignments(ctx context.Context) (int, error) {
-	now := s.nowTime()
-	expired, err := s.assignments.ListExpired(ctx, now)
+// ReleaseExpiredAssignments 释放组队超时的车队接单，订单回到车队大厅
+func (s *Service) ReleaseExpiredAssignments(ctx context.Context, limit int) (int, error) {
+	now := s.now()
+	expired, err := s.teams.ListExpiredAssignments(ctx, now, limit)
 	if err != nil {
 		return 0, err
 	}
-	count := 0
+	released := 0
 	for i := range expired {
-		a := expired[i]
-		a.Status = model.TeamAssignmentStatusReleased
-		a.ReleasedAt = &now
-		if err := s.assignments.Update(ctx, &a); err != nil {
-			return count, err
+		a := &expired[i]
+		ok, err := s.teams.ReleaseAssignment(ctx, a, now)
+		if err != nil {
+			return released, err
+		}
+		if !ok {
+			continue
 		}
-		// clear order assigned team
-		if err := s.orders.UpdateColumns(ctx, a.OrderID, map[string]any{
-			"assigned_team_id": nil,
-		}); err != nil {
-			return count, err
+		released++
+		if order, err := s.orders.Get(ctx, a.OrderID); err == nil {
+			s.notifyUser(ctx, a.LeaderUserID, order, "车队组队超时",
+				fmt.Sprintf("订单 %s 未在时限内完成组队，已释放回车队大厅", orderLabel(order)))
 		}
-		count++
 	}
-	return count, nil
+	return released, nil
 }
 
-// UpsertPayoutPlan creates or updates payout plan for an assignment. Leader only.
-func (s *Service) UpsertPayoutPlan(ctx context.Context, leaderID uint64, req PayoutPlanRequest) (*PayoutPlanResponse, error) {
-	// validate leader role
-	member, err := s.members.GetByTeamAndUser(ctx, req.TeamID, leaderID)
+// SplitCommission 将车队订单的抽成记录按出车成员拆分：均分或按队长设置的比例，
+// 除不尽的部分归队长。非车队订单或未完成组队的订单原样返回。
+func (s *Service) SplitCommission(ctx context.Context, order *model.Order, record *model.CommissionRecord) ([]*model.CommissionRecord, error) {
+	single := []*model.CommissionRecord{record}
+	if !order.IsTeamOrder() {
+		return single, nil
+	}
+	assignment, err := s.teams.GetActiveAssignment(ctx, order.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return single, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	if member.Role != model.TeamRoleLeader || member.Status != model.TeamMemberStatusActive {
-		return nil, errors.New("permission denied: not active team leader")
+	if assignment.Status != model.TeamAssignmentStatusConfirmed {
+		return single, nil
 	}
-
-	// fetch assignment by order
-	assignment, err := s.assignments.GetByOrderID(ctx, req.OrderID)
+	members, err := s.teams.ListAssignmentMembers(ctx, assignment.ID)
 	if err != nil {
 		return nil, err
 	}
-	if assignment.TeamID != req.TeamID {
-		return nil, errors.New("assignment team mismatch")
+	if len(members) == 0 {
+		return single, nil
+	}
+	mode := model.TeamProfitModeEqual
+	if plan, err := s.teams.GetPayoutPlan(ctx, assignment.ID); err == nil {
+		mode = plan.ProfitMode
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
 	}
 
-	// validate custom shares sum to 100
-	if req.ProfitMode == model.TeamProfitModeCustom {
-		sum := 0
-		for _, s := range req.Shares {
-			sum += s.Percent
+	weights := make([]int64, len(members))
+	leaderIdx := 0
+	for i, m := range members {
+		weights[i] = 1
+		if mode == model.TeamProfitModeCustom {
+			weights[i] = int64(m.SharePercent)
 		}
-		if sum != 100 {
-			return nil, errors.New("shares percent must sum to 100")
+		if m.UserID == assignment.LeaderUserID {
+			leaderIdx = i
 		}
 	}
+	totals := splitAmount(record.TotalAmountCents, weights, leaderIdx)
+	commissions := splitAmount(record.CommissionCents, weights, leaderIdx)
+	subsidies := splitAmount(record.SubsidyCents, weights, leaderIdx)
+
+	teamID := assignment.TeamID
+	out := make([]*model.CommissionRecord, 0, len(members))
+	for i, m := range members {
+		out = append(out, &model.CommissionRecord{
+			OrderID:           record.OrderID,
+			PlayerID:          m.PlayerID,
+			TotalAmountCents:  totals[i],
+			CommissionRate:    record.CommissionRate,
+			CommissionCents:   commissions[i],
+			PlayerIncomeCents: totals[i] - commissions[i] + subsidies[i],
+			SubsidyCents:      subsidies[i],
+			Currency:          record.Currency,
+			SettlementStatus:  record.SettlementStatus,
+			SettlementMonth:   record.SettlementMonth,
+			TeamID:            &teamID,
+		})
+	}
+	return out, nil
+}
+
+// splitAmount 按权重拆分金额，向下取整后的余数归 remainderIdx
+func splitAmount(amount int64, weights []int64, remainderIdx int) []int64 {
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	out := make([]int64, len(weights))
+	if sum <= 0 {
+		out[remainderIdx] = amount
+		return out
+	}
+	var allocated int64
+	for i, w := range weights {
+		out[i] = amount * w / sum
+		allocated += out[i]
+	}
+	out[remainderIdx] += amount - allocated
+	return out
+}
 
-	// persist plan
-	plan := &model.TeamPayoutPlan{
-		AssignmentID: assignment.ID,
-		ProfitMode:   req.ProfitMode,
+// tryStart 出车成员全部确认后完成组队：订单指派给队长并进入服务中
+func (s *Service) tryStart(ctx context.Context, order *model.Order, assignment *model.TeamOrderAssignment) error {
+	members, err := s.teams.ListAssignmentMembers(ctx, assignment.ID)
+	if err != nil {
+		return err
+	}
+	if len(members) != order.RequiredMembers {
+		return nil
 	}
-	if err := s.payoutPlans.Upsert(ctx, plan); err != nil {
+	var leaderPlayerID uint64
+	for _, m := range members {
+		if m.State != model.TeamAssignmentMemberConfirmed {
+			return nil
+		}
+		if m.UserID == assignment.LeaderUserID {
+			leaderPlayerID = m.PlayerID
+		}
+	}
+	ok, err := s.teams.ConfirmAssignment(ctx, assignment.ID, s.now())
+	if err != nil || !ok {
+		// 已由其他成员的确认完成组队，或组队已超时释放
+		return err
+	}
+	order.SetPlayerID(leaderPlayerID)
+	leader := assignment.LeaderUserID
+	return orderstate.Transit(ctx, s.orders, s.history, order, model.OrderStatusInProgress, orderstate.Change{
+		Role:        model.OrderActorPlayer,
+		ActorUserID: &leader,
+		Reason:      "车队组队完成",
+	})
+}
+
+func (s *Service) assignmentDetail(ctx context.Context, orderID uint64) (*AssignmentDetail, error) {
+	assignment, err := s.teams.GetActiveAssignment(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.teams.ListAssignmentMembers(ctx, assignment.ID)
+	if err != nil {
 		return nil, err
 	}
+	detail := &AssignmentDetail{Assignment: *assignment, Members: members, ProfitMode: model.TeamProfitModeEqual}
+	if plan, err := s.teams.GetPayoutPlan(ctx, assignment.ID); err == nil {
+		detail.ProfitMode = plan.ProfitMode
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// leaderAssignment 订单当前的车队接单记录，调用方须为该车队队长
+func (s *Service) leaderAssignment(ctx context.Context, leaderUserID, orderID uint64) (*model.Order, *model.TeamOrderAssignment, error) {
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	assignment, err := s.teams.GetActiveAssignment(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, _, err := s.leader(ctx, leaderUserID, assignment.TeamID); err != nil {
+		return nil, nil, err
+	}
+	return order, assignment, nil
+}
 
-	return &PayoutPlanResponse{
-		ProfitMode: req.ProfitMode,
-		Shares:     req.Shares,
-	}, nil
+// leader 校验用户为车队在队队长
+func (s *Service) leader(ctx context.Context, userID, teamID uint64) (*model.Team, *model.TeamMember, error) {
+	team, err := s.activeTeam(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.teams.GetMemberByUser(ctx, teamID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, ErrForbidden
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if member.Role != model.TeamRoleLeader || member.Status != model.TeamMemberStatusActive {
+		return nil, nil, ErrForbidden
+	}
+	return team, member, nil
+}
+
+func (s *Service) activeTeam(ctx context.Context, teamID uint64) (*model.Team, error) {
+	team, err := s.teams.GetTeam(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.Status != model.TeamStatusActive {
+		return nil, ErrNotFound
+	}
+	return team, nil
+}
+
+// player 用户对应的已认证陪玩师
+func (s *Service) player(ctx context.Context, userID uint64) (*model.Player, error) {
+	player, err := s.players.GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrNotPlayer
+	}
+	if err != nil {
+		return nil, err
+	}
+	if player.VerificationStatus != model.VerificationVerified {
+		return nil, ErrNotPlayer
+	}
+	return player, nil
+}
+
+func inLineup(members []model.TeamAssignmentMember, userID uint64) bool {
+	for _, m := range members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyUser 通知用户，失败只记录日志
+func (s *Service) notifyUser(ctx context.Context, userID uint64, order *model.Order, title, message string) {
+	if s.notifications == nil {
+		return
+	}
+	id := order.ID
+	err := s.notifications.Create(ctx, &model.NotificationEvent{
+		UserID:        userID,
+		Title:         title,
+		Message:       message,
+		Priority:      model.NotificationPriorityHigh,
+		ReferenceType: string(model.OpEntityOrder),
+		ReferenceID:   &id,
+	})
+	if err != nil {
+		slog.Warn("notify team member failed", slog.Uint64("order_id", order.ID), slog.Uint64("user_id", userID), slog.String("error", err.Error()))
+	}
+}
+
+// orderLabel 通知中展示的订单标识
+func orderLabel(order *model.Order) string {
+	if order.OrderNo != "" {
+		return order.OrderNo
+	}
+	return fmt.Sprintf("#%d", order.ID)
 }
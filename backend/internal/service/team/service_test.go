@@ -1,501 +1,297 @@
-//go:build team_service
-// +build team_service
-
 package team
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"sync"
-	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 
 	"gamelink/internal/model"
-	"gamelink/internal/repository"
+	notificationrepo "gamelink/internal/repository/notification"
+	operationlogrepo "gamelink/internal/repository/operation_log"
 	orderrepo "gamelink/internal/repository/order"
+	orderhistory "gamelink/internal/repository/order_history"
+	playerrepo "gamelink/internal/repository/player"
+	serviceitemrepo "gamelink/internal/repository/serviceitem"
+	teamrepo "gamelink/internal/repository/team"
 )
 
-func TestServiceSnatchOrderConcurrent(t *testing.T) {
-	t.Parallel()
-
-	ctx := context.Background()
-	env := newTestEnv()
-
-	const (
-		leaderID = uint64(11)
-		teamID   = uint64(77)
-		orderID  = uint64(900)
-	)
-
-	env.members.addMember(teamID, leaderID, model.TeamRoleLeader, model.TeamMemberStatusActive)
-	env.orders.saveOrder(&model.Order{Base: model.Base{ID: orderID}, QueueType: model.OrderQueueTypeTeam, RequiredMembers: 3, Status: model.OrderStatusPending})
-
-	var success int32
-	var wg sync.WaitGroup
-	for i := 0; i < 100; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if _, err := env.svc.SnatchOrder(ctx, leaderID, SnatchRequest{OrderID: orderID, TeamID: teamID}); err == nil {
-				atomic.AddInt32(&success, 1)
-			}
-		}()
-	}
-	wg.Wait()
-
-	require.Equal(t, int32(1), success, "only one snatch should succeed")
-	assignment, err := env.assignments.GetByOrderID(ctx, orderID)
-	require.NoError(t, err)
-	require.Equal(t, teamID, assignment.TeamID)
-	require.Equal(t, model.TeamAssignmentStatusDispatching, assignment.Status)
-	require.NotNil(t, env.orders.orders[orderID].AssignedTeamID)
-	require.Equal(t, teamID, *env.orders.orders[orderID].AssignedTeamID)
+type teamEnv struct {
+	db      *gorm.DB
+	svc     *Service
+	now     time.Time
+	players []*model.Player // 0 为队长，1、2 为成员，3 为另一车队队长
+	item    *model.ServiceItem
 }
 
-func TestServiceReleaseExpiredAssignments(t *testing.T) {
-	t.Parallel()
-
-	ctx := context.Background()
-	env := newTestEnv()
-
-	const (
-		teamID  = uint64(50)
-		orderID = uint64(200)
-	)
-
-	assignedTeamID := teamID
-	env.orders.saveOrder(&model.Order{Base: model.Base{ID: orderID}, AssignedTeamID: &assignedTeamID})
-
-	env.nowValue = env.nowValue.Add(-10 * time.Minute)
-	err := env.assignments.Create(ctx, &model.TeamOrderAssignment{
-		OrderID:          orderID,
-		TeamID:           teamID,
-		Status:           model.TeamAssignmentStatusDispatching,
-		DispatchDeadline: env.nowValue.Add(-time.Minute),
-		LockedAt:         env.nowValue.Add(-5 * time.Minute),
-	})
+func newTeamEnv(t *testing.T) *teamEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
 	require.NoError(t, err)
-
-	env.nowValue = env.nowValue.Add(15 * time.Minute)
-	released, err := env.svc.ReleaseExpiredAssignments(ctx)
+	// 内存库每个连接各自独立，并发抢单须共用同一连接
+	sqlDB, err := db.DB()
 	require.NoError(t, err)
-	require.Equal(t, 1, released)
-
-	updated, err := env.assignments.GetByOrderID(ctx, orderID)
-	require.NoError(t, err)
-	require.Equal(t, model.TeamAssignmentStatusReleased, updated.Status)
-	require.NotNil(t, updated.ReleasedAt)
-	require.Nil(t, env.orders.orders[orderID].AssignedTeamID)
-}
-
-func TestServiceUpsertPayoutPlan(t *testing.T) {
-	t.Parallel()
-
-	ctx := context.Background()
-	env := newTestEnv()
-
-	const (
-		leaderID = uint64(4)
-		teamID   = uint64(5)
-		orderID  = uint64(6)
-	)
-
-	env.members.addMember(teamID, leaderID, model.TeamRoleLeader, model.TeamMemberStatusActive)
-	require.NoError(t, env.assignments.Create(ctx, &model.TeamOrderAssignment{OrderID: orderID, TeamID: teamID}))
-
-	resp, err := env.svc.UpsertPayoutPlan(ctx, leaderID, PayoutPlanRequest{
-		OrderID:    orderID,
-		TeamID:     teamID,
-		ProfitMode: model.TeamProfitModeCustom,
-		Shares: []PayoutShare{
-			{MemberID: 1, Percent: 60},
-			{MemberID: 2, Percent: 40},
-		},
-	})
-	require.NoError(t, err)
-	require.Equal(t, model.TeamProfitModeCustom, resp.ProfitMode)
-	require.Len(t, resp.Shares, 2)
-
-	plan, err := env.payoutPlans.GetByAssignment(ctx, env.assignments.mustGet(orderID).ID)
-	require.NoError(t, err)
-	require.Equal(t, model.TeamProfitModeCustom, plan.ProfitMode)
-}
-
-func TestServiceUpsertPayoutPlanValidation(t *testing.T) {
-	t.Parallel()
-
-	ctx := context.Background()
-	env := newTestEnv()
-
-	const (
-		leaderID = uint64(8)
-		teamID   = uint64(9)
-		orderID  = uint64(10)
-	)
-
-	env.members.addMember(teamID, leaderID, model.TeamRoleLeader, model.TeamMemberStatusActive)
-	require.NoError(t, env.assignments.Create(ctx, &model.TeamOrderAssignment{OrderID: orderID, TeamID: teamID}))
-
-	_, err := env.svc.UpsertPayoutPlan(ctx, leaderID, PayoutPlanRequest{
-		OrderID: orderID,
-		TeamID:  teamID,
-		Shares:  []PayoutShare{{MemberID: 1, Percent: 90}},
-	})
-	require.Error(t, err)
-}
-
-// --- test helpers ---
-
-type testEnv struct {
-	svc          *Service
-	teams        *fakeTeamRepo
-	members      *fakeTeamMemberRepo
-	assignments  *fakeAssignmentRepo
-	assignMember *fakeAssignmentMemberRepo
-	payoutPlans  *fakePayoutPlanRepo
-	orders       *fakeOrderRepo
-	opLogs       *fakeOpLogRepo
-	nowValue     time.Time
-}
-
-func newTestEnv() *testEnv {
-	teams := &fakeTeamRepo{}
-	members := newFakeTeamMemberRepo()
-	assignments := newFakeAssignmentRepo()
-	assignMembers := newFakeAssignmentMemberRepo()
-	payoutPlans := newFakePayoutPlanRepo()
-	orders := newFakeOrderRepo()
-	opLogs := newFakeOpLogRepo()
-	env := &testEnv{
-		teams:        teams,
-		members:      members,
-		assignments:  assignments,
-		assignMember: assignMembers,
-		payoutPlans:  payoutPlans,
-		orders:       orders,
-		opLogs:       opLogs,
-		nowValue:     time.Now().UTC(),
-	}
-	svc := &Service{
-		teams:             teams,
-		members:           members,
-		assignments:       assignments,
-		assignmentMembers: assignMembers,
-		payoutPlans:       payoutPlans,
-		orders:            orders,
-		opLogs:            opLogs,
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.Player{}, &model.Order{}, &model.OrderStatusHistory{}, &model.ServiceItem{},
+		&model.OperationLog{}, &model.NotificationEvent{}, &model.Team{}, &model.TeamMember{},
+		&model.TeamOrderAssignment{}, &model.TeamAssignmentMember{}, &model.TeamPayoutPlan{}))
+
+	env := &teamEnv{db: db, now: time.Now()}
+	for i := 0; i < 4; i++ {
+		p := &model.Player{UserID: uint64(201 + i), VerificationStatus: model.VerificationVerified}
+		require.NoError(t, db.Create(p).Error)
+		env.players = append(env.players, p)
 	}
-	svc.now = func() time.Time {
-		return env.nowValue
-	}
-	env.svc = svc
+	gameID := uint64(7)
+	env.item = &model.ServiceItem{ItemCode: "TEAM-3", Name: "三人车队", SubCategory: model.SubCategoryTeam, GameID: &gameID,
+		BasePriceCents: 30001, ServiceHours: 2, CommissionRate: 0.2, MaxPlayers: 3, IsActive: true}
+	require.NoError(t, db.Create(env.item).Error)
+
+	env.svc = NewService(teamrepo.NewTeamRepository(db), orderrepo.NewOrderRepository(db), playerrepo.NewPlayerRepository(db),
+		serviceitemrepo.NewServiceItemRepository(db))
+	env.svc.SetStatusHistory(orderhistory.NewHistoryRepository(db))
+	env.svc.SetOperationLogs(operationlogrepo.NewOperationLogRepository(db))
+	env.svc.SetNotifications(notificationrepo.NewNotificationRepository(db))
+	env.svc.now = func() time.Time { return env.now }
 	return env
 }
 
-type fakeTeamRepo struct{}
-
-func (r *fakeTeamRepo) Create(ctx context.Context, team *model.Team) error { return nil }
-func (r *fakeTeamRepo) Get(ctx context.Context, id uint64) (*model.Team, error) {
-	return nil, repository.ErrNotFound
-}
-func (r *fakeTeamRepo) ListByLeader(ctx context.Context, leaderID uint64) ([]model.Team, error) {
-	return nil, nil
-}
-func (r *fakeTeamRepo) ListByUser(ctx context.Context, userID uint64) ([]model.Team, error) {
-	return nil, nil
-}
-
-type fakeTeamMemberRepo struct {
-	mu     sync.Mutex
-	byTeam map[uint64]map[uint64]*model.TeamMember
-	byID   map[uint64]*model.TeamMember
-	nextID uint64
-}
-
-func newFakeTeamMemberRepo() *fakeTeamMemberRepo {
-	return &fakeTeamMemberRepo{byTeam: make(map[uint64]map[uint64]*model.TeamMember), byID: make(map[uint64]*model.TeamMember)}
-}
-
-func (r *fakeTeamMemberRepo) addMember(teamID, userID uint64, role model.TeamRole, status model.TeamMemberStatus) uint64 {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.nextID++
-	member := &model.TeamMember{TeamID: teamID, UserID: userID, Role: role, Status: status}
-	member.ID = r.nextID
-	if _, ok := r.byTeam[teamID]; !ok {
-		r.byTeam[teamID] = make(map[uint64]*model.TeamMember)
-	}
-	r.byTeam[teamID][userID] = member
-	r.byID[member.ID] = member
-	return member.ID
-}
-
-func (r *fakeTeamMemberRepo) Create(ctx context.Context, member *model.TeamMember) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.nextID++
-	member.ID = r.nextID
-	if _, ok := r.byTeam[member.TeamID]; !ok {
-		r.byTeam[member.TeamID] = make(map[uint64]*model.TeamMember)
-	}
-	cp := *member
-	r.byTeam[member.TeamID][member.UserID] = &cp
-	r.byID[cp.ID] = &cp
-	return nil
-}
-
-func (r *fakeTeamMemberRepo) ListByTeam(ctx context.Context, teamID uint64) ([]model.TeamMember, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	result := make([]model.TeamMember, 0)
-	for _, member := range r.byTeam[teamID] {
-		result = append(result, *member)
+// newTeam 以 players[leader] 为队长组建车队，其余 members 入队，返回车队与成员 ID（按入队顺序）
+func (e *teamEnv) newTeam(t *testing.T, leader int, members ...int) (uint64, []uint64) {
+	t.Helper()
+	ctx := context.Background()
+	detail, err := e.svc.CreateTeam(ctx, e.players[leader].UserID, CreateTeamRequest{Name: "车队"})
+	require.NoError(t, err)
+	ids := []uint64{detail.Members[0].ID}
+	for _, i := range members {
+		m, err := e.svc.AddMember(ctx, e.players[leader].UserID, detail.Team.ID, AddMemberRequest{UserID: e.players[i].UserID})
+		require.NoError(t, err)
+		ids = append(ids, m.ID)
 	}
-	return result, nil
+	return detail.Team.ID, ids
 }
 
-func (r *fakeTeamMemberRepo) GetByTeamAndUser(ctx context.Context, teamID, userID uint64) (*model.TeamMember, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if teamMembers, ok := r.byTeam[teamID]; ok {
-		if member, ok := teamMembers[userID]; ok {
-			cp := *member
-			return &cp, nil
-		}
-	}
-	return nil, repository.ErrNotFound
+// paidTeamOrder 下车队订单并模拟支付完成
+func (e *teamEnv) paidTeamOrder(t *testing.T) uint64 {
+	t.Helper()
+	start := e.now.Add(24 * time.Hour)
+	resp, err := e.svc.CreateTeamOrder(context.Background(), 9, CreateTeamOrderRequest{ItemID: e.item.ID, ScheduledStart: &start})
+	require.NoError(t, err)
+	require.NoError(t, e.db.Model(&model.Order{}).Where("id = ?", resp.OrderID).Update("status", model.OrderStatusConfirmed).Error)
+	return resp.OrderID
 }
 
-func (r *fakeTeamMemberRepo) Update(ctx context.Context, member *model.TeamMember) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if existing, ok := r.byID[member.ID]; ok {
-		existing.Role = member.Role
-		existing.Status = member.Status
-		existing.ProfitShareDefault = member.ProfitShareDefault
-		return nil
-	}
-	return repository.ErrNotFound
+func (e *teamEnv) order(t *testing.T, id uint64) model.Order {
+	t.Helper()
+	var o model.Order
+	require.NoError(t, e.db.First(&o, id).Error)
+	return o
 }
 
-type fakeAssignmentRepo struct {
-	mu      sync.Mutex
-	byOrder map[uint64]*model.TeamOrderAssignment
-	nextID  uint64
-}
+func TestTeamMembers(t *testing.T) {
+	env := newTeamEnv(t)
+	ctx := context.Background()
+	teamID, ids := env.newTeam(t, 0, 1)
+
+	// 非队长不能拉人，非陪玩师不能入队
+	_, err := env.svc.AddMember(ctx, env.players[1].UserID, teamID, AddMemberRequest{UserID: env.players[2].UserID})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = env.svc.AddMember(ctx, env.players[0].UserID, teamID, AddMemberRequest{UserID: 999})
+	assert.ErrorIs(t, err, ErrNotPlayer)
+	_, err = env.svc.AddMember(ctx, env.players[0].UserID, teamID, AddMemberRequest{UserID: env.players[1].UserID})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	// 队长不能退出；成员可以自行退出后再被拉回
+	assert.ErrorIs(t, env.svc.RemoveMember(ctx, env.players[0].UserID, teamID, ids[0]), ErrValidation)
+	require.NoError(t, env.svc.RemoveMember(ctx, env.players[1].UserID, teamID, ids[1]))
+	_, err = env.svc.GetTeam(ctx, env.players[1].UserID, teamID)
+	assert.ErrorIs(t, err, ErrForbidden)
+	back, err := env.svc.AddMember(ctx, env.players[0].UserID, teamID, AddMemberRequest{UserID: env.players[1].UserID})
+	require.NoError(t, err)
+	assert.Equal(t, ids[1], back.ID)
 
-func newFakeAssignmentRepo() *fakeAssignmentRepo {
-	return &fakeAssignmentRepo{byOrder: make(map[uint64]*model.TeamOrderAssignment)}
+	detail, err := env.svc.GetTeam(ctx, env.players[1].UserID, teamID)
+	require.NoError(t, err)
+	assert.Len(t, detail.Members, 2)
+	teams, err := env.svc.ListMyTeams(ctx, env.players[1].UserID)
+	require.NoError(t, err)
+	assert.Len(t, teams, 1)
 }
 
-func (r *fakeAssignmentRepo) Create(ctx context.Context, assignment *model.TeamOrderAssignment) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if existing, ok := r.byOrder[assignment.OrderID]; ok && existing.Status != model.TeamAssignmentStatusReleased {
-		return fmt.Errorf("assignment exists")
-	}
-	r.nextID++
-	if assignment.ID == 0 {
-		assignment.ID = r.nextID
-	}
-	cp := *assignment
-	r.byOrder[assignment.OrderID] = &cp
-	return nil
-}
+func TestSnatchOrderOnlyOneTeamWins(t *testing.T) {
+	env := newTeamEnv(t)
+	ctx := context.Background()
+	teamA, _ := env.newTeam(t, 0, 1, 2)
+	teamB, _ := env.newTeam(t, 3, 1, 2)
+	orderID := env.paidTeamOrder(t)
 
-func (r *fakeAssignmentRepo) GetByOrderID(ctx context.Context, orderID uint64) (*model.TeamOrderAssignment, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if assignment, ok := r.byOrder[orderID]; ok {
-		cp := *assignment
-		return &cp, nil
-	}
-	return nil, repository.ErrNotFound
-}
+	pool, total, err := env.svc.ListPool(ctx, PoolRequest{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, pool, 1)
+	assert.Equal(t, 3, pool[0].RequiredMembers)
 
-func (r *fakeAssignmentRepo) Update(ctx context.Context, assignment *model.TeamOrderAssignment) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if _, ok := r.byOrder[assignment.OrderID]; !ok {
-		return repository.ErrNotFound
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, c := range []struct {
+		leader uint64
+		team   uint64
+	}{{env.players[0].UserID, teamA}, {env.players[3].UserID, teamB}} {
+		wg.Add(1)
+		go func(i int, leader, team uint64) {
+			defer wg.Done()
+			_, errs[i] = env.svc.SnatchOrder(ctx, leader, orderID, SnatchRequest{TeamID: team})
+		}(i, c.leader, c.team)
 	}
-	cp := *assignment
-	r.byOrder[assignment.OrderID] = &cp
-	return nil
-}
+	wg.Wait()
 
-func (r *fakeAssignmentRepo) ListExpired(ctx context.Context, now time.Time) ([]model.TeamOrderAssignment, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	var result []model.TeamOrderAssignment
-	for _, assignment := range r.byOrder {
-		if assignment.Status == model.TeamAssignmentStatusDispatching && assignment.DispatchDeadline.Before(now) {
-			result = append(result, *assignment)
+	var won int
+	for _, err := range errs {
+		if err == nil {
+			won++
+		} else {
+			assert.ErrorIs(t, err, ErrOrderUnavailable)
 		}
 	}
-	return result, nil
-}
-
-func (r *fakeAssignmentRepo) mustGet(orderID uint64) *model.TeamOrderAssignment {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if assignment, ok := r.byOrder[orderID]; ok {
-		return assignment
-	}
-	return nil
-}
+	assert.Equal(t, 1, won)
 
-type fakeAssignmentMemberRepo struct {
-	mu      sync.Mutex
-	records map[uint64][]model.TeamAssignmentMember
+	saved := env.order(t, orderID)
+	require.NotNil(t, saved.AssignedTeamID)
+	assert.Equal(t, model.AssignmentSourceTeam, saved.AssignmentSource)
+	_, total, err = env.svc.ListPool(ctx, PoolRequest{})
+	require.NoError(t, err)
+	assert.Zero(t, total)
 }
 
-func newFakeAssignmentMemberRepo() *fakeAssignmentMemberRepo {
-	return &fakeAssignmentMemberRepo{records: make(map[uint64][]model.TeamAssignmentMember)}
-}
+func TestLineupConfirmAndSplitCommission(t *testing.T) {
+	env := newTeamEnv(t)
+	ctx := context.Background()
+	teamID, ids := env.newTeam(t, 0, 1, 2)
+	orderID := env.paidTeamOrder(t)
+	leader := env.players[0].UserID
 
-func (r *fakeAssignmentMemberRepo) Replace(ctx context.Context, assignmentID uint64, members []*model.TeamAssignmentMember) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	cloned := make([]model.TeamAssignmentMember, len(members))
-	for i, m := range members {
-		cloned[i] = *m
-	}
-	r.records[assignmentID] = cloned
-	return nil
-}
+	_, err := env.svc.SnatchOrder(ctx, leader, orderID, SnatchRequest{TeamID: teamID})
+	require.NoError(t, err)
 
-func (r *fakeAssignmentMemberRepo) ListByAssignment(ctx context.Context, assignmentID uint64) ([]model.TeamAssignmentMember, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if members, ok := r.records[assignmentID]; ok {
-		copied := make([]model.TeamAssignmentMember, len(members))
-		copy(copied, members)
-		return copied, nil
-	}
-	return nil, nil
-}
+	// 人数不符或不含队长均不可
+	_, err = env.svc.SetLineup(ctx, leader, orderID, LineupRequest{MemberIDs: ids[:2]})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = env.svc.SetLineup(ctx, env.players[1].UserID, orderID, LineupRequest{MemberIDs: ids})
+	assert.ErrorIs(t, err, ErrForbidden)
 
-func (r *fakeAssignmentMemberRepo) UpdateState(ctx context.Context, assignmentID, memberID uint64, state model.TeamAssignmentMemberState, confirmedAt *time.Time) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	members := r.records[assignmentID]
-	for i := range members {
-		if members[i].MemberID == memberID {
-			members[i].State = state
-			members[i].ConfirmedAt = confirmedAt
-			r.records[assignmentID][i] = members[i]
-			return nil
-		}
-	}
-	return repository.ErrNotFound
-}
+	detail, err := env.svc.SetLineup(ctx, leader, orderID, LineupRequest{MemberIDs: ids})
+	require.NoError(t, err)
+	require.Len(t, detail.Members, 3)
+	assert.Equal(t, model.TeamAssignmentMemberConfirmed, detail.Members[0].State)
+	assert.Equal(t, model.TeamAssignmentMemberPending, detail.Members[1].State)
 
-type fakePayoutPlanRepo struct {
-	mu    sync.Mutex
-	plans map[uint64]*model.TeamPayoutPlan
-}
+	_, err = env.svc.UpsertPayoutPlan(ctx, leader, orderID, PayoutPlanRequest{
+		ProfitMode: model.TeamProfitModeCustom,
+		Shares:     []PayoutShare{{MemberID: ids[0], Percent: 50}, {MemberID: ids[1], Percent: 30}},
+	})
+	assert.ErrorIs(t, err, ErrValidation)
+	detail, err = env.svc.UpsertPayoutPlan(ctx, leader, orderID, PayoutPlanRequest{
+		ProfitMode: model.TeamProfitModeCustom,
+		Shares:     []PayoutShare{{MemberID: ids[0], Percent: 50}, {MemberID: ids[1], Percent: 30}, {MemberID: ids[2], Percent: 20}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.TeamProfitModeCustom, detail.ProfitMode)
+	assert.Equal(t, 30, detail.Members[1].SharePercent)
 
-func newFakePayoutPlanRepo() *fakePayoutPlanRepo {
-	return &fakePayoutPlanRepo{plans: make(map[uint64]*model.TeamPayoutPlan)}
-}
+	// 非出车成员不能确认；全部确认后订单指派给队长并开始服务
+	_, err = env.svc.ConfirmAssignment(ctx, env.players[3].UserID, orderID)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = env.svc.ConfirmAssignment(ctx, env.players[1].UserID, orderID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrderStatusConfirmed, env.order(t, orderID).Status)
+	detail, err = env.svc.ConfirmAssignment(ctx, env.players[2].UserID, orderID)
+	require.NoError(t, err)
+	assert.Equal(t, model.TeamAssignmentStatusConfirmed, detail.Assignment.Status)
 
-func (r *fakePayoutPlanRepo) Upsert(ctx context.Context, plan *model.TeamPayoutPlan) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	cp := *plan
-	r.plans[plan.AssignmentID] = &cp
-	return nil
-}
+	saved := env.order(t, orderID)
+	assert.Equal(t, model.OrderStatusInProgress, saved.Status)
+	assert.Equal(t, env.players[0].ID, saved.GetPlayerID())
 
-func (r *fakePayoutPlanRepo) GetByAssignment(ctx context.Context, assignmentID uint64) (*model.TeamPayoutPlan, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if plan, ok := r.plans[assignmentID]; ok {
-		cp := *plan
-		return &cp, nil
+	records, err := env.svc.SplitCommission(ctx, &saved, &model.CommissionRecord{
+		OrderID: orderID, PlayerID: env.players[0].ID, TotalAmountCents: 30001, CommissionRate: 20,
+		CommissionCents: 6000, PlayerIncomeCents: 24001, Currency: model.CurrencyCNY,
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	var total, income int64
+	for i, r := range records {
+		assert.Equal(t, env.players[i].ID, r.PlayerID)
+		require.NotNil(t, r.TeamID)
+		assert.Equal(t, teamID, *r.TeamID)
+		total += r.TotalAmountCents
+		income += r.PlayerIncomeCents
 	}
-	return nil, repository.ErrNotFound
+	// 除不尽的 1 分归队长
+	assert.EqualValues(t, 15001, records[0].TotalAmountCents)
+	assert.EqualValues(t, 12001, records[0].PlayerIncomeCents)
+	assert.EqualValues(t, 7200, records[1].PlayerIncomeCents)
+	assert.EqualValues(t, 4800, records[2].PlayerIncomeCents)
+	assert.EqualValues(t, 30001, total)
+	assert.EqualValues(t, 24001, income)
 }
 
-type fakeOrderRepo struct {
-	mu     sync.Mutex
-	orders map[uint64]*model.Order
-}
-
-func newFakeOrderRepo() *fakeOrderRepo {
-	return &fakeOrderRepo{orders: make(map[uint64]*model.Order)}
-}
+func TestReleaseExpiredAssignments(t *testing.T) {
+	env := newTeamEnv(t)
+	ctx := context.Background()
+	teamID, ids := env.newTeam(t, 0, 1, 2)
+	orderID := env.paidTeamOrder(t)
+	leader := env.players[0].UserID
 
-func (r *fakeOrderRepo) ListPool(ctx context.Context, opts orderrepo.PoolListOptions) ([]model.Order, *uint64, error) {
-	return nil, nil, nil
-}
+	assignment, err := env.svc.SnatchOrder(ctx, leader, orderID, SnatchRequest{TeamID: teamID})
+	require.NoError(t, err)
+	assert.Equal(t, env.now.Add(DefaultDispatchWindow), assignment.DispatchDeadline)
+	_, err = env.svc.SetLineup(ctx, leader, orderID, LineupRequest{MemberIDs: ids})
+	require.NoError(t, err)
 
-func (r *fakeOrderRepo) AcquireForSnatch(ctx context.Context, orderID uint64) (*model.Order, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if order, ok := r.orders[orderID]; ok {
-		cp := *order
-		return &cp, nil
-	}
-	return nil, repository.ErrNotFound
-}
+	n, err := env.svc.ReleaseExpiredAssignments(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
 
-func (r *fakeOrderRepo) UpdateColumns(ctx context.Context, orderID uint64, values map[string]any) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	order, ok := r.orders[orderID]
-	if !ok {
-		return repository.ErrNotFound
-	}
-	for key, val := range values {
-		switch key {
-		case "assigned_team_id":
-			if val == nil {
-				order.AssignedTeamID = nil
-			} else {
-				switch v := val.(type) {
-				case uint64:
-					order.AssignedTeamID = &v
-				case *uint64:
-					order.AssignedTeamID = v
-				}
-			}
-		case "assignment_source":
-			switch v := val.(type) {
-			case model.OrderAssignmentSource:
-				order.AssignmentSource = v
-			case string:
-				order.AssignmentSource = model.OrderAssignmentSource(v)
-			}
-		}
-	}
-	return nil
-}
+	env.now = env.now.Add(DefaultDispatchWindow + time.Second)
+	n, err = env.svc.ReleaseExpiredAssignments(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
 
-func (r *fakeOrderRepo) saveOrder(order *model.Order) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.orders[order.ID] = order
-}
+	saved := env.order(t, orderID)
+	assert.Nil(t, saved.AssignedTeamID)
+	assert.Equal(t, model.OrderStatusConfirmed, saved.Status)
+	_, err = env.svc.ConfirmAssignment(ctx, env.players[1].UserID, orderID)
+	assert.True(t, errors.Is(err, ErrNotFound))
 
-type fakeOpLogRepo struct {
-	mu   sync.Mutex
-	logs []model.OperationLog
+	// 释放后订单回到车队大厅，可被重新抢单
+	_, total, err := env.svc.ListPool(ctx, PoolRequest{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	_, err = env.svc.SnatchOrder(ctx, leader, orderID, SnatchRequest{TeamID: teamID})
+	require.NoError(t, err)
 }
 
-func newFakeOpLogRepo() *fakeOpLogRepo { return &fakeOpLogRepo{} }
+func TestCreateTeamOrderRequiresTeamItem(t *testing.T) {
+	env := newTeamEnv(t)
+	solo := &model.ServiceItem{ItemCode: "SOLO", Name: "单人", SubCategory: model.SubCategorySolo, BasePriceCents: 100, MaxPlayers: 1, IsActive: true}
+	require.NoError(t, env.db.Create(solo).Error)
+	start := env.now.Add(time.Hour)
 
-func (r *fakeOpLogRepo) Append(ctx context.Context, log *model.OperationLog) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.logs = append(r.logs, *log)
-	return nil
-}
+	_, err := env.svc.CreateTeamOrder(context.Background(), 9, CreateTeamOrderRequest{ItemID: solo.ID, ScheduledStart: &start})
+	assert.ErrorIs(t, err, ErrValidation)
 
-func (r *fakeOpLogRepo) ListByEntity(ctx context.Context, entityType string, entityID uint64, opts repository.OperationLogListOptions) ([]model.OperationLog, int64, error) {
-	return nil, 0, nil
+	resp, err := env.svc.CreateTeamOrder(context.Background(), 9, CreateTeamOrderRequest{ItemID: env.item.ID, ScheduledStart: &start})
+	require.NoError(t, err)
+	saved := env.order(t, resp.OrderID)
+	assert.True(t, saved.IsTeamOrder())
+	assert.Equal(t, 3, saved.RequiredMembers)
+	assert.EqualValues(t, 30001, saved.TotalPriceCents)
+	assert.Equal(t, "三人车队", saved.Title)
+	assert.True(t, start.Add(2*time.Hour).Equal(*saved.ScheduledEnd))
 }
@@ -0,0 +1,266 @@
+// Package upload 上传文件：内容写入存储后端并登记 Upload 记录（哈希、属主、尺寸），
+// 下载一律经服务端签发的限时链接；争议证据、聊天图片与动态配图按上传 ID 引用文件。
+package upload
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册 GIF 解码器以读取尺寸
+	_ "image/jpeg" // 注册 JPEG 解码器以读取尺寸
+	_ "image/png"  // 注册 PNG 解码器以读取尺寸
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	uploadrepo "gamelink/internal/repository/upload"
+	"gamelink/internal/storage"
+)
+
+var (
+	// ErrNotFound 上传记录或文件不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrInvalidSignature 下载链接签名无效或已过期
+	ErrInvalidSignature = errors.New("invalid or expired download link")
+	// ErrInvalidAttachment 引用的上传不存在、未完成、重复或属于他人
+	ErrInvalidAttachment = errors.New("invalid attachment")
+)
+
+const (
+	defaultURLTTL = 15 * time.Minute
+	// contentPath 签名下载链接的路径，与 handler 注册的公开下载路由一致
+	contentPath = "/api/v1/uploads/%d/content"
+)
+
+// UploadRequest 一次文件上传。Body 需可回绕：先计算哈希与尺寸，去重未命中时再写入存储。
+type UploadRequest struct {
+	Type     model.UploadType
+	FileName string
+	Body     io.ReadSeeker
+}
+
+// UploadView 上传记录与签名下载链接
+type UploadView struct {
+	ID         uint64           `json:"id"`
+	FileName   string           `json:"fileName"`
+	FileSize   int64            `json:"fileSize"`
+	MimeType   string           `json:"mimeType"`
+	UploadType model.UploadType `json:"uploadType"`
+	Width      int              `json:"width,omitempty"`
+	Height     int              `json:"height,omitempty"`
+	URL        string           `json:"url"`
+	ExpiresAt  time.Time        `json:"expiresAt"`
+	CreatedAt  time.Time        `json:"createdAt"`
+}
+
+// Service 上传文件服务
+type Service struct {
+	uploads    uploadrepo.UploadRepository
+	store      storage.Storage
+	signingKey []byte
+	ttl        time.Duration
+	now        func() time.Time
+}
+
+// NewService 创建上传服务；signingKey 用于签发下载链接，ttl 非正时使用 15 分钟。
+func NewService(uploads uploadrepo.UploadRepository, store storage.Storage, signingKey string, ttl time.Duration) *Service {
+	if ttl <= 0 {
+		ttl = defaultURLTTL
+	}
+	return &Service{uploads: uploads, store: store, signingKey: []byte(signingKey), ttl: ttl, now: time.Now}
+}
+
+// Upload 保存文件并登记上传记录。同一用户重复上传相同内容时直接返回已有记录。
+// 大小、扩展名与 MIME 白名单由调用方按 middleware.GetImageConfig/GetVideoConfig 校验。
+func (s *Service) Upload(ctx context.Context, userID uint64, req UploadRequest) (*UploadView, error) {
+	fileName := filepath.Base(strings.TrimSpace(req.FileName))
+	if userID == 0 || req.Body == nil || fileName == "." || fileName == "/" {
+		return nil, ErrValidation
+	}
+	if req.Type == "" {
+		req.Type = model.UploadTypeOther
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(req.Body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("%w: empty file", ErrValidation)
+	}
+	mimeType := strings.Split(http.DetectContentType(head[:n]), ";")[0]
+
+	hasher := sha256.New()
+	if _, err := req.Body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(hasher, req.Body)
+	if err != nil {
+		return nil, err
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if existing, err := s.uploads.GetByHash(ctx, userID, hash); err == nil {
+		return s.view(existing), nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	upload := &model.Upload{
+		UserID:     userID,
+		FileName:   fileName,
+		FileSize:   size,
+		MimeType:   mimeType,
+		UploadType: req.Type,
+		Status:     model.UploadStatusCompleted,
+		Hash:       hash,
+	}
+	if upload.IsImage() {
+		if _, err := req.Body.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		// 解码器未注册的格式（如 WebP）不记录尺寸
+		if cfg, _, err := image.DecodeConfig(req.Body); err == nil {
+			upload.Width, upload.Height = cfg.Width, cfg.Height
+		}
+	}
+
+	upload.FilePath = fmt.Sprintf("%s/%s/%s%s", req.Type, s.now().Format("2006/01"), uuid.NewString(), strings.ToLower(filepath.Ext(fileName)))
+	if _, err := req.Body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, upload.FilePath, req.Body, size, mimeType); err != nil {
+		return nil, err
+	}
+	if err := s.uploads.Create(ctx, upload); err != nil {
+		_ = s.store.Delete(ctx, upload.FilePath)
+		return nil, err
+	}
+	return s.view(upload), nil
+}
+
+// GetUpload 返回用户自己的上传记录与新的下载链接。
+func (s *Service) GetUpload(ctx context.Context, userID, id uint64) (*UploadView, error) {
+	upload, err := s.uploads.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return s.view(upload), nil
+}
+
+// Attach 校验待引用的上传：全部存在、已完成且属于 ownerID，按入参顺序返回；不满足时返回 ErrInvalidAttachment。
+func (s *Service) Attach(ctx context.Context, ownerID uint64, ids []uint64) ([]model.Upload, error) {
+	seen := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			return nil, fmt.Errorf("%w: upload %d is invalid or duplicated", ErrInvalidAttachment, id)
+		}
+		seen[id] = true
+	}
+	rows, err := s.uploads.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]model.Upload, len(rows))
+	for _, u := range rows {
+		byID[u.ID] = u
+	}
+	out := make([]model.Upload, 0, len(ids))
+	for _, id := range ids {
+		u, ok := byID[id]
+		if !ok || u.Status != model.UploadStatusCompleted {
+			return nil, fmt.Errorf("%w: upload %d not found", ErrInvalidAttachment, id)
+		}
+		if u.UserID != ownerID {
+			return nil, fmt.Errorf("%w: upload %d belongs to another user", ErrInvalidAttachment, id)
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// URLs 为引用的上传签发下载链接，已不存在的上传不出现在结果中。
+func (s *Service) URLs(ctx context.Context, ids []uint64) (map[uint64]string, error) {
+	urls := make(map[uint64]string, len(ids))
+	if len(ids) == 0 {
+		return urls, nil
+	}
+	rows, err := s.uploads.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range rows {
+		if u.Status == model.UploadStatusCompleted {
+			urls[u.ID], _ = s.SignedURL(u.ID)
+		}
+	}
+	return urls, nil
+}
+
+// SignedURL 签发下载链接，返回链接与过期时间。
+func (s *Service) SignedURL(id uint64) (string, time.Time) {
+	expires := s.now().Add(s.ttl).Unix()
+	return fmt.Sprintf(contentPath+"?expires=%d&signature=%s", id, expires, s.sign(id, expires)), time.Unix(expires, 0)
+}
+
+// Open 校验下载链接签名并打开文件，调用方负责关闭。
+func (s *Service) Open(ctx context.Context, id uint64, expires, signature string) (*model.Upload, io.ReadCloser, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.now().Unix() > exp || !hmac.Equal([]byte(signature), []byte(s.sign(id, exp))) {
+		return nil, nil, ErrInvalidSignature
+	}
+	upload, err := s.uploads.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if upload.Status != model.UploadStatusCompleted {
+		return nil, nil, ErrNotFound
+	}
+	body, err := s.store.Open(ctx, upload.FilePath)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return upload, body, nil
+}
+
+func (s *Service) sign(id uint64, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%d:%d", id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) view(u *model.Upload) *UploadView {
+	url, expiresAt := s.SignedURL(u.ID)
+	return &UploadView{
+		ID:         u.ID,
+		FileName:   u.FileName,
+		FileSize:   u.FileSize,
+		MimeType:   u.MimeType,
+		UploadType: u.UploadType,
+		Width:      u.Width,
+		Height:     u.Height,
+		URL:        url,
+		ExpiresAt:  expiresAt,
+		CreatedAt:  u.CreatedAt,
+	}
+}
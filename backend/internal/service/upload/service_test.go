@@ -0,0 +1,126 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	uploadrepo "gamelink/internal/repository/upload"
+	"gamelink/internal/storage"
+)
+
+func newTestService(t *testing.T) (*Service, storage.Storage) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Upload{}))
+	store, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	return NewService(uploadrepo.NewUploadRepository(db), store, "test-key", time.Minute), store
+}
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return buf.Bytes()
+}
+
+func TestUpload_StoresAndDedupes(t *testing.T) {
+	ctx := context.Background()
+	svc, store := newTestService(t)
+	data := pngBytes(t, 4, 3)
+
+	view, err := svc.Upload(ctx, 1, UploadRequest{Type: model.UploadTypeDisputeEvidence, FileName: "shot.PNG", Body: bytes.NewReader(data)})
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", view.MimeType)
+	assert.EqualValues(t, len(data), view.FileSize)
+	assert.Equal(t, 4, view.Width)
+	assert.Equal(t, 3, view.Height)
+
+	stored, err := svc.uploads.Get(ctx, view.ID)
+	require.NoError(t, err)
+	assert.Len(t, stored.Hash, 64)
+	assert.True(t, strings.HasPrefix(stored.FilePath, "dispute_evidence/"))
+	assert.True(t, strings.HasSuffix(stored.FilePath, ".png"))
+	rc, err := store.Open(ctx, stored.FilePath)
+	require.NoError(t, err)
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, data, got)
+
+	again, err := svc.Upload(ctx, 1, UploadRequest{Type: model.UploadTypeDisputeEvidence, FileName: "copy.png", Body: bytes.NewReader(data)})
+	require.NoError(t, err)
+	assert.Equal(t, view.ID, again.ID)
+	other, err := svc.Upload(ctx, 2, UploadRequest{Type: model.UploadTypeDisputeEvidence, FileName: "copy.png", Body: bytes.NewReader(data)})
+	require.NoError(t, err)
+	assert.NotEqual(t, view.ID, other.ID)
+
+	_, err = svc.Upload(ctx, 1, UploadRequest{FileName: "empty.png", Body: bytes.NewReader(nil)})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	_, err = svc.GetUpload(ctx, 2, view.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSignedURL_OpenAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t)
+	view, err := svc.Upload(ctx, 1, UploadRequest{FileName: "note.txt", Body: strings.NewReader("hello evidence")})
+	require.NoError(t, err)
+
+	u, err := url.Parse(view.URL)
+	require.NoError(t, err)
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+
+	upload, body, err := svc.Open(ctx, view.ID, expires, signature)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "hello evidence", string(data))
+	assert.Equal(t, "text/plain", upload.MimeType)
+
+	_, _, err = svc.Open(ctx, view.ID+1, expires, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, _, err = svc.Open(ctx, view.ID, expires, signature[:len(signature)-1]+"x")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, _, err = svc.Open(ctx, view.ID, expires, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestAttach_ChecksOwnership(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t)
+	mine, err := svc.Upload(ctx, 1, UploadRequest{FileName: "a.png", Body: bytes.NewReader(pngBytes(t, 1, 1))})
+	require.NoError(t, err)
+	theirs, err := svc.Upload(ctx, 2, UploadRequest{FileName: "b.png", Body: bytes.NewReader(pngBytes(t, 2, 2))})
+	require.NoError(t, err)
+
+	uploads, err := svc.Attach(ctx, 1, []uint64{mine.ID})
+	require.NoError(t, err)
+	require.Len(t, uploads, 1)
+	assert.True(t, uploads[0].IsImage())
+
+	for _, ids := range [][]uint64{{mine.ID, theirs.ID}, {mine.ID, 999}, {mine.ID, mine.ID}, {0}} {
+		_, err = svc.Attach(ctx, 1, ids)
+		assert.ErrorIs(t, err, ErrInvalidAttachment, ids)
+	}
+
+	urls, err := svc.URLs(ctx, []uint64{mine.ID, theirs.ID, 999})
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+	assert.Contains(t, urls[mine.ID], "/api/v1/uploads/")
+}
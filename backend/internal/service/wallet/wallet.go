@@ -0,0 +1,331 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	walletrepo "gamelink/internal/repository/wallet"
+)
+
+var (
+	// ErrNotFound 钱包或流水不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrInsufficientBalance 余额不足
+	ErrInsufficientBalance = errors.New("insufficient wallet balance")
+	// ErrConcurrentUpdate 钱包在加锁期间仍被其他请求修改
+	ErrConcurrentUpdate = errors.New("wallet is being updated concurrently, please retry")
+)
+
+// WalletService 陪玩师钱包服务
+//
+// 功能：
+// 1. 订单收入记入待结算，月结后转入可提现
+// 2. 申请提现时从可提现冻结；拒绝或失败时解冻，打款完成后扣减冻结
+//
+// 每次变动写一条不可修改的钱包流水，按（流水类型, 业务单据）幂等；
+// 钱包行在事务内加行锁后读取并更新（版本号兜底校验），余额任何一桶都不会变为负数。
+type WalletService struct {
+	wallets walletrepo.WalletRepository
+	tx      TxManager
+}
+
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
+}
+
+// NewWalletService 创建钱包服务
+func NewWalletService(wallets walletrepo.WalletRepository) *WalletService {
+	return &WalletService{wallets: wallets}
+}
+
+// SetTxManager 注入事务管理器，余额更新与流水写入在同一事务内完成。
+func (s *WalletService) SetTxManager(tx TxManager) { s.tx = tx }
+
+// GetWallet 获取陪玩师指定币种的钱包，不存在时返回空钱包；币种为空表示本位币。
+func (s *WalletService) GetWallet(ctx context.Context, playerID uint64, currency model.Currency) (*model.PlayerWallet, error) {
+	currency = currency.OrDefault()
+	if playerID == 0 || !model.IsValidCurrency(currency) {
+		return nil, ErrValidation
+	}
+	return s.wallets.GetOrCreate(ctx, playerID, currency)
+}
+
+// ListWallets 列出陪玩师的各币种钱包，至少包含本位币钱包。
+func (s *WalletService) ListWallets(ctx context.Context, playerID uint64) ([]model.PlayerWallet, error) {
+	if playerID == 0 {
+		return nil, ErrValidation
+	}
+	wallets, err := s.wallets.ListByPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(wallets) == 0 {
+		w, err := s.wallets.GetOrCreate(ctx, playerID, model.DefaultCurrency)
+		if err != nil {
+			return nil, err
+		}
+		wallets = []model.PlayerWallet{*w}
+	}
+	return wallets, nil
+}
+
+// ListTransactions 查询钱包流水。
+func (s *WalletService) ListTransactions(ctx context.Context, opts walletrepo.TransactionListOptions) ([]model.WalletTransaction, int64, error) {
+	return s.wallets.ListTransactions(ctx, opts)
+}
+
+// CreditIncome 订单抽成确认后，陪玩师收入记入待结算。
+func (s *WalletService) CreditIncome(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error {
+	if record.PlayerIncomeCents <= 0 {
+		return nil
+	}
+	return s.inTx(ctx, r, func(repo walletrepo.WalletRepository) error {
+		_, err := apply(ctx, repo, incomeChange(record))
+		return err
+	})
+}
+
+// SettleIncome 月结：抽成记录对应的收入从待结算转入可提现。
+//
+// 钱包上线前产生的抽成记录没有入账流水，结算时先补记收入。
+func (s *WalletService) SettleIncome(ctx context.Context, r *common.Repos, record *model.CommissionRecord) error {
+	if record.PlayerIncomeCents <= 0 {
+		return nil
+	}
+	return s.inTx(ctx, r, func(repo walletrepo.WalletRepository) error {
+		if _, err := apply(ctx, repo, incomeChange(record)); err != nil {
+			return err
+		}
+		_, err := apply(ctx, repo, change{
+			playerID:     record.PlayerID,
+			currency:     record.Currency,
+			txType:       model.WalletTxSettle,
+			businessType: model.WalletBusinessCommission,
+			businessID:   record.ID,
+			amount:       record.PlayerIncomeCents,
+			pending:      -record.PlayerIncomeCents,
+			available:    record.PlayerIncomeCents,
+			remark:       fmt.Sprintf("%s 月结", record.SettlementMonth),
+		})
+		return err
+	})
+}
+
+// DeductIncome 争议扣款：冲减陪玩师收入。
+//
+// settled 为 false 时从待结算扣除（抽成记录尚未月结）；为 true 时从可提现扣除（结转到月结时扣款）。
+func (s *WalletService) DeductIncome(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment, settled bool) error {
+	c := adjustmentChange(adj, model.WalletTxDeduction, -adj.AmountCents, settled)
+	c.remark = fmt.Sprintf("订单 %d 争议扣款", adj.OrderID)
+	return s.inTx(ctx, r, func(repo walletrepo.WalletRepository) error {
+		_, err := apply(ctx, repo, c)
+		return err
+	})
+}
+
+// RestoreIncome 争议回退：退回扣款。settled 含义同 DeductIncome，按退回时抽成记录的结算状态选择余额桶。
+func (s *WalletService) RestoreIncome(ctx context.Context, r *common.Repos, adj *model.CommissionAdjustment, settled bool) error {
+	c := adjustmentChange(adj, model.WalletTxDeductionRevert, adj.AmountCents, settled)
+	c.remark = fmt.Sprintf("订单 %d 争议回退，退回扣款", adj.OrderID)
+	return s.inTx(ctx, r, func(repo walletrepo.WalletRepository) error {
+		_, err := apply(ctx, repo, c)
+		return err
+	})
+}
+
+// FreezeWithdraw 申请提现：从可提现冻结提现金额，余额不足返回 ErrInsufficientBalance。
+func (s *WalletService) FreezeWithdraw(ctx context.Context, r *common.Repos, withdraw *model.Withdraw) error {
+	return s.inTx(ctx, r, func(repo walletrepo.WalletRepository) error {
+		_, err := apply(ctx, repo, change{
+			playerID:     withdraw.PlayerID,
+			currency:     withdraw.Currency,
+			txType:       model.WalletTxWithdrawFreeze,
+			businessType: model.WalletBusinessWithdraw,
+			businessID:   withdraw.ID,
+			amount:       withdraw.AmountCents,
+			available:    -withdraw.AmountCents,
+			frozen:       withdraw.AmountCents,
+			remark:       "申请提现",
+		})
+		return err
+	})
+}
+
+// ReleaseWithdraw 提现被拒绝或打款失败：冻结金额退回可提现。
+func (s *WalletService) ReleaseWithdraw(ctx context.Context, r *common.Repos, withdraw *model.Withdraw, reason string) error {
+	return s.inTx(ctx, r, func(repo walletrepo.WalletRepository) error {
+		frozen, err := hasFreeze(ctx, repo, withdraw)
+		if err != nil || !frozen {
+			return err
+		}
+		if reason == "" {
+			reason = "提现退回"
+		}
+		_, err = apply(ctx, repo, change{
+			playerID:     withdraw.PlayerID,
+			currency:     withdraw.Currency,
+			txType:       model.WalletTxWithdrawRelease,
+			businessType: model.WalletBusinessWithdraw,
+			businessID:   withdraw.ID,
+			amount:       withdraw.AmountCents,
+			frozen:       -withdraw.AmountCents,
+			available:    withdraw.AmountCents,
+			remark:       reason,
+		})
+		return err
+	})
+}
+
+// ConfirmWithdrawPaid 提现打款完成：扣减冻结金额并累计已提现。
+func (s *WalletService) ConfirmWithdrawPaid(ctx context.Context, r *common.Repos, withdraw *model.Withdraw) error {
+	return s.inTx(ctx, r, func(repo walletrepo.WalletRepository) error {
+		frozen, err := hasFreeze(ctx, repo, withdraw)
+		if err != nil || !frozen {
+			return err
+		}
+		_, err = apply(ctx, repo, change{
+			playerID:     withdraw.PlayerID,
+			currency:     withdraw.Currency,
+			txType:       model.WalletTxWithdrawPaid,
+			businessType: model.WalletBusinessWithdraw,
+			businessID:   withdraw.ID,
+			amount:       withdraw.AmountCents,
+			frozen:       -withdraw.AmountCents,
+			withdrawn:    withdraw.AmountCents,
+			remark:       "提现打款完成",
+		})
+		return err
+	})
+}
+
+// hasFreeze 钱包上线前申请的提现没有冻结流水，解冻与扣减直接跳过。
+func hasFreeze(ctx context.Context, repo walletrepo.WalletRepository, withdraw *model.Withdraw) (bool, error) {
+	_, err := repo.FindTransaction(ctx, model.WalletTxWithdrawFreeze, model.WalletBusinessWithdraw, withdraw.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func incomeChange(record *model.CommissionRecord) change {
+	return change{
+		playerID:     record.PlayerID,
+		currency:     record.Currency,
+		txType:       model.WalletTxIncome,
+		businessType: model.WalletBusinessCommission,
+		businessID:   record.ID,
+		amount:       record.PlayerIncomeCents,
+		pending:      record.PlayerIncomeCents,
+		income:       record.PlayerIncomeCents,
+		remark:       fmt.Sprintf("订单 %d 收入", record.OrderID),
+	}
+}
+
+func adjustmentChange(adj *model.CommissionAdjustment, txType model.WalletTransactionType, delta int64, settled bool) change {
+	c := change{
+		playerID:     adj.PlayerID,
+		currency:     adj.Currency,
+		txType:       txType,
+		businessType: model.WalletBusinessAdjustment,
+		businessID:   adj.ID,
+		amount:       adj.AmountCents,
+		income:       delta,
+	}
+	if settled {
+		c.available = delta
+	} else {
+		c.pending = delta
+	}
+	return c
+}
+
+// change 一次钱包变动：各桶增减额及关联业务单据；只作用于单据币种的钱包。
+type change struct {
+	playerID     uint64
+	currency     model.Currency
+	txType       model.WalletTransactionType
+	businessType string
+	businessID   uint64
+	amount       int64
+	available    int64
+	frozen       int64
+	pending      int64
+	income       int64
+	withdrawn    int64
+	remark       string
+}
+
+// apply 更新钱包余额并写入流水；同一业务单据的同类变动已存在时直接返回原流水。
+func apply(ctx context.Context, repo walletrepo.WalletRepository, c change) (*model.WalletTransaction, error) {
+	c.currency = c.currency.OrDefault()
+	if c.playerID == 0 || c.businessID == 0 || c.amount <= 0 || !model.IsValidCurrency(c.currency) {
+		return nil, ErrValidation
+	}
+	if existing, err := repo.FindTransaction(ctx, c.txType, c.businessType, c.businessID); err == nil {
+		return existing, nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	// 行锁在事务内串行化同一钱包的变动；在调用方事务内重读也能看到最新余额
+	wallet, err := repo.LockForUpdate(ctx, c.playerID, c.currency)
+	if err != nil {
+		return nil, err
+	}
+	wallet.AvailableCents += c.available
+	wallet.FrozenCents += c.frozen
+	wallet.PendingCents += c.pending
+	wallet.TotalIncomeCents += c.income
+	wallet.TotalWithdrawnCents += c.withdrawn
+	if wallet.AvailableCents < 0 || wallet.FrozenCents < 0 || wallet.PendingCents < 0 {
+		return nil, ErrInsufficientBalance
+	}
+	if err := repo.UpdateBalances(ctx, wallet); err != nil {
+		if errors.Is(err, walletrepo.ErrVersionConflict) {
+			return nil, ErrConcurrentUpdate
+		}
+		return nil, err
+	}
+	tx := &model.WalletTransaction{
+		WalletID:       wallet.ID,
+		PlayerID:       c.playerID,
+		Type:           c.txType,
+		BusinessType:   c.businessType,
+		BusinessID:     c.businessID,
+		AmountCents:    c.amount,
+		Currency:       c.currency,
+		AvailableDelta: c.available,
+		FrozenDelta:    c.frozen,
+		PendingDelta:   c.pending,
+		AvailableAfter: wallet.AvailableCents,
+		FrozenAfter:    wallet.FrozenCents,
+		PendingAfter:   wallet.PendingCents,
+		Remark:         c.remark,
+	}
+	if err := repo.CreateTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (s *WalletService) withTx(ctx context.Context, fn func(repo walletrepo.WalletRepository) error) error {
+	if s.tx != nil {
+		return s.tx.WithTx(ctx, func(r *common.Repos) error { return fn(r.Wallets) })
+	}
+	return fn(s.wallets)
+}
+
+// inTx 优先使用调用方事务内的仓储。
+func (s *WalletService) inTx(ctx context.Context, r *common.Repos, fn func(repo walletrepo.WalletRepository) error) error {
+	if r != nil && r.Wallets != nil {
+		return fn(r.Wallets)
+	}
+	return s.withTx(ctx, fn)
+}
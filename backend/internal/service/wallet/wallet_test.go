@@ -0,0 +1,194 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+	walletrepo "gamelink/internal/repository/wallet"
+)
+
+func newTestWallet(t *testing.T) (*WalletService, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&model.PlayerWallet{}, &model.WalletTransaction{}))
+
+	svc := NewWalletService(walletrepo.NewWalletRepository(db))
+	svc.SetTxManager(common.NewUnitOfWork(db))
+	return svc, db
+}
+
+func commission(id uint64, income int64) *model.CommissionRecord {
+	return &model.CommissionRecord{ID: id, OrderID: id, PlayerID: 1, PlayerIncomeCents: income, SettlementMonth: "2024-05"}
+}
+
+func assertBuckets(t *testing.T, svc *WalletService, available, frozen, pending int64) *model.PlayerWallet {
+	t.Helper()
+	w, err := svc.GetWallet(context.Background(), 1, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, available, w.AvailableCents, "available")
+	assert.Equal(t, frozen, w.FrozenCents, "frozen")
+	assert.Equal(t, pending, w.PendingCents, "pending")
+	return w
+}
+
+func TestWallet_IncomeSettleAndWithdraw(t *testing.T) {
+	svc, _ := newTestWallet(t)
+	ctx := context.Background()
+
+	require.NoError(t, svc.CreditIncome(ctx, nil, commission(1, 8000)))
+	require.NoError(t, svc.CreditIncome(ctx, nil, commission(2, 4000)))
+	// 重复入账按幂等处理
+	require.NoError(t, svc.CreditIncome(ctx, nil, commission(1, 8000)))
+	assertBuckets(t, svc, 0, 0, 12000)
+
+	require.NoError(t, svc.SettleIncome(ctx, nil, commission(1, 8000)))
+	assertBuckets(t, svc, 8000, 0, 4000)
+
+	w1 := &model.Withdraw{ID: 11, PlayerID: 1, AmountCents: 5000}
+	require.NoError(t, svc.FreezeWithdraw(ctx, nil, w1))
+	assertBuckets(t, svc, 3000, 5000, 4000)
+
+	// 超过可提现余额的提现被拒绝，余额不变
+	err := svc.FreezeWithdraw(ctx, nil, &model.Withdraw{ID: 12, PlayerID: 1, AmountCents: 3001})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assertBuckets(t, svc, 3000, 5000, 4000)
+
+	require.NoError(t, svc.ConfirmWithdrawPaid(ctx, nil, w1))
+	w := assertBuckets(t, svc, 3000, 0, 4000)
+	assert.Equal(t, int64(12000), w.TotalIncomeCents)
+	assert.Equal(t, int64(5000), w.TotalWithdrawnCents)
+
+	w2 := &model.Withdraw{ID: 13, PlayerID: 1, AmountCents: 2000}
+	require.NoError(t, svc.FreezeWithdraw(ctx, nil, w2))
+	require.NoError(t, svc.ReleaseWithdraw(ctx, nil, w2, "账户信息有误"))
+	require.NoError(t, svc.ReleaseWithdraw(ctx, nil, w2, "账户信息有误"))
+	assertBuckets(t, svc, 3000, 0, 4000)
+
+	txs, total, err := svc.ListTransactions(ctx, walletrepo.TransactionListOptions{PlayerID: 1, PageSize: 50})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), total)
+	last := txs[0]
+	assert.Equal(t, model.WalletTxWithdrawRelease, last.Type)
+	assert.Equal(t, int64(3000), last.AvailableAfter)
+	assert.Equal(t, "账户信息有误", last.Remark)
+}
+
+func TestWallet_SettleBackfillsIncome(t *testing.T) {
+	svc, _ := newTestWallet(t)
+	ctx := context.Background()
+
+	// 钱包上线前的抽成记录：结算时补记收入
+	require.NoError(t, svc.SettleIncome(ctx, nil, commission(5, 3000)))
+	w := assertBuckets(t, svc, 3000, 0, 0)
+	assert.Equal(t, int64(3000), w.TotalIncomeCents)
+
+	// 钱包上线前的提现没有冻结流水，解冻与打款跳过
+	legacy := &model.Withdraw{ID: 9, PlayerID: 1, AmountCents: 1000}
+	require.NoError(t, svc.ReleaseWithdraw(ctx, nil, legacy, ""))
+	require.NoError(t, svc.ConfirmWithdrawPaid(ctx, nil, legacy))
+	assertBuckets(t, svc, 3000, 0, 0)
+}
+
+func TestWallet_BalancesPerCurrency(t *testing.T) {
+	svc, _ := newTestWallet(t)
+	ctx := context.Background()
+
+	usd := commission(7, 2500)
+	usd.Currency = model.CurrencyUSD
+	require.NoError(t, svc.SettleIncome(ctx, nil, usd))
+	require.NoError(t, svc.SettleIncome(ctx, nil, commission(8, 1000)))
+
+	// 外币收入不会计入人民币钱包，提现只能从同币种钱包扣减
+	assertBuckets(t, svc, 1000, 0, 0)
+	err := svc.FreezeWithdraw(ctx, nil, &model.Withdraw{ID: 1, PlayerID: 1, AmountCents: 2000})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	require.NoError(t, svc.FreezeWithdraw(ctx, nil, &model.Withdraw{ID: 2, PlayerID: 1, AmountCents: 2000, Currency: model.CurrencyUSD}))
+
+	wallets, err := svc.ListWallets(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, wallets, 2)
+	assert.Equal(t, model.CurrencyUSD, wallets[1].Currency)
+	assert.Equal(t, int64(500), wallets[1].AvailableCents)
+	assert.Equal(t, int64(2000), wallets[1].FrozenCents)
+
+	typ := model.WalletTxWithdrawFreeze
+	txs, _, err := svc.ListTransactions(ctx, walletrepo.TransactionListOptions{PlayerID: 1, Type: &typ})
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, model.CurrencyUSD, txs[0].Currency)
+
+	_, err = svc.GetWallet(ctx, 1, "JPY")
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+// racingRepo 在更新前模拟绕过行锁的写入修改了钱包。
+type racingRepo struct {
+	walletrepo.WalletRepository
+	db *gorm.DB
+}
+
+func (r *racingRepo) UpdateBalances(ctx context.Context, w *model.PlayerWallet) error {
+	r.db.Model(&model.PlayerWallet{}).Where("id = ?", w.ID).
+		Updates(map[string]any{"available_cents": gorm.Expr("available_cents - ?", 4000), "version": gorm.Expr("version + 1")})
+	return r.WalletRepository.UpdateBalances(ctx, w)
+}
+
+func TestWallet_VersionConflictIsReported(t *testing.T) {
+	svc, db := newTestWallet(t)
+	ctx := context.Background()
+	require.NoError(t, svc.SettleIncome(ctx, nil, commission(1, 10000)))
+
+	racing := NewWalletService(&racingRepo{WalletRepository: walletrepo.NewWalletRepository(db), db: db})
+
+	// 版本号兜底：不在同一事务内重试，冲突直接返回，由调用方整体重试
+	err := racing.FreezeWithdraw(ctx, nil, &model.Withdraw{ID: 1, PlayerID: 1, AmountCents: 5000})
+	assert.ErrorIs(t, err, ErrConcurrentUpdate)
+	assertBuckets(t, svc, 6000, 0, 0)
+	var n int64
+	require.NoError(t, db.Model(&model.WalletTransaction{}).Where("type = ?", model.WalletTxWithdrawFreeze).Count(&n).Error)
+	assert.Zero(t, n)
+}
+
+func TestWallet_RejectsInvalidChange(t *testing.T) {
+	svc, _ := newTestWallet(t)
+	err := svc.FreezeWithdraw(context.Background(), nil, &model.Withdraw{ID: 1, PlayerID: 1, AmountCents: 0})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = svc.GetWallet(context.Background(), 0, "")
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestWallet_DeductAndRestoreIncome(t *testing.T) {
+	svc, _ := newTestWallet(t)
+	ctx := context.Background()
+	require.NoError(t, svc.CreditIncome(ctx, nil, commission(1, 8000)))
+
+	adj := &model.CommissionAdjustment{ID: 1, OrderID: 1, PlayerID: 1, AmountCents: 3000}
+	require.NoError(t, svc.DeductIncome(ctx, nil, adj, false))
+	require.NoError(t, svc.DeductIncome(ctx, nil, adj, false), "idempotent per adjustment")
+	w := assertBuckets(t, svc, 0, 0, 5000)
+	assert.Equal(t, int64(5000), w.TotalIncomeCents)
+
+	// 月结后退回的扣款进入可提现
+	require.NoError(t, svc.SettleIncome(ctx, nil, commission(1, 5000)))
+	require.NoError(t, svc.RestoreIncome(ctx, nil, adj, true))
+	w = assertBuckets(t, svc, 8000, 0, 0)
+	assert.Equal(t, int64(8000), w.TotalIncomeCents)
+
+	// 结转扣款从可提现扣除，余额不足时拒绝
+	carried := &model.CommissionAdjustment{ID: 2, OrderID: 2, PlayerID: 1, AmountCents: 9000}
+	assert.ErrorIs(t, svc.DeductIncome(ctx, nil, carried, true), ErrInsufficientBalance)
+	carried.AmountCents = 2000
+	require.NoError(t, svc.DeductIncome(ctx, nil, carried, true))
+	assertBuckets(t, svc, 6000, 0, 0)
+}
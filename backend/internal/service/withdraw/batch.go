@@ -0,0 +1,193 @@
+package withdraw
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+	withdrawrepo "gamelink/internal/repository/withdraw"
+)
+
+const (
+	defaultBatchSize = 200
+	maxBatchSize     = 1000
+)
+
+// CreateBatchRequest 生成打款批次请求
+type CreateBatchRequest struct {
+	Channel   model.PayoutChannel
+	Currency  model.Currency        // 批次币种，为空表示本位币
+	Method    *model.WithdrawMethod // 为空表示不限提现方式
+	Limit     int                   // 单批最多纳入的提现数，默认 200
+	CreatedBy uint64
+}
+
+// BatchDetail 打款批次及其提现明细
+type BatchDetail struct {
+	Batch     model.WithdrawBatch `json:"batch"`
+	Withdraws []model.Withdraw    `json:"withdraws"`
+}
+
+// BatchFile 导出的银行代付文件
+type BatchFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// CreateBatch 把已审核的提现纳入打款批次并置为打款中
+//
+// 银行文件批次导出后由财务线下提交，再逐笔确认成功或失败；代付批次由调度器自动提交渠道。
+func (s *WithdrawService) CreateBatch(ctx context.Context, req CreateBatchRequest) (*BatchDetail, error) {
+	switch req.Channel {
+	case model.PayoutChannelBankFile:
+	case model.PayoutChannelProvider:
+		if s.provider == nil {
+			return nil, ErrPayoutNotConfigured
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported payout channel %q", ErrValidation, req.Channel)
+	}
+	req.Currency = req.Currency.OrDefault()
+	if req.CreatedBy == 0 || !model.IsValidCurrency(req.Currency) {
+		return nil, ErrValidation
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultBatchSize
+	}
+	if req.Limit > maxBatchSize {
+		req.Limit = maxBatchSize
+	}
+
+	detail := &BatchDetail{}
+	err := s.withTx(ctx, func(r *common.Repos) error {
+		approved, err := r.Payouts.ListApproved(ctx, req.Currency, req.Method, req.Limit)
+		if err != nil {
+			return err
+		}
+		if len(approved) == 0 {
+			return fmt.Errorf("%w: no approved withdraws to pay out", ErrValidation)
+		}
+		batch := &model.WithdrawBatch{
+			BatchNo:   model.GenerateOrderNo("PB"),
+			Channel:   req.Channel,
+			Method:    req.Method,
+			Currency:  req.Currency,
+			Status:    model.WithdrawBatchStatusProcessing,
+			CreatedBy: req.CreatedBy,
+		}
+		if err := r.Payouts.CreateBatch(ctx, batch); err != nil {
+			return err
+		}
+		for i := range approved {
+			w := approved[i]
+			w.Status = model.WithdrawStatusProcessing
+			w.BatchID = &batch.ID
+			w.PayoutChannel = req.Channel
+			w.OutPayoutNo = fmt.Sprintf("%s%04d", batch.BatchNo, i+1)
+			w.NextRetryAt = nil
+			if err := r.Payouts.Transition(ctx, &w, model.WithdrawStatusApproved); err != nil {
+				if errors.Is(err, withdrawrepo.ErrStatusConflict) {
+					continue // 已被其他批次纳入或已人工处理
+				}
+				return err
+			}
+			batch.TotalCount++
+			batch.TotalAmountCents += w.AmountCents
+			detail.Withdraws = append(detail.Withdraws, w)
+		}
+		if batch.TotalCount == 0 {
+			return fmt.Errorf("%w: no approved withdraws to pay out", ErrValidation)
+		}
+		if err := r.Payouts.UpdateBatch(ctx, batch); err != nil {
+			return err
+		}
+		detail.Batch = *batch
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range detail.Withdraws {
+		w := &detail.Withdraws[i]
+		s.notify(ctx, w, model.NotificationPriorityNormal, "提现打款中",
+			fmt.Sprintf("您的提现（%s）已进入打款流程，付款单号 %s。", formatAmount(w.AmountCents, w.Currency), w.OutPayoutNo))
+	}
+	return detail, nil
+}
+
+// ListBatches 查询打款批次
+func (s *WithdrawService) ListBatches(ctx context.Context, opts withdrawrepo.BatchListOptions) ([]model.WithdrawBatch, int64, error) {
+	return s.payouts.ListBatches(ctx, opts)
+}
+
+// GetBatch 获取打款批次及明细
+func (s *WithdrawService) GetBatch(ctx context.Context, id uint64) (*BatchDetail, error) {
+	batch, err := s.payouts.GetBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	withdraws, err := s.payouts.ListByBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchDetail{Batch: *batch, Withdraws: withdraws}, nil
+}
+
+// ExportBatchFile 导出银行批量代付 CSV（UTF-8 BOM，金额单位：批次币种的主单位）
+func (s *WithdrawService) ExportBatchFile(ctx context.Context, id uint64) (*BatchFile, error) {
+	detail, err := s.GetBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write([]byte{0xEF, 0xBB, 0xBF}) // Excel 识别 UTF-8
+	w := csv.NewWriter(&buf)
+	records := [][]string{{"序号", "商户付款单号", "提现方式", "收款账户", "金额", "币种", "备注"}}
+	for i, item := range detail.Withdraws {
+		records = append(records, []string{
+			strconv.Itoa(i + 1),
+			item.OutPayoutNo,
+			string(item.Method),
+			item.AccountInfo,
+			formatYuan(item.AmountCents),
+			string(detail.Batch.Currency.OrDefault()),
+			fmt.Sprintf("陪玩收益提现 %d", item.ID),
+		})
+	}
+	records = append(records, []string{"合计", strconv.FormatInt(detail.Batch.TotalCount, 10), "", "", formatYuan(detail.Batch.TotalAmountCents), string(detail.Batch.Currency.OrDefault()), ""})
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return &BatchFile{
+		Name:        detail.Batch.BatchNo + ".csv",
+		ContentType: "text/csv; charset=utf-8",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// refreshBatch 更新批次成功、失败笔数；全部终结后批次完成
+func refreshBatch(ctx context.Context, repo withdrawrepo.PayoutRepository, batchID uint64) error {
+	batch, err := repo.GetBatch(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	counts, err := repo.CountBatchStatuses(ctx, batchID)
+	if err != nil {
+		return err
+	}
+	batch.SuccessCount = counts[model.WithdrawStatusCompleted]
+	batch.FailedCount = counts[model.WithdrawStatusFailed]
+	if batch.Status != model.WithdrawBatchStatusCompleted && batch.SuccessCount+batch.FailedCount >= batch.TotalCount {
+		now := time.Now()
+		batch.Status = model.WithdrawBatchStatusCompleted
+		batch.CompletedAt = &now
+	}
+	return repo.UpdateBatch(ctx, batch)
+}
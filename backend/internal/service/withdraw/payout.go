@@ -0,0 +1,192 @@
+package withdraw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+)
+
+// ErrPayoutNotConfigured 未配置代付渠道
+var ErrPayoutNotConfigured = errors.New("payout provider not configured")
+
+const (
+	// maxPayoutRetries 渠道提交失败的最大次数，达到后提现标记失败并退回钱包
+	maxPayoutRetries = 5
+	// payoutRetryBase 提交失败后的首次重试间隔，之后逐次翻倍
+	payoutRetryBase = time.Minute
+	// payoutRetryMax 重试间隔上限
+	payoutRetryMax = time.Hour
+	// payoutQueryInterval 渠道处理中时的查询间隔
+	payoutQueryInterval = time.Minute
+)
+
+// PayoutState 渠道侧付款状态（已归一化）
+type PayoutState string
+
+// PayoutState values normalise provider-specific payout states.
+const (
+	PayoutStateProcessing PayoutState = "processing"
+	PayoutStateSucceeded  PayoutState = "succeeded"
+	PayoutStateFailed     PayoutState = "failed"
+)
+
+// PayoutRequest 代付请求；同一 OutPayoutNo 重复提交在渠道侧是幂等的
+type PayoutRequest struct {
+	OutPayoutNo string
+	Method      model.WithdrawMethod
+	AccountInfo string
+	AmountCents int64
+	Currency    model.Currency
+	Remark      string
+}
+
+// PayoutResult 代付受理或查询结果
+type PayoutResult struct {
+	OutPayoutNo      string
+	ProviderPayoutNo string
+	State            PayoutState
+	FailReason       string // State 为 failed 时的渠道原因（如账户不存在）
+}
+
+// PayoutProvider 代付渠道
+//
+// Submit/Query 返回 error 表示调用本身失败（网络、渠道繁忙），可重试；
+// 渠道明确拒绝付款时返回 State=failed。
+type PayoutProvider interface {
+	Submit(ctx context.Context, req PayoutRequest) (*PayoutResult, error)
+	Query(ctx context.Context, outPayoutNo string) (*PayoutResult, error)
+}
+
+// LocalPayoutProvider 本地代付桩：所有付款立即成功，用于开发与测试环境
+type LocalPayoutProvider struct{}
+
+// NewLocalPayoutProvider 创建本地代付桩
+func NewLocalPayoutProvider() *LocalPayoutProvider { return &LocalPayoutProvider{} }
+
+// Submit 直接返回成功
+func (LocalPayoutProvider) Submit(ctx context.Context, req PayoutRequest) (*PayoutResult, error) {
+	return &PayoutResult{OutPayoutNo: req.OutPayoutNo, ProviderPayoutNo: "local_" + req.OutPayoutNo, State: PayoutStateSucceeded}, nil
+}
+
+// Query 直接返回成功
+func (LocalPayoutProvider) Query(ctx context.Context, outPayoutNo string) (*PayoutResult, error) {
+	return &PayoutResult{OutPayoutNo: outPayoutNo, ProviderPayoutNo: "local_" + outPayoutNo, State: PayoutStateSucceeded}, nil
+}
+
+// ProcessPayouts 推进代付批次中的提现：提交未受理的付款，查询处理中的付款，返回本轮终结的数量
+func (s *WithdrawService) ProcessPayouts(ctx context.Context, limit int) (int, error) {
+	if s.provider == nil {
+		return 0, ErrPayoutNotConfigured
+	}
+	due, err := s.payouts.ListDuePayouts(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+	finished := 0
+	for i := range due {
+		updated, err := s.advancePayout(ctx, &due[i])
+		if err != nil {
+			slog.Warn("advance payout failed", slog.Uint64("withdraw_id", due[i].ID), slog.String("error", err.Error()))
+			continue
+		}
+		if updated.Status.IsFinal() {
+			finished++
+		}
+	}
+	return finished, nil
+}
+
+func (s *WithdrawService) advancePayout(ctx context.Context, w *model.Withdraw) (*model.Withdraw, error) {
+	if w.ProviderPayoutNo == "" {
+		result, err := s.provider.Submit(ctx, PayoutRequest{
+			OutPayoutNo: w.OutPayoutNo,
+			Method:      w.Method,
+			AccountInfo: w.AccountInfo,
+			AmountCents: w.AmountCents,
+			Currency:    w.Currency.OrDefault(),
+			Remark:      "陪玩收益提现",
+		})
+		if err != nil {
+			return s.recordSubmitFailure(ctx, w, err)
+		}
+		return s.applyPayoutResult(ctx, w, result)
+	}
+	result, err := s.provider.Query(ctx, w.OutPayoutNo)
+	if err != nil {
+		// 已受理的付款查询失败不计入重试次数：资金可能已经付出，只能等渠道结果
+		w.LastError = err.Error()
+		w.NextRetryAt = timePtr(time.Now().Add(payoutQueryInterval))
+		if serr := s.save(ctx, w); serr != nil {
+			return nil, serr
+		}
+		return w, fmt.Errorf("query payout: %w", err)
+	}
+	return s.applyPayoutResult(ctx, w, result)
+}
+
+// recordSubmitFailure 提交失败按指数退避重试，达到上限后标记失败并退回钱包
+func (s *WithdrawService) recordSubmitFailure(ctx context.Context, w *model.Withdraw, cause error) (*model.Withdraw, error) {
+	w.RetryCount++
+	w.LastError = cause.Error()
+	if w.RetryCount >= maxPayoutRetries {
+		retries := w.RetryCount
+		failed, err := s.fail(ctx, w.ID, nil, fmt.Sprintf("代付提交失败 %d 次：%s", retries, cause.Error()),
+			func(w *model.Withdraw) { w.RetryCount = retries })
+		if err != nil {
+			return nil, err
+		}
+		return failed, nil
+	}
+	w.NextRetryAt = timePtr(time.Now().Add(retryBackoff(w.RetryCount)))
+	if err := s.save(ctx, w); err != nil {
+		return nil, err
+	}
+	return w, fmt.Errorf("submit payout: %w", cause)
+}
+
+func (s *WithdrawService) applyPayoutResult(ctx context.Context, w *model.Withdraw, result *PayoutResult) (*model.Withdraw, error) {
+	switch result.State {
+	case PayoutStateSucceeded:
+		return s.complete(ctx, w.ID, nil, result.ProviderPayoutNo)
+	case PayoutStateFailed:
+		reason := result.FailReason
+		if reason == "" {
+			reason = "渠道付款失败"
+		}
+		return s.fail(ctx, w.ID, nil, reason, nil)
+	default:
+		w.ProviderPayoutNo = result.ProviderPayoutNo
+		if w.ProviderPayoutNo == "" {
+			// 渠道未返回单号时用商户单号标记已受理，后续改为查询
+			w.ProviderPayoutNo = w.OutPayoutNo
+		}
+		w.LastError = ""
+		w.NextRetryAt = timePtr(time.Now().Add(payoutQueryInterval))
+		if err := s.save(ctx, w); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+}
+
+// save 保存打款进度（状态保持 processing）
+func (s *WithdrawService) save(ctx context.Context, w *model.Withdraw) error {
+	return s.withTx(ctx, func(r *common.Repos) error {
+		return r.Payouts.Transition(ctx, w, model.WithdrawStatusProcessing)
+	})
+}
+
+func retryBackoff(attempt int) time.Duration {
+	d := payoutRetryBase << (attempt - 1)
+	if d <= 0 || d > payoutRetryMax {
+		return payoutRetryMax
+	}
+	return d
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
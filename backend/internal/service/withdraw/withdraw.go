@@ -0,0 +1,283 @@
+package withdraw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository"
+	"gamelink/internal/repository/common"
+	withdrawrepo "gamelink/internal/repository/withdraw"
+)
+
+var (
+	// ErrNotFound 提现或批次不存在
+	ErrNotFound = repository.ErrNotFound
+	// ErrValidation 表示输入校验失败
+	ErrValidation = errors.New("validation failed")
+	// ErrInvalidTransition 当前状态不允许该操作
+	ErrInvalidTransition = errors.New("invalid withdraw status transition")
+)
+
+// WithdrawService 提现处理服务
+//
+// 状态机：pending → approved → processing → completed / failed，pending 可被拒绝；
+// 每次流转都以原状态为条件更新，并发操作只有一方成功。
+// 拒绝与失败时冻结金额退回钱包，完成时扣减冻结并记账；每一步都通知陪玩师。
+type WithdrawService struct {
+	withdraws     withdrawrepo.WithdrawRepository
+	payouts       withdrawrepo.PayoutRepository
+	tx            TxManager
+	wallet        Wallet
+	ledger        Ledger
+	notifications repository.NotificationRepository
+	provider      PayoutProvider
+}
+
+// TxManager abstracts UnitOfWork for transactional operations.
+type TxManager interface {
+	WithTx(ctx context.Context, fn func(r *common.Repos) error) error
+}
+
+// Wallet 提现解冻与打款扣减（由钱包服务实现）
+type Wallet interface {
+	ReleaseWithdraw(ctx context.Context, r *common.Repos, withdraw *model.Withdraw, reason string) error
+	ConfirmWithdrawPaid(ctx context.Context, r *common.Repos, withdraw *model.Withdraw) error
+}
+
+// Ledger 提现打款记账（由总账服务实现）
+type Ledger interface {
+	PostWithdrawalPaid(ctx context.Context, r *common.Repos, withdraw *model.Withdraw) error
+}
+
+// NewWithdrawService 创建提现处理服务
+func NewWithdrawService(withdraws withdrawrepo.WithdrawRepository, payouts withdrawrepo.PayoutRepository) *WithdrawService {
+	return &WithdrawService{withdraws: withdraws, payouts: payouts}
+}
+
+// SetTxManager 注入事务管理器，状态流转与钱包变动在同一事务内完成
+func (s *WithdrawService) SetTxManager(tx TxManager) { s.tx = tx }
+
+// SetWallet 注入钱包服务
+func (s *WithdrawService) SetWallet(w Wallet) { s.wallet = w }
+
+// SetLedger 注入总账服务，打款完成后记账
+func (s *WithdrawService) SetLedger(l Ledger) { s.ledger = l }
+
+// SetNotifications 注入通知仓储，提现每次流转后通知陪玩师
+func (s *WithdrawService) SetNotifications(n repository.NotificationRepository) { s.notifications = n }
+
+// SetPayoutProvider 注入代付渠道；未注入时只能使用银行文件批次
+func (s *WithdrawService) SetPayoutProvider(p PayoutProvider) { s.provider = p }
+
+// List 查询提现记录
+func (s *WithdrawService) List(ctx context.Context, opts withdrawrepo.WithdrawListOptions) ([]model.Withdraw, int64, error) {
+	return s.withdraws.List(ctx, opts)
+}
+
+// Get 获取提现记录
+func (s *WithdrawService) Get(ctx context.Context, id uint64) (*model.Withdraw, error) {
+	return s.withdraws.Get(ctx, id)
+}
+
+// Approve 审核通过，等待纳入打款批次
+func (s *WithdrawService) Approve(ctx context.Context, id, adminID uint64, remark string) (*model.Withdraw, error) {
+	w, err := s.transition(ctx, id, model.WithdrawStatusApproved, func(r *common.Repos, w *model.Withdraw) error {
+		now := time.Now()
+		w.ProcessedBy = &adminID
+		w.ProcessedAt = &now
+		w.AdminRemark = remark
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, w, model.NotificationPriorityNormal, "提现申请已通过",
+		fmt.Sprintf("您的提现申请（%s）已审核通过，将尽快安排打款。", formatAmount(w.AmountCents, w.Currency)))
+	return w, nil
+}
+
+// Reject 拒绝提现，冻结金额退回可提现余额
+func (s *WithdrawService) Reject(ctx context.Context, id, adminID uint64, reason string) (*model.Withdraw, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reject reason required", ErrValidation)
+	}
+	w, err := s.transition(ctx, id, model.WithdrawStatusRejected, func(r *common.Repos, w *model.Withdraw) error {
+		now := time.Now()
+		w.ProcessedBy = &adminID
+		w.ProcessedAt = &now
+		w.RejectReason = reason
+		return s.release(ctx, r, w, "提现被拒绝："+reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, w, model.NotificationPriorityHigh, "提现申请被拒绝",
+		fmt.Sprintf("您的提现申请（%s）未通过审核，原因：%s。冻结金额已退回可提现余额。", formatAmount(w.AmountCents, w.Currency), reason))
+	return w, nil
+}
+
+// Complete 人工确认打款成功（银行文件批次或线下打款）
+func (s *WithdrawService) Complete(ctx context.Context, id, adminID uint64) (*model.Withdraw, error) {
+	return s.complete(ctx, id, &adminID, "")
+}
+
+// Fail 人工标记打款失败，冻结金额退回可提现余额
+func (s *WithdrawService) Fail(ctx context.Context, id, adminID uint64, reason string) (*model.Withdraw, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: failure reason required", ErrValidation)
+	}
+	return s.fail(ctx, id, &adminID, reason, nil)
+}
+
+func (s *WithdrawService) complete(ctx context.Context, id uint64, adminID *uint64, providerPayoutNo string) (*model.Withdraw, error) {
+	w, err := s.transition(ctx, id, model.WithdrawStatusCompleted, func(r *common.Repos, w *model.Withdraw) error {
+		now := time.Now()
+		w.CompletedAt = &now
+		w.NextRetryAt = nil
+		w.LastError = ""
+		if w.ProcessedBy == nil {
+			w.ProcessedBy = adminID
+		}
+		if providerPayoutNo != "" {
+			w.ProviderPayoutNo = providerPayoutNo
+		}
+		if s.wallet != nil {
+			if err := s.wallet.ConfirmWithdrawPaid(ctx, r, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.ledger != nil {
+		// 打款已完成，记账失败仅记录日志（记账按提现单幂等，可补记）
+		if err := s.ledger.PostWithdrawalPaid(ctx, nil, w); err != nil {
+			slog.Warn("post withdrawal to ledger failed", slog.Uint64("withdraw_id", w.ID), slog.String("error", err.Error()))
+		}
+	}
+	s.notify(ctx, w, model.NotificationPriorityNormal, "提现已到账",
+		fmt.Sprintf("您的提现（%s）已打款成功，请注意查收。", formatAmount(w.AmountCents, w.Currency)))
+	return w, nil
+}
+
+// fail 标记打款失败并退回钱包；update 可在同一事务内补充打款进度字段
+func (s *WithdrawService) fail(ctx context.Context, id uint64, adminID *uint64, reason string, update func(w *model.Withdraw)) (*model.Withdraw, error) {
+	w, err := s.transition(ctx, id, model.WithdrawStatusFailed, func(r *common.Repos, w *model.Withdraw) error {
+		if update != nil {
+			update(w)
+		}
+		now := time.Now()
+		w.LastError = reason
+		w.NextRetryAt = nil
+		if w.ProcessedBy == nil {
+			w.ProcessedBy = adminID
+		}
+		if w.ProcessedAt == nil {
+			w.ProcessedAt = &now
+		}
+		return s.release(ctx, r, w, "提现失败："+reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, w, model.NotificationPriorityHigh, "提现失败",
+		fmt.Sprintf("您的提现（%s）打款失败，原因：%s。金额已退回可提现余额，请核对收款账户后重新申请。", formatAmount(w.AmountCents, w.Currency), reason))
+	return w, nil
+}
+
+// transition 在事务内把提现推进到 next；mutate 可修改字段并执行同事务内的附带操作
+func (s *WithdrawService) transition(ctx context.Context, id uint64, next model.WithdrawStatus, mutate func(r *common.Repos, w *model.Withdraw) error) (*model.Withdraw, error) {
+	var out *model.Withdraw
+	err := s.withTx(ctx, func(r *common.Repos) error {
+		w, err := r.Withdraws.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		from := w.Status
+		if !from.CanTransitionTo(next) {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, next)
+		}
+		w.Status = next
+		if mutate != nil {
+			if err := mutate(r, w); err != nil {
+				return err
+			}
+		}
+		if err := r.Payouts.Transition(ctx, w, from); err != nil {
+			if errors.Is(err, withdrawrepo.ErrStatusConflict) {
+				return fmt.Errorf("%w: %v", ErrInvalidTransition, err)
+			}
+			return err
+		}
+		if w.BatchID != nil && next.IsFinal() {
+			if err := refreshBatch(ctx, r.Payouts, *w.BatchID); err != nil {
+				return err
+			}
+		}
+		out = w
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *WithdrawService) release(ctx context.Context, r *common.Repos, w *model.Withdraw, reason string) error {
+	if s.wallet == nil {
+		return nil
+	}
+	return s.wallet.ReleaseWithdraw(ctx, r, w, reason)
+}
+
+// notify 通知失败不影响提现流转
+func (s *WithdrawService) notify(ctx context.Context, w *model.Withdraw, priority model.NotificationPriority, title, message string) {
+	if s.notifications == nil || w.UserID == 0 {
+		return
+	}
+	id := w.ID
+	err := s.notifications.Create(ctx, &model.NotificationEvent{
+		UserID:        w.UserID,
+		Title:         title,
+		Message:       message,
+		Priority:      priority,
+		ReferenceType: string(model.OpEntityWithdraw),
+		ReferenceID:   &id,
+	})
+	if err != nil {
+		slog.Warn("notify withdraw status failed", slog.Uint64("withdraw_id", w.ID), slog.String("error", err.Error()))
+	}
+}
+
+func (s *WithdrawService) withTx(ctx context.Context, fn func(r *common.Repos) error) error {
+	if s.tx != nil {
+		return s.tx.WithTx(ctx, fn)
+	}
+	return fn(&common.Repos{Withdraws: s.withdraws, Payouts: s.payouts})
+}
+
+// formatAmount 通知中展示的金额：人民币显示为“元”，外币附币种代码。
+func formatAmount(cents int64, currency model.Currency) string {
+	if c := currency.OrDefault(); c != model.CurrencyCNY {
+		return formatYuan(cents) + " " + string(c)
+	}
+	return formatYuan(cents) + " 元"
+}
+
+func formatYuan(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,388 @@
+package withdraw
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"gamelink/internal/model"
+	"gamelink/internal/repository/common"
+	notificationrepo "gamelink/internal/repository/notification"
+	walletrepo "gamelink/internal/repository/wallet"
+	withdrawrepo "gamelink/internal/repository/withdraw"
+	walletservice "gamelink/internal/service/wallet"
+)
+
+type testEnv struct {
+	svc    *WithdrawService
+	wallet *walletservice.WalletService
+	db     *gorm.DB
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(
+		&model.Withdraw{}, &model.WithdrawBatch{},
+		&model.PlayerWallet{}, &model.WalletTransaction{},
+		&model.NotificationEvent{},
+	))
+
+	uow := common.NewUnitOfWork(db)
+	wallet := walletservice.NewWalletService(walletrepo.NewWalletRepository(db))
+	wallet.SetTxManager(uow)
+
+	svc := NewWithdrawService(withdrawrepo.NewWithdrawRepository(db), withdrawrepo.NewPayoutRepository(db))
+	svc.SetTxManager(uow)
+	svc.SetWallet(wallet)
+	svc.SetNotifications(notificationrepo.NewNotificationRepository(db))
+	return &testEnv{svc: svc, wallet: wallet, db: db}
+}
+
+// requestWithdraw 模拟陪玩师申请提现：入账结算后冻结提现金额
+func (e *testEnv) requestWithdraw(t *testing.T, id, amount int64) *model.Withdraw {
+	t.Helper()
+	ctx := context.Background()
+	record := &model.CommissionRecord{ID: uint64(id), OrderID: uint64(id), PlayerID: 1, PlayerIncomeCents: amount, SettlementMonth: "2024-05"}
+	require.NoError(t, e.wallet.SettleIncome(ctx, nil, record))
+	w := &model.Withdraw{
+		ID:          uint64(id),
+		PlayerID:    1,
+		UserID:      100,
+		AmountCents: amount,
+		Method:      model.WithdrawMethodBank,
+		AccountInfo: "6222000011112222",
+		Status:      model.WithdrawStatusPending,
+	}
+	require.NoError(t, e.db.Create(w).Error)
+	require.NoError(t, e.wallet.FreezeWithdraw(ctx, nil, w))
+	return w
+}
+
+func (e *testEnv) assertWallet(t *testing.T, available, frozen int64) {
+	t.Helper()
+	w, err := e.wallet.GetWallet(context.Background(), 1, model.CurrencyCNY)
+	require.NoError(t, err)
+	assert.Equal(t, available, w.AvailableCents, "available")
+	assert.Equal(t, frozen, w.FrozenCents, "frozen")
+}
+
+func (e *testEnv) notificationTitles(t *testing.T) []string {
+	t.Helper()
+	var events []model.NotificationEvent
+	require.NoError(t, e.db.Where("user_id = ?", 100).Order("id ASC").Find(&events).Error)
+	titles := make([]string, 0, len(events))
+	for _, ev := range events {
+		titles = append(titles, ev.Title)
+	}
+	return titles
+}
+
+func TestWithdraw_StateMachine(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.requestWithdraw(t, 1, 5000)
+
+	// 待审核的提现不能直接完成或失败
+	_, err := env.svc.Complete(ctx, 1, 9)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	_, err = env.svc.Fail(ctx, 1, 9, "bank returned")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	w, err := env.svc.Approve(ctx, 1, 9, "ok")
+	require.NoError(t, err)
+	assert.Equal(t, model.WithdrawStatusApproved, w.Status)
+	require.NotNil(t, w.ProcessedBy)
+	assert.Equal(t, uint64(9), *w.ProcessedBy)
+
+	// 已审核的提现不能重复审核或拒绝
+	_, err = env.svc.Approve(ctx, 1, 9, "")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	_, err = env.svc.Reject(ctx, 1, 9, "late")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	w, err = env.svc.Complete(ctx, 1, 9)
+	require.NoError(t, err)
+	assert.Equal(t, model.WithdrawStatusCompleted, w.Status)
+	assert.NotNil(t, w.CompletedAt)
+	env.assertWallet(t, 0, 0)
+
+	// 终态不可再流转
+	_, err = env.svc.Fail(ctx, 1, 9, "oops")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	_, err = env.svc.Approve(ctx, 404, 9, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.Equal(t, []string{"提现申请已通过", "提现已到账"}, env.notificationTitles(t))
+}
+
+func TestWithdraw_RejectReleasesFunds(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.requestWithdraw(t, 1, 3000)
+	env.assertWallet(t, 0, 3000)
+
+	_, err := env.svc.Reject(ctx, 1, 9, "  ")
+	assert.ErrorIs(t, err, ErrValidation)
+
+	w, err := env.svc.Reject(ctx, 1, 9, "账户信息有误")
+	require.NoError(t, err)
+	assert.Equal(t, model.WithdrawStatusRejected, w.Status)
+	assert.Equal(t, "账户信息有误", w.RejectReason)
+	env.assertWallet(t, 3000, 0)
+
+	// 重复拒绝不会再次解冻
+	_, err = env.svc.Reject(ctx, 1, 9, "again")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	env.assertWallet(t, 3000, 0)
+	assert.Equal(t, []string{"提现申请被拒绝"}, env.notificationTitles(t))
+}
+
+func TestWithdraw_ConcurrentApproveOnlyOneWins(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.requestWithdraw(t, 1, 1000)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 2)
+	wg.Add(2)
+	go func() { defer wg.Done(); _, err := env.svc.Approve(ctx, 1, 9, ""); results <- err }()
+	go func() { defer wg.Done(); _, err := env.svc.Reject(ctx, 1, 8, "dup"); results <- err }()
+	wg.Wait()
+	close(results)
+
+	var ok, conflicts int
+	for err := range results {
+		switch {
+		case err == nil:
+			ok++
+		case errors.Is(err, ErrInvalidTransition):
+			conflicts++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, ok)
+	assert.Equal(t, 1, conflicts)
+}
+
+func TestWithdraw_BankFileBatch(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	env.requestWithdraw(t, 1, 5000)
+	env.requestWithdraw(t, 2, 2550)
+	env.requestWithdraw(t, 3, 1000) // 未审核，不纳入批次
+	for _, id := range []uint64{1, 2} {
+		_, err := env.svc.Approve(ctx, id, 9, "")
+		require.NoError(t, err)
+	}
+
+	_, err := env.svc.CreateBatch(ctx, CreateBatchRequest{Channel: "cash", CreatedBy: 9})
+	assert.ErrorIs(t, err, ErrValidation)
+	_, err = env.svc.CreateBatch(ctx, CreateBatchRequest{Channel: model.PayoutChannelProvider, CreatedBy: 9})
+	assert.ErrorIs(t, err, ErrPayoutNotConfigured)
+
+	detail, err := env.svc.CreateBatch(ctx, CreateBatchRequest{Channel: model.PayoutChannelBankFile, CreatedBy: 9})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), detail.Batch.TotalCount)
+	assert.Equal(t, int64(7550), detail.Batch.TotalAmountCents)
+	require.Len(t, detail.Withdraws, 2)
+	for _, w := range detail.Withdraws {
+		assert.Equal(t, model.WithdrawStatusProcessing, w.Status)
+		assert.True(t, strings.HasPrefix(w.OutPayoutNo, detail.Batch.BatchNo))
+	}
+
+	// 没有新的已审核提现时不生成空批次
+	_, err = env.svc.CreateBatch(ctx, CreateBatchRequest{Channel: model.PayoutChannelBankFile, CreatedBy: 9})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	file, err := env.svc.ExportBatchFile(ctx, detail.Batch.ID)
+	require.NoError(t, err)
+	content := string(file.Data)
+	assert.True(t, strings.HasPrefix(content, "\xEF\xBB\xBF序号,商户付款单号"))
+	assert.Contains(t, content, "6222000011112222,50.00")
+	assert.Contains(t, content, "合计,2,,,75.50,")
+
+	_, err = env.svc.Complete(ctx, 1, 9)
+	require.NoError(t, err)
+	got, err := env.svc.GetBatch(ctx, detail.Batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.WithdrawBatchStatusProcessing, got.Batch.Status)
+
+	_, err = env.svc.Fail(ctx, 2, 9, "银行退票")
+	require.NoError(t, err)
+	got, err = env.svc.GetBatch(ctx, detail.Batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.WithdrawBatchStatusCompleted, got.Batch.Status)
+	assert.Equal(t, int64(1), got.Batch.SuccessCount)
+	assert.Equal(t, int64(1), got.Batch.FailedCount)
+	assert.NotNil(t, got.Batch.CompletedAt)
+
+	// 2550 退回可提现余额，3 号提现仍冻结
+	env.assertWallet(t, 2550, 1000)
+}
+
+// fakeProvider 按脚本返回提交与查询结果
+type fakeProvider struct {
+	submitErrs []error
+	submit     PayoutState
+	query      PayoutState
+	submits    int
+	queries    int
+}
+
+func (p *fakeProvider) Submit(ctx context.Context, req PayoutRequest) (*PayoutResult, error) {
+	p.submits++
+	if len(p.submitErrs) > 0 {
+		err := p.submitErrs[0]
+		p.submitErrs = p.submitErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &PayoutResult{OutPayoutNo: req.OutPayoutNo, ProviderPayoutNo: "P" + req.OutPayoutNo, State: p.submit, FailReason: "收款账户不存在"}, nil
+}
+
+func (p *fakeProvider) Query(ctx context.Context, outPayoutNo string) (*PayoutResult, error) {
+	p.queries++
+	return &PayoutResult{OutPayoutNo: outPayoutNo, ProviderPayoutNo: "P" + outPayoutNo, State: p.query}, nil
+}
+
+func (e *testEnv) providerBatch(t *testing.T, ids ...int64) {
+	t.Helper()
+	ctx := context.Background()
+	for _, id := range ids {
+		e.requestWithdraw(t, id, 1000*id)
+		_, err := e.svc.Approve(ctx, uint64(id), 9, "")
+		require.NoError(t, err)
+	}
+	_, err := e.svc.CreateBatch(ctx, CreateBatchRequest{Channel: model.PayoutChannelProvider, CreatedBy: 9})
+	require.NoError(t, err)
+}
+
+// makeDue 让等待重试或查询的提现立即到期
+func (e *testEnv) makeDue(t *testing.T) {
+	t.Helper()
+	past := time.Now().Add(-time.Second)
+	require.NoError(t, e.db.Model(&model.Withdraw{}).Where("next_retry_at IS NOT NULL").Update("next_retry_at", past).Error)
+}
+
+func (e *testEnv) get(t *testing.T, id uint64) *model.Withdraw {
+	t.Helper()
+	w, err := e.svc.Get(context.Background(), id)
+	require.NoError(t, err)
+	return w
+}
+
+func TestProcessPayouts_SubmitSucceedsAndFails(t *testing.T) {
+	env := newTestEnv(t)
+	provider := &fakeProvider{submit: PayoutStateSucceeded}
+	env.svc.SetPayoutProvider(provider)
+	env.providerBatch(t, 1)
+
+	finished, err := env.svc.ProcessPayouts(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, finished)
+	w := env.get(t, 1)
+	assert.Equal(t, model.WithdrawStatusCompleted, w.Status)
+	assert.Equal(t, "P"+w.OutPayoutNo, w.ProviderPayoutNo)
+	env.assertWallet(t, 0, 0)
+
+	// 渠道明确拒绝：直接失败并退回钱包
+	provider.submit = PayoutStateFailed
+	env.providerBatch(t, 2)
+	finished, err = env.svc.ProcessPayouts(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, finished)
+	w = env.get(t, 2)
+	assert.Equal(t, model.WithdrawStatusFailed, w.Status)
+	assert.Equal(t, "收款账户不存在", w.LastError)
+	env.assertWallet(t, 2000, 0)
+
+	titles := env.notificationTitles(t)
+	assert.Contains(t, titles, "提现打款中")
+	assert.Contains(t, titles, "提现已到账")
+	assert.Equal(t, "提现失败", titles[len(titles)-1])
+}
+
+func TestProcessPayouts_RetriesThenFails(t *testing.T) {
+	env := newTestEnv(t)
+	down := errors.New("provider unavailable")
+	provider := &fakeProvider{submitErrs: []error{down, down, down, down, down}, submit: PayoutStateSucceeded}
+	env.svc.SetPayoutProvider(provider)
+	env.providerBatch(t, 1)
+	ctx := context.Background()
+
+	_, err := env.svc.ProcessPayouts(ctx, 10)
+	require.NoError(t, err)
+	w := env.get(t, 1)
+	assert.Equal(t, model.WithdrawStatusProcessing, w.Status)
+	assert.Equal(t, 1, w.RetryCount)
+	assert.Equal(t, "provider unavailable", w.LastError)
+	require.NotNil(t, w.NextRetryAt)
+	assert.WithinDuration(t, time.Now().Add(payoutRetryBase), *w.NextRetryAt, 5*time.Second)
+
+	// 未到重试时间不会再次提交
+	_, err = env.svc.ProcessPayouts(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.submits)
+
+	for i := 2; i <= maxPayoutRetries; i++ {
+		env.makeDue(t)
+		_, err = env.svc.ProcessPayouts(ctx, 10)
+		require.NoError(t, err)
+	}
+	w = env.get(t, 1)
+	assert.Equal(t, model.WithdrawStatusFailed, w.Status)
+	assert.Equal(t, maxPayoutRetries, w.RetryCount)
+	assert.Contains(t, w.LastError, "provider unavailable")
+	env.assertWallet(t, 1000, 0)
+}
+
+func TestProcessPayouts_QueriesAcceptedPayouts(t *testing.T) {
+	env := newTestEnv(t)
+	provider := &fakeProvider{submit: PayoutStateProcessing, query: PayoutStateProcessing}
+	env.svc.SetPayoutProvider(provider)
+	env.providerBatch(t, 1)
+	ctx := context.Background()
+
+	finished, err := env.svc.ProcessPayouts(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, finished)
+	w := env.get(t, 1)
+	assert.Equal(t, model.WithdrawStatusProcessing, w.Status)
+	assert.NotEmpty(t, w.ProviderPayoutNo)
+
+	env.makeDue(t)
+	_, err = env.svc.ProcessPayouts(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.submits, "accepted payouts are queried, not resubmitted")
+	assert.Equal(t, 1, provider.queries)
+
+	provider.query = PayoutStateSucceeded
+	env.makeDue(t)
+	finished, err = env.svc.ProcessPayouts(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, finished)
+	assert.Equal(t, model.WithdrawStatusCompleted, env.get(t, 1).Status)
+	env.assertWallet(t, 0, 0)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, retryBackoff(1))
+	assert.Equal(t, 4*time.Minute, retryBackoff(3))
+	assert.Equal(t, payoutRetryMax, retryBackoff(10))
+	assert.Equal(t, payoutRetryMax, retryBackoff(100))
+}
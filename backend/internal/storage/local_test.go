@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/config"
+)
+
+func TestLocal_PutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "evidence/2026/10/a.txt", strings.NewReader("hello"), 5, "text/plain"))
+	rc, err := store.Open(ctx, "evidence/2026/10/a.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "hello", string(data))
+
+	require.NoError(t, store.Delete(ctx, "evidence/2026/10/a.txt"))
+	_, err = store.Open(ctx, "evidence/2026/10/a.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, "evidence/2026/10/a.txt"))
+}
+
+func TestLocal_RejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", `a\b`} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "")
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
+
+func TestNew(t *testing.T) {
+	store, err := New(config.StorageConfig{Driver: "local", LocalDir: t.TempDir()})
+	require.NoError(t, err)
+	assert.IsType(t, &Local{}, store)
+
+	_, err = New(config.StorageConfig{Driver: "s3"})
+	assert.Error(t, err)
+	_, err = New(config.StorageConfig{Driver: "ftp"})
+	assert.Error(t, err)
+}
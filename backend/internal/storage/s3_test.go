@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gamelink/internal/config"
+)
+
+// s3StandIn 是内存版的 S3 兼容服务，校验 SigV4 签名后按 path-style 存取对象。
+type s3StandIn struct {
+	accessKey, secretKey, region string
+
+	mu      sync.Mutex
+	objects map[string]s3Object
+}
+
+type s3Object struct {
+	data        []byte
+	contentType string
+}
+
+func newS3StandIn(accessKey, secretKey, region string) *s3StandIn {
+	return &s3StandIn{accessKey: accessKey, secretKey: secretKey, region: region, objects: map[string]s3Object{}}
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.verify(r) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = s3Object{data: data, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		_, _ = w.Write(obj.data)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *s3StandIn) verify(r *http.Request) bool {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), s3Algorithm+" ")
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			fields[k] = v
+		}
+	}
+	at, err := time.Parse(s3TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil || time.Since(at) > 15*time.Minute || time.Until(at) > 15*time.Minute {
+		return false
+	}
+	if fields["Credential"] != s.accessKey+"/"+s3Scope(at, s.region) {
+		return false
+	}
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	want := s3Signature(r, signed, s.secretKey, s.region, at)
+	return hmac.Equal([]byte(want), []byte(fields["Signature"]))
+}
+
+func newTestS3(t *testing.T, secret string) (*S3, *s3StandIn) {
+	standIn := newS3StandIn("AKIDEXAMPLE", "secret", "cn-test-1")
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	store, err := NewS3(config.S3Config{Endpoint: srv.URL, Region: "cn-test-1", Bucket: "gamelink", AccessKey: "AKIDEXAMPLE", SecretKey: secret})
+	require.NoError(t, err)
+	return store, standIn
+}
+
+func TestS3_PutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	store, standIn := newTestS3(t, "secret")
+
+	key := "dispute_evidence/2026/10/a b+c.png"
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("png-bytes"), 9, "image/png"))
+	assert.Equal(t, "image/png", standIn.objects["gamelink/"+key].contentType)
+
+	rc, err := store.Open(ctx, key)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "png-bytes", string(data))
+
+	require.NoError(t, store.Delete(ctx, key))
+	_, err = store.Open(ctx, key)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, key))
+
+	_, err = store.Open(ctx, "../escape")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestS3_RejectedSignature(t *testing.T) {
+	store, _ := newTestS3(t, "wrong-secret")
+	err := store.Put(context.Background(), "a.txt", strings.NewReader("x"), 1, "text/plain")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
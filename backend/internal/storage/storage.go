@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"gamelink/internal/config"
+)
+
+var (
+	// ErrNotFound 对象不存在。
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrInvalidKey 对象键为空、为绝对路径或包含 ".."。
+	ErrInvalidKey = errors.New("storage: invalid object key")
+)
+
+// Storage 上传文件的存储后端，对象以 "/" 分隔的相对键寻址。
+type Storage interface {
+	// Put 写入对象，size 为 -1 表示长度未知；同名对象被覆盖。
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取对象，调用方负责关闭。
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不报错。
+	Delete(ctx context.Context, key string) error
+}
+
+// New 根据配置创建存储后端。
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocal(cfg.LocalDir)
+	case "s3":
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", cfg.Driver)
+	}
+}
+
+// cleanKey 校验对象键，避免越出存储根目录或桶前缀。
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return path.Clean(key), nil
+}